@@ -0,0 +1,134 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/telegram"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/rs/zerolog/log"
+)
+
+// Alerter posts operational alerts to the configured admin chat.
+type Alerter struct {
+	cfg        config.AdminConfig
+	cfgMu      sync.RWMutex
+	botStore   *database.TelegramBotStore
+	proxyStore *database.ProxyStore
+	tgClient   Sender
+
+	sentMu sync.Mutex
+	sent   map[string]time.Time // Last alert by key, for SendThrottled
+}
+
+// Sender sends alerts: the Telegram client, or the sandbox notifier that
+// records them instead.
+type Sender interface {
+	interfaces.Notifier
+	ProxyPicker() interfaces.ProxyPicker
+}
+
+// NewAlerter creates an Alerter. Alerts are dropped while no admin chat is
+// configured, as they are by a nil *Alerter, so callers need no checks.
+func NewAlerter(cfg config.AdminConfig, botStore *database.TelegramBotStore, proxyStore *database.ProxyStore, tgClient Sender) *Alerter {
+	return &Alerter{cfg: cfg, botStore: botStore, proxyStore: proxyStore, tgClient: tgClient, sent: make(map[string]time.Time)}
+}
+
+// SetConfig replaces the admin chat and bot, e.g. after a config reload.
+func (a *Alerter) SetConfig(cfg config.AdminConfig) {
+	a.cfgMu.Lock()
+	defer a.cfgMu.Unlock()
+	a.cfg = cfg
+}
+
+// Enabled reports whether an admin chat is configured.
+func (a *Alerter) Enabled() bool {
+	return a.config().ChatID != ""
+}
+
+func (a *Alerter) config() config.AdminConfig {
+	if a == nil {
+		return config.AdminConfig{}
+	}
+	a.cfgMu.RLock()
+	defer a.cfgMu.RUnlock()
+	return a.cfg
+}
+
+// Send posts text to the admin chat. Text is sent as HTML, so callers must
+// escape any dynamic content with telegram.EscapeHTML.
+func (a *Alerter) Send(ctx context.Context, text string) error {
+	cfg := a.config()
+	if cfg.ChatID == "" {
+		return nil
+	}
+	token, tgProxy, err := a.adminBot(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	parts := []interfaces.FormattedMessagePart{{Text: text, ParseMode: "HTML"}}
+	if err := a.tgClient.Send(ctx, token, cfg.ChatID, parts, tgProxy); err != nil {
+		return fmt.Errorf("sending admin alert: %w", err)
+	}
+	return nil
+}
+
+// SendThrottled is Send for alerts about one thing, named by key such as
+// "feed:12": it drops the alert when one with the same key was sent within
+// admin.alerts.cooldown.
+func (a *Alerter) SendThrottled(ctx context.Context, key, text string) error {
+	cfg := a.config()
+	if cfg.ChatID == "" {
+		return nil
+	}
+	now := time.Now()
+	a.sentMu.Lock()
+	if last, ok := a.sent[key]; ok && now.Sub(last) < cfg.Alerts.Cooldown {
+		a.sentMu.Unlock()
+		log.Debug().Str("alert_key", key).Msg("Admin alert dropped during cooldown")
+		return nil
+	}
+	a.sent[key] = now
+	a.sentMu.Unlock()
+	return a.Send(ctx, text)
+}
+
+// ProxyHealthChanged alerts the admin chat that the health checker marked a
+// proxy down, with the failed probe's error, or up again, when
+// admin.alerts.proxy_down is set.
+func (a *Alerter) ProxyHealthChanged(ctx context.Context, p *database.Proxy, healthy bool, probeErr error) {
+	if !a.config().Alerts.ProxyDown {
+		return
+	}
+	text := fmt.Sprintf("✅ <b>Proxy up</b> %s\n%s passes its health checks again.", telegram.EscapeHTML(p.Name), telegram.EscapeHTML(p.Address))
+	if !healthy {
+		text = fmt.Sprintf("🔌 <b>Proxy down</b> %s\n%s is unreachable; routing around it.", telegram.EscapeHTML(p.Name), telegram.EscapeHTML(p.Address))
+		if probeErr != nil {
+			text += "\n" + telegram.EscapeHTML(probeErr.Error())
+		}
+	}
+	if err := a.SendThrottled(ctx, fmt.Sprintf("proxy:%d:%t", p.ID, healthy), text); err != nil {
+		log.Warn().Err(err).Str("proxy_name", p.Name).Msg("Failed to alert admin chat about proxy health")
+	}
+}
+
+// adminBot returns the token of the admin bot and the Telegram proxy to
+// reach it through.
+func (a *Alerter) adminBot(ctx context.Context, cfg config.AdminConfig) (string, *database.Proxy, error) {
+	if cfg.BotID == 0 {
+		return "", nil, fmt.Errorf("admin.bot_id is not configured")
+	}
+	token, err := a.botStore.GetTokenByBotID(ctx, cfg.BotID)
+	if err != nil {
+		return "", nil, fmt.Errorf("retrieving admin bot token: %w", err)
+	}
+	tgProxy, err := telegram.DefaultProxy(ctx, a.proxyStore, a.tgClient.ProxyPicker(), 0)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get default Telegram proxy for the admin bot")
+	}
+	return token, tgProxy, nil
+}
@@ -0,0 +1,61 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSender records the text of each alert sent.
+type recordingSender struct{ texts []string }
+
+func (s *recordingSender) Send(ctx context.Context, botToken, chatID string, parts []interfaces.FormattedMessagePart, proxy *database.Proxy) error {
+	s.texts = append(s.texts, parts[0].Text)
+	return nil
+}
+
+func (s *recordingSender) Name() string                        { return "test" }
+func (s *recordingSender) ProxyPicker() interfaces.ProxyPicker { return nil }
+
+func TestSendThrottled(t *testing.T) {
+	require.NoError(t, database.InitEncryptionKey("alert test key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "alerts.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+	bots := database.NewTelegramBotStore(db)
+	botID, err := bots.CreateBot(ctx, "123:abc", nil)
+	require.NoError(t, err)
+
+	sender := &recordingSender{}
+	cfg := config.AdminConfig{BotID: botID, ChatID: "-100", Alerts: config.AlertsConfig{ProxyDown: true, Cooldown: time.Hour}}
+	a := NewAlerter(cfg, bots, database.NewProxyStore(db), sender)
+
+	require.NoError(t, a.SendThrottled(ctx, "feed:1", "first"))
+	require.NoError(t, a.SendThrottled(ctx, "feed:1", "again"))
+	require.NoError(t, a.SendThrottled(ctx, "feed:2", "other feed"))
+	assert.Equal(t, []string{"first", "other feed"}, sender.texts, "repeats within the cooldown are dropped")
+
+	p := &database.Proxy{ID: 4, Name: "edge", Address: "http://10.0.0.1:3128"}
+	a.ProxyHealthChanged(ctx, p, false, errors.New("dial tcp: connection refused"))
+	a.ProxyHealthChanged(ctx, p, true, nil)
+	require.Len(t, sender.texts, 4)
+	assert.Contains(t, sender.texts[2], "Proxy down")
+	assert.Contains(t, sender.texts[2], "connection refused")
+	assert.Contains(t, sender.texts[3], "Proxy up")
+
+	cfg.Alerts = config.AlertsConfig{}
+	a.SetConfig(cfg)
+	a.ProxyHealthChanged(ctx, &database.Proxy{ID: 5, Name: "other"}, false, nil)
+	require.NoError(t, a.SendThrottled(ctx, "feed:1", "no cooldown"))
+	require.Len(t, sender.texts, 5, "proxy alerts are off")
+	assert.Equal(t, "no cooldown", sender.texts[4], "without a cooldown nothing is dropped")
+}
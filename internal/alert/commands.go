@@ -0,0 +1,257 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/telegram"
+	"github.com/rs/zerolog/log"
+)
+
+// CommandListener receives the commands sent to a bot: the Telegram client.
+type CommandListener interface {
+	ListenCommands(ctx context.Context, botToken string, proxy *database.Proxy, handle telegram.CommandHandler) error
+}
+
+// commandsHelp answers /help.
+const commandsHelp = `<b>Feed commands</b>
+/list [tag] – list the feeds
+/add &lt;url&gt; [chat_id] – deliver a feed to chat_id, or to this chat
+/remove &lt;id&gt; – delete a feed
+/pause &lt;id&gt; – stop fetching a feed
+/resume &lt;id&gt; – fetch a paused feed again`
+
+// Commander lets the admin bot manage feeds through commands sent in
+// Telegram. Only the chats and users of admin.commands.allowed, or the admin
+// chat, are answered. It reads its settings from the Alerter, so config
+// reloads apply, except for enabling it, which takes a restart.
+type Commander struct {
+	alerter          *Alerter
+	feeds            *database.FeedStore
+	listener         CommandListener
+	defaultFrequency int
+	onFeedEnabled    func(*database.Feed)
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// NewCommander creates a Commander receiving commands through listener.
+// Feeds added with /add are fetched every defaultFrequency seconds.
+func NewCommander(alerter *Alerter, feeds *database.FeedStore, listener CommandListener, defaultFrequency int) *Commander {
+	return &Commander{alerter: alerter, feeds: feeds, listener: listener, defaultFrequency: defaultFrequency}
+}
+
+// OnFeedEnabled sets the function called with each feed added or resumed,
+// e.g. to make sure it is scheduled.
+func (c *Commander) OnFeedEnabled(fn func(*database.Feed)) {
+	c.onFeedEnabled = fn
+}
+
+// Start listens for commands to the admin bot until Stop is called or ctx is
+// done. It does nothing unless admin.commands.enabled is set.
+func (c *Commander) Start(ctx context.Context) {
+	cfg := c.alerter.config()
+	if !cfg.Commands.Enabled {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		return
+	}
+	ctx, c.cancel = context.WithCancel(ctx)
+	if len(cfg.Commands.Allowed) == 0 && cfg.ChatID == "" {
+		log.Warn().Msg("admin.commands is enabled but neither admin.commands.allowed nor admin.chat_id is set, so all commands are ignored")
+	}
+
+	go func() {
+		token, proxy, err := c.alerter.adminBot(ctx, cfg)
+		if err != nil {
+			log.Error().Err(err).Msg("Bot commands are not available")
+			return
+		}
+		if err := c.listener.ListenCommands(ctx, token, proxy, c.handle); err != nil {
+			log.Error().Err(err).Msg("Bot commands are not available")
+		}
+	}()
+}
+
+// Stop ends listening for commands.
+func (c *Commander) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+}
+
+// allowed reports whether cmd comes from an allowed chat or user.
+func (c *Commander) allowed(cmd telegram.Command) bool {
+	cfg := c.alerter.config()
+	allowed := cfg.Commands.Allowed
+	if len(allowed) == 0 && cfg.ChatID != "" {
+		allowed = []string{cfg.ChatID}
+	}
+	if slices.Contains(allowed, strconv.FormatInt(cmd.ChatID, 10)) {
+		return true
+	}
+	return cmd.UserID != 0 && slices.Contains(allowed, strconv.FormatInt(cmd.UserID, 10))
+}
+
+// handle answers a command with an HTML reply.
+func (c *Commander) handle(ctx context.Context, cmd telegram.Command) string {
+	l := log.With().Str("command", cmd.Name).Int64("chat_id", cmd.ChatID).Int64("user_id", cmd.UserID).Logger()
+	if !c.allowed(cmd) {
+		l.Warn().Str("username", cmd.Username).Msg("Ignoring bot command from a chat or user not in admin.commands.allowed")
+		return ""
+	}
+	l.Info().Strs("args", cmd.Args).Msg("Handling bot command")
+
+	var reply string
+	var err error
+	switch cmd.Name {
+	case "help", "start":
+		return commandsHelp
+	case "list":
+		reply, err = c.list(ctx, cmd.Args)
+	case "add":
+		reply, err = c.add(ctx, cmd)
+	case "remove", "delete":
+		reply, err = c.withFeed(ctx, cmd.Args, func(feed *database.Feed) (string, error) {
+			if err := c.feeds.DeleteFeed(ctx, feed.ID); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Removed feed %d (%s).", feed.ID, telegram.EscapeHTML(feed.URL)), nil
+		})
+	case "pause":
+		reply, err = c.withFeed(ctx, cmd.Args, func(feed *database.Feed) (string, error) {
+			if err := c.feeds.SetFeedEnabled(ctx, feed.ID, false); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Paused feed %d (%s).", feed.ID, telegram.EscapeHTML(feed.URL)), nil
+		})
+	case "resume":
+		reply, err = c.withFeed(ctx, cmd.Args, func(feed *database.Feed) (string, error) {
+			if err := c.feeds.SetFeedEnabled(ctx, feed.ID, true); err != nil {
+				return "", err
+			}
+			feed.IsEnabled = true
+			if c.onFeedEnabled != nil {
+				c.onFeedEnabled(feed)
+			}
+			return fmt.Sprintf("Resumed feed %d (%s).", feed.ID, telegram.EscapeHTML(feed.URL)), nil
+		})
+	default:
+		return fmt.Sprintf("Unknown command /%s. Send /help for the list of commands.", telegram.EscapeHTML(cmd.Name))
+	}
+	if err != nil {
+		l.Error().Err(err).Msg("Bot command failed")
+		return "Failed: " + telegram.EscapeHTML(err.Error())
+	}
+	return reply
+}
+
+// list lists the feeds, only those tagged with args[0] if given.
+func (c *Commander) list(ctx context.Context, args []string) (string, error) {
+	feeds, err := c.feeds.ListFeeds(ctx)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, f := range feeds {
+		if len(args) > 0 && !slices.ContainsFunc(f.Tags, func(tag string) bool { return strings.EqualFold(tag, args[0]) }) {
+			continue
+		}
+		status := ""
+		if !f.IsEnabled {
+			status = " (paused)"
+		}
+		name := f.URL
+		if f.UserTitle != nil && *f.UserTitle != "" {
+			name = *f.UserTitle
+		}
+		fmt.Fprintf(&b, "%d. <a href=\"%s\">%s</a> → %s, every %s%s\n", f.ID, telegram.EscapeHTML(f.URL), telegram.EscapeHTML(name),
+			telegram.EscapeHTML(f.TelegramChatID), time.Duration(f.FrequencySeconds)*time.Second, status)
+	}
+	if b.Len() == 0 {
+		return "No feeds.", nil
+	}
+	return b.String(), nil
+}
+
+// add creates a feed for args[0], delivered by the admin bot to the chat in
+// args[1], or the one the command came from.
+func (c *Commander) add(ctx context.Context, cmd telegram.Command) (string, error) {
+	if len(cmd.Args) < 1 || len(cmd.Args) > 2 {
+		return "Usage: /add &lt;url&gt; [chat_id]", nil
+	}
+	u, err := url.Parse(cmd.Args[0])
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Sprintf("%s is not an http(s) URL.", telegram.EscapeHTML(cmd.Args[0])), nil
+	}
+	chatID := strconv.FormatInt(cmd.ChatID, 10)
+	if len(cmd.Args) == 2 {
+		chatID = cmd.Args[1]
+	}
+
+	existing, err := c.feeds.ListFeeds(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range existing {
+		if f.URL == u.String() {
+			return fmt.Sprintf("Feed %d already delivers %s to %s.", f.ID, telegram.EscapeHTML(f.URL), telegram.EscapeHTML(f.TelegramChatID)), nil
+		}
+	}
+
+	botID := c.alerter.config().BotID
+	feed := &database.Feed{
+		URL:              u.String(),
+		FrequencySeconds: c.defaultFrequency,
+		TelegramBotID:    &botID,
+		TelegramChatID:   chatID,
+		SourceType:       database.FeedSourceRSS,
+		IsEnabled:        true,
+	}
+	id, err := c.feeds.CreateFeed(ctx, feed)
+	if err != nil {
+		return "", err
+	}
+	created, err := c.feeds.GetFeedByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if created != nil && c.onFeedEnabled != nil {
+		c.onFeedEnabled(created)
+	}
+	return fmt.Sprintf("Added feed %d: %s → %s, every %s.", id, telegram.EscapeHTML(feed.URL), telegram.EscapeHTML(chatID),
+		time.Duration(feed.FrequencySeconds)*time.Second), nil
+}
+
+// withFeed runs fn with the feed whose ID is args[0].
+func (c *Commander) withFeed(ctx context.Context, args []string, fn func(*database.Feed) (string, error)) (string, error) {
+	if len(args) != 1 {
+		return "Give the feed ID, as shown by /list.", nil
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Sprintf("Invalid feed ID %s.", telegram.EscapeHTML(args[0])), nil
+	}
+	feed, err := c.feeds.GetFeedByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if feed == nil {
+		return fmt.Sprintf("No feed %d.", id), nil
+	}
+	return fn(feed)
+}
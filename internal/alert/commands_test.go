@@ -0,0 +1,64 @@
+package alert
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/telegram"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommanderHandle(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "commands.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+	feeds := database.NewFeedStore(db)
+
+	alerter := NewAlerter(config.AdminConfig{BotID: 3, ChatID: "-100", Commands: config.CommandsConfig{Enabled: true}}, nil, nil, nil)
+	c := NewCommander(alerter, feeds, nil, 600)
+	var enabled []int64
+	c.OnFeedEnabled(func(f *database.Feed) { enabled = append(enabled, f.ID) })
+	admin := func(name string, args ...string) string {
+		return c.handle(ctx, telegram.Command{ChatID: -100, UserID: 7, Name: name, Args: args})
+	}
+
+	assert.Empty(t, c.handle(ctx, telegram.Command{ChatID: 42, UserID: 7, Name: "list"}), "other chats are ignored")
+	assert.Equal(t, "No feeds.", admin("list"))
+	assert.Contains(t, admin("add", "ftp://example.com/feed"), "not an http(s) URL")
+
+	assert.Equal(t, "Added feed 1: https://example.com/feed.xml → -100, every 10m0s.", admin("add", "https://example.com/feed.xml"))
+	feed, err := feeds.GetFeedByID(ctx, 1)
+	require.NoError(t, err)
+	require.NotNil(t, feed.TelegramBotID)
+	assert.EqualValues(t, 3, *feed.TelegramBotID)
+	assert.Equal(t, 600, feed.FrequencySeconds)
+	assert.Contains(t, admin("add", "https://example.com/feed.xml"), "Feed 1 already delivers")
+	assert.Contains(t, admin("add", "https://example.com/feed.xml", "@channel"), "Feed 1 already delivers https://example.com/feed.xml to -100")
+	assert.Equal(t, "Added feed 2: https://example.com/other.xml → @channel, every 10m0s.", admin("add", "https://example.com/other.xml", "@channel"))
+	assert.Equal(t, []int64{1, 2}, enabled)
+
+	assert.Equal(t, "Paused feed 1 (https://example.com/feed.xml).", admin("pause", "1"))
+	list := admin("list")
+	assert.Contains(t, list, `1. <a href="https://example.com/feed.xml">https://example.com/feed.xml</a> → -100, every 10m0s (paused)`)
+	assert.Contains(t, list, "2. ")
+	assert.Contains(t, admin("resume", "1"), "Resumed feed 1")
+	assert.Equal(t, []int64{1, 2, 1}, enabled)
+	feed, err = feeds.GetFeedByID(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, feed.IsEnabled)
+
+	assert.Equal(t, "Removed feed 2 (https://example.com/other.xml).", admin("remove", "2"))
+	assert.Equal(t, "No feed 2.", admin("pause", "2"))
+	assert.Equal(t, "Invalid feed ID x.", admin("pause", "x"))
+	assert.Contains(t, admin("frobnicate"), "Unknown command /frobnicate")
+
+	// Allowed users may send commands from other chats
+	alerter.SetConfig(config.AdminConfig{BotID: 3, ChatID: "-100", Commands: config.CommandsConfig{Enabled: true, Allowed: []string{"7"}}})
+	assert.Equal(t, commandsHelp, c.handle(ctx, telegram.Command{ChatID: 42, UserID: 7, Name: "help"}))
+	assert.Empty(t, c.handle(ctx, telegram.Command{ChatID: -100, UserID: 8, Name: "list"}), "the admin chat is no longer allowed")
+}
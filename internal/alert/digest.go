@@ -0,0 +1,304 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/telegram"
+	"github.com/rs/zerolog/log"
+)
+
+// statsRetention is how long hourly feed statistics are kept; enough for
+// weekly digests and a few weeks of history.
+const statsRetention = 35 * 24 * time.Hour
+
+// defaultDigestTemplate renders a Digest as Telegram HTML.
+const defaultDigestTemplate = `<b>{{if eq .Period "weekly"}}Weekly{{else}}Daily{{end}} digest</b>, {{.From.Format "Jan 2 15:04"}} – {{.To.Format "Jan 2 15:04"}}
+Feeds: {{.EnabledFeeds}} enabled of {{.Feeds}}
+Runs: {{.Runs}}, {{.Failures}} failed
+Items delivered: {{.Items}}
+{{- if .TopFeeds}}
+
+<b>Most active</b>
+{{- range .TopFeeds}}
+• {{escapeHTML .Name}}: {{.Items}} items
+{{- end}}
+{{- end}}
+{{- if .FailingFeeds}}
+
+<b>Failing</b>
+{{- range .FailingFeeds}}
+• {{escapeHTML .Name}}: {{.Failures}} of {{.Runs}} runs failed{{with .LastError}}: <i>{{escapeHTML (summarize . 200)}}</i>{{end}}
+{{- end}}
+{{- end}}
+{{- if .DisabledFeeds}}
+
+<b>Disabled ({{.Disabled}})</b>
+{{- range .DisabledFeeds}}
+• {{escapeHTML .Name}}
+{{- end}}
+{{- end}}`
+
+// Digest is the data a digest template is executed with.
+type Digest struct {
+	Period        string // "daily" or "weekly"
+	From, To      time.Time
+	Feeds         int // All feeds
+	EnabledFeeds  int
+	Disabled      int // Disabled feeds, of which DisabledFeeds lists the first
+	Runs          int64
+	Failures      int64
+	Items         int64
+	TopFeeds      []DigestFeed // Most items delivered first
+	FailingFeeds  []DigestFeed // Most failed runs first
+	DisabledFeeds []DigestFeed
+}
+
+// DigestFeed is one feed's statistics in a Digest.
+type DigestFeed struct {
+	ID        int64
+	Name      string // The feed's title, or its URL when untitled
+	URL       string
+	Runs      int64
+	Failures  int64
+	Items     int64
+	LastError string
+}
+
+// digestSchedule is a parsed DigestConfig.
+type digestSchedule struct {
+	period   string
+	hour     int
+	minute   int
+	weekday  time.Weekday
+	topFeeds int
+	tmpl     *template.Template
+}
+
+// ValidateDigestConfig checks the schedule and template of cfg.
+func ValidateDigestConfig(cfg config.DigestConfig) error {
+	_, err := parseDigestConfig(cfg)
+	return err
+}
+
+// parseDigestConfig returns nil when digests are disabled.
+func parseDigestConfig(cfg config.DigestConfig) (*digestSchedule, error) {
+	s := &digestSchedule{period: strings.ToLower(cfg.Interval), topFeeds: cfg.TopFeeds}
+	switch s.period {
+	case "":
+		return nil, nil
+	case "daily", "weekly":
+	default:
+		return nil, fmt.Errorf("admin.digest.interval %q: expected daily or weekly", cfg.Interval)
+	}
+	at, err := time.Parse("15:04", cfg.At)
+	if err != nil {
+		return nil, fmt.Errorf("admin.digest.at %q: expected HH:MM", cfg.At)
+	}
+	s.hour, s.minute = at.Hour(), at.Minute()
+	if s.period == "weekly" {
+		weekday, ok := database.ParseWeekday(cfg.Weekday)
+		if !ok {
+			return nil, fmt.Errorf("admin.digest.weekday %q: expected a day such as monday", cfg.Weekday)
+		}
+		s.weekday = weekday
+	}
+	if s.topFeeds <= 0 {
+		s.topFeeds = 5
+	}
+	text := cfg.Template
+	if text == "" {
+		text = defaultDigestTemplate
+	}
+	s.tmpl, err = template.New("digest").Funcs(template.FuncMap{
+		"escapeHTML": telegram.EscapeHTML,
+		"summarize": func(s string, length int) string {
+			runes := []rune(s)
+			if len(runes) < length {
+				return s
+			}
+			return string(runes[:length]) + "..."
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("admin.digest.template: %w", err)
+	}
+	return s, nil
+}
+
+// last returns the most recent scheduled send time at or before now.
+func (s *digestSchedule) last(now time.Time) time.Time {
+	t := time.Date(now.Year(), now.Month(), now.Day(), s.hour, s.minute, 0, 0, now.Location())
+	days := 1
+	if s.period == "weekly" {
+		days = 7
+		t = t.AddDate(0, 0, -((int(t.Weekday()) - int(s.weekday) + 7) % 7))
+	}
+	if t.After(now) {
+		t = t.AddDate(0, 0, -days)
+	}
+	return t
+}
+
+// from returns the start of the digest period ending at to.
+func (s *digestSchedule) from(to time.Time) time.Time {
+	if s.period == "weekly" {
+		return to.AddDate(0, 0, -7)
+	}
+	return to.AddDate(0, 0, -1)
+}
+
+// buildDigest summarizes stats, as returned by FeedStatsSince, for the
+// period from from to to.
+func buildDigest(period string, from, to time.Time, stats []*database.FeedStats, topFeeds int) *Digest {
+	d := &Digest{Period: period, From: from, To: to, Feeds: len(stats)}
+	var active, failing []DigestFeed
+	for _, fs := range stats {
+		f := DigestFeed{ID: fs.FeedID, Name: fs.URL, URL: fs.URL, Runs: fs.Runs, Failures: fs.Failures, Items: fs.Items}
+		if fs.Title != nil && *fs.Title != "" {
+			f.Name = *fs.Title
+		}
+		if fs.LastError != nil {
+			f.LastError = *fs.LastError
+		}
+		d.Runs += f.Runs
+		d.Failures += f.Failures
+		d.Items += f.Items
+		if fs.Enabled {
+			d.EnabledFeeds++
+		} else {
+			d.Disabled++
+			if len(d.DisabledFeeds) < topFeeds {
+				d.DisabledFeeds = append(d.DisabledFeeds, f)
+			}
+		}
+		if f.Items > 0 {
+			active = append(active, f)
+		}
+		if f.Failures > 0 {
+			failing = append(failing, f)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool { return active[i].Items > active[j].Items })
+	sort.SliceStable(failing, func(i, j int) bool { return failing[i].Failures > failing[j].Failures })
+	d.TopFeeds = active[:min(len(active), topFeeds)]
+	d.FailingFeeds = failing[:min(len(failing), topFeeds)]
+	return d
+}
+
+// Digester posts the scheduled statistics digest to the admin chat. It
+// reads the digest settings from the Alerter, so config reloads apply.
+type Digester struct {
+	alerter *Alerter
+	stats   *database.StatsStore
+
+	mu     sync.Mutex
+	stopCh chan struct{}
+}
+
+// NewDigester creates a Digester that sends through alerter.
+func NewDigester(alerter *Alerter, stats *database.StatsStore) *Digester {
+	return &Digester{alerter: alerter, stats: stats}
+}
+
+// Start checks every minute whether a digest is due until Stop is called or
+// ctx is done. A digest that fell due while the bot was not running is not
+// sent late. Statistics older than statsRetention are pruned once a day.
+func (d *Digester) Start(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopCh != nil {
+		return
+	}
+	stopCh := make(chan struct{})
+	d.stopCh = stopCh
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		sent := time.Now()
+		var pruned time.Time
+		var badCfg *config.DigestConfig
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stopCh:
+				return
+			case now := <-ticker.C:
+				if now.Sub(pruned) >= 24*time.Hour {
+					pruned = now
+					if n, err := d.stats.PruneFeedStats(ctx, now.Add(-statsRetention)); err != nil {
+						log.Warn().Err(err).Msg("Failed to prune feed statistics")
+					} else if n > 0 {
+						log.Debug().Int64("rows", n).Msg("Pruned old feed statistics")
+					}
+				}
+
+				cfg := d.alerter.config()
+				schedule, err := parseDigestConfig(cfg.Digest)
+				if err != nil {
+					if badCfg == nil || *badCfg != cfg.Digest {
+						log.Error().Err(err).Msg("Invalid admin.digest settings, no digest is sent")
+						badCfg = &cfg.Digest
+					}
+					continue
+				}
+				badCfg = nil
+				if schedule == nil || cfg.ChatID == "" {
+					continue
+				}
+				due := schedule.last(now)
+				if !due.After(sent) {
+					continue
+				}
+				sent = now
+				if err := d.send(ctx, schedule, due); err != nil {
+					log.Error().Err(err).Msg("Failed to send admin digest")
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends the scheduled digests.
+func (d *Digester) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopCh != nil {
+		close(d.stopCh)
+		d.stopCh = nil
+	}
+}
+
+// render builds the digest for the period ending at to and executes the
+// configured template with it.
+func (d *Digester) render(ctx context.Context, schedule *digestSchedule, to time.Time) (string, error) {
+	from := schedule.from(to)
+	stats, err := d.stats.FeedStatsSince(ctx, from)
+	if err != nil {
+		return "", fmt.Errorf("loading feed statistics: %w", err)
+	}
+	digest := buildDigest(schedule.period, from, to, stats, schedule.topFeeds)
+	var buf bytes.Buffer
+	if err := schedule.tmpl.Execute(&buf, digest); err != nil {
+		return "", fmt.Errorf("executing admin.digest.template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func (d *Digester) send(ctx context.Context, schedule *digestSchedule, to time.Time) error {
+	text, err := d.render(ctx, schedule, to)
+	if err != nil {
+		return err
+	}
+	return d.alerter.Send(ctx, text)
+}
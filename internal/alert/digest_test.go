@@ -0,0 +1,82 @@
+package alert
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestScheduleLast(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, 5, 15, 10, 30, 0, 0, time.UTC)
+
+	daily, err := parseDigestConfig(config.DigestConfig{Interval: "daily", At: "09:00"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC), daily.last(now))
+	assert.Equal(t, time.Date(2024, 5, 14, 9, 0, 0, 0, time.UTC), daily.last(now.Add(-2*time.Hour)))
+
+	weekly, err := parseDigestConfig(config.DigestConfig{Interval: "weekly", At: "09:00", Weekday: "Mon"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 13, 9, 0, 0, 0, time.UTC), weekly.last(now))
+	assert.Equal(t, time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC), weekly.last(time.Date(2024, 5, 13, 8, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC), weekly.from(weekly.last(now)))
+}
+
+func TestParseDigestConfig(t *testing.T) {
+	disabled, err := parseDigestConfig(config.DigestConfig{At: "bogus"})
+	require.NoError(t, err)
+	assert.Nil(t, disabled)
+
+	for _, cfg := range []config.DigestConfig{
+		{Interval: "hourly", At: "09:00"},
+		{Interval: "daily", At: "9am"},
+		{Interval: "weekly", At: "09:00", Weekday: "someday"},
+		{Interval: "daily", At: "09:00", Template: "{{.Nope"},
+	} {
+		assert.Error(t, ValidateDigestConfig(cfg), "%+v", cfg)
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	title := "Busy <news>"
+	failure := "fetch: 503 & retry"
+	stats := []*database.FeedStats{
+		{FeedID: 1, URL: "https://a.example/feed", Title: &title, Enabled: true, Runs: 24, Items: 40},
+		{FeedID: 2, URL: "https://b.example/feed", Enabled: true, Runs: 24, Failures: 6, Items: 2, LastError: &failure},
+		{FeedID: 3, URL: "https://c.example/feed", Enabled: true, Runs: 24, Items: 9},
+		{FeedID: 4, URL: "https://d.example/feed"},
+	}
+	to := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
+	d := buildDigest("daily", to.AddDate(0, 0, -1), to, stats, 2)
+	assert.Equal(t, 4, d.Feeds)
+	assert.Equal(t, 3, d.EnabledFeeds)
+	assert.EqualValues(t, 72, d.Runs)
+	assert.EqualValues(t, 6, d.Failures)
+	assert.EqualValues(t, 51, d.Items)
+	require.Len(t, d.TopFeeds, 2)
+	assert.Equal(t, []int64{1, 3}, []int64{d.TopFeeds[0].ID, d.TopFeeds[1].ID})
+	require.Len(t, d.FailingFeeds, 1)
+	require.Len(t, d.DisabledFeeds, 1)
+
+	schedule, err := parseDigestConfig(config.DigestConfig{Interval: "daily", At: "09:00"})
+	require.NoError(t, err)
+	var text strings.Builder
+	require.NoError(t, schedule.tmpl.Execute(&text, d))
+	out := text.String()
+	assert.Contains(t, out, "<b>Daily digest</b>")
+	assert.Contains(t, out, "Items delivered: 51")
+	assert.Contains(t, out, "• Busy &lt;news&gt;: 40 items")
+	assert.Contains(t, out, "• https://b.example/feed: 6 of 24 runs failed: <i>fetch: 503 &amp; retry</i>")
+	assert.Contains(t, out, "<b>Disabled (1)</b>\n• https://d.example/feed")
+
+	custom, err := parseDigestConfig(config.DigestConfig{Interval: "daily", At: "09:00", Template: "{{.Items}} items from {{.EnabledFeeds}} feeds"})
+	require.NoError(t, err)
+	text.Reset()
+	require.NoError(t, custom.tmpl.Execute(&text, d))
+	assert.Equal(t, "51 items from 3 feeds", text.String())
+}
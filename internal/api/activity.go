@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/events"
+)
+
+// maxDeliveries is how many recent deliveries the UI can list.
+const maxDeliveries = 100
+
+// activity keeps what the bot did lately: the last deliveries and the result
+// of each feed's last run. It only covers the time since startup.
+type activity struct {
+	mu          sync.Mutex
+	deliveries  []events.Event         // Oldest first, at most maxDeliveries
+	lastResults map[int64]events.Event // By feed ID
+}
+
+// track records events from ch until it is closed.
+func (a *activity) track(ch <-chan events.Event) {
+	for e := range ch {
+		a.record(e)
+	}
+}
+
+func (a *activity) record(e events.Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch e.Type {
+	case events.TypeItemSent:
+		if len(a.deliveries) == maxDeliveries {
+			a.deliveries = append(a.deliveries[:0], a.deliveries[1:]...)
+		}
+		a.deliveries = append(a.deliveries, e)
+	case events.TypeFeedProcessed:
+		if a.lastResults == nil {
+			a.lastResults = make(map[int64]events.Event)
+		}
+		a.lastResults[e.FeedID] = e
+	}
+}
+
+// annotate adds the result of the feed's last run, if known, to f.
+func (a *activity) annotate(f *feedJSON) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if e, ok := a.lastResults[f.ID]; ok {
+		at := e.Time
+		f.LastResult, f.LastResultAt = e.Result, &at
+	}
+}
+
+// deliveryJSON is a delivered item as returned by the API.
+type deliveryJSON struct {
+	FeedID    int64     `json:"feed_id"`
+	FeedURL   string    `json:"feed_url"`
+	ItemTitle string    `json:"item_title"`
+	ItemLink  string    `json:"item_link"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// recentDeliveries returns the recorded deliveries, newest first.
+func (a *activity) recentDeliveries() []deliveryJSON {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]deliveryJSON, 0, len(a.deliveries))
+	for i := len(a.deliveries) - 1; i >= 0; i-- {
+		e := a.deliveries[i]
+		out = append(out, deliveryJSON{FeedID: e.FeedID, FeedURL: e.FeedURL, ItemTitle: e.ItemTitle, ItemLink: e.ItemLink, SentAt: e.Time})
+	}
+	return out
+}
+
+// listDeliveries returns the items delivered since startup, newest first.
+func (s *Server) listDeliveries(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.activity.recentDeliveries())
+}
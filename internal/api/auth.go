@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/rs/zerolog/log"
+)
+
+// roleRanks orders roles so that each includes the permissions of those
+// below it.
+var roleRanks = map[string]int{
+	database.RoleViewer: 1,
+	database.RoleEditor: 2,
+	database.RoleAdmin:  3,
+}
+
+// roleAllows reports whether role includes the permissions of required.
+func roleAllows(role, required string) bool {
+	return roleRanks[role] >= roleRanks[required]
+}
+
+type roleKey struct{}
+
+// roleFromContext returns the role of the authenticated caller.
+func roleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(roleKey{}).(string)
+	return role
+}
+
+// authenticate rejects requests without a valid bearer token or API key and
+// records the caller's role for requireRole.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		var role string
+		if ok {
+			var err error
+			if role, err = s.roleFor(r.Context(), token); err != nil {
+				writeFailure(w, r, err)
+				return
+			}
+		}
+		if role == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="rss-telegram-bot"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid API token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), roleKey{}, role)))
+	})
+}
+
+// requireRole rejects requests from callers whose role doesn't include
+// required.
+func requireRole(required string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !roleAllows(roleFromContext(r.Context()), required) {
+				writeError(w, http.StatusForbidden, required+" role required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// roleFor returns the role token grants, or "" if it grants none. Configured
+// tokens are admins.
+func (s *Server) roleFor(ctx context.Context, token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+	if s.validToken(token) {
+		return database.RoleAdmin, nil
+	}
+	key, err := s.keys.GetAPIKeyByKey(ctx, token)
+	if err != nil || key == nil {
+		return "", err
+	}
+	if err := s.keys.TouchAPIKey(ctx, key); err != nil {
+		log.Warn().Err(err).Int64("api_key_id", key.ID).Msg("Failed to record API key use")
+	}
+	return key.Role, nil
+}
+
+func (s *Server) validToken(token string) bool {
+	valid := false
+	for _, t := range s.tokens {
+		// Check every token so the time taken doesn't reveal which one nearly matched.
+		if subtle.ConstantTimeCompare([]byte(token), t) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// keyJSON is the API representation of an API key. Key is only set in the
+// response to creating it.
+type keyJSON struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	Key        string     `json:"key,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newKeyJSON(k *database.APIKey) keyJSON {
+	return keyJSON{ID: k.ID, Name: k.Name, Role: k.Role, LastUsedAt: k.LastUsedAt, CreatedAt: k.CreatedAt}
+}
+
+func (s *Server) listKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.keys.ListAPIKeys(r.Context())
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	out := make([]keyJSON, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, newKeyJSON(k))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createKey(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Name string `json:"name"`
+		Role string `json:"role"`
+	}
+	if err := decodeBody(r, &in); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	if in.Name == "" {
+		writeFailure(w, r, badRequest("name is required"))
+		return
+	}
+	if !database.ValidRole(in.Role) {
+		writeFailure(w, r, badRequest("role must be admin, editor or viewer"))
+		return
+	}
+	key, err := database.NewAPIKey()
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	id, err := s.keys.CreateAPIKey(r.Context(), in.Name, in.Role, key)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			err = badRequest("an API key named %q already exists", in.Name)
+		}
+		writeFailure(w, r, err)
+		return
+	}
+	created, err := s.keys.GetAPIKeyByID(r.Context(), id)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	out := newKeyJSON(created)
+	out.Key = key
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func (s *Server) deleteKey(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	k, err := s.keys.GetAPIKeyByID(r.Context(), id)
+	if err == nil && k == nil {
+		err = notFound("API key", id)
+	}
+	if err == nil {
+		err = s.keys.DeleteAPIKey(r.Context(), id)
+	}
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,455 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+)
+
+// testFeedTimeout bounds the fetch made by the feed test endpoint.
+const testFeedTimeout = 60 * time.Second
+
+// feedJSON is a feed as returned by the API.
+type feedJSON struct {
+	ID                  int64      `json:"id"`
+	URL                 string     `json:"url"`
+	Title               *string    `json:"title,omitempty"`
+	ChatID              string     `json:"chat_id"`
+	ThreadID            *int       `json:"thread_id,omitempty"`
+	BotID               *int64     `json:"bot_id,omitempty"`
+	BotPoolID           *int64     `json:"bot_pool_id,omitempty"`
+	ProxyID             *int64     `json:"proxy_id,omitempty"`
+	ProxyPoolID         *int64     `json:"proxy_pool_id,omitempty"`
+	FormattingProfileID *int64     `json:"formatting_profile_id,omitempty"`
+	FrequencySeconds    int        `json:"frequency_seconds"`
+	Enabled             bool       `json:"enabled"`
+	SourceType          string     `json:"source_type"`
+	UserAgent           *string    `json:"user_agent,omitempty"`
+	LastFetchedAt       *time.Time `json:"last_fetched_at,omitempty"`
+	NewestItemAt        *time.Time `json:"newest_item_at,omitempty"`
+	LastResult          string     `json:"last_result,omitempty"` // Outcome of the last run since startup, e.g. "success" or "fetch_error"
+	LastResultAt        *time.Time `json:"last_result_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+func newFeedJSON(f *database.Feed) feedJSON {
+	sourceType := f.SourceType
+	if sourceType == "" {
+		sourceType = database.FeedSourceRSS
+	}
+	return feedJSON{
+		ID:                  f.ID,
+		URL:                 f.URL,
+		Title:               f.UserTitle,
+		ChatID:              f.TelegramChatID,
+		ThreadID:            f.TelegramThreadID,
+		BotID:               f.TelegramBotID,
+		BotPoolID:           f.BotPoolID,
+		ProxyID:             f.ProxyID,
+		ProxyPoolID:         f.ProxyPoolID,
+		FormattingProfileID: f.FormattingProfileID,
+		FrequencySeconds:    f.FrequencySeconds,
+		Enabled:             f.IsEnabled,
+		SourceType:          sourceType,
+		UserAgent:           f.UserAgent,
+		LastFetchedAt:       f.LastFetchedAt,
+		NewestItemAt:        f.NewestItemAt,
+		CreatedAt:           f.CreatedAt,
+	}
+}
+
+// feedInput holds the settings accepted when creating or updating a feed.
+// Omitted fields are left unchanged; 0 or "" clears an optional one.
+type feedInput struct {
+	URL                 *string `json:"url"`
+	Title               *string `json:"title"`
+	ChatID              *string `json:"chat_id"`
+	ThreadID            *int    `json:"thread_id"`
+	BotID               *int64  `json:"bot_id"`
+	BotPoolID           *int64  `json:"bot_pool_id"`
+	ProxyID             *int64  `json:"proxy_id"`
+	ProxyPoolID         *int64  `json:"proxy_pool_id"`
+	FormattingProfileID *int64  `json:"formatting_profile_id"`
+	FrequencySeconds    *int    `json:"frequency_seconds"`
+	Enabled             *bool   `json:"enabled"`
+	SourceType          *string `json:"source_type"`
+	UserAgent           *string `json:"user_agent"`
+}
+
+// applyFeedInput copies the set fields of in onto f after checking that referenced
+// bots, proxies and profiles exist.
+func (s *Server) applyFeedInput(ctx context.Context, in feedInput, f *database.Feed) error {
+	if in.URL != nil {
+		if *in.URL == "" {
+			return badRequest("url must not be empty")
+		}
+		f.URL = *in.URL
+	}
+	if in.ChatID != nil {
+		if *in.ChatID == "" {
+			return badRequest("chat_id must not be empty")
+		}
+		f.TelegramChatID = *in.ChatID
+	}
+	if in.FrequencySeconds != nil {
+		if *in.FrequencySeconds <= 0 {
+			return badRequest("frequency_seconds must be positive")
+		}
+		f.FrequencySeconds = *in.FrequencySeconds
+	}
+	if in.SourceType != nil {
+		switch *in.SourceType {
+		case database.FeedSourceRSS, database.FeedSourceSitemap:
+			f.SourceType = *in.SourceType
+		default:
+			return badRequest("source_type must be %q or %q; add other feed types with 'feed add'", database.FeedSourceRSS, database.FeedSourceSitemap)
+		}
+	}
+	if in.Enabled != nil {
+		f.IsEnabled = *in.Enabled
+	}
+	if in.Title != nil {
+		f.UserTitle = optionalString(*in.Title)
+	}
+	if in.UserAgent != nil {
+		f.UserAgent = optionalString(*in.UserAgent)
+	}
+	if in.ThreadID != nil {
+		f.TelegramThreadID = nil
+		if *in.ThreadID != 0 {
+			f.TelegramThreadID = in.ThreadID
+		}
+	}
+
+	if in.BotID != nil {
+		if *in.BotID != 0 {
+			bot, err := s.bots.GetBotByID(ctx, *in.BotID)
+			if err != nil {
+				return err
+			}
+			if bot == nil {
+				return badRequest("bot %d not found", *in.BotID)
+			}
+		}
+		f.TelegramBotID = optionalID(*in.BotID)
+	}
+	if in.BotPoolID != nil {
+		if *in.BotPoolID != 0 {
+			pool, err := s.bots.GetPoolByID(ctx, *in.BotPoolID)
+			if err != nil {
+				return err
+			}
+			if pool == nil {
+				return badRequest("bot pool %d not found", *in.BotPoolID)
+			}
+		}
+		f.BotPoolID = optionalID(*in.BotPoolID)
+	}
+	if in.ProxyID != nil {
+		if *in.ProxyID != 0 {
+			p, err := s.proxies.GetProxyByID(ctx, *in.ProxyID)
+			if err != nil {
+				return err
+			}
+			if p == nil {
+				return badRequest("proxy %d not found", *in.ProxyID)
+			}
+		}
+		f.ProxyID = optionalID(*in.ProxyID)
+	}
+	if in.ProxyPoolID != nil {
+		if *in.ProxyPoolID != 0 {
+			pool, err := s.proxies.GetPoolByID(ctx, *in.ProxyPoolID)
+			if err != nil {
+				return err
+			}
+			if pool == nil {
+				return badRequest("proxy pool %d not found", *in.ProxyPoolID)
+			}
+		}
+		f.ProxyPoolID = optionalID(*in.ProxyPoolID)
+	}
+	if in.FormattingProfileID != nil {
+		if *in.FormattingProfileID != 0 {
+			p, err := s.profiles.GetProfileByID(ctx, *in.FormattingProfileID)
+			if err != nil {
+				return err
+			}
+			if p == nil {
+				return badRequest("formatting profile %d not found", *in.FormattingProfileID)
+			}
+		}
+		f.FormattingProfileID = optionalID(*in.FormattingProfileID)
+	}
+	return nil
+}
+
+func (s *Server) listFeeds(w http.ResponseWriter, r *http.Request) {
+	feeds, err := s.feeds.ListFeeds(r.Context())
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	out := make([]feedJSON, 0, len(feeds))
+	for _, f := range feeds {
+		j := newFeedJSON(f)
+		s.activity.annotate(&j)
+		out = append(out, j)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// feedByID loads a feed, failing with errNotFound when it doesn't exist.
+func (s *Server) feedByID(ctx context.Context, id int64) (*database.Feed, error) {
+	feed, err := s.feeds.GetFeedByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if feed == nil {
+		return nil, notFound("feed", id)
+	}
+	return feed, nil
+}
+
+func (s *Server) getFeed(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	feed, err := s.feedByID(r.Context(), id)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	j := newFeedJSON(feed)
+	s.activity.annotate(&j)
+	writeJSON(w, http.StatusOK, j)
+}
+
+// addFeed creates a feed from in, which must name a URL and chat, and
+// schedules it when enabled.
+func (s *Server) addFeed(ctx context.Context, in feedInput) (*database.Feed, error) {
+	if in.URL == nil || in.ChatID == nil {
+		return nil, badRequest("url and chat_id are required")
+	}
+	feed := &database.Feed{FrequencySeconds: s.defaultFrequency, IsEnabled: true, SourceType: database.FeedSourceRSS}
+	if err := s.applyFeedInput(ctx, in, feed); err != nil {
+		return nil, err
+	}
+	id, err := s.feeds.CreateFeed(ctx, feed)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.feedByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if created.IsEnabled && s.onFeedCreated != nil {
+		s.onFeedCreated(created)
+	}
+	return created, nil
+}
+
+func (s *Server) createFeed(w http.ResponseWriter, r *http.Request) {
+	var in feedInput
+	if err := decodeBody(r, &in); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	feed, err := s.addFeed(r.Context(), in)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newFeedJSON(feed))
+}
+
+// editFeed applies in to feed id and returns the stored result.
+func (s *Server) editFeed(ctx context.Context, id int64, in feedInput) (*database.Feed, error) {
+	feed, err := s.feedByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyFeedInput(ctx, in, feed); err != nil {
+		return nil, err
+	}
+	if err := s.feeds.UpdateFeed(ctx, feed); err != nil {
+		return nil, err
+	}
+	return s.feedByID(ctx, id)
+}
+
+func (s *Server) updateFeed(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	var in feedInput
+	if err := decodeBody(r, &in); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	feed, err := s.editFeed(r.Context(), id, in)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newFeedJSON(feed))
+}
+
+func (s *Server) removeFeed(ctx context.Context, id int64) error {
+	if _, err := s.feedByID(ctx, id); err != nil {
+		return err
+	}
+	return s.feeds.DeleteFeed(ctx, id)
+}
+
+func (s *Server) deleteFeed(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	if err := s.removeFeed(r.Context(), id); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startFetch processes an enabled feed in the background right away instead
+// of waiting for its next poll.
+func (s *Server) startFetch(ctx context.Context, id int64) error {
+	if s.onFetchNow == nil {
+		return fmt.Errorf("%w: fetching is not available", errUnavailable)
+	}
+	feed, err := s.feedByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !feed.IsEnabled {
+		return badRequest("feed %d is disabled", feed.ID)
+	}
+	go s.onFetchNow(feed)
+	return nil
+}
+
+func (s *Server) fetchFeedNow(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	if err := s.startFetch(r.Context(), id); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
+
+// testFeedRequest names a feed URL to try, optionally through a proxy.
+type testFeedRequest struct {
+	URL     string `json:"url"`
+	ProxyID int64  `json:"proxy_id"`
+}
+
+// testFeedResult reports whether a feed could be fetched and what it holds.
+type testFeedResult struct {
+	OK        bool           `json:"ok"`
+	Error     string         `json:"error,omitempty"`
+	Title     string         `json:"title,omitempty"`
+	ItemCount int            `json:"item_count"`
+	Items     []testItemJSON `json:"items,omitempty"`
+	HubURL    string         `json:"hub_url,omitempty"`
+}
+
+// testItemJSON summarizes an item found by the feed test endpoint.
+type testItemJSON struct {
+	Title     string     `json:"title"`
+	Link      string     `json:"link"`
+	Published *time.Time `json:"published,omitempty"`
+}
+
+// runFeedTest fetches and parses a feed URL without storing or sending
+// anything, to check a feed before adding it. Fetch failures are reported in
+// the result rather than as an error.
+func (s *Server) runFeedTest(ctx context.Context, in testFeedRequest) (*testFeedResult, error) {
+	if in.URL == "" {
+		return nil, badRequest("url is required")
+	}
+	var p *database.Proxy
+	if in.ProxyID != 0 {
+		var err error
+		if p, err = s.proxyByID(ctx, in.ProxyID); err != nil {
+			if errors.Is(err, errNotFound) {
+				return nil, badRequest("proxy %d not found", in.ProxyID)
+			}
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, testFeedTimeout)
+	defer cancel()
+	result, err := s.fetcher.Fetch(ctx, in.URL, nil, nil, p, interfaces.FetchOptions{})
+	if err != nil {
+		return &testFeedResult{Error: err.Error()}, nil
+	}
+	if result == nil || result.Feed == nil {
+		return &testFeedResult{Error: "no feed returned"}, nil
+	}
+	return &testFeedResult{
+		OK:        true,
+		Title:     result.Feed.Title,
+		ItemCount: len(result.Feed.Items),
+		Items:     testItems(result.Feed.Items, 10),
+		HubURL:    result.HubURL,
+	}, nil
+}
+
+func (s *Server) testFeed(w http.ResponseWriter, r *http.Request) {
+	var in testFeedRequest
+	if err := decodeBody(r, &in); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	result, err := s.runFeedTest(r.Context(), in)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// testItems summarizes up to limit items.
+func testItems(items []*gofeed.Item, limit int) []testItemJSON {
+	out := make([]testItemJSON, 0, min(len(items), limit))
+	for _, item := range items {
+		if len(out) == limit {
+			break
+		}
+		out = append(out, testItemJSON{Title: item.Title, Link: item.Link, Published: item.PublishedParsed})
+	}
+	return out
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func optionalID(id int64) *int64 {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,530 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/events"
+	"github.com/haytac/rss-telegram-bot/pkg/managementpb"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watchBuffer is how many events a slow WatchEvents client may fall behind
+// before it misses some.
+const watchBuffer = 64
+
+// GRPCServer returns a gRPC server offering the management service, guarded
+// by the same tokens as the REST API.
+func (s *Server) GRPCServer() *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.authenticateUnary),
+		grpc.ChainStreamInterceptor(s.authenticateStream),
+	)
+	managementpb.RegisterManagementServer(srv, &grpcService{s: s})
+	return srv
+}
+
+// StartGRPCServer serves the gRPC API on addr in the background.
+func (s *Server) StartGRPCServer(addr string) {
+	if len(s.tokens) == 0 {
+		log.Warn().Str("address", addr).Msg("api.tokens is empty; only API keys created with 'apikey add' can use the gRPC API")
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Error().Err(err).Str("address", addr).Msg("gRPC API server failed to listen")
+		return
+	}
+	log.Info().Str("address", addr).Msg("Starting gRPC API server")
+	go func() {
+		if err := s.GRPCServer().Serve(lis); err != nil {
+			log.Error().Err(err).Msg("gRPC API server failed")
+		}
+	}()
+}
+
+func (s *Server) authenticateUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.authorizeCall(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authenticateStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authorizeCall(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// methodRoles is the least role allowed to call each method, mirroring the
+// REST routes. Methods missing here need admin.
+var methodRoles = map[string]string{
+	managementpb.Management_ListFeeds_FullMethodName:     database.RoleViewer,
+	managementpb.Management_GetFeed_FullMethodName:       database.RoleViewer,
+	managementpb.Management_ListBots_FullMethodName:      database.RoleViewer,
+	managementpb.Management_ListProxies_FullMethodName:   database.RoleViewer,
+	managementpb.Management_GetProxy_FullMethodName:      database.RoleViewer,
+	managementpb.Management_ListProfiles_FullMethodName:  database.RoleViewer,
+	managementpb.Management_GetProfile_FullMethodName:    database.RoleViewer,
+	managementpb.Management_GetStats_FullMethodName:      database.RoleViewer,
+	managementpb.Management_WatchEvents_FullMethodName:   database.RoleViewer,
+	managementpb.Management_CreateFeed_FullMethodName:    database.RoleEditor,
+	managementpb.Management_UpdateFeed_FullMethodName:    database.RoleEditor,
+	managementpb.Management_DeleteFeed_FullMethodName:    database.RoleEditor,
+	managementpb.Management_TestFeed_FullMethodName:      database.RoleEditor,
+	managementpb.Management_FetchFeedNow_FullMethodName:  database.RoleEditor,
+	managementpb.Management_CreateProfile_FullMethodName: database.RoleEditor,
+	managementpb.Management_UpdateProfile_FullMethodName: database.RoleEditor,
+	managementpb.Management_DeleteProfile_FullMethodName: database.RoleEditor,
+}
+
+// authorizeCall rejects calls without a valid "authorization: Bearer" token
+// or API key, or whose role may not call method.
+func (s *Server) authorizeCall(ctx context.Context, method string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(value, "Bearer ")
+		if !ok {
+			continue
+		}
+		role, err := s.roleFor(ctx, token)
+		if err != nil {
+			log.Error().Err(err).Str("method", method).Msg("API key lookup failed")
+			return status.Error(codes.Internal, "internal error")
+		}
+		if role == "" {
+			continue
+		}
+		required, ok := methodRoles[method]
+		if !ok {
+			required = database.RoleAdmin
+		}
+		if !roleAllows(role, required) {
+			return status.Errorf(codes.PermissionDenied, "%s role required", required)
+		}
+		return nil
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid API token")
+}
+
+// grpcError maps err to a gRPC status the way writeFailure maps it to an HTTP
+// status.
+func grpcError(ctx context.Context, method string, err error) error {
+	for _, e := range []struct {
+		kind error
+		code codes.Code
+	}{{errBadRequest, codes.InvalidArgument}, {errNotFound, codes.NotFound}, {errUnavailable, codes.Unavailable}} {
+		if errors.Is(err, e.kind) {
+			return status.Error(e.code, clientMessage(err, e.kind))
+		}
+	}
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	log.Error().Err(err).Str("method", method).Msg("gRPC API request failed")
+	return status.Error(codes.Internal, "internal error")
+}
+
+// grpcService implements managementpb.ManagementServer on top of the
+// operations shared with the REST handlers.
+type grpcService struct {
+	managementpb.UnimplementedManagementServer
+	s *Server
+}
+
+func (g *grpcService) ListFeeds(ctx context.Context, _ *managementpb.ListFeedsRequest) (*managementpb.ListFeedsResponse, error) {
+	feeds, err := g.s.feeds.ListFeeds(ctx)
+	if err != nil {
+		return nil, grpcError(ctx, "ListFeeds", err)
+	}
+	out := &managementpb.ListFeedsResponse{Feeds: make([]*managementpb.Feed, 0, len(feeds))}
+	for _, f := range feeds {
+		out.Feeds = append(out.Feeds, feedPB(f))
+	}
+	return out, nil
+}
+
+func (g *grpcService) GetFeed(ctx context.Context, req *managementpb.IDRequest) (*managementpb.Feed, error) {
+	feed, err := g.s.feedByID(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(ctx, "GetFeed", err)
+	}
+	return feedPB(feed), nil
+}
+
+func (g *grpcService) CreateFeed(ctx context.Context, req *managementpb.FeedInput) (*managementpb.Feed, error) {
+	feed, err := g.s.addFeed(ctx, feedInputFromPB(req))
+	if err != nil {
+		return nil, grpcError(ctx, "CreateFeed", err)
+	}
+	return feedPB(feed), nil
+}
+
+func (g *grpcService) UpdateFeed(ctx context.Context, req *managementpb.UpdateFeedRequest) (*managementpb.Feed, error) {
+	feed, err := g.s.editFeed(ctx, req.GetId(), feedInputFromPB(req.GetFeed()))
+	if err != nil {
+		return nil, grpcError(ctx, "UpdateFeed", err)
+	}
+	return feedPB(feed), nil
+}
+
+func (g *grpcService) DeleteFeed(ctx context.Context, req *managementpb.IDRequest) (*managementpb.Empty, error) {
+	if err := g.s.removeFeed(ctx, req.GetId()); err != nil {
+		return nil, grpcError(ctx, "DeleteFeed", err)
+	}
+	return &managementpb.Empty{}, nil
+}
+
+func (g *grpcService) TestFeed(ctx context.Context, req *managementpb.TestFeedRequest) (*managementpb.TestFeedResponse, error) {
+	result, err := g.s.runFeedTest(ctx, testFeedRequest{URL: req.GetUrl(), ProxyID: req.GetProxyId()})
+	if err != nil {
+		return nil, grpcError(ctx, "TestFeed", err)
+	}
+	out := &managementpb.TestFeedResponse{
+		Ok:        result.OK,
+		Error:     result.Error,
+		Title:     result.Title,
+		ItemCount: int32(result.ItemCount),
+		HubUrl:    result.HubURL,
+	}
+	for _, item := range result.Items {
+		out.Items = append(out.Items, &managementpb.TestItem{Title: item.Title, Link: item.Link, Published: timestampPB(item.Published)})
+	}
+	return out, nil
+}
+
+func (g *grpcService) FetchFeedNow(ctx context.Context, req *managementpb.IDRequest) (*managementpb.Empty, error) {
+	if err := g.s.startFetch(ctx, req.GetId()); err != nil {
+		return nil, grpcError(ctx, "FetchFeedNow", err)
+	}
+	return &managementpb.Empty{}, nil
+}
+
+func (g *grpcService) ListBots(ctx context.Context, _ *managementpb.ListBotsRequest) (*managementpb.ListBotsResponse, error) {
+	bots, err := g.s.bots.ListBots(ctx)
+	if err != nil {
+		return nil, grpcError(ctx, "ListBots", err)
+	}
+	out := &managementpb.ListBotsResponse{Bots: make([]*managementpb.Bot, 0, len(bots))}
+	for _, b := range bots {
+		out.Bots = append(out.Bots, botPB(b))
+	}
+	return out, nil
+}
+
+func (g *grpcService) CreateBot(ctx context.Context, req *managementpb.BotInput) (*managementpb.Bot, error) {
+	bot, err := g.s.addBot(ctx, botInput{Token: req.GetToken(), Description: req.Description})
+	if err != nil {
+		return nil, grpcError(ctx, "CreateBot", err)
+	}
+	return botPB(bot), nil
+}
+
+func (g *grpcService) UpdateBot(ctx context.Context, req *managementpb.UpdateBotRequest) (*managementpb.Bot, error) {
+	var in botInput
+	if b := req.GetBot(); b != nil {
+		in = botInput{Token: b.GetToken(), Description: b.Description}
+	}
+	bot, err := g.s.editBot(ctx, req.GetId(), in)
+	if err != nil {
+		return nil, grpcError(ctx, "UpdateBot", err)
+	}
+	return botPB(bot), nil
+}
+
+func (g *grpcService) DeleteBot(ctx context.Context, req *managementpb.IDRequest) (*managementpb.Empty, error) {
+	if err := g.s.removeBot(ctx, req.GetId()); err != nil {
+		return nil, grpcError(ctx, "DeleteBot", err)
+	}
+	return &managementpb.Empty{}, nil
+}
+
+func (g *grpcService) ListProxies(ctx context.Context, _ *managementpb.ListProxiesRequest) (*managementpb.ListProxiesResponse, error) {
+	proxies, err := g.s.proxies.ListProxies(ctx)
+	if err != nil {
+		return nil, grpcError(ctx, "ListProxies", err)
+	}
+	out := &managementpb.ListProxiesResponse{Proxies: make([]*managementpb.Proxy, 0, len(proxies))}
+	for _, p := range proxies {
+		out.Proxies = append(out.Proxies, proxyPB(p))
+	}
+	return out, nil
+}
+
+func (g *grpcService) GetProxy(ctx context.Context, req *managementpb.IDRequest) (*managementpb.Proxy, error) {
+	p, err := g.s.proxyByID(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(ctx, "GetProxy", err)
+	}
+	return proxyPB(p), nil
+}
+
+func (g *grpcService) CreateProxy(ctx context.Context, req *managementpb.ProxyInput) (*managementpb.Proxy, error) {
+	p, err := g.s.addProxy(ctx, proxyInputFromPB(req))
+	if err != nil {
+		return nil, grpcError(ctx, "CreateProxy", err)
+	}
+	return proxyPB(p), nil
+}
+
+func (g *grpcService) UpdateProxy(ctx context.Context, req *managementpb.UpdateProxyRequest) (*managementpb.Proxy, error) {
+	p, err := g.s.editProxy(ctx, req.GetId(), proxyInputFromPB(req.GetProxy()))
+	if err != nil {
+		return nil, grpcError(ctx, "UpdateProxy", err)
+	}
+	return proxyPB(p), nil
+}
+
+func (g *grpcService) DeleteProxy(ctx context.Context, req *managementpb.IDRequest) (*managementpb.Empty, error) {
+	if err := g.s.removeProxy(ctx, req.GetId()); err != nil {
+		return nil, grpcError(ctx, "DeleteProxy", err)
+	}
+	return &managementpb.Empty{}, nil
+}
+
+func (g *grpcService) ListProfiles(ctx context.Context, _ *managementpb.ListProfilesRequest) (*managementpb.ListProfilesResponse, error) {
+	profiles, err := g.s.profiles.ListProfiles(ctx)
+	if err != nil {
+		return nil, grpcError(ctx, "ListProfiles", err)
+	}
+	out := &managementpb.ListProfilesResponse{Profiles: make([]*managementpb.Profile, 0, len(profiles))}
+	for _, p := range profiles {
+		pb, err := profilePB(p)
+		if err != nil {
+			return nil, grpcError(ctx, "ListProfiles", err)
+		}
+		out.Profiles = append(out.Profiles, pb)
+	}
+	return out, nil
+}
+
+func (g *grpcService) GetProfile(ctx context.Context, req *managementpb.IDRequest) (*managementpb.Profile, error) {
+	p, err := g.s.profileByID(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(ctx, "GetProfile", err)
+	}
+	return g.profileResponse(ctx, "GetProfile", p)
+}
+
+func (g *grpcService) CreateProfile(ctx context.Context, req *managementpb.ProfileInput) (*managementpb.Profile, error) {
+	in, err := profileInputFromPB(req)
+	if err != nil {
+		return nil, grpcError(ctx, "CreateProfile", err)
+	}
+	p, err := g.s.addProfile(ctx, in)
+	if err != nil {
+		return nil, grpcError(ctx, "CreateProfile", err)
+	}
+	return g.profileResponse(ctx, "CreateProfile", p)
+}
+
+func (g *grpcService) UpdateProfile(ctx context.Context, req *managementpb.UpdateProfileRequest) (*managementpb.Profile, error) {
+	in, err := profileInputFromPB(req.GetProfile())
+	if err != nil {
+		return nil, grpcError(ctx, "UpdateProfile", err)
+	}
+	p, err := g.s.editProfile(ctx, req.GetId(), in)
+	if err != nil {
+		return nil, grpcError(ctx, "UpdateProfile", err)
+	}
+	return g.profileResponse(ctx, "UpdateProfile", p)
+}
+
+func (g *grpcService) profileResponse(ctx context.Context, method string, p *database.FormattingProfile) (*managementpb.Profile, error) {
+	pb, err := profilePB(p)
+	if err != nil {
+		return nil, grpcError(ctx, method, err)
+	}
+	return pb, nil
+}
+
+func (g *grpcService) DeleteProfile(ctx context.Context, req *managementpb.IDRequest) (*managementpb.Empty, error) {
+	if err := g.s.removeProfile(ctx, req.GetId()); err != nil {
+		return nil, grpcError(ctx, "DeleteProfile", err)
+	}
+	return &managementpb.Empty{}, nil
+}
+
+func (g *grpcService) GetStats(ctx context.Context, _ *managementpb.GetStatsRequest) (*managementpb.Stats, error) {
+	stats, err := g.s.collectStats(ctx)
+	if err != nil {
+		return nil, grpcError(ctx, "GetStats", err)
+	}
+	return &managementpb.Stats{
+		Feeds:              int32(stats.Feeds),
+		EnabledFeeds:       int32(stats.EnabledFeeds),
+		Bots:               int32(stats.Bots),
+		Proxies:            int32(stats.Proxies),
+		ProxiesDown:        int32(stats.ProxiesDown),
+		Profiles:           int32(stats.Profiles),
+		ItemsProcessed:     stats.ItemsProcessed,
+		ItemsProcessed_24H: stats.ItemsProcessed24h,
+	}, nil
+}
+
+// WatchEvents streams processing events until the client goes away.
+func (g *grpcService) WatchEvents(req *managementpb.WatchEventsRequest, stream managementpb.Management_WatchEventsServer) error {
+	if g.s.events == nil {
+		return status.Error(codes.Unavailable, "events are not available")
+	}
+	ch, cancel := g.s.events.Subscribe(watchBuffer)
+	defer cancel()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case e := <-ch:
+			if req.FeedId != nil && e.FeedID != *req.FeedId {
+				continue
+			}
+			if err := stream.Send(eventPB(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func feedPB(f *database.Feed) *managementpb.Feed {
+	j := newFeedJSON(f)
+	out := &managementpb.Feed{
+		Id:                  j.ID,
+		Url:                 j.URL,
+		Title:               j.Title,
+		ChatId:              j.ChatID,
+		BotId:               j.BotID,
+		BotPoolId:           j.BotPoolID,
+		ProxyId:             j.ProxyID,
+		ProxyPoolId:         j.ProxyPoolID,
+		FormattingProfileId: j.FormattingProfileID,
+		FrequencySeconds:    int32(j.FrequencySeconds),
+		Enabled:             j.Enabled,
+		SourceType:          j.SourceType,
+		UserAgent:           j.UserAgent,
+		LastFetchedAt:       timestampPB(j.LastFetchedAt),
+		NewestItemAt:        timestampPB(j.NewestItemAt),
+		CreatedAt:           timestamppb.New(j.CreatedAt),
+	}
+	if j.ThreadID != nil {
+		threadID := int32(*j.ThreadID)
+		out.ThreadId = &threadID
+	}
+	return out
+}
+
+func feedInputFromPB(in *managementpb.FeedInput) feedInput {
+	if in == nil {
+		return feedInput{}
+	}
+	out := feedInput{
+		URL:                 in.Url,
+		Title:               in.Title,
+		ChatID:              in.ChatId,
+		BotID:               in.BotId,
+		BotPoolID:           in.BotPoolId,
+		ProxyID:             in.ProxyId,
+		ProxyPoolID:         in.ProxyPoolId,
+		FormattingProfileID: in.FormattingProfileId,
+		Enabled:             in.Enabled,
+		SourceType:          in.SourceType,
+		UserAgent:           in.UserAgent,
+	}
+	if in.ThreadId != nil {
+		threadID := int(*in.ThreadId)
+		out.ThreadID = &threadID
+	}
+	if in.FrequencySeconds != nil {
+		frequency := int(*in.FrequencySeconds)
+		out.FrequencySeconds = &frequency
+	}
+	return out
+}
+
+func botPB(b *database.TelegramBot) *managementpb.Bot {
+	return &managementpb.Bot{Id: b.ID, Description: b.Description, CreatedAt: timestamppb.New(b.CreatedAt)}
+}
+
+func proxyPB(p *database.Proxy) *managementpb.Proxy {
+	j := newProxyJSON(p)
+	return &managementpb.Proxy{
+		Id:                 j.ID,
+		Name:               j.Name,
+		Type:               j.Type,
+		Address:            j.Address,
+		Username:           j.Username,
+		Password:           j.Password,
+		DefaultForRss:      j.DefaultForRSS,
+		DefaultForTelegram: j.DefaultForTelegram,
+		DirectFallback:     j.DirectFallback,
+		HealthCheckedAt:    timestampPB(j.HealthCheckedAt),
+		HealthError:        j.HealthError,
+		CreatedAt:          timestamppb.New(j.CreatedAt),
+	}
+}
+
+func proxyInputFromPB(in *managementpb.ProxyInput) proxyInput {
+	if in == nil {
+		return proxyInput{}
+	}
+	return proxyInput{
+		Name:               in.Name,
+		Type:               in.Type,
+		Address:            in.Address,
+		Username:           in.Username,
+		Password:           in.Password,
+		DefaultForRSS:      in.DefaultForRss,
+		DefaultForTelegram: in.DefaultForTelegram,
+		DirectFallback:     in.DirectFallback,
+	}
+}
+
+func profilePB(p *database.FormattingProfile) (*managementpb.Profile, error) {
+	config, err := json.Marshal(p.ParsedConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &managementpb.Profile{Id: p.ID, Name: p.Name, ConfigJson: string(config), CreatedAt: timestamppb.New(p.CreatedAt)}, nil
+}
+
+func profileInputFromPB(in *managementpb.ProfileInput) (profileInput, error) {
+	if in == nil {
+		return profileInput{}, nil
+	}
+	out := profileInput{Name: in.Name}
+	if in.ConfigJson != nil {
+		var config database.FormattingProfileConfig
+		if err := json.Unmarshal([]byte(*in.ConfigJson), &config); err != nil {
+			return out, badRequest("invalid config_json: %v", err)
+		}
+		out.Config = &config
+	}
+	return out, nil
+}
+
+func eventPB(e events.Event) *managementpb.Event {
+	return &managementpb.Event{
+		Type:      e.Type,
+		FeedId:    e.FeedID,
+		FeedUrl:   e.FeedURL,
+		Result:    e.Result,
+		ItemTitle: e.ItemTitle,
+		ItemLink:  e.ItemLink,
+		Time:      timestamppb.New(e.Time),
+	}
+}
+
+func timestampPB(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/events"
+	"github.com/haytac/rss-telegram-bot/pkg/managementpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+func setupGRPCClient(t *testing.T, s *Server) managementpb.ManagementClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := s.GRPCServer()
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return managementpb.NewManagementClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestGRPCAuthentication(t *testing.T) {
+	client := setupGRPCClient(t, setupTestServer(t))
+	_, err := client.ListFeeds(withToken("wrong"), &managementpb.ListFeedsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.ListFeeds(withToken("secret"), &managementpb.ListFeedsRequest{})
+	assert.NoError(t, err)
+}
+
+func TestGRPCRoles(t *testing.T) {
+	s := setupTestServer(t)
+	client := setupGRPCClient(t, s)
+	key, err := database.NewAPIKey()
+	require.NoError(t, err)
+	_, err = s.keys.CreateAPIKey(context.Background(), "viewer", database.RoleViewer, key)
+	require.NoError(t, err)
+
+	_, err = client.ListFeeds(withToken(key), &managementpb.ListFeedsRequest{})
+	assert.NoError(t, err)
+	_, err = client.CreateFeed(withToken(key), &managementpb.FeedInput{Url: proto.String("https://example.com/feed.xml"), ChatId: proto.String("1")})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.DeleteBot(withToken(key), &managementpb.IDRequest{Id: 1})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestGRPCFeedCRUD(t *testing.T) {
+	client := setupGRPCClient(t, setupTestServer(t))
+	ctx := withToken("secret")
+
+	created, err := client.CreateFeed(ctx, &managementpb.FeedInput{Url: proto.String("https://example.com/feed.xml"), ChatId: proto.String("@channel")})
+	require.NoError(t, err)
+	assert.Equal(t, int32(300), created.FrequencySeconds)
+
+	_, err = client.CreateFeed(ctx, &managementpb.FeedInput{Url: proto.String("https://example.com/b.xml")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	updated, err := client.UpdateFeed(ctx, &managementpb.UpdateFeedRequest{Id: created.Id, Feed: &managementpb.FeedInput{Title: proto.String("Example")}})
+	require.NoError(t, err)
+	assert.Equal(t, "Example", updated.GetTitle())
+
+	_, err = client.DeleteFeed(ctx, &managementpb.IDRequest{Id: created.Id})
+	require.NoError(t, err)
+	_, err = client.GetFeed(ctx, &managementpb.IDRequest{Id: created.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCWatchEvents(t *testing.T) {
+	s := setupTestServer(t)
+	bus := events.NewBus()
+	s.SetEventBus(bus)
+	client := setupGRPCClient(t, s)
+
+	ctx, cancel := context.WithTimeout(withToken("secret"), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchEvents(ctx, &managementpb.WatchEventsRequest{FeedId: proto.Int64(2)})
+	require.NoError(t, err)
+
+	// The subscription starts once the server handles the call; keep publishing until it does.
+	go func() {
+		for ctx.Err() == nil {
+			bus.Publish(events.Event{Type: events.TypeFeedProcessed, FeedID: 1, Result: "fetch_error"})
+			bus.Publish(events.Event{Type: events.TypeFeedProcessed, FeedID: 2, Result: "success"})
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	e, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), e.FeedId)
+	assert.Equal(t, "success", e.Result)
+}
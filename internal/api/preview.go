@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+)
+
+// previewRequest picks the item to preview, the newest of a stored feed or of
+// a feed URL, and optionally the formatting to try instead of the feed's own
+// profile.
+type previewRequest struct {
+	FeedID  int64                             `json:"feed_id"`
+	URL     string                            `json:"url"`
+	ProxyID int64                             `json:"proxy_id"`
+	Config  *database.FormattingProfileConfig `json:"config"`
+}
+
+// previewPartJSON is one message of a formatted item.
+type previewPartJSON struct {
+	Text         string                      `json:"text,omitempty"`
+	ParseMode    string                      `json:"parse_mode,omitempty"`
+	PhotoURL     string                      `json:"photo_url,omitempty"`
+	VideoURL     string                      `json:"video_url,omitempty"`
+	AnimationURL string                      `json:"animation_url,omitempty"`
+	DocumentURL  string                      `json:"document_url,omitempty"`
+	MediaGroup   []interfaces.MediaGroupItem `json:"media_group,omitempty"`
+}
+
+// previewResult is the newest item of a feed as it would be sent. Fetch
+// failures are reported in it rather than as an error, as for feed tests.
+type previewResult struct {
+	OK        bool              `json:"ok"`
+	Error     string            `json:"error,omitempty"`
+	ItemTitle string            `json:"item_title,omitempty"`
+	Parts     []previewPartJSON `json:"parts,omitempty"`
+}
+
+// renderPreview fetches a feed and formats its newest item without sending
+// or recording anything.
+func (s *Server) renderPreview(ctx context.Context, in previewRequest) (*previewResult, error) {
+	feed := &database.Feed{URL: in.URL}
+	if in.FeedID != 0 {
+		var err error
+		if feed, err = s.feedByID(ctx, in.FeedID); err != nil {
+			return nil, err
+		}
+	} else if in.URL == "" {
+		return nil, badRequest("feed_id or url is required")
+	}
+	p := feed.Proxy
+	if in.ProxyID != 0 {
+		var err error
+		if p, err = s.proxyByID(ctx, in.ProxyID); err != nil {
+			if errors.Is(err, errNotFound) {
+				return nil, badRequest("proxy %d not found", in.ProxyID)
+			}
+			return nil, err
+		}
+	}
+	profile := feed.FormattingProfile
+	if in.Config != nil {
+		profile = &database.FormattingProfile{Name: "preview", ParsedConfig: *in.Config}
+		if err := profile.MarshalConfig(); err != nil {
+			return nil, badRequest("invalid config: %v", err)
+		}
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, testFeedTimeout)
+	defer cancel()
+	result, err := s.fetcher.Fetch(fetchCtx, feed.URL, nil, nil, p, interfaces.FetchOptionsForFeed(feed))
+	if err != nil {
+		return &previewResult{Error: err.Error()}, nil
+	}
+	if result == nil || result.Feed == nil || len(result.Feed.Items) == 0 {
+		return &previewResult{Error: "feed has no items"}, nil
+	}
+	item := result.Feed.Items[0]
+	if result.EnrichItems != nil {
+		result.EnrichItems(fetchCtx, result.Feed.Items[:1])
+	}
+	title := item.Title
+	parts, err := s.formatter.FormatItem(ctx, item, feed, profile)
+	if err != nil {
+		return &previewResult{Error: err.Error()}, nil
+	}
+	out := &previewResult{OK: true, ItemTitle: title}
+	for _, part := range parts {
+		out.Parts = append(out.Parts, previewPartJSON{
+			Text:         part.Text,
+			ParseMode:    part.ParseMode,
+			PhotoURL:     part.PhotoURL,
+			VideoURL:     part.VideoURL,
+			AnimationURL: part.AnimationURL,
+			DocumentURL:  part.DocumentURL,
+			MediaGroup:   part.MediaGroup,
+		})
+	}
+	return out, nil
+}
+
+// previewItem shows how the newest item of a feed would be sent, to try out
+// templates before saving them.
+func (s *Server) previewItem(w http.ResponseWriter, r *http.Request) {
+	var in previewRequest
+	if err := decodeBody(r, &in); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	result, err := s.renderPreview(r.Context(), in)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,497 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+)
+
+// botJSON is a bot as returned by the API. Tokens are never returned.
+type botJSON struct {
+	ID          int64     `json:"id"`
+	Description *string   `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// botInput holds the settings accepted when creating or updating a bot.
+type botInput struct {
+	Token       string  `json:"token"` // Only when creating
+	Description *string `json:"description"`
+}
+
+func newBotJSON(b *database.TelegramBot) botJSON {
+	return botJSON{ID: b.ID, Description: b.Description, CreatedAt: b.CreatedAt}
+}
+
+func (s *Server) listBots(w http.ResponseWriter, r *http.Request) {
+	bots, err := s.bots.ListBots(r.Context())
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	out := make([]botJSON, 0, len(bots))
+	for _, b := range bots {
+		out = append(out, newBotJSON(b))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// botByID loads a bot, failing with errNotFound when it doesn't exist.
+func (s *Server) botByID(ctx context.Context, id int64) (*database.TelegramBot, error) {
+	bot, err := s.bots.GetBotByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if bot == nil {
+		return nil, notFound("bot", id)
+	}
+	return bot, nil
+}
+
+func (s *Server) addBot(ctx context.Context, in botInput) (*database.TelegramBot, error) {
+	if in.Token == "" {
+		return nil, badRequest("token is required")
+	}
+	id, err := s.bots.CreateBot(ctx, in.Token, in.Description)
+	if err != nil {
+		return nil, err
+	}
+	return s.botByID(ctx, id)
+}
+
+func (s *Server) createBot(w http.ResponseWriter, r *http.Request) {
+	var in botInput
+	if err := decodeBody(r, &in); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	bot, err := s.addBot(r.Context(), in)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newBotJSON(bot))
+}
+
+func (s *Server) editBot(ctx context.Context, id int64, in botInput) (*database.TelegramBot, error) {
+	if in.Token != "" {
+		return nil, badRequest("a bot's token can't be changed; add a new bot instead")
+	}
+	bot, err := s.botByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if in.Description != nil {
+		bot.Description = optionalString(*in.Description)
+		if err := s.bots.SetBotDescription(ctx, id, bot.Description); err != nil {
+			return nil, err
+		}
+	}
+	return bot, nil
+}
+
+func (s *Server) updateBot(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	var in botInput
+	if err := decodeBody(r, &in); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	bot, err := s.editBot(r.Context(), id, in)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newBotJSON(bot))
+}
+
+func (s *Server) removeBot(ctx context.Context, id int64) error {
+	if _, err := s.botByID(ctx, id); err != nil {
+		return err
+	}
+	return s.bots.DeleteBot(ctx, id)
+}
+
+func (s *Server) deleteBot(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	if err := s.removeBot(r.Context(), id); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// proxyJSON is a proxy as returned by the API. Literal passwords are
+// redacted; env: and file: references are shown as stored.
+type proxyJSON struct {
+	ID                 int64      `json:"id"`
+	Name               string     `json:"name"`
+	Type               string     `json:"type"`
+	Address            string     `json:"address"`
+	Username           *string    `json:"username,omitempty"`
+	Password           *string    `json:"password,omitempty"`
+	DefaultForRSS      bool       `json:"default_for_rss"`
+	DefaultForTelegram bool       `json:"default_for_telegram"`
+	DirectFallback     *bool      `json:"direct_fallback,omitempty"`
+	HealthCheckedAt    *time.Time `json:"health_checked_at,omitempty"`
+	HealthError        *string    `json:"health_error,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// redactedPassword replaces literal passwords in responses.
+const redactedPassword = "********"
+
+func newProxyJSON(p *database.Proxy) proxyJSON {
+	out := proxyJSON{
+		ID:                 p.ID,
+		Name:               p.Name,
+		Type:               p.Type,
+		Address:            p.Address,
+		Username:           p.Username,
+		DefaultForRSS:      p.IsDefaultForRSS,
+		DefaultForTelegram: p.IsDefaultForTelegram,
+		DirectFallback:     p.DirectFallback,
+		HealthCheckedAt:    p.HealthCheckedAt,
+		HealthError:        p.HealthError,
+		CreatedAt:          p.CreatedAt,
+	}
+	if p.Password != nil {
+		password := redactedPassword
+		if proxy.IsSecretRef(*p.Password) {
+			password = *p.Password
+		}
+		out.Password = &password
+	}
+	return out
+}
+
+// proxyInput holds the settings accepted when creating or updating a proxy.
+// Omitted fields are left unchanged; "" clears the username or password.
+type proxyInput struct {
+	Name               *string `json:"name"`
+	Type               *string `json:"type"`
+	Address            *string `json:"address"`
+	Username           *string `json:"username"`
+	Password           *string `json:"password"`
+	DefaultForRSS      *bool   `json:"default_for_rss"`
+	DefaultForTelegram *bool   `json:"default_for_telegram"`
+	DirectFallback     *bool   `json:"direct_fallback"`
+}
+
+func applyProxyInput(in proxyInput, p *database.Proxy) error {
+	if in.Name != nil {
+		if *in.Name == "" {
+			return badRequest("name must not be empty")
+		}
+		p.Name = *in.Name
+	}
+	if in.Type != nil {
+		t := strings.ToLower(*in.Type)
+		if t != "http" && t != "https" && t != "socks5" && t != "socks5h" && t != "pac" {
+			return badRequest("invalid proxy type %q; must be http, https, socks5, socks5h or pac", *in.Type)
+		}
+		p.Type = t
+	}
+	if in.Address != nil {
+		if *in.Address == "" {
+			return badRequest("address must not be empty")
+		}
+		p.Address = *in.Address
+	}
+	if in.Username != nil {
+		p.Username = optionalString(*in.Username)
+	}
+	if in.Password != nil {
+		p.Password = optionalString(*in.Password)
+	}
+	if in.DefaultForRSS != nil {
+		p.IsDefaultForRSS = *in.DefaultForRSS
+	}
+	if in.DefaultForTelegram != nil {
+		p.IsDefaultForTelegram = *in.DefaultForTelegram
+	}
+	if in.DirectFallback != nil {
+		p.DirectFallback = in.DirectFallback
+	}
+	return nil
+}
+
+func (s *Server) listProxies(w http.ResponseWriter, r *http.Request) {
+	proxies, err := s.proxies.ListProxies(r.Context())
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	out := make([]proxyJSON, 0, len(proxies))
+	for _, p := range proxies {
+		out = append(out, newProxyJSON(p))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// proxyByID loads a proxy, failing with errNotFound when it doesn't exist.
+func (s *Server) proxyByID(ctx context.Context, id int64) (*database.Proxy, error) {
+	p, err := s.proxies.GetProxyByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, notFound("proxy", id)
+	}
+	return p, nil
+}
+
+func (s *Server) getProxy(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	p, err := s.proxyByID(r.Context(), id)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newProxyJSON(p))
+}
+
+func (s *Server) addProxy(ctx context.Context, in proxyInput) (*database.Proxy, error) {
+	if in.Name == nil || in.Type == nil || in.Address == nil {
+		return nil, badRequest("name, type and address are required")
+	}
+	p := &database.Proxy{}
+	if err := applyProxyInput(in, p); err != nil {
+		return nil, err
+	}
+	id, err := s.proxies.CreateProxy(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return s.proxyByID(ctx, id)
+}
+
+func (s *Server) createProxy(w http.ResponseWriter, r *http.Request) {
+	var in proxyInput
+	if err := decodeBody(r, &in); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	p, err := s.addProxy(r.Context(), in)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newProxyJSON(p))
+}
+
+func (s *Server) editProxy(ctx context.Context, id int64, in proxyInput) (*database.Proxy, error) {
+	p, err := s.proxyByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyProxyInput(in, p); err != nil {
+		return nil, err
+	}
+	if err := s.proxies.UpdateProxy(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Server) updateProxy(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	var in proxyInput
+	if err := decodeBody(r, &in); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	p, err := s.editProxy(r.Context(), id, in)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newProxyJSON(p))
+}
+
+func (s *Server) removeProxy(ctx context.Context, id int64) error {
+	if _, err := s.proxyByID(ctx, id); err != nil {
+		return err
+	}
+	return s.proxies.DeleteProxy(ctx, id)
+}
+
+func (s *Server) deleteProxy(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	if err := s.removeProxy(r.Context(), id); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// profileJSON is a formatting profile as returned by the API.
+type profileJSON struct {
+	ID        int64                            `json:"id"`
+	Name      string                           `json:"name"`
+	Config    database.FormattingProfileConfig `json:"config"`
+	CreatedAt time.Time                        `json:"created_at"`
+}
+
+// profileInput holds the settings accepted when creating or updating a
+// formatting profile. A config replaces the previous one entirely.
+type profileInput struct {
+	Name   *string                           `json:"name"`
+	Config *database.FormattingProfileConfig `json:"config"`
+}
+
+func newProfileJSON(p *database.FormattingProfile) profileJSON {
+	return profileJSON{ID: p.ID, Name: p.Name, Config: p.ParsedConfig, CreatedAt: p.CreatedAt}
+}
+
+func (s *Server) listProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles, err := s.profiles.ListProfiles(r.Context())
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	out := make([]profileJSON, 0, len(profiles))
+	for _, p := range profiles {
+		out = append(out, newProfileJSON(p))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// profileByID loads a formatting profile, failing with errNotFound when it
+// doesn't exist.
+func (s *Server) profileByID(ctx context.Context, id int64) (*database.FormattingProfile, error) {
+	p, err := s.profiles.GetProfileByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, notFound("formatting profile", id)
+	}
+	return p, nil
+}
+
+func (s *Server) getProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	p, err := s.profileByID(r.Context(), id)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newProfileJSON(p))
+}
+
+func (s *Server) addProfile(ctx context.Context, in profileInput) (*database.FormattingProfile, error) {
+	if in.Name == nil || *in.Name == "" {
+		return nil, badRequest("name is required")
+	}
+	p := &database.FormattingProfile{Name: *in.Name}
+	if in.Config != nil {
+		p.ParsedConfig = *in.Config
+	}
+	id, err := s.profiles.CreateProfile(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return s.profileByID(ctx, id)
+}
+
+func (s *Server) createProfile(w http.ResponseWriter, r *http.Request) {
+	var in profileInput
+	if err := decodeBody(r, &in); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	p, err := s.addProfile(r.Context(), in)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newProfileJSON(p))
+}
+
+func (s *Server) editProfile(ctx context.Context, id int64, in profileInput) (*database.FormattingProfile, error) {
+	p, err := s.profileByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if in.Name != nil {
+		if *in.Name == "" {
+			return nil, badRequest("name must not be empty")
+		}
+		p.Name = *in.Name
+	}
+	if in.Config != nil {
+		p.ParsedConfig = *in.Config
+	}
+	if err := s.profiles.UpdateProfile(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Server) updateProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	var in profileInput
+	if err := decodeBody(r, &in); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	p, err := s.editProfile(r.Context(), id, in)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newProfileJSON(p))
+}
+
+func (s *Server) removeProfile(ctx context.Context, id int64) error {
+	if _, err := s.profileByID(ctx, id); err != nil {
+		return err
+	}
+	return s.profiles.DeleteProfile(ctx, id)
+}
+
+func (s *Server) deleteProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	if err := s.removeProfile(r.Context(), id); err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
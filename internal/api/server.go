@@ -0,0 +1,226 @@
+// Package api serves a token-protected JSON API for managing feeds, bots,
+// proxies and formatting profiles of a running bot.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/events"
+	"github.com/haytac/rss-telegram-bot/internal/formatter"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/rs/zerolog/log"
+)
+
+// maxRequestBody caps JSON request bodies.
+const maxRequestBody = 1 << 20
+
+// Server implements the API. Routes live under /api/v1 and require one of the
+// configured tokens or an API key as "Authorization: Bearer <token>". Tokens
+// have the admin role; API keys have the role they were created with.
+type Server struct {
+	feeds            *database.FeedStore
+	proxies          *database.ProxyStore
+	profiles         *database.FormattingProfileStore
+	bots             *database.TelegramBotStore
+	keys             *database.APIKeyStore
+	fetcher          interfaces.FeedFetcher
+	tokens           [][]byte
+	defaultFrequency int                  // Seconds, for feeds created without a frequency
+	onFeedCreated    func(*database.Feed) // Schedules new enabled feeds; nil when nothing is running
+	onFetchNow       func(*database.Feed) // Processes a feed immediately; nil disables fetch-now
+	events           *events.Bus          // Source of WatchEvents streams; nil disables them
+	formatter        interfaces.Formatter // Renders template previews
+	activity         activity             // Recent deliveries and feed results, for the UI
+	ui               bool                 // Serve the web UI at /
+}
+
+// NewServer creates a Server accepting tokens. fetcher backs the feed test
+// endpoint; defaultFrequency (in seconds) applies to feeds created without one.
+func NewServer(db *database.DB, tokens []string, fetcher interfaces.FeedFetcher, defaultFrequency int) *Server {
+	s := &Server{
+		feeds:            database.NewFeedStore(db),
+		proxies:          database.NewProxyStore(db),
+		profiles:         database.NewFormattingProfileStore(db),
+		bots:             database.NewTelegramBotStore(db),
+		keys:             database.NewAPIKeyStore(db),
+		fetcher:          fetcher,
+		defaultFrequency: defaultFrequency,
+		formatter:        formatter.NewDefaultFormatter(),
+	}
+	for _, token := range tokens {
+		if token != "" {
+			s.tokens = append(s.tokens, []byte(token))
+		}
+	}
+	return s
+}
+
+// OnFeedCreated sets the function called with each feed created enabled, e.g.
+// to add it to the scheduler.
+func (s *Server) OnFeedCreated(fn func(*database.Feed)) {
+	s.onFeedCreated = fn
+}
+
+// OnFetchNow sets the function that processes a feed on request. It runs in
+// the background; the request returns once it has started.
+func (s *Server) OnFetchNow(fn func(*database.Feed)) {
+	s.onFetchNow = fn
+}
+
+// SetEventBus sets the bus WatchEvents streams from and starts recording the
+// recent deliveries and feed results the UI shows. Without one, WatchEvents
+// fails as unavailable and the UI shows no activity.
+func (s *Server) SetEventBus(bus *events.Bus) {
+	s.events = bus
+	if bus == nil {
+		return
+	}
+	ch, _ := bus.Subscribe(watchBuffer)
+	go s.activity.track(ch)
+}
+
+// EnableUI serves the embedded web UI at / alongside the REST API.
+func (s *Server) EnableUI() {
+	s.ui = true
+}
+
+// Handler returns the HTTP handler for the API.
+func (s *Server) Handler() http.Handler {
+	mux := chi.NewRouter()
+	mux.Route("/api/v1", func(r chi.Router) {
+		r.Use(s.authenticate)
+
+		// Viewers may read everything except API keys.
+		r.Get("/stats", s.handleStats)
+		r.Get("/deliveries", s.listDeliveries)
+		r.Get("/feeds", s.listFeeds)
+		r.Get("/feeds/{id}", s.getFeed)
+		r.Get("/bots", s.listBots)
+		r.Get("/proxies", s.listProxies)
+		r.Get("/proxies/{id}", s.getProxy)
+		r.Get("/profiles", s.listProfiles)
+		r.Get("/profiles/{id}", s.getProfile)
+
+		// Editors manage feeds and profiles. Tests and previews count as
+		// writes since they make the bot fetch arbitrary URLs.
+		r.Group(func(r chi.Router) {
+			r.Use(requireRole(database.RoleEditor))
+			r.Post("/preview", s.previewItem)
+			r.Post("/feeds", s.createFeed)
+			r.Post("/feeds/test", s.testFeed)
+			r.Patch("/feeds/{id}", s.updateFeed)
+			r.Delete("/feeds/{id}", s.deleteFeed)
+			r.Post("/feeds/{id}/fetch", s.fetchFeedNow)
+			r.Post("/profiles", s.createProfile)
+			r.Patch("/profiles/{id}", s.updateProfile)
+			r.Delete("/profiles/{id}", s.deleteProfile)
+		})
+
+		// Admins also manage bots, proxies and API keys.
+		r.Group(func(r chi.Router) {
+			r.Use(requireRole(database.RoleAdmin))
+			r.Post("/bots", s.createBot)
+			r.Patch("/bots/{id}", s.updateBot)
+			r.Delete("/bots/{id}", s.deleteBot)
+			r.Post("/proxies", s.createProxy)
+			r.Patch("/proxies/{id}", s.updateProxy)
+			r.Delete("/proxies/{id}", s.deleteProxy)
+			r.Get("/keys", s.listKeys)
+			r.Post("/keys", s.createKey)
+			r.Delete("/keys/{id}", s.deleteKey)
+		})
+	})
+	if s.ui {
+		mux.Handle("/*", uiHandler())
+	}
+	return mux
+}
+
+// StartServer serves the API on addr in the background.
+func (s *Server) StartServer(addr string) {
+	if len(s.tokens) == 0 {
+		log.Warn().Str("address", addr).Msg("api.tokens is empty; only API keys created with 'apikey add' can use the API")
+	}
+	log.Info().Str("address", addr).Msg("Starting API server")
+	go func() {
+		if err := http.ListenAndServe(addr, s.Handler()); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("API server failed")
+		}
+	}()
+}
+
+// Errors marking failures caused by the request rather than the server.
+var (
+	errBadRequest  = errors.New("bad request")
+	errNotFound    = errors.New("not found")
+	errUnavailable = errors.New("unavailable")
+)
+
+// badRequest returns an error reported to the client with status 400.
+func badRequest(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", errBadRequest, fmt.Sprintf(format, args...))
+}
+
+// notFound returns an error reported to the client with status 404.
+func notFound(kind string, id int64) error {
+	return fmt.Errorf("%w: %s %d not found", errNotFound, kind, id)
+}
+
+// decodeBody reads a JSON request body into v, rejecting unknown fields.
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// idParam parses the {id} URL parameter.
+func idParam(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, badRequest("invalid id %q", chi.URLParam(r, "id"))
+	}
+	return id, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warn().Err(err).Msg("Failed to write API response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// writeFailure reports err as a 400, 404 or 503 when the request caused it,
+// otherwise as a logged 500 that doesn't leak internals.
+func writeFailure(w http.ResponseWriter, r *http.Request, err error) {
+	for _, e := range []struct {
+		kind   error
+		status int
+	}{{errBadRequest, http.StatusBadRequest}, {errNotFound, http.StatusNotFound}, {errUnavailable, http.StatusServiceUnavailable}} {
+		if errors.Is(err, e.kind) {
+			writeError(w, e.status, clientMessage(err, e.kind))
+			return
+		}
+	}
+	log.Error().Err(err).Str("method", r.Method).Str("path", r.URL.Path).Msg("API request failed")
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+// clientMessage strips the kind prefix from an error caused by the request.
+func clientMessage(err, kind error) string {
+	return strings.TrimPrefix(err.Error(), kind.Error()+": ")
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/events"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubFetcher struct{}
+
+func (stubFetcher) Fetch(ctx context.Context, url string, etag, lastModified *string, p *database.Proxy, opts interfaces.FetchOptions) (*interfaces.FetchResult, error) {
+	return &interfaces.FetchResult{Feed: &gofeed.Feed{Title: "Stub", Items: []*gofeed.Item{{Title: "one"}, {Title: "two"}}}}, nil
+}
+
+func setupTestServer(t *testing.T) *Server {
+	t.Helper()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "api.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return NewServer(db, []string{"secret"}, stubFetcher{}, 300)
+}
+
+func do(t *testing.T, h http.Handler, method, path, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthentication(t *testing.T) {
+	h := setupTestServer(t).Handler()
+	assert.Equal(t, http.StatusUnauthorized, do(t, h, http.MethodGet, "/api/v1/feeds", "", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, do(t, h, http.MethodGet, "/api/v1/feeds", "wrong", nil).Code)
+	assert.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/api/v1/feeds", "secret", nil).Code)
+}
+
+func TestAPIKeyRoles(t *testing.T) {
+	h := setupTestServer(t).Handler()
+
+	keys := map[string]string{}
+	for _, role := range []string{"viewer", "editor"} {
+		rec := do(t, h, http.MethodPost, "/api/v1/keys", "secret", map[string]any{"name": role, "role": role})
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var created keyJSON
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+		require.NotEmpty(t, created.Key)
+		keys[role] = created.Key
+	}
+	assert.Equal(t, http.StatusBadRequest, do(t, h, http.MethodPost, "/api/v1/keys", "secret", map[string]any{"name": "viewer", "role": "viewer"}).Code)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, http.MethodPost, "/api/v1/keys", "secret", map[string]any{"name": "x", "role": "owner"}).Code)
+
+	feed := map[string]any{"url": "https://example.com/feed.xml", "chat_id": "1"}
+	assert.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/api/v1/feeds", keys["viewer"], nil).Code)
+	assert.Equal(t, http.StatusForbidden, do(t, h, http.MethodPost, "/api/v1/feeds", keys["viewer"], feed).Code)
+	assert.Equal(t, http.StatusCreated, do(t, h, http.MethodPost, "/api/v1/feeds", keys["editor"], feed).Code)
+	assert.Equal(t, http.StatusForbidden, do(t, h, http.MethodPost, "/api/v1/bots", keys["editor"], map[string]any{"token": "123:abc"}).Code)
+	assert.Equal(t, http.StatusForbidden, do(t, h, http.MethodGet, "/api/v1/keys", keys["editor"], nil).Code)
+
+	rec := do(t, h, http.MethodGet, "/api/v1/keys", "secret", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listed []keyJSON
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	require.Len(t, listed, 2)
+	assert.Empty(t, listed[0].Key, "keys are only shown when created")
+	assert.NotNil(t, listed[1].LastUsedAt)
+
+	assert.Equal(t, http.StatusNoContent, do(t, h, http.MethodDelete, "/api/v1/keys/1", "secret", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do(t, h, http.MethodDelete, "/api/v1/keys/1", "secret", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, do(t, h, http.MethodGet, "/api/v1/feeds", keys["viewer"], nil).Code)
+}
+
+func TestFeedCRUD(t *testing.T) {
+	s := setupTestServer(t)
+	var scheduled []int64
+	s.OnFeedCreated(func(f *database.Feed) { scheduled = append(scheduled, f.ID) })
+	h := s.Handler()
+
+	rec := do(t, h, http.MethodPost, "/api/v1/feeds", "secret", map[string]any{"url": "https://example.com/feed.xml", "chat_id": "@channel"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created feedJSON
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, 300, created.FrequencySeconds)
+	assert.True(t, created.Enabled)
+	assert.Equal(t, []int64{created.ID}, scheduled)
+
+	rec = do(t, h, http.MethodPost, "/api/v1/feeds", "secret", map[string]any{"url": "https://example.com/b.xml", "chat_id": "1", "proxy_id": 42})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "proxy 42 not found")
+
+	rec = do(t, h, http.MethodPatch, "/api/v1/feeds/1", "secret", map[string]any{"title": "Example", "enabled": false})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var updated feedJSON
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updated))
+	require.NotNil(t, updated.Title)
+	assert.Equal(t, "Example", *updated.Title)
+	assert.False(t, updated.Enabled)
+	assert.Equal(t, "https://example.com/feed.xml", updated.URL)
+
+	assert.Equal(t, http.StatusNoContent, do(t, h, http.MethodDelete, "/api/v1/feeds/1", "secret", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do(t, h, http.MethodGet, "/api/v1/feeds/1", "secret", nil).Code)
+}
+
+func TestProxyPasswordRedacted(t *testing.T) {
+	h := setupTestServer(t).Handler()
+	rec := do(t, h, http.MethodPost, "/api/v1/proxies", "secret", map[string]any{"name": "eu", "type": "socks5", "address": "10.0.0.1:1080", "password": "hunter2"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "hunter2")
+	assert.Contains(t, rec.Body.String(), redactedPassword)
+}
+
+func TestTestFeedAndStats(t *testing.T) {
+	h := setupTestServer(t).Handler()
+	rec := do(t, h, http.MethodPost, "/api/v1/feeds/test", "secret", map[string]any{"url": "https://example.com/feed.xml"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result struct {
+		OK        bool   `json:"ok"`
+		Title     string `json:"title"`
+		ItemCount int    `json:"item_count"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.True(t, result.OK)
+	assert.Equal(t, "Stub", result.Title)
+	assert.Equal(t, 2, result.ItemCount)
+
+	rec = do(t, h, http.MethodGet, "/api/v1/stats", "secret", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stats statsJSON
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Zero(t, stats.Feeds)
+	assert.Zero(t, stats.ItemsProcessed)
+}
+
+func TestUI(t *testing.T) {
+	s := setupTestServer(t)
+	assert.Equal(t, http.StatusNotFound, do(t, s.Handler(), http.MethodGet, "/", "", nil).Code)
+
+	s.EnableUI()
+	h := s.Handler()
+	rec := do(t, h, http.MethodGet, "/", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<script src=\"app.js\">")
+	assert.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/app.js", "", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, do(t, h, http.MethodGet, "/api/v1/feeds", "", nil).Code)
+}
+
+func TestPreview(t *testing.T) {
+	h := setupTestServer(t).Handler()
+	rec := do(t, h, http.MethodPost, "/api/v1/preview", "secret", map[string]any{
+		"url":    "https://example.com/feed.xml",
+		"config": map[string]any{"message_template": "Item: {{.ItemTitle}}"},
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var result previewResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	require.True(t, result.OK, result.Error)
+	assert.Equal(t, "one", result.ItemTitle)
+	require.NotEmpty(t, result.Parts)
+	assert.Contains(t, result.Parts[0].Text, "Item: one")
+
+	assert.Equal(t, http.StatusBadRequest, do(t, h, http.MethodPost, "/api/v1/preview", "secret", map[string]any{}).Code)
+}
+
+func TestDeliveries(t *testing.T) {
+	s := setupTestServer(t)
+	s.activity.record(events.Event{Type: events.TypeItemSent, FeedID: 1, ItemTitle: "first"})
+	s.activity.record(events.Event{Type: events.TypeItemSent, FeedID: 1, ItemTitle: "second"})
+	s.activity.record(events.Event{Type: events.TypeFeedProcessed, FeedID: 1, Result: "success"})
+
+	rec := do(t, s.Handler(), http.MethodGet, "/api/v1/deliveries", "secret", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var deliveries []deliveryJSON
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &deliveries))
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, "second", deliveries[0].ItemTitle)
+
+	f := feedJSON{ID: 1}
+	s.activity.annotate(&f)
+	assert.Equal(t, "success", f.LastResult)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// statsJSON summarizes what the bot manages and how much it has sent.
+type statsJSON struct {
+	Feeds             int   `json:"feeds"`
+	EnabledFeeds      int   `json:"enabled_feeds"`
+	Bots              int   `json:"bots"`
+	Proxies           int   `json:"proxies"`
+	ProxiesDown       int   `json:"proxies_down"`
+	Profiles          int   `json:"profiles"`
+	ItemsProcessed    int64 `json:"items_processed"`
+	ItemsProcessed24h int64 `json:"items_processed_24h"`
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.collectStats(r.Context())
+	if err != nil {
+		writeFailure(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// collectStats counts what the bot manages and the items it has processed.
+func (s *Server) collectStats(ctx context.Context) (*statsJSON, error) {
+	var stats statsJSON
+
+	feeds, err := s.feeds.ListFeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.Feeds = len(feeds)
+	for _, f := range feeds {
+		if f.IsEnabled {
+			stats.EnabledFeeds++
+		}
+	}
+
+	bots, err := s.bots.ListBots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.Bots = len(bots)
+
+	proxies, err := s.proxies.ListProxies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.Proxies = len(proxies)
+	for _, p := range proxies {
+		if p.HealthError != nil {
+			stats.ProxiesDown++
+		}
+	}
+
+	profiles, err := s.profiles.ListProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.Profiles = len(profiles)
+
+	if stats.ItemsProcessed, err = s.feeds.CountProcessedItems(ctx, time.Time{}); err != nil {
+		return nil, err
+	}
+	if stats.ItemsProcessed24h, err = s.feeds.CountProcessedItems(ctx, time.Now().Add(-24*time.Hour)); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
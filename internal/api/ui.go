@@ -0,0 +1,21 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// uiFiles is the web UI: a static page that calls the REST API with a token
+// the operator enters, so serving it needs no authentication.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+func uiHandler() http.Handler {
+	root, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err) // The embedded directory is fixed at build time
+	}
+	return http.FileServerFS(root)
+}
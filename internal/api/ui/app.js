@@ -0,0 +1,328 @@
+// Admin UI for the REST API. The token is kept in localStorage and sent as a
+// bearer token with every request.
+"use strict";
+
+const api = "/api/v1";
+let token = localStorage.getItem("rssbot-token") || "";
+let bots = [], proxies = [], profiles = [], feeds = [];
+
+const $ = (sel) => document.querySelector(sel);
+
+async function call(method, path, body) {
+  const res = await fetch(api + path, {
+    method,
+    headers: { "Authorization": "Bearer " + token, "Content-Type": "application/json" },
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+  if (res.status === 401) {
+    showLogin();
+    throw new Error("Sign in with a valid API token.");
+  }
+  if (res.status === 204) return null;
+  const data = await res.json();
+  if (!res.ok) throw new Error(data.error || res.statusText);
+  return data;
+}
+
+function say(text, ok) {
+  const el = $("#message");
+  el.textContent = text;
+  el.className = ok ? "ok" : "";
+  el.hidden = !text;
+}
+
+function fail(err) { say(err.message); }
+
+function showLogin() {
+  $("#login").hidden = false;
+  document.querySelector("main").hidden = true;
+}
+
+function when(t) { return t ? new Date(t).toLocaleString() : "—"; }
+
+function cell(row, text, cls) {
+  const td = row.insertCell();
+  td.textContent = text;
+  if (cls) td.className = cls;
+  return td;
+}
+
+function link(href, text) {
+  const a = document.createElement("a");
+  a.href = href;
+  a.textContent = text || href;
+  a.target = "_blank";
+  a.rel = "noopener";
+  return a;
+}
+
+function statusClass(result) {
+  if (!result) return "status-idle";
+  return ["success", "no_new_items", "not_modified"].includes(result) ? "status-ok" : "status-error";
+}
+
+// Feeds
+
+async function loadFeeds() {
+  [feeds, bots, proxies, profiles] = await Promise.all([
+    call("GET", "/feeds"), call("GET", "/bots"), call("GET", "/proxies"), call("GET", "/profiles"),
+  ]);
+  const stats = await call("GET", "/stats");
+  $("#stats").textContent = `${stats.enabled_feeds}/${stats.feeds} feeds enabled · ${stats.bots} bots · ` +
+    `${stats.proxies} proxies (${stats.proxies_down} down) · ${stats.items_processed_24h} items in the last 24h`;
+
+  const body = $("#feed-rows");
+  body.replaceChildren();
+  for (const f of feeds) {
+    const row = body.insertRow();
+    cell(row, f.id);
+    const name = cell(row, "", "url");
+    name.append(f.title ? f.title + " " : "", link(f.url));
+    cell(row, f.chat_id + (f.thread_id ? ` (topic ${f.thread_id})` : ""));
+    cell(row, f.frequency_seconds + "s");
+    cell(row, f.enabled ? "enabled" : "disabled", f.enabled ? "" : "status-idle");
+    cell(row, f.last_result ? `${f.last_result} · ${when(f.last_result_at)}` : when(f.last_fetched_at), statusClass(f.last_result));
+    cell(row, when(f.newest_item_at));
+    const actions = row.insertCell();
+    actions.append(
+      button("Edit", () => editFeed(f)),
+      button("Fetch now", () => call("POST", `/feeds/${f.id}/fetch`).then(() => say("Fetch started.", true)).catch(fail)),
+      button("Delete", () => deleteFeed(f)),
+    );
+  }
+  fillSelect($("#feed-form [name=bot_id]"), bots, (b) => b.description || `Bot ${b.id}`);
+  fillSelect($("#feed-form [name=proxy_id]"), proxies, (p) => p.name);
+  fillSelect($("#feed-form [name=formatting_profile_id]"), profiles, (p) => p.name);
+  fillSelect($("#preview-form [name=feed_id]"), feeds, (f) => f.title || f.url);
+}
+
+function button(text, onClick) {
+  const b = document.createElement("button");
+  b.type = "button";
+  b.className = "link";
+  b.textContent = text;
+  b.addEventListener("click", onClick);
+  return b;
+}
+
+function fillSelect(select, items, label) {
+  const current = select.value;
+  select.replaceChildren(new Option("—", "0"));
+  for (const item of items) select.add(new Option(label(item), item.id));
+  select.value = current || "0";
+}
+
+function editFeed(f) {
+  const form = $("#feed-form");
+  form.reset();
+  $("#feed-form-title").textContent = f ? `Edit feed #${f.id}` : "Add feed";
+  $("#test-result").hidden = true;
+  if (f) {
+    form.elements.id.value = f.id;
+    form.elements.url.value = f.url;
+    form.elements.title.value = f.title || "";
+    form.elements.chat_id.value = f.chat_id;
+    form.elements.thread_id.value = f.thread_id || "";
+    form.elements.bot_id.value = f.bot_id || 0;
+    form.elements.proxy_id.value = f.proxy_id || 0;
+    form.elements.formatting_profile_id.value = f.formatting_profile_id || 0;
+    form.elements.frequency_seconds.value = f.frequency_seconds;
+    form.elements.enabled.checked = f.enabled;
+  } else {
+    form.elements.id.value = "";
+  }
+  form.hidden = false;
+  form.scrollIntoView();
+}
+
+async function saveFeed(e) {
+  e.preventDefault();
+  const form = e.target;
+  const body = {
+    url: form.elements.url.value,
+    title: form.elements.title.value,
+    chat_id: form.elements.chat_id.value,
+    thread_id: Number(form.elements.thread_id.value) || 0,
+    bot_id: Number(form.elements.bot_id.value),
+    proxy_id: Number(form.elements.proxy_id.value),
+    formatting_profile_id: Number(form.elements.formatting_profile_id.value),
+    enabled: form.elements.enabled.checked,
+  };
+  if (form.elements.frequency_seconds.value) body.frequency_seconds = Number(form.elements.frequency_seconds.value);
+  try {
+    if (form.elements.id.value) {
+      await call("PATCH", `/feeds/${form.elements.id.value}`, body);
+    } else {
+      await call("POST", "/feeds", body);
+    }
+    form.hidden = true;
+    say("Feed saved.", true);
+    await loadFeeds();
+  } catch (err) {
+    fail(err);
+  }
+}
+
+async function deleteFeed(f) {
+  if (!confirm(`Delete feed #${f.id} (${f.url})?`)) return;
+  try {
+    await call("DELETE", `/feeds/${f.id}`);
+    say("Feed deleted.", true);
+    await loadFeeds();
+  } catch (err) {
+    fail(err);
+  }
+}
+
+async function testFeed() {
+  const form = $("#feed-form");
+  const out = $("#test-result");
+  out.hidden = false;
+  out.textContent = "Fetching…";
+  try {
+    const r = await call("POST", "/feeds/test", { url: form.elements.url.value, proxy_id: Number(form.elements.proxy_id.value) });
+    out.textContent = r.ok
+      ? `${r.title} — ${r.item_count} items\n` + (r.items || []).map((i) => `• ${i.title}`).join("\n")
+      : "Failed: " + r.error;
+  } catch (err) {
+    out.textContent = err.message;
+  }
+}
+
+// Preview
+
+// Telegram HTML allows only a few tags; render those and show everything else as text.
+const allowedTags = new Set(["B", "STRONG", "I", "EM", "U", "INS", "S", "STRIKE", "DEL", "A", "CODE", "PRE", "BLOCKQUOTE"]);
+
+function renderTelegramHTML(html) {
+  const doc = new DOMParser().parseFromString(`<div>${html}</div>`, "text/html");
+  const copy = (node) => {
+    if (node.nodeType === Node.TEXT_NODE) return document.createTextNode(node.textContent);
+    const children = Array.from(node.childNodes).map(copy);
+    if (node.nodeType !== Node.ELEMENT_NODE || !allowedTags.has(node.tagName)) {
+      const frag = document.createDocumentFragment();
+      frag.append(...children);
+      return frag;
+    }
+    const el = document.createElement(node.tagName);
+    if (node.tagName === "A" && /^https?:/i.test(node.getAttribute("href") || "")) {
+      el.href = node.getAttribute("href");
+      el.target = "_blank";
+      el.rel = "noopener";
+    }
+    el.append(...children);
+    return el;
+  };
+  return copy(doc.body.firstChild);
+}
+
+async function preview(e) {
+  e.preventDefault();
+  const form = e.target;
+  const out = $("#preview-result");
+  out.textContent = "Fetching…";
+  const body = { feed_id: Number(form.elements.feed_id.value), url: form.elements.url.value };
+  if (!form.elements.use_profile.checked) {
+    body.config = {
+      title_template: form.elements.title_template.value,
+      message_template: form.elements.message_template.value,
+      hashtags: form.elements.hashtags.value.split(/\s+/).filter(Boolean),
+    };
+  }
+  try {
+    const r = await call("POST", "/preview", body);
+    out.replaceChildren();
+    if (!r.ok) {
+      out.textContent = "Failed: " + r.error;
+      return;
+    }
+    for (const part of r.parts || []) {
+      const div = document.createElement("div");
+      div.className = "part";
+      if (part.photo_url) {
+        const img = document.createElement("img");
+        img.src = part.photo_url;
+        img.alt = "";
+        div.append(img);
+      }
+      for (const media of [part.video_url, part.animation_url, part.document_url]) {
+        if (media) div.append(link(media), document.createElement("br"));
+      }
+      div.append(part.parse_mode === "HTML" ? renderTelegramHTML(part.text) : part.text);
+      out.append(div);
+    }
+  } catch (err) {
+    out.textContent = err.message;
+  }
+}
+
+// Proxies and deliveries
+
+async function loadProxies() {
+  proxies = await call("GET", "/proxies");
+  const body = $("#proxy-rows");
+  body.replaceChildren();
+  for (const p of proxies) {
+    const row = body.insertRow();
+    cell(row, p.id);
+    cell(row, p.name);
+    cell(row, p.type);
+    cell(row, p.address, "url");
+    cell(row, [p.default_for_rss && "RSS", p.default_for_telegram && "Telegram"].filter(Boolean).join(", ") || "—");
+    if (p.health_error) {
+      cell(row, "down: " + p.health_error, "status-error");
+    } else {
+      cell(row, p.health_checked_at ? "up" : "unknown", p.health_checked_at ? "status-ok" : "status-idle");
+    }
+    cell(row, when(p.health_checked_at));
+  }
+}
+
+async function loadDeliveries() {
+  const deliveries = await call("GET", "/deliveries");
+  const body = $("#delivery-rows");
+  body.replaceChildren();
+  for (const d of deliveries) {
+    const row = body.insertRow();
+    cell(row, when(d.sent_at));
+    const feed = feeds.find((f) => f.id === d.feed_id);
+    cell(row, feed && feed.title ? feed.title : d.feed_url, "url");
+    cell(row, "", "url").append(d.item_link ? link(d.item_link, d.item_title || d.item_link) : d.item_title);
+  }
+  if (!deliveries.length) cell(body.insertRow(), "Nothing delivered yet.", "hint").colSpan = 3;
+}
+
+const loaders = { feeds: loadFeeds, preview: loadFeeds, proxies: loadProxies, deliveries: loadDeliveries };
+
+function showTab(name) {
+  for (const b of document.querySelectorAll("nav button")) b.classList.toggle("active", b.dataset.tab === name);
+  for (const s of document.querySelectorAll(".tab")) s.hidden = s.id !== name;
+  say("");
+  loaders[name]().catch(fail);
+}
+
+document.querySelectorAll("nav button").forEach((b) => b.addEventListener("click", () => showTab(b.dataset.tab)));
+$("#add-feed").addEventListener("click", () => editFeed(null));
+$("#cancel-feed").addEventListener("click", () => { $("#feed-form").hidden = true; });
+$("#feed-form").addEventListener("submit", saveFeed);
+$("#test-feed").addEventListener("click", testFeed);
+$("#preview-form").addEventListener("submit", preview);
+$("#login-form").addEventListener("submit", (e) => {
+  e.preventDefault();
+  token = e.target.elements.token.value;
+  localStorage.setItem("rssbot-token", token);
+  $("#login").hidden = true;
+  document.querySelector("main").hidden = false;
+  showTab("feeds");
+});
+$("#logout").addEventListener("click", () => {
+  localStorage.removeItem("rssbot-token");
+  token = "";
+  showLogin();
+});
+
+if (token) {
+  showTab("feeds");
+} else {
+  showLogin();
+}
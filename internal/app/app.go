@@ -2,20 +2,34 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/haytac/rss-telegram-bot/internal/alert"
+	"github.com/haytac/rss-telegram-bot/internal/api"
 	"github.com/haytac/rss-telegram-bot/internal/config"       // Module path
 	"github.com/haytac/rss-telegram-bot/internal/database"    // Module path
+	"github.com/haytac/rss-telegram-bot/internal/errorreport"
+	"github.com/haytac/rss-telegram-bot/internal/events"
+	"github.com/haytac/rss-telegram-bot/internal/feedsync"
 	"github.com/haytac/rss-telegram-bot/internal/formatter"   // Module path
+	"github.com/haytac/rss-telegram-bot/internal/logging"
 	"github.com/haytac/rss-telegram-bot/internal/metrics"     // Module path
+	"github.com/haytac/rss-telegram-bot/internal/notify"
+	"github.com/haytac/rss-telegram-bot/internal/output"
 	"github.com/haytac/rss-telegram-bot/internal/proxy"       // Module path
 	"github.com/haytac/rss-telegram-bot/internal/rss"         // Module path
+	"github.com/haytac/rss-telegram-bot/internal/sandbox"
 	"github.com/haytac/rss-telegram-bot/internal/scheduler"   // Module path
+	"github.com/haytac/rss-telegram-bot/internal/systemd"
 	"github.com/haytac/rss-telegram-bot/internal/telegram"    // Module path
+	"github.com/haytac/rss-telegram-bot/internal/websub"
 	"github.com/haytac/rss-telegram-bot/pkg/interfaces" // Module path
 )
 
@@ -25,12 +39,24 @@ type Application struct {
 	DB         *database.DB
 	Scheduler  interfaces.Scheduler
 	FeedWorker *FeedWorker
+	ProxyHealth *proxy.HealthChecker // nil when proxy_health.interval is 0
+	Digest      *alert.Digester      // Sends admin.digest; idle while it is disabled
+	Commands    *alert.Commander     // Answers admin.commands; idle while they are disabled
+	API         *api.Server          // nil when api.listen_addr and api.grpc_listen_addr are empty
+	Output      *output.Server       // nil when output.listen_addr is empty
+	EventStream *events.Stream       // nil when event_stream is not configured
+	Sandbox     *sandbox.Server      // nil unless notifier is sandbox and sandbox.listen_addr is set
 	
 	// Stores
 	FeedStore            *database.FeedStore
 	ProxyStore           *database.ProxyStore
 	TelegramBotStore     *database.TelegramBotStore
 	FormattingProfStore  *database.FormattingProfileStore
+
+	fetcher  *rss.GoFeedFetcher
+	alerter  *alert.Alerter
+	telegram *telegram.Client
+	sandbox  *sandbox.Notifier // nil unless notifier is sandbox
 }
 
 // NewApplication creates and initializes a new application instance.
@@ -45,7 +71,7 @@ func NewApplication(cfg *config.AppConfig) (*Application, error) {
     }
 
 
-	db, err := database.Connect(cfg.DatabasePath, "internal/database/migrations")
+	db, err := database.Connect(cfg.DatabasePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -56,35 +82,375 @@ func NewApplication(cfg *config.AppConfig) (*Application, error) {
 	tgBotStore := database.NewTelegramBotStore(db) // Add encryption key here if implementing
 	fmtProfStore := database.NewFormattingProfileStore(db)
 
-	httpClientFactory := proxy.NewHTTPClientFactory() // Pass proxyStore if factory needs it
+	resolver, err := proxy.NewResolver(cfg.DNS.Server, cfg.DNS.DoHURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dns configuration: %w", err)
+	}
+	network, err := proxy.NewNetworkOptions(cfg.Network.IPFamily, cfg.Network.BindAddress, cfg.Network.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network configuration: %w", err)
+	}
+	proxyRules, err := proxyStore.ListProxyRules(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("loading proxy rules: %w", err)
+	}
+	httpClientFactory := proxy.NewHTTPClientFactory().WithCookieStore(database.NewCookieStore(db)).WithResolver(resolver).WithNetwork(network).WithDirectFallback(cfg.ProxyHealth.DirectFallback).WithProxyRules(proxyRules).WithNoProxy(append(cfg.Network.NoProxy, proxy.NoProxyFromEnv()...)) // Pass proxyStore if factory needs it
 
-	rssFetcher := rss.NewGoFeedFetcher(httpClientFactory)
+	rssFetcher := rss.NewGoFeedFetcher(httpClientFactory, cfg.UserAgent).WithDialer(httpClientFactory.DialContext).WithFetchCache(cfg.Fetch.CacheTTL).WithMaxBodySize(cfg.MaxResponseBytes).WithRetryPolicy(rss.RetryPolicy{
+		MaxRetries:   cfg.Fetch.MaxRetries,
+		InitialDelay: cfg.Fetch.RetryDelay,
+		MaxDelay:     cfg.Fetch.MaxRetryDelay,
+		Timeout:      cfg.Fetch.Timeout,
+	}).WithHostRateLimit(cfg.Fetch.HostRequestsPerMinute, cfg.Fetch.HostMinInterval, cfg.Fetch.HostLimits).WithDirectFallback(cfg.Fetch.DirectFallback)
+	if cfg.FlareSolverr.URL != "" {
+		rssFetcher.WithFlareSolverr(rss.NewFlareSolverr(cfg.FlareSolverr.URL, cfg.FlareSolverr.MaxTimeout))
+	}
 	msgFormatter := formatter.NewDefaultFormatter()
 	// Pass client factory for proxy support to Telegram client
 	tgNotifier := telegram.NewClient(httpClientFactory) 
+	// Items, alerts and Telegram destinations go through sender; the sandbox
+	// notifier records them instead of sending.
+	var sender alert.Sender = tgNotifier
+	var sandboxNotifier *sandbox.Notifier
+	var sandboxServer *sandbox.Server
+	switch cfg.Notifier {
+	case "", "telegram":
+	case "sandbox":
+		if sandboxNotifier, err = sandbox.NewNotifier(cfg.Sandbox.MaxSends, cfg.Sandbox.File); err != nil {
+			return nil, fmt.Errorf("invalid sandbox configuration: %w", err)
+		}
+		if cfg.Sandbox.ListenAddr != "" {
+			if sandboxServer, err = sandbox.NewServer(sandboxNotifier, cfg.Sandbox.FixturesDir); err != nil {
+				return nil, fmt.Errorf("invalid sandbox configuration: %w", err)
+			}
+		}
+		sender = sandboxNotifier
+		log.Warn().Msg("Sandbox mode: messages are recorded, not sent to Telegram")
+	default:
+		return nil, fmt.Errorf("invalid notifier %q: expected telegram or sandbox", cfg.Notifier)
+	}
 	
-	appScheduler := scheduler.NewFeedScheduler()
+	if cfg.Scheduler.Jitter < 0 || cfg.Scheduler.Jitter > 0.5 {
+		return nil, fmt.Errorf("invalid scheduler.jitter %v: must be between 0 and 0.5", cfg.Scheduler.Jitter)
+	}
+	if cfg.Retention.ProcessedMaxAge < 0 || cfg.Retention.ProcessedKeep < 0 {
+		return nil, fmt.Errorf("invalid retention: processed_max_age and processed_keep must not be negative")
+	}
+	if cfg.Scheduler.DisableAfter < 0 {
+		return nil, fmt.Errorf("invalid scheduler.disable_after %d: must not be negative", cfg.Scheduler.DisableAfter)
+	}
+	appScheduler := scheduler.NewFeedScheduler(cfg.Scheduler)
 
 	// Pass necessary stores to FeedWorker for it to retrieve fresh data
-	worker := NewFeedWorker(db, feedStore, proxyStore, tgBotStore, fmtProfStore, rssFetcher, msgFormatter, tgNotifier, cfg)
+	worker := NewFeedWorker(db, feedStore, proxyStore, tgBotStore, fmtProfStore, rssFetcher, msgFormatter, sender, cfg)
+
+	alerter := alert.NewAlerter(cfg.Admin, tgBotStore, proxyStore, sender)
+	worker.alerter = alerter
+	statsStore := database.NewStatsStore(db)
+	worker.stats = statsStore
+	if err := alert.ValidateDigestConfig(cfg.Admin.Digest); err != nil {
+		return nil, fmt.Errorf("invalid admin.digest configuration: %w", err)
+	}
+	worker.scheduler = appScheduler
+	worker.proxyPicker = httpClientFactory
+	worker.events = events.NewBus()
+	sentryDSN, err := proxy.ResolveSecret(cfg.ErrorReporting.SentryDSN)
+	if err != nil {
+		return nil, fmt.Errorf("resolving error_reporting.sentry_dsn: %w", err)
+	}
+	if worker.reporter, err = errorreport.New(cfg.ErrorReporting, sentryDSN); err != nil {
+		return nil, fmt.Errorf("invalid error_reporting configuration: %w", err)
+	}
+
+	worker.destinations = database.NewDestinationStore(db)
+	worker.filters = database.NewFilterStore(db)
+	worker.digests = database.NewDigestStore(db)
+	if cfg.Outbox.RetryInterval > 0 {
+		worker.outbox = database.NewOutboxStore(db)
+	}
+	webhookClient, err := httpClientFactory.GetClient(nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating webhook HTTP client: %w", err)
+	}
+	worker.notifiers.Register(database.DestinationWebhook, notify.NewWebhookNotifier(webhookClient, cfg.Webhook))
+	worker.notifiers.Register(database.DestinationMattermost, notify.NewMattermostNotifier(webhookClient, cfg.Webhook))
+	pushNotifier := notify.NewPushNotifier(webhookClient, cfg.Webhook)
+	worker.notifiers.Register(database.DestinationNtfy, pushNotifier)
+	worker.notifiers.Register(database.DestinationPushover, pushNotifier)
+	readLater := notify.NewReadLaterNotifier(webhookClient, cfg.Webhook)
+	for _, destType := range []string{database.DestinationPocket, database.DestinationWallabag, database.DestinationReadwise} {
+		worker.notifiers.Register(destType, readLater)
+	}
+	worker.notifiers.Register(database.DestinationTelegram, notify.NewTelegramNotifier(sender, tgBotStore.GetTokenByBotID, func(ctx context.Context, feedID int64) (*database.Proxy, error) {
+		return telegram.DefaultProxy(ctx, proxyStore, httpClientFactory, feedID)
+	}))
+	worker.notifiers.Register(database.DestinationEmail, notify.Unavailable("smtp.host is not configured"))
+	if cfg.SMTP.Host != "" {
+		smtpPassword, err := proxy.ResolveSecret(cfg.SMTP.Password)
+		if err != nil {
+			return nil, fmt.Errorf("resolving smtp.password: %w", err)
+		}
+		emailNotifier, err := notify.NewEmailNotifier(cfg.SMTP, smtpPassword)
+		if err != nil {
+			return nil, fmt.Errorf("invalid smtp configuration: %w", err)
+		}
+		worker.notifiers.Register(database.DestinationEmail, emailNotifier)
+	}
+
+	worker.deliveries = database.NewDeliveryStore(db)
+	if cfg.MQTT.Broker != "" {
+		mqttPassword, err := proxy.ResolveSecret(cfg.MQTT.Password)
+		if err != nil {
+			return nil, fmt.Errorf("resolving mqtt.password: %w", err)
+		}
+		if worker.mqtt, err = notify.NewMQTTPublisher(cfg.MQTT, mqttPassword); err != nil {
+			return nil, fmt.Errorf("invalid mqtt configuration: %w", err)
+		}
+	}
+	var eventStream *events.Stream
+	if cfg.EventStream.NATSURL != "" || cfg.EventStream.KafkaRESTURL != "" {
+		secretRef := cfg.EventStream.NATSToken
+		if cfg.EventStream.KafkaRESTURL != "" {
+			secretRef = cfg.EventStream.KafkaAuthorization
+		}
+		secret, err := proxy.ResolveSecret(secretRef)
+		if err != nil {
+			return nil, fmt.Errorf("resolving event_stream credentials: %w", err)
+		}
+		if eventStream, err = events.NewStream(cfg.EventStream, secret); err != nil {
+			return nil, fmt.Errorf("invalid event_stream configuration: %w", err)
+		}
+	}
+	var outputServer *output.Server
+	if cfg.Output.ListenAddr != "" {
+		outputToken, err := proxy.ResolveSecret(cfg.Output.Token)
+		if err != nil {
+			return nil, fmt.Errorf("resolving output.token: %w", err)
+		}
+		outputServer = output.NewServer(worker.deliveries, cfg.Output, outputToken)
+	}
+
+	if cfg.WebSub.CallbackURL != "" {
+		worker.websub = websub.NewSubscriber(database.NewWebSubStore(db), httpClientFactory, cfg.WebSub.CallbackURL, cfg.WebSub.LeaseSeconds)
+		worker.websub.OnPush(worker.ProcessPushedFeed)
+	}
+
+	var proxyHealth *proxy.HealthChecker
+	if cfg.ProxyHealth.Interval > 0 {
+		proxyHealth = proxy.NewHealthChecker(httpClientFactory, proxyStore, cfg.ProxyHealth.ProbeURL, cfg.ProxyHealth.Interval, cfg.ProxyHealth.FailureThreshold)
+		proxyHealth.OnHealthChange(alerter.ProxyHealthChanged)
+	}
+
+	var apiServer *api.Server
+	if cfg.API.ListenAddr != "" || cfg.API.GRPCListenAddr != "" {
+		tokens := make([]string, 0, len(cfg.API.Tokens))
+		for _, ref := range cfg.API.Tokens {
+			token, err := proxy.ResolveSecret(ref)
+			if err != nil {
+				return nil, fmt.Errorf("resolving api token: %w", err)
+			}
+			tokens = append(tokens, token)
+		}
+		apiServer = api.NewServer(db, tokens, rssFetcher, cfg.DefaultFetchFreq)
+		apiServer.OnFeedCreated(func(f *database.Feed) {
+			if err := appScheduler.Add(f, worker.ProcessFeed); err != nil {
+				log.Error().Err(err).Int64("feed_id", f.ID).Msg("Failed to add feed to scheduler")
+			}
+		})
+		apiServer.OnFetchNow(worker.ProcessFeed)
+		apiServer.SetEventBus(worker.events)
+		if cfg.API.UI {
+			apiServer.EnableUI()
+		}
+	}
+
+	commander := alert.NewCommander(alerter, feedStore, tgNotifier, cfg.DefaultFetchFreq)
+	commander.OnFeedEnabled(func(f *database.Feed) {
+		if appScheduler.Scheduled(f.ID) {
+			return
+		}
+		if err := appScheduler.Add(f, worker.ProcessFeed); err != nil {
+			log.Error().Err(err).Int64("feed_id", f.ID).Msg("Failed to add feed to scheduler")
+		}
+	})
 
 	return &Application{
 		Config:     cfg,
 		DB:         db,
 		Scheduler:  appScheduler,
 		FeedWorker: worker,
+		ProxyHealth: proxyHealth,
+		Digest:      alert.NewDigester(alerter, statsStore),
+		Commands:    commander,
+		API:        apiServer,
+		Output:     outputServer,
+		EventStream: eventStream,
+		Sandbox:     sandboxServer,
 		FeedStore:  feedStore,
 		ProxyStore: proxyStore,
 		TelegramBotStore: tgBotStore,
 		FormattingProfStore: fmtProfStore,
+		fetcher:    rssFetcher,
+		alerter:    alerter,
+		telegram:   tgNotifier,
+		sandbox:    sandboxNotifier,
 	}, nil
 }
+
+// Reload applies the settings that can change while running: the log level,
+// per-host rate limits and spacing, the admin chat, and everything the worker reads per
+// feed run (stale_feed_after, update hints, icon refresh, redirect handling).
+// Other settings, such as the database, DNS, network and proxy health, keep
+// their startup values until a restart. Feeds always come from the database.
+func (app *Application) Reload(cfg *config.AppConfig) {
+	cfg.DryRun = app.Config.DryRun
+	if err := logging.SetLevel(cfg.Log.Level); err != nil {
+		log.Warn().Err(err).Str("configured_level", cfg.Log.Level).Msg("Invalid log level in reloaded config, keeping the current one")
+	}
+	app.fetcher.WithHostRateLimit(cfg.Fetch.HostRequestsPerMinute, cfg.Fetch.HostMinInterval, cfg.Fetch.HostLimits)
+	app.alerter.SetConfig(cfg.Admin)
+	app.FeedWorker.SetConfig(cfg)
+	app.Config = cfg
+	log.Info().Msg("Configuration reloaded")
+}
+
+// reloadConfig re-reads the config file and applies it, keeping the running
+// configuration if the file can't be read.
+func (app *Application) reloadConfig() {
+	notifySystemd(systemd.Reloading())
+	defer notifySystemd(systemd.Ready)
+	cfg, err := config.Reload()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to reload configuration, keeping the current one")
+		return
+	}
+	app.Reload(cfg)
+}
+
+// addReadinessChecks registers what /readyz checks: the database answers,
+// the scheduler runs and, unless in dry-run mode, Telegram accepted a bot.
+func (app *Application) addReadinessChecks() {
+	metrics.AddReadinessCheck("database", func(ctx context.Context) error {
+		return app.DB.PingContext(ctx)
+	})
+	metrics.AddReadinessCheck("scheduler", func(context.Context) error {
+		if !app.Scheduler.Running() {
+			return errors.New("scheduler not running")
+		}
+		return nil
+	})
+	if app.Config.DryRun || app.sandbox != nil {
+		return
+	}
+	metrics.AddReadinessCheck("telegram", func(context.Context) error {
+		if app.telegram.AuthorizedBots() == 0 {
+			return errors.New("no Telegram bot authorized")
+		}
+		return nil
+	})
+}
+
+// authorizeBots checks every stored bot with Telegram at startup, so
+// readiness doesn't wait for the first message to be sent.
+func (app *Application) authorizeBots(ctx context.Context) {
+	bots, err := app.TelegramBotStore.ListBots(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list bots for authorization")
+		return
+	}
+	tgProxy, err := telegram.DefaultProxy(ctx, app.ProxyStore, app.telegram.ProxyPicker(), 0)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load the default Telegram proxy; authorizing bots directly")
+	}
+	for _, bot := range bots {
+		token, err := app.TelegramBotStore.GetTokenByBotID(ctx, bot.ID)
+		if err == nil {
+			err = app.telegram.Authorize(token, tgProxy)
+		}
+		if err != nil {
+			log.Warn().Err(err).Int64("bot_id", bot.ID).Msg("Telegram bot authorization failed")
+		}
+	}
+}
+
+// bootstrap seeds an empty database from bootstrap.file and bootstrap.feeds
+// (the FEEDS env var), so a container can start without any CLI setup. Once
+// the database has feeds, both are ignored.
+func (app *Application) bootstrap(ctx context.Context) error {
+	cfg := app.Config.Bootstrap
+	var docs []*feedsync.Document
+	if cfg.File != "" {
+		doc, err := feedsync.Load(cfg.File)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			log.Debug().Str("file", cfg.File).Msg("No bootstrap file found")
+		case err != nil:
+			return fmt.Errorf("loading %s: %w", cfg.File, err)
+		default:
+			docs = append(docs, doc)
+		}
+	}
+	if cfg.Feeds != "" {
+		doc, err := feedsync.ParseFeedList(cfg.Feeds)
+		if err != nil {
+			return fmt.Errorf("parsing bootstrap feeds: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+
+	plans, err := feedsync.NewSyncer(app.DB, app.Config.DefaultFetchFreq).Seed(ctx, app.Config.DryRun, docs...)
+	if err != nil {
+		return err
+	}
+	if plans == nil {
+		log.Debug().Msg("Database already has feeds, skipping bootstrap")
+		return nil
+	}
+	for _, plan := range plans {
+		log.Info().Bool("dry_run", app.Config.DryRun).Msgf("Seeded empty database:\n%s", plan)
+	}
+	return nil
+}
+
+// notifySystemd tells systemd about a state change when running as a
+// Type=notify service.
+func notifySystemd(state string) {
+	if _, err := systemd.Notify(state); err != nil {
+		log.Warn().Err(err).Msg("Failed to notify systemd")
+	}
+}
 // Run starts the application's main loop (scheduler, metrics server).
 func (app *Application) Run(ctx context.Context) error {
 	log.Info().Msg("Starting application...")
 
 	// Start Prometheus metrics server
+	app.addReadinessChecks()
 	metrics.StartServer(app.Config.MetricsPort)
+	if app.FeedWorker.websub != nil {
+		app.FeedWorker.websub.StartServer(app.Config.WebSub.ListenAddr)
+	}
+	if app.Output != nil {
+		app.Output.StartServer(app.Config.Output.ListenAddr)
+	}
+	if app.EventStream != nil && !app.Config.DryRun {
+		app.EventStream.Start(app.FeedWorker.events)
+	}
+	if app.Sandbox != nil {
+		app.Sandbox.StartServer(app.Config.Sandbox.ListenAddr)
+	}
+	if app.API != nil && app.Config.API.ListenAddr != "" {
+		app.API.StartServer(app.Config.API.ListenAddr)
+	}
+	if app.API != nil && app.Config.API.GRPCListenAddr != "" {
+		app.API.StartGRPCServer(app.Config.API.GRPCListenAddr)
+	}
+
+	if err := app.bootstrap(ctx); err != nil {
+		return fmt.Errorf("bootstrapping database: %w", err)
+	}
 
 	// Load feeds from DB and add to scheduler
 	feeds, err := app.FeedStore.GetEnabledFeeds(ctx)
@@ -109,25 +475,93 @@ func (app *Application) Run(ctx context.Context) error {
 		}
 	}
 	
+	if app.ProxyHealth != nil {
+		app.ProxyHealth.Start(ctx)
+	}
 	app.Scheduler.Start(ctx)
+	app.Digest.Start(ctx)
+	app.FeedWorker.StartDigests(ctx)
+	app.FeedWorker.StartOutbox(ctx)
+	app.FeedWorker.StartRetention(ctx)
+	if !app.Config.DryRun && app.sandbox == nil {
+		go app.authorizeBots(ctx)
+		app.Commands.Start(ctx)
+	}
+
+	// SIGHUP, or a write to the config file with watch_config, reloads the config
+	reloadCh := make(chan struct{}, 1)
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	defer signal.Stop(hupCh)
+	if app.Config.WatchConfig {
+		config.Watch(func() {
+			select {
+			case reloadCh <- struct{}{}:
+			default: // A reload is already pending
+			}
+		})
+	}
 
 	// Graceful shutdown handling
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-	select {
-	case s := <-sigCh:
-		log.Info().Str("signal", s.String()).Msg("Received shutdown signal")
-	case <-ctx.Done(): // If parent context is cancelled
-		log.Info().Msg("Application context done, shutting down")
+	// With WatchdogSec set, systemd restarts the service if the main loop
+	// stops pinging it
+	var watchdog <-chan time.Time
+	if interval := systemd.WatchdogInterval(); interval > 0 {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		watchdog = ticker.C
+	}
+	notifySystemd(systemd.Ready)
+	notifySystemd(systemd.Status(fmt.Sprintf("Watching %d feeds", len(feeds))))
+
+wait:
+	for {
+		select {
+		case <-hupCh:
+			log.Info().Msg("Received SIGHUP, reloading configuration")
+			app.reloadConfig()
+		case <-reloadCh:
+			log.Info().Msg("Config file changed, reloading configuration")
+			app.reloadConfig()
+		case <-watchdog:
+			notifySystemd(systemd.Watchdog)
+		case s := <-sigCh:
+			log.Info().Str("signal", s.String()).Msg("Received shutdown signal")
+			break wait
+		case <-ctx.Done(): // If parent context is cancelled
+			log.Info().Msg("Application context done, shutting down")
+			break wait
+		}
 	}
 
 	// Perform cleanup
+	notifySystemd(systemd.Stopping)
 	log.Info().Msg("Shutting down scheduler...")
-	app.Scheduler.Stop() // This should be blocking or use a waitgroup
+	app.Scheduler.Stop() // Runs it already started are waited for by Drain below
+	if app.ProxyHealth != nil {
+		app.ProxyHealth.Stop()
+	}
+	app.Digest.Stop()
+	app.Commands.Stop()
+
+	log.Info().Dur("timeout", app.Config.ShutdownTimeout).Msg("Waiting for feed runs in progress to finish...")
+	if !app.FeedWorker.Drain(app.Config.ShutdownTimeout) {
+		log.Warn().Dur("timeout", app.Config.ShutdownTimeout).Msg("Shutdown timeout reached, cancelled the remaining feed runs")
+	}
 
-	// TODO: Wait for scheduler to fully stop if it has ongoing tasks.
-	// For simplicity, assuming Stop is relatively quick or non-critical tasks can be interrupted.
+	app.FeedWorker.reporter.Flush()
+	if app.EventStream != nil {
+		app.EventStream.Close(5 * time.Second)
+	}
+	if app.FeedWorker.mqtt != nil {
+		app.FeedWorker.mqtt.Close()
+	}
+	if app.sandbox != nil {
+		app.sandbox.Close()
+	}
 
 	log.Info().Msg("Closing database connection...")
 	if err := app.DB.Close(); err != nil {
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+)
+
+// fetchArticles replaces the content of items with the article extracted
+// from their linked page when the feed's formatting profile has
+// full_article set. Items whose page can't be fetched, or holds no article,
+// keep the content the feed gave them.
+func (w *FeedWorker) fetchArticles(ctx context.Context, run *feedRun, items []*gofeed.Item) {
+	profile := run.feed.FormattingProfile
+	if profile == nil || !profile.ParsedConfig.FullArticle {
+		return
+	}
+	articles, ok := w.fetcher.(interfaces.ArticleFetcher)
+	if !ok {
+		run.l.Warn().Msgf("Fetcher %T can't fetch full articles; sending the feed's content", w.fetcher)
+		return
+	}
+	for _, item := range items {
+		if item.Link == "" || ctx.Err() != nil {
+			continue
+		}
+		opts := run.fetchOpts
+		if !sameHost(item.Link, run.feed.URL) {
+			// The feed's credentials and headers are for its own site.
+			opts.Auth, opts.Headers, opts.Cookies = nil, nil, nil
+		}
+		content, err := articles.FetchArticle(ctx, item.Link, run.proxy, opts)
+		if err != nil {
+			run.l.Warn().Err(err).Str("item_link", item.Link).Msg("Failed to fetch full article; sending the feed's content")
+			continue
+		}
+		item.Content = content
+	}
+}
+
+// sameHost reports whether two URLs are on the same host.
+func sameHost(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	return errA == nil && errB == nil && ua.Host != "" && strings.EqualFold(ua.Host, ub.Host)
+}
@@ -0,0 +1,228 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/events"
+	"github.com/haytac/rss-telegram-bot/internal/metrics"
+	"github.com/haytac/rss-telegram-bot/internal/notify"
+	"github.com/haytac/rss-telegram-bot/internal/rss"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// digestCheckInterval is how often StartDigests looks for digests due.
+const digestCheckInterval = time.Minute
+
+// digestBatch is the digest a feed run sends instead of single items.
+type digestBatch struct {
+	due        time.Time // Scheduled time of the digest
+	lastItemID int64     // Of the newest queued item it includes
+	items      int
+}
+
+// queueForDigest stores the new items of a feed in digest mode for its next
+// digest and records them as processed. It reports whether all of them were
+// queued.
+func (w *FeedWorker) queueForDigest(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed, fetchResult *interfaces.FetchResult, items []*gofeed.Item, latestItemHash string) bool {
+	saveCtx := context.WithoutCancel(ctx)
+	for _, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			l.Error().Err(err).Str("item_title", item.Title).Msg("Failed to encode item for digest")
+			w.recordFailure(currentFeed, "digest_error", err)
+			return false
+		}
+		itemHash := rss.ItemHash(item)
+		queued := &database.DigestItem{FeedID: currentFeed.ID, ItemGUIDHash: itemHash, FeedTitle: fetchResult.Feed.Title, Item: data}
+		if err := w.digests.AddDigestItem(saveCtx, queued); err != nil {
+			l.Error().Err(err).Str("item_title", item.Title).Msg("Failed to queue item for digest")
+			w.recordFailure(currentFeed, "db_error", err)
+			return false
+		}
+		if err := w.feedStore.AddProcessedItem(saveCtx, currentFeed.ID, itemHash); err != nil {
+			l.Error().Err(err).Str("item_guid_hash", itemHash).Msg("Failed to mark item as processed")
+		} else {
+			w.processed.Add(currentFeed.ID, currentFeed.ProcessedEpoch, itemHash)
+		}
+		w.events.Publish(events.Event{Type: events.TypeItemFetched, FeedID: currentFeed.ID, FeedURL: currentFeed.URL, ItemTitle: item.Title, ItemLink: item.Link})
+	}
+
+	hashToStore := currentFeed.LastProcessedItemGUIDHash
+	if latestItemHash != "" {
+		hashToStore = &latestItemHash
+	}
+	if err := w.feedStore.UpdateFeedLastProcessed(saveCtx, currentFeed.ID, hashToStore, fetchResult.NewEtag, fetchResult.NewLastModified, fetchResult.NewBodyHash); err != nil {
+		l.Error().Err(err).Msg("Failed to update feed metadata after queueing items for digest")
+	}
+	l.Info().Int("queued_items", len(items)).Msg("Queued new items for the feed's next digest")
+	w.recordResult(currentFeed, "digest_queued")
+	return true
+}
+
+// StartDigests sends the digests of feeds in digest mode as they fall due,
+// until ctx is done. Digests that fell due while the bot wasn't running are
+// sent when it starts.
+func (w *FeedWorker) StartDigests(ctx context.Context) {
+	if w.digests == nil {
+		return
+	}
+	go func() {
+		w.sendDueDigests(ctx, time.Now())
+		ticker := time.NewTicker(digestCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				w.sendDueDigests(ctx, now)
+			}
+		}
+	}()
+}
+
+// sendDueDigests starts sending each digest due at now that wasn't sent yet.
+func (w *FeedWorker) sendDueDigests(ctx context.Context, now time.Time) {
+	digests, err := w.digests.ListFeedDigests(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load feed digests")
+		return
+	}
+	for _, d := range digests {
+		due, from, err := d.LastDue(now)
+		if err != nil {
+			log.Error().Err(err).Int64("feed_id", d.FeedID).Msg("Invalid feed digest schedule")
+			continue
+		}
+		last := d.CreatedAt
+		if d.LastSentAt != nil {
+			last = *d.LastSentAt
+			from = last
+		}
+		if !due.After(last) {
+			continue
+		}
+		w.SendDigest(d.FeedID, from, due)
+	}
+}
+
+// SendDigest sends the items a feed queued for its digest as one message
+// through the pipeline's send stage, as the digest for the period from from
+// to due. It is skipped while a run of the feed is in progress, to be sent
+// on a later check.
+func (w *FeedWorker) SendDigest(feedID int64, from, due time.Time) {
+	if !w.startRun() {
+		return
+	}
+	unlock, ok := w.tryLockFeed(feedID)
+	if !ok {
+		log.Debug().Int64("feed_id", feedID).Msg("Feed run in progress, postponing its digest")
+		w.runs.Done()
+		return
+	}
+	run := &feedRun{feed: &database.Feed{ID: feedID}, l: log.With().Int64("feed_id", feedID).Logger(), ctx: w.runCtx, unlock: unlock}
+	if !w.prepareDigest(run, from, due) {
+		w.finishRun(run)
+		return
+	}
+	w.pipeline.queueSend(run)
+}
+
+// prepareDigest formats the digest of a feed and prepares its delivery. It
+// reports whether there is a digest to send.
+func (w *FeedWorker) prepareDigest(run *feedRun, from, due time.Time) bool {
+	ctx, cancel := context.WithTimeout(run.ctx, stageTimeout)
+	defer cancel()
+	l := run.l
+
+	currentFeed, err := w.feedStore.GetFeedByID(ctx, run.feed.ID)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to load feed for its digest")
+		return false
+	}
+	if currentFeed == nil || !currentFeed.IsEnabled {
+		return false
+	}
+	run.feed = currentFeed
+	l = l.With().Str("feed_url", currentFeed.URL).Logger()
+	run.l = l
+
+	queued, err := w.digests.ListDigestItems(ctx, currentFeed.ID)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to load items queued for digest")
+		w.recordFailure(currentFeed, "db_error", err)
+		return false
+	}
+	if len(queued) == 0 {
+		l.Debug().Msg("No new items for the feed's digest")
+		if err := w.digests.SetDigestSent(ctx, currentFeed.ID, due); err != nil {
+			l.Error().Err(err).Msg("Failed to record the feed's digest as sent")
+		}
+		return false
+	}
+
+	items := make([]*gofeed.Item, 0, len(queued))
+	var feedTitle string
+	for _, q := range queued {
+		item := &gofeed.Item{}
+		if err := json.Unmarshal(q.Item, item); err != nil {
+			l.Warn().Err(err).Int64("digest_item_id", q.ID).Msg("Skipping undecodable item queued for digest")
+			continue
+		}
+		items = append(items, item)
+		if q.FeedTitle != "" {
+			feedTitle = q.FeedTitle
+		}
+	}
+
+	formatter, ok := w.formatter.(interfaces.DigestFormatter)
+	if !ok {
+		err := fmt.Errorf("formatter %T does not support digests", w.formatter)
+		l.Error().Err(err).Msg("Cannot format digest")
+		w.recordFailure(currentFeed, "config_error", err)
+		return false
+	}
+	parts, err := formatter.FormatDigest(ctx, items, currentFeed, feedTitle, from, due, currentFeed.FormattingProfile)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to format digest")
+		w.recordFailure(currentFeed, "format_error", err)
+		return false
+	}
+	if len(parts) == 0 {
+		w.recordFailure(currentFeed, "format_error", errors.New("digest has no message"))
+		return false
+	}
+
+	d := w.prepareDelivery(ctx, l, currentFeed, feedTitle)
+	if d == nil {
+		return false
+	}
+	digestItem := &gofeed.Item{Title: fmt.Sprintf("Digest of %d items", len(items)), Link: currentFeed.URL, PublishedParsed: &due}
+	d.items = []preparedItem{{item: digestItem, parts: parts, msg: notify.NewMessage(currentFeed, feedTitle, digestItem, parts)}}
+	run.delivery = d
+	run.digest = &digestBatch{due: due, lastItemID: queued[len(queued)-1].ID, items: len(items)}
+	return true
+}
+
+// finishDigest removes the items of a sent digest from the queue.
+func (w *FeedWorker) finishDigest(ctx context.Context, run *feedRun) {
+	l, currentFeed, batch := run.l, run.feed, run.digest
+	if err := w.digests.DeleteDigestItems(ctx, currentFeed.ID, batch.lastItemID); err != nil {
+		l.Error().Err(err).Msg("Failed to remove sent items from the digest queue")
+	}
+	if err := w.digests.SetDigestSent(ctx, currentFeed.ID, batch.due); err != nil {
+		l.Error().Err(err).Msg("Failed to record the feed's digest as sent")
+	}
+	metrics.NewItemsSent.WithLabelValues(currentFeed.URL).Add(float64(batch.items))
+	l.Info().Int("digest_items", batch.items).Msg("Sent feed digest")
+	w.recordResult(currentFeed, "success")
+	run.delivered = true
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/metrics"
+	"github.com/haytac/rss-telegram-bot/internal/telegram"
+	"github.com/rs/zerolog"
+)
+
+// recordFetchFailure counts a failed fetch of a feed, so the scheduler backs
+// off from it. It alerts the admin chat once the feed has failed
+// admin.alerts.feed_failures times in a row, and disables the feed after
+// scheduler.disable_after.
+func (w *FeedWorker) recordFetchFailure(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed, fetchErr error) {
+	failures, err := w.feedStore.RecordFetchFailure(ctx, currentFeed.ID, fetchErr.Error(), time.Now())
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to record failed fetch")
+		return
+	}
+	currentFeed.ConsecutiveFailures = failures
+	metrics.FeedConsecutiveFailures.WithLabelValues(currentFeed.URL).Set(float64(failures))
+	if w.scheduler != nil {
+		w.scheduler.SetFailures(currentFeed.ID, failures)
+	}
+	if w.config().DryRun {
+		return
+	}
+	title := currentFeed.URL
+	if currentFeed.UserTitle != nil && *currentFeed.UserTitle != "" {
+		title = *currentFeed.UserTitle
+	}
+
+	if threshold := w.config().Admin.Alerts.FeedFailures; threshold > 0 && failures == threshold {
+		msg := fmt.Sprintf("🚨 <b>Feed failing</b> #%d %s\nFailed %d fetches in a row: %s\n%s",
+			currentFeed.ID, telegram.EscapeHTML(title), failures, telegram.EscapeHTML(fetchErr.Error()), telegram.EscapeHTML(currentFeed.URL))
+		if err := w.alerter.SendThrottled(ctx, fmt.Sprintf("feed:%d", currentFeed.ID), msg); err != nil {
+			l.Warn().Err(err).Msg("Failed to alert admin chat about failing feed")
+		}
+	}
+
+	limit := w.config().Scheduler.DisableAfter
+	if limit <= 0 || failures < limit {
+		return
+	}
+	if err := w.feedStore.SetFeedEnabled(ctx, currentFeed.ID, false); err != nil {
+		l.Error().Err(err).Msg("Failed to disable failing feed")
+		return
+	}
+	l.Warn().Int("consecutive_failures", failures).Msg("Disabled feed after too many failed fetches")
+	msg := fmt.Sprintf("⛔ <b>Feed disabled</b> #%d %s\nFailed %d fetches in a row; last error: %s\nEnable it again with: feed enable %d",
+		currentFeed.ID, telegram.EscapeHTML(title), failures, telegram.EscapeHTML(fetchErr.Error()), currentFeed.ID)
+	if err := w.alerter.Send(ctx, msg); err != nil {
+		l.Warn().Err(err).Msg("Failed to alert admin chat about disabled feed")
+	}
+}
+
+// clearFetchFailures resets the failure count of a feed fetched successfully.
+func (w *FeedWorker) clearFetchFailures(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed) {
+	if currentFeed.ConsecutiveFailures == 0 {
+		return
+	}
+	if err := w.feedStore.ResetFetchFailures(ctx, currentFeed.ID); err != nil {
+		l.Error().Err(err).Msg("Failed to reset failed fetch count")
+		return
+	}
+	l.Info().Int("consecutive_failures", currentFeed.ConsecutiveFailures).Msg("Feed fetched again after failing")
+	currentFeed.ConsecutiveFailures = 0
+	metrics.FeedConsecutiveFailures.WithLabelValues(currentFeed.URL).Set(0)
+	if w.scheduler != nil {
+		w.scheduler.SetFailures(currentFeed.ID, 0)
+	}
+}
@@ -0,0 +1,191 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/events"
+	"github.com/haytac/rss-telegram-bot/internal/metrics"
+	"github.com/haytac/rss-telegram-bot/internal/notify"
+	"github.com/haytac/rss-telegram-bot/internal/telegram"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// addToOutbox queues an item in the outbox. sendErr is the error its send
+// failed with, or nil for items queued behind others without being tried.
+func (w *FeedWorker) addToOutbox(ctx context.Context, feed *database.Feed, prepared preparedItem, sendErr error) error {
+	data, err := json.Marshal(prepared.msg)
+	if err != nil {
+		return fmt.Errorf("encoding message: %w", err)
+	}
+	m := &database.OutboxMessage{FeedID: feed.ID, ItemGUID: prepared.item.GUID, Message: data, NextAttemptAt: time.Now()}
+	if sendErr != nil {
+		m.Attempts, m.LastError = 1, sendErr.Error()
+		m.NextAttemptAt = m.NextAttemptAt.Add(w.config().Outbox.RetryInterval)
+	}
+	_, err = w.outbox.AddOutboxMessage(ctx, m)
+	return err
+}
+
+// StartOutbox sends the items in the outbox again as their retries fall due,
+// until ctx is done.
+func (w *FeedWorker) StartOutbox(ctx context.Context) {
+	interval := w.config().Outbox.RetryInterval
+	if w.outbox == nil || interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				w.dispatchOutbox(ctx, now)
+			}
+		}
+	}()
+}
+
+// dispatchOutbox sends the outbox items of each feed whose oldest item is due.
+func (w *FeedWorker) dispatchOutbox(ctx context.Context, now time.Time) {
+	feedIDs, err := w.outbox.ListOutboxFeeds(ctx, now)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load feeds with items in the outbox")
+		return
+	}
+	for _, feedID := range feedIDs {
+		w.sendOutbox(feedID, now)
+	}
+}
+
+// sendOutbox sends the outbox items of a feed in order, stopping at the first
+// that fails to reach Telegram again. It is skipped while a run of the feed
+// is in progress, to be tried on a later tick.
+func (w *FeedWorker) sendOutbox(feedID int64, now time.Time) {
+	if !w.startRun() {
+		return
+	}
+	defer w.runs.Done()
+	unlock, ok := w.tryLockFeed(feedID)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(w.runCtx, stageTimeout)
+	defer cancel()
+	saveCtx := context.WithoutCancel(ctx)
+	l := log.With().Int64("feed_id", feedID).Logger()
+
+	messages, err := w.outbox.ListOutboxMessages(ctx, feedID)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to load the feed's outbox")
+		return
+	}
+	currentFeed, err := w.feedStore.GetFeedByID(ctx, feedID)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to load feed to send its outbox")
+		return
+	}
+	if currentFeed == nil {
+		l.Warn().Int("outbox_items", len(messages)).Msg("Dropping outbox items of a deleted feed")
+		for _, m := range messages {
+			w.dropOutbox(saveCtx, l, m)
+		}
+		return
+	}
+	l = l.With().Str("feed_url", currentFeed.URL).Logger()
+
+	var d *delivery
+	for _, m := range messages {
+		msg := &notify.Message{}
+		if err := json.Unmarshal(m.Message, msg); err != nil {
+			l.Error().Err(err).Int64("outbox_id", m.ID).Msg("Dropping undecodable outbox item")
+			w.dropOutbox(saveCtx, l, m)
+			continue
+		}
+		if maxAge := w.config().Outbox.MaxAge; maxAge > 0 && now.Sub(m.CreatedAt) > maxAge {
+			w.expireOutbox(saveCtx, l, currentFeed, m, msg)
+			continue
+		}
+		if !currentFeed.IsEnabled {
+			return // Kept until the feed is enabled again, or they expire
+		}
+		if d == nil {
+			if d = w.prepareDelivery(ctx, l, currentFeed, msg.FeedTitle); d == nil {
+				return
+			}
+		}
+
+		var err error
+		if d.sendTelegram {
+			err = w.sendToChat(ctx, l, currentFeed, d.botTokens, &d.chatTarget, d.threadID, msg.Parts, d.proxy)
+		}
+		if err != nil {
+			metrics.TelegramAPICalls.WithLabelValues(w.notifier.Name(), "send_error").Inc()
+			delay := outboxRetryDelay(w.config().Outbox.RetryInterval, w.config().Outbox.MaxRetryDelay, m.Attempts+1)
+			l.Warn().Err(err).Str("item_title", msg.Title).Int("attempts", m.Attempts+1).Dur("retry_in", delay).Msg("Failed to send outbox item")
+			if errRetry := w.outbox.SetOutboxRetry(saveCtx, m.ID, err.Error(), now.Add(delay)); errRetry != nil {
+				l.Error().Err(errRetry).Int64("outbox_id", m.ID).Msg("Failed to schedule outbox retry")
+			}
+			w.recordFailure(currentFeed, "send_error", err)
+			return
+		}
+		if d.sendTelegram {
+			metrics.TelegramAPICalls.WithLabelValues(w.notifier.Name(), "success").Inc()
+		}
+		w.dropOutbox(saveCtx, l, m)
+		// The chat has the item now, so a destination failing doesn't keep it
+		// in the outbox to be sent to the chat again.
+		if err := w.sendToDestinations(ctx, d.destinations, msg); err != nil {
+			l.Error().Err(err).Str("item_title", msg.Title).Msg("Failed to send outbox item to a feed destination")
+		}
+		w.recordDelivery(saveCtx, currentFeed, msg, m.ItemGUID)
+		if w.mqtt != nil {
+			if err := w.mqtt.Publish(saveCtx, msg); err != nil {
+				l.Warn().Err(err).Str("item_title", msg.Title).Msg("Failed to publish item to MQTT")
+			}
+		}
+		metrics.NewItemsSent.WithLabelValues(currentFeed.URL).Inc()
+		w.events.Publish(events.Event{Type: events.TypeItemSent, FeedID: currentFeed.ID, FeedURL: currentFeed.URL, ItemTitle: msg.Title, ItemLink: msg.Link})
+		l.Info().Str("item_title", msg.Title).Int("attempts", m.Attempts+1).Msg("Sent outbox item")
+	}
+}
+
+// outboxRetryDelay is the wait after an outbox item failed attempts times:
+// interval, doubled after each failed retry up to maxDelay.
+func outboxRetryDelay(interval, maxDelay time.Duration, attempts int) time.Duration {
+	delay := interval
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if maxDelay > 0 && delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return delay
+}
+
+// dropOutbox removes an item from the outbox.
+func (w *FeedWorker) dropOutbox(ctx context.Context, l zerolog.Logger, m *database.OutboxMessage) {
+	if err := w.outbox.DeleteOutboxMessage(ctx, m.ID); err != nil {
+		l.Error().Err(err).Int64("outbox_id", m.ID).Msg("Failed to remove item from the outbox")
+	}
+}
+
+// expireOutbox gives up on an item that stayed in the outbox longer than
+// outbox.max_age, telling the admin chat.
+func (w *FeedWorker) expireOutbox(ctx context.Context, l zerolog.Logger, feed *database.Feed, m *database.OutboxMessage, msg *notify.Message) {
+	l.Error().Str("item_title", msg.Title).Int("attempts", m.Attempts).Str("last_error", m.LastError).Msg("Dropping outbox item not sent in time")
+	w.dropOutbox(ctx, l, m)
+	alert := fmt.Sprintf("📭 <b>Item dropped</b> #%d\n%s\n%s\nNot sent after %d attempts: %s",
+		feed.ID, telegram.EscapeHTML(msg.Title), telegram.EscapeHTML(msg.Link), m.Attempts, telegram.EscapeHTML(m.LastError))
+	if err := w.alerter.Send(ctx, alert); err != nil {
+		l.Warn().Err(err).Msg("Failed to alert admin chat about dropped outbox item")
+	}
+}
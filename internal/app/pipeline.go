@@ -0,0 +1,221 @@
+package app
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/logging"
+	"github.com/haytac/rss-telegram-bot/internal/metrics"
+	"github.com/haytac/rss-telegram-bot/internal/notify"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+	"github.com/rs/zerolog"
+)
+
+// Pipeline stage names, as used by the rssbot_pipeline_queued metric.
+const (
+	stageFetch   = "fetch"
+	stageProcess = "process"
+	stageSend    = "send"
+)
+
+// sendQueueIdle is how long a chat's send queue waits for more runs before
+// its goroutine exits.
+const sendQueueIdle = time.Minute
+
+// feedRun is one run of a feed on its way through the pipeline. It holds the
+// feed's lock until it is finished.
+type feedRun struct {
+	feed       *database.Feed
+	l          zerolog.Logger
+	ctx        context.Context // Parent of the stages' contexts; cancelled by a timed-out drain
+	unlock     func()
+	cleanup    []func()
+	fetched    *interfaces.FetchResult
+	proxy      *database.Proxy         // The fetch's proxy, also used for the items' pages
+	fetchOpts  interfaces.FetchOptions // The fetch's request settings
+	backfilled bool                    // The fetch included the feed's archive backfill
+	delivery   *delivery               // Set by the process stage
+	digest     *digestBatch            // Set for runs sending a digest rather than new items
+	delivered  bool                    // Every new item was delivered
+}
+
+// delivery is what the process stage prepared for sending the new items of
+// a feed.
+type delivery struct {
+	items          []preparedItem
+	latestItemHash string // Of the newest item in the feed, stored when no item is sent
+	destinations   []*database.FeedDestination
+	sendTelegram   bool
+	botTokens      []string
+	proxy          *database.Proxy // For Telegram
+	chatTarget     string
+	threadID       int
+}
+
+// preparedItem is a new item formatted for sending.
+type preparedItem struct {
+	item  *gofeed.Item
+	parts []interfaces.FormattedMessagePart
+	msg   *notify.Message
+}
+
+// pipeline connects the stages of feed runs: a pool of fetch workers, a pool
+// of process workers finding and formatting new items, and a send queue per
+// chat delivering them in order. Stages only wait for the next one when its
+// queue is full, so a chat that is slow to send to holds up only runs for the
+// same chat.
+type pipeline struct {
+	w         *FeedWorker
+	fetch     chan *feedRun
+	process   chan *feedRun
+	sendQueue int
+
+	mu    sync.Mutex
+	chats map[string]*sendQueue // By chat, or feed for feeds without one
+}
+
+// sendQueue is the queue of runs for one chat, served by one goroutine.
+type sendQueue struct {
+	runs    chan *feedRun
+	pending int // Runs queued or being sent; guarded by pipeline.mu
+}
+
+// newPipeline creates the pipeline of w and starts its worker pools.
+func newPipeline(w *FeedWorker, cfg config.PipelineConfig) *pipeline {
+	p := &pipeline{
+		w:         w,
+		fetch:     make(chan *feedRun, max(cfg.FetchQueue, 0)),
+		process:   make(chan *feedRun, max(cfg.ProcessQueue, 0)),
+		sendQueue: max(cfg.SendQueue, 0),
+		chats:     make(map[string]*sendQueue),
+	}
+	for i := 0; i < max(cfg.FetchWorkers, 1); i++ {
+		go p.runStage(stageFetch, p.fetch, func(run *feedRun) {
+			if w.fetchFeed(run) {
+				p.queueProcess(run)
+			} else {
+				w.finishRun(run)
+			}
+		})
+	}
+	for i := 0; i < max(cfg.ProcessWorkers, 1); i++ {
+		go p.runStage(stageProcess, p.process, func(run *feedRun) {
+			if w.processFetched(run) {
+				p.queueSend(run)
+			} else {
+				w.finishRun(run)
+			}
+		})
+	}
+	return p
+}
+
+// queueFetch hands a run to the fetch stage, waiting while its queue is full.
+func (p *pipeline) queueFetch(run *feedRun) {
+	metrics.PipelineQueued.WithLabelValues(stageFetch).Inc()
+	p.fetch <- run
+}
+
+// queueProcess hands a fetched run to the process stage, waiting while its
+// queue is full.
+func (p *pipeline) queueProcess(run *feedRun) {
+	metrics.PipelineQueued.WithLabelValues(stageProcess).Inc()
+	p.process <- run
+}
+
+// queueSend hands a processed run to the send queue of its chat, starting
+// the queue if needed and waiting while it is full.
+func (p *pipeline) queueSend(run *feedRun) {
+	key := "feed:" + strconv.FormatInt(run.feed.ID, 10)
+	if run.delivery.sendTelegram {
+		key = "chat:" + run.delivery.chatTarget
+	}
+	p.mu.Lock()
+	q := p.chats[key]
+	if q == nil {
+		q = &sendQueue{runs: make(chan *feedRun, p.sendQueue)}
+		p.chats[key] = q
+		go p.serveSendQueue(key, q)
+	}
+	q.pending++
+	p.mu.Unlock()
+
+	metrics.PipelineQueued.WithLabelValues(stageSend).Inc()
+	q.runs <- run
+}
+
+// runStage handles the runs arriving on queue one at a time.
+func (p *pipeline) runStage(stage string, queue <-chan *feedRun, handle func(*feedRun)) {
+	for run := range queue {
+		metrics.PipelineQueued.WithLabelValues(stage).Dec()
+		p.handle(run, handle)
+	}
+}
+
+// serveSendQueue sends the runs of one chat in order. It exits, removing the
+// queue, once the queue has been idle for sendQueueIdle.
+func (p *pipeline) serveSendQueue(key string, q *sendQueue) {
+	idle := time.NewTimer(sendQueueIdle)
+	defer idle.Stop()
+	for {
+		select {
+		case run := <-q.runs:
+			metrics.PipelineQueued.WithLabelValues(stageSend).Dec()
+			p.handle(run, func(run *feedRun) {
+				p.w.sendItems(run)
+				p.w.finishRun(run)
+			})
+			p.mu.Lock()
+			q.pending--
+			p.mu.Unlock()
+			idle.Reset(sendQueueIdle)
+		case <-idle.C:
+			p.mu.Lock()
+			if q.pending == 0 {
+				delete(p.chats, key)
+				p.mu.Unlock()
+				return
+			}
+			p.mu.Unlock()
+			idle.Reset(sendQueueIdle)
+		}
+	}
+}
+
+// handle runs one stage of a run, counting it as an active feed worker.
+func (p *pipeline) handle(run *feedRun, handle func(*feedRun)) {
+	metrics.ActiveFeedWorkers.Inc()
+	defer metrics.ActiveFeedWorkers.Dec()
+	defer p.w.reporter.RecoverFeed(run.feed.ID, run.feed.URL)
+	handle(run)
+}
+
+// verboseRun turns on verbose logging for the rest of a run.
+func (w *FeedWorker) verboseRun(run *feedRun) {
+	l, done := logging.Verbose(run.l)
+	run.l = l
+	run.ctx = l.WithContext(run.ctx)
+	run.cleanup = append(run.cleanup, done)
+}
+
+// finishRun ends a feed run, whichever stage it got to, and releases the feed
+// for its next run.
+func (w *FeedWorker) finishRun(run *feedRun) {
+	if run.delivered && run.backfilled {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(run.ctx), 5*time.Second)
+		if err := w.feedStore.ClearBackfill(ctx, run.feed.ID); err != nil {
+			run.l.Error().Err(err).Msg("Failed to mark archive backfill as done")
+		}
+		cancel()
+	}
+	for _, done := range run.cleanup {
+		done()
+	}
+	run.unlock()
+	w.runs.Done()
+}
@@ -0,0 +1,601 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/notify"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// oneItemFetcher serves a feed with a single item named after the feed URL.
+type oneItemFetcher struct{}
+
+func (oneItemFetcher) Fetch(ctx context.Context, url string, etag, lastModified *string, proxy *database.Proxy, opts interfaces.FetchOptions) (*interfaces.FetchResult, error) {
+	return &interfaces.FetchResult{Feed: &gofeed.Feed{Title: url, Items: []*gofeed.Item{{GUID: url + "#1", Title: "Item of " + url, Link: url + "/1"}}}}, nil
+}
+
+type titleFormatter struct{}
+
+func (titleFormatter) FormatItem(ctx context.Context, item *gofeed.Item, feed *database.Feed, profile *database.FormattingProfile) ([]interfaces.FormattedMessagePart, error) {
+	return []interfaces.FormattedMessagePart{{Text: item.Title}}, nil
+}
+
+// blockingNotifier reports sends on sent; sends to the chat "slow" wait for
+// release first.
+type blockingNotifier struct {
+	sent    chan string
+	release chan struct{}
+}
+
+func (n *blockingNotifier) Send(ctx context.Context, botToken, chatID string, parts []interfaces.FormattedMessagePart, proxy *database.Proxy) error {
+	if chatID == "slow" {
+		select {
+		case <-n.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	n.sent <- chatID + ": " + parts[0].Text
+	return nil
+}
+
+func (n *blockingNotifier) Name() string { return "test" }
+
+func TestPipelineSlowChatDoesNotBlockOthers(t *testing.T) {
+	require.NoError(t, database.InitEncryptionKey("pipeline test key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "pipeline.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	feedStore := database.NewFeedStore(db)
+	botStore := database.NewTelegramBotStore(db)
+	botID, err := botStore.CreateBot(ctx, "123:abc", nil)
+	require.NoError(t, err)
+	slowID, err := feedStore.CreateFeed(ctx, &database.Feed{URL: "https://slow.example.com", FrequencySeconds: 300, TelegramChatID: "slow", TelegramBotID: &botID, IsEnabled: true})
+	require.NoError(t, err)
+	fastID, err := feedStore.CreateFeed(ctx, &database.Feed{URL: "https://fast.example.com", FrequencySeconds: 300, TelegramChatID: "fast", TelegramBotID: &botID, IsEnabled: true})
+	require.NoError(t, err)
+
+	notifier := &blockingNotifier{sent: make(chan string, 4), release: make(chan struct{})}
+	// One worker per stage: only the per-chat send queues run in parallel.
+	cfg := &config.AppConfig{Pipeline: config.PipelineConfig{FetchWorkers: 1, ProcessWorkers: 1, SendQueue: 1}}
+	w := NewFeedWorker(db, feedStore, database.NewProxyStore(db), botStore, database.NewFormattingProfileStore(db), oneItemFetcher{}, titleFormatter{}, notifier, cfg)
+	w.destinations = database.NewDestinationStore(db)
+
+	slow, err := feedStore.GetFeedByID(ctx, slowID)
+	require.NoError(t, err)
+	fast, err := feedStore.GetFeedByID(ctx, fastID)
+	require.NoError(t, err)
+	w.ProcessFeed(slow)
+	w.ProcessFeed(fast)
+
+	select {
+	case got := <-notifier.sent:
+		assert.Equal(t, "fast: Item of https://fast.example.com", got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("fast chat was held up by the slow one")
+	}
+	// The slow feed's run is still sending, so its next poll is skipped.
+	w.ProcessFeed(slow)
+
+	close(notifier.release)
+	require.True(t, w.Drain(5*time.Second))
+	assert.Equal(t, "slow: Item of https://slow.example.com", <-notifier.sent)
+	assert.Empty(t, notifier.sent)
+	processed, err := feedStore.ListProcessedItems(ctx, slowID)
+	require.NoError(t, err)
+	assert.Len(t, processed, 1)
+}
+
+func TestDrainTimeoutCancelsRuns(t *testing.T) {
+	require.NoError(t, database.InitEncryptionKey("pipeline test key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "drain.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	feedStore := database.NewFeedStore(db)
+	botStore := database.NewTelegramBotStore(db)
+	botID, err := botStore.CreateBot(ctx, "123:abc", nil)
+	require.NoError(t, err)
+	feedID, err := feedStore.CreateFeed(ctx, &database.Feed{URL: "https://slow.example.com", FrequencySeconds: 300, TelegramChatID: "slow", TelegramBotID: &botID, IsEnabled: true})
+	require.NoError(t, err)
+
+	// Never released: the send only ends when the drain cancels it.
+	notifier := &blockingNotifier{sent: make(chan string, 1), release: make(chan struct{})}
+	w := NewFeedWorker(db, feedStore, database.NewProxyStore(db), botStore, database.NewFormattingProfileStore(db), oneItemFetcher{}, titleFormatter{}, notifier, &config.AppConfig{})
+	w.destinations = database.NewDestinationStore(db)
+
+	feed, err := feedStore.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	w.ProcessFeed(feed)
+
+	start := time.Now()
+	assert.False(t, w.Drain(100*time.Millisecond))
+	assert.Less(t, time.Since(start), drainGrace, "the cancelled run finishes without using up the grace period")
+	assert.Empty(t, notifier.sent)
+	processed, err := feedStore.ListProcessedItems(ctx, feedID)
+	require.NoError(t, err)
+	assert.Empty(t, processed, "the unsent item is left to the next start")
+
+	// Runs after the drain are skipped.
+	w.ProcessFeed(feed)
+	assert.True(t, w.Drain(time.Second))
+}
+
+func TestDrainWaitsForPushedFeed(t *testing.T) {
+	require.NoError(t, database.InitEncryptionKey("pipeline test key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "push.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	feedStore := database.NewFeedStore(db)
+	botStore := database.NewTelegramBotStore(db)
+	botID, err := botStore.CreateBot(ctx, "123:abc", nil)
+	require.NoError(t, err)
+	feedID, err := feedStore.CreateFeed(ctx, &database.Feed{URL: "https://slow.example.com", FrequencySeconds: 300, TelegramChatID: "slow", TelegramBotID: &botID, IsEnabled: true})
+	require.NoError(t, err)
+
+	notifier := &blockingNotifier{sent: make(chan string, 1), release: make(chan struct{})}
+	w := NewFeedWorker(db, feedStore, database.NewProxyStore(db), botStore, database.NewFormattingProfileStore(db), oneItemFetcher{}, titleFormatter{}, notifier, &config.AppConfig{})
+	w.destinations = database.NewDestinationStore(db)
+
+	body := []byte(`<rss version="2.0"><channel><title>Slow</title><item><guid>p1</guid><title>Pushed</title></item></channel></rss>`)
+	require.True(t, w.ProcessPushedFeed(feedID, body))
+
+	drained := make(chan bool)
+	go func() { drained <- w.Drain(5 * time.Second) }()
+	select {
+	case <-drained:
+		t.Fatal("drain did not wait for the pushed feed")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(notifier.release)
+	assert.True(t, <-drained)
+	assert.Equal(t, "slow: Pushed", <-notifier.sent)
+
+	// Pushes after the drain are refused, for the hub to retry.
+	assert.False(t, w.ProcessPushedFeed(feedID, body))
+}
+
+func TestFilteredItemsAreProcessedButNotSent(t *testing.T) {
+	require.NoError(t, database.InitEncryptionKey("pipeline test key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "filters.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	feedStore := database.NewFeedStore(db)
+	botStore := database.NewTelegramBotStore(db)
+	botID, err := botStore.CreateBot(ctx, "123:abc", nil)
+	require.NoError(t, err)
+	feedID, err := feedStore.CreateFeed(ctx, &database.Feed{URL: "https://ads.example.com", FrequencySeconds: 300, TelegramChatID: "chat", TelegramBotID: &botID, IsEnabled: true})
+	require.NoError(t, err)
+	filters := database.NewFilterStore(db)
+	_, err = filters.CreateFilter(ctx, &database.FeedFilter{FeedID: feedID, Action: database.FilterExclude, Pattern: "ads.example.com", Fields: []string{database.FilterFieldTitle}})
+	require.NoError(t, err)
+
+	notifier := &blockingNotifier{sent: make(chan string, 1)}
+	w := NewFeedWorker(db, feedStore, database.NewProxyStore(db), botStore, database.NewFormattingProfileStore(db), oneItemFetcher{}, titleFormatter{}, notifier, &config.AppConfig{})
+	w.destinations = database.NewDestinationStore(db)
+	w.filters = filters
+
+	feed, err := feedStore.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	w.ProcessFeed(feed)
+	require.True(t, w.Drain(5*time.Second))
+	assert.Empty(t, notifier.sent)
+	processed, err := feedStore.ListProcessedItems(ctx, feedID)
+	require.NoError(t, err)
+	assert.Len(t, processed, 1, "filtered items are recorded as processed")
+}
+
+// digestFormatter lists the titles of a digest's items.
+type digestFormatter struct{ titleFormatter }
+
+func (digestFormatter) FormatDigest(ctx context.Context, items []*gofeed.Item, feed *database.Feed, feedTitle string, from, to time.Time, profile *database.FormattingProfile) ([]interfaces.FormattedMessagePart, error) {
+	text := "Digest"
+	for _, item := range items {
+		text += " | " + item.Title
+	}
+	return []interfaces.FormattedMessagePart{{Text: text}}, nil
+}
+
+func TestDigestFeedSendsQueuedItemsTogether(t *testing.T) {
+	require.NoError(t, database.InitEncryptionKey("pipeline test key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "digests.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	feedStore := database.NewFeedStore(db)
+	botStore := database.NewTelegramBotStore(db)
+	botID, err := botStore.CreateBot(ctx, "123:abc", nil)
+	require.NoError(t, err)
+	feedID, err := feedStore.CreateFeed(ctx, &database.Feed{URL: "https://daily.example.com", FrequencySeconds: 300, TelegramChatID: "chat", TelegramBotID: &botID, IsEnabled: true})
+	require.NoError(t, err)
+	digests := database.NewDigestStore(db)
+	require.NoError(t, digests.SetFeedDigest(ctx, &database.FeedDigest{FeedID: feedID, Period: database.DigestDaily, At: "08:00"}))
+
+	notifier := &blockingNotifier{sent: make(chan string, 2)}
+	w := NewFeedWorker(db, feedStore, database.NewProxyStore(db), botStore, database.NewFormattingProfileStore(db), oneItemFetcher{}, digestFormatter{}, notifier, &config.AppConfig{})
+	w.destinations = database.NewDestinationStore(db)
+	w.digests = digests
+
+	feed, err := feedStore.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	w.ProcessFeed(feed)
+	require.Eventually(t, func() bool {
+		items, err := digests.ListDigestItems(ctx, feedID)
+		return err == nil && len(items) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	processed, err := feedStore.ListProcessedItems(ctx, feedID)
+	require.NoError(t, err)
+	assert.Len(t, processed, 1, "queued items are recorded as processed")
+
+	// Nothing is due before the first send time after the digest was set up
+	w.sendDueDigests(ctx, time.Now())
+	w.sendDueDigests(ctx, time.Now().Add(48*time.Hour))
+	require.True(t, w.Drain(5*time.Second))
+	assert.Equal(t, "chat: Digest | Item of https://daily.example.com", <-notifier.sent)
+	assert.Empty(t, notifier.sent)
+
+	items, err := digests.ListDigestItems(ctx, feedID)
+	require.NoError(t, err)
+	assert.Empty(t, items, "sent items leave the queue")
+	d, err := digests.GetFeedDigest(ctx, feedID)
+	require.NoError(t, err)
+	require.NotNil(t, d.LastSentAt)
+}
+
+// flakyNotifier fails sends while down is set.
+type flakyNotifier struct {
+	down atomic.Bool
+	sent chan string
+}
+
+func (n *flakyNotifier) Send(ctx context.Context, botToken, chatID string, parts []interfaces.FormattedMessagePart, proxy *database.Proxy) error {
+	if n.down.Load() {
+		return errors.New("telegram is down")
+	}
+	n.sent <- chatID + ": " + parts[0].Text
+	return nil
+}
+
+func (n *flakyNotifier) Name() string { return "test" }
+
+func TestFailedSendsWaitInOutbox(t *testing.T) {
+	require.NoError(t, database.InitEncryptionKey("pipeline test key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "outbox.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	feedStore := database.NewFeedStore(db)
+	botStore := database.NewTelegramBotStore(db)
+	botID, err := botStore.CreateBot(ctx, "123:abc", nil)
+	require.NoError(t, err)
+	feedID, err := feedStore.CreateFeed(ctx, &database.Feed{URL: "https://down.example.com", FrequencySeconds: 300, TelegramChatID: "chat", TelegramBotID: &botID, IsEnabled: true})
+	require.NoError(t, err)
+
+	notifier := &flakyNotifier{sent: make(chan string, 1)}
+	notifier.down.Store(true)
+	cfg := &config.AppConfig{Outbox: config.OutboxConfig{RetryInterval: time.Minute, MaxAge: time.Hour}}
+	w := NewFeedWorker(db, feedStore, database.NewProxyStore(db), botStore, database.NewFormattingProfileStore(db), oneItemFetcher{}, titleFormatter{}, notifier, cfg)
+	w.destinations = database.NewDestinationStore(db)
+	outbox := database.NewOutboxStore(db)
+	w.outbox = outbox
+
+	feed, err := feedStore.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	w.ProcessFeed(feed)
+	require.Eventually(t, func() bool {
+		messages, err := outbox.ListOutboxMessages(ctx, feedID)
+		return err == nil && len(messages) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	processed, err := feedStore.ListProcessedItems(ctx, feedID)
+	require.NoError(t, err)
+	assert.Len(t, processed, 1, "items in the outbox aren't sent again by later runs")
+
+	// Not due yet, then failing again
+	w.dispatchOutbox(ctx, time.Now())
+	w.dispatchOutbox(ctx, time.Now().Add(2*time.Minute))
+	messages, err := outbox.ListOutboxMessages(ctx, feedID)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, 2, messages[0].Attempts)
+	assert.Equal(t, "telegram is down", messages[0].LastError)
+
+	notifier.down.Store(false)
+	w.dispatchOutbox(ctx, time.Now().Add(10*time.Minute))
+	assert.Equal(t, "chat: Item of https://down.example.com", <-notifier.sent)
+	messages, err = outbox.ListOutboxMessages(ctx, feedID)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+	require.True(t, w.Drain(5*time.Second))
+}
+
+func TestOutboxRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Minute, outboxRetryDelay(time.Minute, 5*time.Minute, 1))
+	assert.Equal(t, 4*time.Minute, outboxRetryDelay(time.Minute, 5*time.Minute, 3))
+	assert.Equal(t, 5*time.Minute, outboxRetryDelay(time.Minute, 5*time.Minute, 10))
+}
+
+// downFetcher fails every fetch.
+type downFetcher struct{}
+
+func (downFetcher) Fetch(ctx context.Context, url string, etag, lastModified *string, proxy *database.Proxy, opts interfaces.FetchOptions) (*interfaces.FetchResult, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestFailingFeedIsDisabled(t *testing.T) {
+	require.NoError(t, database.InitEncryptionKey("pipeline test key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "failing.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	feedStore := database.NewFeedStore(db)
+	botStore := database.NewTelegramBotStore(db)
+	botID, err := botStore.CreateBot(ctx, "123:abc", nil)
+	require.NoError(t, err)
+	feedID, err := feedStore.CreateFeed(ctx, &database.Feed{URL: "https://down.example.com", FrequencySeconds: 300, TelegramChatID: "chat", TelegramBotID: &botID, IsEnabled: true})
+	require.NoError(t, err)
+
+	notifier := &blockingNotifier{sent: make(chan string, 1)}
+	cfg := &config.AppConfig{Scheduler: config.SchedulerConfig{DisableAfter: 2}}
+	w := NewFeedWorker(db, feedStore, database.NewProxyStore(db), botStore, database.NewFormattingProfileStore(db), downFetcher{}, titleFormatter{}, notifier, cfg)
+	w.destinations = database.NewDestinationStore(db)
+
+	failures := func() int {
+		feed, err := feedStore.GetFeedByID(ctx, feedID)
+		require.NoError(t, err)
+		return feed.ConsecutiveFailures
+	}
+	feed, err := feedStore.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	w.ProcessFeed(feed)
+	require.Eventually(t, func() bool { return failures() == 1 }, 5*time.Second, 10*time.Millisecond)
+	feed, err = feedStore.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	assert.True(t, feed.IsEnabled)
+
+	// Runs started while the first is finishing are skipped; runs after the
+	// feed is disabled stop before fetching.
+	require.Eventually(t, func() bool {
+		w.ProcessFeed(feed)
+		return failures() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.True(t, w.Drain(5*time.Second))
+	feed, err = feedStore.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, feed.ConsecutiveFailures)
+	assert.False(t, feed.IsEnabled, "disabled after scheduler.disable_after failures")
+	require.NotNil(t, feed.LastError)
+	assert.Contains(t, *feed.LastError, "connection refused")
+}
+
+// articleFetcher serves oneItemFetcher's feeds and an article for each item,
+// recording the request settings it was asked to use.
+type articleFetcher struct {
+	oneItemFetcher
+	opts chan interfaces.FetchOptions
+}
+
+func (f articleFetcher) FetchArticle(ctx context.Context, pageURL string, proxy *database.Proxy, opts interfaces.FetchOptions) (string, error) {
+	f.opts <- opts
+	return "Article at " + pageURL, nil
+}
+
+// contentFormatter sends an item's content.
+type contentFormatter struct{}
+
+func (contentFormatter) FormatItem(ctx context.Context, item *gofeed.Item, feed *database.Feed, profile *database.FormattingProfile) ([]interfaces.FormattedMessagePart, error) {
+	return []interfaces.FormattedMessagePart{{Text: item.Content}}, nil
+}
+
+func TestFullArticleReplacesContent(t *testing.T) {
+	require.NoError(t, database.InitEncryptionKey("pipeline test key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "article.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	feedStore := database.NewFeedStore(db)
+	botStore := database.NewTelegramBotStore(db)
+	botID, err := botStore.CreateBot(ctx, "123:abc", nil)
+	require.NoError(t, err)
+	profiles := database.NewFormattingProfileStore(db)
+	profile := &database.FormattingProfile{Name: "full", ParsedConfig: database.FormattingProfileConfig{FullArticle: true}}
+	require.NoError(t, profile.MarshalConfig())
+	profileID, err := profiles.CreateProfile(ctx, profile)
+	require.NoError(t, err)
+	feedID, err := feedStore.CreateFeed(ctx, &database.Feed{URL: "https://blog.example.com", FrequencySeconds: 300, TelegramChatID: "chat", TelegramBotID: &botID,
+		FormattingProfileID: &profileID, RequestHeaders: map[string]string{"X-Api-Key": "k"}, IsEnabled: true})
+	require.NoError(t, err)
+
+	notifier := &blockingNotifier{sent: make(chan string, 1)}
+	fetcher := articleFetcher{opts: make(chan interfaces.FetchOptions, 1)}
+	w := NewFeedWorker(db, feedStore, database.NewProxyStore(db), botStore, profiles, fetcher, contentFormatter{}, notifier, &config.AppConfig{})
+	w.destinations = database.NewDestinationStore(db)
+
+	feed, err := feedStore.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	w.ProcessFeed(feed)
+	require.True(t, w.Drain(5*time.Second))
+	assert.Equal(t, "chat: Article at https://blog.example.com/1", <-notifier.sent)
+	assert.Equal(t, "k", (<-fetcher.opts).Headers["X-Api-Key"], "the feed's headers are sent to its own site")
+}
+
+func TestSameHost(t *testing.T) {
+	assert.True(t, sameHost("https://Example.com/post/1", "https://example.com/feed.xml"))
+	assert.False(t, sameHost("https://other.example.com/post", "https://example.com/feed.xml"))
+	assert.False(t, sameHost("/relative", "https://example.com/feed.xml"))
+}
+
+// newItemFetcher serves a feed with a new item on every fetch.
+type newItemFetcher struct{ n *atomic.Int32 }
+
+func (f newItemFetcher) Fetch(ctx context.Context, url string, etag, lastModified *string, proxy *database.Proxy, opts interfaces.FetchOptions) (*interfaces.FetchResult, error) {
+	n := f.n.Add(1)
+	return &interfaces.FetchResult{Feed: &gofeed.Feed{Title: url, Items: []*gofeed.Item{{GUID: fmt.Sprintf("%s#%d", url, n), Title: fmt.Sprintf("Item %d", n)}}}}, nil
+}
+
+// threadNotifier records sends like blockingNotifier, resolves @usernames
+// through resolved and creates forum topics numbered from 1.
+type threadNotifier struct {
+	blockingNotifier
+	resolved map[string]int64
+	topics   atomic.Int32
+}
+
+func (n *threadNotifier) SendToThread(ctx context.Context, botToken, chatID string, threadID int, parts []interfaces.FormattedMessagePart, proxy *database.Proxy) error {
+	if threadID != 0 {
+		chatID = fmt.Sprintf("%s/%d", chatID, threadID)
+	}
+	return n.Send(ctx, botToken, chatID, parts, proxy)
+}
+
+func (n *threadNotifier) NextPoolToken(poolKey string, tokens []string) string { return tokens[0] }
+
+func (n *threadNotifier) ResolvedChatID(chatID string) (int64, bool) {
+	id, ok := n.resolved[chatID]
+	return id, ok
+}
+
+func (n *threadNotifier) CreateForumTopic(ctx context.Context, botToken, chatID, name string, proxy *database.Proxy) (int, error) {
+	return int(n.topics.Add(1)), nil
+}
+
+func TestChangedChatDropsResolvedChatID(t *testing.T) {
+	require.NoError(t, database.InitEncryptionKey("pipeline test key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "chat.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	feedStore := database.NewFeedStore(db)
+	botStore := database.NewTelegramBotStore(db)
+	botID, err := botStore.CreateBot(ctx, "123:abc", nil)
+	require.NoError(t, err)
+	feedID, err := feedStore.CreateFeed(ctx, &database.Feed{URL: "https://news.example.com", FrequencySeconds: 300, TelegramChatID: "@old", TelegramBotID: &botID, IsEnabled: true})
+	require.NoError(t, err)
+
+	notifier := &threadNotifier{blockingNotifier: blockingNotifier{sent: make(chan string, 1)}, resolved: map[string]int64{"@old": -100}}
+	fetcher := newItemFetcher{n: new(atomic.Int32)}
+	run := func() *database.Feed { // A drained worker takes no more runs
+		w := NewFeedWorker(db, feedStore, database.NewProxyStore(db), botStore, database.NewFormattingProfileStore(db), fetcher, titleFormatter{}, notifier, &config.AppConfig{})
+		w.destinations = database.NewDestinationStore(db)
+		feed, err := feedStore.GetFeedByID(ctx, feedID)
+		require.NoError(t, err)
+		w.ProcessFeed(feed)
+		require.True(t, w.Drain(5*time.Second))
+		feed, err = feedStore.GetFeedByID(ctx, feedID)
+		require.NoError(t, err)
+		return feed
+	}
+
+	feed := run()
+	assert.Equal(t, "@old: Item 1", <-notifier.sent)
+	require.NotNil(t, feed.ResolvedChat())
+	assert.EqualValues(t, -100, *feed.ResolvedChat())
+	feed = run()
+	assert.Equal(t, "-100: Item 2", <-notifier.sent, "later sends use the cached ID")
+
+	feed.TelegramChatID = "@new"
+	require.NoError(t, feedStore.UpdateFeed(ctx, feed))
+	feed = run()
+	assert.Equal(t, "@new: Item 3", <-notifier.sent, "the ID cached for the old chat isn't used")
+	assert.Nil(t, feed.ResolvedChatID)
+}
+
+func TestUnstoredForumTopicFailsRun(t *testing.T) {
+	require.NoError(t, database.InitEncryptionKey("pipeline test key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "topic.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	feedStore := database.NewFeedStore(db)
+	botStore := database.NewTelegramBotStore(db)
+	botID, err := botStore.CreateBot(ctx, "123:abc", nil)
+	require.NoError(t, err)
+	feedID, err := feedStore.CreateFeed(ctx, &database.Feed{URL: "https://news.example.com", FrequencySeconds: 300, TelegramChatID: "-100", TelegramBotID: &botID, AutoCreateTopic: true, IsEnabled: true})
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `CREATE TRIGGER fail_thread BEFORE UPDATE OF telegram_thread_id ON feeds BEGIN SELECT RAISE(ABORT, 'disk full'); END`)
+	require.NoError(t, err)
+
+	notifier := &threadNotifier{blockingNotifier: blockingNotifier{sent: make(chan string, 1)}}
+	w := NewFeedWorker(db, feedStore, database.NewProxyStore(db), botStore, database.NewFormattingProfileStore(db), oneItemFetcher{}, titleFormatter{}, notifier, &config.AppConfig{})
+	w.destinations = database.NewDestinationStore(db)
+	feed, err := feedStore.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	w.ProcessFeed(feed)
+	require.True(t, w.Drain(5*time.Second))
+
+	assert.EqualValues(t, 1, notifier.topics.Load())
+	assert.Empty(t, notifier.sent, "nothing is sent without the topic on record")
+	processed, err := feedStore.ListProcessedItems(ctx, feedID)
+	require.NoError(t, err)
+	assert.Empty(t, processed)
+}
+
+func TestFailedDestinationDoesNotResendItem(t *testing.T) {
+	require.NoError(t, database.InitEncryptionKey("pipeline test key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "destinations.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	feedStore := database.NewFeedStore(db)
+	botStore := database.NewTelegramBotStore(db)
+	botID, err := botStore.CreateBot(ctx, "123:abc", nil)
+	require.NoError(t, err)
+	feedID, err := feedStore.CreateFeed(ctx, &database.Feed{URL: "https://news.example.com", FrequencySeconds: 300, TelegramChatID: "-100", TelegramBotID: &botID, IsEnabled: true})
+	require.NoError(t, err)
+	destinations := database.NewDestinationStore(db)
+	for _, destType := range []string{database.DestinationWebhook, database.DestinationNtfy} {
+		_, err := destinations.CreateDestination(ctx, &database.FeedDestination{FeedID: feedID, Type: destType})
+		require.NoError(t, err)
+	}
+
+	notifier := &blockingNotifier{sent: make(chan string, 2)}
+	var pushed atomic.Int32
+	run := func() {
+		w := NewFeedWorker(db, feedStore, database.NewProxyStore(db), botStore, database.NewFormattingProfileStore(db), oneItemFetcher{}, titleFormatter{}, notifier, &config.AppConfig{})
+		w.destinations = destinations
+		w.notifiers.Register(database.DestinationWebhook, notify.Unavailable("webhook down"))
+		w.notifiers.Register(database.DestinationNtfy, notify.NotifierFunc(func(context.Context, *database.FeedDestination, *notify.Message) error {
+			pushed.Add(1)
+			return nil
+		}))
+		feed, err := feedStore.GetFeedByID(ctx, feedID)
+		require.NoError(t, err)
+		w.ProcessFeed(feed)
+		require.True(t, w.Drain(5*time.Second))
+	}
+
+	run()
+	assert.Equal(t, "-100: Item of https://news.example.com", <-notifier.sent)
+	assert.EqualValues(t, 1, pushed.Load(), "the other destinations still get the item")
+	processed, err := feedStore.ListProcessedItems(ctx, feedID)
+	require.NoError(t, err)
+	assert.Len(t, processed, 1)
+
+	run()
+	assert.Empty(t, notifier.sent, "the chat doesn't get the item again")
+	assert.EqualValues(t, 1, pushed.Load())
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"container/list"
+	"sync"
+)
+
+// processedCacheSize is how many item hashes are remembered per feed, enough
+// for the items of all but the largest feeds.
+const processedCacheSize = 2000
+
+// processedCache remembers the hashes of items recently processed for each
+// feed, so polling an unchanged feed needs no processed_items lookups. What
+// it learns is tied to the feed's processed epoch, which the database bumps
+// whenever processed items are deleted (reset, prune or the feed's removal):
+// on a new epoch the feed's entries are dropped.
+type processedCache struct {
+	mu    sync.Mutex
+	feeds map[int64]*processedFeed
+	size  int // Hashes per feed at most
+}
+
+// processedFeed is the least recently used list of a feed's processed hashes.
+type processedFeed struct {
+	epoch  int64
+	order  *list.List               // Hashes, most recently used first
+	hashes map[string]*list.Element // Into order
+}
+
+func newProcessedCache(size int) *processedCache {
+	return &processedCache{feeds: make(map[int64]*processedFeed), size: size}
+}
+
+// feed returns the entries of the feed for epoch, starting over when they
+// belong to an earlier one. c.mu must be held.
+func (c *processedCache) feed(feedID, epoch int64) *processedFeed {
+	f := c.feeds[feedID]
+	if f == nil || f.epoch != epoch {
+		f = &processedFeed{epoch: epoch, order: list.New(), hashes: make(map[string]*list.Element)}
+		c.feeds[feedID] = f
+	}
+	return f
+}
+
+// add records hashes as processed. c.mu must be held.
+func (c *processedCache) add(f *processedFeed, hashes []string) {
+	for _, hash := range hashes {
+		if e, ok := f.hashes[hash]; ok {
+			f.order.MoveToFront(e)
+			continue
+		}
+		f.hashes[hash] = f.order.PushFront(hash)
+		if f.order.Len() > c.size {
+			oldest := f.order.Back()
+			f.order.Remove(oldest)
+			delete(f.hashes, oldest.Value.(string))
+		}
+	}
+}
+
+// FilterUnprocessed returns the hashes not known to be processed, in order.
+// Hashes missing from the cache are checked with lookup, which returns those
+// of them not processed yet; the rest are cached.
+func (c *processedCache) FilterUnprocessed(feedID, epoch int64, hashes []string, lookup func([]string) ([]string, error)) ([]string, error) {
+	c.mu.Lock()
+	f := c.feed(feedID, epoch)
+	var unknown []string
+	for _, hash := range hashes {
+		if e, ok := f.hashes[hash]; ok {
+			f.order.MoveToFront(e)
+		} else {
+			unknown = append(unknown, hash)
+		}
+	}
+	c.mu.Unlock()
+	if len(unknown) == 0 {
+		return nil, nil
+	}
+
+	unprocessed, err := lookup(unknown)
+	if err != nil {
+		return nil, err
+	}
+	isUnprocessed := make(map[string]bool, len(unprocessed))
+	for _, hash := range unprocessed {
+		isUnprocessed[hash] = true
+	}
+	var processed []string
+	for _, hash := range unknown {
+		if !isUnprocessed[hash] {
+			processed = append(processed, hash)
+		}
+	}
+	c.mu.Lock()
+	c.add(c.feed(feedID, epoch), processed)
+	c.mu.Unlock()
+	return unprocessed, nil
+}
+
+// Add records that an item of the feed was processed.
+func (c *processedCache) Add(feedID, epoch int64, hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(c.feed(feedID, epoch), []string{hash})
+}
+
+// Forget drops the entries of a feed, e.g. one that was removed.
+func (c *processedCache) Forget(feedID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.feeds, feedID)
+}
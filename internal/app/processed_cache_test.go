@@ -0,0 +1,57 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessedCache(t *testing.T) {
+	cache := newProcessedCache(3)
+	var lookups [][]string
+	db := map[string]bool{"a": true, "b": true} // Processed in the database
+	lookup := func(hashes []string) ([]string, error) {
+		lookups = append(lookups, hashes)
+		var unprocessed []string
+		for _, h := range hashes {
+			if !db[h] {
+				unprocessed = append(unprocessed, h)
+			}
+		}
+		return unprocessed, nil
+	}
+
+	got, err := cache.FilterUnprocessed(1, 0, []string{"a", "b", "c"}, lookup)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, got)
+	cache.Add(1, 0, "c")
+
+	// Unchanged feed: answered from the cache
+	got, err = cache.FilterUnprocessed(1, 0, []string{"a", "b", "c"}, lookup)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	assert.Len(t, lookups, 1)
+
+	// Beyond the size limit, the least recently used hash is looked up again
+	cache.Add(1, 0, "d")
+	_, err = cache.FilterUnprocessed(1, 0, []string{"a", "b", "c"}, lookup)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, lookups[1])
+
+	// Deleting processed items bumps the epoch, which drops the cached ones
+	delete(db, "a")
+	got, err = cache.FilterUnprocessed(1, 1, []string{"a", "b"}, lookup)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, got)
+	assert.Equal(t, []string{"a", "b"}, lookups[2])
+
+	// Feeds are cached separately
+	_, err = cache.FilterUnprocessed(2, 0, []string{"b"}, lookup)
+	require.NoError(t, err)
+	assert.Len(t, lookups, 4)
+	cache.Forget(2)
+	_, err = cache.FilterUnprocessed(2, 0, []string{"b"}, lookup)
+	require.NoError(t, err)
+	assert.Len(t, lookups, 5)
+}
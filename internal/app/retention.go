@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// seenMarkShare is the share of a feed's processed_max_age after which the
+// items still in the feed are marked seen again. Marking them more often
+// would only add writes; retention only needs them marked well within it.
+const seenMarkShare = 10
+
+// processedRetention is the global retention of processed items.
+func (w *FeedWorker) processedRetention() database.ProcessedRetention {
+	cfg := w.config().Retention
+	return database.ProcessedRetention{MaxAge: cfg.ProcessedMaxAge, Keep: cfg.ProcessedKeep}
+}
+
+// markSeen records that the processed items among hashes, every item in the
+// fetched feed, are still in it, so retention keeps them however old they
+// are. It does so at most once per seenMarkShare of the feed's max age.
+func (w *FeedWorker) markSeen(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed, hashes []string) {
+	maxAge := currentFeed.Retention(w.processedRetention()).MaxAge
+	if maxAge <= 0 || len(hashes) == 0 || w.config().DryRun {
+		return
+	}
+	now := time.Now()
+	if last, ok := w.seenMarked.Load(currentFeed.ID); ok && now.Sub(last.(time.Time)) < maxAge/seenMarkShare {
+		return
+	}
+	if err := w.feedStore.MarkItemsSeen(ctx, currentFeed.ID, hashes, now); err != nil {
+		l.Warn().Err(err).Msg("Failed to mark the feed's items as seen")
+		return
+	}
+	w.seenMarked.Store(currentFeed.ID, now)
+}
+
+// StartRetention prunes processed items every retention.interval until ctx
+// is done.
+func (w *FeedWorker) StartRetention(ctx context.Context) {
+	interval := w.config().Retention.Interval
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				w.pruneProcessed(ctx, now)
+			}
+		}
+	}()
+}
+
+// pruneProcessed deletes the processed items retention lets go as of now.
+func (w *FeedWorker) pruneProcessed(ctx context.Context, now time.Time) {
+	if w.config().DryRun {
+		return
+	}
+	n, err := w.feedStore.PruneAllProcessedItems(ctx, w.processedRetention(), now, false)
+	if err != nil {
+		log.Error().Err(err).Int64("pruned", n).Msg("Failed to prune processed items")
+		return
+	}
+	log.Info().Int64("pruned", n).Msg("Pruned processed items past their retention")
+}
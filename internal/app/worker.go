@@ -1,17 +1,29 @@
 package app
 
 import (
+	"bytes"
 	"context"
-	"crypto/sha256"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
+	"github.com/mmcdole/gofeed"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/haytac/rss-telegram-bot/internal/config"       // Module path
 	"github.com/haytac/rss-telegram-bot/internal/database"    // Module path
 	"github.com/haytac/rss-telegram-bot/internal/metrics"     // Module path
+	"github.com/haytac/rss-telegram-bot/internal/notify"
 	"github.com/haytac/rss-telegram-bot/internal/rss"         // Module path
 	"github.com/haytac/rss-telegram-bot/pkg/interfaces" // Module path
     "github.com/haytac/rss-telegram-bot/internal/telegram" // No alias, so use telegram.Client
+	"github.com/haytac/rss-telegram-bot/internal/alert"
+	"github.com/haytac/rss-telegram-bot/internal/websub"
+	"github.com/haytac/rss-telegram-bot/internal/events"
+	"github.com/haytac/rss-telegram-bot/internal/errorreport"
+	"github.com/haytac/rss-telegram-bot/internal/filter"
 )
 
 // FeedWorker handles fetching and processing a single feed.
@@ -23,10 +35,43 @@ type FeedWorker struct {
 	formattingProfStore  *database.FormattingProfileStore
 	fetcher              interfaces.FeedFetcher
 	formatter            interfaces.Formatter
-	notifier             interfaces.Notifier // This is now the telegram.Client
-	appConfig            *config.AppConfig
+	notifier             interfaces.Notifier // Sends to feeds' own chats; forum topics and bot pools need an interfaces.ThreadNotifier
+	appConfig            atomic.Pointer[config.AppConfig] // Swapped on config reload; read through config()
+	websub               *websub.Subscriber // nil unless WebSub subscriber mode is enabled
+	alerter              *alert.Alerter     // Drops alerts unless an admin chat is configured
+	scheduler            interfaces.Scheduler // Receives feeds' update hints and failure counts; nil disables them
+	proxyPicker          interfaces.ProxyPicker // Rotates feeds through their proxy pools; nil uses a pool's first proxy
+	events               *events.Bus            // Receives processing events for API watchers; nil drops them
+	reporter             *errorreport.Reporter  // Reports panics and persistent failures; nil disables reporting
+	stats                *database.StatsStore   // Counts runs and failures per feed for the admin digest; nil disables counting
+	destinations         *database.DestinationStore // Extra destinations of feeds besides their Telegram chat
+	filters              *database.FilterStore      // Keyword and regex filters of feeds; nil sends every item
+	digests              *database.DigestStore      // Digest schedules and the items waiting for them; nil sends items one by one
+	outbox               *database.OutboxStore      // Items whose Telegram send failed, sent again later; nil leaves them to the next run
+	notifiers            *notify.Registry           // Delivers destinations by type
+	deliveries           *database.DeliveryStore    // Records delivered items for the output feed; nil disables recording
+	mqtt                 *notify.MQTTPublisher      // Publishes delivered items; nil unless mqtt.broker is set
+	processed            *processedCache            // Recently processed items, sparing lookups of unchanged feeds
+	pipeline             *pipeline                  // Stages feed runs pass through
+
+	feedLocks            sync.Map // feed ID -> *sync.Mutex; serializes polled and pushed processing
+	seenMarked           sync.Map // feed ID -> time.Time its items were last marked seen, for retention
+
+	runMu                sync.Mutex         // Guards stopping and runs.Add
+	stopping             bool               // Set by Drain; later runs are skipped
+	runs                 sync.WaitGroup     // Feed runs in progress
+	runCtx               context.Context    // Parent of every feed run
+	cancelRuns           context.CancelFunc // Cancels runs still going when a drain times out
 }
 
+// stageTimeout bounds each pipeline stage of a feed run. Time spent queued
+// for a stage doesn't count.
+const stageTimeout = 5 * time.Minute
+
+// drainGrace is how long runs cancelled by a timed-out drain get to record
+// what they already delivered before the database is closed.
+const drainGrace = 5 * time.Second
+
 // NewFeedWorker creates a new FeedWorker.
 func NewFeedWorker(
 	db *database.DB,
@@ -36,10 +81,10 @@ func NewFeedWorker(
 	fps *database.FormattingProfileStore,
 	fetcher interfaces.FeedFetcher,
 	formatter interfaces.Formatter,
-	notifier interfaces.Notifier, // Changed from telegram.Client to interfaces.Notifier
+	notifier interfaces.Notifier,
 	appCfg *config.AppConfig,
 ) *FeedWorker {
-	return &FeedWorker{
+	w := &FeedWorker{
 		db:                  db,
 		feedStore:           fs,
 		proxyStore:          ps,
@@ -48,19 +93,52 @@ func NewFeedWorker(
 		fetcher:             fetcher,
 		formatter:           formatter,
 		notifier:            notifier,
-		appConfig:           appCfg,
+		notifiers:           notify.NewRegistry(),
+		processed:           newProcessedCache(processedCacheSize),
 	}
+	w.runCtx, w.cancelRuns = context.WithCancel(context.Background())
+	w.appConfig.Store(appCfg)
+	w.pipeline = newPipeline(w, appCfg.Pipeline)
+	return w
 }
 
-// ProcessFeed fetches, formats, and sends updates for a given feed.
-func (w *FeedWorker) ProcessFeed(feedFromScheduler *database.Feed) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+// SetConfig makes feeds processed from now on use cfg.
+func (w *FeedWorker) SetConfig(cfg *config.AppConfig) {
+	w.appConfig.Store(cfg)
+}
 
-	metrics.ActiveFeedWorkers.Inc()
-	defer metrics.ActiveFeedWorkers.Dec()
+func (w *FeedWorker) config() *config.AppConfig {
+	return w.appConfig.Load()
+}
 
+// ProcessFeed queues a run of a feed through the pipeline, which fetches it,
+// formats its new items and sends them. A feed whose previous run is still
+// in the pipeline is skipped.
+func (w *FeedWorker) ProcessFeed(feedFromScheduler *database.Feed) {
+	if !w.startRun() {
+		log.Debug().Int64("feed_id", feedFromScheduler.ID).Msg("Shutting down, skipping feed run")
+		return
+	}
+	unlock, ok := w.tryLockFeed(feedFromScheduler.ID)
+	if !ok {
+		log.Debug().Int64("feed_id", feedFromScheduler.ID).Msg("Previous run of the feed still in progress, skipping feed run")
+		w.runs.Done()
+		return
+	}
 	l := log.With().Int64("feed_id", feedFromScheduler.ID).Str("feed_url", feedFromScheduler.URL).Logger()
+	w.pipeline.queueFetch(&feedRun{feed: feedFromScheduler, l: l, ctx: w.runCtx, unlock: unlock})
+}
+
+// fetchFeed is the fetch stage of a feed run. It reports whether the feed
+// was fetched and the run goes on to the process stage.
+func (w *FeedWorker) fetchFeed(run *feedRun) bool {
+	ctx, cancel := context.WithTimeout(run.ctx, stageTimeout)
+	defer cancel()
+	feedFromScheduler := run.feed
+	l := run.l
+	if ctx.Err() != nil {
+		return false // Queued when a timed-out drain cancelled the runs
+	}
 	l.Info().Msg("Starting to process feed")
 
 	// Reload feed details to get the absolute latest config, including joined Proxy and FormattingProfile.
@@ -68,12 +146,19 @@ func (w *FeedWorker) ProcessFeed(feedFromScheduler *database.Feed) {
 	currentFeed, err := w.feedStore.GetFeedByID(ctx, feedFromScheduler.ID)
 	if err != nil {
 		l.Error().Err(err).Msg("Failed to reload feed details from DB")
-		metrics.FeedsProcessed.WithLabelValues(feedFromScheduler.URL, "db_error").Inc()
-		return
+		w.recordFailure(feedFromScheduler, "db_error", err)
+		return false
 	}
 	if currentFeed == nil || !currentFeed.IsEnabled {
 		l.Info().Msg("Feed no longer exists or is disabled, skipping.")
-		return
+		w.processed.Forget(feedFromScheduler.ID)
+		return false
+	}
+	run.feed = currentFeed
+	if currentFeed.Debug {
+		w.verboseRun(run)
+		l = run.l
+		ctx = l.WithContext(ctx)
 	}
 	
 	// currentFeed.Proxy and currentFeed.FormattingProfile are now populated by GetFeedByID if they exist.
@@ -87,7 +172,10 @@ func (w *FeedWorker) ProcessFeed(feedFromScheduler *database.Feed) {
 	
 		// Determine proxy for RSS fetch
 		rssProxy := currentFeed.Proxy
-		if rssProxy == nil && !w.appConfig.DryRun { // Don't fetch default proxy in dry run if not needed for logic
+		if currentFeed.ProxyPoolID != nil {
+			rssProxy = w.poolProxy(ctx, l, currentFeed)
+		}
+		if rssProxy == nil && !w.config().DryRun { // Don't fetch default proxy in dry run if not needed for logic
 			defaultRSSProxy, errP := w.proxyStore.GetDefaultProxy(ctx, "rss")
 			if errP != nil {
 				l.Warn().Err(errP).Msg("Failed to get default RSS proxy")
@@ -96,72 +184,593 @@ func (w *FeedWorker) ProcessFeed(feedFromScheduler *database.Feed) {
 				rssProxy = defaultRSSProxy
 			}
 		}
+		if rssProxy != nil && currentFeed.ProxyDirectFallback != nil {
+			// The feed's fallback setting wins over the proxy's; copy so the shared proxy is untouched.
+			feedProxy := *rssProxy
+			feedProxy.DirectFallback = currentFeed.ProxyDirectFallback
+			rssProxy = &feedProxy
+		}
 	
-		fetchResult, err := w.fetcher.Fetch(ctx, currentFeed.URL, currentFeed.HTTPEtag, currentFeed.HTTPLastModified, rssProxy)
+		fetchOpts := interfaces.FetchOptionsForFeed(currentFeed)
+		if currentFeed.AuthType != nil {
+			creds, errCreds := w.feedStore.GetFeedCredentials(ctx, currentFeed.ID)
+			if errCreds != nil {
+				l.Error().Err(errCreds).Msg("Failed to retrieve feed credentials")
+				w.recordFailure(currentFeed, "config_error", errCreds)
+				return false
+			}
+			fetchOpts.Auth = creds
+		}
+		var fetchResult *interfaces.FetchResult
+		fetchStart := time.Now()
+		if currentFeed.SourceType == database.FeedSourceScrape {
+			scraper, ok := w.fetcher.(interfaces.ScrapeFetcher)
+			if !ok {
+				err = fmt.Errorf("fetcher %T does not support scrape feeds", w.fetcher)
+			} else {
+				fetchResult, err = scraper.FetchScrape(ctx, currentFeed.URL, currentFeed.ScrapeConfig, currentFeed.HTTPEtag, currentFeed.HTTPLastModified, rssProxy, fetchOpts)
+			}
+		} else if currentFeed.SourceType == database.FeedSourceSitemap {
+			sitemaps, ok := w.fetcher.(interfaces.SitemapFetcher)
+			if !ok {
+				err = fmt.Errorf("fetcher %T does not support sitemap feeds", w.fetcher)
+			} else {
+				fetchResult, err = sitemaps.FetchSitemap(ctx, currentFeed.URL, currentFeed.HTTPEtag, currentFeed.HTTPLastModified, rssProxy, fetchOpts)
+			}
+		} else if currentFeed.SourceType == database.FeedSourceIMAP {
+			mail, ok := w.fetcher.(interfaces.MailFetcher)
+			if !ok {
+				err = fmt.Errorf("fetcher %T does not support IMAP feeds", w.fetcher)
+			} else {
+				fetchResult, err = mail.FetchIMAP(ctx, currentFeed.URL, currentFeed.HTTPEtag, fetchOpts)
+			}
+		} else {
+			fetchResult, err = w.fetcher.Fetch(ctx, currentFeed.URL, currentFeed.HTTPEtag, currentFeed.HTTPLastModified, rssProxy, fetchOpts)
+		}
 		if err != nil {
 		l.Error().Err(err).Msg("Failed to fetch RSS feed")
-		metrics.FeedsProcessed.WithLabelValues(currentFeed.URL, "fetch_error").Inc()
-		return
+		w.recordFailure(currentFeed, "fetch_error", err)
+		if run.ctx.Err() == nil { // Not a fetch cut short by a timed-out drain
+			w.recordFetchFailure(context.WithoutCancel(ctx), l, currentFeed, err)
+		}
+		return false
+	}
+	w.clearFetchFailures(ctx, l, currentFeed)
+	metrics.FetchDuration.WithLabelValues(currentFeed.URL).Observe((time.Since(fetchStart) - fetchResult.ParseDuration).Seconds())
+	if fetchResult.Feed != nil {
+		metrics.ParseDuration.WithLabelValues(currentFeed.URL).Observe(fetchResult.ParseDuration.Seconds())
 	}
 
+	if fetchResult.PermanentURL != "" && fetchResult.PermanentURL != currentFeed.URL {
+		w.handlePermanentRedirect(ctx, l, currentFeed, fetchResult.PermanentURL)
+	}
+
+	if w.websub != nil && !w.config().DryRun && (currentFeed.SourceType == "" || currentFeed.SourceType == database.FeedSourceRSS) {
+		if errSub := w.websub.Ensure(ctx, currentFeed.ID, fetchResult.HubURL, fetchResult.TopicURL, rssProxy); errSub != nil {
+			l.Warn().Err(errSub).Str("hub_url", fetchResult.HubURL).Msg("Failed to subscribe to WebSub hub; continuing to poll")
+		}
+	}
+
+	w.checkFreshness(ctx, l, currentFeed, fetchResult.Feed)
+
 	// ... (rest of the fetchResult handling, 304, etc. remains similar) ...
 	if fetchResult.Feed == nil { 
 		l.Info().Msg("Feed content not modified")
 		metrics.HTTPCacheEvents.WithLabelValues(currentFeed.URL, "not_modified").Inc()
-		if err := w.feedStore.UpdateFeedLastProcessed(ctx, currentFeed.ID, currentFeed.LastProcessedItemGUIDHash, currentFeed.HTTPEtag, currentFeed.HTTPLastModified); err != nil {
+		if err := w.feedStore.UpdateFeedLastProcessed(ctx, currentFeed.ID, currentFeed.LastProcessedItemGUIDHash, currentFeed.HTTPEtag, currentFeed.HTTPLastModified, currentFeed.HTTPBodyHash); err != nil {
 			l.Error().Err(err).Msg("Failed to update feed last fetched time after 304")
 		}
-		metrics.FeedsProcessed.WithLabelValues(currentFeed.URL, "not_modified").Inc()
-		return
+		w.recordResult(currentFeed, "not_modified")
+		return false
 	}
 	metrics.HTTPCacheEvents.WithLabelValues(currentFeed.URL, "fetched").Inc()
 
+	w.applyUpdateHint(ctx, l, currentFeed, fetchResult.Feed)
+	w.refreshIcon(ctx, l, currentFeed, fetchResult.Feed, rssProxy, fetchOpts)
+	run.backfilled = w.addBackfill(ctx, l, currentFeed, fetchResult.Feed, rssProxy, fetchOpts)
+	run.fetched = fetchResult
+	run.proxy, run.fetchOpts = rssProxy, fetchOpts
+	return true
+}
+
+// poolProxy picks the proxy for this fetch from the feed's proxy pool. It
+// returns nil when the pool can't be loaded or is empty, so the default RSS
+// proxy is used instead.
+func (w *FeedWorker) poolProxy(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed) *database.Proxy {
+	pool, err := w.proxyStore.GetPoolByID(ctx, *currentFeed.ProxyPoolID)
+	if err != nil {
+		l.Error().Err(err).Int64("proxy_pool_id", *currentFeed.ProxyPoolID).Msg("Failed to load proxy pool")
+		return nil
+	}
+	if pool == nil || len(pool.Proxies) == 0 {
+		l.Warn().Int64("proxy_pool_id", *currentFeed.ProxyPoolID).Msg("Proxy pool is missing or empty; using the default RSS proxy")
+		return nil
+	}
+	p := pool.Proxies[0]
+	if w.proxyPicker != nil {
+		if p = w.proxyPicker.PoolProxy(pool, currentFeed.ID); p == nil {
+			l.Warn().Str("proxy_pool", pool.Name).Msg("No healthy proxy in pool; using the default RSS proxy")
+			return nil
+		}
+	}
+	l.Debug().Str("proxy_pool", pool.Name).Str("strategy", pool.Strategy).Str("proxy_name", p.Name).Msg("Using proxy from pool")
+	return p
+}
 
-	isItemProcessed := func(itemGUIDHash string) (bool, error) {
-		return w.feedStore.IsItemProcessed(ctx, currentFeed.ID, itemGUIDHash)
+// addBackfill appends the items of a pending archive backfill (feed add
+// --backfill) to fetched, walking its RFC 5005 prev-archive pages. It reports
+// whether the walk completed, so the backfill can be cleared once delivered;
+// after a failed page the items gathered so far are still delivered and the
+// walk is retried on the next poll, already-sent items being skipped.
+func (w *FeedWorker) addBackfill(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed, fetched *gofeed.Feed, proxy *database.Proxy, opts interfaces.FetchOptions) bool {
+	if currentFeed.BackfillLimit == nil {
+		return false
 	}
-	newItems, latestItemInFeedHash, err := rss.GetNewItems(fetchResult.Feed, isItemProcessed)
+	archives, ok := w.fetcher.(interfaces.ArchiveFetcher)
+	if !ok || (currentFeed.SourceType != "" && currentFeed.SourceType != database.FeedSourceRSS) {
+		l.Warn().Msg("Archive backfill is only supported for RSS/Atom feeds; dropping it")
+		return true
+	}
+	var maxAge time.Duration
+	if currentFeed.BackfillMaxAgeSeconds != nil {
+		maxAge = time.Duration(*currentFeed.BackfillMaxAgeSeconds) * time.Second
+	}
+	items, err := archives.FetchArchives(ctx, fetched, currentFeed.URL, *currentFeed.BackfillLimit, maxAge, proxy, opts)
+	fetched.Items = append(fetched.Items, items...)
 	if err != nil {
-		l.Error().Err(err).Msg("Failed to identify new items")
-		metrics.FeedsProcessed.WithLabelValues(currentFeed.URL, "filter_error").Inc()
+		l.Warn().Err(err).Int("archived_items", len(items)).Msg("Archive backfill incomplete; retrying next poll")
+		return false
+	}
+	if rss.PrevArchiveURL(fetched, currentFeed.URL) == "" {
+		l.Info().Msg("Feed has no prev-archive link; nothing to backfill")
+	} else {
+		l.Info().Int("archived_items", len(items)).Msg("Backfilling items from feed archives")
+	}
+	return true
+}
+
+// ProcessPushedFeed delivers new items from a feed document pushed by a WebSub
+// hub. Thin pings without a parseable body fall back to a regular fetch. The
+// run is registered with Drain before ProcessPushedFeed returns and goes on in
+// the background; it reports false, skipping the push, once shutdown has begun.
+func (w *FeedWorker) ProcessPushedFeed(feedID int64, body []byte) bool {
+	if !w.startRun() {
+		log.Debug().Int64("feed_id", feedID).Msg("Shutting down, skipping pushed feed")
+		return false
+	}
+	go w.processPushedFeed(feedID, body)
+	return true
+}
+
+// processPushedFeed is the part of ProcessPushedFeed run in the background.
+func (w *FeedWorker) processPushedFeed(feedID int64, body []byte) {
+	queued := false
+	defer func() {
+		if !queued {
+			w.runs.Done()
+		}
+	}()
+	ctx, cancel := context.WithTimeout(w.runCtx, stageTimeout)
+	defer cancel()
+
+	defer w.reporter.RecoverFeed(feedID, "")
+
+	l := log.With().Int64("feed_id", feedID).Str("source", "websub").Logger()
+
+	currentFeed, err := w.feedStore.GetFeedByID(ctx, feedID)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to load pushed feed from DB")
+		return
+	}
+	if currentFeed == nil || !currentFeed.IsEnabled {
+		l.Info().Msg("Pushed feed no longer exists or is disabled, ignoring.")
+		return
+	}
+
+	parseStart := time.Now()
+	parsed, err := rss.ParseFeed(bytes.NewReader(body))
+	if err == nil {
+		metrics.ParseDuration.WithLabelValues(currentFeed.URL).Observe(time.Since(parseStart).Seconds())
+	}
+	if err != nil || len(parsed.Items) == 0 {
+		l.Debug().Err(err).Msg("Push carried no usable content, fetching feed instead")
+		w.ProcessFeed(currentFeed)
+		return
+	}
+
+	// Unlike polls, pushes wait for a run in progress: their content would be lost.
+	unlock := w.lockFeed(feedID)
+	run := &feedRun{feed: currentFeed, l: l.With().Str("feed_url", currentFeed.URL).Logger(), ctx: w.runCtx, unlock: unlock,
+		proxy: currentFeed.Proxy, fetchOpts: interfaces.FetchOptionsForFeed(currentFeed)}
+	if currentFeed.Debug {
+		w.verboseRun(run)
+		ctx = run.l.WithContext(ctx)
+	}
+	run.l.Info().Int("items", len(parsed.Items)).Msg("Processing pushed feed content")
+	w.checkFreshness(ctx, run.l, currentFeed, parsed)
+	// Pushes don't carry our conditional request validators; keep the stored ones.
+	run.fetched = &interfaces.FetchResult{
+		Feed:            parsed,
+		NewEtag:         currentFeed.HTTPEtag,
+		NewLastModified: currentFeed.HTTPLastModified,
+		NewBodyHash:     currentFeed.HTTPBodyHash,
+	}
+	queued = true
+	w.pipeline.queueProcess(run)
+}
+
+// handlePermanentRedirect reports a feed that has moved for good and, when
+// update_redirected_feed_urls is enabled, stores the new URL so later fetches
+// skip the redirect chain.
+func (w *FeedWorker) handlePermanentRedirect(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed, newURL string) {
+	l = l.With().Str("new_url", newURL).Logger()
+	if !w.config().UpdateRedirectedFeedURLs || w.config().DryRun {
+		l.Warn().Msg("Feed is permanently redirected; update its URL or enable update_redirected_feed_urls")
+		return
+	}
+	if err := w.feedStore.SetFeedURL(ctx, currentFeed.ID, newURL); err != nil {
+		l.Error().Err(err).Msg("Failed to update permanently redirected feed URL")
+		return
+	}
+	l.Warn().Str("old_url", currentFeed.URL).Msg("Feed permanently redirected; stored URL updated")
+	msg := fmt.Sprintf("↪️ <b>Feed moved</b> #%d\n%s\n→ %s", currentFeed.ID, telegram.EscapeHTML(currentFeed.URL), telegram.EscapeHTML(newURL))
+	if err := w.alerter.Send(ctx, msg); err != nil {
+		l.Warn().Err(err).Msg("Failed to send feed URL change alert")
+	}
+	currentFeed.URL = newURL
+}
+
+// applyUpdateHint stores the update interval fetched declares (<ttl> or
+// sy:updatePeriod, capped at fetch.max_update_hint) and hands it to the
+// scheduler, which never polls the feed more often than that.
+func (w *FeedWorker) applyUpdateHint(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed, fetched *gofeed.Feed) {
+	var hint time.Duration
+	if w.config().Fetch.RespectUpdateHints {
+		hint = rss.UpdateInterval(fetched)
+		if limit := w.config().Fetch.MaxUpdateHint; limit > 0 && hint > limit {
+			hint = limit
+		}
+	}
+	var seconds *int
+	if hint >= time.Second {
+		secs := int(hint / time.Second)
+		seconds = &secs
+	}
+	previous := currentFeed.UpdateHintSeconds
+	if (seconds == nil && previous == nil) || (seconds != nil && previous != nil && *seconds == *previous) {
+		return
+	}
+	if err := w.feedStore.SetUpdateHint(ctx, currentFeed.ID, seconds); err != nil {
+		l.Warn().Err(err).Msg("Failed to store feed update hint")
+		return
+	}
+	currentFeed.UpdateHintSeconds = seconds
+	if w.scheduler != nil {
+		w.scheduler.SetUpdateHint(currentFeed.ID, hint)
+	}
+
+	configured := time.Duration(currentFeed.FrequencySeconds) * time.Second
+	if currentFeed.PollInterval() > configured {
+		l.Info().Dur("declared_interval", hint).Dur("configured_interval", configured).Msg("Feed declares a longer update interval; polling it less often")
+	} else if previous != nil && time.Duration(*previous)*time.Second > configured {
+		l.Info().Dur("configured_interval", configured).Msg("Feed no longer declares a longer update interval; polling at the configured frequency")
+	}
+}
+
+// refreshIcon looks up the favicon of the feed's site when the last lookup is
+// older than fetch.icon_refresh. Failed lookups are recorded too, so sites
+// without an icon aren't asked again on every poll.
+func (w *FeedWorker) refreshIcon(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed, fetched *gofeed.Feed, proxy *database.Proxy, opts interfaces.FetchOptions) {
+	refresh := w.config().Fetch.IconRefresh
+	if refresh <= 0 || (currentFeed.IconCheckedAt != nil && time.Since(*currentFeed.IconCheckedAt) < refresh) {
+		return
+	}
+	icons, ok := w.fetcher.(interfaces.IconFetcher)
+	if !ok || currentFeed.SourceType == database.FeedSourceIMAP {
+		return
+	}
+	icon, err := icons.FetchIcon(ctx, fetched, currentFeed.URL, proxy, opts)
+	if err != nil {
+		l.Debug().Err(err).Msg("No site icon found for feed")
+	} else {
+		l.Debug().Str("icon_url", icon.URL).Int("bytes", len(icon.Data)).Msg("Fetched site icon for feed")
+	}
+	now := time.Now()
+	if err := w.feedStore.SetFeedIcon(ctx, currentFeed.ID, icon, now); err != nil {
+		l.Warn().Err(err).Msg("Failed to store feed icon")
+		return
+	}
+	currentFeed.IconCheckedAt = &now
+}
+
+// checkFreshness records the newest item time seen in fetched (which may be nil
+// for a 304) and alerts the admin chat once when the feed has published nothing
+// for longer than its stale threshold.
+func (w *FeedWorker) checkFreshness(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed, fetched *gofeed.Feed) {
+	if newest := rss.NewestItemTime(fetched); newest != nil && (currentFeed.NewestItemAt == nil || newest.After(*currentFeed.NewestItemAt)) {
+		if err := w.feedStore.SetNewestItemAt(ctx, currentFeed.ID, *newest); err != nil {
+			l.Warn().Err(err).Msg("Failed to record newest item time")
+		} else {
+			if currentFeed.StaleAlertedAt != nil {
+				l.Info().Time("newest_item_at", *newest).Msg("Stale feed is publishing again")
+			}
+			currentFeed.NewestItemAt, currentFeed.StaleAlertedAt = newest, nil
+		}
+	}
+
+	threshold := w.config().StaleFeedAfter
+	if currentFeed.StaleAfterSeconds != nil {
+		threshold = time.Duration(*currentFeed.StaleAfterSeconds) * time.Second
+	}
+	lastActivity := currentFeed.CreatedAt
+	if currentFeed.NewestItemAt != nil {
+		lastActivity = *currentFeed.NewestItemAt
+		metrics.FeedNewestItemAge.WithLabelValues(currentFeed.URL).Set(time.Since(lastActivity).Seconds())
+	}
+	if threshold <= 0 || time.Since(lastActivity) < threshold {
+		metrics.FeedStale.WithLabelValues(currentFeed.URL).Set(0)
+		return
+	}
+	metrics.FeedStale.WithLabelValues(currentFeed.URL).Set(1)
+	if currentFeed.StaleAlertedAt != nil || !w.alerter.Enabled() || w.config().DryRun {
+		return
+	}
+
+	title := currentFeed.URL
+	if currentFeed.UserTitle != nil && *currentFeed.UserTitle != "" {
+		title = *currentFeed.UserTitle
+	}
+	msg := fmt.Sprintf("⚠️ <b>Stale feed</b> #%d %s\nNo new items since %s (threshold %s).\n%s",
+		currentFeed.ID, telegram.EscapeHTML(title), lastActivity.UTC().Format("2006-01-02 15:04 MST"), threshold, telegram.EscapeHTML(currentFeed.URL))
+	if err := w.alerter.Send(ctx, msg); err != nil {
+		l.Warn().Err(err).Msg("Failed to send stale feed alert")
+		return
+	}
+	now := time.Now()
+	if err := w.feedStore.MarkStaleAlerted(ctx, currentFeed.ID, now); err != nil {
+		l.Warn().Err(err).Msg("Failed to record stale feed alert")
+	}
+	currentFeed.StaleAlertedAt = &now
+	l.Warn().Time("last_activity", lastActivity).Dur("threshold", threshold).Msg("Feed is stale, admin alerted")
+}
+
+// recordResult counts the outcome of a feed run that didn't fail and
+// publishes it as an event.
+func (w *FeedWorker) recordResult(feed *database.Feed, result string) {
+	metrics.FeedsProcessed.WithLabelValues(feed.URL, result).Inc()
+	w.events.Publish(events.Event{Type: events.TypeFeedProcessed, FeedID: feed.ID, FeedURL: feed.URL, Result: result})
+	w.reporter.FeedSucceeded(feed.ID)
+	w.recordStats(feed, nil)
+}
+
+// recordFailure counts a failed feed run, publishes it as an event and hands
+// err to the error reporter.
+func (w *FeedWorker) recordFailure(feed *database.Feed, result string, err error) {
+	metrics.FeedsProcessed.WithLabelValues(feed.URL, result).Inc()
+	w.events.Publish(events.Event{Type: events.TypeFeedProcessed, FeedID: feed.ID, FeedURL: feed.URL, Result: result, Error: err.Error()})
+	w.reporter.FeedFailed(feed.ID, feed.URL, result, err)
+	w.recordStats(feed, err)
+}
+
+// recordStats adds a run, failed when err is not nil, to the feed's hourly
+// statistics.
+func (w *FeedWorker) recordStats(feed *database.Feed, err error) {
+	if w.stats == nil {
 		return
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if statsErr := w.stats.RecordRun(ctx, feed.ID, time.Now(), err); statsErr != nil {
+		log.Warn().Err(statsErr).Int64("feed_id", feed.ID).Msg("Failed to record feed statistics")
+	}
+}
+
+// startRun registers a feed run with Drain. It returns false once shutdown
+// has begun, in which case the run must be skipped.
+func (w *FeedWorker) startRun() bool {
+	w.runMu.Lock()
+	defer w.runMu.Unlock()
+	if w.stopping {
+		return false
+	}
+	w.runs.Add(1)
+	return true
+}
+
+// Drain stops new feed runs and waits up to timeout for those in progress,
+// wherever they are in the pipeline, to finish sending and record what they
+// delivered. Runs still going after that
+// are cancelled and get drainGrace to record the items they already sent. It
+// reports whether every run finished within timeout.
+func (w *FeedWorker) Drain(timeout time.Duration) bool {
+	w.runMu.Lock()
+	w.stopping = true
+	w.runMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.runs.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+	}
+	w.cancelRuns()
+	select {
+	case <-done:
+	case <-time.After(drainGrace):
+	}
+	return false
+}
+
+// lockFeed serializes processing of one feed and returns the unlock function.
+func (w *FeedWorker) lockFeed(feedID int64) func() {
+	mu, _ := w.feedLocks.LoadOrStore(feedID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// tryLockFeed is lockFeed for runs that are skipped rather than wait: it
+// reports false when the feed is locked.
+func (w *FeedWorker) tryLockFeed(feedID int64) (func(), bool) {
+	mu, _ := w.feedLocks.LoadOrStore(feedID, &sync.Mutex{})
+	if !mu.(*sync.Mutex).TryLock() {
+		return nil, false
+	}
+	return mu.(*sync.Mutex).Unlock, true
+}
+
+// processFetched is the process stage of a feed run: it finds the new items
+// of the fetched feed, prepares their delivery and formats them. It reports
+// whether there are items for the send stage; otherwise the run is done.
+func (w *FeedWorker) processFetched(run *feedRun) bool {
+	ctx, cancel := context.WithTimeout(run.ctx, stageTimeout)
+	defer cancel()
+	l, currentFeed, fetchResult := run.l, run.feed, run.fetched
+
+	lookup := func(itemGUIDHashes []string) ([]string, error) {
+		return w.feedStore.FilterUnprocessedHashes(ctx, currentFeed.ID, itemGUIDHashes)
+	}
+	var inFeed []string // Hashes of every item in the feed
+	filterUnprocessed := func(itemGUIDHashes []string) ([]string, error) {
+		inFeed = itemGUIDHashes
+		return w.processed.FilterUnprocessed(currentFeed.ID, currentFeed.ProcessedEpoch, itemGUIDHashes, lookup)
+	}
+	newItems, latestItemInFeedHash, err := rss.GetNewItems(fetchResult.Feed, filterUnprocessed)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to identify new items")
+		w.recordFailure(currentFeed, "filter_error", err)
+		return false
+	}
+	w.markSeen(ctx, l, currentFeed, inFeed)
+
+	if len(newItems) > 0 && fetchResult.EnrichItems != nil {
+		fetchResult.EnrichItems(ctx, newItems)
+	}
+	if len(newItems) > 0 {
+		if newItems, err = w.filterItems(ctx, l, currentFeed, newItems); err != nil {
+			l.Error().Err(err).Msg("Failed to apply feed filters")
+			w.recordFailure(currentFeed, "config_error", err)
+			return false
+		}
+	}
 
 	if len(newItems) == 0 {
 		l.Info().Msg("No new items found in feed")
 		var hashToStore *string
 		if latestItemInFeedHash != "" { hashToStore = &latestItemInFeedHash } else { hashToStore = currentFeed.LastProcessedItemGUIDHash }
-		if err := w.feedStore.UpdateFeedLastProcessed(ctx, currentFeed.ID, hashToStore, fetchResult.NewEtag, fetchResult.NewLastModified); err != nil {
+		if err := w.feedStore.UpdateFeedLastProcessed(ctx, currentFeed.ID, hashToStore, fetchResult.NewEtag, fetchResult.NewLastModified, fetchResult.NewBodyHash); err != nil {
 			l.Error().Err(err).Msg("Failed to update feed metadata after no new items")
 		}
-		metrics.FeedsProcessed.WithLabelValues(currentFeed.URL, "no_new_items").Inc()
-		return
+		w.recordResult(currentFeed, "no_new_items")
+		run.delivered = true
+		return false
 	}
 	l.Info().Int("new_items_count", len(newItems)).Msg("New items found")
 
+	if w.digests != nil {
+		digest, err := w.digests.GetFeedDigest(ctx, currentFeed.ID)
+		if err != nil {
+			l.Error().Err(err).Msg("Failed to load feed digest settings")
+			w.recordFailure(currentFeed, "db_error", err)
+			return false
+		}
+		if digest != nil {
+			run.delivered = w.queueForDigest(ctx, l, currentFeed, fetchResult, newItems, latestItemInFeedHash)
+			return false
+		}
+	}
+
+	d := w.prepareDelivery(ctx, l, currentFeed, fetchResult.Feed.Title)
+	if d == nil {
+		return false
+	}
+	d.latestItemHash = latestItemInFeedHash
+	w.fetchArticles(ctx, run, newItems)
+	for _, item := range newItems {
+		itemCtx := l.With().Str("item_title", Truncate(item.Title, 50)).Str("item_link", item.Link).Logger().WithContext(ctx)
+		w.events.Publish(events.Event{Type: events.TypeItemFetched, FeedID: currentFeed.ID, FeedURL: currentFeed.URL, ItemTitle: item.Title, ItemLink: item.Link})
+		
+		// currentFeed.FormattingProfile is already populated
+		formatStart := time.Now()
+		formattedParts, err := w.formatter.FormatItem(itemCtx, item, currentFeed, currentFeed.FormattingProfile)
+		metrics.FormatDuration.WithLabelValues(currentFeed.URL).Observe(time.Since(formatStart).Seconds())
+		if err != nil {
+			l.Error().Err(err).Str("item_title", item.Title).Msg("Failed to format item")
+			continue
+		}
+		l.Debug().Str("item_title", item.Title).Interface("formatted_parts", formattedParts).Msg("Formatted item")
+		d.items = append(d.items, preparedItem{item: item, parts: formattedParts, msg: notify.NewMessage(currentFeed, fetchResult.Feed.Title, item, formattedParts)})
+	}
+	run.delivery = d
+	return true
+}
+
+// prepareDelivery loads the destinations, bot tokens, proxy and chat a
+// feed's messages are sent with, creating its forum topic if needed. It
+// records the failure and returns nil when the feed can't be sent to.
+func (w *FeedWorker) prepareDelivery(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed, feedTitle string) *delivery {
+	destinations, err := w.destinations.ListFeedDestinations(ctx, currentFeed.ID)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to load feed destinations")
+		w.recordFailure(currentFeed, "db_error", err)
+		return nil
+	}
+	// Feeds without a chat only deliver to their other destinations.
+	sendTelegram := currentFeed.TelegramChatID != ""
 
-	// Get Bot Token (securely, on-demand)
-	var botToken string
-	if currentFeed.TelegramBotID != nil {
+	// Get Bot Token(s) (securely, on-demand). A bot pool takes precedence over a single bot.
+	var botTokens []string
+	if !sendTelegram {
+		if len(destinations) == 0 {
+			l.Error().Msg("Feed has neither a Telegram chat nor other destinations, cannot send messages.")
+			w.recordFailure(currentFeed, "config_error", errors.New("feed has no Telegram chat or destinations"))
+			return nil
+		}
+	} else if currentFeed.BotPoolID != nil {
+		botIDs, errPool := w.botStore.GetPoolBotIDs(ctx, *currentFeed.BotPoolID)
+		if errPool != nil {
+			l.Error().Err(errPool).Int64("bot_pool_id", *currentFeed.BotPoolID).Msg("Failed to load bot pool members")
+			w.recordFailure(currentFeed, "token_error", errPool)
+			return nil
+		}
+		for _, botID := range botIDs {
+			token, errToken := w.botStore.GetTokenByBotID(ctx, botID)
+			if errToken != nil {
+				l.Warn().Err(errToken).Int64("bot_id", botID).Msg("Skipping pool bot whose token could not be retrieved")
+				continue
+			}
+			botTokens = append(botTokens, token)
+		}
+		if len(botTokens) == 0 {
+			l.Error().Int64("bot_pool_id", *currentFeed.BotPoolID).Msg("Bot pool has no usable bots, cannot send messages.")
+			w.recordFailure(currentFeed, "config_error", fmt.Errorf("bot pool %d has no usable bots", *currentFeed.BotPoolID))
+			return nil
+		}
+	} else if currentFeed.TelegramBotID != nil {
 		token, errToken := w.botStore.GetTokenByBotID(ctx, *currentFeed.TelegramBotID)
 		if errToken != nil {
 			l.Error().Err(errToken).Int64("bot_id", *currentFeed.TelegramBotID).Msg("Failed to retrieve Telegram bot token")
-			metrics.FeedsProcessed.WithLabelValues(currentFeed.URL, "token_error").Inc()
-			return // Cannot proceed without token
+			w.recordFailure(currentFeed, "token_error", errToken)
+			return nil // Cannot proceed without token
 		}
-		botToken = token
+		botTokens = []string{token}
 	} else {
 		// This case should ideally be prevented by DB constraints or CLI validation (feed needs a bot).
 		// Or there's a global default bot token in appConfig.
-		l.Error().Msg("Feed is not associated with a Telegram bot ID, cannot send messages.")
-		metrics.FeedsProcessed.WithLabelValues(currentFeed.URL, "config_error").Inc()
-		return
+		l.Error().Msg("Feed is not associated with a Telegram bot ID or bot pool, cannot send messages.")
+		w.recordFailure(currentFeed, "config_error", errors.New("feed has no Telegram bot or bot pool"))
+		return nil
 	}
     
-    // Determine proxy for Telegram: could be feed-specific, global default, or none
+    // Determine proxy for Telegram: could be feed-specific, the Telegram pool, global default, or none
     telegramProxy := currentFeed.Proxy // Start with feed-specific proxy
-	if telegramProxy == nil && !w.appConfig.DryRun { // No feed-specific proxy, try global Telegram default
-		defaultTGProxy, errP := w.proxyStore.GetDefaultProxy(ctx, "telegram")
+	if sendTelegram && telegramProxy == nil && !w.config().DryRun { // No feed-specific proxy, try the Telegram pool and global default
+		defaultTGProxy, errP := telegram.DefaultProxy(ctx, w.proxyStore, w.proxyPicker, currentFeed.ID)
 		if errP != nil {
 			l.Warn().Err(errP).Msg("Failed to get default Telegram proxy")
 		} else if defaultTGProxy != nil {
@@ -171,71 +780,272 @@ func (w *FeedWorker) ProcessFeed(feedFromScheduler *database.Feed) {
 	}
 
 
-	var lastSuccessfullyProcessedItemHash string
-	for _, item := range newItems {
-		itemCtx := log.With().Str("item_title", Truncate(item.Title, 50)).Str("item_link", item.Link).Logger().WithContext(ctx)
-		
-		// currentFeed.FormattingProfile is already populated
-		formattedParts, err := w.formatter.FormatItem(itemCtx, item, currentFeed, currentFeed.FormattingProfile)
-		if err != nil {
-			l.Error().Err(err).Str("item_title", item.Title).Msg("Failed to format item")
+	// Prefer the numeric chat ID cached from an earlier delivery to an @username.
+	chatTarget := currentFeed.TelegramChatID
+	if resolved := currentFeed.ResolvedChat(); resolved != nil {
+		chatTarget = strconv.FormatInt(*resolved, 10)
+	}
+
+	threadID := 0
+	if currentFeed.TelegramThreadID != nil {
+		threadID = *currentFeed.TelegramThreadID
+	}
+	if sendTelegram && currentFeed.AutoCreateTopic && threadID == 0 && !w.config().DryRun {
+		if tgClient, ok := w.notifier.(interfaces.ThreadNotifier); ok {
+			topicName := currentFeed.URL
+			if currentFeed.UserTitle != nil && *currentFeed.UserTitle != "" {
+				topicName = *currentFeed.UserTitle
+			} else if feedTitle != "" {
+				topicName = feedTitle
+			}
+			createdID, errTopic := tgClient.CreateForumTopic(ctx, botTokens[0], chatTarget, topicName, telegramProxy)
+			if errTopic != nil {
+				l.Error().Err(errTopic).Msg("Failed to create forum topic for feed")
+				w.recordFailure(currentFeed, "send_error", errTopic)
+				return nil // Retry next cycle rather than posting into the general topic
+			}
+			if errStore := w.feedStore.SetTelegramThreadID(ctx, currentFeed.ID, createdID); errStore != nil {
+				// Sending without the topic on record would create another one next cycle
+				l.Error().Err(errStore).Int("thread_id", createdID).Msg("Failed to store created forum topic ID")
+				w.recordFailure(currentFeed, "db_error", errStore)
+				return nil
+			}
+			l.Info().Str("topic_name", topicName).Int("thread_id", createdID).Msg("Created forum topic for feed")
+			threadID = createdID
+		}
+	}
+
+	return &delivery{
+		destinations: destinations,
+		sendTelegram: sendTelegram,
+		botTokens:    botTokens,
+		proxy:        telegramProxy,
+		chatTarget:   chatTarget,
+		threadID:     threadID,
+	}
+}
+
+// filterItems returns the items passing the feed's filters. The others are
+// recorded as processed, so they aren't looked at again.
+func (w *FeedWorker) filterItems(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed, items []*gofeed.Item) ([]*gofeed.Item, error) {
+	if w.filters == nil {
+		return items, nil
+	}
+	feedFilters, err := w.filters.ListFeedFilters(ctx, currentFeed.ID)
+	if err != nil {
+		return nil, err
+	}
+	set, err := filter.Compile(feedFilters)
+	if err != nil || set == nil {
+		return items, err
+	}
+
+	kept := items[:0]
+	for _, item := range items {
+		ok, by := set.Allows(item)
+		if ok {
+			kept = append(kept, item)
 			continue
 		}
+		l.Debug().Str("item_title", item.Title).Int64("filter_id", by.ID).Str("filter_action", by.Action).Msg("Item filtered out")
+		metrics.ItemsFiltered.WithLabelValues(currentFeed.URL).Inc()
+		itemHash := rss.ItemHash(item)
+		if err := w.feedStore.AddProcessedItem(context.WithoutCancel(ctx), currentFeed.ID, itemHash); err != nil {
+			l.Error().Err(err).Str("item_guid_hash", itemHash).Msg("Failed to mark filtered item as processed")
+		} else {
+			w.processed.Add(currentFeed.ID, currentFeed.ProcessedEpoch, itemHash)
+		}
+	}
+	if skipped := len(items) - len(kept); skipped > 0 {
+		l.Info().Int("filtered_items_count", skipped).Msg("Skipped items not passing the feed's filters")
+	}
+	return kept, nil
+}
+
+// sendItems is the send stage of a feed run: it sends the formatted items in
+// order and records what was processed. Sending stops at the first failure;
+// the items left are sent by a later run. With the outbox, the item that
+// failed to reach Telegram and those after it are queued there instead.
+func (w *FeedWorker) sendItems(run *feedRun) {
+	ctx, cancel := context.WithTimeout(run.ctx, stageTimeout)
+	defer cancel()
+	// Checkpoints are written even when a shutdown cancels the run, so sent
+	// items aren't sent again after a restart.
+	saveCtx := context.WithoutCancel(ctx)
+	l, currentFeed, fetchResult, d := run.l, run.feed, run.fetched, run.delivery
+	sendTelegram, destinations := d.sendTelegram, d.destinations
+
+	// New items wait behind those of the feed already in the outbox, so the
+	// chat gets them in order.
+	useOutbox := w.outbox != nil && run.digest == nil && sendTelegram && !w.config().DryRun
+	outboxed := false
+	if useOutbox {
+		var err error
+		if outboxed, err = w.outbox.HasOutboxMessages(ctx, currentFeed.ID); err != nil {
+			l.Error().Err(err).Msg("Failed to check the outbox for the feed's items")
+		}
+	}
+	var sendErr error // Of the send that started queueing items in the outbox
+
+	var lastSuccessfullyProcessedItemHash string
+	for _, prepared := range d.items {
+		item, formattedParts, msg := prepared.item, prepared.parts, prepared.msg
+		itemCtx := l.With().Str("item_title", Truncate(item.Title, 50)).Str("item_link", item.Link).Logger().WithContext(ctx)
+		var err error
 
-		if w.appConfig.DryRun {
-			l.Info().Interface("formatted_parts", formattedParts).Msg("[DRY RUN] Would send formatted item")
+		if w.config().DryRun {
+			l.Info().Interface("formatted_parts", formattedParts).Bool("telegram", sendTelegram).Int("destinations", len(destinations)).Msg("[DRY RUN] Would send formatted item")
 		} else {
-			// The notifier interface's Send method should ideally take the proxy.
-			// Let's assume the telegram.Client's Send method (which implements interfaces.Notifier)
-			// is modified to accept a proxy *database.Proxy argument.
-			// We need to cast w.notifier to its concrete type or modify interface.
-			// For simplicity, let's assume interfaces.Notifier.Send takes proxy.
-			// If Notifier is specifically telegram.Client:
-			if tgClient, ok := w.notifier.(*telegram.Client); ok {
-				err = tgClient.Send(itemCtx, botToken, currentFeed.TelegramChatID, formattedParts, telegramProxy)
-			} else {
-				// Fallback or error if notifier is not the expected type
-				// This indicates a mismatch in DI. For now, assume it's telegram.Client.
-				// Or, the Notifier interface needs to be:
-				// Send(ctx context.Context, recipient string, message interface{}, proxy *database.Proxy) error
-				l.Error().Msg("Notifier is not of expected type *telegram.Client to pass proxy")
-				err = fmt.Errorf("notifier type mismatch for proxy handling") 
+			if sendTelegram && !outboxed {
+				err = w.sendToChat(itemCtx, l, currentFeed, d.botTokens, &d.chatTarget, d.threadID, formattedParts, d.proxy)
 			}
 
-
 			if err != nil {
 				l.Error().Err(err).Str("item_title", item.Title).Msg("Failed to send item to notifier")
 				metrics.TelegramAPICalls.WithLabelValues(w.notifier.Name(), "send_error").Inc()
-				return 
+				if !useOutbox {
+					w.recordFailure(currentFeed, "send_error", err)
+					return
+				}
+				outboxed, sendErr = true, err
 			}
-			metrics.TelegramAPICalls.WithLabelValues(w.notifier.Name(), "success").Inc()
 		}
 
-		itemIdentifier := item.GUID
-		if itemIdentifier == "" { itemIdentifier = item.Link }
-		currentItemHash := fmt.Sprintf("%x", sha256.Sum256([]byte(itemIdentifier)))
-		if err := w.feedStore.AddProcessedItem(itemCtx, currentFeed.ID, currentItemHash); err != nil {
+		if outboxed {
+			if err := w.addToOutbox(saveCtx, currentFeed, prepared, err); err != nil {
+				l.Error().Err(err).Str("item_title", item.Title).Msg("Failed to queue item in the outbox")
+				w.recordFailure(currentFeed, "db_error", err)
+				return
+			}
+			l.Info().Str("item_title", item.Title).Msg("Queued item in the outbox to be sent later")
+		} else if !w.config().DryRun {
+			if sendTelegram {
+				metrics.TelegramAPICalls.WithLabelValues(w.notifier.Name(), "success").Inc()
+			}
+			// As in sendOutbox, a destination failing doesn't keep the item to
+			// be sent again to the chat and the destinations that have it.
+			if err := w.sendToDestinations(itemCtx, destinations, msg); err != nil {
+				l.Error().Err(err).Str("item_title", item.Title).Msg("Failed to send item to a feed destination")
+			}
+			if item.PublishedParsed != nil {
+				if latency := time.Since(*item.PublishedParsed); latency >= 0 {
+					metrics.ItemDeliveryLatency.WithLabelValues(currentFeed.URL).Observe(latency.Seconds())
+				}
+			}
+			w.recordDelivery(saveCtx, currentFeed, msg, item.GUID)
+			if w.mqtt != nil {
+				if err := w.mqtt.Publish(saveCtx, msg); err != nil {
+					l.Warn().Err(err).Str("item_title", item.Title).Msg("Failed to publish item to MQTT")
+				}
+			}
+		}
+
+		if run.digest != nil {
+			continue // Its items were recorded as processed when they were queued
+		}
+
+		currentItemHash := rss.ItemHash(item)
+		if err := w.feedStore.AddProcessedItem(saveCtx, currentFeed.ID, currentItemHash); err != nil {
 			l.Error().Err(err).Str("item_guid_hash", currentItemHash).Msg("Failed to mark item as processed")
+		} else {
+			w.processed.Add(currentFeed.ID, currentFeed.ProcessedEpoch, currentItemHash)
 		}
 		lastSuccessfullyProcessedItemHash = currentItemHash
+		if outboxed {
+			continue // Counted once the outbox sends it
+		}
 		metrics.NewItemsSent.WithLabelValues(currentFeed.URL).Inc()
+		w.events.Publish(events.Event{Type: events.TypeItemSent, FeedID: currentFeed.ID, FeedURL: currentFeed.URL, ItemTitle: item.Title, ItemLink: item.Link})
+	}
+
+	if run.digest != nil {
+		w.finishDigest(saveCtx, run)
+		return
 	}
 
 	var finalHashToStore *string
 	if lastSuccessfullyProcessedItemHash != "" {
 		finalHashToStore = &lastSuccessfullyProcessedItemHash
-	} else if latestItemInFeedHash != "" {
-		finalHashToStore = &latestItemInFeedHash
+	} else if d.latestItemHash != "" {
+		finalHashToStore = &d.latestItemHash
 	} else {
 		finalHashToStore = currentFeed.LastProcessedItemGUIDHash
 	}
 
-	if err := w.feedStore.UpdateFeedLastProcessed(ctx, currentFeed.ID, finalHashToStore, fetchResult.NewEtag, fetchResult.NewLastModified); err != nil {
+	if err := w.feedStore.UpdateFeedLastProcessed(saveCtx, currentFeed.ID, finalHashToStore, fetchResult.NewEtag, fetchResult.NewLastModified, fetchResult.NewBodyHash); err != nil {
 		l.Error().Err(err).Msg("Failed to update feed metadata after processing items")
 	}
 
-	l.Info().Int("new_items_processed", len(newItems)).Msg("Finished processing feed")
-	metrics.FeedsProcessed.WithLabelValues(currentFeed.URL, "success").Inc()
+	l.Info().Int("new_items_processed", len(d.items)).Msg("Finished processing feed")
+	if sendErr != nil {
+		w.recordFailure(currentFeed, "send_error", sendErr)
+	} else {
+		w.recordResult(currentFeed, "success")
+	}
+	run.delivered = true
+}
+
+// sendToChat sends an item's parts to the feed's own chat. A chat given as
+// @username is replaced in chatTarget by its numeric ID once it is learned.
+func (w *FeedWorker) sendToChat(ctx context.Context, l zerolog.Logger, currentFeed *database.Feed, botTokens []string, chatTarget *string, threadID int, parts []interfaces.FormattedMessagePart, proxy *database.Proxy) error {
+	tgClient, ok := w.notifier.(interfaces.ThreadNotifier)
+	if !ok {
+		if threadID != 0 {
+			return fmt.Errorf("%s cannot post into forum topics", w.notifier.Name())
+		}
+		return w.notifier.Send(ctx, botTokens[0], *chatTarget, parts, proxy)
+	}
+	// All parts of one item go through the same bot so replies can thread.
+	botToken := botTokens[0]
+	if currentFeed.BotPoolID != nil {
+		botToken = tgClient.NextPoolToken(fmt.Sprintf("pool:%d", *currentFeed.BotPoolID), botTokens)
+	}
+	sendStart := time.Now()
+	err := tgClient.SendToThread(ctx, botToken, *chatTarget, threadID, parts, proxy)
+	sendStatus := "success"
+	if err != nil {
+		sendStatus = "error"
+	}
+	metrics.SendDuration.WithLabelValues(currentFeed.URL, sendStatus).Observe(time.Since(sendStart).Seconds())
+	if err == nil && currentFeed.ResolvedChat() == nil {
+		if chatID, ok := tgClient.ResolvedChatID(*chatTarget); ok {
+			if errResolve := w.feedStore.SetResolvedChatID(ctx, currentFeed.ID, *chatTarget, chatID); errResolve != nil {
+				l.Warn().Err(errResolve).Msg("Failed to store resolved chat ID")
+			} else {
+				l.Info().Str("chat_username", *chatTarget).Int64("chat_id", chatID).Msg("Resolved and cached numeric chat ID")
+				chatFor := *chatTarget
+				currentFeed.ResolvedChatID, currentFeed.ResolvedChatFor = &chatID, &chatFor
+				*chatTarget = strconv.FormatInt(chatID, 10)
+			}
+		}
+	}
+	return err
+}
+
+// recordDelivery stores a delivered item for the output feed.
+func (w *FeedWorker) recordDelivery(ctx context.Context, feed *database.Feed, msg *notify.Message, guid string) {
+	if w.deliveries == nil {
+		return
+	}
+	d := &database.Delivery{
+		FeedID: feed.ID, FeedTitle: msg.FeedTitle, ChatID: feed.TelegramChatID, GUID: guid,
+		Title: msg.Title, Link: msg.Link, Author: msg.Author, Text: msg.PlainText(), PublishedAt: msg.Published,
+	}
+	if _, err := w.deliveries.RecordDelivery(ctx, d); err != nil {
+		log.Warn().Err(err).Int64("feed_id", feed.ID).Msg("Failed to record delivery")
+	}
+}
+
+// sendToDestinations delivers msg to each of a feed's extra destinations. One
+// failing doesn't stop the others; the errors are returned together.
+func (w *FeedWorker) sendToDestinations(ctx context.Context, destinations []*database.FeedDestination, msg *notify.Message) error {
+	var errs []error
+	for _, d := range destinations {
+		if err := w.notifiers.Send(ctx, d, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s destination %d: %w", d.Type, d.ID, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // ... (Truncate function) ...
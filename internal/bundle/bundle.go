@@ -0,0 +1,330 @@
+// Package bundle exports the whole configuration of a bot — proxies, pools,
+// rules, formatting profiles, bots and feeds with their delivery history — to
+// a JSON document, and imports it into another database, e.g. when moving to a
+// new host.
+package bundle
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+)
+
+// Version is the bundle format written by Export.
+const Version = 1
+
+// pbkdf2Iterations derives the key sealing secrets from the passphrase.
+const pbkdf2Iterations = 600000
+
+// Bundle is an exported configuration. IDs are those of the exporting
+// database and only link entries within the bundle.
+type Bundle struct {
+	Version    int         `json:"version"`
+	ExportedAt time.Time   `json:"exported_at"`
+	Salt       string      `json:"salt,omitempty"` // Key derivation salt, set when secrets are included
+	Proxies    []Proxy     `json:"proxies"`
+	ProxyPools []ProxyPool `json:"proxy_pools"`
+	ProxyRules []ProxyRule `json:"proxy_rules"`
+	Profiles   []Profile   `json:"profiles"`
+	Bots       []Bot       `json:"bots"`
+	BotPools   []BotPool   `json:"bot_pools"`
+	Feeds      []Feed      `json:"feeds"`
+}
+
+// Proxy is an exported proxy. Credentials are kept as stored: literal, or
+// env:/file: references that must resolve on the importing host.
+type Proxy struct {
+	ID                 int64               `json:"id"`
+	Name               string              `json:"name"`
+	Type               string              `json:"type"`
+	Address            string              `json:"address"`
+	Username           *string             `json:"username,omitempty"`
+	Password           *string             `json:"password,omitempty"`
+	DefaultForRSS      bool                `json:"default_for_rss,omitempty"`
+	DefaultForTelegram bool                `json:"default_for_telegram,omitempty"`
+	DirectFallback     *bool               `json:"direct_fallback,omitempty"`
+	TLS                *database.TLSConfig `json:"tls,omitempty"`
+}
+
+// ProxyPool is an exported proxy pool.
+type ProxyPool struct {
+	ID                 int64   `json:"id"`
+	Name               string  `json:"name"`
+	Strategy           string  `json:"strategy"`
+	DefaultForTelegram bool    `json:"default_for_telegram,omitempty"`
+	ProxyIDs           []int64 `json:"proxy_ids"`
+}
+
+// ProxyRule is an exported host-pattern proxy rule.
+type ProxyRule struct {
+	Pattern  string `json:"pattern"`
+	ProxyID  int64  `json:"proxy_id"`
+	Priority int    `json:"priority"`
+}
+
+// Profile is an exported formatting profile.
+type Profile struct {
+	ID     int64                            `json:"id"`
+	Name   string                           `json:"name"`
+	Config database.FormattingProfileConfig `json:"config"`
+}
+
+// Bot is an exported Telegram bot. Its token is only included, sealed with
+// the export passphrase, when one was given; otherwise it is identified by the
+// token's hash and the token must be supplied again on import.
+type Bot struct {
+	ID          int64   `json:"id"`
+	Description *string `json:"description,omitempty"`
+	TokenHash   string  `json:"token_hash"`
+	SealedToken string  `json:"sealed_token,omitempty"`
+}
+
+// BotPool is an exported bot pool.
+type BotPool struct {
+	ID     int64   `json:"id"`
+	Name   string  `json:"name"`
+	BotIDs []int64 `json:"bot_ids"`
+}
+
+// Feed is an exported feed with the hashes of the items already delivered,
+// so the importing bot doesn't send them again.
+type Feed struct {
+	ID                     int64                  `json:"id"`
+	URL                    string                 `json:"url"`
+	Title                  *string                `json:"title,omitempty"`
+	FrequencySeconds       int                    `json:"frequency_seconds"`
+	BotID                  *int64                 `json:"bot_id,omitempty"`
+	BotPoolID              *int64                 `json:"bot_pool_id,omitempty"`
+	ChatID                 string                 `json:"chat_id"`
+	ThreadID               *int                   `json:"thread_id,omitempty"`
+	AutoCreateTopic        bool                   `json:"auto_create_topic,omitempty"`
+	SourceType             string                 `json:"source_type"`
+	ScrapeConfig           *database.ScrapeConfig `json:"scrape_config,omitempty"`
+	UserAgent              *string                `json:"user_agent,omitempty"`
+	RequestHeaders         map[string]string      `json:"request_headers,omitempty"`
+	Cookies                map[string]string      `json:"cookies,omitempty"`
+	StaleAfterSeconds      *int                   `json:"stale_after_seconds,omitempty"`
+	FetchTimeoutSeconds    *int                   `json:"fetch_timeout_seconds,omitempty"`
+	FetchMaxRetries        *int                   `json:"fetch_max_retries,omitempty"`
+	FetchRetryDelaySeconds *int                   `json:"fetch_retry_delay_seconds,omitempty"`
+	TLS                    *database.TLSConfig    `json:"tls,omitempty"`
+	UseFlareSolverr        bool                   `json:"use_flaresolverr,omitempty"`
+	ProxyID                *int64                 `json:"proxy_id,omitempty"`
+	ProxyPoolID            *int64                 `json:"proxy_pool_id,omitempty"`
+	ProxyDirectFallback    *bool                  `json:"proxy_direct_fallback,omitempty"`
+	ProfileID              *int64                 `json:"profile_id,omitempty"`
+	Enabled                bool                   `json:"enabled"`
+	Debug                  bool                   `json:"debug,omitempty"`
+	Auth                   *FeedAuth              `json:"auth,omitempty"`
+	Processed              []string               `json:"processed,omitempty"`
+}
+
+// FeedAuth holds a feed's credentials. The secret is only included, sealed
+// with the export passphrase, when one was given.
+type FeedAuth struct {
+	Type         string `json:"type"`
+	Username     string `json:"username,omitempty"`
+	Header       string `json:"header,omitempty"`
+	SealedSecret string `json:"sealed_secret,omitempty"`
+}
+
+// ExportOptions control what Export includes.
+type ExportOptions struct {
+	Passphrase string // Seals bot tokens and feed credentials into the bundle; empty leaves them out
+	NoHistory  bool   // Leave out processed item hashes
+}
+
+// Export reads the configuration stored in db.
+func Export(ctx context.Context, db *database.DB, opts ExportOptions) (*Bundle, error) {
+	feedStore := database.NewFeedStore(db)
+	proxyStore := database.NewProxyStore(db)
+	botStore := database.NewTelegramBotStore(db)
+	b := &Bundle{
+		Version: Version, ExportedAt: time.Now().UTC(),
+		Proxies: []Proxy{}, ProxyPools: []ProxyPool{}, ProxyRules: []ProxyRule{}, Profiles: []Profile{},
+		Bots: []Bot{}, BotPools: []BotPool{}, Feeds: []Feed{},
+	}
+
+	var aead cipher.AEAD
+	if opts.Passphrase != "" {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("generating salt: %w", err)
+		}
+		b.Salt = base64.StdEncoding.EncodeToString(salt)
+		var err error
+		if aead, err = b.cipher(opts.Passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	proxies, err := proxyStore.ListProxies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range proxies {
+		b.Proxies = append(b.Proxies, Proxy{
+			ID: p.ID, Name: p.Name, Type: p.Type, Address: p.Address, Username: p.Username, Password: p.Password,
+			DefaultForRSS: p.IsDefaultForRSS, DefaultForTelegram: p.IsDefaultForTelegram, DirectFallback: p.DirectFallback, TLS: p.TLS,
+		})
+	}
+	proxyPools, err := proxyStore.ListPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, pool := range proxyPools {
+		exported := ProxyPool{ID: pool.ID, Name: pool.Name, Strategy: pool.Strategy, DefaultForTelegram: pool.IsDefaultForTelegram, ProxyIDs: []int64{}}
+		for _, p := range pool.Proxies {
+			exported.ProxyIDs = append(exported.ProxyIDs, p.ID)
+		}
+		b.ProxyPools = append(b.ProxyPools, exported)
+	}
+	rules, err := proxyStore.ListProxyRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		b.ProxyRules = append(b.ProxyRules, ProxyRule{Pattern: r.Pattern, ProxyID: r.ProxyID, Priority: r.Priority})
+	}
+
+	profiles, err := database.NewFormattingProfileStore(db).ListProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		b.Profiles = append(b.Profiles, Profile{ID: p.ID, Name: p.Name, Config: p.ParsedConfig})
+	}
+
+	bots, err := botStore.ListBots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, bot := range bots {
+		exported := Bot{ID: bot.ID, Description: bot.Description, TokenHash: bot.TokenHash}
+		if aead != nil {
+			token, err := botStore.GetTokenByBotID(ctx, bot.ID)
+			if err != nil {
+				return nil, fmt.Errorf("bot %d: %w", bot.ID, err)
+			}
+			if exported.SealedToken, err = seal(aead, token); err != nil {
+				return nil, err
+			}
+		}
+		b.Bots = append(b.Bots, exported)
+	}
+	botPools, err := botStore.ListPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, pool := range botPools {
+		b.BotPools = append(b.BotPools, BotPool{ID: pool.ID, Name: pool.Name, BotIDs: append([]int64{}, pool.BotIDs...)})
+	}
+
+	feeds, err := feedStore.ListFeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range feeds {
+		exported := Feed{
+			ID: f.ID, URL: f.URL, Title: f.UserTitle, FrequencySeconds: f.FrequencySeconds,
+			BotID: f.TelegramBotID, BotPoolID: f.BotPoolID, ChatID: f.TelegramChatID, ThreadID: f.TelegramThreadID, AutoCreateTopic: f.AutoCreateTopic,
+			SourceType: f.SourceType, ScrapeConfig: f.ScrapeConfig, UserAgent: f.UserAgent, RequestHeaders: f.RequestHeaders, Cookies: f.Cookies,
+			StaleAfterSeconds: f.StaleAfterSeconds, FetchTimeoutSeconds: f.FetchTimeoutSeconds, FetchMaxRetries: f.FetchMaxRetries,
+			FetchRetryDelaySeconds: f.FetchRetryDelaySeconds, TLS: f.TLS, UseFlareSolverr: f.UseFlareSolverr,
+			ProxyID: f.ProxyID, ProxyPoolID: f.ProxyPoolID, ProxyDirectFallback: f.ProxyDirectFallback, ProfileID: f.FormattingProfileID,
+			Enabled: f.IsEnabled, Debug: f.Debug,
+		}
+		if f.AuthType != nil && *f.AuthType != "" {
+			exported.Auth = &FeedAuth{Type: *f.AuthType}
+			if aead != nil {
+				creds, err := feedStore.GetFeedCredentials(ctx, f.ID)
+				if err != nil {
+					return nil, fmt.Errorf("feed %d: %w", f.ID, err)
+				}
+				if creds != nil {
+					exported.Auth.Username, exported.Auth.Header = creds.Username, creds.Header
+					if exported.Auth.SealedSecret, err = seal(aead, creds.Secret); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		if !opts.NoHistory {
+			if exported.Processed, err = feedStore.ListProcessedItems(ctx, f.ID); err != nil {
+				return nil, err
+			}
+		}
+		b.Feeds = append(b.Feeds, exported)
+	}
+	return b, nil
+}
+
+// Write encodes b as indented JSON.
+func (b *Bundle) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// Read decodes a bundle, rejecting formats newer than Version.
+func Read(r io.Reader) (*Bundle, error) {
+	var b Bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, fmt.Errorf("decoding bundle: %w", err)
+	}
+	if b.Version < 1 || b.Version > Version {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	return &b, nil
+}
+
+// cipher derives the AEAD sealing the bundle's secrets from passphrase.
+func (b *Bundle) cipher(passphrase string) (cipher.AEAD, error) {
+	salt, err := base64.StdEncoding.DecodeString(b.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, errors.New("bundle has no valid salt")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generating nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+// errWrongPassphrase is returned when sealed secrets don't open.
+var errWrongPassphrase = errors.New("wrong passphrase or corrupted bundle")
+
+func open(aead cipher.AEAD, sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", errWrongPassphrase
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errWrongPassphrase
+	}
+	return string(plaintext), nil
+}
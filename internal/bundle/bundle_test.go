@@ -0,0 +1,140 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	require.NoError(t, database.InitEncryptionKey("test-key"))
+	db, err := database.Connect(filepath.Join(t.TempDir(), "bundle.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// seed fills db with one of everything a bundle carries.
+func seed(t *testing.T, ctx context.Context, db *database.DB) {
+	t.Helper()
+	proxies := database.NewProxyStore(db)
+	proxyID, err := proxies.CreateProxy(ctx, &database.Proxy{Name: "eu", Type: "socks5h", Address: "10.0.0.1:1080"})
+	require.NoError(t, err)
+	poolID, err := proxies.CreatePool(ctx, "rotating", database.ProxyPoolRoundRobin)
+	require.NoError(t, err)
+	require.NoError(t, proxies.AddProxyToPool(ctx, poolID, proxyID))
+	_, err = proxies.CreateProxyRule(ctx, "*.onion", proxyID, 5)
+	require.NoError(t, err)
+
+	profileID, err := database.NewFormattingProfileStore(db).CreateProfile(ctx, &database.FormattingProfile{
+		Name: "compact", ParsedConfig: database.FormattingProfileConfig{Hashtags: []string{"news"}},
+	})
+	require.NoError(t, err)
+	description := "main"
+	botID, err := database.NewTelegramBotStore(db).CreateBot(ctx, "123:abc", &description)
+	require.NoError(t, err)
+
+	feeds := database.NewFeedStore(db)
+	feedID, err := feeds.CreateFeed(ctx, &database.Feed{
+		URL: "https://example.com/feed.xml", FrequencySeconds: 600, TelegramBotID: &botID, TelegramChatID: "@news",
+		ProxyPoolID: &poolID, FormattingProfileID: &profileID, IsEnabled: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, feeds.SetFeedCredentials(ctx, feedID, &database.FeedCredentials{Type: database.FeedAuthBasic, Username: "u", Secret: "s3cret"}))
+	require.NoError(t, feeds.AddProcessedItems(ctx, feedID, []string{"hash1", "hash2"}))
+}
+
+func roundTrip(t *testing.T, b *Bundle) *Bundle {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, b.Write(&buf))
+	read, err := Read(&buf)
+	require.NoError(t, err)
+	return read
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	src := setupTestDB(t)
+	seed(t, ctx, src)
+
+	b, err := Export(ctx, src, ExportOptions{Passphrase: "correct horse"})
+	require.NoError(t, err)
+	b = roundTrip(t, b)
+	require.Len(t, b.Feeds, 1)
+	assert.Equal(t, []string{"hash1", "hash2"}, b.Feeds[0].Processed)
+	assert.NotContains(t, b.Bots[0].SealedToken, "123:abc")
+
+	dst := setupTestDB(t)
+	_, err = Import(ctx, dst, b, ImportOptions{Passphrase: "wrong"})
+	assert.ErrorIs(t, err, errWrongPassphrase)
+
+	result, err := Import(ctx, dst, b, ImportOptions{Passphrase: "correct horse"})
+	require.NoError(t, err)
+	for _, kind := range []string{"proxy", "proxy pool", "proxy rule", "profile", "bot", "bot pool", "feed"} {
+		assert.Equal(t, 0, result.Skipped[kind], kind)
+	}
+	assert.Equal(t, 1, result.Created["feed"])
+	assert.Empty(t, result.NeedAuth)
+
+	feedStore := database.NewFeedStore(dst)
+	feeds, err := feedStore.ListFeeds(ctx)
+	require.NoError(t, err)
+	require.Len(t, feeds, 1)
+	feed := feeds[0]
+	require.NotNil(t, feed.FormattingProfile)
+	assert.Equal(t, []string{"news"}, feed.FormattingProfile.ParsedConfig.Hashtags)
+	require.NotNil(t, feed.ProxyPoolID)
+	pool, err := database.NewProxyStore(dst).GetPoolByID(ctx, *feed.ProxyPoolID)
+	require.NoError(t, err)
+	require.Len(t, pool.Proxies, 1)
+	assert.Equal(t, "eu", pool.Proxies[0].Name)
+	token, err := database.NewTelegramBotStore(dst).GetTokenByBotID(ctx, *feed.TelegramBotID)
+	require.NoError(t, err)
+	assert.Equal(t, "123:abc", token)
+	creds, err := feedStore.GetFeedCredentials(ctx, feed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", creds.Secret)
+	processed, err := feedStore.IsItemProcessed(ctx, feed.ID, "hash2")
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	// Importing again changes nothing.
+	result, err = Import(ctx, dst, b, ImportOptions{Passphrase: "correct horse"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Created["feed"])
+	assert.Equal(t, 1, result.Skipped["feed"])
+	assert.Equal(t, 1, result.Skipped["bot"])
+}
+
+func TestImportWithoutSecrets(t *testing.T) {
+	ctx := context.Background()
+	src := setupTestDB(t)
+	seed(t, ctx, src)
+	b, err := Export(ctx, src, ExportOptions{NoHistory: true})
+	require.NoError(t, err)
+	b = roundTrip(t, b)
+	assert.Empty(t, b.Bots[0].SealedToken)
+	assert.Empty(t, b.Feeds[0].Processed)
+
+	dst := setupTestDB(t)
+	_, err = Import(ctx, dst, b, ImportOptions{})
+	require.ErrorContains(t, err, "missing tokens for bots 1 (main)")
+	feeds, err := database.NewFeedStore(dst).ListFeeds(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, feeds, "nothing is written without every token")
+
+	_, err = Import(ctx, dst, b, ImportOptions{BotTokens: map[int64]string{1: "456:other"}})
+	assert.ErrorContains(t, err, "not the bot's token")
+
+	result, err := Import(ctx, dst, b, ImportOptions{BotTokens: map[int64]string{1: "123:abc"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/feed.xml"}, result.NeedAuth)
+	assert.Contains(t, result.String(), "feeds:      1 created, 0 already present")
+}
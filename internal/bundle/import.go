@@ -0,0 +1,362 @@
+package bundle
+
+import (
+	"context"
+	"crypto/cipher"
+	"fmt"
+	"strings"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+)
+
+// ImportOptions supply the secrets a bundle doesn't carry.
+type ImportOptions struct {
+	Passphrase string           // Opens sealed bot tokens and feed credentials
+	BotTokens  map[int64]string // Tokens by bundle bot ID, for bots exported without theirs
+}
+
+// Result counts what Import created and what already existed.
+type Result struct {
+	Created, Skipped map[string]int // Keyed by "proxy", "proxy pool", "proxy rule", "profile", "bot", "bot pool" and "feed"
+	NeedAuth         []string       // URLs of imported feeds whose credentials must be set again
+}
+
+// String summarizes r, one line per kind.
+func (r *Result) String() string {
+	var b strings.Builder
+	for _, kind := range []string{"proxy", "proxy pool", "proxy rule", "profile", "bot", "bot pool", "feed"} {
+		fmt.Fprintf(&b, "%-11s %d created, %d already present\n", kind+"s:", r.Created[kind], r.Skipped[kind])
+	}
+	for _, url := range r.NeedAuth {
+		fmt.Fprintf(&b, "Set credentials again with 'feed auth' for %s\n", url)
+	}
+	return b.String()
+}
+
+// importer maps bundle IDs to the IDs of the importing database.
+type importer struct {
+	db     *database.DB
+	aead   cipher.AEAD
+	result *Result
+
+	proxies, proxyPools, profiles, bots, botPools map[int64]int64
+}
+
+// Import adds the bundle's entries to db. Entries that already exist are kept
+// as they are and reused: proxies, pools and profiles are matched by name,
+// bots by token and feeds by URL and chat. Bot tokens and feed credentials are
+// encrypted with the importing host's key. Every new bot's token is checked
+// for before anything is written.
+func Import(ctx context.Context, db *database.DB, b *Bundle, opts ImportOptions) (*Result, error) {
+	im := &importer{
+		db:         db,
+		result:     &Result{Created: map[string]int{}, Skipped: map[string]int{}},
+		proxies:    map[int64]int64{},
+		proxyPools: map[int64]int64{},
+		profiles:   map[int64]int64{},
+		bots:       map[int64]int64{},
+		botPools:   map[int64]int64{},
+	}
+	if opts.Passphrase != "" {
+		if b.Salt == "" {
+			return nil, fmt.Errorf("bundle was exported without secrets; pass bot tokens instead of a passphrase")
+		}
+		var err error
+		if im.aead, err = b.cipher(opts.Passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	tokens, err := im.botTokens(ctx, b, opts.BotTokens)
+	if err != nil {
+		return nil, err
+	}
+	if err := im.importProxies(ctx, b); err != nil {
+		return nil, err
+	}
+	if err := im.importProfiles(ctx, b); err != nil {
+		return nil, err
+	}
+	if err := im.importBots(ctx, b, tokens); err != nil {
+		return nil, err
+	}
+	if err := im.importFeeds(ctx, b); err != nil {
+		return nil, err
+	}
+	return im.result, nil
+}
+
+// botTokens returns the token of every bundle bot that doesn't exist yet, by
+// bundle ID, and records the existing ones.
+func (im *importer) botTokens(ctx context.Context, b *Bundle, given map[int64]string) (map[int64]string, error) {
+	existing, err := database.NewTelegramBotStore(im.db).ListBots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byHash := map[string]int64{}
+	for _, bot := range existing {
+		byHash[bot.TokenHash] = bot.ID
+	}
+
+	tokens := map[int64]string{}
+	var missing []string
+	for _, bot := range b.Bots {
+		if id, ok := byHash[bot.TokenHash]; ok {
+			im.bots[bot.ID] = id
+			continue
+		}
+		token := given[bot.ID]
+		if token == "" && bot.SealedToken != "" && im.aead != nil {
+			if token, err = open(im.aead, bot.SealedToken); err != nil {
+				return nil, fmt.Errorf("bot %d token: %w", bot.ID, err)
+			}
+		}
+		switch {
+		case token == "":
+			name := fmt.Sprintf("%d", bot.ID)
+			if bot.Description != nil && *bot.Description != "" {
+				name += fmt.Sprintf(" (%s)", *bot.Description)
+			}
+			missing = append(missing, name)
+		case database.TokenHash(token) != bot.TokenHash:
+			return nil, fmt.Errorf("token given for bot %d is not the bot's token", bot.ID)
+		default:
+			tokens[bot.ID] = token
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing tokens for bots %s; pass them as --bot-token ID=TOKEN or export with a passphrase", strings.Join(missing, ", "))
+	}
+	return tokens, nil
+}
+
+func (im *importer) importProxies(ctx context.Context, b *Bundle) error {
+	store := database.NewProxyStore(im.db)
+	existing, err := store.ListProxies(ctx)
+	if err != nil {
+		return err
+	}
+	byName := map[string]int64{}
+	for _, p := range existing {
+		byName[p.Name] = p.ID
+	}
+	for _, p := range b.Proxies {
+		if id, ok := byName[p.Name]; ok {
+			im.proxies[p.ID] = id
+			im.result.Skipped["proxy"]++
+			continue
+		}
+		id, err := store.CreateProxy(ctx, &database.Proxy{
+			Name: p.Name, Type: p.Type, Address: p.Address, Username: p.Username, Password: p.Password,
+			IsDefaultForRSS: p.DefaultForRSS, IsDefaultForTelegram: p.DefaultForTelegram, DirectFallback: p.DirectFallback, TLS: p.TLS,
+		})
+		if err != nil {
+			return fmt.Errorf("proxy %s: %w", p.Name, err)
+		}
+		im.proxies[p.ID] = id
+		im.result.Created["proxy"]++
+	}
+
+	pools, err := store.ListPools(ctx)
+	if err != nil {
+		return err
+	}
+	poolsByName := map[string]int64{}
+	for _, pool := range pools {
+		poolsByName[pool.Name] = pool.ID
+	}
+	for _, pool := range b.ProxyPools {
+		if id, ok := poolsByName[pool.Name]; ok {
+			im.proxyPools[pool.ID] = id
+			im.result.Skipped["proxy pool"]++
+			continue
+		}
+		id, err := store.CreatePool(ctx, pool.Name, pool.Strategy)
+		if err != nil {
+			return fmt.Errorf("proxy pool %s: %w", pool.Name, err)
+		}
+		im.proxyPools[pool.ID] = id
+		for _, proxyID := range pool.ProxyIDs {
+			if err := store.AddProxyToPool(ctx, id, im.proxies[proxyID]); err != nil {
+				return fmt.Errorf("proxy pool %s: %w", pool.Name, err)
+			}
+		}
+		if pool.DefaultForTelegram {
+			if err := store.SetTelegramPool(ctx, id); err != nil {
+				return fmt.Errorf("proxy pool %s: %w", pool.Name, err)
+			}
+		}
+		im.result.Created["proxy pool"]++
+	}
+
+	rules, err := store.ListProxyRules(ctx)
+	if err != nil {
+		return err
+	}
+	haveRule := map[string]bool{}
+	for _, r := range rules {
+		haveRule[fmt.Sprintf("%s|%d", r.Pattern, r.ProxyID)] = true
+	}
+	for _, r := range b.ProxyRules {
+		proxyID := im.proxies[r.ProxyID]
+		if haveRule[fmt.Sprintf("%s|%d", r.Pattern, proxyID)] {
+			im.result.Skipped["proxy rule"]++
+			continue
+		}
+		if _, err := store.CreateProxyRule(ctx, r.Pattern, proxyID, r.Priority); err != nil {
+			return fmt.Errorf("proxy rule %s: %w", r.Pattern, err)
+		}
+		im.result.Created["proxy rule"]++
+	}
+	return nil
+}
+
+func (im *importer) importProfiles(ctx context.Context, b *Bundle) error {
+	store := database.NewFormattingProfileStore(im.db)
+	existing, err := store.ListProfiles(ctx)
+	if err != nil {
+		return err
+	}
+	byName := map[string]int64{}
+	for _, p := range existing {
+		byName[p.Name] = p.ID
+	}
+	for _, p := range b.Profiles {
+		if id, ok := byName[p.Name]; ok {
+			im.profiles[p.ID] = id
+			im.result.Skipped["profile"]++
+			continue
+		}
+		id, err := store.CreateProfile(ctx, &database.FormattingProfile{Name: p.Name, ParsedConfig: p.Config})
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+		im.profiles[p.ID] = id
+		im.result.Created["profile"]++
+	}
+	return nil
+}
+
+func (im *importer) importBots(ctx context.Context, b *Bundle, tokens map[int64]string) error {
+	store := database.NewTelegramBotStore(im.db)
+	for _, bot := range b.Bots {
+		if _, ok := im.bots[bot.ID]; ok {
+			im.result.Skipped["bot"]++
+			continue
+		}
+		id, err := store.CreateBot(ctx, tokens[bot.ID], bot.Description)
+		if err != nil {
+			return fmt.Errorf("bot %d: %w", bot.ID, err)
+		}
+		im.bots[bot.ID] = id
+		im.result.Created["bot"]++
+	}
+
+	pools, err := store.ListPools(ctx)
+	if err != nil {
+		return err
+	}
+	byName := map[string]int64{}
+	for _, pool := range pools {
+		byName[pool.Name] = pool.ID
+	}
+	for _, pool := range b.BotPools {
+		if id, ok := byName[pool.Name]; ok {
+			im.botPools[pool.ID] = id
+			im.result.Skipped["bot pool"]++
+			continue
+		}
+		id, err := store.CreatePool(ctx, pool.Name)
+		if err != nil {
+			return fmt.Errorf("bot pool %s: %w", pool.Name, err)
+		}
+		im.botPools[pool.ID] = id
+		for _, botID := range pool.BotIDs {
+			if err := store.AddBotToPool(ctx, id, im.bots[botID]); err != nil {
+				return fmt.Errorf("bot pool %s: %w", pool.Name, err)
+			}
+		}
+		im.result.Created["bot pool"]++
+	}
+	return nil
+}
+
+func (im *importer) importFeeds(ctx context.Context, b *Bundle) error {
+	store := database.NewFeedStore(im.db)
+	existing, err := store.ListFeeds(ctx)
+	if err != nil {
+		return err
+	}
+	have := map[string]bool{}
+	for _, f := range existing {
+		have[f.URL+"|"+f.TelegramChatID] = true
+	}
+	for _, f := range b.Feeds {
+		if have[f.URL+"|"+f.ChatID] {
+			im.result.Skipped["feed"]++
+			continue
+		}
+		feed := &database.Feed{
+			URL: f.URL, UserTitle: f.Title, FrequencySeconds: f.FrequencySeconds,
+			TelegramBotID: mapID(im.bots, f.BotID), BotPoolID: mapID(im.botPools, f.BotPoolID),
+			TelegramChatID: f.ChatID, TelegramThreadID: f.ThreadID, AutoCreateTopic: f.AutoCreateTopic,
+			SourceType: f.SourceType, ScrapeConfig: f.ScrapeConfig, UserAgent: f.UserAgent, RequestHeaders: f.RequestHeaders, Cookies: f.Cookies,
+			StaleAfterSeconds: f.StaleAfterSeconds, FetchTimeoutSeconds: f.FetchTimeoutSeconds, FetchMaxRetries: f.FetchMaxRetries,
+			FetchRetryDelaySeconds: f.FetchRetryDelaySeconds, TLS: f.TLS, UseFlareSolverr: f.UseFlareSolverr,
+			ProxyID: mapID(im.proxies, f.ProxyID), ProxyPoolID: mapID(im.proxyPools, f.ProxyPoolID), ProxyDirectFallback: f.ProxyDirectFallback,
+			FormattingProfileID: mapID(im.profiles, f.ProfileID), IsEnabled: f.Enabled,
+		}
+		id, err := store.CreateFeed(ctx, feed)
+		if err != nil {
+			return fmt.Errorf("feed %s: %w", f.URL, err)
+		}
+		if f.Debug {
+			if err := store.SetFeedDebug(ctx, id, true); err != nil {
+				return fmt.Errorf("feed %s: %w", f.URL, err)
+			}
+		}
+		if err := im.importAuth(ctx, store, id, f); err != nil {
+			return err
+		}
+		if len(f.Processed) > 0 {
+			if err := store.AddProcessedItems(ctx, id, f.Processed); err != nil {
+				return fmt.Errorf("feed %s: %w", f.URL, err)
+			}
+		}
+		im.result.Created["feed"]++
+	}
+	return nil
+}
+
+// importAuth stores an imported feed's credentials, or records that they
+// have to be set again when the bundle doesn't carry them.
+func (im *importer) importAuth(ctx context.Context, store *database.FeedStore, feedID int64, f Feed) error {
+	if f.Auth == nil {
+		return nil
+	}
+	if f.Auth.SealedSecret == "" || im.aead == nil {
+		im.result.NeedAuth = append(im.result.NeedAuth, f.URL)
+		return nil
+	}
+	secret, err := open(im.aead, f.Auth.SealedSecret)
+	if err != nil {
+		return fmt.Errorf("feed %s credentials: %w", f.URL, err)
+	}
+	creds := &database.FeedCredentials{Type: f.Auth.Type, Username: f.Auth.Username, Header: f.Auth.Header, Secret: secret}
+	if err := store.SetFeedCredentials(ctx, feedID, creds); err != nil {
+		return fmt.Errorf("feed %s: %w", f.URL, err)
+	}
+	return nil
+}
+
+// mapID translates a bundle ID to the importing database's, keeping nil.
+func mapID(ids map[int64]int64, id *int64) *int64 {
+	if id == nil {
+		return nil
+	}
+	mapped, ok := ids[*id]
+	if !ok {
+		return nil
+	}
+	return &mapped
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/spf13/cobra"
+)
+
+// NewAPIKeyCmd creates the 'apikey' command and its subcommands.
+func NewAPIKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apikey",
+		Short: "Manage API keys for the REST and gRPC APIs",
+		Long: `API keys authenticate clients of the management API. Each has a role:
+  viewer  read feeds, bots, proxies, profiles, stats and deliveries
+  editor  also create, change, test and fetch feeds and profiles
+  admin   also manage bots, proxies and API keys
+Only a hash of each key is stored, so a key is shown once, when it is added.
+Tokens in api.tokens keep working and act as admin keys.`,
+		Aliases: []string{"apikeys"},
+	}
+	cmd.AddCommand(newAPIKeyAddCmd())
+	cmd.AddCommand(newAPIKeyListCmd())
+	cmd.AddCommand(newAPIKeyRemoveCmd())
+	return cmd
+}
+
+func newAPIKeyAddCmd() *cobra.Command {
+	var role string
+	addCmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Create an API key and print it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !database.ValidRole(role) {
+				return fmt.Errorf("invalid role %q: must be admin, editor or viewer", role)
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			key, err := database.NewAPIKey()
+			if err != nil { return err }
+			id, err := database.NewAPIKeyStore(db).CreateAPIKey(cmd.Context(), args[0], role, key)
+			if err != nil { return fmt.Errorf("failed to add API key: %w", err) }
+			fmt.Printf("API key %q added with ID %d and role %s. It won't be shown again:\n%s\n", args[0], id, role, key)
+			return nil
+		},
+	}
+	addCmd.Flags().StringVar(&role, "role", database.RoleViewer, "Role of the key: admin, editor or viewer")
+	return addCmd
+}
+
+func newAPIKeyListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List API keys",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			keys, err := database.NewAPIKeyStore(db).ListAPIKeys(cmd.Context())
+			if err != nil { return fmt.Errorf("failed to list API keys: %w", err) }
+			if len(keys) == 0 {
+				fmt.Println("No API keys configured.")
+				return nil
+			}
+			fmt.Println("API Keys:")
+			for _, k := range keys {
+				lastUsed := "never"
+				if k.LastUsedAt != nil {
+					lastUsed = k.LastUsedAt.Local().Format("2006-01-02 15:04")
+				}
+				fmt.Printf("ID: %d, Name: %s, Role: %s, Last used: %s\n", k.ID, k.Name, k.Role, lastUsed)
+			}
+			return nil
+		},
+	}
+}
+
+func newAPIKeyRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <key_id>",
+		Short: "Revoke an API key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var keyID int64
+			if _, err := fmt.Sscan(args[0], &keyID); err != nil {
+				return fmt.Errorf("invalid key ID: %s", args[0])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			if err := database.NewAPIKeyStore(db).DeleteAPIKey(cmd.Context(), keyID); err != nil {
+				return fmt.Errorf("failed to remove API key: %w", err)
+			}
+			fmt.Printf("API key %d revoked.\n", keyID)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/spf13/cobra"
+)
+
+// NewArchiveCmd creates the 'archive' command for the items the bot delivered.
+func NewArchiveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "archive",
+		Short: "Search and export the items the bot delivered",
+		Long: `Every delivered item is kept with its title, link, text, chat and times.
+Builds with the sqlite_fts5 tag (as the Docker image) search them with SQLite's
+full-text index; other builds fall back to a slower substring search.`,
+	}
+	cmd.AddCommand(newArchiveSearchCmd())
+	cmd.AddCommand(newArchiveExportCmd())
+	return cmd
+}
+
+// archiveFilterFlags adds the flags selecting deliveries to cmd.
+func archiveFilterFlags(cmd *cobra.Command, filter *database.DeliveryFilter, since *time.Duration) {
+	cmd.Flags().Int64SliceVar(&filter.FeedIDs, "feed", nil, "Only items of this feed ID (repeatable)")
+	cmd.Flags().StringVar(&filter.ChatID, "chat", "", "Only items sent to this chat")
+	cmd.Flags().DurationVar(since, "since", 0, "Only items delivered within this long, e.g. 720h")
+}
+
+func newArchiveSearchCmd() *cobra.Command {
+	var filter database.DeliveryFilter
+	var since time.Duration
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search delivered items by title, text, author or feed",
+		Long: `Searches delivered items. With full-text search, the query may use "phrases",
+prefix* matches, OR and NOT, and the best matches are listed first.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if since > 0 {
+				filter.Since = time.Now().Add(-since)
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			found, err := database.NewDeliveryStore(db).SearchDeliveries(cmd.Context(), strings.Join(args, " "), filter)
+			if err != nil { return err }
+			if len(found) == 0 {
+				fmt.Println("No delivered items match.")
+				return nil
+			}
+			for _, d := range found {
+				fmt.Printf("%s  [%s] %s\n", d.DeliveredAt.Local().Format("2006-01-02 15:04"), d.FeedTitle, d.Title)
+				if d.Link != "" {
+					fmt.Printf("    %s\n", d.Link)
+				}
+			}
+			return nil
+		},
+	}
+	archiveFilterFlags(cmd, &filter, &since)
+	cmd.Flags().IntVar(&filter.Limit, "limit", 20, "Items listed at most; 0 lists all")
+	return cmd
+}
+
+// archiveRecord is a delivered item as exported.
+type archiveRecord struct {
+	ID          int64      `json:"id"`
+	FeedID      int64      `json:"feed_id"`
+	FeedTitle   string     `json:"feed_title"`
+	FeedURL     string     `json:"feed_url,omitempty"`
+	ChatID      string     `json:"chat_id,omitempty"`
+	GUID        string     `json:"guid,omitempty"`
+	Title       string     `json:"title"`
+	Link        string     `json:"link,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	Text        string     `json:"text,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	DeliveredAt time.Time  `json:"delivered_at"`
+}
+
+func newArchiveExportCmd() *cobra.Command {
+	var filter database.DeliveryFilter
+	var since time.Duration
+	var outputPath string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export delivered items as JSON Lines, oldest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if since > 0 {
+				filter.Since = time.Now().Add(-since)
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			deliveries, err := database.NewDeliveryStore(db).ListDeliveries(cmd.Context(), filter)
+			if err != nil { return err }
+
+			var out io.Writer = os.Stdout
+			if outputPath != "" && outputPath != "-" {
+				f, err := os.Create(outputPath)
+				if err != nil { return fmt.Errorf("creating output file: %w", err) }
+				defer f.Close()
+				out = f
+			}
+			w := bufio.NewWriter(out)
+			enc := json.NewEncoder(w)
+			for i := len(deliveries) - 1; i >= 0; i-- {
+				d := deliveries[i]
+				if err := enc.Encode(archiveRecord{
+					ID: d.ID, FeedID: d.FeedID, FeedTitle: d.FeedTitle, FeedURL: d.FeedURL, ChatID: d.ChatID, GUID: d.GUID,
+					Title: d.Title, Link: d.Link, Author: d.Author, Text: d.Text, PublishedAt: d.PublishedAt, DeliveredAt: d.DeliveredAt,
+				}); err != nil {
+					return fmt.Errorf("writing export: %w", err)
+				}
+			}
+			if err := w.Flush(); err != nil { return fmt.Errorf("writing export: %w", err) }
+			if out != os.Stdout {
+				fmt.Fprintf(os.Stderr, "Exported %d items to %s\n", len(deliveries), outputPath)
+			}
+			return nil
+		},
+	}
+	archiveFilterFlags(cmd, &filter, &since)
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "File to write (default: standard output)")
+	return cmd
+}
@@ -16,6 +16,7 @@ func NewBotCmd() *cobra.Command {
 	}
 	cmd.AddCommand(newBotAddCmd())
 	cmd.AddCommand(newBotListCmd())
+	cmd.AddCommand(newBotPoolCmd())
 	// Add update, remove commands
 	return cmd
 }
@@ -46,7 +47,7 @@ func newBotAddCmd() *cobra.Command {
             // it's a bit more complex if they don't run NewApplication.
             // Let's ensure main.go calls it.
 
-			db, err := database.Connect(AppCfg.DatabasePath, "internal/database/migrations")
+			db, err := database.Connect(AppCfg.DatabasePath)
 			if err != nil {
 				return fmt.Errorf("db connect: %w", err)
 			}
@@ -77,7 +78,7 @@ func newBotListCmd() *cobra.Command {
 		Short: "List configured Telegram Bots (metadata only)",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
-			db, err := database.Connect(AppCfg.DatabasePath, "internal/database/migrations")
+			db, err := database.Connect(AppCfg.DatabasePath)
 			if err != nil { return fmt.Errorf("db connect: %w", err) }
 			defer db.Close()
 			botStore := database.NewTelegramBotStore(db)
@@ -103,4 +104,100 @@ func newBotListCmd() *cobra.Command {
 		},
 	}
 	return listCmd
-}
\ No newline at end of file
+}
+
+// newBotPoolCmd groups the bot pool subcommands.
+func newBotPoolCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "pool",
+		Short:   "Manage bot pools used to round-robin deliveries across several bots",
+		Aliases: []string{"pools"},
+	}
+	cmd.AddCommand(newBotPoolCreateCmd())
+	cmd.AddCommand(newBotPoolMemberCmd("add", "Add a bot to a pool"))
+	cmd.AddCommand(newBotPoolMemberCmd("remove", "Remove a bot from a pool"))
+	cmd.AddCommand(newBotPoolListCmd())
+	return cmd
+}
+
+func newBotPoolCreateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "create <pool_name>",
+		Short: "Create a new, empty bot pool",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+			botStore := database.NewTelegramBotStore(db)
+
+			id, err := botStore.CreatePool(cmd.Context(), args[0])
+			if err != nil { return fmt.Errorf("failed to create bot pool: %w", err) }
+			fmt.Printf("Bot pool '%s' created with ID: %d\n", args[0], id)
+			return nil
+		},
+	}
+}
+
+// newBotPoolMemberCmd builds the "add" and "remove" membership subcommands, which share their arguments.
+func newBotPoolMemberCmd(action, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   action + " <pool_id> <bot_id>",
+		Short: short,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var poolID, botID int64
+			if _, err := fmt.Sscan(args[0], &poolID); err != nil {
+				return fmt.Errorf("invalid pool ID: %s", args[0])
+			}
+			if _, err := fmt.Sscan(args[1], &botID); err != nil {
+				return fmt.Errorf("invalid bot ID: %s", args[1])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+			botStore := database.NewTelegramBotStore(db)
+
+			if action == "add" {
+				if err := botStore.AddBotToPool(cmd.Context(), poolID, botID); err != nil {
+					return fmt.Errorf("failed to add bot to pool: %w", err)
+				}
+				fmt.Printf("Bot %d added to pool %d.\n", botID, poolID)
+				return nil
+			}
+			if err := botStore.RemoveBotFromPool(cmd.Context(), poolID, botID); err != nil {
+				return fmt.Errorf("failed to remove bot from pool: %w", err)
+			}
+			fmt.Printf("Bot %d removed from pool %d.\n", botID, poolID)
+			return nil
+		},
+	}
+}
+
+func newBotPoolListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List bot pools and their member bots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+			botStore := database.NewTelegramBotStore(db)
+
+			pools, err := botStore.ListPools(cmd.Context())
+			if err != nil { return fmt.Errorf("failed to list bot pools: %w", err) }
+			if len(pools) == 0 {
+				fmt.Println("No bot pools configured.")
+				return nil
+			}
+			fmt.Println("Configured Bot Pools:")
+			for _, p := range pools {
+				fmt.Printf("ID: %d, Name: %s, Bot IDs: %v\n", p.ID, p.Name, p.BotIDs)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/haytac/rss-telegram-bot/internal/bundle"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+// NewExportCmd writes the whole configuration to a bundle file.
+func NewExportCmd() *cobra.Command {
+	var output, passphrase string
+	var noHistory bool
+	cmd := &cobra.Command{
+		Use:   "export [--output bundle.json]",
+		Short: "Export feeds, bots, proxies, pools, rules and profiles to a JSON bundle",
+		Long: `Writes every proxy, proxy pool and rule, formatting profile, bot, bot pool and feed,
+with the items each feed already delivered, to a JSON bundle that 'import' reads on
+another host or database.
+
+Bot tokens and feed credentials are only included with --passphrase, sealed with a
+key derived from it; without it they must be given again on import. Proxy credentials
+are exported as stored. Either way, keep the bundle private.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			secret, err := proxy.ResolveSecret(passphrase)
+			if err != nil { return fmt.Errorf("resolving passphrase: %w", err) }
+
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			b, err := bundle.Export(cmd.Context(), db, bundle.ExportOptions{Passphrase: secret, NoHistory: noHistory})
+			if err != nil { return fmt.Errorf("exporting: %w", err) }
+			if output == "" || output == "-" {
+				return b.Write(cmd.OutOrStdout())
+			}
+			f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+			if err != nil { return fmt.Errorf("creating %s: %w", output, err) }
+			if err := b.Write(f); err != nil {
+				f.Close()
+				return fmt.Errorf("writing %s: %w", output, err)
+			}
+			if err := f.Close(); err != nil { return fmt.Errorf("writing %s: %w", output, err) }
+			fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d feeds, %d bots, %d proxies and %d profiles to %s.\n", len(b.Feeds), len(b.Bots), len(b.Proxies), len(b.Profiles), output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write (default stdout)")
+	cmd.Flags().StringVar(&passphrase, "passphrase", "", "Seal bot tokens and feed credentials into the bundle with this passphrase (literal, env:NAME or file:PATH)")
+	cmd.Flags().BoolVar(&noHistory, "no-history", false, "Leave out the items feeds already delivered (they are then sent again after import)")
+	return cmd
+}
+
+// NewImportCmd adds the contents of a bundle file to the database.
+func NewImportCmd() *cobra.Command {
+	var passphrase string
+	var botTokens []string
+	cmd := &cobra.Command{
+		Use:   "import <bundle.json>",
+		Short: "Import a bundle written by 'export'",
+		Long: `Adds the bundle's proxies, pools, rules, profiles, bots and feeds to the database.
+Entries that already exist are kept and reused: proxies, pools and profiles are matched
+by name, bots by token and feeds by URL and chat ID. Tokens and feed credentials are
+encrypted with this host's encryption_key.
+
+Bots not yet in the database need their tokens: pass the export --passphrase, or
+--bot-token ID=TOKEN (the bot's ID in the bundle) for each. Nothing is imported while
+a token is missing. Restart the bot for new feeds to be scheduled.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			f, err := os.Open(args[0])
+			if err != nil { return err }
+			b, err := bundle.Read(f)
+			f.Close()
+			if err != nil { return fmt.Errorf("reading %s: %w", args[0], err) }
+
+			opts := bundle.ImportOptions{BotTokens: map[int64]string{}}
+			if opts.Passphrase, err = proxy.ResolveSecret(passphrase); err != nil { return fmt.Errorf("resolving passphrase: %w", err) }
+			for _, entry := range botTokens {
+				idText, ref, ok := strings.Cut(entry, "=")
+				id, errID := strconv.ParseInt(idText, 10, 64)
+				if !ok || errID != nil { return fmt.Errorf("invalid --bot-token %q: expected ID=TOKEN", idText+"=...") }
+				if opts.BotTokens[id], err = proxy.ResolveSecret(ref); err != nil { return fmt.Errorf("bot %d token: %w", id, err) }
+			}
+			if AppCfg.DryRun {
+				fmt.Printf("Dry run: %s has %d feeds, %d bots, %d proxies and %d profiles; nothing imported.\n", args[0], len(b.Feeds), len(b.Bots), len(b.Proxies), len(b.Profiles))
+				return nil
+			}
+
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			result, err := bundle.Import(cmd.Context(), db, b, opts)
+			if err != nil { return fmt.Errorf("importing: %w", err) }
+			fmt.Print(result.String())
+			fmt.Println("Import complete. Restart the bot for new feeds to be scheduled.")
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&passphrase, "passphrase", "", "Passphrase given to export (literal, env:NAME or file:PATH)")
+	cmd.Flags().StringArrayVar(&botTokens, "bot-token", nil, "Token for a bot exported without it, as ID=TOKEN (TOKEN may be env:NAME or file:PATH); repeatable")
+	return cmd
+}
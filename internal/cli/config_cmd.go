@@ -1,19 +1,135 @@
 package cli
 
-// import (
-//  "fmt"
-//  "github.com/spf13/cobra"
-// )
-
-// func NewConfigCmd() *cobra.Command {
-//  cmd := &cobra.Command{
-//      Use:   "config",
-//      Short: "Manage application configuration settings (placeholder)",
-//  }
-//  // Add subcommands like get, set, list if needed
-//  cmd.AddCommand(&cobra.Command{Use: "get", RunE: func(cmd *cobra.Command, args []string) error {
-//      fmt.Println("Config get not implemented")
-//      return nil
-//  }})
-//  return cmd
-// }
\ No newline at end of file
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// starterConfig is the minimal configuration written by 'config init'. See
+// config.yml.example for every option.
+const starterConfig = `database_path: "./data/rss_bot.db"
+
+log:
+  level: "info" # debug, info, warn, error
+  console: true
+
+metrics_port: ":9090"
+
+default_fetch_frequency_seconds: 300
+
+# Set via RSS_BOT_ENCRYPTION_KEY instead to keep it out of this file.
+# encryption_key: ""
+`
+
+// systemdUnitTemplate is the unit written by 'config init --systemd'. The
+// bot runs as a Type=notify service: it reports READY=1 once feeds are
+// scheduled, RELOADING=1 on SIGHUP, and pings the watchdog from its main loop.
+const systemdUnitTemplate = `[Unit]
+Description=RSS to Telegram bot
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart=%s run --config %s
+ExecReload=/bin/kill -HUP $MAINPID
+# Relative paths in the config, like database_path, are resolved against this directory.
+WorkingDirectory=%s
+Restart=on-failure
+RestartSec=5s
+WatchdogSec=60s
+# Leaves room for shutdown_timeout (default 25s) to drain feed runs
+TimeoutStopSec=40s
+NoNewPrivileges=true
+ProtectSystem=strict
+ProtectHome=read-only
+ReadWritePaths=%s
+PrivateTmp=true
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// NewConfigCmd creates the 'config' command and its subcommands.
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Create configuration and service files",
+	}
+	cmd.AddCommand(newConfigInitCmd())
+	return cmd
+}
+
+func newConfigInitCmd() *cobra.Command {
+	var output string
+	var unit, force bool
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write a starter config file, or a systemd unit with --systemd",
+		Long: `Without flags, writes a minimal config.yml to start from.
+
+With --systemd, prints a systemd unit that runs the bot from the current
+directory with the config given by --config. Install it with e.g.
+  rss-telegram-bot config init --systemd --config /opt/rss-bot/config.yml \
+    --output /etc/systemd/system/rss-telegram-bot.service
+  systemctl daemon-reload && systemctl enable --now rss-telegram-bot`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content := starterConfig
+			if unit {
+				var err error
+				if content, err = systemdUnit(); err != nil { return err }
+			} else if output == "" {
+				output = "config.yml"
+			}
+			if output == "" {
+				fmt.Print(content)
+				return nil
+			}
+			if _, err := os.Stat(output); err == nil && !force {
+				return fmt.Errorf("%s already exists; use --force to overwrite it", output)
+			}
+			if err := os.WriteFile(output, []byte(content), 0o644); err != nil { return fmt.Errorf("failed to write %s: %w", output, err) }
+			fmt.Printf("Wrote %s.\n", output)
+			return nil
+		},
+	}
+	initCmd.Flags().BoolVar(&unit, "systemd", false, "Generate a systemd unit instead of a config file")
+	initCmd.Flags().StringVarP(&output, "output", "o", "", "File to write (default config.yml; stdout with --systemd)")
+	initCmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing file")
+	return initCmd
+}
+
+// systemdUnit fills in the unit template for this executable, the current
+// directory and the --config file.
+func systemdUnit() (string, error) {
+	if AppCfg == nil { return "", fmt.Errorf("configuration not loaded") }
+	exe, err := os.Executable()
+	if err != nil { return "", fmt.Errorf("locating executable: %w", err) }
+	dir, err := os.Getwd()
+	if err != nil { return "", fmt.Errorf("getting working directory: %w", err) }
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = "config.yml"
+	}
+	if !filepath.IsAbs(configPath) {
+		configPath = filepath.Join(dir, configPath)
+	}
+	dataDir := filepath.Dir(AppCfg.DatabasePath)
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(dir, dataDir)
+	}
+	return fmt.Sprintf(systemdUnitTemplate, quoteUnitArg(exe), quoteUnitArg(configPath), dir, dataDir), nil
+}
+
+// quoteUnitArg quotes a command line argument containing spaces for ExecStart.
+func quoteUnitArg(s string) string {
+	if !strings.ContainsAny(s, " \t\"") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -1,12 +1,16 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"time"
 
+	"github.com/haytac/rss-telegram-bot/internal/alert"
 	// Ensure database is imported if you use database.Connect
 	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+	"github.com/haytac/rss-telegram-bot/internal/telegram"
 	"github.com/spf13/cobra"
 	// config "github.com/haytac/rss-telegram-bot/internal/config" // Not needed if using global cli.AppCfg
 )
@@ -20,6 +24,7 @@ func NewDbCmd() *cobra.Command { // No appCfg parameter
 
 	cmd.AddCommand(newDbBackupCmd()) // No appCfg parameter
 	cmd.AddCommand(newDbRestoreCmd()) // No appCfg parameter
+	cmd.AddCommand(newDbPruneCmd())
 
 	return cmd
 }
@@ -35,7 +40,7 @@ func newDbBackupCmd() *cobra.Command { // No appCfg parameter
 				return fmt.Errorf("configuration not loaded for db backup")
 			}
 			// Use AppCfg directly
-			db, err := database.Connect(AppCfg.DatabasePath, "")
+			db, err := database.Open(AppCfg.DatabasePath)
 			if err != nil {
 				return fmt.Errorf("failed to connect to database: %w", err)
 			}
@@ -50,6 +55,7 @@ func newDbBackupCmd() *cobra.Command { // No appCfg parameter
 
 			fmt.Printf("Backing up database from '%s' to '%s'...\n", AppCfg.DatabasePath, outputPath)
 			if err := db.Backup(outputPath); err != nil {
+				alertBackupFailed(cmd.Context(), db, outputPath, err)
 				return fmt.Errorf("database backup failed: %w", err)
 			}
 			fmt.Println("Database backup successful.")
@@ -60,6 +66,68 @@ func newDbBackupCmd() *cobra.Command { // No appCfg parameter
 	return backupCmd
 }
 
+// newDbPruneCmd creates the 'db prune' command.
+func newDbPruneCmd() *cobra.Command {
+	var (
+		maxAge time.Duration
+		keep   int
+	)
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete processed items past their retention",
+		Long: `Deletes the records of processed items that were last seen in their feed
+longer ago than retention.processed_max_age, keeping each feed's newest
+retention.processed_keep, and those of deleted feeds. Feeds' own retention
+settings ('feed update --retention-max-age/--retention-keep') apply as well.
+The running bot does this every retention.interval. With --dry-run, only
+counts what would be deleted.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			retention := database.ProcessedRetention{MaxAge: AppCfg.Retention.ProcessedMaxAge, Keep: AppCfg.Retention.ProcessedKeep}
+			if cmd.Flags().Changed("max-age") {
+				retention.MaxAge = maxAge
+			}
+			if cmd.Flags().Changed("keep") {
+				retention.Keep = keep
+			}
+			if retention.MaxAge < 0 || retention.Keep < 0 {
+				return fmt.Errorf("--max-age and --keep must not be negative")
+			}
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			n, err := database.NewFeedStore(db).PruneAllProcessedItems(cmd.Context(), retention, time.Now(), AppCfg.DryRun)
+			if err != nil {
+				return fmt.Errorf("failed to prune processed items: %w", err)
+			}
+			if AppCfg.DryRun {
+				fmt.Printf("Dry run: %d processed items would be deleted.\n", n)
+			} else {
+				fmt.Printf("Deleted %d processed items.\n", n)
+			}
+			return nil
+		},
+	}
+	pruneCmd.Flags().DurationVar(&maxAge, "max-age", 0, "Delete items last seen longer ago than this (default: retention.processed_max_age); 0 prunes only deleted feeds' items")
+	pruneCmd.Flags().IntVar(&keep, "keep", 0, "Newest items kept per feed whatever their age (default: retention.processed_keep)")
+	return pruneCmd
+}
+
+// alertBackupFailed tells the admin chat that a backup failed, when
+// admin.alerts.backup_failed is set.
+func alertBackupFailed(ctx context.Context, db *database.DB, outputPath string, backupErr error) {
+	if !AppCfg.Admin.Alerts.BackupFailed || AppCfg.Admin.ChatID == "" {
+		return
+	}
+	alerter := alert.NewAlerter(AppCfg.Admin, database.NewTelegramBotStore(db), database.NewProxyStore(db), telegram.NewClient(proxy.NewHTTPClientFactory()))
+	msg := fmt.Sprintf("💾 <b>Database backup failed</b>\n%s\n%s", telegram.EscapeHTML(outputPath), telegram.EscapeHTML(backupErr.Error()))
+	if err := alerter.Send(ctx, msg); err != nil {
+		fmt.Printf("Warning: could not alert the admin chat: %v\n", err)
+	}
+}
+
 // Apply similar changes to newDbRestoreCmd and all RunE functions in proxy_cmd.go
 // Ensure they use the global `cli.AppCfg` variable.
 func newDbRestoreCmd() *cobra.Command { // No appCfg parameter
@@ -74,7 +142,7 @@ func newDbRestoreCmd() *cobra.Command { // No appCfg parameter
 				return fmt.Errorf("configuration not loaded for db restore")
 			}
 			// ... rest of the logic using AppCfg ...
-			tempDB, err := database.Connect(AppCfg.DatabasePath, "")
+			tempDB, err := database.Open(AppCfg.DatabasePath)
             if err != nil {
                 fmt.Printf("Note: Could not connect to current database (may not exist): %v\n", err)
                 if tempDB == nil { // This part might need review if Connect always errors on non-existent DB
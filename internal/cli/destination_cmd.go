@@ -0,0 +1,413 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/notify"
+	"github.com/spf13/cobra"
+)
+
+// newFeedDestinationCmd creates the 'feed destination' command and its subcommands.
+func newFeedDestinationCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "destination",
+		Short:   "Manage where a feed's items are delivered besides its Telegram chat",
+		Aliases: []string{"destinations", "dest"},
+	}
+	addCmd := &cobra.Command{
+		Use:   "add",
+		Short: "Add a destination to a feed",
+	}
+	addCmd.AddCommand(newDestinationAddEmailCmd())
+	addCmd.AddCommand(newDestinationAddWebhookCmd())
+	addCmd.AddCommand(newDestinationAddMattermostCmd())
+	addCmd.AddCommand(newDestinationAddTelegramCmd())
+	addCmd.AddCommand(newDestinationAddNtfyCmd())
+	addCmd.AddCommand(newDestinationAddPushoverCmd())
+	addCmd.AddCommand(newDestinationAddPocketCmd())
+	addCmd.AddCommand(newDestinationAddWallabagCmd())
+	addCmd.AddCommand(newDestinationAddReadwiseCmd())
+	cmd.AddCommand(addCmd)
+	cmd.AddCommand(newDestinationListCmd())
+	cmd.AddCommand(newDestinationRemoveCmd())
+	return cmd
+}
+
+// newDestinationAddEmailCmd creates the 'feed destination add email' command.
+func newDestinationAddEmailCmd() *cobra.Command {
+	var to []string
+	var subject, templateFile string
+	cmd := &cobra.Command{
+		Use:   "email <feed_id> --to ADDRESS...",
+		Short: "Email the feed's items to a list of recipients",
+		Long: `Emails each new item of the feed to the recipients, through the SMTP server
+configured under 'smtp'. The message is rendered with smtp.subject and
+smtp.template unless --subject or --template-file override them for this
+destination. The running bot applies the change from the feed's next run.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			var template string
+			if templateFile != "" {
+				data, err := os.ReadFile(templateFile)
+				if err != nil { return fmt.Errorf("reading template: %w", err) }
+				template = string(data)
+			}
+			config, err := emailDestinationConfig(to, subject, template)
+			if err != nil { return err }
+			return addDestination(cmd, feedID, database.DestinationEmail, config)
+		},
+	}
+	cmd.Flags().StringArrayVar(&to, "to", nil, "Recipient address, e.g. 'Ann <ann@example.com>' (repeatable, required)")
+	cmd.Flags().StringVar(&subject, "subject", "", "Go text/template for the subject (default: smtp.subject)")
+	cmd.Flags().StringVar(&templateFile, "template-file", "", "File with a Go html/template for the body (default: smtp.template)")
+	_ = cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+// newDestinationAddWebhookCmd creates the 'feed destination add webhook' command.
+func newDestinationAddWebhookCmd() *cobra.Command {
+	var secret, templateFile string
+	var headers []string
+	cmd := &cobra.Command{
+		Use:   "webhook <feed_id> <url>",
+		Short: "POST the feed's items as JSON to a URL",
+		Long: `POSTs each new item of the feed as JSON to the URL. Without --template-file the
+body holds the feed, the item and its formatted parts; a template (Go text/template
+over the same fields, with a json function to quote values) can shape it for the
+receiving service instead. With --secret the body is signed with HMAC-SHA256 in an
+X-Hub-Signature-256: sha256=<hex> header. Requests failing with a network error,
+429 or 5xx are retried as configured under 'webhook'.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			d := notify.WebhookDestination{URL: args[1], Secret: secret}
+			if d.Headers, err = parseHeaderFlags(headers); err != nil { return err }
+			if templateFile != "" {
+				data, err := os.ReadFile(templateFile)
+				if err != nil { return fmt.Errorf("reading template: %w", err) }
+				d.Template = string(data)
+			}
+			config, err := json.Marshal(d)
+			if err != nil { return err }
+			if _, err := notify.ParseWebhookDestination(config); err != nil { return err }
+			return addDestination(cmd, feedID, database.DestinationWebhook, config)
+		},
+	}
+	cmd.Flags().StringVar(&secret, "secret", "", "Key to sign request bodies with (literal, env:NAME or file:PATH; references are resolved at delivery time)")
+	cmd.Flags().StringArrayVar(&headers, "header", nil, "Extra request header as 'Name: value' (repeatable), e.g. for authentication")
+	cmd.Flags().StringVar(&templateFile, "template-file", "", "File with a Go text/template producing the JSON body")
+	return cmd
+}
+
+// newDestinationAddMattermostCmd creates the 'feed destination add mattermost' command.
+func newDestinationAddMattermostCmd() *cobra.Command {
+	var d notify.MattermostDestination
+	cmd := &cobra.Command{
+		Use:   "mattermost <feed_id> <webhook_url>",
+		Short: "Post the feed's items to a Mattermost incoming webhook",
+		Long: `Posts each new item of the feed to a Mattermost incoming webhook, converted to
+Markdown from the feed's formatting profile. The webhook URL is a credential: pass
+it as env:NAME or file:PATH to keep it out of the database. --channel posts to
+another channel than the webhook's own, e.g. to share one webhook between feeds.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			d.URL = args[1]
+			config, err := json.Marshal(d)
+			if err != nil { return err }
+			if _, err := notify.ParseMattermostDestination(config); err != nil { return err }
+			return addDestination(cmd, feedID, database.DestinationMattermost, config)
+		},
+	}
+	cmd.Flags().StringVar(&d.Channel, "channel", "", "Channel to post to instead of the webhook's, e.g. 'town-square' or '@username'")
+	cmd.Flags().StringVar(&d.Username, "username", "", "Name to post as, if the server allows overriding it")
+	cmd.Flags().StringVar(&d.IconURL, "icon-url", "", "Avatar to post with, if the server allows overriding it")
+	return cmd
+}
+
+// newDestinationAddTelegramCmd creates the 'feed destination add telegram' command.
+func newDestinationAddTelegramCmd() *cobra.Command {
+	var d notify.TelegramDestination
+	cmd := &cobra.Command{
+		Use:   "telegram <feed_id> <chat_id>",
+		Short: "Also send the feed's items to another Telegram chat",
+		Long: `Sends each new item of the feed to another Telegram chat as well, formatted like
+the items sent to the feed's own chat. The bot defaults to the feed's bot; feeds
+sending through a bot pool need --bot-id.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			d.ChatID = args[1]
+			if d.BotID == 0 {
+				if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+				db, err := database.Connect(AppCfg.DatabasePath)
+				if err != nil { return fmt.Errorf("db connect: %w", err) }
+				feed, err := database.NewFeedStore(db).GetFeedByID(cmd.Context(), feedID)
+				db.Close()
+				if err != nil { return fmt.Errorf("loading feed: %w", err) }
+				if feed == nil { return fmt.Errorf("feed %d not found", feedID) }
+				if feed.TelegramBotID == nil { return fmt.Errorf("feed %d has no bot of its own; pass --bot-id", feedID) }
+				d.BotID = *feed.TelegramBotID
+			}
+			config, err := json.Marshal(d)
+			if err != nil { return err }
+			if _, err := notify.ParseTelegramDestination(config); err != nil { return err }
+			return addDestination(cmd, feedID, database.DestinationTelegram, config)
+		},
+	}
+	cmd.Flags().Int64Var(&d.BotID, "bot-id", 0, "ID of the bot to send with (default: the feed's bot)")
+	cmd.Flags().IntVar(&d.ThreadID, "thread-id", 0, "Forum topic to post into")
+	return cmd
+}
+
+// newDestinationAddNtfyCmd creates the 'feed destination add ntfy' command.
+func newDestinationAddNtfyCmd() *cobra.Command {
+	var d notify.NtfyDestination
+	cmd := &cobra.Command{
+		Use:   "ntfy <feed_id> <topic>",
+		Short: "Push a notification to an ntfy topic for each item",
+		Long: `Publishes a short notification for each new item of the feed to an ntfy topic:
+the feed's title, the item's title, and the item's link to open on click. Topics on
+public servers can be read by anyone who guesses their name; protect them with an
+access token or use an unguessable topic.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			d.Topic = args[1]
+			config, err := json.Marshal(d)
+			if err != nil { return err }
+			if _, err := notify.ParseNtfyDestination(config); err != nil { return err }
+			return addDestination(cmd, feedID, database.DestinationNtfy, config)
+		},
+	}
+	cmd.Flags().StringVar(&d.Server, "server", "", "ntfy server (default https://ntfy.sh)")
+	cmd.Flags().StringVar(&d.Token, "token", "", "Access token (literal, env:NAME or file:PATH)")
+	cmd.Flags().IntVar(&d.Priority, "priority", 0, "Priority from 1 (min) to 5 (max)")
+	cmd.Flags().StringSliceVar(&d.Tags, "tag", nil, "Tag or emoji shortcode shown with the notification (repeatable)")
+	return cmd
+}
+
+// newDestinationAddPushoverCmd creates the 'feed destination add pushover' command.
+func newDestinationAddPushoverCmd() *cobra.Command {
+	var d notify.PushoverDestination
+	cmd := &cobra.Command{
+		Use:   "pushover <feed_id> --app-token TOKEN --user-key KEY",
+		Short: "Push a notification to a Pushover user for each item",
+		Long: `Sends a short Pushover notification for each new item of the feed: the feed's
+title, the item's title and its link. Both keys accept env:NAME or file:PATH
+references, resolved at delivery time.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			config, err := json.Marshal(d)
+			if err != nil { return err }
+			if _, err := notify.ParsePushoverDestination(config); err != nil { return err }
+			return addDestination(cmd, feedID, database.DestinationPushover, config)
+		},
+	}
+	cmd.Flags().StringVar(&d.AppToken, "app-token", "", "API token of your Pushover application (required)")
+	cmd.Flags().StringVar(&d.UserKey, "user-key", "", "User or group key to notify (required)")
+	cmd.Flags().StringVar(&d.Device, "device", "", "Only notify this device of the user")
+	cmd.Flags().IntVar(&d.Priority, "priority", 0, "Priority from -2 (silent) to 1 (high)")
+	cmd.Flags().StringVar(&d.Sound, "sound", "", "Notification sound, e.g. 'pushover' or 'none'")
+	_ = cmd.MarkFlagRequired("app-token")
+	_ = cmd.MarkFlagRequired("user-key")
+	return cmd
+}
+
+// newDestinationAddPocketCmd creates the 'feed destination add pocket' command.
+func newDestinationAddPocketCmd() *cobra.Command {
+	var d notify.PocketDestination
+	cmd := &cobra.Command{
+		Use:   "pocket <feed_id> --consumer-key KEY --access-token TOKEN",
+		Short: "Save each item's link to Pocket",
+		Long: `Saves the link of each new item of the feed to a Pocket account. Get a consumer
+key by creating a Pocket application, and an access token by authorizing it for
+the account. Both accept env:NAME or file:PATH references, resolved at delivery time.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return addReadLaterDestination(cmd, args[0], database.DestinationPocket, d)
+		},
+	}
+	cmd.Flags().StringVar(&d.ConsumerKey, "consumer-key", "", "Consumer key of your Pocket application (required)")
+	cmd.Flags().StringVar(&d.AccessToken, "access-token", "", "Access token of the account (required)")
+	addReadLaterFlags(cmd, &d.ReadLaterOptions)
+	_ = cmd.MarkFlagRequired("consumer-key")
+	_ = cmd.MarkFlagRequired("access-token")
+	return cmd
+}
+
+// newDestinationAddWallabagCmd creates the 'feed destination add wallabag' command.
+func newDestinationAddWallabagCmd() *cobra.Command {
+	var d notify.WallabagDestination
+	cmd := &cobra.Command{
+		Use:   "wallabag <feed_id> <url> --client-id ID --client-secret SECRET --username USER --password PASS",
+		Short: "Save each item's link to a wallabag instance",
+		Long: `Saves the link of each new item of the feed to a wallabag account, logging in
+with an API client created under "API clients management". The client secret and
+password accept env:NAME or file:PATH references, resolved at delivery time.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d.URL = args[1]
+			return addReadLaterDestination(cmd, args[0], database.DestinationWallabag, d)
+		},
+	}
+	cmd.Flags().StringVar(&d.ClientID, "client-id", "", "API client ID (required)")
+	cmd.Flags().StringVar(&d.ClientSecret, "client-secret", "", "API client secret (required)")
+	cmd.Flags().StringVar(&d.Username, "username", "", "Account username (required)")
+	cmd.Flags().StringVar(&d.Password, "password", "", "Account password (required)")
+	addReadLaterFlags(cmd, &d.ReadLaterOptions)
+	for _, name := range []string{"client-id", "client-secret", "username", "password"} {
+		_ = cmd.MarkFlagRequired(name)
+	}
+	return cmd
+}
+
+// newDestinationAddReadwiseCmd creates the 'feed destination add readwise' command.
+func newDestinationAddReadwiseCmd() *cobra.Command {
+	var d notify.ReadwiseDestination
+	cmd := &cobra.Command{
+		Use:   "readwise <feed_id> --token TOKEN",
+		Short: "Save each item's link to Readwise Reader",
+		Long: `Saves the link of each new item of the feed to the "Later" list of Readwise
+Reader. The access token (from readwise.io/access_token) accepts env:NAME or
+file:PATH references, resolved at delivery time.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return addReadLaterDestination(cmd, args[0], database.DestinationReadwise, d)
+		},
+	}
+	cmd.Flags().StringVar(&d.Token, "token", "", "Readwise access token (required)")
+	addReadLaterFlags(cmd, &d.ReadLaterOptions)
+	_ = cmd.MarkFlagRequired("token")
+	return cmd
+}
+
+// addReadLaterFlags adds the flags common to read-later destinations.
+func addReadLaterFlags(cmd *cobra.Command, opts *notify.ReadLaterOptions) {
+	cmd.Flags().StringVar(&opts.Match, "match", "", "Only save items whose title or link matches this regexp")
+	cmd.Flags().StringSliceVar(&opts.Tags, "tag", nil, "Tag to file saved items under (repeatable)")
+}
+
+// addReadLaterDestination checks and stores a read-later destination.
+func addReadLaterDestination(cmd *cobra.Command, feedArg, destType string, d interface{}) error {
+	feedID, err := strconv.ParseInt(feedArg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid feed ID: %s", feedArg)
+	}
+	config, err := json.Marshal(d)
+	if err != nil { return err }
+	if _, err := notify.ParseReadLaterDestination(destType, config); err != nil { return err }
+	return addDestination(cmd, feedID, destType, config)
+}
+
+// emailDestinationConfig builds and checks the config of an email destination.
+func emailDestinationConfig(to []string, subject, template string) (json.RawMessage, error) {
+	config, err := json.Marshal(notify.EmailDestination{To: to, Subject: subject, Template: template})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := notify.ParseEmailDestination(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// addDestination stores a destination of the given type for a feed.
+func addDestination(cmd *cobra.Command, feedID int64, destType string, config json.RawMessage) error {
+	if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+	if AppCfg.DryRun {
+		fmt.Printf("Dry run: would add %s destination %s to feed %d.\n", destType, config, feedID)
+		return nil
+	}
+	db, err := database.Connect(AppCfg.DatabasePath)
+	if err != nil { return fmt.Errorf("db connect: %w", err) }
+	defer db.Close()
+
+	feed, err := database.NewFeedStore(db).GetFeedByID(cmd.Context(), feedID)
+	if err != nil { return fmt.Errorf("loading feed: %w", err) }
+	if feed == nil { return fmt.Errorf("feed %d not found", feedID) }
+	id, err := database.NewDestinationStore(db).CreateDestination(cmd.Context(), &database.FeedDestination{FeedID: feedID, Type: destType, Config: config})
+	if err != nil { return fmt.Errorf("failed to add destination: %w", err) }
+	fmt.Printf("Destination added to feed %d with ID: %d\n", feedID, id)
+	return nil
+}
+
+// newDestinationListCmd creates the 'feed destination list' command.
+func newDestinationListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list <feed_id>",
+		Short: "List a feed's destinations",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			destinations, err := database.NewDestinationStore(db).ListFeedDestinations(cmd.Context(), feedID)
+			if err != nil { return fmt.Errorf("failed to list destinations: %w", err) }
+			if len(destinations) == 0 {
+				fmt.Printf("Feed %d has no destinations besides its Telegram chat.\n", feedID)
+				return nil
+			}
+			for _, d := range destinations {
+				fmt.Printf("ID: %d, Type: %s, Config: %s\n", d.ID, d.Type, d.Config)
+			}
+			return nil
+		},
+	}
+}
+
+// newDestinationRemoveCmd creates the 'feed destination remove' command.
+func newDestinationRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "remove <destination_id>",
+		Short:   "Remove a destination",
+		Aliases: []string{"rm"},
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid destination ID: %s", args[0])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			if err := database.NewDestinationStore(db).DeleteDestination(cmd.Context(), id); err != nil {
+				return err
+			}
+			fmt.Printf("Destination %d removed.\n", id)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/spf13/cobra"
+)
+
+// newFeedDigestCmd creates the 'feed digest' command and its subcommands.
+func newFeedDigestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "digest",
+		Short: "Send a feed's new items as one scheduled digest message",
+	}
+	cmd.AddCommand(newDigestSetCmd())
+	cmd.AddCommand(newDigestShowCmd())
+	cmd.AddCommand(newDigestOffCmd())
+	return cmd
+}
+
+// newDigestSetCmd creates the 'feed digest set' command.
+func newDigestSetCmd() *cobra.Command {
+	var daily, weekly bool
+	var at, weekday string
+	cmd := &cobra.Command{
+		Use:   "set <feed_id> --daily|--weekly",
+		Short: "Put a feed into digest mode, or change its schedule",
+		Long: `Puts the feed into digest mode: its new items are kept and sent together as
+one message at --at (local time of the bot) every day, or with --weekly on
+--weekday. The message is rendered with the digest_template of the feed's
+formatting profile, and moved to Telegraph like single items when it is longer
+than the profile's use_telegraph_threshold_chars. The running bot applies the
+change from the feed's next run.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			if daily == weekly { return fmt.Errorf("give one of --daily or --weekly") }
+			d := &database.FeedDigest{FeedID: feedID, Period: database.DigestDaily, At: at}
+			if weekly {
+				day, ok := database.ParseWeekday(weekday)
+				if !ok { return fmt.Errorf("--weekly needs --weekday, a day such as monday") }
+				d.Period, d.Weekday = database.DigestWeekly, strings.ToLower(day.String())
+			}
+			if _, _, err := d.LastDue(time.Now()); err != nil { return err }
+
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			if AppCfg.DryRun {
+				fmt.Printf("Dry run: would send feed %d as a %s.\n", feedID, describeDigest(d))
+				return nil
+			}
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			feed, err := database.NewFeedStore(db).GetFeedByID(cmd.Context(), feedID)
+			if err != nil { return fmt.Errorf("loading feed: %w", err) }
+			if feed == nil { return fmt.Errorf("feed %d not found", feedID) }
+			if err := database.NewDigestStore(db).SetFeedDigest(cmd.Context(), d); err != nil {
+				return fmt.Errorf("failed to set digest: %w", err)
+			}
+			fmt.Printf("Feed %d is sent as a %s.\n", feedID, describeDigest(d))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&daily, "daily", false, "Send a digest every day")
+	cmd.Flags().BoolVar(&weekly, "weekly", false, "Send a digest every week, on --weekday")
+	cmd.Flags().StringVar(&at, "at", "08:00", "Time of day to send the digest, as HH:MM")
+	cmd.Flags().StringVar(&weekday, "weekday", "", "Day of the week for --weekly, such as monday")
+	return cmd
+}
+
+// newDigestShowCmd creates the 'feed digest show' command.
+func newDigestShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <feed_id>",
+		Short: "Show a feed's digest schedule and the items waiting for it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			store := database.NewDigestStore(db)
+			d, err := store.GetFeedDigest(cmd.Context(), feedID)
+			if err != nil { return fmt.Errorf("failed to load digest: %w", err) }
+			if d == nil {
+				fmt.Printf("Feed %d is not in digest mode; its items are sent one by one.\n", feedID)
+				return nil
+			}
+			items, err := store.ListDigestItems(cmd.Context(), feedID)
+			if err != nil { return fmt.Errorf("failed to list waiting items: %w", err) }
+			lastSent := "never"
+			if d.LastSentAt != nil {
+				lastSent = d.LastSentAt.Local().Format(time.RFC3339)
+			}
+			fmt.Printf("Feed %d is sent as a %s.\nLast digest: %s, items waiting: %d\n", feedID, describeDigest(d), lastSent, len(items))
+			return nil
+		},
+	}
+}
+
+// newDigestOffCmd creates the 'feed digest off' command.
+func newDigestOffCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "off <feed_id>",
+		Short: "Take a feed out of digest mode",
+		Long: `Takes the feed out of digest mode, so its new items are sent one by one again.
+Items still waiting for a digest are sent by the feed's next run if the feed
+still lists them.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			if err := database.NewDigestStore(db).DeleteFeedDigest(cmd.Context(), feedID); err != nil {
+				return err
+			}
+			fmt.Printf("Feed %d is no longer in digest mode.\n", feedID)
+			return nil
+		},
+	}
+}
+
+// describeDigest describes the schedule of a digest, such as "daily digest at 08:00".
+func describeDigest(d *database.FeedDigest) string {
+	if d.Period == database.DigestWeekly {
+		return fmt.Sprintf("weekly digest on %s at %s", d.Weekday, d.At)
+	}
+	return fmt.Sprintf("daily digest at %s", d.At)
+}
@@ -1,10 +1,19 @@
 package cli
 
 import (
+	"context"
 	"fmt"
-	// "strconv" // <--- REMOVE THIS LINE if not used
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"strconv"
 
 	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+	"github.com/haytac/rss-telegram-bot/internal/rss"
+	"github.com/haytac/rss-telegram-bot/internal/telegram"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
 	// "github.com/haytac/rss-telegram-bot/internal/config" // Not needed if using global AppCfg
 	"github.com/spf13/cobra"
 )
@@ -21,7 +30,17 @@ func NewFeedCmd() *cobra.Command {
 	// Subcommand constructors no longer take appCfg.
 	cmd.AddCommand(newFeedAddCmd())
 	cmd.AddCommand(newFeedListCmd())
-	// Add update, remove commands
+	cmd.AddCommand(newFeedValidateCmd())
+	cmd.AddCommand(newFeedDebugCmd())
+	cmd.AddCommand(newFeedDestinationCmd())
+	cmd.AddCommand(newFeedFilterCmd())
+	cmd.AddCommand(newFeedDigestCmd())
+	cmd.AddCommand(newFeedImportOPMLCmd())
+	cmd.AddCommand(newFeedExportOPMLCmd())
+	cmd.AddCommand(newFeedUpdateCmd())
+	cmd.AddCommand(newFeedRemoveCmd())
+	cmd.AddCommand(newFeedSetEnabledCmd(true))
+	cmd.AddCommand(newFeedSetEnabledCmd(false))
 
 	return cmd
 }
@@ -33,10 +52,38 @@ func newFeedAddCmd() *cobra.Command {
 		userTitle           string
 		freqSeconds         int
 		botTokenID          int64
+		botPoolID           int64
 		chatID              string
 		proxyID             int64
+		proxyPoolID         int64
+		directFallback      bool
 		formatProfileID     int64
 		enabled             bool
+		skipVerify          bool
+		threadID            int
+		autoTopic           bool
+		sourceType          string
+		autoPick            bool
+		userAgent           string
+		headers             []string
+		cookieFlags         []string
+		staleAfter          time.Duration
+		fetchTimeout        time.Duration
+		fetchMaxRetries     int
+		fetchRetryDelay     time.Duration
+		tlsFlags            database.TLSConfig
+		useFlareSolverr     bool
+		authUsername        string
+		authPassword        string
+		authBearerToken     string
+		authHeaderName      string
+		authHeaderValue     string
+		noDiscover          bool
+		scrapeCfg           database.ScrapeConfig
+		backfill            string
+		backfillMaxAge      time.Duration
+		emailTo             []string
+		tags                []string
 	)
 
 	addCmd := &cobra.Command{
@@ -51,7 +98,7 @@ func newFeedAddCmd() *cobra.Command {
 				return fmt.Errorf("configuration not loaded for feed add")
 			}
 
-			db, err := database.Connect(AppCfg.DatabasePath, "internal/database/migrations")
+			db, err := database.Connect(AppCfg.DatabasePath)
 			if err != nil {
 				return fmt.Errorf("failed to connect to database: %w", err)
 			}
@@ -74,10 +121,57 @@ func newFeedAddCmd() *cobra.Command {
 			// }
 
 
+			if chatID == "" && len(emailTo) == 0 {
+				return fmt.Errorf("specify --chat-id, --email-to or both")
+			}
+			var emailDest []byte
+			if len(emailTo) > 0 {
+				if emailDest, err = emailDestinationConfig(emailTo, "", ""); err != nil {
+					return err
+				}
+			}
+
+			requestHeaders, err := parseHeaderFlags(headers)
+			if err != nil {
+				return err
+			}
+			cookies, err := parseCookieFlags(cookieFlags)
+			if err != nil {
+				return err
+			}
+			creds, err := feedCredentialsFromFlags(authUsername, authPassword, authBearerToken, authHeaderName, authHeaderValue)
+			if err != nil {
+				return err
+			}
+			feedTLS, err := tlsConfigFromFlags(cmd, tlsFlags)
+			if err != nil {
+				return err
+			}
+			fetchOpts := interfaces.FetchOptions{UserAgent: AppCfg.UserAgent, Headers: requestHeaders, Auth: creds, Cookies: cookies, TLS: feedTLS}
+			if userAgent != "" {
+				fetchOpts.UserAgent = userAgent
+			}
+
+			if sourceType == database.FeedSourceRSS && rss.AdapterFor(urlFromArg) != nil {
+				if _, err := rss.ResolveSourceURL(urlFromArg); err != nil {
+					return err
+				}
+			} else if sourceType == database.FeedSourceRSS && !noDiscover && !useFlareSolverr {
+				var feedProxyID *int64
+				if cmd.Flags().Changed("proxy-id") {
+					feedProxyID = &proxyID
+				}
+				urlFromArg, err = discoverFeedURL(cmd, db, urlFromArg, feedProxyID, fetchOpts, autoPick)
+				if err != nil {
+					return err
+				}
+			}
+
 			feed := &database.Feed{
 				URL:              urlFromArg,
 				FrequencySeconds: freqSeconds, // Will be the flag's value or its static default
 				TelegramChatID:   chatID,
+				Tags:             tags,
 				IsEnabled:        enabled,
 			}
 			if cmd.Flags().Changed("title") {
@@ -86,17 +180,110 @@ func newFeedAddCmd() *cobra.Command {
 			if cmd.Flags().Changed("bot-token-id") {
 				feed.TelegramBotID = &botTokenID
 			}
+			if cmd.Flags().Changed("thread-id") {
+				feed.TelegramThreadID = &threadID
+			}
+			feed.AutoCreateTopic = autoTopic
+			if userAgent != "" {
+				feed.UserAgent = &userAgent
+			}
+			feed.RequestHeaders = requestHeaders
+			feed.Cookies = cookies
+			feed.TLS = feedTLS
+			feed.UseFlareSolverr = useFlareSolverr
+			if cmd.Flags().Changed("stale-after") {
+				secs := int(staleAfter / time.Second)
+				feed.StaleAfterSeconds = &secs
+			}
+			if cmd.Flags().Changed("timeout") {
+				secs := int(fetchTimeout / time.Second)
+				feed.FetchTimeoutSeconds = &secs
+			}
+			if cmd.Flags().Changed("max-retries") {
+				feed.FetchMaxRetries = &fetchMaxRetries
+			}
+			if cmd.Flags().Changed("retry-delay") {
+				secs := int(fetchRetryDelay / time.Second)
+				feed.FetchRetryDelaySeconds = &secs
+			}
+			if backfill != "" {
+				if sourceType != database.FeedSourceRSS {
+					return fmt.Errorf("--backfill is only supported for RSS/Atom feeds")
+				}
+				limit, err := parseBackfillFlag(backfill)
+				if err != nil {
+					return err
+				}
+				feed.BackfillLimit = &limit
+				if backfillMaxAge > 0 {
+					secs := int(backfillMaxAge / time.Second)
+					feed.BackfillMaxAgeSeconds = &secs
+				}
+			} else if cmd.Flags().Changed("backfill-max-age") {
+				return fmt.Errorf("--backfill-max-age requires --backfill")
+			}
+			switch sourceType {
+			case database.FeedSourceRSS:
+			case database.FeedSourceScrape:
+				if scrapeCfg.ItemSelector == "" {
+					return fmt.Errorf("--scrape-item is required for scrape feeds")
+				}
+				feed.ScrapeConfig = &scrapeCfg
+			case database.FeedSourceSitemap:
+			case database.FeedSourceIMAP:
+				if !strings.HasPrefix(urlFromArg, "imap://") && !strings.HasPrefix(urlFromArg, "imaps://") {
+					return fmt.Errorf("IMAP feeds take an imaps://host/Mailbox (or imap:// for STARTTLS) URL")
+				}
+				if creds == nil || creds.Type != database.FeedAuthBasic {
+					return fmt.Errorf("IMAP feeds need --auth-username and --auth-password for the mailbox login")
+				}
+			default:
+				return fmt.Errorf("unknown --type %q (expected %q, %q, %q or %q)", sourceType, database.FeedSourceRSS, database.FeedSourceScrape, database.FeedSourceSitemap, database.FeedSourceIMAP)
+			}
+			feed.SourceType = sourceType
+			if cmd.Flags().Changed("bot-pool-id") {
+				feed.BotPoolID = &botPoolID
+			}
 			if cmd.Flags().Changed("proxy-id") {
 				feed.ProxyID = &proxyID
 			}
+			if cmd.Flags().Changed("proxy-pool-id") {
+				pool, err := database.NewProxyStore(db).GetPoolByID(cmd.Context(), proxyPoolID)
+				if err != nil {
+					return fmt.Errorf("failed to load proxy pool: %w", err)
+				}
+				if pool == nil {
+					return fmt.Errorf("proxy pool %d not found", proxyPoolID)
+				}
+				feed.ProxyPoolID = &proxyPoolID
+			}
+			if cmd.Flags().Changed("proxy-direct-fallback") {
+				feed.ProxyDirectFallback = &directFallback
+			}
 			if cmd.Flags().Changed("format-profile-id") {
 				feed.FormattingProfileID = &formatProfileID
 			}
 
+			if chatID != "" && !skipVerify && !AppCfg.DryRun {
+				if err := verifyFeedDestination(cmd.Context(), db, feed); err != nil {
+					return fmt.Errorf("chat verification failed (use --skip-verify to add anyway): %w", err)
+				}
+			}
+
 			id, err := feedStore.CreateFeed(cmd.Context(), feed)
 			if err != nil {
 				return fmt.Errorf("failed to add feed: %w", err)
 			}
+			if creds != nil {
+				if err := feedStore.SetFeedCredentials(cmd.Context(), id, creds); err != nil {
+					return fmt.Errorf("feed %d added but storing its credentials failed: %w", id, err)
+				}
+			}
+			if emailDest != nil {
+				if _, err := database.NewDestinationStore(db).CreateDestination(cmd.Context(), &database.FeedDestination{FeedID: id, Type: database.DestinationEmail, Config: emailDest}); err != nil {
+					return fmt.Errorf("feed %d added but storing its email destination failed: %w", id, err)
+				}
+			}
 			fmt.Printf("Feed added successfully with ID: %d\n", id)
 			return nil
 		},
@@ -110,42 +297,95 @@ func newFeedAddCmd() *cobra.Command {
 	// The RunE logic can then override if the flag wasn't explicitly set by the user.
 	addCmd.Flags().IntVarP(&freqSeconds, "freq", "f", 300, "Fetch frequency in seconds (default: 300 if AppCfg not loaded, otherwise uses AppCfg.DefaultFetchFreq if not specified)")
 	addCmd.Flags().Int64Var(&botTokenID, "bot-token-id", 0, "ID of the Telegram Bot configuration to use")
-	addCmd.Flags().StringVar(&chatID, "chat-id", "", "Telegram Chat ID (numeric) or @channelusername (required)")
-	_ = addCmd.MarkFlagRequired("chat-id") // Error can be ignored for MarkFlagRequired in init
+	addCmd.Flags().Int64Var(&botPoolID, "bot-pool-id", 0, "ID of a bot pool to round-robin deliveries across (overrides --bot-token-id)")
+	addCmd.Flags().StringVar(&chatID, "chat-id", "", "Telegram Chat ID (numeric) or @channelusername (required unless --email-to is given)")
+	addCmd.Flags().StringArrayVar(&emailTo, "email-to", nil, "Also email items to this address through the configured SMTP server (repeatable); without --chat-id items are only emailed")
 	addCmd.Flags().Int64Var(&proxyID, "proxy-id", 0, "ID of the Proxy configuration to use")
+	addCmd.Flags().Int64Var(&proxyPoolID, "proxy-pool-id", 0, "ID of a proxy pool to rotate fetches through (overrides --proxy-id)")
+	addCmd.Flags().BoolVar(&directFallback, "proxy-direct-fallback", false, "Whether fetches may bypass the proxy when it fails or is down (default: the proxy's setting, then the config)")
 	addCmd.Flags().Int64Var(&formatProfileID, "format-profile-id", 0, "ID of the Formatting Profile to use")
 	addCmd.Flags().BoolVar(&enabled, "enabled", true, "Enable the feed immediately")
+	addCmd.Flags().StringArrayVar(&tags, "tag", nil, "Tag for grouping the feed (repeatable)")
+	addCmd.Flags().IntVar(&threadID, "thread-id", 0, "Forum topic (message thread ID) to post into")
+	addCmd.Flags().BoolVar(&autoTopic, "auto-topic", false, "Create a forum topic named after the feed and post all items there")
+	addCmd.Flags().StringVar(&sourceType, "type", database.FeedSourceRSS, "Source type: 'rss' for RSS/Atom/JSON Feed, 'scrape' to build items from an HTML page, 'sitemap' to announce new or modified URLs from a sitemap.xml, 'imap' to deliver emails from an imaps://host/Mailbox")
+	addCmd.Flags().StringVar(&scrapeCfg.ItemSelector, "scrape-item", "", "CSS selector matching each item (scrape feeds)")
+	addCmd.Flags().StringVar(&scrapeCfg.TitleSelector, "scrape-title", "", "CSS selector for the title within an item (default: item text)")
+	addCmd.Flags().StringVar(&scrapeCfg.LinkSelector, "scrape-link", "", "CSS selector for the link within an item (default: first <a href>)")
+	addCmd.Flags().StringVar(&scrapeCfg.DateSelector, "scrape-date", "", "CSS selector for the date within an item")
+	addCmd.Flags().StringVar(&scrapeCfg.DateLayout, "scrape-date-layout", "", "Go time layout for --scrape-date (default: try common formats)")
+	addCmd.Flags().StringVar(&scrapeCfg.ContentSelector, "scrape-content", "", "CSS selector for the item body within an item")
+	addCmd.Flags().StringVar(&userAgent, "user-agent", "", "User-Agent to fetch this feed with (default: the configured user_agent)")
+	addCmd.Flags().StringArrayVar(&headers, "header", nil, "Extra request header as 'Name: value' (repeatable), e.g. for API keys")
+	addCmd.Flags().StringArrayVar(&cookieFlags, "cookie", nil, "Cookie to send as 'name=value' (repeatable), e.g. a session cookie; cookies the site sets are kept per feed")
+	addCmd.Flags().DurationVar(&staleAfter, "stale-after", 0, "Alert the admin chat if the feed publishes nothing for this long, e.g. 72h; 0 disables (default: the configured stale_feed_after)")
+	addCmd.Flags().DurationVar(&fetchTimeout, "timeout", 0, "Per-request fetch timeout for this feed, e.g. 2m (default: the configured fetch.timeout)")
+	addCmd.Flags().IntVar(&fetchMaxRetries, "max-retries", 0, "Retries after a failed fetch of this feed (default: the configured fetch.max_retries)")
+	addCmd.Flags().DurationVar(&fetchRetryDelay, "retry-delay", 0, "Initial backoff between fetch retries, doubled each time (default: the configured fetch.retry_delay)")
+	addTLSFlags(addCmd, &tlsFlags)
+	addCmd.Flags().BoolVar(&useFlareSolverr, "flaresolverr", false, "Always fetch through the configured FlareSolverr instance (for Cloudflare-protected sites); implies --no-discover")
+	addCmd.Flags().StringVar(&authUsername, "auth-username", "", "Username for HTTP Basic auth on protected feeds")
+	addCmd.Flags().StringVar(&authPassword, "auth-password", "", "Password for HTTP Basic auth (or set RSS_BOT_FEED_AUTH_PASSWORD); stored encrypted")
+	addCmd.Flags().StringVar(&authBearerToken, "auth-bearer-token", "", "Bearer token for protected feeds (or set RSS_BOT_FEED_AUTH_TOKEN); stored encrypted")
+	addCmd.Flags().StringVar(&authHeaderName, "auth-header", "", "Header carrying a secret for protected feeds, e.g. X-Api-Key (one per feed); unlike --header, its value is stored encrypted and not sent to other hosts the feed redirects to")
+	addCmd.Flags().StringVar(&authHeaderValue, "auth-header-value", "", "Value of --auth-header (or set RSS_BOT_FEED_AUTH_HEADER_VALUE); stored encrypted")
+	addCmd.Flags().BoolVar(&autoPick, "auto", false, "If the URL is a web page advertising several feeds, use the first one instead of prompting")
+	addCmd.Flags().BoolVar(&noDiscover, "no-discover", false, "Store the URL as given without checking it for a feed or discovering one")
+	addCmd.Flags().BoolVar(&skipVerify, "skip-verify", false, "Don't check that the bot can post to the chat before adding the feed")
+	addCmd.Flags().StringVar(&backfill, "backfill", "", "Also deliver older items from the feed's RFC 5005 archive pages (rel=\"prev-archive\"): a number of items, or 'all'")
+	addCmd.Flags().DurationVar(&backfillMaxAge, "backfill-max-age", 0, "Skip archived items older than this, e.g. 720h (requires --backfill)")
 
 	return addCmd
 }
 
 // newFeedListCmd no longer takes appCfg
 func newFeedListCmd() *cobra.Command {
+	var filter database.FeedListFilter
+	var enabledOnly, disabledOnly bool
 	listCmd := &cobra.Command{
 		Use:   "list",
-		Short: "List all configured RSS feeds",
+		Short: "List configured RSS feeds, enabled or not",
+		Long: `Lists configured feeds, enabled or not, narrowed by the filter flags. With
+--failing, lists only feeds whose last fetch failed, with how many fetches
+failed in a row and the last error. --limit and --offset page through long lists.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if enabledOnly && disabledOnly {
+				return fmt.Errorf("specify at most one of --enabled or --disabled")
+			}
+			if filter.Limit < 0 || filter.Offset < 0 {
+				return fmt.Errorf("--limit and --offset can't be negative")
+			}
+			if enabledOnly || disabledOnly {
+				filter.Enabled = &enabledOnly
+			}
 			// Use the global cli.AppCfg
 			if AppCfg == nil {
 				return fmt.Errorf("configuration not loaded for feed list")
 			}
-			db, err := database.Connect(AppCfg.DatabasePath, "internal/database/migrations")
+			db, err := database.Connect(AppCfg.DatabasePath)
 			if err != nil {
 				return fmt.Errorf("failed to list feeds: %w", err)
 			}
 			defer db.Close()
 			feedStore := database.NewFeedStore(db)
 
-			feeds, err := feedStore.GetEnabledFeeds(cmd.Context()) // Or a ListAllFeeds method
+			feeds, err := feedStore.FindFeeds(cmd.Context(), filter)
 			if err != nil {
 				return fmt.Errorf("failed to list feeds: %w", err)
 			}
-
 			if len(feeds) == 0 {
-				fmt.Println("No feeds configured.")
+				if filter != (database.FeedListFilter{}) {
+					fmt.Println("No feeds match.")
+				} else {
+					fmt.Println("No feeds configured.")
+				}
 				return nil
 			}
-			fmt.Println("Configured Feeds:")
+			if filter.Failing {
+				fmt.Println("Failing Feeds:")
+			} else {
+				fmt.Println("Configured Feeds:")
+			}
 			for _, f := range feeds {
 				title := f.URL
 				if f.UserTitle != nil && *f.UserTitle != "" {
@@ -155,11 +395,360 @@ func newFeedListCmd() *cobra.Command {
 				if f.IsEnabled {
 					status = "Enabled"
 				}
-				fmt.Printf("ID: %d, Title: %s, URL: %s, Freq: %ds, ChatID: %s, Status: %s\n",
+				fmt.Printf("ID: %d, Title: %s, URL: %s, Freq: %ds, ChatID: %s, Status: %s",
 					f.ID, title, f.URL, f.FrequencySeconds, f.TelegramChatID, status)
+				if len(f.Tags) > 0 {
+					fmt.Printf(", Tags: %s", strings.Join(f.Tags, ", "))
+				}
+				if f.ConsecutiveFailures > 0 {
+					fmt.Printf(", Failures: %d", f.ConsecutiveFailures)
+				}
+				fmt.Println()
+				if filter.Failing && f.LastError != nil {
+					lastErrorAt := ""
+					if f.LastErrorAt != nil {
+						lastErrorAt = " (" + f.LastErrorAt.Local().Format("2006-01-02 15:04") + ")"
+					}
+					fmt.Printf("    Last error%s: %s\n", lastErrorAt, *f.LastError)
+				}
 			}
 			return nil
 		},
 	}
+	listCmd.Flags().BoolVar(&filter.Failing, "failing", false, "List only feeds whose last fetch failed, with their last error")
+	listCmd.Flags().BoolVar(&enabledOnly, "enabled", false, "List only enabled feeds")
+	listCmd.Flags().BoolVar(&disabledOnly, "disabled", false, "List only disabled feeds")
+	listCmd.Flags().Int64Var(&filter.BotID, "bot-token-id", 0, "List only feeds sending with this Telegram Bot configuration")
+	listCmd.Flags().StringVar(&filter.ChatID, "chat-id", "", "List only feeds sending to this chat")
+	listCmd.Flags().StringVar(&filter.Tag, "tag", "", "List only feeds with this tag")
+	listCmd.Flags().StringVar(&filter.URLContains, "url", "", "List only feeds whose URL contains this text")
+	listCmd.Flags().IntVar(&filter.Limit, "limit", 0, "List at most this many feeds (0 lists all)")
+	listCmd.Flags().IntVar(&filter.Offset, "offset", 0, "Skip this many matching feeds, to page with --limit")
 	return listCmd
-}
\ No newline at end of file
+}
+
+// newFeedDebugCmd creates the 'feed debug' command.
+func newFeedDebugCmd() *cobra.Command {
+	var on, off bool
+	debugCmd := &cobra.Command{
+		Use:   "debug <feed_id> --on|--off",
+		Short: "Log one feed's processing at debug level",
+		Long: `Logs everything about one feed's runs at debug level, whatever log.level is:
+request and response headers (credentials redacted) and each formatted message.
+The running bot applies the change from the feed's next run.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if on == off {
+				return fmt.Errorf("specify exactly one of --on or --off")
+			}
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			if err := database.NewFeedStore(db).SetFeedDebug(cmd.Context(), feedID, on); err != nil {
+				return fmt.Errorf("failed to update feed: %w", err)
+			}
+			if on {
+				fmt.Printf("Debug logging enabled for feed %d.\n", feedID)
+			} else {
+				fmt.Printf("Debug logging disabled for feed %d.\n", feedID)
+			}
+			return nil
+		},
+	}
+	debugCmd.Flags().BoolVar(&on, "on", false, "Enable debug logging for the feed")
+	debugCmd.Flags().BoolVar(&off, "off", false, "Disable debug logging for the feed")
+	return debugCmd
+}
+
+// newFeedValidateCmd creates the 'feed validate' command.
+func newFeedValidateCmd() *cobra.Command {
+	var (
+		proxyID   int64
+		userAgent string
+		headers   []string
+		cookies   []string
+	)
+	validateCmd := &cobra.Command{
+		Use:   "validate <url>",
+		Short: "Fetch a feed and report problems that affect how the bot handles it",
+		Long: `Fetches a feed and reports issues such as items without GUIDs or dates,
+malformed XML the parser had to recover from, very large item counts, and
+missing caching headers. Exits with an error if any finding is an error.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if AppCfg == nil {
+				return fmt.Errorf("configuration not loaded for feed validate")
+			}
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil {
+				return fmt.Errorf("failed to connect to database: %w", err)
+			}
+			defer db.Close()
+
+			requestHeaders, err := parseHeaderFlags(headers)
+			if err != nil {
+				return err
+			}
+			requestCookies, err := parseCookieFlags(cookies)
+			if err != nil {
+				return err
+			}
+			opts := interfaces.FetchOptions{UserAgent: AppCfg.UserAgent, Headers: requestHeaders, Cookies: requestCookies}
+			if userAgent != "" {
+				opts.UserAgent = userAgent
+			}
+			var feedProxyID *int64
+			if cmd.Flags().Changed("proxy-id") {
+				feedProxyID = &proxyID
+			}
+			httpClient, err := feedCheckClient(cmd, db, feedProxyID, opts)
+			if err != nil {
+				return err
+			}
+
+			report, err := rss.ValidateFeed(cmd.Context(), httpClient, args[0], opts)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if report.FeedType != "" {
+				fmt.Fprintf(out, "%s: %q (%s, %d items)\n", report.URL, report.Title, report.FeedType, report.Items)
+			}
+			if len(report.Findings) == 0 {
+				fmt.Fprintln(out, "No issues found.")
+				return nil
+			}
+			for _, f := range report.Findings {
+				fmt.Fprintf(out, "  [%s] %s\n", f.Severity, f.Message)
+			}
+			if report.HasErrors() {
+				return fmt.Errorf("feed has errors")
+			}
+			return nil
+		},
+	}
+	validateCmd.Flags().Int64Var(&proxyID, "proxy-id", 0, "ID of the Proxy configuration to fetch through (default: the default RSS proxy)")
+	validateCmd.Flags().StringVar(&userAgent, "user-agent", "", "User-Agent to fetch with (default: the configured user_agent)")
+	validateCmd.Flags().StringArrayVar(&headers, "header", nil, "Extra request header as 'Name: value' (repeatable)")
+	validateCmd.Flags().StringArrayVar(&cookies, "cookie", nil, "Cookie to send as 'name=value' (repeatable)")
+	return validateCmd
+}
+
+// feedCredentialsFromFlags builds feed credentials from the auth flags, falling
+// back to environment variables for secrets so they stay out of shell history.
+func feedCredentialsFromFlags(username, password, bearerToken, headerName, headerValue string) (*database.FeedCredentials, error) {
+	if password == "" {
+		password = os.Getenv("RSS_BOT_FEED_AUTH_PASSWORD")
+	}
+	if bearerToken == "" {
+		bearerToken = os.Getenv("RSS_BOT_FEED_AUTH_TOKEN")
+	}
+	if headerValue == "" {
+		headerValue = os.Getenv("RSS_BOT_FEED_AUTH_HEADER_VALUE")
+	}
+	methods := 0
+	for _, set := range []bool{username != "", bearerToken != "", headerName != ""} {
+		if set {
+			methods++
+		}
+	}
+	switch {
+	case methods > 1:
+		return nil, fmt.Errorf("use only one of --auth-username/--auth-password, --auth-bearer-token or --auth-header")
+	case username != "":
+		return &database.FeedCredentials{Type: database.FeedAuthBasic, Username: username, Secret: password}, nil
+	case bearerToken != "":
+		return &database.FeedCredentials{Type: database.FeedAuthBearer, Secret: bearerToken}, nil
+	case headerName != "":
+		name := strings.TrimSpace(headerName)
+		if strings.ContainsAny(name, ": ") {
+			return nil, fmt.Errorf("invalid --auth-header %q, expected a header name such as X-Api-Key", headerName)
+		}
+		if headerValue == "" {
+			return nil, fmt.Errorf("--auth-header requires --auth-header-value")
+		}
+		return &database.FeedCredentials{Type: database.FeedAuthHeader, Header: http.CanonicalHeaderKey(name), Secret: headerValue}, nil
+	case password != "":
+		return nil, fmt.Errorf("--auth-password requires --auth-username")
+	case headerValue != "":
+		return nil, fmt.Errorf("--auth-header-value requires --auth-header")
+	}
+	return nil, nil
+}
+
+// parseBackfillFlag parses --backfill: "all" (0, no limit) or a positive item count.
+func parseBackfillFlag(value string) (int, error) {
+	if strings.EqualFold(value, "all") {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid --backfill %q, expected a positive number of items or 'all'", value)
+	}
+	return n, nil
+}
+
+// parseHeaderFlags turns repeated "Name: value" flags into a header map.
+func parseHeaderFlags(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	headers := make(map[string]string, len(values))
+	for _, v := range values {
+		name, value, found := strings.Cut(v, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid --header %q, expected 'Name: value'", v)
+		}
+		headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// parseCookieFlags turns repeated "name=value" flags into a cookie map.
+func parseCookieFlags(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	cookies := make(map[string]string, len(values))
+	for _, v := range values {
+		name, value, found := strings.Cut(v, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid --cookie %q, expected 'name=value'", v)
+		}
+		cookies[name] = strings.TrimSpace(value)
+	}
+	return cookies, nil
+}
+
+// feedCheckClient returns an HTTP client for fetching a feed from the CLI,
+// through the given proxy or else the default RSS proxy.
+func feedCheckClient(cmd *cobra.Command, db *database.DB, proxyID *int64, opts interfaces.FetchOptions) (*http.Client, error) {
+	proxyStore := database.NewProxyStore(db)
+	var rssProxy *database.Proxy
+	var err error
+	if proxyID != nil {
+		rssProxy, err = proxyStore.GetProxyByID(cmd.Context(), *proxyID)
+	} else {
+		rssProxy, err = proxyStore.GetDefaultProxy(cmd.Context(), "rss")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading RSS proxy: %w", err)
+	}
+	httpClient, err := proxy.NewHTTPClientFactory().GetClientForFeed(rssProxy, 0, opts.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("creating HTTP client: %w", err)
+	}
+	return httpClient, nil
+}
+
+// discoverFeedURL returns rawURL if it is a feed; otherwise it looks for feeds
+// advertised by the page, or served at the usual paths, and returns the only
+// one found, the first one with auto, or the one the user picks.
+func discoverFeedURL(cmd *cobra.Command, db *database.DB, rawURL string, proxyID *int64, opts interfaces.FetchOptions, auto bool) (string, error) {
+	ctx := cmd.Context()
+	httpClient, err := feedCheckClient(cmd, db, proxyID, opts)
+	if err != nil {
+		return "", err
+	}
+
+	discovery, err := rss.DiscoverFeeds(ctx, httpClient, rawURL, opts)
+	if err != nil {
+		return "", fmt.Errorf("feed discovery failed (use --no-discover to add the URL as is): %w", err)
+	}
+	if discovery.IsFeed {
+		return rawURL, nil
+	}
+	out := cmd.OutOrStdout()
+	switch {
+	case len(discovery.Feeds) == 0:
+		return "", fmt.Errorf("%s is not a feed, advertises no feeds and serves none at the usual paths (use --no-discover to add it anyway)", rawURL)
+	case len(discovery.Feeds) == 1 || auto:
+		chosen := discovery.Feeds[0]
+		fmt.Fprintf(out, "Discovered feed: %s\n", chosen.URL)
+		return chosen.URL, nil
+	}
+
+	fmt.Fprintf(out, "%s advertises %d feeds:\n", rawURL, len(discovery.Feeds))
+	for i, f := range discovery.Feeds {
+		title := f.Title
+		if title == "" {
+			title = f.Type
+		}
+		fmt.Fprintf(out, "  [%d] %s (%s)\n", i+1, f.URL, title)
+	}
+	fmt.Fprintf(out, "Choose a feed [1-%d]: ", len(discovery.Feeds))
+	var choice int
+	if _, err := fmt.Fscanln(cmd.InOrStdin(), &choice); err != nil || choice < 1 || choice > len(discovery.Feeds) {
+		return "", fmt.Errorf("invalid choice; rerun with --auto or pass a feed URL directly")
+	}
+	return discovery.Feeds[choice-1].URL, nil
+}
+
+// verifyFeedDestination asks Telegram whether the feed's bot can post to its chat,
+// using the same bot and proxy selection the worker will use at delivery time.
+func verifyFeedDestination(ctx context.Context, db *database.DB, feed *database.Feed) error {
+	botStore := database.NewTelegramBotStore(db)
+	proxyStore := database.NewProxyStore(db)
+
+	var botIDs []int64
+	if feed.BotPoolID != nil {
+		ids, err := botStore.GetPoolBotIDs(ctx, *feed.BotPoolID)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("bot pool %d has no bots", *feed.BotPoolID)
+		}
+		botIDs = ids
+	} else if feed.TelegramBotID != nil {
+		botIDs = []int64{*feed.TelegramBotID}
+	} else {
+		return fmt.Errorf("feed needs --bot-token-id or --bot-pool-id")
+	}
+
+	factory := proxy.NewHTTPClientFactory()
+	var tgProxy *database.Proxy
+	var err error
+	if feed.ProxyID != nil {
+		tgProxy, err = proxyStore.GetProxyByID(ctx, *feed.ProxyID)
+	} else {
+		tgProxy, err = telegram.DefaultProxy(ctx, proxyStore, factory, 0)
+	}
+	if err != nil {
+		return fmt.Errorf("loading Telegram proxy: %w", err)
+	}
+
+	client := telegram.NewClient(factory)
+	for _, botID := range botIDs { // Every bot in a pool must be able to post, since any of them may deliver
+		token, err := botStore.GetTokenByBotID(ctx, botID)
+		if err != nil {
+			return err
+		}
+		threadID := 0
+		if feed.TelegramThreadID != nil {
+			threadID = *feed.TelegramThreadID
+		}
+		info, err := client.VerifyChatAccess(ctx, token, feed.TelegramChatID, threadID, tgProxy)
+		if err != nil {
+			return fmt.Errorf("bot %d: %w", botID, err)
+		}
+		if feed.AutoCreateTopic && threadID == 0 && botID == botIDs[0] { // Only the first bot creates topics
+			if !info.IsForum {
+				return fmt.Errorf("chat '%s' has no topics enabled, cannot use --auto-topic", feed.TelegramChatID)
+			}
+			if !info.CanManageTopics {
+				return fmt.Errorf("bot %d needs the 'Manage topics' administrator right for --auto-topic", botID)
+			}
+		}
+		fmt.Printf("Verified bot %d can post to %s chat '%s' (ID %d).\n", botID, info.Type, feed.TelegramChatID, info.ID)
+	}
+	return nil
+}
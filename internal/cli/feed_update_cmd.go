@@ -0,0 +1,424 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/spf13/cobra"
+)
+
+// feedUnsetColumns maps the flags 'feed update --unset' accepts to the
+// columns they clear.
+var feedUnsetColumns = map[string]string{
+	"title":                 "user_title",
+	"bot-token-id":          "telegram_bot_id",
+	"bot-pool-id":           "bot_pool_id",
+	"thread-id":             "telegram_thread_id",
+	"user-agent":            "user_agent",
+	"header":                "request_headers",
+	"cookie":                "cookies",
+	"tag":                   "tags",
+	"stale-after":           "stale_after_seconds",
+	"timeout":               "fetch_timeout_seconds",
+	"max-retries":           "fetch_max_retries",
+	"retry-delay":           "fetch_retry_delay_seconds",
+	"retention-max-age":     "processed_max_age_seconds",
+	"retention-keep":        "processed_keep",
+	"tls":                   "tls_config",
+	"proxy-id":              "proxy_id",
+	"proxy-pool-id":         "proxy_pool_id",
+	"proxy-direct-fallback": "proxy_direct_fallback",
+	"format-profile-id":     "formatting_profile_id",
+}
+
+// tlsFlagNames are the flags registered by addTLSFlags.
+var tlsFlagNames = []string{"tls-skip-verify", "tls-ca-file", "tls-min-version", "tls-client-cert", "tls-client-key", "http-version"}
+
+// newFeedUpdateCmd creates the 'feed update' command.
+func newFeedUpdateCmd() *cobra.Command {
+	var (
+		feedURL         string
+		userTitle       string
+		freqSeconds     int
+		botTokenID      int64
+		botPoolID       int64
+		chatID          string
+		threadID        int
+		autoTopic       bool
+		sourceType      string
+		scrapeCfg       database.ScrapeConfig
+		userAgent       string
+		headers         []string
+		cookieFlags     []string
+		tags            []string
+		staleAfter      time.Duration
+		fetchTimeout    time.Duration
+		fetchMaxRetries int
+		fetchRetryDelay time.Duration
+		retentionMaxAge time.Duration
+		retentionKeep   int
+		tlsFlags        database.TLSConfig
+		useFlareSolverr bool
+		proxyID         int64
+		proxyPoolID     int64
+		directFallback  bool
+		formatProfileID int64
+		enabled         bool
+		authUsername    string
+		authPassword    string
+		authBearerToken string
+		authHeaderName  string
+		authHeaderValue string
+		unset           []string
+	)
+
+	updateCmd := &cobra.Command{
+		Use:   "update <feed_id> [flags]",
+		Short: "Change settings of a feed",
+		Long: `Changes the settings given as flags and leaves the others as they are. --header,
+--cookie and --tag replace all of the feed's headers, cookies or tags, and the
+--tls-* flags its whole TLS configuration. --unset <flag> (repeatable) clears a
+setting, falling back to the default: one of ` + strings.Join(feedUnsetNames(), ", ") + ` or auth.
+
+The running bot applies the changes from the feed's next run, except for --freq
+and enabling a feed that was disabled at startup, which take a restart.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded for feed update") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("failed to connect to database: %w", err) }
+			defer db.Close()
+			feedStore := database.NewFeedStore(db)
+
+			feed, err := feedStore.GetFeedByID(cmd.Context(), feedID)
+			if err != nil { return fmt.Errorf("failed to load feed: %w", err) }
+			if feed == nil { return fmt.Errorf("feed %d not found", feedID) }
+
+			flags := cmd.Flags()
+			changes := make(map[string]any)
+			clearAuth := false
+			for _, name := range unset {
+				if name == "auth" {
+					clearAuth = true
+					continue
+				}
+				column, ok := feedUnsetColumns[name]
+				if !ok {
+					return fmt.Errorf("unknown --unset %q (expected one of %s or auth)", name, strings.Join(feedUnsetNames(), ", "))
+				}
+				if flags.Changed(name) || (name == "tls" && anyChanged(cmd, tlsFlagNames...)) {
+					return fmt.Errorf("--%s can't be both set and unset", name)
+				}
+				changes[column] = nil
+			}
+
+			if flags.Changed("url") {
+				if feedURL == "" { return fmt.Errorf("--url must not be empty") }
+				changes["url"] = feedURL
+			}
+			if flags.Changed("title") {
+				changes["user_title"] = userTitle
+			}
+			if flags.Changed("freq") {
+				if freqSeconds <= 0 { return fmt.Errorf("--freq must be positive") }
+				changes["frequency_seconds"] = freqSeconds
+			}
+			if flags.Changed("bot-token-id") {
+				changes["telegram_bot_id"] = botTokenID
+			}
+			if flags.Changed("bot-pool-id") {
+				changes["bot_pool_id"] = botPoolID
+			}
+			if flags.Changed("chat-id") {
+				if chatID == "" { return fmt.Errorf("--chat-id must not be empty") }
+				changes["telegram_chat_id"] = chatID
+			}
+			if flags.Changed("thread-id") {
+				changes["telegram_thread_id"] = threadID
+			}
+			if flags.Changed("auto-topic") {
+				changes["auto_create_topic"] = autoTopic
+			}
+
+			newType := feed.SourceType
+			if flags.Changed("type") {
+				switch sourceType {
+				case database.FeedSourceRSS, database.FeedSourceScrape, database.FeedSourceSitemap, database.FeedSourceIMAP:
+				default:
+					return fmt.Errorf("unknown --type %q (expected %q, %q, %q or %q)", sourceType, database.FeedSourceRSS, database.FeedSourceScrape, database.FeedSourceSitemap, database.FeedSourceIMAP)
+				}
+				newType = sourceType
+				changes["source_type"] = sourceType
+			}
+			if anyChanged(cmd, "scrape-item", "scrape-title", "scrape-link", "scrape-date", "scrape-date-layout", "scrape-content") {
+				merged := database.ScrapeConfig{}
+				if feed.ScrapeConfig != nil {
+					merged = *feed.ScrapeConfig
+				}
+				for flag, field := range map[string]*string{
+					"scrape-item": &merged.ItemSelector, "scrape-title": &merged.TitleSelector, "scrape-link": &merged.LinkSelector,
+					"scrape-date": &merged.DateSelector, "scrape-date-layout": &merged.DateLayout, "scrape-content": &merged.ContentSelector,
+				} {
+					if flags.Changed(flag) {
+						value, _ := flags.GetString(flag)
+						*field = value
+					}
+				}
+				changes["scrape_config"] = &merged
+				feed.ScrapeConfig = &merged
+			}
+			if newType == database.FeedSourceScrape && (feed.ScrapeConfig == nil || feed.ScrapeConfig.ItemSelector == "") {
+				return fmt.Errorf("--scrape-item is required for scrape feeds")
+			}
+
+			if flags.Changed("user-agent") {
+				changes["user_agent"] = userAgent
+			}
+			if flags.Changed("header") {
+				requestHeaders, err := parseHeaderFlags(headers)
+				if err != nil { return err }
+				changes["request_headers"] = requestHeaders
+			}
+			if flags.Changed("cookie") {
+				cookies, err := parseCookieFlags(cookieFlags)
+				if err != nil { return err }
+				changes["cookies"] = cookies
+			}
+			if flags.Changed("tag") {
+				changes["tags"] = tags
+			}
+			if flags.Changed("stale-after") {
+				changes["stale_after_seconds"] = int(staleAfter / time.Second)
+			}
+			if flags.Changed("timeout") {
+				changes["fetch_timeout_seconds"] = int(fetchTimeout / time.Second)
+			}
+			if flags.Changed("max-retries") {
+				changes["fetch_max_retries"] = fetchMaxRetries
+			}
+			if flags.Changed("retry-delay") {
+				changes["fetch_retry_delay_seconds"] = int(fetchRetryDelay / time.Second)
+			}
+			if flags.Changed("retention-max-age") {
+				if retentionMaxAge < 0 { return fmt.Errorf("--retention-max-age must not be negative") }
+				changes["processed_max_age_seconds"] = int(retentionMaxAge / time.Second)
+			}
+			if flags.Changed("retention-keep") {
+				if retentionKeep < 0 { return fmt.Errorf("--retention-keep must not be negative") }
+				changes["processed_keep"] = retentionKeep
+			}
+			if anyChanged(cmd, tlsFlagNames...) {
+				feedTLS, err := tlsConfigFromFlags(cmd, tlsFlags)
+				if err != nil { return err }
+				changes["tls_config"] = feedTLS
+			}
+			if flags.Changed("flaresolverr") {
+				changes["use_flaresolverr"] = useFlareSolverr
+			}
+			if flags.Changed("proxy-id") {
+				changes["proxy_id"] = proxyID
+			}
+			if flags.Changed("proxy-pool-id") {
+				pool, err := database.NewProxyStore(db).GetPoolByID(cmd.Context(), proxyPoolID)
+				if err != nil { return fmt.Errorf("failed to load proxy pool: %w", err) }
+				if pool == nil { return fmt.Errorf("proxy pool %d not found", proxyPoolID) }
+				changes["proxy_pool_id"] = proxyPoolID
+			}
+			if flags.Changed("proxy-direct-fallback") {
+				changes["proxy_direct_fallback"] = directFallback
+			}
+			if flags.Changed("format-profile-id") {
+				changes["formatting_profile_id"] = formatProfileID
+			}
+			if flags.Changed("enabled") {
+				changes["is_enabled"] = enabled
+			}
+
+			var creds *database.FeedCredentials
+			if anyChanged(cmd, "auth-username", "auth-password", "auth-bearer-token", "auth-header", "auth-header-value") {
+				if clearAuth {
+					return fmt.Errorf("--auth can't be both set and unset")
+				}
+				if creds, err = feedCredentialsFromFlags(authUsername, authPassword, authBearerToken, authHeaderName, authHeaderValue); err != nil {
+					return err
+				}
+			}
+			if len(changes) == 0 && creds == nil && !clearAuth {
+				return fmt.Errorf("nothing to update: give the settings to change as flags")
+			}
+			if newType == database.FeedSourceIMAP && (creds != nil && creds.Type != database.FeedAuthBasic || clearAuth) {
+				return fmt.Errorf("IMAP feeds need --auth-username and --auth-password for the mailbox login")
+			}
+
+			if AppCfg.DryRun {
+				fmt.Printf("Dry run: would change %s of feed %d.\n", strings.Join(changedNames(changes, creds != nil || clearAuth), ", "), feedID)
+				return nil
+			}
+			if err := feedStore.PatchFeed(cmd.Context(), feedID, changes); err != nil {
+				return fmt.Errorf("failed to update feed: %w", err)
+			}
+			if creds != nil || clearAuth {
+				if err := feedStore.SetFeedCredentials(cmd.Context(), feedID, creds); err != nil {
+					return fmt.Errorf("failed to update feed credentials: %w", err)
+				}
+			}
+			fmt.Printf("Feed %d updated: %s.\n", feedID, strings.Join(changedNames(changes, creds != nil || clearAuth), ", "))
+			return nil
+		},
+	}
+
+	f := updateCmd.Flags()
+	f.StringVar(&feedURL, "url", "", "New URL of the feed (stored as given, without discovery)")
+	f.StringVarP(&userTitle, "title", "t", "", "Custom title for the feed")
+	f.IntVarP(&freqSeconds, "freq", "f", 0, "Fetch frequency in seconds")
+	f.Int64Var(&botTokenID, "bot-token-id", 0, "ID of the Telegram Bot configuration to use")
+	f.Int64Var(&botPoolID, "bot-pool-id", 0, "ID of a bot pool to round-robin deliveries across (overrides --bot-token-id)")
+	f.StringVar(&chatID, "chat-id", "", "Telegram Chat ID (numeric) or @channelusername; an --auto-topic feed gets a new topic in it")
+	f.IntVar(&threadID, "thread-id", 0, "Forum topic (message thread ID) to post into")
+	f.BoolVar(&autoTopic, "auto-topic", false, "Create a forum topic named after the feed and post all items there")
+	f.StringVar(&sourceType, "type", "", "Source type: 'rss', 'scrape', 'sitemap' or 'imap'")
+	f.StringVar(&scrapeCfg.ItemSelector, "scrape-item", "", "CSS selector matching each item (scrape feeds)")
+	f.StringVar(&scrapeCfg.TitleSelector, "scrape-title", "", "CSS selector for the title within an item")
+	f.StringVar(&scrapeCfg.LinkSelector, "scrape-link", "", "CSS selector for the link within an item")
+	f.StringVar(&scrapeCfg.DateSelector, "scrape-date", "", "CSS selector for the date within an item")
+	f.StringVar(&scrapeCfg.DateLayout, "scrape-date-layout", "", "Go time layout for --scrape-date")
+	f.StringVar(&scrapeCfg.ContentSelector, "scrape-content", "", "CSS selector for the item body within an item")
+	f.StringVar(&userAgent, "user-agent", "", "User-Agent to fetch this feed with")
+	f.StringArrayVar(&headers, "header", nil, "Extra request header as 'Name: value' (repeatable; replaces the feed's headers)")
+	f.StringArrayVar(&cookieFlags, "cookie", nil, "Cookie to send as 'name=value' (repeatable; replaces the feed's cookies)")
+	f.StringArrayVar(&tags, "tag", nil, "Tag for grouping the feed (repeatable; replaces the feed's tags)")
+	f.DurationVar(&staleAfter, "stale-after", 0, "Alert the admin chat if the feed publishes nothing for this long; 0 disables")
+	f.DurationVar(&fetchTimeout, "timeout", 0, "Per-request fetch timeout for this feed")
+	f.IntVar(&fetchMaxRetries, "max-retries", 0, "Retries after a failed fetch of this feed")
+	f.DurationVar(&fetchRetryDelay, "retry-delay", 0, "Initial backoff between fetch retries, doubled each time")
+	f.DurationVar(&retentionMaxAge, "retention-max-age", 0, "Forget processed items last seen longer ago than this (overrides retention.processed_max_age); 0 keeps them forever")
+	f.IntVar(&retentionKeep, "retention-keep", 0, "Newest processed items kept whatever their age (overrides retention.processed_keep)")
+	addTLSFlags(updateCmd, &tlsFlags)
+	f.BoolVar(&useFlareSolverr, "flaresolverr", false, "Always fetch through the configured FlareSolverr instance")
+	f.Int64Var(&proxyID, "proxy-id", 0, "ID of the Proxy configuration to use")
+	f.Int64Var(&proxyPoolID, "proxy-pool-id", 0, "ID of a proxy pool to rotate fetches through (overrides --proxy-id)")
+	f.BoolVar(&directFallback, "proxy-direct-fallback", false, "Whether fetches may bypass the proxy when it fails or is down")
+	f.Int64Var(&formatProfileID, "format-profile-id", 0, "ID of the Formatting Profile to use")
+	f.BoolVar(&enabled, "enabled", true, "Enable or (with --enabled=false) disable the feed")
+	f.StringVar(&authUsername, "auth-username", "", "Username for HTTP Basic auth on protected feeds")
+	f.StringVar(&authPassword, "auth-password", "", "Password for HTTP Basic auth (or set RSS_BOT_FEED_AUTH_PASSWORD); stored encrypted")
+	f.StringVar(&authBearerToken, "auth-bearer-token", "", "Bearer token for protected feeds (or set RSS_BOT_FEED_AUTH_TOKEN); stored encrypted")
+	f.StringVar(&authHeaderName, "auth-header", "", "Header carrying a secret for protected feeds, e.g. X-Api-Key (one per feed); its value is stored encrypted")
+	f.StringVar(&authHeaderValue, "auth-header-value", "", "Value of --auth-header (or set RSS_BOT_FEED_AUTH_HEADER_VALUE); stored encrypted")
+	f.StringArrayVar(&unset, "unset", nil, "Clear a setting, e.g. --unset proxy-id (repeatable)")
+	return updateCmd
+}
+
+// newFeedRemoveCmd creates the 'feed remove' command.
+func newFeedRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "remove <feed_id>",
+		Short:   "Delete a feed",
+		Aliases: []string{"rm"},
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			if AppCfg.DryRun {
+				fmt.Printf("Dry run: would remove feed %d.\n", feedID)
+				return nil
+			}
+			if err := database.NewFeedStore(db).DeleteFeed(cmd.Context(), feedID); err != nil {
+				return fmt.Errorf("failed to remove feed: %w", err)
+			}
+			fmt.Printf("Feed %d removed.\n", feedID)
+			return nil
+		},
+	}
+}
+
+// newFeedSetEnabledCmd creates the 'feed enable' or, with enabled false, the
+// 'feed disable' command.
+func newFeedSetEnabledCmd(enabled bool) *cobra.Command {
+	use, short, done := "enable", "Resume fetching a feed", "enabled"
+	if !enabled {
+		use, short, done = "disable", "Stop fetching a feed, keeping its settings", "disabled"
+	}
+	return &cobra.Command{
+		Use:   use + " <feed_id>...",
+		Short: short,
+		Long: short + `. The running bot applies it from the feed's next run; a feed
+that was disabled when the bot started is only fetched after a restart.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var ids []int64
+			for _, arg := range args {
+				id, err := strconv.ParseInt(arg, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid feed ID: %s", arg)
+				}
+				ids = append(ids, id)
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			feedStore := database.NewFeedStore(db)
+			for _, id := range ids {
+				if AppCfg.DryRun {
+					fmt.Printf("Dry run: feed %d would be %s.\n", id, done)
+					continue
+				}
+				if err := feedStore.SetFeedEnabled(cmd.Context(), id, enabled); err != nil {
+					return fmt.Errorf("failed to update feed: %w", err)
+				}
+				fmt.Printf("Feed %d %s.\n", id, done)
+			}
+			return nil
+		},
+	}
+}
+
+// anyChanged reports whether any of the named flags was given.
+func anyChanged(cmd *cobra.Command, names ...string) bool {
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// feedUnsetNames lists the names --unset accepts, sorted.
+func feedUnsetNames() []string {
+	names := make([]string, 0, len(feedUnsetColumns))
+	for name := range feedUnsetColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// changedNames lists the changed columns, sorted, for messages.
+func changedNames(changes map[string]any, auth bool) []string {
+	names := make([]string, 0, len(changes)+1)
+	for column := range changes {
+		names = append(names, column)
+	}
+	if auth {
+		names = append(names, "credentials")
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/filter"
+	"github.com/spf13/cobra"
+)
+
+// newFeedFilterCmd creates the 'feed filter' command and its subcommands.
+func newFeedFilterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "filter",
+		Short:   "Manage which of a feed's items are sent, by keyword or regex",
+		Aliases: []string{"filters"},
+	}
+	cmd.AddCommand(newFilterAddCmd())
+	cmd.AddCommand(newFilterListCmd())
+	cmd.AddCommand(newFilterRemoveCmd())
+	return cmd
+}
+
+// newFilterAddCmd creates the 'feed filter add' command.
+func newFilterAddCmd() *cobra.Command {
+	var include, exclude, regex bool
+	var fields []string
+	cmd := &cobra.Command{
+		Use:   "add <feed_id> --include|--exclude <pattern>",
+		Short: "Add a keyword or regex filter to a feed",
+		Long: `Adds a filter deciding which new items of the feed are sent. Items matching any
+--exclude filter are skipped; if the feed has --include filters, items must
+match at least one of them. Skipped items are recorded as processed and not
+looked at again.
+
+Patterns are keywords or phrases matched as whole words, ignoring case, or with
+--regex Go regular expressions (add (?i) to ignore case). They are matched
+against the item's title, content (without HTML), categories and author, or
+only the --field ones. The running bot applies the change from the feed's next run.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			if include == exclude { return fmt.Errorf("give one of --include or --exclude") }
+			f := &database.FeedFilter{FeedID: feedID, Action: database.FilterExclude, MatchType: database.FilterKeyword, Pattern: args[1], Fields: fields}
+			if include {
+				f.Action = database.FilterInclude
+			}
+			if regex {
+				f.MatchType = database.FilterRegex
+			}
+			if err := filter.Validate(f); err != nil { return err }
+
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			if AppCfg.DryRun {
+				fmt.Printf("Dry run: would add %s %s filter %q to feed %d.\n", f.Action, f.MatchType, f.Pattern, feedID)
+				return nil
+			}
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			feed, err := database.NewFeedStore(db).GetFeedByID(cmd.Context(), feedID)
+			if err != nil { return fmt.Errorf("loading feed: %w", err) }
+			if feed == nil { return fmt.Errorf("feed %d not found", feedID) }
+			id, err := database.NewFilterStore(db).CreateFilter(cmd.Context(), f)
+			if err != nil { return fmt.Errorf("failed to add filter: %w", err) }
+			fmt.Printf("Filter added to feed %d with ID: %d\n", feedID, id)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&include, "include", false, "Only send items matching this or another include filter")
+	cmd.Flags().BoolVar(&exclude, "exclude", false, "Skip items matching this filter")
+	cmd.Flags().BoolVar(&regex, "regex", false, "The pattern is a Go regular expression rather than a keyword")
+	cmd.Flags().StringArrayVar(&fields, "field", nil, "Item field to match: title, content, categories or author (repeatable; default all)")
+	return cmd
+}
+
+// newFilterListCmd creates the 'feed filter list' command.
+func newFilterListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list <feed_id>",
+		Short: "List a feed's filters",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %s", args[0])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			filters, err := database.NewFilterStore(db).ListFeedFilters(cmd.Context(), feedID)
+			if err != nil { return fmt.Errorf("failed to list filters: %w", err) }
+			if len(filters) == 0 {
+				fmt.Printf("Feed %d has no filters; all its items are sent.\n", feedID)
+				return nil
+			}
+			for _, f := range filters {
+				fields := "all"
+				if len(f.Fields) > 0 {
+					fields = strings.Join(f.Fields, ", ")
+				}
+				fmt.Printf("ID: %d, Action: %s, Match: %s, Pattern: %q, Fields: %s\n", f.ID, f.Action, f.MatchType, f.Pattern, fields)
+			}
+			return nil
+		},
+	}
+}
+
+// newFilterRemoveCmd creates the 'feed filter remove' command.
+func newFilterRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "remove <filter_id>",
+		Short:   "Remove a filter",
+		Aliases: []string{"rm"},
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid filter ID: %s", args[0])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			if err := database.NewFilterStore(db).DeleteFilter(cmd.Context(), id); err != nil {
+				return err
+			}
+			fmt.Printf("Filter %d removed.\n", id)
+			return nil
+		},
+	}
+}
@@ -28,6 +28,11 @@ func newFormatProfileAddCmd() *cobra.Command {
 		hashtags              []string
 		includeAuthor         bool
 		omitGenericTitleRegex string
+		reactionEmoji         string
+		reactionMatchRegex    string
+		discussButton         string
+		digestTemplate        string
+		fullArticle           bool
 	)
 
 	addCmd := &cobra.Command{
@@ -40,7 +45,7 @@ or individual flags. Flags override file settings if both are provided for the s
 			profileName := args[0]
 			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
 
-			db, err := database.Connect(AppCfg.DatabasePath, "internal/database/migrations")
+			db, err := database.Connect(AppCfg.DatabasePath)
 			if err != nil { return fmt.Errorf("db connect: %w", err) }
 			defer db.Close()
 			profileStore := database.NewFormattingProfileStore(db)
@@ -66,6 +71,11 @@ or individual flags. Flags override file settings if both are provided for the s
 			if cmd.Flags().Changed("hashtags") { profile.ParsedConfig.Hashtags = hashtags }
 			if cmd.Flags().Changed("include-author") { profile.ParsedConfig.IncludeAuthor = includeAuthor }
 			if cmd.Flags().Changed("omit-generic-title-regex") { profile.ParsedConfig.OmitGenericTitleRegex = omitGenericTitleRegex }
+			if cmd.Flags().Changed("reaction-emoji") { profile.ParsedConfig.ReactionEmoji = reactionEmoji }
+			if cmd.Flags().Changed("reaction-match-regex") { profile.ParsedConfig.ReactionMatchRegex = reactionMatchRegex }
+			if cmd.Flags().Changed("discuss-button") { profile.ParsedConfig.DiscussButton = discussButton }
+			if cmd.Flags().Changed("digest-template") { profile.ParsedConfig.DigestTemplate = digestTemplate }
+			if cmd.Flags().Changed("full-article") { profile.ParsedConfig.FullArticle = fullArticle }
 			// Add other flags for UseTelegraphThresholdChars, etc.
 
 			if errMarshal := profile.MarshalConfig(); errMarshal != nil { // To update ConfigJSON
@@ -84,6 +94,11 @@ or individual flags. Flags override file settings if both are provided for the s
 	addCmd.Flags().StringSliceVar(&hashtags, "hashtags", []string{}, "Comma-separated list of hashtags (e.g., tag1,tag2)")
 	addCmd.Flags().BoolVar(&includeAuthor, "include-author", false, "Include author name in messages")
 	addCmd.Flags().StringVar(&omitGenericTitleRegex, "omit-generic-title-regex", "", "Regex to detect and omit generic RSS item titles")
+	addCmd.Flags().StringVar(&reactionEmoji, "reaction-emoji", "", "Emoji reaction to set on delivered messages (e.g. 🔥)")
+	addCmd.Flags().StringVar(&reactionMatchRegex, "reaction-match-regex", "", "Only react to items whose title or content matches this regex")
+	addCmd.Flags().StringVar(&discussButton, "discuss-button", "", "Label of a button opening each post's comments in the channel's discussion group (e.g. \"💬 Discuss\")")
+	addCmd.Flags().StringVar(&digestTemplate, "digest-template", "", "Go template for the digests of feeds in digest mode ('feed digest set')")
+	addCmd.Flags().BoolVar(&fullArticle, "full-article", false, "Send the article extracted from each item's page instead of the feed's summary")
 	// Add more flags as needed
 
 	return addCmd
@@ -95,7 +110,7 @@ func newFormatProfileListCmd() *cobra.Command {
 		Short: "List configured formatting profiles",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
-			db, err := database.Connect(AppCfg.DatabasePath, "internal/database/migrations")
+			db, err := database.Connect(AppCfg.DatabasePath)
 			if err != nil { return fmt.Errorf("db connect: %w", err) }
 			defer db.Close()
 			profileStore := database.NewFormattingProfileStore(db)
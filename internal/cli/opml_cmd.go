@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/opml"
+	"github.com/spf13/cobra"
+)
+
+// newFeedImportOPMLCmd creates the 'feed import-opml' command.
+func newFeedImportOPMLCmd() *cobra.Command {
+	var (
+		chatID      string
+		botTokenID  int64
+		botPoolID   int64
+		freqSeconds int
+		tags        []string
+		enabled     bool
+		skipVerify  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "import-opml <file>",
+		Short: "Add the feeds of an OPML file exported by another reader",
+		Long: `Adds every feed of an OPML subscription list, all delivering to --chat-id.
+Feeds whose URL is already in the database are skipped. The folders a feed is in
+become its tags, and so do the entries of its category attribute.
+
+Titles and frequencies written by 'feed export-opml' are kept; other feeds get
+--freq and the title they publish. Use '-' to read the file from stdin. Restart
+the bot for new feeds to be scheduled.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if chatID == "" { return fmt.Errorf("--chat-id is required") }
+			if freqSeconds <= 0 { return fmt.Errorf("--freq must be positive") }
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded for feed import-opml") }
+
+			var in io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil { return fmt.Errorf("opening %s: %w", args[0], err) }
+				defer f.Close()
+				in = f
+			}
+			entries, err := opml.Parse(in)
+			if err != nil { return err }
+
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("failed to connect to database: %w", err) }
+			defer db.Close()
+			feedStore := database.NewFeedStore(db)
+
+			existing, err := feedStore.ListFeeds(cmd.Context())
+			if err != nil { return fmt.Errorf("failed to list feeds: %w", err) }
+			seen := make(map[string]bool)
+			for _, f := range existing {
+				seen[f.URL] = true
+			}
+
+			var fresh []*database.Feed
+			duplicates := 0
+			for _, e := range entries {
+				if seen[e.URL] {
+					duplicates++
+					continue
+				}
+				seen[e.URL] = true
+				feed := &database.Feed{
+					URL:              e.URL,
+					FrequencySeconds: freqSeconds,
+					TelegramChatID:   chatID,
+					Tags:             database.NormalizeTags(append(e.Tags, tags...)),
+					IsEnabled:        enabled,
+				}
+				if e.UserTitle != "" {
+					title := e.UserTitle
+					feed.UserTitle = &title
+				}
+				if e.FrequencySeconds > 0 {
+					feed.FrequencySeconds = e.FrequencySeconds
+				}
+				if cmd.Flags().Changed("bot-token-id") {
+					feed.TelegramBotID = &botTokenID
+				}
+				if cmd.Flags().Changed("bot-pool-id") {
+					feed.BotPoolID = &botPoolID
+				}
+				fresh = append(fresh, feed)
+			}
+			if len(fresh) == 0 {
+				fmt.Printf("Nothing to import: all %d feeds are already added.\n", len(entries))
+				return nil
+			}
+			if AppCfg.DryRun {
+				for _, f := range fresh {
+					fmt.Printf("Would add %s (every %ds, tags: %v)\n", f.URL, f.FrequencySeconds, f.Tags)
+				}
+				fmt.Printf("Dry run: %d feeds to add, %d duplicate URLs skipped.\n", len(fresh), duplicates)
+				return nil
+			}
+			// Every feed goes to the same chat, so checking one of them is enough
+			if !skipVerify {
+				if err := verifyFeedDestination(cmd.Context(), db, fresh[0]); err != nil {
+					return fmt.Errorf("chat verification failed (use --skip-verify to import anyway): %w", err)
+				}
+			}
+
+			added := 0
+			for _, f := range fresh {
+				if _, err := feedStore.CreateFeed(cmd.Context(), f); err != nil {
+					return fmt.Errorf("failed to add %s after adding %d feeds: %w", f.URL, added, err)
+				}
+				added++
+			}
+			fmt.Printf("Imported %d feeds, skipped %d duplicate URLs.\n", added, duplicates)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&chatID, "chat-id", "", "Telegram Chat ID (numeric) or @channelusername to deliver every imported feed to (required)")
+	cmd.Flags().Int64Var(&botTokenID, "bot-token-id", 0, "ID of the Telegram Bot configuration to use")
+	cmd.Flags().Int64Var(&botPoolID, "bot-pool-id", 0, "ID of a bot pool to round-robin deliveries across (overrides --bot-token-id)")
+	cmd.Flags().IntVarP(&freqSeconds, "freq", "f", 300, "Fetch frequency in seconds for feeds the file has none for")
+	cmd.Flags().StringArrayVar(&tags, "tag", nil, "Tag every imported feed with this (repeatable)")
+	cmd.Flags().BoolVar(&enabled, "enabled", true, "Enable the imported feeds immediately")
+	cmd.Flags().BoolVar(&skipVerify, "skip-verify", false, "Don't check that the bot can post to the chat before importing")
+	return cmd
+}
+
+// newFeedExportOPMLCmd creates the 'feed export-opml' command.
+func newFeedExportOPMLCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-opml <file>",
+		Short: "Write the feeds to an OPML file other readers can import",
+		Long: `Writes every RSS/Atom/JSON feed, enabled or not, to an OPML 2.0 file. Feeds are
+put in a folder named after their first tag and list all their tags as categories.
+User titles and frequencies are kept in rssbot:userTitle and rssbot:frequency
+attributes, which 'feed import-opml' reads back. Scrape, sitemap and IMAP feeds are
+left out. Use '-' to write to stdout.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded for feed export-opml") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("failed to connect to database: %w", err) }
+			defer db.Close()
+
+			feeds, err := database.NewFeedStore(db).ListFeeds(cmd.Context())
+			if err != nil { return fmt.Errorf("failed to list feeds: %w", err) }
+			var entries []opml.Feed
+			skipped := 0
+			for _, f := range feeds {
+				if f.SourceType != "" && f.SourceType != database.FeedSourceRSS {
+					skipped++
+					continue
+				}
+				e := opml.Feed{URL: f.URL, FrequencySeconds: f.FrequencySeconds, Tags: f.Tags}
+				if f.UserTitle != nil {
+					e.Title, e.UserTitle = *f.UserTitle, *f.UserTitle
+				}
+				entries = append(entries, e)
+			}
+
+			if args[0] == "-" {
+				return opml.Write(cmd.OutOrStdout(), "rss-telegram-bot feeds", entries)
+			}
+			out, err := os.Create(args[0])
+			if err != nil { return fmt.Errorf("creating %s: %w", args[0], err) }
+			if err := opml.Write(out, "rss-telegram-bot feeds", entries); err != nil {
+				out.Close()
+				return fmt.Errorf("writing %s: %w", args[0], err)
+			}
+			if err := out.Close(); err != nil { return fmt.Errorf("writing %s: %w", args[0], err) }
+			fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d feeds to %s (%d scrape, sitemap or IMAP feeds left out).\n", len(entries), args[0], skipped)
+			return nil
+		},
+	}
+	return cmd
+}
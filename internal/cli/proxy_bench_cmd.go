@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+func newProxyBenchCmd() *cobra.Command {
+	var (
+		target string
+		runs   int
+		save   bool
+	)
+
+	benchCmd := &cobra.Command{
+		Use:   "bench [proxy_id...]",
+		Short: "Measure connect time, time to first byte and success rate of proxies (all by default)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if runs < 1 {
+				return fmt.Errorf("--runs must be at least 1")
+			}
+			if AppCfg == nil {
+				return fmt.Errorf("configuration not loaded for proxy bench")
+			}
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil {
+				return fmt.Errorf("failed to connect to database: %w", err)
+			}
+			defer db.Close()
+			proxyStore := database.NewProxyStore(db)
+
+			var proxies []*database.Proxy
+			if len(args) == 0 {
+				if proxies, err = proxyStore.ListProxies(cmd.Context()); err != nil {
+					return fmt.Errorf("failed to list proxies: %w", err)
+				}
+			}
+			for _, arg := range args {
+				var id int64
+				if _, err := fmt.Sscan(arg, &id); err != nil {
+					return fmt.Errorf("invalid proxy ID: %s", arg)
+				}
+				p, err := proxyStore.GetProxyByID(cmd.Context(), id)
+				if err != nil {
+					return fmt.Errorf("failed to get proxy %d: %w", id, err)
+				}
+				if p == nil {
+					return fmt.Errorf("proxy with ID %d not found", id)
+				}
+				proxies = append(proxies, p)
+			}
+			if len(proxies) == 0 {
+				fmt.Println("No proxies configured.")
+				return nil
+			}
+
+			fmt.Printf("Benchmarking %d proxies against %s (%d runs each)...\n", len(proxies), target, runs)
+			results := proxy.Bench(cmd.Context(), proxy.NewHTTPClientFactory(), proxies, target, runs)
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "RANK\tID\tNAME\tSUCCESS\tCONNECT\tTTFB\tLAST ERROR")
+			for i, r := range results {
+				lastErr := ""
+				if r.LastErr != nil {
+					lastErr = r.LastErr.Error()
+				}
+				fmt.Fprintf(tw, "%d\t%d\t%s\t%d/%d\t%s\t%s\t%s\n", i+1, r.Proxy.ID, r.Proxy.Name, r.Successes, r.Runs,
+					r.Connect.Round(time.Millisecond), r.TTFB.Round(time.Millisecond), lastErr)
+			}
+			tw.Flush()
+
+			if save {
+				now := time.Now()
+				for _, r := range results {
+					if err := proxyStore.SetProxyBench(cmd.Context(), r.Proxy.ID, r.TTFB, r.SuccessRate(), now); err != nil {
+						return fmt.Errorf("failed to save results: %w", err)
+					}
+				}
+				fmt.Println("Results saved; proxy pools now list their best members first.")
+			}
+			return nil
+		},
+	}
+	benchCmd.Flags().StringVar(&target, "target", "https://www.google.com/generate_204", "URL to request through each proxy")
+	benchCmd.Flags().IntVar(&runs, "runs", 5, "Requests per proxy")
+	benchCmd.Flags().BoolVar(&save, "save", false, "Store the results so proxy pools order their members by them")
+	return benchCmd
+}
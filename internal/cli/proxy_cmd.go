@@ -23,6 +23,10 @@ func NewProxyCmd() *cobra.Command {
 	cmd.AddCommand(newProxyAddCmd())
 	cmd.AddCommand(newProxyListCmd())
 	cmd.AddCommand(newProxyValidateCmd())
+	cmd.AddCommand(newProxyPoolCmd())
+	cmd.AddCommand(newProxyBenchCmd())
+	cmd.AddCommand(newProxyImportCmd())
+	cmd.AddCommand(newProxyRuleCmd())
 	// Add update, remove commands
 
 	return cmd
@@ -38,6 +42,8 @@ func newProxyAddCmd() *cobra.Command {
 		password           string
 		defaultForRSS      bool
 		defaultForTelegram bool
+		directFallback     bool
+		tlsFlags           database.TLSConfig
 	)
 
 	addCmd := &cobra.Command{
@@ -54,15 +60,18 @@ func newProxyAddCmd() *cobra.Command {
 				return fmt.Errorf("configuration not loaded for proxy add")
 			}
 			// Connect to DB using path from global AppCfg
-			db, err := database.Connect(AppCfg.DatabasePath, "internal/database/migrations")
+			db, err := database.Connect(AppCfg.DatabasePath)
 			if err != nil {
 				return fmt.Errorf("failed to connect to database: %w", err)
 			}
 			defer db.Close()
 			proxyStore := database.NewProxyStore(db)
 
-			if pType != "http" && pType != "https" && pType != "socks5" {
-				return fmt.Errorf("invalid proxy type: %s. Must be http, https, or socks5", pType)
+			if pType != "http" && pType != "https" && pType != "socks5" && pType != "socks5h" && pType != "pac" {
+				return fmt.Errorf("invalid proxy type: %s. Must be http, https, socks5, socks5h, or pac", pType)
+			}
+			if pType == "pac" && !proxy.PACSupported() {
+				return fmt.Errorf("pac proxies need a build with -tags pac")
 			}
 
 			p := &database.Proxy{
@@ -78,20 +87,28 @@ func newProxyAddCmd() *cobra.Command {
 			if cmd.Flags().Changed("password") {
 				p.Password = &password
 			}
+			if cmd.Flags().Changed("direct-fallback") {
+				p.DirectFallback = &directFallback
+			}
+			if p.TLS, err = tlsConfigFromFlags(cmd, tlsFlags); err != nil {
+				return err
+			}
 
 			id, err := proxyStore.CreateProxy(cmd.Context(), p)
 			if err != nil {
 				return fmt.Errorf("failed to add proxy: %w", err)
 			}
-			fmt.Printf("Proxy '%s' added successfully with ID: %d\n", name, id)
+			fmt.Fprintf(cmd.OutOrStdout(), "Proxy '%s' added successfully with ID: %d\n", name, id)
 			return nil
 		},
 	}
 
-	addCmd.Flags().StringVarP(&username, "username", "u", "", "Proxy username")
-	addCmd.Flags().StringVarP(&password, "password", "p", "", "Proxy password")
+	addCmd.Flags().StringVarP(&username, "username", "u", "", "Proxy username, or an env:NAME / file:PATH reference resolved when connecting")
+	addCmd.Flags().StringVarP(&password, "password", "p", "", "Proxy password, or an env:NAME / file:PATH reference resolved when connecting")
 	addCmd.Flags().BoolVar(&defaultForRSS, "default-rss", false, "Set as default proxy for RSS feeds")
 	addCmd.Flags().BoolVar(&defaultForTelegram, "default-telegram", false, "Set as default proxy for Telegram communication")
+	addCmd.Flags().BoolVar(&directFallback, "direct-fallback", false, "Whether traffic may connect directly when this proxy fails or is down (default: fetch.direct_fallback / proxy_health.direct_fallback)")
+	addTLSFlags(addCmd, &tlsFlags)
 
 	return addCmd
 }
@@ -106,7 +123,7 @@ func newProxyListCmd() *cobra.Command {
 			if AppCfg == nil {
 				return fmt.Errorf("configuration not loaded for proxy list")
 			}
-			db, err := database.Connect(AppCfg.DatabasePath, "internal/database/migrations")
+			db, err := database.Connect(AppCfg.DatabasePath)
 			if err != nil {
 				return fmt.Errorf("failed to connect to database: %w", err)
 			}
@@ -136,8 +153,15 @@ func newProxyListCmd() *cobra.Command {
 					tgDef = "[Default TG]"
 				}
 
-				fmt.Printf("ID: %d, Name: %s, Type: %s, Address: %s, Auth: %s %s %s\n",
-					p.ID, p.Name, p.Type, p.Address, auth, rssDef, tgDef)
+				health := "unchecked"
+				if p.HealthError != nil {
+					health = "down (" + *p.HealthError + ")"
+				} else if p.HealthCheckedAt != nil {
+					health = "ok"
+				}
+
+				fmt.Printf("ID: %d, Name: %s, Type: %s, Address: %s, Auth: %s, Health: %s %s %s\n",
+					p.ID, p.Name, p.Type, p.Address, auth, health, rssDef, tgDef)
 			}
 			return nil
 		},
@@ -163,7 +187,7 @@ func newProxyValidateCmd() *cobra.Command {
 			if AppCfg == nil {
 				return fmt.Errorf("configuration not loaded for proxy validate")
 			}
-			db, err := database.Connect(AppCfg.DatabasePath, "internal/database/migrations")
+			db, err := database.Connect(AppCfg.DatabasePath)
 			if err != nil {
 				return fmt.Errorf("failed to connect to database: %w", err)
 			}
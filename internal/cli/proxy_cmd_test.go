@@ -8,8 +8,10 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/haytac/rss-telegram-bot/internal/config"    // Module path
+	"github.com/haytac/rss-telegram-bot/internal/config"   // Module path
 	"github.com/haytac/rss-telegram-bot/internal/database" // Module path
+	"github.com/haytac/rss-telegram-bot/internal/logging"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -23,25 +25,24 @@ func setupTestAppCfg(t *testing.T) (*config.AppConfig, func()) {
 
 	dbPath := filepath.Join(tempDir, "cli_test.db")
 	// Ensure migrations are run for the CLI test DB if commands interact with schema
-    // Reusing setupTestDB's logic for connecting and migrating is a good idea.
-    // For simplicity here, we'll just set the path.
-	
-	// Initialize a dummy logger config
-    logCfg := logging.Config{Level: "error", Console: true} // Quiet logger for tests
+	// Reusing setupTestDB's logic for connecting and migrating is a good idea.
+	// For simplicity here, we'll just set the path.
 
+	// Initialize a dummy logger config
+	logCfg := logging.Config{Level: "error", Console: true} // Quiet logger for tests
 
 	cfg := &config.AppConfig{
 		DatabasePath: dbPath,
-		Log: logCfg,
+		Log:          logCfg,
 		// Set other necessary fields if commands depend on them
 	}
-    
-    // Initialize global AppCfg for CLI commands that use it
-    AppCfg = cfg 
+
+	// Initialize global AppCfg for CLI commands that use it
+	AppCfg = cfg
 
 	cleanup := func() {
 		os.RemoveAll(tempDir)
-        AppCfg = nil // Reset global
+		AppCfg = nil // Reset global
 	}
 	return cfg, cleanup
 }
@@ -52,29 +53,28 @@ func executeCommand(root *cobra.Command, args ...string) (string, error) {
 	root.SetOut(&buf)
 	root.SetErr(&buf) // Capture stderr as well
 	root.SetArgs(args)
-	
+
 	// Need to simulate PersistentPreRunE if commands rely on it
-    // Or ensure the test setup handles what PersistentPreRunE would do.
-    // For tests, it's often cleaner to have the setup function (like setupTestAppCfg)
-    // initialize everything that PersistentPreRunE would.
+	// Or ensure the test setup handles what PersistentPreRunE would do.
+	// For tests, it's often cleaner to have the setup function (like setupTestAppCfg)
+	// initialize everything that PersistentPreRunE would.
 
 	err := root.ExecuteContext(context.Background()) // Use ExecuteContext for cancellable commands
 	return strings.TrimSpace(buf.String()), err
 }
 
-
 func TestProxyAddCmd(t *testing.T) {
 	cfg, cleanup := setupTestAppCfg(t)
 	defer cleanup()
 
-    // Initialize the database for this test run
-    testDB, dbCleanup := database.setupTestDB(t) // Use the helper from database tests
-    defer dbCleanup()
-    cfg.DatabasePath = testDB.DBName() // Update AppCfg with the actual test DB path
-    AppCfg = cfg // Ensure global AppCfg is updated with test DB path
+	// Initialize the database for this test run
+	testDB, err := database.Connect(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer testDB.Close()
+	AppCfg = cfg // Ensure global AppCfg is updated with test DB path
 
 	rootCmd := &cobra.Command{Use: "root"} // Dummy root
-	proxyCmd := NewProxyCmd() // This will use the global AppCfg
+	proxyCmd := NewProxyCmd()              // This will use the global AppCfg
 	rootCmd.AddCommand(proxyCmd)
 
 	// Test adding a proxy
@@ -100,6 +100,10 @@ func TestProxyAddCmd(t *testing.T) {
 		}
 	}
 	assert.True(t, found, "Proxy added via CLI not found in database")
+
+	// pac proxies are refused by builds that can't evaluate PAC files
+	_, err = executeCommand(rootCmd, "proxy", "add", "corp", "pac", "http://wpad/wpad.dat")
+	assert.Equal(t, !proxy.PACSupported(), err != nil)
 }
 
-// Add TestProxyListCmd, TestProxyValidateCmd etc.
\ No newline at end of file
+// Add TestProxyListCmd, TestProxyValidateCmd etc.
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+// maxProxyListSize bounds a downloaded proxy subscription.
+const maxProxyListSize = 10 << 20
+
+func newProxyImportCmd() *cobra.Command {
+	var (
+		defaultType string
+		namePrefix  string
+		validate    bool
+		targetURL   string
+		concurrency int
+	)
+
+	importCmd := &cobra.Command{
+		Use:   "import <file|url>",
+		Short: "Import proxies from a file or subscription URL (host:port, host:port:user:pass, user:pass@host:port or scheme://... lines)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			defaultType = strings.ToLower(defaultType)
+			if defaultType != "http" && defaultType != "https" && defaultType != "socks5" && defaultType != "socks5h" {
+				return fmt.Errorf("invalid --type: %s. Must be http, https, socks5, or socks5h", defaultType)
+			}
+			if concurrency < 1 {
+				return fmt.Errorf("--concurrency must be at least 1")
+			}
+			if AppCfg == nil {
+				return fmt.Errorf("configuration not loaded for proxy import")
+			}
+
+			list, err := openProxyList(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			parsed, parseErrs := proxy.ParseProxyList(list, defaultType)
+			list.Close()
+			for _, e := range parseErrs {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %v\n", e)
+			}
+
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil {
+				return fmt.Errorf("failed to connect to database: %w", err)
+			}
+			defer db.Close()
+			proxyStore := database.NewProxyStore(db)
+
+			existing, err := proxyStore.ListProxies(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to list proxies: %w", err)
+			}
+			seen := make(map[string]bool)
+			for _, p := range existing {
+				seen[proxy.ProxyKey(p)] = true
+				seen["name:"+p.Name] = true
+			}
+			var fresh []*database.Proxy
+			duplicates := 0
+			for _, p := range parsed {
+				p.Name = importedProxyName(namePrefix, p)
+				if seen[proxy.ProxyKey(p)] || seen["name:"+p.Name] {
+					duplicates++
+					continue
+				}
+				seen[proxy.ProxyKey(p)], seen["name:"+p.Name] = true, true
+				fresh = append(fresh, p)
+			}
+
+			failed := 0
+			if validate && len(fresh) > 0 {
+				fmt.Printf("Validating %d proxies against %s...\n", len(fresh), targetURL)
+				fresh, failed = validateImported(cmd, fresh, targetURL, concurrency)
+			}
+			if len(fresh) > 0 {
+				if err := proxyStore.CreateProxies(cmd.Context(), fresh); err != nil {
+					return fmt.Errorf("failed to import proxies: %w", err)
+				}
+			}
+			fmt.Printf("Imported %d proxies (%d duplicates, %d failed validation, %d unparsable lines).\n", len(fresh), duplicates, failed, len(parseErrs))
+			return nil
+		},
+	}
+	importCmd.Flags().StringVar(&defaultType, "type", "http", "Proxy type for entries without a scheme: http, https, socks5 or socks5h")
+	importCmd.Flags().StringVar(&namePrefix, "name-prefix", "import", "Imported proxies are named <prefix>-[user@]host:port")
+	importCmd.Flags().BoolVar(&validate, "validate", false, "Only import proxies that pass a connectivity check")
+	importCmd.Flags().StringVar(&targetURL, "target-url", "https://www.google.com/generate_204", "URL to test proxy connectivity against with --validate")
+	importCmd.Flags().IntVar(&concurrency, "concurrency", 10, "Proxies validated at once")
+	return importCmd
+}
+
+// openProxyList opens a local file, or downloads a subscription URL.
+func openProxyList(cmd *cobra.Command, source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("opening proxy list: %w", err)
+		}
+		return f, nil
+	}
+	client, err := proxy.NewHTTPClientFactory().GetClient(nil)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(cmd.Context(), http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription URL: %w", err)
+	}
+	req.Header.Set("User-Agent", AppCfg.UserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching proxy subscription: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching proxy subscription: status %d", resp.StatusCode)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(resp.Body, maxProxyListSize), resp.Body}, nil
+}
+
+func importedProxyName(prefix string, p *database.Proxy) string {
+	name := p.Address
+	if p.Username != nil {
+		name = *p.Username + "@" + name
+	}
+	if p.Type != "http" {
+		name = p.Type + "://" + name
+	}
+	return prefix + "-" + name
+}
+
+// validateImported checks proxies concurrently and returns those that passed
+// and the number that failed.
+func validateImported(cmd *cobra.Command, proxies []*database.Proxy, targetURL string, concurrency int) ([]*database.Proxy, int) {
+	validator := proxy.NewDefaultProxyValidator(proxy.NewHTTPClientFactory())
+	ok := make([]bool, len(proxies))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, p := range proxies {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, p *database.Proxy) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := validator.Validate(cmd.Context(), p, targetURL); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %s: %v\n", p.Name, err)
+				return
+			}
+			ok[i] = true
+		}(i, p)
+	}
+	wg.Wait()
+
+	var passed []*database.Proxy
+	for i, p := range proxies {
+		if ok[i] {
+			passed = append(passed, p)
+		}
+	}
+	return passed, len(proxies) - len(passed)
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/spf13/cobra"
+)
+
+// proxyPoolStrategies are the rotation strategies accepted by 'proxy pool create'.
+var proxyPoolStrategies = map[string]bool{
+	database.ProxyPoolRoundRobin: true,
+	database.ProxyPoolRandom:     true,
+	database.ProxyPoolSticky:     true,
+}
+
+// newProxyPoolCmd groups the proxy pool subcommands.
+func newProxyPoolCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "pool",
+		Short:   "Manage proxy pools that feeds rotate their fetches through",
+		Aliases: []string{"pools"},
+	}
+	cmd.AddCommand(newProxyPoolCreateCmd())
+	cmd.AddCommand(newProxyPoolMemberCmd("add", "Add a proxy to a pool"))
+	cmd.AddCommand(newProxyPoolMemberCmd("remove", "Remove a proxy from a pool"))
+	cmd.AddCommand(newProxyPoolListCmd())
+	cmd.AddCommand(newProxyPoolTelegramCmd())
+	return cmd
+}
+
+func newProxyPoolCreateCmd() *cobra.Command {
+	var strategy string
+	var forTelegram bool
+	createCmd := &cobra.Command{
+		Use:   "create <pool_name>",
+		Short: "Create a new, empty proxy pool",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !proxyPoolStrategies[strategy] {
+				return fmt.Errorf("invalid --strategy %q (expected %s, %s or %s)", strategy, database.ProxyPoolRoundRobin, database.ProxyPoolRandom, database.ProxyPoolSticky)
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+			proxyStore := database.NewProxyStore(db)
+
+			id, err := proxyStore.CreatePool(cmd.Context(), args[0], strategy)
+			if err != nil { return fmt.Errorf("failed to create proxy pool: %w", err) }
+			fmt.Printf("Proxy pool '%s' (%s) created with ID: %d\n", args[0], strategy, id)
+			if forTelegram {
+				if err := proxyStore.SetTelegramPool(cmd.Context(), id); err != nil { return fmt.Errorf("failed to set Telegram pool: %w", err) }
+				fmt.Println("Telegram traffic without a feed proxy now goes through this pool.")
+			}
+			return nil
+		},
+	}
+	createCmd.Flags().StringVar(&strategy, "strategy", database.ProxyPoolRoundRobin, "Rotation: round_robin (next proxy each fetch), random, or sticky (same proxy per feed)")
+	createCmd.Flags().BoolVar(&forTelegram, "default-telegram", false, "Route Telegram traffic of feeds without their own proxy through this pool")
+	return createCmd
+}
+
+// newProxyPoolMemberCmd builds the "add" and "remove" membership subcommands, which share their arguments.
+func newProxyPoolMemberCmd(action, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   action + " <pool_id> <proxy_id>",
+		Short: short,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var poolID, proxyID int64
+			if _, err := fmt.Sscan(args[0], &poolID); err != nil {
+				return fmt.Errorf("invalid pool ID: %s", args[0])
+			}
+			if _, err := fmt.Sscan(args[1], &proxyID); err != nil {
+				return fmt.Errorf("invalid proxy ID: %s", args[1])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+			proxyStore := database.NewProxyStore(db)
+
+			if action == "add" {
+				if err := proxyStore.AddProxyToPool(cmd.Context(), poolID, proxyID); err != nil {
+					return fmt.Errorf("failed to add proxy to pool: %w", err)
+				}
+				fmt.Printf("Proxy %d added to pool %d.\n", proxyID, poolID)
+				return nil
+			}
+			if err := proxyStore.RemoveProxyFromPool(cmd.Context(), poolID, proxyID); err != nil {
+				return fmt.Errorf("failed to remove proxy from pool: %w", err)
+			}
+			fmt.Printf("Proxy %d removed from pool %d.\n", proxyID, poolID)
+			return nil
+		},
+	}
+}
+
+func newProxyPoolListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List proxy pools and their member proxies",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+			proxyStore := database.NewProxyStore(db)
+
+			pools, err := proxyStore.ListPools(cmd.Context())
+			if err != nil { return fmt.Errorf("failed to list proxy pools: %w", err) }
+			if len(pools) == 0 {
+				fmt.Println("No proxy pools configured.")
+				return nil
+			}
+			fmt.Println("Configured Proxy Pools:")
+			for _, p := range pools {
+				ids := make([]int64, len(p.Proxies))
+				for i, member := range p.Proxies {
+					ids[i] = member.ID
+				}
+				tgDef := ""
+				if p.IsDefaultForTelegram {
+					tgDef = " [Default TG]"
+				}
+				fmt.Printf("ID: %d, Name: %s, Strategy: %s, Proxy IDs: %v%s\n", p.ID, p.Name, p.Strategy, ids, tgDef)
+			}
+			return nil
+		},
+	}
+}
+
+func newProxyPoolTelegramCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "telegram <pool_id|none>",
+		Short: "Route Telegram traffic of feeds without their own proxy through a pool",
+		Long: `Sets the pool that carries Telegram Bot API traffic (deliveries, admin alerts)
+for feeds without a proxy of their own. It takes precedence over the proxy added
+with --default-telegram, which is used when none of the pool's proxies is usable.
+"none" clears it.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var poolID int64
+			if args[0] != "none" {
+				if _, err := fmt.Sscan(args[0], &poolID); err != nil {
+					return fmt.Errorf("invalid pool ID: %s", args[0])
+				}
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+			proxyStore := database.NewProxyStore(db)
+
+			if err := proxyStore.SetTelegramPool(cmd.Context(), poolID); err != nil {
+				return fmt.Errorf("failed to set Telegram pool: %w", err)
+			}
+			if poolID == 0 {
+				fmt.Println("Telegram proxy pool cleared.")
+			} else {
+				fmt.Printf("Telegram traffic without a feed proxy now goes through pool %d.\n", poolID)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/spf13/cobra"
+)
+
+// newProxyRuleCmd groups the host-pattern proxy rule subcommands.
+func newProxyRuleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rule",
+		Short: "Route hosts matching a pattern through a proxy",
+		Long: `Proxy rules send requests for matching hosts (e.g. "*.medium.com" or "*.onion")
+through a proxy, for feeds and bots without a proxy or pool of their own. They
+take precedence over the default proxies. Rules are loaded when the bot starts.`,
+		Aliases: []string{"rules"},
+	}
+	cmd.AddCommand(newProxyRuleAddCmd())
+	cmd.AddCommand(newProxyRuleListCmd())
+	cmd.AddCommand(newProxyRuleRemoveCmd())
+	return cmd
+}
+
+func newProxyRuleAddCmd() *cobra.Command {
+	var priority int
+	addCmd := &cobra.Command{
+		Use:   "add <host_pattern> <proxy_id>",
+		Short: "Add a proxy rule",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var proxyID int64
+			if _, err := fmt.Sscan(args[1], &proxyID); err != nil {
+				return fmt.Errorf("invalid proxy ID: %s", args[1])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+			proxyStore := database.NewProxyStore(db)
+
+			p, err := proxyStore.GetProxyByID(cmd.Context(), proxyID)
+			if err != nil { return fmt.Errorf("failed to load proxy: %w", err) }
+			if p == nil { return fmt.Errorf("proxy %d not found", proxyID) }
+
+			id, err := proxyStore.CreateProxyRule(cmd.Context(), args[0], proxyID, priority)
+			if err != nil { return fmt.Errorf("failed to add proxy rule: %w", err) }
+			fmt.Printf("Proxy rule %d added: %s -> %s (ID %d). Restart the bot to apply it.\n", id, args[0], p.Name, proxyID)
+			return nil
+		},
+	}
+	addCmd.Flags().IntVar(&priority, "priority", 0, "Rules with higher priority are matched first; ties go to the older rule")
+	return addCmd
+}
+
+func newProxyRuleListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List proxy rules in matching order",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+			proxyStore := database.NewProxyStore(db)
+
+			rules, err := proxyStore.ListProxyRules(cmd.Context())
+			if err != nil { return fmt.Errorf("failed to list proxy rules: %w", err) }
+			if len(rules) == 0 {
+				fmt.Println("No proxy rules configured.")
+				return nil
+			}
+			fmt.Println("Proxy Rules:")
+			for _, r := range rules {
+				name := "?"
+				if r.Proxy != nil {
+					name = r.Proxy.Name
+				}
+				fmt.Printf("ID: %d, Pattern: %s, Proxy: %s (ID %d), Priority: %d\n", r.ID, r.Pattern, name, r.ProxyID, r.Priority)
+			}
+			return nil
+		},
+	}
+}
+
+func newProxyRuleRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <rule_id>",
+		Short: "Remove a proxy rule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var ruleID int64
+			if _, err := fmt.Sscan(args[0], &ruleID); err != nil {
+				return fmt.Errorf("invalid rule ID: %s", args[0])
+			}
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+
+			if err := database.NewProxyStore(db).DeleteProxyRule(cmd.Context(), ruleID); err != nil {
+				return fmt.Errorf("failed to remove proxy rule: %w", err)
+			}
+			fmt.Printf("Proxy rule %d removed.\n", ruleID)
+			return nil
+		},
+	}
+}
@@ -66,6 +66,10 @@ func init() {
 	RootCmd.AddCommand(NewDbCmd())
 	RootCmd.AddCommand(NewBotCmd())
 	RootCmd.AddCommand(NewFormatProfileCmd())
-	// RootCmd.AddCommand(NewOPMLCmd())
-	// RootCmd.AddCommand(NewConfigCmd()) // For managing formatting profiles, telegram bots
+	RootCmd.AddCommand(NewSyncCmd())
+	RootCmd.AddCommand(NewExportCmd())
+	RootCmd.AddCommand(NewImportCmd())
+	RootCmd.AddCommand(NewAPIKeyCmd())
+	RootCmd.AddCommand(NewArchiveCmd())
+	RootCmd.AddCommand(NewConfigCmd())
 }
\ No newline at end of file
@@ -15,6 +15,7 @@ import (
 // NewRunCmd creates the run command.
 // It no longer takes appCfg as a parameter.
 func NewRunCmd() *cobra.Command {
+	var notifier string
 	cmd := &cobra.Command{
 		Use:   "run",
 		Short: "Starts the RSS feed fetching and Telegram notification service",
@@ -29,6 +30,7 @@ func NewRunCmd() *cobra.Command {
 
 			// database.InitEncryptionKey() is now handled in root.go's PersistentPreRunE,
 			// so it's not called here.
+			if notifier != "" { AppCfg.Notifier = notifier }
 
 			// Pass the global AppCfg to NewApplication
 			application, err := app.NewApplication(AppCfg)
@@ -45,5 +47,6 @@ func NewRunCmd() *cobra.Command {
 			return application.Run(ctx)
 		},
 	}
+	cmd.Flags().StringVar(&notifier, "notifier", "", "where messages go: telegram, or sandbox to record them (see sandbox in the config); overrides the notifier setting")
 	return cmd
 }
\ No newline at end of file
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/feedsync"
+	"github.com/spf13/cobra"
+)
+
+// NewSyncCmd reconciles the database with a declarative feeds.yaml.
+func NewSyncCmd() *cobra.Command {
+	var file string
+	var prune bool
+	cmd := &cobra.Command{
+		Use:   "sync --file feeds.yaml [--prune]",
+		Short: "Make feeds, bots, proxies and formatting profiles match a YAML document",
+		Long: `Reads a declarative document of proxies, formatting profiles, bots (tokens given as
+env:NAME or file:PATH references) and feeds, prints the changes needed to make the
+database match it, and applies them. With --prune, entries missing from the document
+are deleted. Use the global --dry-run flag to only print the changes.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if AppCfg == nil { return fmt.Errorf("configuration not loaded") }
+			doc, err := feedsync.Load(file)
+			if err != nil { return fmt.Errorf("loading %s: %w", file, err) }
+
+			db, err := database.Connect(AppCfg.DatabasePath)
+			if err != nil { return fmt.Errorf("db connect: %w", err) }
+			defer db.Close()
+			syncer := feedsync.NewSyncer(db, AppCfg.DefaultFetchFreq)
+
+			plan, err := syncer.Plan(cmd.Context(), doc, prune)
+			if err != nil { return fmt.Errorf("planning sync: %w", err) }
+			if plan.Empty() {
+				fmt.Fprintln(cmd.OutOrStdout(), "Database already matches the document.")
+				return nil
+			}
+			fmt.Fprint(cmd.OutOrStdout(), plan.String())
+			if AppCfg.DryRun {
+				fmt.Fprintln(cmd.OutOrStdout(), "Dry run: no changes applied.")
+				return nil
+			}
+			if err := syncer.Apply(cmd.Context(), plan); err != nil { return fmt.Errorf("applying sync: %w", err) }
+			fmt.Fprintln(cmd.OutOrStdout(), "Sync complete. Restart the bot for feed changes to take effect.")
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "feeds.yaml", "Path of the YAML document")
+	cmd.Flags().BoolVar(&prune, "prune", false, "Delete feeds, bots, proxies and profiles that are not in the document")
+	return cmd
+}
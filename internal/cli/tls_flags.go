@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+// addTLSFlags registers the --tls-* and --http-version flags shared by 'feed add' and 'proxy add'.
+func addTLSFlags(cmd *cobra.Command, cfg *database.TLSConfig) {
+	cmd.Flags().BoolVar(&cfg.InsecureSkipVerify, "tls-skip-verify", false, "Don't verify the server certificate (insecure; prefer --tls-ca-file)")
+	cmd.Flags().StringVar(&cfg.CAFile, "tls-ca-file", "", "PEM bundle of extra CAs to trust, e.g. a private corporate CA")
+	cmd.Flags().StringVar(&cfg.MinVersion, "tls-min-version", "", "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3")
+	cmd.Flags().StringVar(&cfg.ClientCertFile, "tls-client-cert", "", "PEM client certificate for mutual TLS (requires --tls-client-key)")
+	cmd.Flags().StringVar(&cfg.ClientKeyFile, "tls-client-key", "", "PEM private key for --tls-client-cert")
+	cmd.Flags().StringVar(&cfg.HTTPVersion, "http-version", "", "Force an HTTP version: 1.1, 2 or 3 (HTTP/3 falls back to HTTP/2 when QUIC is unavailable)")
+}
+
+// tlsConfigFromFlags validates the --tls-* flags by loading the referenced
+// files, and returns nil when none were given.
+func tlsConfigFromFlags(cmd *cobra.Command, cfg database.TLSConfig) (*database.TLSConfig, error) {
+	if cfg.IsZero() {
+		return nil, nil
+	}
+	if _, err := proxy.BuildTLSConfig(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid TLS flags: %w", err)
+	}
+	if cfg.InsecureSkipVerify {
+		fmt.Fprintln(cmd.ErrOrStderr(), "Warning: --tls-skip-verify disables certificate verification; traffic can be intercepted.")
+	}
+	return &cfg, nil
+}
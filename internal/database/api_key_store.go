@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// apiKeyPrefix marks generated keys so they are recognisable in configs and logs.
+const apiKeyPrefix = "rtb_"
+
+// apiKeyTouchInterval limits how often a key's last use is written back, so
+// busy clients don't turn every read into a write.
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyStore handles database operations for API keys.
+type APIKeyStore struct {
+	db *DB
+}
+
+// NewAPIKeyStore creates a new APIKeyStore.
+func NewAPIKeyStore(db *DB) *APIKeyStore {
+	return &APIKeyStore{db: db}
+}
+
+// NewAPIKey returns a new random API key.
+func NewAPIKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("NewAPIKey: %w", err)
+	}
+	return apiKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// ValidRole reports whether role is one of the API key roles.
+func ValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleEditor, RoleViewer:
+		return true
+	}
+	return false
+}
+
+// CreateAPIKey stores a key under name with role and returns its ID. Only
+// the key's hash is kept.
+func (s *APIKeyStore) CreateAPIKey(ctx context.Context, name, role, key string) (int64, error) {
+	if !ValidRole(role) {
+		return 0, fmt.Errorf("CreateAPIKey: invalid role %q", role)
+	}
+	stmt, err := s.db.PrepareContext(ctx, `INSERT INTO api_keys (name, key_hash, role) VALUES (?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("CreateAPIKey prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, name, hashToken(key), role)
+	if err != nil {
+		return 0, fmt.Errorf("CreateAPIKey exec: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// GetAPIKeyByKey finds the API key matching key. It returns nil, nil when
+// there is none.
+func (s *APIKeyStore) GetAPIKeyByKey(ctx context.Context, key string) (*APIKey, error) {
+	k := &APIKey{}
+	err := s.db.QueryRowContext(ctx, `SELECT id, name, role, last_used_at, created_at FROM api_keys WHERE key_hash = ?`, hashToken(key)).
+		Scan(&k.ID, &k.Name, &k.Role, &k.LastUsedAt, &k.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetAPIKeyByKey scan: %w", err)
+	}
+	return k, nil
+}
+
+// GetAPIKeyByID retrieves an API key by its ID. It returns nil, nil when
+// there is none.
+func (s *APIKeyStore) GetAPIKeyByID(ctx context.Context, id int64) (*APIKey, error) {
+	k := &APIKey{}
+	err := s.db.QueryRowContext(ctx, `SELECT id, name, role, last_used_at, created_at FROM api_keys WHERE id = ?`, id).
+		Scan(&k.ID, &k.Name, &k.Role, &k.LastUsedAt, &k.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetAPIKeyByID scan for key %d: %w", id, err)
+	}
+	return k, nil
+}
+
+// ListAPIKeys retrieves all API keys, oldest first.
+func (s *APIKeyStore) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, role, last_used_at, created_at FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("ListAPIKeys query: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		k := &APIKey{}
+		if err := rows.Scan(&k.ID, &k.Name, &k.Role, &k.LastUsedAt, &k.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ListAPIKeys scan: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListAPIKeys rows error: %w", err)
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey revokes an API key.
+func (s *APIKeyStore) DeleteAPIKey(ctx context.Context, id int64) error {
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM api_keys WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("DeleteAPIKey prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("DeleteAPIKey exec for key %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("DeleteAPIKey: key %d not found", id)
+	}
+	return nil
+}
+
+// TouchAPIKey records that k was just used, unless that was already recorded
+// within the last minute.
+func (s *APIKeyStore) TouchAPIKey(ctx context.Context, k *APIKey) error {
+	now := time.Now().UTC()
+	if k.LastUsedAt != nil && now.Sub(*k.LastUsedAt) < apiKeyTouchInterval {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, now, k.ID); err != nil {
+		return fmt.Errorf("TouchAPIKey exec for key %d: %w", k.ID, err)
+	}
+	k.LastUsedAt = &now
+	return nil
+}
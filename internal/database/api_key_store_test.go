@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyStore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := NewAPIKeyStore(db)
+	ctx := context.Background()
+
+	key, err := NewAPIKey()
+	require.NoError(t, err)
+	assert.Regexp(t, `^rtb_[0-9a-f]{48}$`, key)
+
+	_, err = store.CreateAPIKey(ctx, "ci", "owner", key)
+	assert.Error(t, err, "unknown roles are rejected")
+
+	id, err := store.CreateAPIKey(ctx, "ci", RoleEditor, key)
+	require.NoError(t, err)
+
+	var stored string
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT key_hash FROM api_keys WHERE id = ?`, id).Scan(&stored))
+	assert.NotContains(t, stored, key)
+
+	found, err := store.GetAPIKeyByKey(ctx, key)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "ci", found.Name)
+	assert.Equal(t, RoleEditor, found.Role)
+	assert.Nil(t, found.LastUsedAt)
+
+	missing, err := store.GetAPIKeyByKey(ctx, key+"x")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	require.NoError(t, store.TouchAPIKey(ctx, found))
+	found, err = store.GetAPIKeyByKey(ctx, key)
+	require.NoError(t, err)
+	assert.NotNil(t, found.LastUsedAt)
+
+	keys, err := store.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	require.NoError(t, store.DeleteAPIKey(ctx, id))
+	assert.Error(t, store.DeleteAPIKey(ctx, id))
+	found, err = store.GetAPIKeyByKey(ctx, key)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
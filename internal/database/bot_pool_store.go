@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// CreatePool adds a new, empty bot pool.
+func (s *TelegramBotStore) CreatePool(ctx context.Context, name string) (int64, error) {
+	stmt, err := s.db.PrepareContext(ctx, `INSERT INTO bot_pools (name) VALUES (?)`)
+	if err != nil {
+		return 0, fmt.Errorf("CreatePool prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, name)
+	if err != nil {
+		return 0, fmt.Errorf("CreatePool exec: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// AddBotToPool adds a bot to a pool. Adding a bot that is already a member is a no-op.
+func (s *TelegramBotStore) AddBotToPool(ctx context.Context, poolID, botID int64) error {
+	stmt, err := s.db.PrepareContext(ctx, `INSERT OR IGNORE INTO bot_pool_members (pool_id, bot_id) VALUES (?, ?)`)
+	if err != nil {
+		return fmt.Errorf("AddBotToPool prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, poolID, botID); err != nil {
+		return fmt.Errorf("AddBotToPool exec for pool %d, bot %d: %w", poolID, botID, err)
+	}
+	return nil
+}
+
+// RemoveBotFromPool removes a bot from a pool.
+func (s *TelegramBotStore) RemoveBotFromPool(ctx context.Context, poolID, botID int64) error {
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM bot_pool_members WHERE pool_id = ? AND bot_id = ?`)
+	if err != nil {
+		return fmt.Errorf("RemoveBotFromPool prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, poolID, botID)
+	if err != nil {
+		return fmt.Errorf("RemoveBotFromPool exec for pool %d, bot %d: %w", poolID, botID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("RemoveBotFromPool: bot %d is not a member of pool %d", botID, poolID)
+	}
+	return nil
+}
+
+// GetPoolBotIDs returns the IDs of the bots in a pool, ordered by bot ID.
+func (s *TelegramBotStore) GetPoolBotIDs(ctx context.Context, poolID int64) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT bot_id FROM bot_pool_members WHERE pool_id = ? ORDER BY bot_id`, poolID)
+	if err != nil {
+		return nil, fmt.Errorf("GetPoolBotIDs query: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("GetPoolBotIDs scan: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetPoolBotIDs rows error: %w", err)
+	}
+	return ids, nil
+}
+
+// GetPoolByID retrieves a bot pool and its member bot IDs.
+func (s *TelegramBotStore) GetPoolByID(ctx context.Context, id int64) (*BotPool, error) {
+	pool := &BotPool{}
+	err := s.db.QueryRowContext(ctx, `SELECT id, name, created_at, updated_at FROM bot_pools WHERE id = ?`, id).
+		Scan(&pool.ID, &pool.Name, &pool.CreatedAt, &pool.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("GetPoolByID scan: %w", err)
+	}
+	if pool.BotIDs, err = s.GetPoolBotIDs(ctx, id); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// ListPools retrieves all bot pools with their member bot IDs.
+func (s *TelegramBotStore) ListPools(ctx context.Context) ([]*BotPool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, created_at, updated_at FROM bot_pools ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("ListPools query: %w", err)
+	}
+	defer rows.Close()
+
+	var pools []*BotPool
+	for rows.Next() {
+		pool := &BotPool{}
+		if err := rows.Scan(&pool.ID, &pool.Name, &pool.CreatedAt, &pool.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("ListPools scan: %w", err)
+		}
+		pools = append(pools, pool)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListPools rows error: %w", err)
+	}
+	rows.Close()
+
+	for _, pool := range pools {
+		if pool.BotIDs, err = s.GetPoolBotIDs(ctx, pool.ID); err != nil {
+			return nil, err
+		}
+	}
+	return pools, nil
+}
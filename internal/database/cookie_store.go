@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// CookieStore persists per-feed cookie jars.
+type CookieStore struct {
+	db *DB
+}
+
+// NewCookieStore creates a new CookieStore.
+func NewCookieStore(db *DB) *CookieStore {
+	return &CookieStore{db: db}
+}
+
+// ListCookies returns the cookies stored for a feed that have not expired.
+func (s *CookieStore) ListCookies(ctx context.Context, feedID int64) ([]*StoredCookie, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT feed_id, name, domain, path, host_only, value, secure, http_only, expires_at
+		FROM feed_cookies
+		WHERE feed_id = ? AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`, feedID)
+	if err != nil {
+		return nil, fmt.Errorf("ListCookies query: %w", err)
+	}
+	defer rows.Close()
+
+	var cookies []*StoredCookie
+	for rows.Next() {
+		c := &StoredCookie{}
+		if err := rows.Scan(&c.FeedID, &c.Name, &c.Domain, &c.Path, &c.HostOnly, &c.Value, &c.Secure, &c.HTTPOnly, &c.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("ListCookies scan: %w", err)
+		}
+		cookies = append(cookies, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListCookies rows error: %w", err)
+	}
+	return cookies, nil
+}
+
+// SaveCookie creates or replaces a stored cookie.
+func (s *CookieStore) SaveCookie(ctx context.Context, c *StoredCookie) error {
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO feed_cookies (feed_id, name, domain, path, host_only, value, secure, http_only, expires_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(feed_id, name, domain, path) DO UPDATE SET
+			host_only = excluded.host_only, value = excluded.value, secure = excluded.secure,
+			http_only = excluded.http_only, expires_at = excluded.expires_at, updated_at = CURRENT_TIMESTAMP`)
+	if err != nil {
+		return fmt.Errorf("SaveCookie prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, c.FeedID, c.Name, c.Domain, c.Path, c.HostOnly, c.Value, c.Secure, c.HTTPOnly, c.ExpiresAt); err != nil {
+		return fmt.Errorf("SaveCookie exec for feed ID %d: %w", c.FeedID, err)
+	}
+	return nil
+}
+
+// DeleteCookie removes a stored cookie, e.g. when the server expires it.
+func (s *CookieStore) DeleteCookie(ctx context.Context, feedID int64, name, domain, path string) error {
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM feed_cookies WHERE feed_id = ? AND name = ? AND domain = ? AND path = ?`)
+	if err != nil {
+		return fmt.Errorf("DeleteCookie prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, feedID, name, domain, path); err != nil {
+		return fmt.Errorf("DeleteCookie exec for feed ID %d: %w", feedID, err)
+	}
+	return nil
+}
@@ -7,10 +7,8 @@ import (
     "io"           // <--- ENSURE THIS IS PRESENT
     "os"
     "path/filepath"
+    "sync"
 
-    "github.com/golang-migrate/migrate/v4"
-    "github.com/golang-migrate/migrate/v4/database/sqlite3"
-    _ "github.com/golang-migrate/migrate/v4/source/file"
     _ "github.com/mattn/go-sqlite3"
     "github.com/rs/zerolog/log"
 )
@@ -18,10 +16,61 @@ import (
 // DB wraps the sql.DB connection.
 type DB struct {
 	*sql.DB
+
+	stmtsMu sync.Mutex
+	stmts   map[string]*sql.Stmt // Prepared statements by query, for PrepareCached
+}
+
+// PrepareCached returns a prepared statement for query, preparing it on first
+// use and reusing it afterwards. The statement is owned by db and closed with
+// it, so callers must not close it. Use it for statements run on every feed
+// run; one-off queries are better off with PrepareContext.
+func (db *DB) PrepareCached(ctx context.Context, query string) (*sql.Stmt, error) {
+	db.stmtsMu.Lock()
+	defer db.stmtsMu.Unlock()
+	if stmt, ok := db.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := db.DB.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if db.stmts == nil {
+		db.stmts = make(map[string]*sql.Stmt)
+	}
+	db.stmts[query] = stmt
+	return stmt, nil
+}
+
+// Close closes the cached statements and the database.
+func (db *DB) Close() error {
+	db.stmtsMu.Lock()
+	for query, stmt := range db.stmts {
+		stmt.Close()
+		delete(db.stmts, query)
+	}
+	db.stmtsMu.Unlock()
+	return db.DB.Close()
 }
 
-// Connect initializes the database connection and runs migrations.
-func Connect(dataSourceName string, migrationsPath string) (*DB, error) {
+// Connect opens the database and brings its schema up to date with the
+// migrations built into the binary.
+func Connect(dataSourceName string) (*DB, error) {
+	db, err := Open(dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateUp(db.DB); err != nil {
+		db.Close()
+		return nil, err
+	}
+	log.Info().Msg("Database migrations applied successfully or no changes detected")
+	return db, nil
+}
+
+// Open opens the database without migrating it, for commands that handle the
+// file as a whole, like backup and restore.
+func Open(dataSourceName string) (*DB, error) {
 	// Ensure the directory for the database file exists
 	dbDir := filepath.Dir(dataSourceName)
 	if _, err := os.Stat(dbDir); os.IsNotExist(err) {
@@ -48,32 +97,7 @@ func Connect(dataSourceName string, migrationsPath string) (*DB, error) {
 
 	log.Info().Str("path", dataSourceName).Msg("Database connection established")
 
-	// Run migrations
-	if migrationsPath != "" {
-		driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
-		if err != nil {
-			db.Close()
-			return nil, fmt.Errorf("failed to create sqlite3 migrate driver: %w", err)
-		}
-		m, err := migrate.NewWithDatabaseInstance(
-			fmt.Sprintf("file://%s", migrationsPath),
-			"sqlite3", driver)
-		if err != nil {
-			db.Close()
-			return nil, fmt.Errorf("failed to init migrate instance: %w", err)
-		}
-
-		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-			db.Close()
-			return nil, fmt.Errorf("failed to apply migrations: %w", err)
-		}
-		log.Info().Msg("Database migrations applied successfully or no changes detected")
-	} else {
-		log.Warn().Msg("Migrations path not provided, skipping migrations.")
-	}
-
-
-	return &DB{db}, nil
+	return &DB{DB: db}, nil
 }
 
 // Backup creates a backup of the SQLite database.
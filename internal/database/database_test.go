@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareCached(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	first, err := db.PrepareCached(ctx, `SELECT 1`)
+	require.NoError(t, err)
+	second, err := db.PrepareCached(ctx, `SELECT 1`)
+	require.NoError(t, err)
+	assert.Same(t, first, second, "statement should be prepared once")
+
+	other, err := db.PrepareCached(ctx, `SELECT 2`)
+	require.NoError(t, err)
+	assert.NotSame(t, first, other)
+
+	_, err = db.PrepareCached(ctx, `SELECT FROM`)
+	assert.Error(t, err)
+}
+
+// The benchmarks below compare the hot paths of a feed run with their
+// statements prepared on every call, as before, and cached on the DB.
+
+func benchmarkFeed(b *testing.B) (*DB, *FeedStore, int64, func()) {
+	db, cleanup := setupTestDB(b)
+	store := NewFeedStore(db)
+	feedID, err := store.CreateFeed(context.Background(), &Feed{URL: "https://example.com/feed.xml", FrequencySeconds: 300, TelegramChatID: "1"})
+	require.NoError(b, err)
+	return db, store, feedID, cleanup
+}
+
+const benchmarkInsertProcessed = `INSERT OR IGNORE INTO processed_items (feed_id, item_guid_hash, processed_at) VALUES (?, ?, CURRENT_TIMESTAMP)`
+
+func BenchmarkAddProcessedItem(b *testing.B) {
+	ctx := context.Background()
+	b.Run("prepare per call", func(b *testing.B) {
+		db, _, feedID, cleanup := benchmarkFeed(b)
+		defer cleanup()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			stmt, err := db.PrepareContext(ctx, benchmarkInsertProcessed)
+			require.NoError(b, err)
+			_, err = stmt.ExecContext(ctx, feedID, fmt.Sprintf("hash%d", i))
+			stmt.Close()
+			require.NoError(b, err)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		_, store, feedID, cleanup := benchmarkFeed(b)
+		defer cleanup()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, store.AddProcessedItem(ctx, feedID, fmt.Sprintf("hash%d", i)))
+		}
+	})
+}
+
+func BenchmarkGetFeedByID(b *testing.B) {
+	ctx := context.Background()
+	b.Run("prepare per call", func(b *testing.B) {
+		db, _, feedID, cleanup := benchmarkFeed(b)
+		defer cleanup()
+		query := feedSelectQuery + ` WHERE f.id = ?`
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			stmt, err := db.PrepareContext(ctx, query)
+			require.NoError(b, err)
+			err = scanFeed(stmt.QueryRowContext(ctx, feedID), &Feed{})
+			stmt.Close()
+			require.NoError(b, err)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		_, store, feedID, cleanup := benchmarkFeed(b)
+		defer cleanup()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, err := store.GetFeedByID(ctx, feedID)
+			require.NoError(b, err)
+		}
+	})
+}
+
+func BenchmarkUpdateFeedLastProcessed(b *testing.B) {
+	ctx := context.Background()
+	hash := "hash"
+	b.Run("prepare per call", func(b *testing.B) {
+		db, _, feedID, cleanup := benchmarkFeed(b)
+		defer cleanup()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			stmt, err := db.PrepareContext(ctx, `UPDATE feeds SET last_processed_item_guid_hash = ?, last_fetched_at = CURRENT_TIMESTAMP WHERE id = ?`)
+			require.NoError(b, err)
+			_, err = stmt.ExecContext(ctx, hash, feedID)
+			stmt.Close()
+			require.NoError(b, err)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		_, store, feedID, cleanup := benchmarkFeed(b)
+		defer cleanup()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, store.UpdateFeedLastProcessed(ctx, feedID, &hash, nil, nil, nil))
+		}
+	})
+}
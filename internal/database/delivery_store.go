@@ -0,0 +1,231 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// searchIndexSQL creates the full-text index of deliveries. It holds its own
+// copy of the indexed columns, keyed by delivery ID, and is written by
+// DeliveryStore rather than triggers: triggers on deliveries would break every
+// insert and delete in builds without FTS5.
+const searchIndexSQL = `CREATE VIRTUAL TABLE deliveries_fts USING fts5(title, text, author, feed_title)`
+
+// syncSearchIndexSQL drops the index entries of deleted deliveries and indexes
+// the deliveries recorded since the index was last written, e.g. by a build
+// without FTS5. Delivery IDs are never reused.
+const syncSearchIndexSQL = `
+DELETE FROM deliveries_fts WHERE rowid NOT IN (SELECT id FROM deliveries);
+INSERT INTO deliveries_fts (rowid, title, text, author, feed_title)
+	SELECT id, title, text, author, feed_title FROM deliveries
+	WHERE id > (SELECT COALESCE(MAX(rowid), 0) FROM deliveries_fts);`
+
+// Delivery is an item the bot delivered.
+type Delivery struct {
+	ID          int64
+	FeedID      int64
+	FeedTitle   string // The feed's title when the item was delivered
+	FeedURL     string // The feed's current URL
+	ChatID      string // The feed's Telegram chat; empty for feeds without one
+	GUID        string
+	Title       string
+	Link        string
+	Author      string
+	Text        string // Plain text of the formatted item
+	PublishedAt *time.Time
+	DeliveredAt time.Time
+}
+
+// DeliveryFilter selects deliveries. Zero fields match everything.
+type DeliveryFilter struct {
+	FeedIDs []int64
+	ChatID  string
+	Since   time.Time // Only deliveries from then on
+	Limit   int       // Most recent (or best matching) deliveries returned; 0 returns all
+}
+
+// where returns the SQL conditions selecting the filter's deliveries, aliased
+// d, and their arguments.
+func (f DeliveryFilter) where() ([]string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if len(f.FeedIDs) > 0 {
+		conds = append(conds, "d.feed_id IN (?"+strings.Repeat(", ?", len(f.FeedIDs)-1)+")")
+		for _, id := range f.FeedIDs {
+			args = append(args, id)
+		}
+	}
+	if f.ChatID != "" {
+		conds = append(conds, "d.chat_id = ?")
+		args = append(args, f.ChatID)
+	}
+	if !f.Since.IsZero() {
+		conds = append(conds, "d.delivered_at >= ?")
+		args = append(args, f.Since.UTC())
+	}
+	return conds, args
+}
+
+// DeliveryStore records delivered items and searches them.
+type DeliveryStore struct {
+	db *DB
+
+	searchOnce sync.Once
+	fullText   bool // Whether deliveries_fts is available; SQLite needs FTS5 (build tag sqlite_fts5)
+}
+
+// NewDeliveryStore creates a new DeliveryStore.
+func NewDeliveryStore(db *DB) *DeliveryStore {
+	return &DeliveryStore{db: db}
+}
+
+// RecordDelivery stores a delivered item and returns its ID.
+func (s *DeliveryStore) RecordDelivery(ctx context.Context, d *Delivery) (int64, error) {
+	stmt, err := s.db.PrepareCached(ctx, `
+		INSERT INTO deliveries (feed_id, feed_title, chat_id, guid, title, link, author, text, published_at, delivered_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("RecordDelivery prepare: %w", err)
+	}
+
+	var published sql.NullTime
+	if d.PublishedAt != nil {
+		published = sql.NullTime{Time: d.PublishedAt.UTC(), Valid: true}
+	}
+	deliveredAt := d.DeliveredAt
+	if deliveredAt.IsZero() {
+		deliveredAt = time.Now()
+	}
+	res, err := stmt.ExecContext(ctx, d.FeedID, d.FeedTitle, d.ChatID, d.GUID, d.Title, d.Link, d.Author, d.Text, published, deliveredAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("RecordDelivery exec: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("RecordDelivery last insert ID: %w", err)
+	}
+	if s.searchIndex(ctx) {
+		// The delivery is stored; an unindexed one is picked up by the next sync
+		if _, err := s.db.ExecContext(ctx, `INSERT INTO deliveries_fts (rowid, title, text, author, feed_title) VALUES (?, ?, ?, ?, ?)`,
+			id, d.Title, d.Text, d.Author, d.FeedTitle); err != nil {
+			log.Warn().Err(err).Int64("delivery_id", id).Msg("Failed to index delivery for search")
+		}
+	}
+	return id, nil
+}
+
+// ListDeliveries returns the deliveries matching filter, newest first.
+func (s *DeliveryStore) ListDeliveries(ctx context.Context, filter DeliveryFilter) ([]*Delivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM deliveries d LEFT JOIN feeds f ON f.id = d.feed_id`
+	conds, args := filter.where()
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY d.delivered_at DESC, d.id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+	return s.queryDeliveries(ctx, "ListDeliveries", query, args...)
+}
+
+// SearchDeliveries returns the deliveries matching filter whose title, text,
+// author or feed title match query. With full-text search, query is an FTS5
+// query (words, "phrases", prefix*, OR, NOT) and the best matches come first;
+// without it, deliveries containing every word of query are returned newest
+// first.
+func (s *DeliveryStore) SearchDeliveries(ctx context.Context, query string, filter DeliveryFilter) ([]*Delivery, error) {
+	conds, args := filter.where()
+	var sqlQuery string
+	if s.searchIndex(ctx) {
+		sqlQuery = `
+			SELECT ` + deliveryColumns + `
+			FROM deliveries_fts JOIN deliveries d ON d.id = deliveries_fts.rowid LEFT JOIN feeds f ON f.id = d.feed_id`
+		conds = append([]string{"deliveries_fts MATCH ?"}, conds...)
+		args = append([]interface{}{query}, args...)
+		sqlQuery += " WHERE " + strings.Join(conds, " AND ") + " ORDER BY deliveries_fts.rank"
+	} else {
+		sqlQuery = `
+			SELECT ` + deliveryColumns + `
+			FROM deliveries d LEFT JOIN feeds f ON f.id = d.feed_id`
+		for _, word := range strings.Fields(query) {
+			conds = append(conds, "(d.title || ' ' || d.text || ' ' || d.author || ' ' || d.feed_title) LIKE ? ESCAPE '\\'")
+			args = append(args, "%"+likeEscaper.Replace(word)+"%")
+		}
+		if len(conds) > 0 {
+			sqlQuery += " WHERE " + strings.Join(conds, " AND ")
+		}
+		sqlQuery += " ORDER BY d.delivered_at DESC, d.id DESC"
+	}
+	if filter.Limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+	deliveries, err := s.queryDeliveries(ctx, "SearchDeliveries", sqlQuery, args...)
+	if err != nil && strings.Contains(err.Error(), "fts5: syntax error") {
+		return nil, fmt.Errorf("invalid search query %q: quote words with special characters", query)
+	}
+	return deliveries, err
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// searchIndex creates or brings up to date the full-text index on first use
+// and reports whether it is available.
+func (s *DeliveryStore) searchIndex(ctx context.Context) bool {
+	s.searchOnce.Do(func() {
+		var exists int
+		err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'deliveries_fts'`).Scan(&exists)
+		if err == nil && exists == 0 {
+			_, err = s.db.ExecContext(ctx, searchIndexSQL)
+		}
+		if err == nil {
+			_, err = s.db.ExecContext(ctx, syncSearchIndexSQL)
+		}
+		if err != nil {
+			log.Debug().Err(err).Msg("Full-text search unavailable, searching deliveries with LIKE")
+			return
+		}
+		s.fullText = true
+	})
+	return s.fullText
+}
+
+// deliveryColumns are the columns queryDeliveries scans, from deliveries d
+// joined with feeds f.
+const deliveryColumns = `d.id, d.feed_id, d.feed_title, COALESCE(f.url, ''), d.chat_id, d.guid, d.title, d.link, d.author, d.text, d.published_at, d.delivered_at`
+
+// queryDeliveries runs a query selecting the columns ListDeliveries does.
+func (s *DeliveryStore) queryDeliveries(ctx context.Context, caller, query string, args ...interface{}) ([]*Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s query: %w", caller, err)
+	}
+	defer rows.Close()
+
+	var deliveries []*Delivery
+	for rows.Next() {
+		d := &Delivery{}
+		var published sql.NullTime
+		if err := rows.Scan(&d.ID, &d.FeedID, &d.FeedTitle, &d.FeedURL, &d.ChatID, &d.GUID, &d.Title, &d.Link, &d.Author, &d.Text, &published, &d.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("%s scan: %w", caller, err)
+		}
+		if published.Valid {
+			d.PublishedAt = &published.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s rows error: %w", caller, err)
+	}
+	return deliveries, nil
+}
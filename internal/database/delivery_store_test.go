@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryStore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	feeds := NewFeedStore(db)
+	newsID, err := feeds.CreateFeed(ctx, &Feed{URL: "https://example.com/news.xml", FrequencySeconds: 300, TelegramChatID: "-100"})
+	require.NoError(t, err)
+	blogID, err := feeds.CreateFeed(ctx, &Feed{URL: "https://example.com/blog.xml", FrequencySeconds: 300, TelegramChatID: "@blog"})
+	require.NoError(t, err)
+
+	store := NewDeliveryStore(db)
+	now := time.Now().Truncate(time.Second)
+	published := now.Add(-time.Hour)
+	_, err = store.RecordDelivery(ctx, &Delivery{FeedID: newsID, FeedTitle: "News", ChatID: "-100", GUID: "n1", Title: "First", Link: "https://example.com/n1", Text: "first item", PublishedAt: &published, DeliveredAt: now.Add(-time.Minute)})
+	require.NoError(t, err)
+	_, err = store.RecordDelivery(ctx, &Delivery{FeedID: blogID, FeedTitle: "Blog", ChatID: "@blog", Title: "Post", DeliveredAt: now})
+	require.NoError(t, err)
+	_, err = store.RecordDelivery(ctx, &Delivery{FeedID: newsID, FeedTitle: "News", ChatID: "-100", Title: "Second", DeliveredAt: now.Add(time.Minute)})
+	require.NoError(t, err)
+
+	all, err := store.ListDeliveries(ctx, DeliveryFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, []string{"Second", "Post", "First"}, []string{all[0].Title, all[1].Title, all[2].Title})
+	first := all[2]
+	assert.Equal(t, "https://example.com/news.xml", first.FeedURL)
+	assert.Equal(t, "first item", first.Text)
+	require.NotNil(t, first.PublishedAt)
+	assert.True(t, published.Equal(*first.PublishedAt))
+	assert.Nil(t, all[0].PublishedAt)
+
+	news, err := store.ListDeliveries(ctx, DeliveryFilter{FeedIDs: []int64{newsID}, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, news, 1)
+	assert.Equal(t, "Second", news[0].Title)
+
+	blog, err := store.ListDeliveries(ctx, DeliveryFilter{ChatID: "@blog"})
+	require.NoError(t, err)
+	require.Len(t, blog, 1)
+	assert.Equal(t, blogID, blog[0].FeedID)
+}
+
+func TestSearchDeliveries(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	feedID, err := NewFeedStore(db).CreateFeed(ctx, &Feed{URL: "https://example.com/news.xml", FrequencySeconds: 300, TelegramChatID: "-100"})
+	require.NoError(t, err)
+	store := NewDeliveryStore(db)
+	now := time.Now()
+	for i, d := range []*Delivery{
+		{Title: "Budget vote delayed", Text: "Parliament postponed the vote."},
+		{Title: "Weather", Text: "Rain expected, budget for umbrellas."},
+		{Title: "Sports", Text: "100% effort", Author: "Ann"},
+	} {
+		d.FeedID, d.FeedTitle, d.DeliveredAt = feedID, "News", now.Add(time.Duration(i)*time.Minute)
+		_, err := store.RecordDelivery(ctx, d)
+		require.NoError(t, err)
+	}
+
+	titles := func(query string) []string {
+		t.Helper()
+		found, err := store.SearchDeliveries(ctx, query, DeliveryFilter{})
+		require.NoError(t, err)
+		var titles []string
+		for _, d := range found {
+			titles = append(titles, d.Title)
+		}
+		return titles
+	}
+	assert.ElementsMatch(t, []string{"Budget vote delayed", "Weather"}, titles("budget"))
+	assert.Equal(t, []string{"Budget vote delayed"}, titles("budget vote"))
+	assert.Equal(t, []string{"Sports"}, titles("ann"))
+	assert.Empty(t, titles("election"))
+
+	// Items delivered after the index was created are found too
+	_, err = store.RecordDelivery(ctx, &Delivery{FeedID: feedID, Title: "Budget passed", DeliveredAt: now.Add(time.Hour)})
+	require.NoError(t, err)
+	found, err := store.SearchDeliveries(ctx, "budget", DeliveryFilter{Since: now.Add(30 * time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "Budget passed", found[0].Title)
+}
+
+func TestSearchDeliveriesIndexSync(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	feeds := NewFeedStore(db)
+	feedID, err := feeds.CreateFeed(ctx, &Feed{URL: "https://example.com/news.xml", FrequencySeconds: 300, TelegramChatID: "-100"})
+	require.NoError(t, err)
+	store := NewDeliveryStore(db)
+	_, err = store.RecordDelivery(ctx, &Delivery{FeedID: feedID, Title: "Budget vote delayed"})
+	require.NoError(t, err)
+	_, err = store.SearchDeliveries(ctx, "budget", DeliveryFilter{})
+	require.NoError(t, err)
+
+	// Recorded by a build without full-text search
+	_, err = db.ExecContext(ctx, `INSERT INTO deliveries (feed_id, title, delivered_at) VALUES (?, 'Budget passed', ?)`, feedID, time.Now().UTC())
+	require.NoError(t, err)
+	found, err := NewDeliveryStore(db).SearchDeliveries(ctx, "budget", DeliveryFilter{})
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	// Deliveries deleted without touching the index are no longer found
+	_, err = db.ExecContext(ctx, `DELETE FROM deliveries WHERE feed_id = ?`, feedID)
+	require.NoError(t, err)
+	found, err = NewDeliveryStore(db).SearchDeliveries(ctx, "budget", DeliveryFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// DestinationStore handles the extra destinations of feeds.
+type DestinationStore struct {
+	db *DB
+}
+
+// NewDestinationStore creates a new DestinationStore.
+func NewDestinationStore(db *DB) *DestinationStore {
+	return &DestinationStore{db: db}
+}
+
+// CreateDestination adds a destination to a feed and returns its ID.
+func (s *DestinationStore) CreateDestination(ctx context.Context, d *FeedDestination) (int64, error) {
+	stmt, err := s.db.PrepareContext(ctx, `INSERT INTO feed_destinations (feed_id, type, config) VALUES (?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("CreateDestination prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	config := string(d.Config)
+	if config == "" {
+		config = "{}"
+	}
+	res, err := stmt.ExecContext(ctx, d.FeedID, d.Type, config)
+	if err != nil {
+		return 0, fmt.Errorf("CreateDestination exec: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// ListFeedDestinations returns the destinations of a feed, oldest first.
+func (s *DestinationStore) ListFeedDestinations(ctx context.Context, feedID int64) ([]*FeedDestination, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, feed_id, type, config, created_at FROM feed_destinations WHERE feed_id = ? ORDER BY id`, feedID)
+	if err != nil {
+		return nil, fmt.Errorf("ListFeedDestinations query: %w", err)
+	}
+	defer rows.Close()
+
+	var destinations []*FeedDestination
+	for rows.Next() {
+		d := &FeedDestination{}
+		var config string
+		if err := rows.Scan(&d.ID, &d.FeedID, &d.Type, &config, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ListFeedDestinations scan: %w", err)
+		}
+		d.Config = []byte(config)
+		destinations = append(destinations, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListFeedDestinations rows error: %w", err)
+	}
+	return destinations, nil
+}
+
+// DeleteDestination removes a destination.
+func (s *DestinationStore) DeleteDestination(ctx context.Context, id int64) error {
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM feed_destinations WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("DeleteDestination prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("DeleteDestination exec: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("DeleteDestination: destination %d not found", id)
+	}
+	return nil
+}
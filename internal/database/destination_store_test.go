@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestinationStore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	feedID, err := NewFeedStore(db).CreateFeed(ctx, &Feed{URL: "https://example.com/feed.xml", FrequencySeconds: 300, IsEnabled: true})
+	require.NoError(t, err)
+
+	store := NewDestinationStore(db)
+	id, err := store.CreateDestination(ctx, &FeedDestination{FeedID: feedID, Type: DestinationEmail, Config: json.RawMessage(`{"to":["a@example.com"]}`)})
+	require.NoError(t, err)
+
+	destinations, err := store.ListFeedDestinations(ctx, feedID)
+	require.NoError(t, err)
+	require.Len(t, destinations, 1)
+	assert.Equal(t, id, destinations[0].ID)
+	assert.Equal(t, DestinationEmail, destinations[0].Type)
+	assert.JSONEq(t, `{"to":["a@example.com"]}`, string(destinations[0].Config))
+
+	require.NoError(t, store.DeleteDestination(ctx, id))
+	assert.Error(t, store.DeleteDestination(ctx, id))
+	destinations, err = store.ListFeedDestinations(ctx, feedID)
+	require.NoError(t, err)
+	assert.Empty(t, destinations)
+}
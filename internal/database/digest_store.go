@@ -0,0 +1,199 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DigestStore handles the digest schedules of feeds and the items waiting
+// for their next digest.
+type DigestStore struct {
+	db *DB
+}
+
+// NewDigestStore creates a new DigestStore.
+func NewDigestStore(db *DB) *DigestStore {
+	return &DigestStore{db: db}
+}
+
+// SetFeedDigest puts a feed into digest mode, or changes its schedule. The
+// time of the last digest sent is kept.
+func (s *DigestStore) SetFeedDigest(ctx context.Context, d *FeedDigest) error {
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO feed_digests (feed_id, period, at, weekday) VALUES (?, ?, ?, ?)
+		ON CONFLICT (feed_id) DO UPDATE SET period = excluded.period, at = excluded.at, weekday = excluded.weekday`)
+	if err != nil {
+		return fmt.Errorf("SetFeedDigest prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	var weekday sql.NullString
+	if d.Weekday != "" {
+		weekday = sql.NullString{String: d.Weekday, Valid: true}
+	}
+	if _, err := stmt.ExecContext(ctx, d.FeedID, d.Period, d.At, weekday); err != nil {
+		return fmt.Errorf("SetFeedDigest exec: %w", err)
+	}
+	return nil
+}
+
+const feedDigestColumns = `feed_id, period, at, weekday, last_sent_at, created_at`
+
+func scanFeedDigest(scanner interface{ Scan(...any) error }) (*FeedDigest, error) {
+	d := &FeedDigest{}
+	var weekday sql.NullString
+	var lastSentAt sql.NullTime
+	if err := scanner.Scan(&d.FeedID, &d.Period, &d.At, &weekday, &lastSentAt, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	d.Weekday = weekday.String
+	if lastSentAt.Valid {
+		d.LastSentAt = &lastSentAt.Time
+	}
+	return d, nil
+}
+
+// GetFeedDigest returns the digest schedule of a feed, or nil when it sends
+// its items one by one.
+func (s *DigestStore) GetFeedDigest(ctx context.Context, feedID int64) (*FeedDigest, error) {
+	stmt, err := s.db.PrepareCached(ctx, `SELECT `+feedDigestColumns+` FROM feed_digests WHERE feed_id = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("GetFeedDigest prepare: %w", err)
+	}
+	d, err := scanFeedDigest(stmt.QueryRowContext(ctx, feedID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetFeedDigest scan: %w", err)
+	}
+	return d, nil
+}
+
+// ListFeedDigests returns the digest schedules of all enabled feeds.
+func (s *DigestStore) ListFeedDigests(ctx context.Context) ([]*FeedDigest, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT d.feed_id, d.period, d.at, d.weekday, d.last_sent_at, d.created_at
+		FROM feed_digests d JOIN feeds f ON f.id = d.feed_id
+		WHERE f.is_enabled = 1 ORDER BY d.feed_id`)
+	if err != nil {
+		return nil, fmt.Errorf("ListFeedDigests query: %w", err)
+	}
+	defer rows.Close()
+
+	var digests []*FeedDigest
+	for rows.Next() {
+		d, err := scanFeedDigest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ListFeedDigests scan: %w", err)
+		}
+		digests = append(digests, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListFeedDigests rows error: %w", err)
+	}
+	return digests, nil
+}
+
+// DeleteFeedDigest takes a feed out of digest mode. Items still waiting for
+// a digest are forgotten as processed, so the feed's next run sends them one
+// by one if the feed still lists them.
+func (s *DigestStore) DeleteFeedDigest(ctx context.Context, feedID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteFeedDigest begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM feed_digests WHERE feed_id = ?`, feedID)
+	if err != nil {
+		return fmt.Errorf("DeleteFeedDigest exec: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("DeleteFeedDigest: feed %d has no digest", feedID)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM processed_items WHERE feed_id = ? AND item_guid_hash IN (SELECT item_guid_hash FROM digest_items WHERE feed_id = ?)`, feedID, feedID); err != nil {
+		return fmt.Errorf("DeleteFeedDigest forget processed items: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM digest_items WHERE feed_id = ?`, feedID); err != nil {
+		return fmt.Errorf("DeleteFeedDigest delete items: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("DeleteFeedDigest commit: %w", err)
+	}
+	return nil
+}
+
+// SetDigestSent records the scheduled time of the last digest sent for a feed.
+func (s *DigestStore) SetDigestSent(ctx context.Context, feedID int64, due time.Time) error {
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE feed_digests SET last_sent_at = ? WHERE feed_id = ?`)
+	if err != nil {
+		return fmt.Errorf("SetDigestSent prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, due, feedID); err != nil {
+		return fmt.Errorf("SetDigestSent exec: %w", err)
+	}
+	return nil
+}
+
+// AddDigestItem queues an item for its feed's next digest. Items already
+// queued are left as they are.
+func (s *DigestStore) AddDigestItem(ctx context.Context, item *DigestItem) error {
+	stmt, err := s.db.PrepareCached(ctx, `
+		INSERT OR IGNORE INTO digest_items (feed_id, item_guid_hash, feed_title, item) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("AddDigestItem prepare: %w", err)
+	}
+	if _, err := stmt.ExecContext(ctx, item.FeedID, item.ItemGUIDHash, item.FeedTitle, string(item.Item)); err != nil {
+		return fmt.Errorf("AddDigestItem exec: %w", err)
+	}
+	return nil
+}
+
+// ListDigestItems returns the items waiting for a feed's next digest, in the
+// order they were queued.
+func (s *DigestStore) ListDigestItems(ctx context.Context, feedID int64) ([]*DigestItem, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, feed_id, item_guid_hash, feed_title, item, created_at FROM digest_items WHERE feed_id = ? ORDER BY id`, feedID)
+	if err != nil {
+		return nil, fmt.Errorf("ListDigestItems query: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*DigestItem
+	for rows.Next() {
+		item := &DigestItem{}
+		var feedTitle sql.NullString
+		var data string
+		if err := rows.Scan(&item.ID, &item.FeedID, &item.ItemGUIDHash, &feedTitle, &data, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ListDigestItems scan: %w", err)
+		}
+		item.FeedTitle = feedTitle.String
+		item.Item = []byte(data)
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListDigestItems rows error: %w", err)
+	}
+	return items, nil
+}
+
+// DeleteDigestItems removes the items of a feed queued up to and including
+// the one with ID upTo, once they were sent.
+func (s *DigestStore) DeleteDigestItems(ctx context.Context, feedID, upTo int64) error {
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM digest_items WHERE feed_id = ? AND id <= ?`)
+	if err != nil {
+		return fmt.Errorf("DeleteDigestItems prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, feedID, upTo); err != nil {
+		return fmt.Errorf("DeleteDigestItems exec: %w", err)
+	}
+	return nil
+}
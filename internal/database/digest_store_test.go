@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestStore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	feedStore := NewFeedStore(db)
+	feedID, err := feedStore.CreateFeed(ctx, &Feed{URL: "https://example.com/feed.xml", FrequencySeconds: 300, IsEnabled: true})
+	require.NoError(t, err)
+
+	store := NewDigestStore(db)
+	d, err := store.GetFeedDigest(ctx, feedID)
+	require.NoError(t, err)
+	assert.Nil(t, d)
+
+	require.NoError(t, store.SetFeedDigest(ctx, &FeedDigest{FeedID: feedID, Period: DigestDaily, At: "08:00"}))
+	due := time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, store.SetDigestSent(ctx, feedID, due))
+	require.NoError(t, store.SetFeedDigest(ctx, &FeedDigest{FeedID: feedID, Period: DigestWeekly, At: "09:30", Weekday: "friday"}))
+	d, err = store.GetFeedDigest(ctx, feedID)
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	assert.Equal(t, DigestWeekly, d.Period)
+	assert.Equal(t, "09:30", d.At)
+	assert.Equal(t, "friday", d.Weekday)
+	require.NotNil(t, d.LastSentAt, "changing the schedule keeps the last send")
+	assert.True(t, due.Equal(*d.LastSentAt))
+
+	digests, err := store.ListFeedDigests(ctx)
+	require.NoError(t, err)
+	assert.Len(t, digests, 1)
+	require.NoError(t, feedStore.SetFeedEnabled(ctx, feedID, false))
+	digests, err = store.ListFeedDigests(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, digests, "disabled feeds send no digests")
+
+	for i, hash := range []string{"a", "b", "a", "c"} {
+		require.NoError(t, store.AddDigestItem(ctx, &DigestItem{FeedID: feedID, ItemGUIDHash: hash, FeedTitle: "Example", Item: json.RawMessage(`{"title":"` + hash + `"}`)}), i)
+	}
+	items, err := store.ListDigestItems(ctx, feedID)
+	require.NoError(t, err)
+	require.Len(t, items, 3, "items are queued once")
+	assert.Equal(t, "Example", items[0].FeedTitle)
+	assert.JSONEq(t, `{"title":"a"}`, string(items[0].Item))
+
+	require.NoError(t, store.DeleteDigestItems(ctx, feedID, items[1].ID))
+	items, err = store.ListDigestItems(ctx, feedID)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "c", items[0].ItemGUIDHash)
+
+	// Leaving digest mode makes waiting items new again
+	require.NoError(t, feedStore.AddProcessedItems(ctx, feedID, []string{"a", "c"}))
+	require.NoError(t, store.DeleteFeedDigest(ctx, feedID))
+	assert.Error(t, store.DeleteFeedDigest(ctx, feedID))
+	items, err = store.ListDigestItems(ctx, feedID)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	processed, err := feedStore.ListProcessedItems(ctx, feedID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, processed)
+}
+
+func TestFeedDigestLastDue(t *testing.T) {
+	now := time.Date(2026, 10, 15, 7, 30, 0, 0, time.UTC) // A Thursday
+
+	due, from, err := (&FeedDigest{Period: DigestDaily, At: "08:00"}).LastDue(now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC), due)
+	assert.Equal(t, time.Date(2026, 10, 13, 8, 0, 0, 0, time.UTC), from)
+
+	due, _, err = (&FeedDigest{Period: DigestDaily, At: "07:30"}).LastDue(now)
+	require.NoError(t, err)
+	assert.Equal(t, now, due)
+
+	due, from, err = (&FeedDigest{Period: DigestWeekly, At: "08:00", Weekday: "mon"}).LastDue(now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC), due)
+	assert.Equal(t, time.Date(2026, 10, 5, 8, 0, 0, 0, time.UTC), from)
+
+	due, _, err = (&FeedDigest{Period: DigestWeekly, At: "08:00", Weekday: "thursday"}).LastDue(now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 8, 8, 0, 0, 0, time.UTC), due, "today's send time hasn't come yet")
+
+	for _, d := range []*FeedDigest{
+		{Period: "hourly", At: "08:00"},
+		{Period: DigestDaily, At: "8am"},
+		{Period: DigestWeekly, At: "08:00", Weekday: "someday"},
+	} {
+		_, _, err := d.LastDue(now)
+		assert.Error(t, err, "%+v", d)
+	}
+}
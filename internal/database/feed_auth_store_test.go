@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedStore_Credentials(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	require.NoError(t, InitEncryptionKey("test-key"))
+
+	store := NewFeedStore(db)
+	ctx := context.Background()
+	feedID, err := store.CreateFeed(ctx, &Feed{URL: "https://example.com/private.xml", FrequencySeconds: 300, TelegramChatID: "1", IsEnabled: true})
+	require.NoError(t, err)
+
+	creds, err := store.GetFeedCredentials(ctx, feedID)
+	require.NoError(t, err)
+	assert.Nil(t, creds)
+
+	require.NoError(t, store.SetFeedCredentials(ctx, feedID, &FeedCredentials{Type: FeedAuthBasic, Username: "alice", Secret: "s3cret"}))
+
+	var stored string
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT auth_secret_encrypted FROM feeds WHERE id = ?`, feedID).Scan(&stored))
+	assert.NotContains(t, stored, "s3cret")
+
+	creds, err = store.GetFeedCredentials(ctx, feedID)
+	require.NoError(t, err)
+	assert.Equal(t, &FeedCredentials{Type: FeedAuthBasic, Username: "alice", Secret: "s3cret"}, creds)
+
+	feed, err := store.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	require.NotNil(t, feed.AuthType)
+	assert.Equal(t, FeedAuthBasic, *feed.AuthType)
+
+	apiKey := &FeedCredentials{Type: FeedAuthHeader, Header: "X-Api-Key", Secret: "k3y"}
+	require.NoError(t, store.SetFeedCredentials(ctx, feedID, apiKey))
+	creds, err = store.GetFeedCredentials(ctx, feedID)
+	require.NoError(t, err)
+	assert.Equal(t, apiKey, creds)
+	assert.Error(t, store.SetFeedCredentials(ctx, feedID, &FeedCredentials{Type: FeedAuthHeader, Secret: "k3y"}), "header auth needs a header name")
+
+	require.NoError(t, store.SetFeedCredentials(ctx, feedID, nil))
+	creds, err = store.GetFeedCredentials(ctx, feedID)
+	require.NoError(t, err)
+	assert.Nil(t, creds)
+}
@@ -3,7 +3,10 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time" // Added for UpdateFeedLastProcessed and AddProcessedItem timestamps
 )
 
@@ -32,6 +35,13 @@ func scanFeed(scanner interface{ Scan(...interface{}) error }, feed *Feed) error
 		formatProfileID         sql.NullInt64
 		formatProfileName       sql.NullString
 		formatProfileConfigJSON sql.NullString
+		scrapeConfigJSON        sql.NullString
+		requestHeadersJSON      sql.NullString
+		cookiesJSON             sql.NullString
+		tagsJSON                sql.NullString
+		tlsConfigJSON           sql.NullString
+		proxyTLSConfigJSON      sql.NullString
+		proxyDirectFallback     sql.NullBool
 	)
 
 	// Note: Scanning directly into feed.TelegramBotID (if it's *int64)
@@ -39,11 +49,17 @@ func scanFeed(scanner interface{ Scan(...interface{}) error }, feed *Feed) error
 	// Similarly for feed.UserTitle, feed.LastProcessedItemGUIDHash, feed.LastFetchedAt,
 	// feed.HTTPEtag, feed.HTTPLastModified if they are pointer types.
 	err := scanner.Scan(
-		&feed.ID, &feed.URL, &feed.UserTitle, &feed.FrequencySeconds, &feed.TelegramBotID, &feed.TelegramChatID,
-		&feed.LastProcessedItemGUIDHash, &feed.LastFetchedAt, &feed.IsEnabled,
-		&feed.HTTPEtag, &feed.HTTPLastModified, &feed.CreatedAt, &feed.UpdatedAt,
+		&feed.ID, &feed.URL, &feed.UserTitle, &feed.FrequencySeconds, &feed.TelegramBotID, &feed.BotPoolID, &feed.TelegramChatID, &feed.ResolvedChatID, &feed.ResolvedChatFor,
+		&feed.TelegramThreadID, &feed.AutoCreateTopic, &feed.SourceType, &scrapeConfigJSON,
+		&feed.UserAgent, &requestHeadersJSON, &cookiesJSON, &tagsJSON, &feed.AuthType,
+		&feed.NewestItemAt, &feed.StaleAlertedAt, &feed.StaleAfterSeconds,
+		&feed.FetchTimeoutSeconds, &feed.FetchMaxRetries, &feed.FetchRetryDelaySeconds, &tlsConfigJSON, &feed.UseFlareSolverr, &feed.UpdateHintSeconds,
+		&feed.BackfillLimit, &feed.BackfillMaxAgeSeconds, &feed.ProcessedMaxAgeSeconds, &feed.ProcessedKeep, &feed.IconCheckedAt, &feed.ProxyPoolID, &feed.ProxyDirectFallback, &feed.Debug,
+		&feed.LastProcessedItemGUIDHash, &feed.ProcessedEpoch, &feed.LastFetchedAt, &feed.IsEnabled,
+		&feed.ConsecutiveFailures, &feed.LastError, &feed.LastErrorAt,
+		&feed.HTTPEtag, &feed.HTTPLastModified, &feed.HTTPBodyHash, &feed.CreatedAt, &feed.UpdatedAt,
 		// Joined proxy fields
-		&proxyID, &proxyName, &proxyType, &proxyAddress, &proxyUsername, &proxyPassword, &proxyIsDefaultForRSS, &proxyIsDefaultForTelegram,
+		&proxyID, &proxyName, &proxyType, &proxyAddress, &proxyUsername, &proxyPassword, &proxyIsDefaultForRSS, &proxyIsDefaultForTelegram, &proxyTLSConfigJSON, &proxyDirectFallback,
 		// Joined formatting profile fields
 		&formatProfileID, &formatProfileName, &formatProfileConfigJSON,
 	)
@@ -51,6 +67,37 @@ func scanFeed(scanner interface{ Scan(...interface{}) error }, feed *Feed) error
 		return err
 	}
 
+	feed.ScrapeConfig = nil
+	if scrapeConfigJSON.Valid && scrapeConfigJSON.String != "" {
+		feed.ScrapeConfig = &ScrapeConfig{}
+		if err := json.Unmarshal([]byte(scrapeConfigJSON.String), feed.ScrapeConfig); err != nil {
+			return fmt.Errorf("failed to unmarshal scrape config for feed %d: %w", feed.ID, err)
+		}
+	}
+
+	feed.RequestHeaders = nil
+	if requestHeadersJSON.Valid && requestHeadersJSON.String != "" {
+		if err := json.Unmarshal([]byte(requestHeadersJSON.String), &feed.RequestHeaders); err != nil {
+			return fmt.Errorf("failed to unmarshal request headers for feed %d: %w", feed.ID, err)
+		}
+	}
+	feed.Cookies = nil
+	if cookiesJSON.Valid && cookiesJSON.String != "" {
+		if err := json.Unmarshal([]byte(cookiesJSON.String), &feed.Cookies); err != nil {
+			return fmt.Errorf("failed to unmarshal cookies for feed %d: %w", feed.ID, err)
+		}
+	}
+	feed.Tags = nil
+	if tagsJSON.Valid && tagsJSON.String != "" {
+		if err := json.Unmarshal([]byte(tagsJSON.String), &feed.Tags); err != nil {
+			return fmt.Errorf("failed to unmarshal tags for feed %d: %w", feed.ID, err)
+		}
+	}
+
+	if feed.TLS, err = unmarshalTLSConfig(tlsConfigJSON); err != nil {
+		return fmt.Errorf("failed to unmarshal TLS config for feed %d: %w", feed.ID, err)
+	}
+
 	// Handle feed.ProxyID (*int64)
 	if proxyID.Valid {
 		val := proxyID.Int64
@@ -78,6 +125,12 @@ func scanFeed(scanner interface{ Scan(...interface{}) error }, feed *Feed) error
 		if proxyIsDefaultForTelegram.Valid {
 			feed.Proxy.IsDefaultForTelegram = proxyIsDefaultForTelegram.Bool
 		}
+		if proxyDirectFallback.Valid {
+			feed.Proxy.DirectFallback = &proxyDirectFallback.Bool
+		}
+		if feed.Proxy.TLS, err = unmarshalTLSConfig(proxyTLSConfigJSON); err != nil {
+			return fmt.Errorf("failed to unmarshal TLS config for proxy %d: %w", proxyID.Int64, err)
+		}
 	} else {
 		feed.Proxy = nil // Ensure Proxy struct is nil if no associated proxy
 	}
@@ -106,28 +159,42 @@ func scanFeed(scanner interface{ Scan(...interface{}) error }, feed *Feed) error
 	return nil
 }
 
-// GetFeedByID retrieves a feed by its ID, including related proxy and formatting profile.
-func (s *FeedStore) GetFeedByID(ctx context.Context, id int64) (*Feed, error) {
-	query := `
+// feedSelectQuery selects every feed column plus the joined proxy and formatting
+// profile, in the order expected by scanFeed. Callers append WHERE/ORDER clauses.
+const feedSelectQuery = `
 	SELECT 
-		f.id, f.url, f.user_title, f.frequency_seconds, f.telegram_bot_id, f.telegram_chat_id,
-		f.last_processed_item_guid_hash, f.last_fetched_at, f.is_enabled,
-		f.http_etag, f.http_last_modified, f.created_at, f.updated_at,
+		f.id, f.url, f.user_title, f.frequency_seconds, f.telegram_bot_id, f.bot_pool_id, f.telegram_chat_id, f.resolved_chat_id, f.resolved_chat_for,
+		f.telegram_thread_id, f.auto_create_topic, f.source_type, f.scrape_config,
+		f.user_agent, f.request_headers, f.cookies, f.tags, f.auth_type,
+		f.newest_item_at, f.stale_alerted_at, f.stale_after_seconds,
+		f.fetch_timeout_seconds, f.fetch_max_retries, f.fetch_retry_delay_seconds, f.tls_config, f.use_flaresolverr, f.update_hint_seconds,
+		f.backfill_limit, f.backfill_max_age_seconds, f.processed_max_age_seconds, f.processed_keep, f.icon_checked_at, f.proxy_pool_id, f.proxy_direct_fallback, f.debug,
+		f.last_processed_item_guid_hash, f.processed_epoch, f.last_fetched_at, f.is_enabled,
+		f.consecutive_failures, f.last_error, f.last_error_at,
+		f.http_etag, f.http_last_modified, f.http_body_hash, f.created_at, f.updated_at,
 		
 		p.id AS proxy_id_joined, p.name AS proxy_name, p.type AS proxy_type, 
 		p.address AS proxy_address, p.username AS proxy_username, p.password AS proxy_password,
-		p.is_default_for_rss, p.is_default_for_telegram,
+		p.is_default_for_rss, p.is_default_for_telegram, p.tls_config AS proxy_tls_config, p.direct_fallback AS proxy_direct_fallback,
 
 		fp.id AS fp_id_joined, fp.name AS fp_name, fp.template_config AS fp_config_json
 	FROM feeds f
 	LEFT JOIN proxies p ON f.proxy_id = p.id
-	LEFT JOIN formatting_profiles fp ON f.formatting_profile_id = fp.id
+	LEFT JOIN formatting_profiles fp ON f.formatting_profile_id = fp.id`
+
+// GetFeedByID retrieves a feed by its ID, including related proxy and formatting profile.
+func (s *FeedStore) GetFeedByID(ctx context.Context, id int64) (*Feed, error) {
+	query := feedSelectQuery + `
 	WHERE f.id = ?`
 
-	row := s.db.QueryRowContext(ctx, query, id)
+	stmt, err := s.db.PrepareCached(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("GetFeedByID prepare: %w", err)
+	}
+	row := stmt.QueryRowContext(ctx, id)
 	feed := &Feed{} // Feed struct from models.go
 
-	err := scanFeed(row, feed)
+	err = scanFeed(row, feed)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Or a custom ErrNotFound
@@ -139,20 +206,7 @@ func (s *FeedStore) GetFeedByID(ctx context.Context, id int64) (*Feed, error) {
 
 // GetEnabledFeeds retrieves all enabled feeds with their related proxy and formatting profiles.
 func (s *FeedStore) GetEnabledFeeds(ctx context.Context) ([]*Feed, error) {
-	query := `
-	SELECT 
-		f.id, f.url, f.user_title, f.frequency_seconds, f.telegram_bot_id, f.telegram_chat_id,
-		f.last_processed_item_guid_hash, f.last_fetched_at, f.is_enabled,
-		f.http_etag, f.http_last_modified, f.created_at, f.updated_at,
-
-		p.id AS proxy_id_joined, p.name AS proxy_name, p.type AS proxy_type, 
-		p.address AS proxy_address, p.username AS proxy_username, p.password AS proxy_password,
-		p.is_default_for_rss, p.is_default_for_telegram,
-
-		fp.id AS fp_id_joined, fp.name AS fp_name, fp.template_config AS fp_config_json
-	FROM feeds f
-	LEFT JOIN proxies p ON f.proxy_id = p.id
-	LEFT JOIN formatting_profiles fp ON f.formatting_profile_id = fp.id
+	query := feedSelectQuery + `
 	WHERE f.is_enabled = TRUE
 	ORDER BY f.id`
 
@@ -177,43 +231,184 @@ func (s *FeedStore) GetEnabledFeeds(ctx context.Context) ([]*Feed, error) {
 	return feeds, nil
 }
 
+// ListFeeds retrieves all feeds, enabled or not, with their related proxy and formatting profiles.
+func (s *FeedStore) ListFeeds(ctx context.Context) ([]*Feed, error) {
+	return s.FindFeeds(ctx, FeedListFilter{})
+}
+
+// FeedListFilter selects feeds. Zero fields match everything.
+type FeedListFilter struct {
+	Enabled     *bool  // Only enabled (true) or disabled (false) feeds
+	Failing     bool   // Only feeds whose last fetch failed
+	BotID       int64  // Only feeds sending with this bot
+	ChatID      string // Only feeds sending to this chat
+	Tag         string // Only feeds with this tag, compared case insensitively
+	URLContains string // Only feeds whose URL contains this, compared case insensitively
+	Limit       int    // Feeds returned; 0 returns all
+	Offset      int    // Matching feeds skipped, for paging
+}
+
+// where returns the SQL conditions selecting the filter's feeds, aliased f,
+// and their arguments.
+func (f FeedListFilter) where() ([]string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if f.Enabled != nil {
+		conds = append(conds, "f.is_enabled = ?")
+		args = append(args, *f.Enabled)
+	}
+	if f.Failing {
+		conds = append(conds, "f.consecutive_failures > 0")
+	}
+	if f.BotID != 0 {
+		conds = append(conds, "f.telegram_bot_id = ?")
+		args = append(args, f.BotID)
+	}
+	if f.ChatID != "" {
+		conds = append(conds, "f.telegram_chat_id = ?")
+		args = append(args, f.ChatID)
+	}
+	if f.Tag != "" {
+		conds = append(conds, "EXISTS (SELECT 1 FROM json_each(f.tags) WHERE lower(json_each.value) = lower(?))")
+		args = append(args, strings.TrimSpace(f.Tag))
+	}
+	if f.URLContains != "" {
+		conds = append(conds, "instr(lower(f.url), lower(?)) > 0")
+		args = append(args, f.URLContains)
+	}
+	return conds, args
+}
+
+// FindFeeds retrieves the feeds matching filter by ID, with their related
+// proxy and formatting profiles.
+func (s *FeedStore) FindFeeds(ctx context.Context, filter FeedListFilter) ([]*Feed, error) {
+	query := feedSelectQuery
+	conds, args := filter.where()
+	if len(conds) > 0 {
+		query += `
+	WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += `
+	ORDER BY f.id`
+	if filter.Limit > 0 || filter.Offset > 0 {
+		limit := filter.Limit
+		if limit <= 0 {
+			limit = -1 // SQLite needs a LIMIT for OFFSET; -1 means none
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, filter.Offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("FindFeeds query: %w", err)
+	}
+	defer rows.Close()
+
+	var feeds []*Feed
+	for rows.Next() {
+		feed := &Feed{}
+		if err := scanFeed(rows, feed); err != nil {
+			return nil, fmt.Errorf("FindFeeds scan: %w", err)
+		}
+		feeds = append(feeds, feed)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("FindFeeds rows error: %w", err)
+	}
+	return feeds, nil
+}
+
 // CreateFeed adds a new feed to the database.
 func (s *FeedStore) CreateFeed(ctx context.Context, feed *Feed) (int64, error) {
 	stmt, err := s.db.PrepareContext(ctx, `
-		INSERT INTO feeds (url, user_title, frequency_seconds, telegram_bot_id, telegram_chat_id, 
-		                   proxy_id, formatting_profile_id, is_enabled)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+		INSERT INTO feeds (url, user_title, frequency_seconds, telegram_bot_id, bot_pool_id, telegram_chat_id, 
+		                   telegram_thread_id, auto_create_topic, source_type, scrape_config, user_agent, request_headers,
+		                   cookies, tags, stale_after_seconds, fetch_timeout_seconds, fetch_max_retries, fetch_retry_delay_seconds,
+		                   tls_config, use_flaresolverr, backfill_limit, backfill_max_age_seconds,
+		                   proxy_id, proxy_pool_id, proxy_direct_fallback, formatting_profile_id, is_enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return 0, fmt.Errorf("CreateFeed prepare: %w", err)
 	}
 	defer stmt.Close()
 
+	scrapeConfig, err := marshalScrapeConfig(feed.ScrapeConfig)
+	if err != nil {
+		return 0, fmt.Errorf("CreateFeed: %w", err)
+	}
+	requestHeaders, err := marshalStringMap(feed.RequestHeaders)
+	if err != nil {
+		return 0, fmt.Errorf("CreateFeed request headers: %w", err)
+	}
+	cookies, err := marshalStringMap(feed.Cookies)
+	if err != nil {
+		return 0, fmt.Errorf("CreateFeed cookies: %w", err)
+	}
+	tags, err := marshalTags(feed.Tags)
+	if err != nil {
+		return 0, fmt.Errorf("CreateFeed tags: %w", err)
+	}
+	tlsConfig, err := marshalTLSConfig(feed.TLS)
+	if err != nil {
+		return 0, fmt.Errorf("CreateFeed: %w", err)
+	}
 	res, err := stmt.ExecContext(ctx, feed.URL, feed.UserTitle, feed.FrequencySeconds,
-		feed.TelegramBotID, feed.TelegramChatID, feed.ProxyID, feed.FormattingProfileID, feed.IsEnabled)
+		feed.TelegramBotID, feed.BotPoolID, feed.TelegramChatID, feed.TelegramThreadID, feed.AutoCreateTopic,
+		feedSourceType(feed), scrapeConfig, feed.UserAgent, requestHeaders, cookies, tags, feed.StaleAfterSeconds,
+		feed.FetchTimeoutSeconds, feed.FetchMaxRetries, feed.FetchRetryDelaySeconds, tlsConfig, feed.UseFlareSolverr, feed.BackfillLimit, feed.BackfillMaxAgeSeconds, feed.ProxyID, feed.ProxyPoolID, feed.ProxyDirectFallback, feed.FormattingProfileID, feed.IsEnabled)
 	if err != nil {
 		return 0, fmt.Errorf("CreateFeed exec: %w", err)
 	}
 	return res.LastInsertId()
 }
 
-// UpdateFeed updates an existing feed.
+// UpdateFeed updates an existing feed. The numeric chat ID cached for an
+// @username chat is cleared when the feed now points at another chat.
 // Note: This is a basic update; a real one might use optional fields or a map for partial updates.
 func (s *FeedStore) UpdateFeed(ctx context.Context, feed *Feed) error {
 	stmt, err := s.db.PrepareContext(ctx, `
 		UPDATE feeds 
-		SET url = ?, user_title = ?, frequency_seconds = ?, telegram_bot_id = ?, telegram_chat_id = ?,
-		    proxy_id = ?, formatting_profile_id = ?, is_enabled = ?,
-		    last_processed_item_guid_hash = ?, last_fetched_at = ?, http_etag = ?, http_last_modified = ?
+		SET url = ?, user_title = ?, frequency_seconds = ?, telegram_bot_id = ?, bot_pool_id = ?, telegram_chat_id = ?,
+		    telegram_thread_id = ?, auto_create_topic = ?, source_type = ?, scrape_config = ?,
+		    user_agent = ?, request_headers = ?, cookies = ?, tags = ?, stale_after_seconds = ?,
+		    fetch_timeout_seconds = ?, fetch_max_retries = ?, fetch_retry_delay_seconds = ?, tls_config = ?, use_flaresolverr = ?, proxy_id = ?, proxy_pool_id = ?, proxy_direct_fallback = ?, formatting_profile_id = ?, is_enabled = ?,
+		    last_processed_item_guid_hash = ?, last_fetched_at = ?, http_etag = ?, http_last_modified = ?, http_body_hash = ?,
+		    resolved_chat_id = CASE WHEN resolved_chat_for = ? THEN resolved_chat_id END,
+		    resolved_chat_for = CASE WHEN resolved_chat_for = ? THEN resolved_chat_for END
 		WHERE id = ?`)
 	if err != nil {
 		return fmt.Errorf("UpdateFeed prepare: %w", err)
 	}
 	defer stmt.Close()
 
+	scrapeConfig, err := marshalScrapeConfig(feed.ScrapeConfig)
+	if err != nil {
+		return fmt.Errorf("UpdateFeed: %w", err)
+	}
+	requestHeaders, err := marshalStringMap(feed.RequestHeaders)
+	if err != nil {
+		return fmt.Errorf("UpdateFeed request headers: %w", err)
+	}
+	cookies, err := marshalStringMap(feed.Cookies)
+	if err != nil {
+		return fmt.Errorf("UpdateFeed cookies: %w", err)
+	}
+	tags, err := marshalTags(feed.Tags)
+	if err != nil {
+		return fmt.Errorf("UpdateFeed tags: %w", err)
+	}
+	tlsConfig, err := marshalTLSConfig(feed.TLS)
+	if err != nil {
+		return fmt.Errorf("UpdateFeed: %w", err)
+	}
 	_, err = stmt.ExecContext(ctx,
-		feed.URL, feed.UserTitle, feed.FrequencySeconds, feed.TelegramBotID, feed.TelegramChatID,
-		feed.ProxyID, feed.FormattingProfileID, feed.IsEnabled,
-		feed.LastProcessedItemGUIDHash, feed.LastFetchedAt, feed.HTTPEtag, feed.HTTPLastModified,
+		feed.URL, feed.UserTitle, feed.FrequencySeconds, feed.TelegramBotID, feed.BotPoolID, feed.TelegramChatID,
+		feed.TelegramThreadID, feed.AutoCreateTopic, feedSourceType(feed), scrapeConfig,
+		feed.UserAgent, requestHeaders, cookies, tags, feed.StaleAfterSeconds,
+		feed.FetchTimeoutSeconds, feed.FetchMaxRetries, feed.FetchRetryDelaySeconds, tlsConfig, feed.UseFlareSolverr, feed.ProxyID, feed.ProxyPoolID, feed.ProxyDirectFallback, feed.FormattingProfileID, feed.IsEnabled,
+		feed.LastProcessedItemGUIDHash, feed.LastFetchedAt, feed.HTTPEtag, feed.HTTPLastModified, feed.HTTPBodyHash,
+		feed.TelegramChatID, feed.TelegramChatID, // A chat ID cached for another chat is dropped
 		feed.ID)
 	if err != nil {
 		return fmt.Errorf("UpdateFeed exec for feed ID %d: %w", feed.ID, err)
@@ -221,6 +416,182 @@ func (s *FeedStore) UpdateFeed(ctx context.Context, feed *Feed) error {
 	return nil
 }
 
+// feedPatchColumns are the columns PatchFeed may change.
+var feedPatchColumns = map[string]bool{
+	"url": true, "user_title": true, "frequency_seconds": true, "telegram_bot_id": true, "bot_pool_id": true,
+	"telegram_chat_id": true, "telegram_thread_id": true, "auto_create_topic": true, "source_type": true,
+	"scrape_config": true, "user_agent": true, "request_headers": true, "cookies": true, "tags": true,
+	"stale_after_seconds": true, "fetch_timeout_seconds": true, "fetch_max_retries": true,
+	"processed_max_age_seconds": true, "processed_keep": true,
+	"fetch_retry_delay_seconds": true, "tls_config": true, "use_flaresolverr": true, "proxy_id": true,
+	"proxy_pool_id": true, "proxy_direct_fallback": true, "formatting_profile_id": true, "is_enabled": true,
+}
+
+// PatchFeed changes only the given columns of a feed, leaving the others as
+// they are; enabling a feed also clears its failure count, and changing its
+// chat clears the cached numeric chat ID and, for auto-topic feeds, the topic. Values are typed as in Feed: scrape_config takes a *ScrapeConfig,
+// request_headers and cookies a map[string]string, tags a []string and
+// tls_config a *TLSConfig, stored as JSON. A nil value clears a column.
+func (s *FeedStore) PatchFeed(ctx context.Context, feedID int64, changes map[string]any) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	columns := make([]string, 0, len(changes))
+	for column := range changes {
+		if !feedPatchColumns[column] {
+			return fmt.Errorf("PatchFeed: column %q can't be changed", column)
+		}
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	sets := make([]string, len(columns))
+	args := make([]any, 0, len(columns)+1)
+	for i, column := range columns {
+		sets[i] = column + " = ?"
+		value, err := feedPatchValue(column, changes[column])
+		if err != nil {
+			return fmt.Errorf("PatchFeed %s: %w", column, err)
+		}
+		args = append(args, value)
+	}
+	if enabled, _ := changes["is_enabled"].(bool); enabled {
+		sets = append(sets, "consecutive_failures = 0") // As in SetFeedEnabled
+	}
+	if chatID, ok := changes["telegram_chat_id"]; ok {
+		sets = append(sets, "resolved_chat_id = NULL", "resolved_chat_for = NULL")
+		if _, ok := changes["telegram_thread_id"]; !ok {
+			// A topic created in the old chat means nothing in the new one;
+			// the worker creates another on the next delivery.
+			autoTopic := "auto_create_topic"
+			if auto, ok := changes["auto_create_topic"]; ok {
+				autoTopic = "?"
+				args = append(args, auto)
+			}
+			sets = append(sets, "telegram_thread_id = CASE WHEN "+autoTopic+" AND telegram_chat_id IS NOT ? THEN NULL ELSE telegram_thread_id END")
+			args = append(args, chatID)
+		}
+	}
+	args = append(args, feedID)
+
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE feeds SET `+strings.Join(sets, ", ")+` WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("PatchFeed prepare: %w", err)
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("PatchFeed exec for feed ID %d: %w", feedID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("PatchFeed: feed %d not found", feedID)
+	}
+	return nil
+}
+
+// feedPatchValue converts a PatchFeed value to what is stored in column.
+func feedPatchValue(column string, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch column {
+	case "scrape_config":
+		cfg, ok := value.(*ScrapeConfig)
+		if !ok {
+			return nil, fmt.Errorf("expected *ScrapeConfig, got %T", value)
+		}
+		return marshalScrapeConfig(cfg)
+	case "request_headers", "cookies":
+		m, ok := value.(map[string]string)
+		if !ok {
+			return nil, fmt.Errorf("expected map[string]string, got %T", value)
+		}
+		return marshalStringMap(m)
+	case "tags":
+		tags, ok := value.([]string)
+		if !ok {
+			return nil, fmt.Errorf("expected []string, got %T", value)
+		}
+		return marshalTags(tags)
+	case "tls_config":
+		cfg, ok := value.(*TLSConfig)
+		if !ok {
+			return nil, fmt.Errorf("expected *TLSConfig, got %T", value)
+		}
+		return marshalTLSConfig(cfg)
+	}
+	return value, nil
+}
+
+// feedSourceType returns the feed's source type, defaulting to FeedSourceRSS.
+func feedSourceType(feed *Feed) string {
+	if feed.SourceType == "" {
+		return FeedSourceRSS
+	}
+	return feed.SourceType
+}
+
+// marshalScrapeConfig serializes a scrape config for the scrape_config column.
+func marshalScrapeConfig(cfg *ScrapeConfig) (sql.NullString, error) {
+	if cfg == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("marshal scrape config: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// marshalTLSConfig serializes TLS settings for a tls_config column; empty settings are stored as NULL.
+func marshalTLSConfig(cfg *TLSConfig) (sql.NullString, error) {
+	if cfg.IsZero() {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("marshal TLS config: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// unmarshalTLSConfig is the inverse of marshalTLSConfig.
+func unmarshalTLSConfig(raw sql.NullString) (*TLSConfig, error) {
+	if !raw.Valid || raw.String == "" {
+		return nil, nil
+	}
+	cfg := &TLSConfig{}
+	if err := json.Unmarshal([]byte(raw.String), cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// marshalStringMap serializes a map for a JSON text column such as request_headers.
+func marshalStringMap(m map[string]string) (sql.NullString, error) {
+	if len(m) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// marshalTags serializes feed tags for the tags column.
+func marshalTags(tags []string) (sql.NullString, error) {
+	tags = NormalizeTags(tags)
+	if len(tags) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
 // DeleteFeed deletes a feed by its ID.
 func (s *FeedStore) DeleteFeed(ctx context.Context, id int64) error {
 	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM feeds WHERE id = ?`)
@@ -245,7 +616,7 @@ func (s *FeedStore) DeleteFeed(ctx context.Context, id int64) error {
 
 
 // UpdateFeedLastProcessed updates tracking info for a feed after a fetch attempt.
-func (s *FeedStore) UpdateFeedLastProcessed(ctx context.Context, feedID int64, lastItemHash, etag, lastModified *string) error {
+func (s *FeedStore) UpdateFeedLastProcessed(ctx context.Context, feedID int64, lastItemHash, etag, lastModified, bodyHash *string) error {
 	now := time.Now() // Capture current time for last_fetched_at
 
 	// Prepare arguments, handling potential nil pointers from input by converting to sql.NullString
@@ -261,35 +632,205 @@ func (s *FeedStore) UpdateFeedLastProcessed(ctx context.Context, feedID int64, l
 	if lastModified != nil {
 		sqlLastModified = sql.NullString{String: *lastModified, Valid: true}
 	}
+	var sqlBodyHash sql.NullString
+	if bodyHash != nil {
+		sqlBodyHash = sql.NullString{String: *bodyHash, Valid: true}
+	}
 
 
-	stmt, err := s.db.PrepareContext(ctx, `
+	stmt, err := s.db.PrepareCached(ctx, `
 		UPDATE feeds 
-		SET last_processed_item_guid_hash = ?, http_etag = ?, http_last_modified = ?, last_fetched_at = ?
+		SET last_processed_item_guid_hash = ?, http_etag = ?, http_last_modified = ?, http_body_hash = ?, last_fetched_at = ?
 		WHERE id = ?`)
 	if err != nil {
 		return fmt.Errorf("UpdateFeedLastProcessed prepare: %w", err)
 	}
-	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, sqlLastItemHash, sqlEtag, sqlLastModified, now, feedID)
+	_, err = stmt.ExecContext(ctx, sqlLastItemHash, sqlEtag, sqlLastModified, sqlBodyHash, now, feedID)
 	if err != nil {
 		return fmt.Errorf("UpdateFeedLastProcessed exec: %w", err)
 	}
 	return nil
 }
 
+// SetResolvedChatID stores the numeric chat ID resolved from chatFor, a
+// feed's @username telegram_chat_id, so later sends survive username
+// changes. Nothing is stored if the feed has since moved to another chat.
+func (s *FeedStore) SetResolvedChatID(ctx context.Context, feedID int64, chatFor string, chatID int64) error {
+	stmt, err := s.db.PrepareCached(ctx, `UPDATE feeds SET resolved_chat_id = ?, resolved_chat_for = ? WHERE id = ? AND telegram_chat_id = ?`)
+	if err != nil {
+		return fmt.Errorf("SetResolvedChatID prepare: %w", err)
+	}
+
+	if _, err := stmt.ExecContext(ctx, chatID, chatFor, feedID, chatFor); err != nil {
+		return fmt.Errorf("SetResolvedChatID exec for feed ID %d: %w", feedID, err)
+	}
+	return nil
+}
+
+// SetNewestItemAt records the publication time of the newest item seen for a
+// feed and clears any stale alert, since the feed is evidently publishing.
+func (s *FeedStore) SetNewestItemAt(ctx context.Context, feedID int64, newestItemAt time.Time) error {
+	stmt, err := s.db.PrepareCached(ctx, `UPDATE feeds SET newest_item_at = ?, stale_alerted_at = NULL WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("SetNewestItemAt prepare: %w", err)
+	}
+
+	if _, err := stmt.ExecContext(ctx, newestItemAt, feedID); err != nil {
+		return fmt.Errorf("SetNewestItemAt exec for feed ID %d: %w", feedID, err)
+	}
+	return nil
+}
+
+// SetUpdateHint stores the update interval a feed declares, or clears it when
+// seconds is nil.
+func (s *FeedStore) SetUpdateHint(ctx context.Context, feedID int64, seconds *int) error {
+	stmt, err := s.db.PrepareCached(ctx, `UPDATE feeds SET update_hint_seconds = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("SetUpdateHint prepare: %w", err)
+	}
+	if _, err := stmt.ExecContext(ctx, seconds, feedID); err != nil {
+		return fmt.Errorf("SetUpdateHint exec for feed ID %d: %w", feedID, err)
+	}
+	return nil
+}
+
+// ClearBackfill marks a feed's archive backfill as done.
+func (s *FeedStore) ClearBackfill(ctx context.Context, feedID int64) error {
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE feeds SET backfill_limit = NULL, backfill_max_age_seconds = NULL WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("ClearBackfill prepare: %w", err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.ExecContext(ctx, feedID); err != nil {
+		return fmt.Errorf("ClearBackfill exec for feed ID %d: %w", feedID, err)
+	}
+	return nil
+}
+
+// SetFeedURL changes a feed's URL, e.g. after it was permanently redirected.
+// It fails if another feed already uses url.
+func (s *FeedStore) SetFeedURL(ctx context.Context, feedID int64, url string) error {
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE feeds SET url = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("SetFeedURL prepare: %w", err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.ExecContext(ctx, url, feedID); err != nil {
+		return fmt.Errorf("SetFeedURL exec for feed ID %d: %w", feedID, err)
+	}
+	return nil
+}
+
+// SetFeedDebug turns debug logging of a feed's processing on or off. The
+// worker picks it up on the feed's next run.
+func (s *FeedStore) SetFeedDebug(ctx context.Context, feedID int64, debug bool) error {
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE feeds SET debug = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("SetFeedDebug prepare: %w", err)
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, debug, feedID)
+	if err != nil {
+		return fmt.Errorf("SetFeedDebug exec for feed ID %d: %w", feedID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("SetFeedDebug: feed %d not found", feedID)
+	}
+	return nil
+}
+
+// SetFeedEnabled enables or disables a feed. Disabled feeds are skipped by
+// the worker and not scheduled on the next start. Enabling a feed clears its
+// failure count, so one disabled for failing gets a fresh start.
+func (s *FeedStore) SetFeedEnabled(ctx context.Context, feedID int64, enabled bool) error {
+	stmt, err := s.db.PrepareContext(ctx, `
+		UPDATE feeds SET is_enabled = ?1, consecutive_failures = CASE WHEN ?1 THEN 0 ELSE consecutive_failures END
+		WHERE id = ?2`)
+	if err != nil {
+		return fmt.Errorf("SetFeedEnabled prepare: %w", err)
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, enabled, feedID)
+	if err != nil {
+		return fmt.Errorf("SetFeedEnabled exec for feed ID %d: %w", feedID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("SetFeedEnabled: feed %d not found", feedID)
+	}
+	return nil
+}
+
+// RecordFetchFailure counts a failed fetch of a feed, storing its error, and
+// returns the feed's consecutive failures including this one.
+func (s *FeedStore) RecordFetchFailure(ctx context.Context, feedID int64, fetchErr string, at time.Time) (int, error) {
+	stmt, err := s.db.PrepareCached(ctx, `
+		UPDATE feeds SET consecutive_failures = consecutive_failures + 1, last_error = ?, last_error_at = ?
+		WHERE id = ? RETURNING consecutive_failures`)
+	if err != nil {
+		return 0, fmt.Errorf("RecordFetchFailure prepare: %w", err)
+	}
+	var failures int
+	if err := stmt.QueryRowContext(ctx, fetchErr, at.UTC(), feedID).Scan(&failures); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("RecordFetchFailure: feed %d not found", feedID)
+		}
+		return 0, fmt.Errorf("RecordFetchFailure scan for feed ID %d: %w", feedID, err)
+	}
+	return failures, nil
+}
+
+// ResetFetchFailures clears a feed's failure count after a successful fetch.
+// The last error is kept for reference.
+func (s *FeedStore) ResetFetchFailures(ctx context.Context, feedID int64) error {
+	stmt, err := s.db.PrepareCached(ctx, `UPDATE feeds SET consecutive_failures = 0 WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("ResetFetchFailures prepare: %w", err)
+	}
+	if _, err := stmt.ExecContext(ctx, feedID); err != nil {
+		return fmt.Errorf("ResetFetchFailures exec for feed ID %d: %w", feedID, err)
+	}
+	return nil
+}
+
+// MarkStaleAlerted records that an admin was alerted about a stale feed.
+func (s *FeedStore) MarkStaleAlerted(ctx context.Context, feedID int64, alertedAt time.Time) error {
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE feeds SET stale_alerted_at = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("MarkStaleAlerted prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, alertedAt, feedID); err != nil {
+		return fmt.Errorf("MarkStaleAlerted exec for feed ID %d: %w", feedID, err)
+	}
+	return nil
+}
+
+// SetTelegramThreadID stores the forum topic a feed posts into.
+func (s *FeedStore) SetTelegramThreadID(ctx context.Context, feedID int64, threadID int) error {
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE feeds SET telegram_thread_id = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("SetTelegramThreadID prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, threadID, feedID); err != nil {
+		return fmt.Errorf("SetTelegramThreadID exec for feed ID %d: %w", feedID, err)
+	}
+	return nil
+}
+
 // AddProcessedItem records an item as processed.
 func (s *FeedStore) AddProcessedItem(ctx context.Context, feedID int64, itemGUIDHash string) error {
 	// Using INSERT OR IGNORE to prevent errors if the item was already processed
 	// (e.g., due to a retry or race condition, though a robust system would try to avoid this).
 	// The processed_at timestamp will only be set on the initial successful insert.
-	stmt, err := s.db.PrepareContext(ctx, `
+	stmt, err := s.db.PrepareCached(ctx, `
 		INSERT OR IGNORE INTO processed_items (feed_id, item_guid_hash, processed_at) VALUES (?, ?, ?)`)
 	if err != nil {
 		return fmt.Errorf("AddProcessedItem prepare: %w", err)
 	}
-	defer stmt.Close()
 
 	now := time.Now()
 	_, err = stmt.ExecContext(ctx, feedID, itemGUIDHash, now)
@@ -302,8 +843,11 @@ func (s *FeedStore) AddProcessedItem(ctx context.Context, feedID int64, itemGUID
 // IsItemProcessed checks if an item has already been processed for a feed.
 func (s *FeedStore) IsItemProcessed(ctx context.Context, feedID int64, itemGUIDHash string) (bool, error) {
 	var exists int
-	query := `SELECT EXISTS(SELECT 1 FROM processed_items WHERE feed_id = ? AND item_guid_hash = ? LIMIT 1)`
-	err := s.db.QueryRowContext(ctx, query, feedID, itemGUIDHash).Scan(&exists)
+	stmt, err := s.db.PrepareCached(ctx, `SELECT EXISTS(SELECT 1 FROM processed_items WHERE feed_id = ? AND item_guid_hash = ? LIMIT 1)`)
+	if err != nil {
+		return false, fmt.Errorf("IsItemProcessed prepare: %w", err)
+	}
+	err = stmt.QueryRowContext(ctx, feedID, itemGUIDHash).Scan(&exists)
 	if err != nil {
 		// If QueryRowContext returns sql.ErrNoRows, Scan will also return it.
 		// However, SELECT EXISTS should always return one row (with 0 or 1).
@@ -311,4 +855,244 @@ func (s *FeedStore) IsItemProcessed(ctx context.Context, feedID int64, itemGUIDH
 		return false, fmt.Errorf("IsItemProcessed query: %w", err)
 	}
 	return exists == 1, nil
-}
\ No newline at end of file
+}
+
+// processedLookupChunk is how many hashes FilterUnprocessedHashes checks per
+// query, well below SQLite's limit on bound parameters.
+const processedLookupChunk = 500
+
+// FilterUnprocessedHashes returns the hashes, in their given order, of items
+// not yet processed for a feed. It needs one query per 500 hashes instead of
+// one per item.
+func (s *FeedStore) FilterUnprocessedHashes(ctx context.Context, feedID int64, hashes []string) ([]string, error) {
+	processed := make(map[string]bool, len(hashes))
+	for start := 0; start < len(hashes); start += processedLookupChunk {
+		chunk := hashes[start:min(start+processedLookupChunk, len(hashes))]
+		args := make([]interface{}, 0, len(chunk)+1)
+		args = append(args, feedID)
+		for _, hash := range chunk {
+			args = append(args, hash)
+		}
+		query := `SELECT item_guid_hash FROM processed_items WHERE feed_id = ? AND item_guid_hash IN (?` + strings.Repeat(",?", len(chunk)-1) + `)`
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("FilterUnprocessedHashes query: %w", err)
+		}
+		for rows.Next() {
+			var hash string
+			if err := rows.Scan(&hash); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("FilterUnprocessedHashes scan: %w", err)
+			}
+			processed[hash] = true
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("FilterUnprocessedHashes rows error: %w", err)
+		}
+	}
+
+	var unprocessed []string
+	for _, hash := range hashes {
+		if !processed[hash] {
+			unprocessed = append(unprocessed, hash)
+		}
+	}
+	return unprocessed, nil
+}
+
+// ListProcessedItems returns the hashes of every item processed for a feed,
+// oldest first.
+func (s *FeedStore) ListProcessedItems(ctx context.Context, feedID int64) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT item_guid_hash FROM processed_items WHERE feed_id = ? ORDER BY id`, feedID)
+	if err != nil {
+		return nil, fmt.Errorf("ListProcessedItems query: %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("ListProcessedItems scan: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListProcessedItems rows error: %w", err)
+	}
+	return hashes, nil
+}
+
+// AddProcessedItems marks several items of a feed as processed in one
+// transaction. Items already marked are left as they are.
+func (s *FeedStore) AddProcessedItems(ctx context.Context, feedID int64, itemGUIDHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("AddProcessedItems begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT OR IGNORE INTO processed_items (feed_id, item_guid_hash, processed_at) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("AddProcessedItems prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, hash := range itemGUIDHashes {
+		if _, err := stmt.ExecContext(ctx, feedID, hash, now); err != nil {
+			return fmt.Errorf("AddProcessedItems exec: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("AddProcessedItems commit: %w", err)
+	}
+	return nil
+}
+
+// CountProcessedItems counts items processed since the given time across all
+// feeds; a zero time counts every processed item.
+func (s *FeedStore) CountProcessedItems(ctx context.Context, since time.Time) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM processed_items WHERE processed_at >= ?`
+	if err := s.db.QueryRowContext(ctx, query, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("CountProcessedItems query: %w", err)
+	}
+	return count, nil
+}
+
+// MarkItemsSeen records that the items with the given hashes, if processed,
+// were still in the feed at the given time, so retention keeps them.
+func (s *FeedStore) MarkItemsSeen(ctx context.Context, feedID int64, hashes []string, at time.Time) error {
+	for start := 0; start < len(hashes); start += processedLookupChunk {
+		chunk := hashes[start:min(start+processedLookupChunk, len(hashes))]
+		args := make([]interface{}, 0, len(chunk)+2)
+		args = append(args, at.UTC(), feedID)
+		for _, hash := range chunk {
+			args = append(args, hash)
+		}
+		query := `UPDATE processed_items SET seen_at = ? WHERE feed_id = ? AND item_guid_hash IN (?` + strings.Repeat(",?", len(chunk)-1) + `)`
+		if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("MarkItemsSeen exec for feed ID %d: %w", feedID, err)
+		}
+	}
+	return nil
+}
+
+// prunableProcessedQuery selects the IDs of a feed's processed items that its
+// retention lets go: all but the newest few, last seen before a cutoff.
+const prunableProcessedQuery = `
+	SELECT id FROM (
+		SELECT id, COALESCE(seen_at, processed_at) AS last_seen, ROW_NUMBER() OVER (ORDER BY id DESC) AS newest
+		FROM processed_items WHERE feed_id = ?
+	) WHERE newest > ? AND julianday(last_seen) < julianday(?)`
+
+// PruneProcessedItems deletes the processed items of a feed that retention
+// r lets go as of now, returning how many it deleted. With dryRun it only
+// counts them.
+func (s *FeedStore) PruneProcessedItems(ctx context.Context, feedID int64, r ProcessedRetention, now time.Time, dryRun bool) (int64, error) {
+	if r.MaxAge <= 0 {
+		return 0, nil
+	}
+	args := []interface{}{feedID, max(r.Keep, 0), now.Add(-r.MaxAge).UTC()}
+	if dryRun {
+		var count int64
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM (`+prunableProcessedQuery+`)`, args...).Scan(&count); err != nil {
+			return 0, fmt.Errorf("PruneProcessedItems count for feed ID %d: %w", feedID, err)
+		}
+		return count, nil
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM processed_items WHERE id IN (`+prunableProcessedQuery+`)`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("PruneProcessedItems exec for feed ID %d: %w", feedID, err)
+	}
+	return res.RowsAffected()
+}
+
+// PruneAllProcessedItems prunes the processed items of every feed by its
+// retention, defaults with the feed's overrides, and those of deleted feeds.
+// It returns how many it deleted, or with dryRun would delete.
+func (s *FeedStore) PruneAllProcessedItems(ctx context.Context, defaults ProcessedRetention, now time.Time, dryRun bool) (int64, error) {
+	feeds, err := s.ListFeeds(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total, err := s.PruneOrphanedProcessedItems(ctx, dryRun)
+	if err != nil {
+		return 0, err
+	}
+	for _, feed := range feeds {
+		n, err := s.PruneProcessedItems(ctx, feed.ID, feed.Retention(defaults), now, dryRun)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// PruneOrphanedProcessedItems deletes the processed items of feeds that no
+// longer exist, returning how many it deleted. With dryRun it only counts
+// them.
+func (s *FeedStore) PruneOrphanedProcessedItems(ctx context.Context, dryRun bool) (int64, error) {
+	const orphaned = `FROM processed_items WHERE feed_id NOT IN (SELECT id FROM feeds)`
+	if dryRun {
+		var count int64
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) `+orphaned).Scan(&count); err != nil {
+			return 0, fmt.Errorf("PruneOrphanedProcessedItems count: %w", err)
+		}
+		return count, nil
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE `+orphaned)
+	if err != nil {
+		return 0, fmt.Errorf("PruneOrphanedProcessedItems exec: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// SetFeedIcon records an icon lookup at checkedAt, storing icon when one was
+// found. A nil icon keeps the previously stored one.
+func (s *FeedStore) SetFeedIcon(ctx context.Context, feedID int64, icon *FeedIcon, checkedAt time.Time) error {
+	query := `UPDATE feeds SET icon_checked_at = ? WHERE id = ?`
+	args := []interface{}{checkedAt, feedID}
+	if icon != nil {
+		query = `UPDATE feeds SET icon_checked_at = ?, icon_url = ?, icon_content_type = ?, icon = ? WHERE id = ?`
+		args = []interface{}{checkedAt, icon.URL, icon.ContentType, icon.Data, feedID}
+	}
+	stmt, err := s.db.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("SetFeedIcon prepare: %w", err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.ExecContext(ctx, args...); err != nil {
+		return fmt.Errorf("SetFeedIcon exec for feed ID %d: %w", feedID, err)
+	}
+	return nil
+}
+
+// GetFeedIcon returns a feed's stored icon, or nil when none has been found.
+func (s *FeedStore) GetFeedIcon(ctx context.Context, feedID int64) (*FeedIcon, error) {
+	stmt, err := s.db.PrepareContext(ctx, `SELECT icon_url, icon_content_type, icon, icon_checked_at FROM feeds WHERE id = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("GetFeedIcon prepare: %w", err)
+	}
+	defer stmt.Close()
+	var (
+		iconURL, contentType sql.NullString
+		data                 []byte
+		checkedAt            sql.NullTime
+	)
+	if err := stmt.QueryRowContext(ctx, feedID).Scan(&iconURL, &contentType, &data, &checkedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("GetFeedIcon scan for feed ID %d: %w", feedID, err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return &FeedIcon{URL: iconURL.String, ContentType: contentType.String, Data: data, CheckedAt: checkedAt.Time}, nil
+}
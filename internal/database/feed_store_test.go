@@ -0,0 +1,295 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterUnprocessedHashes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	store := NewFeedStore(db)
+	feedID, err := store.CreateFeed(ctx, &Feed{URL: "https://example.com/feed.xml", FrequencySeconds: 300, TelegramChatID: "1"})
+	require.NoError(t, err)
+	otherID, err := store.CreateFeed(ctx, &Feed{URL: "https://example.com/other.xml", FrequencySeconds: 300, TelegramChatID: "1"})
+	require.NoError(t, err)
+
+	// More hashes than fit one query, every third one processed
+	var hashes, processed, want []string
+	for i := 0; i < 1200; i++ {
+		hash := fmt.Sprintf("hash%d", i)
+		hashes = append(hashes, hash)
+		if i%3 == 0 {
+			processed = append(processed, hash)
+		} else {
+			want = append(want, hash)
+		}
+	}
+	require.NoError(t, store.AddProcessedItems(ctx, feedID, processed))
+	require.NoError(t, store.AddProcessedItems(ctx, otherID, hashes[:10]))
+
+	got, err := store.FilterUnprocessedHashes(ctx, feedID, hashes)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	got, err = store.FilterUnprocessedHashes(ctx, otherID, hashes[:12])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hash10", "hash11"}, got)
+
+	got, err = store.FilterUnprocessedHashes(ctx, feedID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestProcessedEpochBumpedOnDelete(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	store := NewFeedStore(db)
+	feedID, err := store.CreateFeed(ctx, &Feed{URL: "https://example.com/feed.xml", FrequencySeconds: 300, TelegramChatID: "1"})
+	require.NoError(t, err)
+	require.NoError(t, store.AddProcessedItems(ctx, feedID, []string{"a", "b"}))
+
+	feed, err := store.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, feed.ProcessedEpoch, "adding items keeps the epoch")
+
+	_, err = db.ExecContext(ctx, `DELETE FROM processed_items WHERE feed_id = ? AND item_guid_hash = 'a'`, feedID)
+	require.NoError(t, err)
+	feed, err = store.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, feed.ProcessedEpoch)
+}
+
+func TestFetchFailures(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	store := NewFeedStore(db)
+	feedID, err := store.CreateFeed(ctx, &Feed{URL: "https://example.com/feed.xml", FrequencySeconds: 300, TelegramChatID: "1", IsEnabled: true})
+	require.NoError(t, err)
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	failures, err := store.RecordFetchFailure(ctx, feedID, "connection refused", at)
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures)
+	failures, err = store.RecordFetchFailure(ctx, feedID, "HTTP 503", at.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, failures)
+
+	feed, err := store.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, feed.ConsecutiveFailures)
+	require.NotNil(t, feed.LastError)
+	assert.Equal(t, "HTTP 503", *feed.LastError)
+	require.NotNil(t, feed.LastErrorAt)
+	assert.True(t, feed.LastErrorAt.Equal(at.Add(time.Minute)))
+
+	require.NoError(t, store.ResetFetchFailures(ctx, feedID))
+	feed, err = store.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	assert.Zero(t, feed.ConsecutiveFailures)
+	assert.NotNil(t, feed.LastError, "the last error is kept")
+
+	// Enabling a feed disabled for failing starts its count over.
+	_, err = store.RecordFetchFailure(ctx, feedID, "HTTP 503", at)
+	require.NoError(t, err)
+	require.NoError(t, store.SetFeedEnabled(ctx, feedID, false))
+	feed, err = store.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, feed.ConsecutiveFailures, "disabling keeps the count")
+	require.NoError(t, store.SetFeedEnabled(ctx, feedID, true))
+	feed, err = store.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	assert.Zero(t, feed.ConsecutiveFailures)
+
+	_, err = store.RecordFetchFailure(ctx, feedID+100, "gone", at)
+	assert.Error(t, err)
+}
+
+func TestPruneProcessedItems(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	store := NewFeedStore(db)
+	feedID, err := store.CreateFeed(ctx, &Feed{URL: "https://example.com/feed.xml", FrequencySeconds: 300, TelegramChatID: "1"})
+	require.NoError(t, err)
+	require.NoError(t, store.AddProcessedItems(ctx, feedID, []string{"old1", "old2", "pinned", "old3", "new1", "new2"}))
+	// Everything was processed 100 days ago; "pinned" is still in the feed.
+	_, err = db.ExecContext(ctx, `UPDATE processed_items SET processed_at = ?`, time.Now().Add(-100*24*time.Hour).UTC())
+	require.NoError(t, err)
+	require.NoError(t, store.MarkItemsSeen(ctx, feedID, []string{"pinned", "unknown"}, time.Now()))
+	// And a deleted feed left its items behind.
+	require.NoError(t, store.AddProcessedItems(ctx, feedID+1, []string{"orphan"}))
+
+	retention := ProcessedRetention{MaxAge: 90 * 24 * time.Hour, Keep: 2}
+	n, err := store.PruneAllProcessedItems(ctx, retention, time.Now(), true)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, n)
+	hashes, err := store.ListProcessedItems(ctx, feedID)
+	require.NoError(t, err)
+	assert.Len(t, hashes, 6, "a dry run deletes nothing")
+
+	n, err = store.PruneAllProcessedItems(ctx, retention, time.Now(), false)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, n)
+	hashes, err = store.ListProcessedItems(ctx, feedID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pinned", "new1", "new2"}, hashes, "recently seen and newest items are kept")
+	orphans, err := store.ListProcessedItems(ctx, feedID+1)
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+
+	// A feed keeping its items forever overrides the default.
+	forever := 0
+	feed, err := store.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	feed.ProcessedMaxAgeSeconds = &forever
+	n, err = store.PruneProcessedItems(ctx, feedID, feed.Retention(ProcessedRetention{MaxAge: time.Hour}), time.Now().Add(1000*24*time.Hour), false)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestFeedTags(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	store := NewFeedStore(db)
+	feedID, err := store.CreateFeed(ctx, &Feed{URL: "https://example.com/feed.xml", FrequencySeconds: 300, TelegramChatID: "1", Tags: []string{" Tech ", "news", "tech", ""}})
+	require.NoError(t, err)
+	feed, err := store.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Tech", "news"}, feed.Tags)
+
+	feed.Tags = nil
+	require.NoError(t, store.UpdateFeed(ctx, feed))
+	feed, err = store.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	assert.Nil(t, feed.Tags)
+}
+
+func TestFindFeeds(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	store := NewFeedStore(db)
+	botID := int64(7)
+	newsID, err := store.CreateFeed(ctx, &Feed{URL: "https://news.example.com/rss", FrequencySeconds: 300, TelegramChatID: "@news", TelegramBotID: &botID, IsEnabled: true, Tags: []string{"News"}})
+	require.NoError(t, err)
+	blogID, err := store.CreateFeed(ctx, &Feed{URL: "https://blog.example.org/atom", FrequencySeconds: 300, TelegramChatID: "@blog", IsEnabled: false, Tags: []string{"tech", "news"}})
+	require.NoError(t, err)
+	techID, err := store.CreateFeed(ctx, &Feed{URL: "https://tech.example.com/feed", FrequencySeconds: 300, TelegramChatID: "@news", IsEnabled: true})
+	require.NoError(t, err)
+	_, err = store.RecordFetchFailure(ctx, techID, "timeout", time.Now())
+	require.NoError(t, err)
+
+	ids := func(filter FeedListFilter) []int64 {
+		feeds, err := store.FindFeeds(ctx, filter)
+		require.NoError(t, err)
+		var out []int64
+		for _, f := range feeds {
+			out = append(out, f.ID)
+		}
+		return out
+	}
+	enabled, disabled := true, false
+	assert.Equal(t, []int64{newsID, blogID, techID}, ids(FeedListFilter{}))
+	assert.Equal(t, []int64{newsID, techID}, ids(FeedListFilter{Enabled: &enabled}))
+	assert.Equal(t, []int64{blogID}, ids(FeedListFilter{Enabled: &disabled}))
+	assert.Equal(t, []int64{techID}, ids(FeedListFilter{Failing: true}))
+	assert.Equal(t, []int64{newsID}, ids(FeedListFilter{BotID: botID}))
+	assert.Equal(t, []int64{newsID, techID}, ids(FeedListFilter{ChatID: "@news"}))
+	assert.Equal(t, []int64{newsID, blogID}, ids(FeedListFilter{Tag: "NEWS"}))
+	assert.Equal(t, []int64{newsID, techID}, ids(FeedListFilter{URLContains: "Example.COM"}))
+	assert.Equal(t, []int64{newsID}, ids(FeedListFilter{Enabled: &enabled, Tag: "news"}))
+	assert.Equal(t, []int64{blogID}, ids(FeedListFilter{Limit: 1, Offset: 1}))
+	assert.Equal(t, []int64{blogID, techID}, ids(FeedListFilter{Offset: 1}))
+	assert.Empty(t, ids(FeedListFilter{Tag: "sports"}))
+}
+
+func TestPatchFeed(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	store := NewFeedStore(db)
+	title := "Mine"
+	feedID, err := store.CreateFeed(ctx, &Feed{URL: "https://example.com/feed.xml", UserTitle: &title, FrequencySeconds: 300, TelegramChatID: "1",
+		RequestHeaders: map[string]string{"X-Key": "a"}, Tags: []string{"news"}, IsEnabled: true})
+	require.NoError(t, err)
+	etag := "etag-1"
+	require.NoError(t, store.UpdateFeedLastProcessed(ctx, feedID, nil, &etag, nil, nil))
+
+	require.NoError(t, store.PatchFeed(ctx, feedID, map[string]any{
+		"frequency_seconds": 900,
+		"user_title":        nil,
+		"cookies":           map[string]string{"session": "s"},
+		"tls_config":        &TLSConfig{MinVersion: "1.2"},
+		"is_enabled":        false,
+	}))
+	feed, err := store.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	assert.Equal(t, 900, feed.FrequencySeconds)
+	assert.Nil(t, feed.UserTitle)
+	assert.Equal(t, map[string]string{"session": "s"}, feed.Cookies)
+	assert.Equal(t, &TLSConfig{MinVersion: "1.2"}, feed.TLS)
+	assert.False(t, feed.IsEnabled)
+	// Untouched columns keep their values
+	assert.Equal(t, map[string]string{"X-Key": "a"}, feed.RequestHeaders)
+	assert.Equal(t, []string{"news"}, feed.Tags)
+	assert.Equal(t, "1", feed.TelegramChatID)
+	require.NotNil(t, feed.HTTPEtag)
+	assert.Equal(t, "etag-1", *feed.HTTPEtag)
+
+	assert.NoError(t, store.PatchFeed(ctx, feedID, nil))
+	assert.ErrorContains(t, store.PatchFeed(ctx, feedID, map[string]any{"http_etag": "x"}), "can't be changed")
+	assert.ErrorContains(t, store.PatchFeed(ctx, feedID, map[string]any{"tags": "news"}), "expected []string")
+	assert.ErrorContains(t, store.PatchFeed(ctx, feedID+1, map[string]any{"is_enabled": true}), "not found")
+}
+
+func TestPatchFeed_ChatChange(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	store := NewFeedStore(db)
+	newFeed := func(chat string, autoTopic bool) int64 {
+		thread := 7
+		id, err := store.CreateFeed(ctx, &Feed{URL: "https://example.com/" + chat, FrequencySeconds: 300, TelegramChatID: chat, TelegramThreadID: &thread, AutoCreateTopic: autoTopic, IsEnabled: true})
+		require.NoError(t, err)
+		require.NoError(t, store.SetResolvedChatID(ctx, id, chat, -100))
+		return id
+	}
+	autoID, fixedID := newFeed("@auto", true), newFeed("@fixed", false)
+
+	require.NoError(t, store.PatchFeed(ctx, autoID, map[string]any{"frequency_seconds": 600}))
+	feed, err := store.GetFeedByID(ctx, autoID)
+	require.NoError(t, err)
+	assert.NotNil(t, feed.ResolvedChat(), "other changes keep the cached chat ID")
+	assert.NotNil(t, feed.TelegramThreadID)
+
+	require.NoError(t, store.PatchFeed(ctx, autoID, map[string]any{"telegram_chat_id": "@other"}))
+	feed, err = store.GetFeedByID(ctx, autoID)
+	require.NoError(t, err)
+	assert.Nil(t, feed.ResolvedChatID)
+	assert.Nil(t, feed.TelegramThreadID, "the topic created in the old chat is dropped")
+
+	require.NoError(t, store.PatchFeed(ctx, fixedID, map[string]any{"telegram_chat_id": "@other"}))
+	feed, err = store.GetFeedByID(ctx, fixedID)
+	require.NoError(t, err)
+	assert.Nil(t, feed.ResolvedChatID)
+	require.NotNil(t, feed.TelegramThreadID, "a topic set by hand is kept")
+	assert.Equal(t, 7, *feed.TelegramThreadID)
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// FilterStore handles the keyword and regex filters of feeds.
+type FilterStore struct {
+	db *DB
+}
+
+// NewFilterStore creates a new FilterStore.
+func NewFilterStore(db *DB) *FilterStore {
+	return &FilterStore{db: db}
+}
+
+// CreateFilter adds a filter to a feed and returns its ID.
+func (s *FilterStore) CreateFilter(ctx context.Context, f *FeedFilter) (int64, error) {
+	stmt, err := s.db.PrepareContext(ctx, `INSERT INTO feed_filters (feed_id, action, match_type, pattern, fields) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("CreateFilter prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	var fields sql.NullString
+	if len(f.Fields) > 0 {
+		data, err := json.Marshal(f.Fields)
+		if err != nil {
+			return 0, fmt.Errorf("CreateFilter marshal fields: %w", err)
+		}
+		fields = sql.NullString{String: string(data), Valid: true}
+	}
+	matchType := f.MatchType
+	if matchType == "" {
+		matchType = FilterKeyword
+	}
+	res, err := stmt.ExecContext(ctx, f.FeedID, f.Action, matchType, f.Pattern, fields)
+	if err != nil {
+		return 0, fmt.Errorf("CreateFilter exec: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// ListFeedFilters returns the filters of a feed, oldest first.
+func (s *FilterStore) ListFeedFilters(ctx context.Context, feedID int64) ([]*FeedFilter, error) {
+	stmt, err := s.db.PrepareCached(ctx, `
+		SELECT id, feed_id, action, match_type, pattern, fields, created_at FROM feed_filters WHERE feed_id = ? ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("ListFeedFilters prepare: %w", err)
+	}
+	rows, err := stmt.QueryContext(ctx, feedID)
+	if err != nil {
+		return nil, fmt.Errorf("ListFeedFilters query: %w", err)
+	}
+	defer rows.Close()
+
+	var filters []*FeedFilter
+	for rows.Next() {
+		f := &FeedFilter{}
+		var fields sql.NullString
+		if err := rows.Scan(&f.ID, &f.FeedID, &f.Action, &f.MatchType, &f.Pattern, &fields, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ListFeedFilters scan: %w", err)
+		}
+		if fields.Valid && fields.String != "" {
+			if err := json.Unmarshal([]byte(fields.String), &f.Fields); err != nil {
+				return nil, fmt.Errorf("ListFeedFilters unmarshal fields of filter %d: %w", f.ID, err)
+			}
+		}
+		filters = append(filters, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListFeedFilters rows error: %w", err)
+	}
+	return filters, nil
+}
+
+// DeleteFilter removes a filter.
+func (s *FilterStore) DeleteFilter(ctx context.Context, id int64) error {
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM feed_filters WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("DeleteFilter prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("DeleteFilter exec: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("DeleteFilter: filter %d not found", id)
+	}
+	return nil
+}
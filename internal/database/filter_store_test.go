@@ -0,0 +1,39 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterStore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	feedID, err := NewFeedStore(db).CreateFeed(ctx, &Feed{URL: "https://example.com/feed.xml", FrequencySeconds: 300, IsEnabled: true})
+	require.NoError(t, err)
+
+	store := NewFilterStore(db)
+	excludeID, err := store.CreateFilter(ctx, &FeedFilter{FeedID: feedID, Action: FilterExclude, Pattern: "sponsored"})
+	require.NoError(t, err)
+	_, err = store.CreateFilter(ctx, &FeedFilter{FeedID: feedID, Action: FilterInclude, MatchType: FilterRegex, Pattern: `(?i)\bgo\b`, Fields: []string{FilterFieldTitle, FilterFieldCategories}})
+	require.NoError(t, err)
+
+	filters, err := store.ListFeedFilters(ctx, feedID)
+	require.NoError(t, err)
+	require.Len(t, filters, 2)
+	assert.Equal(t, excludeID, filters[0].ID)
+	assert.Equal(t, FilterKeyword, filters[0].MatchType, "keyword is the default match type")
+	assert.Empty(t, filters[0].Fields)
+	assert.Equal(t, FilterRegex, filters[1].MatchType)
+	assert.Equal(t, []string{FilterFieldTitle, FilterFieldCategories}, filters[1].Fields)
+
+	require.NoError(t, store.DeleteFilter(ctx, excludeID))
+	assert.Error(t, store.DeleteFilter(ctx, excludeID))
+	filters, err = store.ListFeedFilters(ctx, feedID)
+	require.NoError(t, err)
+	assert.Len(t, filters, 1)
+}
@@ -80,4 +80,47 @@ func (s *FormattingProfileStore) ListProfiles(ctx context.Context) ([]*Formattin
 		return nil, fmt.Errorf("ListProfiles rows error: %w", err)
 	}
 	return profiles, nil
-}
\ No newline at end of file
+}
+
+// UpdateProfile saves p's name and configuration.
+func (s *FormattingProfileStore) UpdateProfile(ctx context.Context, p *FormattingProfile) error {
+	if err := p.MarshalConfig(); err != nil {
+		return fmt.Errorf("UpdateProfile marshal config: %w", err)
+	}
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE formatting_profiles SET name = ?, template_config = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("UpdateProfile prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, p.Name, p.ConfigJSON, p.ID)
+	if err != nil {
+		return fmt.Errorf("UpdateProfile exec for profile %d: %w", p.ID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("UpdateProfile: profile %d not found", p.ID)
+	}
+	return nil
+}
+
+// DeleteProfile removes a formatting profile. Feeds that used it get the
+// default formatting.
+func (s *FormattingProfileStore) DeleteProfile(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteProfile begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE feeds SET formatting_profile_id = NULL WHERE formatting_profile_id = ?`, id); err != nil {
+		return fmt.Errorf("DeleteProfile clear feeds: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM formatting_profiles WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("DeleteProfile exec for profile %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("DeleteProfile: profile %d not found", id)
+	}
+	return tx.Commit()
+}
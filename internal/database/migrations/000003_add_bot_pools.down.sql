@@ -0,0 +1,5 @@
+-- File: 000003_add_bot_pools.down.sql
+DROP TRIGGER IF EXISTS update_bot_pools_updated_at;
+ALTER TABLE feeds DROP COLUMN bot_pool_id;
+DROP TABLE IF EXISTS bot_pool_members;
+DROP TABLE IF EXISTS bot_pools;
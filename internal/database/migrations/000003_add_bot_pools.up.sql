@@ -0,0 +1,20 @@
+-- File: 000003_add_bot_pools.up.sql
+
+CREATE TABLE bot_pools (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE bot_pool_members (
+    pool_id INTEGER NOT NULL,
+    bot_id INTEGER NOT NULL,
+    PRIMARY KEY (pool_id, bot_id),
+    FOREIGN KEY (pool_id) REFERENCES bot_pools(id) ON DELETE CASCADE,
+    FOREIGN KEY (bot_id) REFERENCES telegram_bots(id) ON DELETE CASCADE
+);
+
+ALTER TABLE feeds ADD COLUMN bot_pool_id INTEGER REFERENCES bot_pools(id) ON DELETE SET NULL;
+
+CREATE TRIGGER update_bot_pools_updated_at AFTER UPDATE ON bot_pools FOR EACH ROW BEGIN UPDATE bot_pools SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id; END;
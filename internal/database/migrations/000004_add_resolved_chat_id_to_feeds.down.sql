@@ -0,0 +1,3 @@
+-- File: 000004_add_resolved_chat_id_to_feeds.down.sql
+ALTER TABLE feeds DROP COLUMN resolved_chat_for;
+ALTER TABLE feeds DROP COLUMN resolved_chat_id;
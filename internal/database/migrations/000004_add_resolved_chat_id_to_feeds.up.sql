@@ -0,0 +1,6 @@
+-- File: 000004_add_resolved_chat_id_to_feeds.up.sql
+-- Numeric chat ID resolved from an @username on first successful delivery.
+ALTER TABLE feeds ADD COLUMN resolved_chat_id INTEGER;
+-- The telegram_chat_id it was resolved from. A cached ID is only used while
+-- the feed still points at that chat.
+ALTER TABLE feeds ADD COLUMN resolved_chat_for TEXT;
@@ -0,0 +1,3 @@
+-- File: 000005_add_forum_topics_to_feeds.down.sql
+ALTER TABLE feeds DROP COLUMN auto_create_topic;
+ALTER TABLE feeds DROP COLUMN telegram_thread_id;
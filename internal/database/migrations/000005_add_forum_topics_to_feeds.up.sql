@@ -0,0 +1,5 @@
+-- File: 000005_add_forum_topics_to_feeds.up.sql
+-- Forum topic (message_thread_id) that a feed posts into, and whether the bot
+-- should create a dedicated topic for the feed on first delivery.
+ALTER TABLE feeds ADD COLUMN telegram_thread_id INTEGER;
+ALTER TABLE feeds ADD COLUMN auto_create_topic BOOLEAN DEFAULT FALSE;
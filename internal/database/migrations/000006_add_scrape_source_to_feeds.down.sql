@@ -0,0 +1,3 @@
+-- File: 000006_add_scrape_source_to_feeds.down.sql
+ALTER TABLE feeds DROP COLUMN scrape_config;
+ALTER TABLE feeds DROP COLUMN source_type;
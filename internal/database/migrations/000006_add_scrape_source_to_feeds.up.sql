@@ -0,0 +1,5 @@
+-- File: 000006_add_scrape_source_to_feeds.up.sql
+-- Feeds can be read from an HTML page instead of an RSS/Atom/JSON feed. For
+-- source_type 'scrape', scrape_config holds the CSS selectors as JSON.
+ALTER TABLE feeds ADD COLUMN source_type TEXT NOT NULL DEFAULT 'rss';
+ALTER TABLE feeds ADD COLUMN scrape_config TEXT;
@@ -0,0 +1,3 @@
+-- File: 000007_add_websub_subscriptions.down.sql
+DROP TRIGGER IF EXISTS update_websub_subscriptions_updated_at;
+DROP TABLE IF EXISTS websub_subscriptions;
@@ -0,0 +1,23 @@
+-- File: 000007_add_websub_subscriptions.up.sql
+
+-- One WebSub subscription per feed. state is 'pending' until the hub verifies
+-- the intent, then 'active' until lease_expires_at; 'denied' if the hub refused.
+-- The pending_* columns hold a renewal of an active subscription awaiting
+-- verification: the active hub_url, topic_url and secret keep authenticating
+-- pushes until the hub verifies it, which promotes them.
+CREATE TABLE websub_subscriptions (
+    feed_id INTEGER PRIMARY KEY,
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    state TEXT NOT NULL DEFAULT 'pending',
+    lease_expires_at DATETIME,
+    pending_hub_url TEXT,
+    pending_topic_url TEXT,
+    pending_secret TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE TRIGGER update_websub_subscriptions_updated_at AFTER UPDATE ON websub_subscriptions FOR EACH ROW BEGIN UPDATE websub_subscriptions SET updated_at = CURRENT_TIMESTAMP WHERE feed_id = OLD.feed_id; END;
@@ -0,0 +1,3 @@
+-- File: 000008_add_request_options_to_feeds.down.sql
+ALTER TABLE feeds DROP COLUMN request_headers;
+ALTER TABLE feeds DROP COLUMN user_agent;
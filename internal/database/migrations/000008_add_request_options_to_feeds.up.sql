@@ -0,0 +1,4 @@
+-- File: 000008_add_request_options_to_feeds.up.sql
+-- Per-feed User-Agent override and extra request headers (JSON object of name -> value).
+ALTER TABLE feeds ADD COLUMN user_agent TEXT;
+ALTER TABLE feeds ADD COLUMN request_headers TEXT;
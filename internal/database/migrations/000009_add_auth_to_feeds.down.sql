@@ -0,0 +1,4 @@
+-- File: 000009_add_auth_to_feeds.down.sql
+ALTER TABLE feeds DROP COLUMN auth_secret_encrypted;
+ALTER TABLE feeds DROP COLUMN auth_username;
+ALTER TABLE feeds DROP COLUMN auth_type;
@@ -0,0 +1,6 @@
+-- File: 000009_add_auth_to_feeds.up.sql
+-- Optional credentials for protected feeds. auth_type is 'basic' or 'bearer';
+-- the password or token is AES-GCM encrypted like telegram_bots.encrypted_token.
+ALTER TABLE feeds ADD COLUMN auth_type TEXT;
+ALTER TABLE feeds ADD COLUMN auth_username TEXT;
+ALTER TABLE feeds ADD COLUMN auth_secret_encrypted TEXT;
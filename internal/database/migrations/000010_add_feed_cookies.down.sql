@@ -0,0 +1,3 @@
+-- File: 000010_add_feed_cookies.down.sql
+DROP TABLE IF EXISTS feed_cookies;
+ALTER TABLE feeds DROP COLUMN cookies;
@@ -0,0 +1,21 @@
+-- File: 000010_add_feed_cookies.up.sql
+
+-- Cookies configured by the user for a feed (JSON object of name -> value),
+-- seeded into the feed's cookie jar when the jar doesn't already hold them.
+ALTER TABLE feeds ADD COLUMN cookies TEXT;
+
+-- Persisted cookie jar: cookies set by servers while fetching a feed.
+CREATE TABLE feed_cookies (
+    feed_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    domain TEXT NOT NULL,
+    path TEXT NOT NULL DEFAULT '/',
+    host_only BOOLEAN NOT NULL DEFAULT TRUE,
+    value TEXT NOT NULL,
+    secure BOOLEAN NOT NULL DEFAULT FALSE,
+    http_only BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at DATETIME, -- NULL for session cookies
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (feed_id, name, domain, path),
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+-- File: 000011_add_freshness_to_feeds.down.sql
+ALTER TABLE feeds DROP COLUMN stale_after_seconds;
+ALTER TABLE feeds DROP COLUMN stale_alerted_at;
+ALTER TABLE feeds DROP COLUMN newest_item_at;
@@ -0,0 +1,7 @@
+-- File: 000011_add_freshness_to_feeds.up.sql
+-- Staleness tracking: when the newest item seen was published, when an admin
+-- was last alerted that the feed went quiet, and a per-feed threshold override
+-- (NULL uses the global stale_feed_after; 0 disables the check for the feed).
+ALTER TABLE feeds ADD COLUMN newest_item_at DATETIME;
+ALTER TABLE feeds ADD COLUMN stale_alerted_at DATETIME;
+ALTER TABLE feeds ADD COLUMN stale_after_seconds INTEGER;
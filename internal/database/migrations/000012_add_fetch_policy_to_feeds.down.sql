@@ -0,0 +1,4 @@
+-- File: 000012_add_fetch_policy_to_feeds.down.sql
+ALTER TABLE feeds DROP COLUMN fetch_retry_delay_seconds;
+ALTER TABLE feeds DROP COLUMN fetch_max_retries;
+ALTER TABLE feeds DROP COLUMN fetch_timeout_seconds;
@@ -0,0 +1,6 @@
+-- File: 000012_add_fetch_policy_to_feeds.up.sql
+-- Per-feed overrides for the global fetch timeout and retry policy.
+-- NULL uses the configured fetch.* value.
+ALTER TABLE feeds ADD COLUMN fetch_timeout_seconds INTEGER;
+ALTER TABLE feeds ADD COLUMN fetch_max_retries INTEGER;
+ALTER TABLE feeds ADD COLUMN fetch_retry_delay_seconds INTEGER;
@@ -0,0 +1,3 @@
+-- File: 000013_add_tls_config.down.sql
+ALTER TABLE proxies DROP COLUMN tls_config;
+ALTER TABLE feeds DROP COLUMN tls_config;
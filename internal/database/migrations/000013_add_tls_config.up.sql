@@ -0,0 +1,5 @@
+-- File: 000013_add_tls_config.up.sql
+-- TLS settings (custom CA, minimum version, client certificate, skip-verify)
+-- stored as JSON. A feed's settings override those of the proxy it uses.
+ALTER TABLE feeds ADD COLUMN tls_config TEXT;
+ALTER TABLE proxies ADD COLUMN tls_config TEXT;
@@ -0,0 +1,2 @@
+-- File: 000014_add_flaresolverr_to_feeds.down.sql
+ALTER TABLE feeds DROP COLUMN use_flaresolverr;
@@ -0,0 +1,4 @@
+-- File: 000014_add_flaresolverr_to_feeds.up.sql
+-- Feeds flagged here are always fetched through the configured FlareSolverr
+-- instance; others only fall back to it when a Cloudflare challenge is detected.
+ALTER TABLE feeds ADD COLUMN use_flaresolverr BOOLEAN NOT NULL DEFAULT FALSE;
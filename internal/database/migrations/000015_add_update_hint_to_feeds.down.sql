@@ -0,0 +1,2 @@
+-- File: 000015_add_update_hint_to_feeds.down.sql
+ALTER TABLE feeds DROP COLUMN update_hint_seconds;
@@ -0,0 +1,4 @@
+-- File: 000015_add_update_hint_to_feeds.up.sql
+-- Update interval the feed itself declares (RSS <ttl>, sy:updatePeriod), in
+-- seconds. Feeds are never polled more often than this.
+ALTER TABLE feeds ADD COLUMN update_hint_seconds INTEGER;
@@ -0,0 +1,3 @@
+-- File: 000016_add_backfill_to_feeds.down.sql
+ALTER TABLE feeds DROP COLUMN backfill_max_age_seconds;
+ALTER TABLE feeds DROP COLUMN backfill_limit;
@@ -0,0 +1,6 @@
+-- File: 000016_add_backfill_to_feeds.up.sql
+-- Pending RFC 5005 archive backfill, requested with 'feed add --backfill'.
+-- backfill_limit is the number of archived items to import (0 for all); NULL
+-- means no backfill is pending. The worker clears it once the items are sent.
+ALTER TABLE feeds ADD COLUMN backfill_limit INTEGER;
+ALTER TABLE feeds ADD COLUMN backfill_max_age_seconds INTEGER;
@@ -0,0 +1,5 @@
+-- File: 000017_add_icon_to_feeds.down.sql
+ALTER TABLE feeds DROP COLUMN icon_checked_at;
+ALTER TABLE feeds DROP COLUMN icon_url;
+ALTER TABLE feeds DROP COLUMN icon_content_type;
+ALTER TABLE feeds DROP COLUMN icon;
@@ -0,0 +1,6 @@
+-- File: 000017_add_icon_to_feeds.up.sql
+-- Cached favicon of the feed's site, refreshed periodically by the worker.
+ALTER TABLE feeds ADD COLUMN icon BLOB;
+ALTER TABLE feeds ADD COLUMN icon_content_type TEXT;
+ALTER TABLE feeds ADD COLUMN icon_url TEXT;
+ALTER TABLE feeds ADD COLUMN icon_checked_at DATETIME; -- Last lookup, successful or not
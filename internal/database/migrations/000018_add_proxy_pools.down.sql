@@ -0,0 +1,5 @@
+-- File: 000018_add_proxy_pools.down.sql
+DROP TRIGGER IF EXISTS update_proxy_pools_updated_at;
+ALTER TABLE feeds DROP COLUMN proxy_pool_id;
+DROP TABLE IF EXISTS proxy_pool_members;
+DROP TABLE IF EXISTS proxy_pools;
@@ -0,0 +1,21 @@
+-- File: 000018_add_proxy_pools.up.sql
+
+CREATE TABLE proxy_pools (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    strategy TEXT NOT NULL DEFAULT 'round_robin', -- round_robin, random or sticky
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE proxy_pool_members (
+    pool_id INTEGER NOT NULL,
+    proxy_id INTEGER NOT NULL,
+    PRIMARY KEY (pool_id, proxy_id),
+    FOREIGN KEY (pool_id) REFERENCES proxy_pools(id) ON DELETE CASCADE,
+    FOREIGN KEY (proxy_id) REFERENCES proxies(id) ON DELETE CASCADE
+);
+
+ALTER TABLE feeds ADD COLUMN proxy_pool_id INTEGER REFERENCES proxy_pools(id) ON DELETE SET NULL;
+
+CREATE TRIGGER update_proxy_pools_updated_at AFTER UPDATE ON proxy_pools FOR EACH ROW BEGIN UPDATE proxy_pools SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id; END;
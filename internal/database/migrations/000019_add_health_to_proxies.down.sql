@@ -0,0 +1,3 @@
+-- File: 000019_add_health_to_proxies.down.sql
+ALTER TABLE proxies DROP COLUMN health_error;
+ALTER TABLE proxies DROP COLUMN health_checked_at;
@@ -0,0 +1,5 @@
+-- File: 000019_add_health_to_proxies.up.sql
+-- Results of the background proxy health check. health_error is set while the
+-- checker considers the proxy down and cleared once it passes again.
+ALTER TABLE proxies ADD COLUMN health_checked_at DATETIME;
+ALTER TABLE proxies ADD COLUMN health_error TEXT;
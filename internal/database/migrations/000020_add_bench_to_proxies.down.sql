@@ -0,0 +1,4 @@
+-- File: 000020_add_bench_to_proxies.down.sql
+ALTER TABLE proxies DROP COLUMN bench_at;
+ALTER TABLE proxies DROP COLUMN bench_success_rate;
+ALTER TABLE proxies DROP COLUMN bench_ttfb_ms;
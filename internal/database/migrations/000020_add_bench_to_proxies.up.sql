@@ -0,0 +1,6 @@
+-- File: 000020_add_bench_to_proxies.up.sql
+-- Latest 'proxy bench --save' results. Pools list their fastest, most reliable
+-- members first.
+ALTER TABLE proxies ADD COLUMN bench_ttfb_ms INTEGER;
+ALTER TABLE proxies ADD COLUMN bench_success_rate REAL;
+ALTER TABLE proxies ADD COLUMN bench_at DATETIME;
@@ -0,0 +1,34 @@
+-- File: 000021_add_socks5h_proxy_type.down.sql
+-- socks5h proxies become socks5 ones, which resolve hostnames locally.
+PRAGMA foreign_keys=off;
+
+CREATE TABLE proxies_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    type TEXT CHECK(type IN ('http', 'https', 'socks5')) NOT NULL,
+    address TEXT NOT NULL,
+    username TEXT,
+    password TEXT,
+    is_default_for_rss BOOLEAN DEFAULT FALSE,
+    is_default_for_telegram BOOLEAN DEFAULT FALSE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    tls_config TEXT,
+    health_checked_at DATETIME,
+    health_error TEXT,
+    bench_ttfb_ms INTEGER,
+    bench_success_rate REAL,
+    bench_at DATETIME
+);
+INSERT INTO proxies_new (id, name, type, address, username, password, is_default_for_rss, is_default_for_telegram,
+                         created_at, updated_at, tls_config, health_checked_at, health_error, bench_ttfb_ms, bench_success_rate, bench_at)
+  SELECT id, name, CASE type WHEN 'socks5h' THEN 'socks5' ELSE type END, address, username, password, is_default_for_rss, is_default_for_telegram,
+         created_at, updated_at, tls_config, health_checked_at, health_error, bench_ttfb_ms, bench_success_rate, bench_at
+  FROM proxies;
+DROP TABLE proxies;
+ALTER TABLE proxies_new RENAME TO proxies;
+
+CREATE INDEX idx_proxies_name ON proxies(name);
+CREATE TRIGGER update_proxies_updated_at AFTER UPDATE ON proxies FOR EACH ROW BEGIN UPDATE proxies SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id; END;
+
+PRAGMA foreign_keys=on;
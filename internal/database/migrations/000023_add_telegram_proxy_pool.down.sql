@@ -0,0 +1,2 @@
+-- File: 000023_add_telegram_proxy_pool.down.sql
+ALTER TABLE proxy_pools DROP COLUMN is_default_for_telegram;
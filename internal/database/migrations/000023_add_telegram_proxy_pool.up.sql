@@ -0,0 +1,4 @@
+-- File: 000023_add_telegram_proxy_pool.up.sql
+-- The pool marked here carries Telegram Bot API traffic for feeds without
+-- their own proxy, ahead of the default Telegram proxy.
+ALTER TABLE proxy_pools ADD COLUMN is_default_for_telegram BOOLEAN DEFAULT FALSE;
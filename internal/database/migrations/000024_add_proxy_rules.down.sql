@@ -0,0 +1,2 @@
+-- File: 000024_add_proxy_rules.down.sql
+DROP TABLE IF EXISTS proxy_rules;
@@ -0,0 +1,10 @@
+-- File: 000024_add_proxy_rules.up.sql
+
+CREATE TABLE proxy_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    pattern TEXT NOT NULL, -- Host glob, e.g. *.medium.com
+    proxy_id INTEGER NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0, -- Higher priorities are matched first
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (proxy_id) REFERENCES proxies(id) ON DELETE CASCADE
+);
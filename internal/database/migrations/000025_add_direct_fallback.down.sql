@@ -0,0 +1,3 @@
+-- File: 000025_add_direct_fallback.down.sql
+ALTER TABLE feeds DROP COLUMN proxy_direct_fallback;
+ALTER TABLE proxies DROP COLUMN direct_fallback;
@@ -0,0 +1,5 @@
+-- File: 000025_add_direct_fallback.up.sql
+-- Whether traffic may bypass a proxy that failed or is down. NULL inherits:
+-- a feed's setting wins over its proxy's, which wins over the config file.
+ALTER TABLE proxies ADD COLUMN direct_fallback BOOLEAN;
+ALTER TABLE feeds ADD COLUMN proxy_direct_fallback BOOLEAN;
@@ -0,0 +1,2 @@
+-- File: 000026_add_api_keys.down.sql
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,10 @@
+-- File: 000026_add_api_keys.up.sql
+
+CREATE TABLE api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    key_hash TEXT NOT NULL UNIQUE, -- SHA-256 of the key; the key itself is only shown once
+    role TEXT NOT NULL CHECK (role IN ('admin', 'editor', 'viewer')),
+    last_used_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
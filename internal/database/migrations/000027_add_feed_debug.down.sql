@@ -0,0 +1,2 @@
+-- File: 000027_add_feed_debug.down.sql
+ALTER TABLE feeds DROP COLUMN debug;
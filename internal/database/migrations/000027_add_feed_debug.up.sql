@@ -0,0 +1,3 @@
+-- File: 000027_add_feed_debug.up.sql
+-- Log this feed's processing at debug level whatever the global log level.
+ALTER TABLE feeds ADD COLUMN debug BOOLEAN NOT NULL DEFAULT 0;
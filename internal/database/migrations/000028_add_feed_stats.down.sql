@@ -0,0 +1,2 @@
+-- File: 000028_add_feed_stats.down.sql
+DROP TABLE IF EXISTS feed_stats;
@@ -0,0 +1,14 @@
+-- File: 000028_add_feed_stats.up.sql
+
+-- Hourly run counters per feed, read by the admin statistics digest.
+CREATE TABLE feed_stats (
+    feed_id INTEGER NOT NULL,
+    hour TEXT NOT NULL, -- UTC hour as YYYY-MM-DDTHH
+    runs INTEGER NOT NULL DEFAULT 0,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT, -- Most recent failure in the hour
+    PRIMARY KEY (feed_id, hour),
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_feed_stats_hour ON feed_stats(hour);
@@ -0,0 +1,4 @@
+-- File: 000029_add_feed_destinations.down.sql
+
+DROP INDEX IF EXISTS idx_feed_destinations_feed;
+DROP TABLE IF EXISTS feed_destinations;
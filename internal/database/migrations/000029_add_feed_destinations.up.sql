@@ -0,0 +1,12 @@
+-- File: 000029_add_feed_destinations.up.sql
+
+CREATE TABLE feed_destinations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    feed_id INTEGER NOT NULL,
+    type TEXT NOT NULL, -- Notifier that delivers to it, e.g. 'email'
+    config TEXT NOT NULL DEFAULT '{}', -- Notifier settings as JSON, e.g. the recipients
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_feed_destinations_feed ON feed_destinations(feed_id);
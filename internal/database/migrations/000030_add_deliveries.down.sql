@@ -0,0 +1,2 @@
+-- File: 000030_add_deliveries.down.sql
+DROP TABLE IF EXISTS deliveries;
@@ -0,0 +1,20 @@
+-- File: 000030_add_deliveries.up.sql
+
+-- Items the bot delivered, re-published by the output feed server.
+CREATE TABLE deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    feed_id INTEGER NOT NULL,
+    feed_title TEXT NOT NULL DEFAULT '', -- As shown when the item was delivered
+    chat_id TEXT NOT NULL DEFAULT '', -- The feed's Telegram chat; empty for feeds without one
+    guid TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    link TEXT NOT NULL DEFAULT '',
+    author TEXT NOT NULL DEFAULT '',
+    text TEXT NOT NULL DEFAULT '', -- Plain text of the formatted item
+    published_at DATETIME,
+    delivered_at DATETIME NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_deliveries_delivered_at ON deliveries(delivered_at);
+CREATE INDEX idx_deliveries_feed ON deliveries(feed_id, delivered_at);
@@ -0,0 +1,3 @@
+-- File: 000031_add_processed_epoch.down.sql
+DROP TRIGGER IF EXISTS processed_items_deleted;
+ALTER TABLE feeds DROP COLUMN processed_epoch;
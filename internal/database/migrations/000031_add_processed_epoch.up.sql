@@ -0,0 +1,6 @@
+-- File: 000031_add_processed_epoch.up.sql
+-- Bumped whenever processed items of a feed are deleted, so that caches of
+-- processed items (such as the worker's) know to forget what they learned.
+ALTER TABLE feeds ADD COLUMN processed_epoch INTEGER NOT NULL DEFAULT 0;
+
+CREATE TRIGGER processed_items_deleted AFTER DELETE ON processed_items FOR EACH ROW BEGIN UPDATE feeds SET processed_epoch = processed_epoch + 1 WHERE id = OLD.feed_id; END;
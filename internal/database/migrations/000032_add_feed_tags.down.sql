@@ -0,0 +1,2 @@
+-- File: 000032_add_feed_tags.down.sql
+ALTER TABLE feeds DROP COLUMN tags;
@@ -0,0 +1,4 @@
+-- File: 000032_add_feed_tags.up.sql
+-- Free-form labels for grouping feeds, stored as a JSON array of strings.
+-- OPML imports set them from the folders feeds were in.
+ALTER TABLE feeds ADD COLUMN tags TEXT;
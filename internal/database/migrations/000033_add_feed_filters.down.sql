@@ -0,0 +1,4 @@
+-- File: 000033_add_feed_filters.down.sql
+
+DROP INDEX IF EXISTS idx_feed_filters_feed;
+DROP TABLE IF EXISTS feed_filters;
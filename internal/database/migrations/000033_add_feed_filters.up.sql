@@ -0,0 +1,14 @@
+-- File: 000033_add_feed_filters.up.sql
+
+CREATE TABLE feed_filters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    feed_id INTEGER NOT NULL,
+    action TEXT NOT NULL, -- 'include' or 'exclude'
+    match_type TEXT NOT NULL DEFAULT 'keyword', -- 'keyword' or 'regex'
+    pattern TEXT NOT NULL,
+    fields TEXT, -- JSON array of item fields to match, e.g. ["title","author"]; NULL matches all of them
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_feed_filters_feed ON feed_filters(feed_id);
@@ -0,0 +1,4 @@
+-- File: 000034_add_feed_digests.down.sql
+
+DROP TABLE IF EXISTS digest_items;
+DROP TABLE IF EXISTS feed_digests;
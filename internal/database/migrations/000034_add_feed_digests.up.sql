@@ -0,0 +1,25 @@
+-- File: 000034_add_feed_digests.up.sql
+
+-- Feeds with a row here collect their new items and send them as one digest
+-- message on schedule instead of one message per item.
+CREATE TABLE feed_digests (
+    feed_id INTEGER PRIMARY KEY,
+    period TEXT NOT NULL, -- 'daily' or 'weekly'
+    at TEXT NOT NULL DEFAULT '08:00', -- Local time of day to send, HH:MM
+    weekday TEXT, -- Day of weekly digests, e.g. 'monday'
+    last_sent_at DATETIME, -- Scheduled time of the last digest sent
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- Items waiting for their feed's next digest.
+CREATE TABLE digest_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    feed_id INTEGER NOT NULL,
+    item_guid_hash TEXT NOT NULL,
+    feed_title TEXT, -- Title of the fetched feed document
+    item TEXT NOT NULL, -- The parsed item as JSON
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (feed_id, item_guid_hash),
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
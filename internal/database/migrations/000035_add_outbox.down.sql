@@ -0,0 +1,4 @@
+-- File: 000035_add_outbox.down.sql
+
+DROP INDEX IF EXISTS idx_outbox_feed_id;
+DROP TABLE IF EXISTS outbox;
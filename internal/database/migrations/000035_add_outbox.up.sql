@@ -0,0 +1,17 @@
+-- File: 000035_add_outbox.up.sql
+
+-- Formatted items whose Telegram send failed, retried in order by the outbox
+-- dispatcher until they are delivered or too old.
+CREATE TABLE outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    feed_id INTEGER NOT NULL,
+    item_guid TEXT, -- GUID of the item, for the delivery record
+    message TEXT NOT NULL, -- The formatted item as JSON
+    attempts INTEGER NOT NULL DEFAULT 0, -- Failed sends so far, the first included
+    last_error TEXT,
+    next_attempt_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_outbox_feed_id ON outbox(feed_id, id);
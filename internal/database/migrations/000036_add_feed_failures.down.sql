@@ -0,0 +1,3 @@
+ALTER TABLE feeds DROP COLUMN last_error_at;
+ALTER TABLE feeds DROP COLUMN last_error;
+ALTER TABLE feeds DROP COLUMN consecutive_failures;
@@ -0,0 +1,5 @@
+-- Consecutive failed fetches of a feed and the last error, reset by the next
+-- successful fetch. The scheduler backs off from failing feeds.
+ALTER TABLE feeds ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN last_error TEXT;
+ALTER TABLE feeds ADD COLUMN last_error_at DATETIME;
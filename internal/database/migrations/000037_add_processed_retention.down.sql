@@ -0,0 +1,3 @@
+ALTER TABLE feeds DROP COLUMN processed_keep;
+ALTER TABLE feeds DROP COLUMN processed_max_age_seconds;
+ALTER TABLE processed_items DROP COLUMN seen_at;
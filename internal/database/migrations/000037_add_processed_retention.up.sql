@@ -0,0 +1,7 @@
+-- When an item was last seen in its feed; NULL means processed_at. Retention
+-- never prunes items seen recently, so items still in a feed aren't sent again.
+ALTER TABLE processed_items ADD COLUMN seen_at DATETIME;
+
+-- Per-feed overrides of the global retention of processed items.
+ALTER TABLE feeds ADD COLUMN processed_max_age_seconds INTEGER;
+ALTER TABLE feeds ADD COLUMN processed_keep INTEGER;
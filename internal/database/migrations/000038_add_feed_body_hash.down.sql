@@ -0,0 +1 @@
+ALTER TABLE feeds DROP COLUMN http_body_hash;
@@ -0,0 +1,3 @@
+-- SHA-256 of the feed's last fetched body, so feeds from servers without
+-- ETag or Last-Modified still skip parsing when nothing changed.
+ALTER TABLE feeds ADD COLUMN http_body_hash TEXT;
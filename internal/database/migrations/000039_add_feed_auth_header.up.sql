@@ -0,0 +1,3 @@
+-- Name of the request header carrying the secret of feeds with auth_type
+-- 'header', e.g. X-Api-Key; the value is in auth_secret_encrypted.
+ALTER TABLE feeds ADD COLUMN auth_header TEXT;
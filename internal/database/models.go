@@ -2,6 +2,8 @@ package database
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -9,16 +11,32 @@ import (
 type Proxy struct {
 	ID                 int64     `db:"id"`
 	Name               string    `db:"name"`
-	Type               string    `db:"type"` // http, https, socks5
+	Type               string    `db:"type"` // http, https, socks5 (local DNS), socks5h (DNS on the proxy) or pac (Address is the PAC file URL)
 	Address            string    `db:"address"`
 	Username           *string   `db:"username"`
 	Password           *string   `db:"password"`
 	IsDefaultForRSS    bool      `db:"is_default_for_rss"`
 	IsDefaultForTelegram bool    `db:"is_default_for_telegram"`
+	TLS                *TLSConfig `db:"tls_config"` // Applies to every connection made through this proxy; stored as JSON
+	HealthCheckedAt    *time.Time `db:"health_checked_at"` // Last background health check
+	HealthError        *string   `db:"health_error"`      // Why the proxy is considered down; nil while healthy
+	BenchTTFBMillis    *int64    `db:"bench_ttfb_ms"`      // Median time to first byte from the last saved 'proxy bench'
+	BenchSuccessRate   *float64  `db:"bench_success_rate"` // Fraction of successful runs in the last saved 'proxy bench'
+	BenchAt            *time.Time `db:"bench_at"`
+	DirectFallback     *bool     `db:"direct_fallback"` // May traffic bypass this proxy when it fails or is down; nil follows the config
 	CreatedAt          time.Time `db:"created_at"`
 	UpdatedAt          time.Time `db:"updated_at"`
 }
 
+// AllowsDirectFallback reports whether traffic may connect directly instead
+// of through p when it fails or is down, given def from the configuration.
+func (p *Proxy) AllowsDirectFallback(def bool) bool {
+	if p.DirectFallback != nil {
+		return *p.DirectFallback
+	}
+	return def
+}
+
 // TelegramBot represents a Telegram bot configuration.
 type TelegramBot struct {
 	ID             int64     `db:"id"`
@@ -29,6 +47,46 @@ type TelegramBot struct {
 	UpdatedAt      time.Time `db:"updated_at"`
 }
 
+// BotPool groups several Telegram bots so deliveries to a destination can be
+// spread across them, multiplying throughput under Telegram's per-bot limits.
+type BotPool struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	BotIDs    []int64   // Member bots, populated by BotPool queries
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Proxy pool rotation strategies.
+const (
+	ProxyPoolRoundRobin = "round_robin" // Each fetch uses the next proxy in turn
+	ProxyPoolRandom     = "random"      // Each fetch uses a random proxy
+	ProxyPoolSticky     = "sticky"      // Each feed keeps using the same proxy while it is in the pool
+)
+
+// ProxyRule sends traffic for hosts matching Pattern through a proxy, for
+// requests that have no feed proxy or pool of their own.
+type ProxyRule struct {
+	ID        int64     `db:"id"`
+	Pattern   string    `db:"pattern"`  // Host glob; "*.example.com" also matches example.com
+	ProxyID   int64     `db:"proxy_id"`
+	Priority  int       `db:"priority"` // Higher priorities are matched first
+	Proxy     *Proxy    // Populated by ListProxyRules
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// ProxyPool groups proxies that feeds rotate through, spreading scraping
+// traffic across several exit addresses.
+type ProxyPool struct {
+	ID                   int64     `db:"id"`
+	Name                 string    `db:"name"`
+	Strategy             string    `db:"strategy"`                // ProxyPoolRoundRobin, ProxyPoolRandom or ProxyPoolSticky
+	IsDefaultForTelegram bool      `db:"is_default_for_telegram"` // Carries Telegram traffic for feeds without their own proxy
+	Proxies              []*Proxy  // Member proxies, populated by ProxyPool queries
+	CreatedAt            time.Time `db:"created_at"`
+	UpdatedAt            time.Time `db:"updated_at"`
+}
+
 // FormattingProfileConfig holds detailed formatting settings.
 type FormattingProfileConfig struct {
 	TitleTemplate             string   `json:"title_template,omitempty"`              // Go template for item title
@@ -38,8 +96,15 @@ type FormattingProfileConfig struct {
 	OmitGenericTitleRegex     string   `json:"omit_generic_title_regex,omitempty"`
 	UseTelegraphThresholdChars int      `json:"use_telegraph_threshold_chars,omitempty"` // 0 means disabled
 	ReplaceEmojiImagesWithAlt bool     `json:"replace_emoji_images_with_alt,omitempty"`
-	MediaFilterRegex          string   `json:"media_filter_regex,omitempty"`
+	MediaFilterRegex          string   `json:"media_filter_regex,omitempty"` // Media URLs matching this are never attached (e.g. tracking pixels)
 	MediaFilterCSSSelector    string   `json:"media_filter_css_selector,omitempty"`
+	ReactionEmoji             string   `json:"reaction_emoji,omitempty"`       // e.g. "🔥"; empty disables reactions
+	ReactionMatchRegex        string   `json:"reaction_match_regex,omitempty"` // React only when title or content matches; empty matches every item
+	AttachMedia               bool     `json:"attach_media,omitempty"`         // Send the item's first image or video (media:, itunes:, enclosures, inline <img>) with the message as caption
+	MediaAlbumLimit           int      `json:"media_album_limit,omitempty"`    // With attach_media, send up to this many (2-10) images and videos as an album; 0 sends only the first
+	DiscussButton             string   `json:"discuss_button,omitempty"`       // e.g. "💬 Discuss"; in channels with a discussion group, adds a button opening the post's comments
+	DigestTemplate            string   `json:"digest_template,omitempty"`      // Go template for the digests of feeds in digest mode; empty lists the items' titles
+	FullArticle               bool     `json:"full_article,omitempty"`         // Replace each new item's content with the article extracted from its linked page
 	// Add more specific media handling preferences here
 }
 
@@ -73,6 +138,64 @@ func (fp *FormattingProfile) MarshalConfig() error {
 }
 
 
+// Feed source types.
+const (
+	FeedSourceRSS    = "rss"    // RSS, Atom, or JSON Feed document
+	FeedSourceScrape = "scrape" // HTML page read with ScrapeConfig selectors
+	FeedSourceSitemap = "sitemap" // sitemap.xml or sitemap index; new or modified URLs become items
+	FeedSourceIMAP    = "imap"    // imap(s):// mailbox; new messages become items (e.g. newsletters)
+)
+
+// ScrapeConfig holds the CSS selectors used to synthesize feed items from an
+// HTML page. Title, link, date, and content selectors are relative to each item.
+type ScrapeConfig struct {
+	ItemSelector    string `json:"item_selector"`
+	TitleSelector   string `json:"title_selector,omitempty"`   // Defaults to the item's own text
+	LinkSelector    string `json:"link_selector,omitempty"`    // Defaults to the first <a href> in the item
+	DateSelector    string `json:"date_selector,omitempty"`    // Uses a datetime attribute when present, else the text
+	DateLayout      string `json:"date_layout,omitempty"`      // Go time layout; common formats are tried when empty
+	ContentSelector string `json:"content_selector,omitempty"` // Inner HTML becomes the item content
+}
+
+// TLSConfig holds TLS settings for feeds on private infrastructure, e.g.
+// internal servers signed by a corporate CA. Files are read when a client is built.
+type TLSConfig struct {
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"` // Disables certificate verification; explicit opt-in only
+	CAFile             string `json:"ca_file,omitempty"`              // PEM bundle trusted in addition to the system roots
+	MinVersion         string `json:"min_version,omitempty"`          // "1.0", "1.1", "1.2" or "1.3"
+	ClientCertFile     string `json:"client_cert_file,omitempty"`     // PEM client certificate for mutual TLS
+	ClientKeyFile      string `json:"client_key_file,omitempty"`      // PEM key for ClientCertFile
+	HTTPVersion        string `json:"http_version,omitempty"`         // "" (negotiate), "1.1", "2" or "3"
+}
+
+// IsZero reports whether c sets nothing.
+func (c *TLSConfig) IsZero() bool {
+	return c == nil || *c == TLSConfig{}
+}
+
+// FeedIcon is the cached favicon of a feed's site.
+type FeedIcon struct {
+	URL         string // Where the icon was downloaded from
+	ContentType string // Image media type, e.g. "image/png"
+	Data        []byte
+	CheckedAt   time.Time
+}
+
+// Feed auth types.
+const (
+	FeedAuthBasic  = "basic"
+	FeedAuthBearer = "bearer"
+	FeedAuthHeader = "header" // Secret sent as the value of a named header, e.g. X-Api-Key
+)
+
+// FeedCredentials are the decrypted credentials of a protected feed.
+type FeedCredentials struct {
+	Type     string // FeedAuthBasic, FeedAuthBearer or FeedAuthHeader
+	Username string // Basic auth only
+	Header   string // Header auth only: the header carrying Secret
+	Secret   string // Password for basic auth, token for bearer auth, header value for header auth
+}
+
 // Feed represents an RSS feed configuration.
 type Feed struct {
 	ID                          int64      `db:"id"`
@@ -80,14 +203,48 @@ type Feed struct {
 	UserTitle                   *string    `db:"user_title"`
 	FrequencySeconds            int        `db:"frequency_seconds"`
 	TelegramBotID               *int64     `db:"telegram_bot_id"`
+	BotPoolID                   *int64     `db:"bot_pool_id"` // When set, deliveries round-robin across the pool's bots
 	TelegramChatID              string     `db:"telegram_chat_id"`
+	ResolvedChatID              *int64     `db:"resolved_chat_id"` // Numeric ID cached for @username chats
+	ResolvedChatFor             *string    `db:"resolved_chat_for"` // The TelegramChatID that ResolvedChatID was resolved from
+	TelegramThreadID            *int       `db:"telegram_thread_id"` // Forum topic to post into
+	AutoCreateTopic             bool       `db:"auto_create_topic"`  // Create a topic named after the feed on first delivery
+	SourceType                  string     `db:"source_type"` // FeedSourceRSS, FeedSourceScrape, FeedSourceSitemap or FeedSourceIMAP
+	ScrapeConfig                *ScrapeConfig `db:"scrape_config"` // Stored as JSON; required for FeedSourceScrape
+	UserAgent                   *string    `db:"user_agent"`      // Overrides the global User-Agent when set
+	RequestHeaders              map[string]string `db:"request_headers"` // Extra headers sent when fetching; stored as JSON
+	Cookies                     map[string]string `db:"cookies"` // Configured cookies seeded into the feed's jar; stored as JSON
+	Tags                        []string   `db:"tags"` // Labels grouping the feed, e.g. its OPML folder; stored as JSON
+	NewestItemAt                *time.Time `db:"newest_item_at"`      // Publication time of the newest item seen
+	StaleAlertedAt              *time.Time `db:"stale_alerted_at"`    // Set when a stale alert was sent; cleared by new items
+	StaleAfterSeconds           *int       `db:"stale_after_seconds"` // Overrides the global stale threshold; 0 disables
+	FetchTimeoutSeconds         *int       `db:"fetch_timeout_seconds"`     // Overrides fetch.timeout when set
+	FetchMaxRetries             *int       `db:"fetch_max_retries"`         // Overrides fetch.max_retries when set
+	FetchRetryDelaySeconds      *int       `db:"fetch_retry_delay_seconds"` // Overrides fetch.retry_delay when set
+	TLS                         *TLSConfig `db:"tls_config"` // Overrides the proxy's TLS settings field by field; stored as JSON
+	UseFlareSolverr             bool       `db:"use_flaresolverr"` // Always fetch through FlareSolverr (for Cloudflare-protected sites)
+	UpdateHintSeconds           *int       `db:"update_hint_seconds"` // Update interval the feed declares (<ttl>, sy:updatePeriod); set by the worker
+	BackfillLimit               *int       `db:"backfill_limit"`           // Pending archive backfill: items to import, 0 for all; nil when none is pending
+	BackfillMaxAgeSeconds       *int       `db:"backfill_max_age_seconds"` // Skip archived items older than this; nil for no limit
+	ProcessedMaxAgeSeconds      *int       `db:"processed_max_age_seconds"` // Overrides retention.processed_max_age when set; 0 keeps processed items forever
+	ProcessedKeep               *int       `db:"processed_keep"`            // Overrides retention.processed_keep when set
+	IconCheckedAt               *time.Time `db:"icon_checked_at"` // Last icon lookup, successful or not; the icon itself is loaded with GetFeedIcon
+	AuthType                    *string    `db:"auth_type"` // FeedAuthBasic, FeedAuthBearer or FeedAuthHeader; credentials are fetched separately
 	LastProcessedItemGUIDHash *string    `db:"last_processed_item_guid_hash"`
+	ProcessedEpoch              int64      `db:"processed_epoch"` // Bumped by a trigger whenever processed items of the feed are deleted
 	LastFetchedAt               *time.Time `db:"last_fetched_at"`
+	ConsecutiveFailures         int        `db:"consecutive_failures"` // Failed fetches since the last successful one; set by the worker
+	LastError                   *string    `db:"last_error"`           // Error of the last failed fetch
+	LastErrorAt                 *time.Time `db:"last_error_at"`
 	ProxyID                     *int64     `db:"proxy_id"`
+	ProxyPoolID                 *int64     `db:"proxy_pool_id"` // When set, fetches rotate through the pool's proxies instead of ProxyID
+	ProxyDirectFallback         *bool      `db:"proxy_direct_fallback"` // Overrides the proxy's DirectFallback for this feed
+	Debug                       bool       `db:"debug"` // Log this feed's processing at debug level, headers and rendered messages included
 	FormattingProfileID         *int64     `db:"formatting_profile_id"`
 	IsEnabled                   bool       `db:"is_enabled"`
 	HTTPEtag                    *string    `db:"http_etag"`
 	HTTPLastModified            *string    `db:"http_last_modified"`
+	HTTPBodyHash                *string    `db:"http_body_hash"` // SHA-256 of the last fetched body, for servers without validators
 	CreatedAt                   time.Time  `db:"created_at"`
 	UpdatedAt                   time.Time  `db:"updated_at"`
 
@@ -97,6 +254,230 @@ type Feed struct {
 	FormattingProfile   *FormattingProfile
 }
 
+// ProcessedRetention is how long processed items are remembered: those last
+// seen in their feed more than MaxAge ago are pruned, except the feed's Keep
+// newest. A zero MaxAge keeps them forever.
+type ProcessedRetention struct {
+	MaxAge time.Duration
+	Keep   int
+}
+
+// Retention is the feed's retention of processed items: defaults, with the
+// feed's overrides applied.
+func (f *Feed) Retention(defaults ProcessedRetention) ProcessedRetention {
+	r := defaults
+	if f.ProcessedMaxAgeSeconds != nil {
+		r.MaxAge = time.Duration(*f.ProcessedMaxAgeSeconds) * time.Second
+	}
+	if f.ProcessedKeep != nil {
+		r.Keep = *f.ProcessedKeep
+	}
+	return r
+}
+
+// PollInterval is how often the feed is fetched: its configured frequency, or
+// the update interval the feed declares when that is longer.
+func (f *Feed) PollInterval() time.Duration {
+	seconds := f.FrequencySeconds
+	if f.UpdateHintSeconds != nil && *f.UpdateHintSeconds > seconds {
+		seconds = *f.UpdateHintSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// ResolvedChat returns the numeric ID cached for the feed's @username chat,
+// or nil when there is none or it was resolved for a chat the feed no longer
+// points at.
+func (f *Feed) ResolvedChat() *int64 {
+	if f.ResolvedChatID == nil || f.ResolvedChatFor == nil || *f.ResolvedChatFor != f.TelegramChatID {
+		return nil
+	}
+	return f.ResolvedChatID
+}
+
+// NormalizeTags trims tags and drops empty ones and repeats, comparing case
+// insensitively and keeping the first spelling.
+func NormalizeTags(tags []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+// Feed destination types.
+const (
+	DestinationEmail      = "email"      // Mailed through the configured SMTP server
+	DestinationWebhook    = "webhook"    // Posted as JSON to a URL
+	DestinationMattermost = "mattermost" // Posted as Markdown to a Mattermost incoming webhook
+	DestinationTelegram   = "telegram"   // Sent to another Telegram chat than the feed's
+	DestinationNtfy       = "ntfy"       // Pushed to an ntfy topic
+	DestinationPushover   = "pushover"   // Pushed to a Pushover user
+	DestinationPocket     = "pocket"     // Saved to a Pocket account
+	DestinationWallabag   = "wallabag"   // Saved to a wallabag account
+	DestinationReadwise   = "readwise"   // Saved to Readwise Reader
+)
+
+// FeedDestination is somewhere besides its Telegram chat that a feed's items
+// are delivered to.
+type FeedDestination struct {
+	ID        int64           `db:"id"`
+	FeedID    int64           `db:"feed_id"`
+	Type      string          `db:"type"`   // A Destination* type
+	Config    json.RawMessage `db:"config"` // Settings of the type's notifier, e.g. the recipients
+	CreatedAt time.Time       `db:"created_at"`
+}
+
+// Feed filter actions.
+const (
+	FilterInclude = "include" // Items must match one of the feed's include filters, if it has any
+	FilterExclude = "exclude" // Items matching any exclude filter are skipped
+)
+
+// Feed filter match types.
+const (
+	FilterKeyword = "keyword" // Whole words or phrases, ignoring case
+	FilterRegex   = "regex"   // Go regular expression
+)
+
+// Item fields feed filters match against.
+const (
+	FilterFieldTitle      = "title"
+	FilterFieldContent    = "content" // Content and description, without HTML
+	FilterFieldCategories = "categories"
+	FilterFieldAuthor     = "author"
+)
+
+// FeedFilter decides by keyword or regex which of a feed's items are sent.
+// Skipped items are recorded as processed like sent ones.
+type FeedFilter struct {
+	ID        int64     `db:"id"`
+	FeedID    int64     `db:"feed_id"`
+	Action    string    `db:"action"`     // FilterInclude or FilterExclude
+	MatchType string    `db:"match_type"` // FilterKeyword or FilterRegex
+	Pattern   string    `db:"pattern"`
+	Fields    []string  `db:"fields"` // FilterField* values; empty matches all fields
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Feed digest periods.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// FeedDigest makes a feed collect its new items and send them as one digest
+// message on schedule instead of one message per item.
+type FeedDigest struct {
+	FeedID     int64      `db:"feed_id"`
+	Period     string     `db:"period"`  // DigestDaily or DigestWeekly
+	At         string     `db:"at"`      // Local time of day to send, "HH:MM"
+	Weekday    string     `db:"weekday"` // Day of weekly digests, e.g. "monday"
+	LastSentAt *time.Time `db:"last_sent_at"` // Scheduled time of the last digest sent
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+// LastDue returns the most recent scheduled send time at or before now, and
+// the start of the period it ends.
+func (d *FeedDigest) LastDue(now time.Time) (due, from time.Time, err error) {
+	at, err := time.Parse("15:04", d.At)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("digest time %q: expected HH:MM", d.At)
+	}
+	due = time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+	days := 1
+	switch d.Period {
+	case DigestDaily:
+	case DigestWeekly:
+		weekday, ok := ParseWeekday(d.Weekday)
+		if !ok {
+			return time.Time{}, time.Time{}, fmt.Errorf("digest weekday %q: expected a day such as monday", d.Weekday)
+		}
+		days = 7
+		due = due.AddDate(0, 0, -((int(due.Weekday()) - int(weekday) + 7) % 7))
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("digest period %q: expected %s or %s", d.Period, DigestDaily, DigestWeekly)
+	}
+	if due.After(now) {
+		due = due.AddDate(0, 0, -days)
+	}
+	return due, due.AddDate(0, 0, -days), nil
+}
+
+// ParseWeekday parses a day name such as "monday" or "mon".
+func ParseWeekday(name string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(name, d.String()) || strings.EqualFold(name, d.String()[:3]) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// DigestItem is an item waiting for its feed's next digest.
+type DigestItem struct {
+	ID           int64           `db:"id"`
+	FeedID       int64           `db:"feed_id"`
+	ItemGUIDHash string          `db:"item_guid_hash"`
+	FeedTitle    string          `db:"feed_title"` // Title of the fetched feed document
+	Item         json.RawMessage `db:"item"`       // The parsed item (a gofeed.Item) as JSON
+	CreatedAt    time.Time       `db:"created_at"`
+}
+
+// OutboxMessage is a formatted item whose Telegram send failed, waiting to be
+// sent again.
+type OutboxMessage struct {
+	ID            int64           `db:"id"`
+	FeedID        int64           `db:"feed_id"`
+	ItemGUID      string          `db:"item_guid"`
+	Message       json.RawMessage `db:"message"` // The formatted item (a notify.Message) as JSON
+	Attempts      int             `db:"attempts"`
+	LastError     string          `db:"last_error"`
+	NextAttemptAt time.Time       `db:"next_attempt_at"`
+	CreatedAt     time.Time       `db:"created_at"`
+}
+
+// WebSub subscription states.
+const (
+	WebSubStatePending = "pending"
+	WebSubStateActive  = "active"
+	WebSubStateDenied  = "denied"
+)
+
+// WebSubSubscription is a feed's push subscription with a WebSub hub.
+type WebSubSubscription struct {
+	FeedID          int64      `db:"feed_id"`
+	HubURL          string     `db:"hub_url"`
+	TopicURL        string     `db:"topic_url"`
+	Secret          string     `db:"secret"` // HMAC key the hub signs content distribution requests with
+	State           string     `db:"state"`
+	LeaseExpiresAt  *time.Time `db:"lease_expires_at"`
+	PendingHubURL   *string    `db:"pending_hub_url"` // Renewal of the active subscription awaiting verification; nil without one
+	PendingTopicURL *string    `db:"pending_topic_url"`
+	PendingSecret   *string    `db:"pending_secret"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+}
+
+// StoredCookie is a cookie persisted in a feed's cookie jar.
+type StoredCookie struct {
+	FeedID    int64      `db:"feed_id"`
+	Name      string     `db:"name"`
+	Domain    string     `db:"domain"`    // Cookie domain, or the request host for host-only cookies
+	Path      string     `db:"path"`
+	HostOnly  bool       `db:"host_only"`
+	Value     string     `db:"value"`
+	Secure    bool       `db:"secure"`
+	HTTPOnly  bool       `db:"http_only"`
+	ExpiresAt *time.Time `db:"expires_at"` // nil for session cookies
+}
+
 // ProcessedItem tracks items that have been sent to Telegram.
 type ProcessedItem struct {
 	ID           int64     `db:"id"`
@@ -105,3 +486,20 @@ type ProcessedItem struct {
 	ProcessedAt  time.Time `db:"processed_at"`
 }
 
+
+// API key roles, from most to least privileged.
+const (
+	RoleAdmin  = "admin"  // Everything, including bots, proxies and API keys
+	RoleEditor = "editor" // Feeds and formatting profiles
+	RoleViewer = "viewer" // Read-only access
+)
+
+// APIKey grants access to the management API with a role. Only a hash of the
+// key is stored.
+type APIKey struct {
+	ID         int64      `db:"id"`
+	Name       string     `db:"name"`
+	Role       string     `db:"role"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// OutboxStore handles formatted items waiting to be sent again after their
+// Telegram send failed.
+type OutboxStore struct {
+	db *DB
+}
+
+// NewOutboxStore creates a new OutboxStore.
+func NewOutboxStore(db *DB) *OutboxStore {
+	return &OutboxStore{db: db}
+}
+
+// AddOutboxMessage queues a message that failed to send, to be tried again
+// at m.NextAttemptAt. It returns the ID of the new entry.
+func (s *OutboxStore) AddOutboxMessage(ctx context.Context, m *OutboxMessage) (int64, error) {
+	stmt, err := s.db.PrepareCached(ctx, `
+		INSERT INTO outbox (feed_id, item_guid, message, attempts, last_error, next_attempt_at) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("AddOutboxMessage prepare: %w", err)
+	}
+	res, err := stmt.ExecContext(ctx, m.FeedID, m.ItemGUID, string(m.Message), m.Attempts, m.LastError, m.NextAttemptAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("AddOutboxMessage exec: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// HasOutboxMessages reports whether messages of a feed are waiting in the
+// outbox, so its new items can queue behind them.
+func (s *OutboxStore) HasOutboxMessages(ctx context.Context, feedID int64) (bool, error) {
+	stmt, err := s.db.PrepareCached(ctx, `SELECT EXISTS (SELECT 1 FROM outbox WHERE feed_id = ?)`)
+	if err != nil {
+		return false, fmt.Errorf("HasOutboxMessages prepare: %w", err)
+	}
+	var exists bool
+	if err := stmt.QueryRowContext(ctx, feedID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("HasOutboxMessages scan: %w", err)
+	}
+	return exists, nil
+}
+
+// ListOutboxFeeds returns the feeds whose oldest outbox message is due at now.
+func (s *OutboxStore) ListOutboxFeeds(ctx context.Context, now time.Time) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.feed_id FROM outbox o
+		WHERE o.id = (SELECT MIN(id) FROM outbox WHERE feed_id = o.feed_id) AND o.next_attempt_at <= ?
+		ORDER BY o.id`, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("ListOutboxFeeds query: %w", err)
+	}
+	defer rows.Close()
+
+	var feedIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("ListOutboxFeeds scan: %w", err)
+		}
+		feedIDs = append(feedIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListOutboxFeeds rows error: %w", err)
+	}
+	return feedIDs, nil
+}
+
+// ListOutboxMessages returns the messages of a feed in the outbox, oldest
+// first; feedID 0 lists those of all feeds.
+func (s *OutboxStore) ListOutboxMessages(ctx context.Context, feedID int64) ([]*OutboxMessage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, feed_id, item_guid, message, attempts, last_error, next_attempt_at, created_at
+		FROM outbox WHERE ? = 0 OR feed_id = ? ORDER BY id`, feedID, feedID)
+	if err != nil {
+		return nil, fmt.Errorf("ListOutboxMessages query: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*OutboxMessage
+	for rows.Next() {
+		m := &OutboxMessage{}
+		var guid, lastError sql.NullString
+		var data string
+		if err := rows.Scan(&m.ID, &m.FeedID, &guid, &data, &m.Attempts, &lastError, &m.NextAttemptAt, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ListOutboxMessages scan: %w", err)
+		}
+		m.ItemGUID, m.LastError, m.Message = guid.String, lastError.String, []byte(data)
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListOutboxMessages rows error: %w", err)
+	}
+	return messages, nil
+}
+
+// SetOutboxRetry records another failed send of an outbox message and when
+// to try it next.
+func (s *OutboxStore) SetOutboxRetry(ctx context.Context, id int64, lastError string, next time.Time) error {
+	stmt, err := s.db.PrepareContext(ctx, `
+		UPDATE outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("SetOutboxRetry prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, lastError, next.UTC(), id); err != nil {
+		return fmt.Errorf("SetOutboxRetry exec: %w", err)
+	}
+	return nil
+}
+
+// DeleteOutboxMessage removes a message from the outbox once it was sent or
+// given up on.
+func (s *OutboxStore) DeleteOutboxMessage(ctx context.Context, id int64) error {
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM outbox WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("DeleteOutboxMessage prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("DeleteOutboxMessage exec: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("DeleteOutboxMessage: outbox message %d not found", id)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxStore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	store := NewOutboxStore(db)
+	now := time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)
+
+	has, err := store.HasOutboxMessages(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	first, err := store.AddOutboxMessage(ctx, &OutboxMessage{FeedID: 1, ItemGUID: "a", Message: json.RawMessage(`{"Title":"a"}`), Attempts: 1, LastError: "down", NextAttemptAt: now.Add(time.Minute)})
+	require.NoError(t, err)
+	_, err = store.AddOutboxMessage(ctx, &OutboxMessage{FeedID: 1, ItemGUID: "b", Message: json.RawMessage(`{"Title":"b"}`), NextAttemptAt: now})
+	require.NoError(t, err)
+	_, err = store.AddOutboxMessage(ctx, &OutboxMessage{FeedID: 2, Message: json.RawMessage(`{}`), NextAttemptAt: now})
+	require.NoError(t, err)
+
+	has, err = store.HasOutboxMessages(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, has)
+
+	feeds, err := store.ListOutboxFeeds(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, feeds, "a feed is due when its oldest message is")
+	feeds, err = store.ListOutboxFeeds(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, feeds)
+
+	require.NoError(t, store.SetOutboxRetry(ctx, first, "still down", now.Add(time.Hour)))
+	messages, err := store.ListOutboxMessages(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "a", messages[0].ItemGUID)
+	assert.Equal(t, 2, messages[0].Attempts)
+	assert.Equal(t, "still down", messages[0].LastError)
+	assert.True(t, now.Add(time.Hour).Equal(messages[0].NextAttemptAt))
+	assert.JSONEq(t, `{"Title":"a"}`, string(messages[0].Message))
+
+	all, err := store.ListOutboxMessages(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	require.NoError(t, store.DeleteOutboxMessage(ctx, first))
+	assert.Error(t, store.DeleteOutboxMessage(ctx, first))
+	messages, err = store.ListOutboxMessages(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "b", messages[0].ItemGUID)
+}
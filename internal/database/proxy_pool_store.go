@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// proxyPoolColumns lists the proxy_pools columns in the order pool queries scan them.
+const proxyPoolColumns = `id, name, strategy, is_default_for_telegram, created_at, updated_at`
+
+// CreatePool adds a new, empty proxy pool rotating with strategy.
+func (s *ProxyStore) CreatePool(ctx context.Context, name, strategy string) (int64, error) {
+	stmt, err := s.db.PrepareContext(ctx, `INSERT INTO proxy_pools (name, strategy) VALUES (?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("CreatePool prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, name, strategy)
+	if err != nil {
+		return 0, fmt.Errorf("CreatePool exec: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// AddProxyToPool adds a proxy to a pool. Adding a proxy that is already a member is a no-op.
+func (s *ProxyStore) AddProxyToPool(ctx context.Context, poolID, proxyID int64) error {
+	stmt, err := s.db.PrepareContext(ctx, `INSERT OR IGNORE INTO proxy_pool_members (pool_id, proxy_id) VALUES (?, ?)`)
+	if err != nil {
+		return fmt.Errorf("AddProxyToPool prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, poolID, proxyID); err != nil {
+		return fmt.Errorf("AddProxyToPool exec for pool %d, proxy %d: %w", poolID, proxyID, err)
+	}
+	return nil
+}
+
+// RemoveProxyFromPool removes a proxy from a pool.
+func (s *ProxyStore) RemoveProxyFromPool(ctx context.Context, poolID, proxyID int64) error {
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM proxy_pool_members WHERE pool_id = ? AND proxy_id = ?`)
+	if err != nil {
+		return fmt.Errorf("RemoveProxyFromPool prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, poolID, proxyID)
+	if err != nil {
+		return fmt.Errorf("RemoveProxyFromPool exec for pool %d, proxy %d: %w", poolID, proxyID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("RemoveProxyFromPool: proxy %d is not a member of pool %d", proxyID, poolID)
+	}
+	return nil
+}
+
+// GetPoolProxies returns the proxies in a pool, best benchmarked first (by
+// success rate, then TTFB) and unbenchmarked ones by proxy ID after them.
+func (s *ProxyStore) GetPoolProxies(ctx context.Context, poolID int64) ([]*Proxy, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+proxyColumns+` FROM proxies
+		WHERE id IN (SELECT proxy_id FROM proxy_pool_members WHERE pool_id = ?)
+		ORDER BY bench_success_rate IS NULL, bench_success_rate DESC, bench_ttfb_ms, id`, poolID)
+	if err != nil {
+		return nil, fmt.Errorf("GetPoolProxies query: %w", err)
+	}
+	defer rows.Close()
+
+	var proxies []*Proxy
+	for rows.Next() {
+		p := &Proxy{}
+		if err := scanProxy(rows, p); err != nil {
+			return nil, fmt.Errorf("GetPoolProxies scan: %w", err)
+		}
+		proxies = append(proxies, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetPoolProxies rows error: %w", err)
+	}
+	return proxies, nil
+}
+
+// GetPoolByID retrieves a proxy pool and its member proxies.
+func (s *ProxyStore) GetPoolByID(ctx context.Context, id int64) (*ProxyPool, error) {
+	pool := &ProxyPool{}
+	err := s.db.QueryRowContext(ctx, `SELECT `+proxyPoolColumns+` FROM proxy_pools WHERE id = ?`, id).
+		Scan(&pool.ID, &pool.Name, &pool.Strategy, &pool.IsDefaultForTelegram, &pool.CreatedAt, &pool.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("GetPoolByID scan: %w", err)
+	}
+	if pool.Proxies, err = s.GetPoolProxies(ctx, id); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// ListPools retrieves all proxy pools with their member proxies.
+func (s *ProxyStore) ListPools(ctx context.Context) ([]*ProxyPool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+proxyPoolColumns+` FROM proxy_pools ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("ListPools query: %w", err)
+	}
+	defer rows.Close()
+
+	var pools []*ProxyPool
+	for rows.Next() {
+		pool := &ProxyPool{}
+		if err := rows.Scan(&pool.ID, &pool.Name, &pool.Strategy, &pool.IsDefaultForTelegram, &pool.CreatedAt, &pool.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("ListPools scan: %w", err)
+		}
+		pools = append(pools, pool)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListPools rows error: %w", err)
+	}
+	rows.Close()
+
+	for _, pool := range pools {
+		if pool.Proxies, err = s.GetPoolProxies(ctx, pool.ID); err != nil {
+			return nil, err
+		}
+	}
+	return pools, nil
+}
+
+// SetTelegramPool makes poolID the pool that carries Telegram traffic, replacing
+// any previous one. A poolID of 0 clears it.
+func (s *ProxyStore) SetTelegramPool(ctx context.Context, poolID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("SetTelegramPool begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE proxy_pools SET is_default_for_telegram = FALSE WHERE is_default_for_telegram = TRUE`); err != nil {
+		return fmt.Errorf("SetTelegramPool clear: %w", err)
+	}
+	if poolID != 0 {
+		res, err := tx.ExecContext(ctx, `UPDATE proxy_pools SET is_default_for_telegram = TRUE WHERE id = ?`, poolID)
+		if err != nil {
+			return fmt.Errorf("SetTelegramPool exec for pool %d: %w", poolID, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("SetTelegramPool: pool %d not found", poolID)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetTelegramPool retrieves the pool that carries Telegram traffic, with its
+// member proxies, or nil if none is set.
+func (s *ProxyStore) GetTelegramPool(ctx context.Context) (*ProxyPool, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM proxy_pools WHERE is_default_for_telegram = TRUE LIMIT 1`).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("GetTelegramPool scan: %w", err)
+	}
+	return s.GetPoolByID(ctx, id)
+}
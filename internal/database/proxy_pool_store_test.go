@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyPools(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	store := NewProxyStore(db)
+	ctx := context.Background()
+
+	first, err := store.CreateProxy(ctx, &Proxy{Name: "a", Type: "http", Address: "10.0.0.1:8080"})
+	require.NoError(t, err)
+	second, err := store.CreateProxy(ctx, &Proxy{Name: "b", Type: "socks5", Address: "10.0.0.2:1080"})
+	require.NoError(t, err)
+
+	poolID, err := store.CreatePool(ctx, "scrapers", ProxyPoolSticky)
+	require.NoError(t, err)
+	require.NoError(t, store.AddProxyToPool(ctx, poolID, second))
+	require.NoError(t, store.AddProxyToPool(ctx, poolID, first))
+	require.NoError(t, store.AddProxyToPool(ctx, poolID, first), "re-adding a member is a no-op")
+
+	pool, err := store.GetPoolByID(ctx, poolID)
+	require.NoError(t, err)
+	require.NotNil(t, pool)
+	assert.Equal(t, ProxyPoolSticky, pool.Strategy)
+	require.Len(t, pool.Proxies, 2)
+	assert.Equal(t, "a", pool.Proxies[0].Name)
+	assert.Equal(t, "socks5", pool.Proxies[1].Type)
+
+	require.NoError(t, store.RemoveProxyFromPool(ctx, poolID, first))
+	assert.Error(t, store.RemoveProxyFromPool(ctx, poolID, first))
+	pools, err := store.ListPools(ctx)
+	require.NoError(t, err)
+	require.Len(t, pools, 1)
+	require.Len(t, pools[0].Proxies, 1)
+	assert.Equal(t, second, pools[0].Proxies[0].ID)
+
+	missing, err := store.GetPoolByID(ctx, poolID+1)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestTelegramPool(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	store := NewProxyStore(db)
+	ctx := context.Background()
+
+	none, err := store.GetTelegramPool(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, none)
+
+	proxyID, err := store.CreateProxy(ctx, &Proxy{Name: "tg", Type: "socks5h", Address: "10.0.0.3:1080"})
+	require.NoError(t, err)
+	first, err := store.CreatePool(ctx, "tg-a", ProxyPoolRoundRobin)
+	require.NoError(t, err)
+	second, err := store.CreatePool(ctx, "tg-b", ProxyPoolRoundRobin)
+	require.NoError(t, err)
+	require.NoError(t, store.AddProxyToPool(ctx, second, proxyID))
+
+	require.NoError(t, store.SetTelegramPool(ctx, first))
+	require.NoError(t, store.SetTelegramPool(ctx, second))
+	pool, err := store.GetTelegramPool(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, pool)
+	assert.Equal(t, second, pool.ID, "setting a pool replaces the previous one")
+	assert.True(t, pool.IsDefaultForTelegram)
+	require.Len(t, pool.Proxies, 1)
+
+	assert.Error(t, store.SetTelegramPool(ctx, second+1))
+	require.NoError(t, store.SetTelegramPool(ctx, 0))
+	pool, err = store.GetTelegramPool(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, pool)
+}
+
+func TestProxyRules(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	store := NewProxyStore(db)
+	ctx := context.Background()
+
+	proxyID, err := store.CreateProxy(ctx, &Proxy{Name: "tor", Type: "socks5h", Address: "127.0.0.1:9050"})
+	require.NoError(t, err)
+	low, err := store.CreateProxyRule(ctx, "*", proxyID, 0)
+	require.NoError(t, err)
+	high, err := store.CreateProxyRule(ctx, "*.onion", proxyID, 10)
+	require.NoError(t, err)
+
+	rules, err := store.ListProxyRules(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, high, rules[0].ID, "higher priorities come first")
+	require.NotNil(t, rules[0].Proxy)
+	assert.Equal(t, "tor", rules[0].Proxy.Name)
+
+	require.NoError(t, store.DeleteProxyRule(ctx, low))
+	assert.Error(t, store.DeleteProxyRule(ctx, low))
+	rules, err = store.ListProxyRules(ctx)
+	require.NoError(t, err)
+	assert.Len(t, rules, 1)
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// CreateProxyRule routes hosts matching pattern through proxyID.
+func (s *ProxyStore) CreateProxyRule(ctx context.Context, pattern string, proxyID int64, priority int) (int64, error) {
+	stmt, err := s.db.PrepareContext(ctx, `INSERT INTO proxy_rules (pattern, proxy_id, priority) VALUES (?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("CreateProxyRule prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, pattern, proxyID, priority)
+	if err != nil {
+		return 0, fmt.Errorf("CreateProxyRule exec: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// DeleteProxyRule removes a proxy rule.
+func (s *ProxyStore) DeleteProxyRule(ctx context.Context, id int64) error {
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM proxy_rules WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("DeleteProxyRule prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("DeleteProxyRule exec for rule %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("DeleteProxyRule: rule %d not found", id)
+	}
+	return nil
+}
+
+// ListProxyRules retrieves all proxy rules with their proxies, in matching
+// order: highest priority first, then oldest first.
+func (s *ProxyStore) ListProxyRules(ctx context.Context) ([]*ProxyRule, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, pattern, proxy_id, priority, created_at FROM proxy_rules ORDER BY priority DESC, id`)
+	if err != nil {
+		return nil, fmt.Errorf("ListProxyRules query: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*ProxyRule
+	for rows.Next() {
+		rule := &ProxyRule{}
+		if err := rows.Scan(&rule.ID, &rule.Pattern, &rule.ProxyID, &rule.Priority, &rule.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ListProxyRules scan: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListProxyRules rows error: %w", err)
+	}
+	rows.Close()
+
+	for _, rule := range rules {
+		if rule.Proxy, err = s.GetProxyByID(ctx, rule.ProxyID); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 )
 
 // ProxyStore provides methods to interact with proxy configurations.
@@ -16,29 +17,137 @@ func NewProxyStore(db *DB) *ProxyStore {
 	return &ProxyStore{db: db}
 }
 
+// proxyColumns lists the proxies columns in the order expected by scanProxy.
+const proxyColumns = `id, name, type, address, username, password, is_default_for_rss, is_default_for_telegram, tls_config, health_checked_at, health_error, bench_ttfb_ms, bench_success_rate, bench_at, direct_fallback, created_at, updated_at`
+
+// scanProxy scans a row selected with proxyColumns.
+func scanProxy(scanner interface{ Scan(...interface{}) error }, p *Proxy) error {
+	var tlsConfigJSON sql.NullString
+	if err := scanner.Scan(&p.ID, &p.Name, &p.Type, &p.Address, &p.Username, &p.Password, &p.IsDefaultForRSS, &p.IsDefaultForTelegram, &tlsConfigJSON, &p.HealthCheckedAt, &p.HealthError, &p.BenchTTFBMillis, &p.BenchSuccessRate, &p.BenchAt, &p.DirectFallback, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return err
+	}
+	var err error
+	if p.TLS, err = unmarshalTLSConfig(tlsConfigJSON); err != nil {
+		return fmt.Errorf("failed to unmarshal TLS config for proxy %d: %w", p.ID, err)
+	}
+	return nil
+}
+
 // CreateProxy adds a new proxy.
 func (s *ProxyStore) CreateProxy(ctx context.Context, p *Proxy) (int64, error) {
 	stmt, err := s.db.PrepareContext(ctx, `
-		INSERT INTO proxies (name, type, address, username, password, is_default_for_rss, is_default_for_telegram)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+		INSERT INTO proxies (name, type, address, username, password, is_default_for_rss, is_default_for_telegram, tls_config, direct_fallback)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return 0, fmt.Errorf("CreateProxy prepare: %w", err)
 	}
 	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, p.Name, p.Type, p.Address, p.Username, p.Password, p.IsDefaultForRSS, p.IsDefaultForTelegram)
+	tlsConfig, err := marshalTLSConfig(p.TLS)
+	if err != nil {
+		return 0, fmt.Errorf("CreateProxy: %w", err)
+	}
+	res, err := stmt.ExecContext(ctx, p.Name, p.Type, p.Address, p.Username, p.Password, p.IsDefaultForRSS, p.IsDefaultForTelegram, tlsConfig, p.DirectFallback)
 	if err != nil {
 		return 0, fmt.Errorf("CreateProxy exec: %w", err)
 	}
 	return res.LastInsertId()
 }
 
+// CreateProxies adds several proxies in one transaction, setting their IDs.
+func (s *ProxyStore) CreateProxies(ctx context.Context, proxies []*Proxy) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("CreateProxies begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO proxies (name, type, address, username, password, is_default_for_rss, is_default_for_telegram, tls_config, direct_fallback)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("CreateProxies prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, p := range proxies {
+		tlsConfig, err := marshalTLSConfig(p.TLS)
+		if err != nil {
+			return fmt.Errorf("CreateProxies %s: %w", p.Name, err)
+		}
+		res, err := stmt.ExecContext(ctx, p.Name, p.Type, p.Address, p.Username, p.Password, p.IsDefaultForRSS, p.IsDefaultForTelegram, tlsConfig, p.DirectFallback)
+		if err != nil {
+			return fmt.Errorf("CreateProxies exec for %s: %w", p.Name, err)
+		}
+		if p.ID, err = res.LastInsertId(); err != nil {
+			return fmt.Errorf("CreateProxies id for %s: %w", p.Name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("CreateProxies commit: %w", err)
+	}
+	return nil
+}
+
+// UpdateProxy saves p's settings. Health and bench results are left untouched.
+func (s *ProxyStore) UpdateProxy(ctx context.Context, p *Proxy) error {
+	stmt, err := s.db.PrepareContext(ctx, `
+		UPDATE proxies
+		SET name = ?, type = ?, address = ?, username = ?, password = ?, is_default_for_rss = ?, is_default_for_telegram = ?, tls_config = ?, direct_fallback = ?
+		WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("UpdateProxy prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	tlsConfig, err := marshalTLSConfig(p.TLS)
+	if err != nil {
+		return fmt.Errorf("UpdateProxy: %w", err)
+	}
+	res, err := stmt.ExecContext(ctx, p.Name, p.Type, p.Address, p.Username, p.Password, p.IsDefaultForRSS, p.IsDefaultForTelegram, tlsConfig, p.DirectFallback, p.ID)
+	if err != nil {
+		return fmt.Errorf("UpdateProxy exec for proxy %d: %w", p.ID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("UpdateProxy: proxy %d not found", p.ID)
+	}
+	return nil
+}
+
+// DeleteProxy removes a proxy along with its pool memberships and rules.
+// Feeds that used it fall back to the default proxies.
+func (s *ProxyStore) DeleteProxy(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteProxy begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE feeds SET proxy_id = NULL WHERE proxy_id = ?`, id); err != nil {
+		return fmt.Errorf("DeleteProxy clear feeds: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM proxy_pool_members WHERE proxy_id = ?`, id); err != nil {
+		return fmt.Errorf("DeleteProxy pool members: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM proxy_rules WHERE proxy_id = ?`, id); err != nil {
+		return fmt.Errorf("DeleteProxy rules: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM proxies WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("DeleteProxy exec for proxy %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("DeleteProxy: proxy %d not found", id)
+	}
+	return tx.Commit()
+}
+
 // GetProxyByID retrieves a proxy by its ID.
 func (s *ProxyStore) GetProxyByID(ctx context.Context, id int64) (*Proxy, error) {
-	query := `SELECT id, name, type, address, username, password, is_default_for_rss, is_default_for_telegram, created_at, updated_at FROM proxies WHERE id = ?`
+	query := `SELECT ` + proxyColumns + ` FROM proxies WHERE id = ?`
 	row := s.db.QueryRowContext(ctx, query, id)
 	p := &Proxy{}
-	err := row.Scan(&p.ID, &p.Name, &p.Type, &p.Address, &p.Username, &p.Password, &p.IsDefaultForRSS, &p.IsDefaultForTelegram, &p.CreatedAt, &p.UpdatedAt)
+	err := scanProxy(row, p)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Or a custom ErrNotFound
@@ -53,16 +162,16 @@ func (s *ProxyStore) GetDefaultProxy(ctx context.Context, forType string) (*Prox
 	var query string
 	switch forType {
 	case "rss":
-		query = `SELECT id, name, type, address, username, password, is_default_for_rss, is_default_for_telegram, created_at, updated_at FROM proxies WHERE is_default_for_rss = TRUE LIMIT 1`
+		query = `SELECT ` + proxyColumns + ` FROM proxies WHERE is_default_for_rss = TRUE LIMIT 1`
 	case "telegram":
-		query = `SELECT id, name, type, address, username, password, is_default_for_rss, is_default_for_telegram, created_at, updated_at FROM proxies WHERE is_default_for_telegram = TRUE LIMIT 1`
+		query = `SELECT ` + proxyColumns + ` FROM proxies WHERE is_default_for_telegram = TRUE LIMIT 1`
 	default:
 		return nil, fmt.Errorf("invalid default proxy type: %s", forType)
 	}
 	
 	row := s.db.QueryRowContext(ctx, query)
 	p := &Proxy{}
-	err := row.Scan(&p.ID, &p.Name, &p.Type, &p.Address, &p.Username, &p.Password, &p.IsDefaultForRSS, &p.IsDefaultForTelegram, &p.CreatedAt, &p.UpdatedAt)
+	err := scanProxy(row, p)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil 
@@ -75,7 +184,7 @@ func (s *ProxyStore) GetDefaultProxy(ctx context.Context, forType string) (*Prox
 
 // ListProxies retrieves all proxies.
 func (s *ProxyStore) ListProxies(ctx context.Context) ([]*Proxy, error) {
-	query := `SELECT id, name, type, address, username, password, is_default_for_rss, is_default_for_telegram, created_at, updated_at FROM proxies ORDER BY name`
+	query := `SELECT ` + proxyColumns + ` FROM proxies ORDER BY name`
 	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("ListProxies query: %w", err)
@@ -85,7 +194,7 @@ func (s *ProxyStore) ListProxies(ctx context.Context) ([]*Proxy, error) {
 	var proxies []*Proxy
 	for rows.Next() {
 		p := &Proxy{}
-		err := rows.Scan(&p.ID, &p.Name, &p.Type, &p.Address, &p.Username, &p.Password, &p.IsDefaultForRSS, &p.IsDefaultForTelegram, &p.CreatedAt, &p.UpdatedAt)
+		err := scanProxy(rows, p)
 		if err != nil {
 			return nil, fmt.Errorf("ListProxies scan: %w", err)
 		}
@@ -97,5 +206,32 @@ func (s *ProxyStore) ListProxies(ctx context.Context) ([]*Proxy, error) {
 	return proxies, nil
 }
 
+// SetProxyHealth records a health check of a proxy. healthErr is nil while
+// the proxy is considered healthy.
+func (s *ProxyStore) SetProxyHealth(ctx context.Context, id int64, checkedAt time.Time, healthErr *string) error {
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE proxies SET health_checked_at = ?, health_error = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("SetProxyHealth prepare: %w", err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.ExecContext(ctx, checkedAt, healthErr, id); err != nil {
+		return fmt.Errorf("SetProxyHealth exec for proxy %d: %w", id, err)
+	}
+	return nil
+}
+
+// SetProxyBench stores the result of a 'proxy bench' run.
+func (s *ProxyStore) SetProxyBench(ctx context.Context, id int64, ttfb time.Duration, successRate float64, at time.Time) error {
+	stmt, err := s.db.PrepareContext(ctx, `UPDATE proxies SET bench_ttfb_ms = ?, bench_success_rate = ?, bench_at = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("SetProxyBench prepare: %w", err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.ExecContext(ctx, ttfb.Milliseconds(), successRate, at, id); err != nil {
+		return fmt.Errorf("SetProxyBench exec for proxy %d: %w", id, err)
+	}
+	return nil
+}
+
 // UpdateProxy updates an existing proxy. (Implement as needed)
 // DeleteProxy deletes a proxy. (Implement as needed)
\ No newline at end of file
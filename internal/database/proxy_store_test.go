@@ -12,34 +12,14 @@ import (
 )
 
 // setupTestDB creates a temporary SQLite DB for testing.
-func setupTestDB(t *testing.T) (*DB, func()) {
+func setupTestDB(t testing.TB) (*DB, func()) {
 	t.Helper()
 	// Create a temporary directory for the test database
 	tempDir, err := os.MkdirTemp("", "testdb_*")
 	require.NoError(t, err, "Failed to create temp dir for test DB")
 
 	dbPath := filepath.Join(tempDir, "test.db")
-	// Use a relative path to where your migrations are located from the test file
-	// Adjust this path based on your project structure and where the test is run from.
-	// If tests are run from project root, this might be "internal/database/migrations"
-	migrationsPath := "migrations" // Assuming migrations are in a 'migrations' subdir relative to this test file
-                                     // Or provide an absolute path or make it configurable.
-
-
-	// Check if migrations directory exists relative to test execution
-	// This is often tricky. A common way is to set an env var or find path relative to go.mod.
-	// For now, let's assume it can be found or this test is run from a dir where 'migrations' is accessible.
-	// If `go test ./internal/database/...` is run from project root:
-	projectRootMigrationsPath := filepath.Join("..", "..", "internal", "database", "migrations") // Adjust based on test file location
-    if _, statErr := os.Stat(projectRootMigrationsPath); statErr == nil {
-        migrationsPath = projectRootMigrationsPath
-    } else {
-         t.Logf("Could not find migrations at %s, trying relative 'migrations/' path. Error: %v", projectRootMigrationsPath, statErr)
-         // If the relative path 'migrations' also fails, Connect might error out or skip migrations.
-    }
-
-
-	db, err := Connect(dbPath, migrationsPath)
+	db, err := Connect(dbPath)
 	require.NoError(t, err, "Failed to connect to test DB")
 
 	cleanup := func() {
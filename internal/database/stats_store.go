@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// statsHourLayout is the format of feed_stats.hour; its values sort by time.
+const statsHourLayout = "2006-01-02T15"
+
+// FeedStats sums one feed's runs over a period.
+type FeedStats struct {
+	FeedID    int64
+	URL       string
+	Title     *string // The feed's user title, if set
+	Enabled   bool
+	Runs      int64 // Scheduled and pushed runs, failed ones included
+	Failures  int64
+	Items     int64   // Items delivered
+	LastError *string // Most recent failure in the period
+}
+
+// StatsStore records per-feed run statistics.
+type StatsStore struct {
+	db *DB
+}
+
+// NewStatsStore creates a new StatsStore.
+func NewStatsStore(db *DB) *StatsStore {
+	return &StatsStore{db: db}
+}
+
+// RecordRun counts a run of a feed at the given time, as a failure when
+// runErr is not nil.
+func (s *StatsStore) RecordRun(ctx context.Context, feedID int64, at time.Time, runErr error) error {
+	stmt, err := s.db.PrepareCached(ctx, `
+		INSERT INTO feed_stats (feed_id, hour, runs, failures, last_error) VALUES (?, ?, 1, ?, ?)
+		ON CONFLICT (feed_id, hour) DO UPDATE SET
+			runs = runs + 1,
+			failures = failures + excluded.failures,
+			last_error = COALESCE(excluded.last_error, last_error)`)
+	if err != nil {
+		return fmt.Errorf("RecordRun prepare: %w", err)
+	}
+
+	var failures int
+	var lastError sql.NullString
+	if runErr != nil {
+		failures = 1
+		lastError = sql.NullString{String: runErr.Error(), Valid: true}
+	}
+	if _, err := stmt.ExecContext(ctx, feedID, at.UTC().Format(statsHourLayout), failures, lastError); err != nil {
+		return fmt.Errorf("RecordRun exec: %w", err)
+	}
+	return nil
+}
+
+// FeedStatsSince sums the runs of every feed from the hour containing since,
+// and counts the items delivered from since. Feeds without runs are included.
+func (s *StatsStore) FeedStatsSince(ctx context.Context, since time.Time) ([]*FeedStats, error) {
+	hour := since.UTC().Format(statsHourLayout)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT f.id, f.url, f.user_title, f.is_enabled,
+		       COALESCE(SUM(st.runs), 0), COALESCE(SUM(st.failures), 0),
+		       (SELECT COUNT(*) FROM processed_items p WHERE p.feed_id = f.id AND p.processed_at >= ?),
+		       (SELECT last_error FROM feed_stats le WHERE le.feed_id = f.id AND le.hour >= ? AND le.last_error IS NOT NULL
+		        ORDER BY le.hour DESC LIMIT 1)
+		FROM feeds f
+		LEFT JOIN feed_stats st ON st.feed_id = f.id AND st.hour >= ?
+		GROUP BY f.id
+		ORDER BY f.id`, since, hour, hour)
+	if err != nil {
+		return nil, fmt.Errorf("FeedStatsSince query: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []*FeedStats
+	for rows.Next() {
+		fs := &FeedStats{}
+		if err := rows.Scan(&fs.FeedID, &fs.URL, &fs.Title, &fs.Enabled, &fs.Runs, &fs.Failures, &fs.Items, &fs.LastError); err != nil {
+			return nil, fmt.Errorf("FeedStatsSince scan: %w", err)
+		}
+		stats = append(stats, fs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("FeedStatsSince rows error: %w", err)
+	}
+	return stats, nil
+}
+
+// PruneFeedStats deletes run statistics from before the hour containing
+// before, returning how many hourly rows were removed.
+func (s *StatsStore) PruneFeedStats(ctx context.Context, before time.Time) (int64, error) {
+	stmt, err := s.db.PrepareContext(ctx, `DELETE FROM feed_stats WHERE hour < ?`)
+	if err != nil {
+		return 0, fmt.Errorf("PruneFeedStats prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, before.UTC().Format(statsHourLayout))
+	if err != nil {
+		return 0, fmt.Errorf("PruneFeedStats exec: %w", err)
+	}
+	return res.RowsAffected()
+}
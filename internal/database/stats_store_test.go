@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsStore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	feeds := NewFeedStore(db)
+	busyID, err := feeds.CreateFeed(ctx, &Feed{URL: "https://example.com/busy.xml", FrequencySeconds: 300, TelegramChatID: "1", IsEnabled: true})
+	require.NoError(t, err)
+	idleID, err := feeds.CreateFeed(ctx, &Feed{URL: "https://example.com/idle.xml", FrequencySeconds: 300, TelegramChatID: "1"})
+	require.NoError(t, err)
+
+	store := NewStatsStore(db)
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	require.NoError(t, store.RecordRun(ctx, busyID, old, errors.New("stale")))
+	require.NoError(t, store.RecordRun(ctx, busyID, now, nil))
+	require.NoError(t, store.RecordRun(ctx, busyID, now, errors.New("timeout")))
+	require.NoError(t, store.RecordRun(ctx, busyID, now, nil))
+	require.NoError(t, feeds.AddProcessedItems(ctx, busyID, []string{"a", "b"}))
+
+	stats, err := store.FeedStatsSince(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	busy, idle := stats[0], stats[1]
+	assert.Equal(t, busyID, busy.FeedID)
+	assert.True(t, busy.Enabled)
+	assert.EqualValues(t, 3, busy.Runs)
+	assert.EqualValues(t, 1, busy.Failures)
+	assert.EqualValues(t, 2, busy.Items)
+	require.NotNil(t, busy.LastError)
+	assert.Equal(t, "timeout", *busy.LastError)
+	assert.Equal(t, idleID, idle.FeedID)
+	assert.False(t, idle.Enabled)
+	assert.Zero(t, idle.Runs)
+	assert.Nil(t, idle.LastError)
+
+	removed, err := store.PruneFeedStats(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, removed)
+}
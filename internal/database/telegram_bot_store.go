@@ -229,4 +229,46 @@ func (s *TelegramBotStore) ListBots(ctx context.Context) ([]*TelegramBot, error)
 	}
 	if err = rows.Err(); err != nil { return nil, fmt.Errorf("ListBots rows error: %w", err) }
 	return bots, nil
-}
\ No newline at end of file
+}
+
+// TokenHash returns the hash under which a bot with rawToken is stored.
+func TokenHash(rawToken string) string {
+	return hashToken(rawToken)
+}
+
+// SetBotDescription replaces a bot's description; nil clears it.
+func (s *TelegramBotStore) SetBotDescription(ctx context.Context, id int64, description *string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE telegram_bots SET description = ? WHERE id = ?`, description, id)
+	if err != nil {
+		return fmt.Errorf("SetBotDescription exec for bot %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("SetBotDescription: bot %d not found", id)
+	}
+	return nil
+}
+
+// DeleteBot removes a bot and its pool memberships. Feeds that used it are
+// left without a bot until one is assigned.
+func (s *TelegramBotStore) DeleteBot(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteBot begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE feeds SET telegram_bot_id = NULL WHERE telegram_bot_id = ?`, id); err != nil {
+		return fmt.Errorf("DeleteBot clear feeds: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM bot_pool_members WHERE bot_id = ?`, id); err != nil {
+		return fmt.Errorf("DeleteBot pool members: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM telegram_bots WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("DeleteBot exec for bot %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("DeleteBot: bot %d not found", id)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// WebSubStore provides methods to interact with WebSub subscriptions in the database.
+type WebSubStore struct {
+	db *DB
+}
+
+// NewWebSubStore creates a new WebSubStore.
+func NewWebSubStore(db *DB) *WebSubStore {
+	return &WebSubStore{db: db}
+}
+
+// SaveSubscription creates or replaces the subscription for sub.FeedID. The
+// subscription starts out pending with no lease until the hub verifies it. An
+// active subscription is kept, with its secret, until ActivateSubscription:
+// sub is stored as its pending renewal so pushes keep being accepted meanwhile.
+func (s *WebSubStore) SaveSubscription(ctx context.Context, sub *WebSubSubscription) error {
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO websub_subscriptions (feed_id, hub_url, topic_url, secret, state, lease_expires_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, NULL)
+		ON CONFLICT(feed_id) DO UPDATE SET
+			hub_url = CASE WHEN state = ?6 THEN hub_url ELSE excluded.hub_url END,
+			topic_url = CASE WHEN state = ?6 THEN topic_url ELSE excluded.topic_url END,
+			secret = CASE WHEN state = ?6 THEN secret ELSE excluded.secret END,
+			lease_expires_at = CASE WHEN state = ?6 THEN lease_expires_at ELSE excluded.lease_expires_at END,
+			pending_hub_url = CASE WHEN state = ?6 THEN excluded.hub_url END,
+			pending_topic_url = CASE WHEN state = ?6 THEN excluded.topic_url END,
+			pending_secret = CASE WHEN state = ?6 THEN excluded.secret END,
+			state = CASE WHEN state = ?6 THEN state ELSE excluded.state END`)
+	if err != nil {
+		return fmt.Errorf("SaveSubscription prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, sub.FeedID, sub.HubURL, sub.TopicURL, sub.Secret, WebSubStatePending, WebSubStateActive); err != nil {
+		return fmt.Errorf("SaveSubscription exec for feed ID %d: %w", sub.FeedID, err)
+	}
+	return nil
+}
+
+// GetSubscription returns the subscription for a feed, or nil if there is none.
+func (s *WebSubStore) GetSubscription(ctx context.Context, feedID int64) (*WebSubSubscription, error) {
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT feed_id, hub_url, topic_url, secret, state, lease_expires_at,
+			pending_hub_url, pending_topic_url, pending_secret, created_at, updated_at
+		FROM websub_subscriptions WHERE feed_id = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("GetSubscription prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	sub := &WebSubSubscription{}
+	err = stmt.QueryRowContext(ctx, feedID).Scan(&sub.FeedID, &sub.HubURL, &sub.TopicURL, &sub.Secret, &sub.State,
+		&sub.LeaseExpiresAt, &sub.PendingHubURL, &sub.PendingTopicURL, &sub.PendingSecret, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("GetSubscription scan: %w", err)
+	}
+	return sub, nil
+}
+
+// ActivateSubscription marks a subscription verified by the hub, with its lease
+// expiry. A pending renewal replaces the subscription's hub, topic and secret.
+func (s *WebSubStore) ActivateSubscription(ctx context.Context, feedID int64, leaseExpiresAt time.Time) error {
+	stmt, err := s.db.PrepareContext(ctx, `
+		UPDATE websub_subscriptions SET state = ?, lease_expires_at = ?,
+			hub_url = COALESCE(pending_hub_url, hub_url), topic_url = COALESCE(pending_topic_url, topic_url),
+			secret = COALESCE(pending_secret, secret),
+			pending_hub_url = NULL, pending_topic_url = NULL, pending_secret = NULL
+		WHERE feed_id = ?`)
+	if err != nil {
+		return fmt.Errorf("ActivateSubscription prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, WebSubStateActive, leaseExpiresAt, feedID); err != nil {
+		return fmt.Errorf("ActivateSubscription exec for feed ID %d: %w", feedID, err)
+	}
+	return nil
+}
+
+// SetSubscriptionState updates a subscription's state, e.g. to WebSubStateDenied,
+// and drops any pending renewal.
+func (s *WebSubStore) SetSubscriptionState(ctx context.Context, feedID int64, state string) error {
+	stmt, err := s.db.PrepareContext(ctx, `
+		UPDATE websub_subscriptions SET state = ?, pending_hub_url = NULL, pending_topic_url = NULL, pending_secret = NULL
+		WHERE feed_id = ?`)
+	if err != nil {
+		return fmt.Errorf("SetSubscriptionState prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, state, feedID); err != nil {
+		return fmt.Errorf("SetSubscriptionState exec for feed ID %d: %w", feedID, err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Package errorreport sends panics and persistent feed errors to Sentry (or
+// any service speaking its protocol), with feed context attached.
+package errorreport
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// flushTimeout bounds how long Flush waits for pending events.
+const flushTimeout = 5 * time.Second
+
+// Reporter reports errors worth a human's attention. Failing feed runs are
+// reported once a feed has failed RepeatThreshold times in a row, then not
+// again until it recovers, so a feed that stays down doesn't flood the sink.
+// A nil *Reporter reports nothing, so callers need no checks.
+type Reporter struct {
+	client      *sentryClient
+	environment string
+	serverName  string
+	sampleRate  float64
+	threshold   int
+
+	mu      sync.Mutex
+	streaks map[int64]int // Consecutive failed runs by feed ID
+	pending sync.WaitGroup
+}
+
+// New creates a Reporter for cfg with the resolved DSN. It returns nil when
+// dsn is empty.
+func New(cfg config.ErrorReportingConfig, dsn string) (*Reporter, error) {
+	if dsn == "" {
+		return nil, nil
+	}
+	client, err := newSentryClient(dsn)
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	threshold := cfg.RepeatThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Reporter{
+		client:      client,
+		environment: cfg.Environment,
+		serverName:  host,
+		sampleRate:  cfg.SampleRate,
+		threshold:   threshold,
+		streaks:     make(map[int64]int),
+	}, nil
+}
+
+// FeedFailed records a failed run of a feed, reporting err once the feed's
+// failure streak reaches the threshold. result names the failure, e.g.
+// "fetch_error".
+func (r *Reporter) FeedFailed(feedID int64, feedURL, result string, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.streaks[feedID]++
+	streak := r.streaks[feedID]
+	r.mu.Unlock()
+	if streak != r.threshold || !r.sampled() {
+		return
+	}
+	msg := result
+	if err != nil {
+		msg = err.Error()
+	}
+	r.capture(&sentryEvent{
+		Level:     "error",
+		Message:   fmt.Sprintf("Feed %d failed %d times in a row: %s", feedID, streak, msg),
+		Exception: []sentryException{{Type: result, Value: msg}},
+		Tags:      map[string]string{"feed_id": strconv.FormatInt(feedID, 10), "result": result},
+		Extra:     map[string]any{"feed_url": feedURL, "consecutive_failures": streak},
+		// Group by feed and kind of failure rather than by the changing message
+		Fingerprint: []string{"feed-failure", strconv.FormatInt(feedID, 10), result},
+	})
+}
+
+// FeedSucceeded ends a feed's failure streak.
+func (r *Reporter) FeedSucceeded(feedID int64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	delete(r.streaks, feedID)
+	r.mu.Unlock()
+}
+
+// RecoverFeed reports a panic in the processing of a feed, waits for the
+// report to be sent and panics again. Use it deferred.
+func (r *Reporter) RecoverFeed(feedID int64, feedURL string) {
+	if r == nil {
+		return
+	}
+	p := recover()
+	if p == nil {
+		return
+	}
+	r.capture(&sentryEvent{
+		Level:     "fatal",
+		Message:   fmt.Sprintf("panic processing feed %d: %v", feedID, p),
+		Exception: []sentryException{{Type: "panic", Value: fmt.Sprint(p)}},
+		Tags:      map[string]string{"feed_id": strconv.FormatInt(feedID, 10)},
+		Extra:     map[string]any{"feed_url": feedURL, "stack": string(debug.Stack())},
+	})
+	r.Flush()
+	panic(p)
+}
+
+// Flush waits up to a few seconds for reports still being sent.
+func (r *Reporter) Flush() {
+	if r == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		r.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(flushTimeout):
+		log.Warn().Msg("Timed out sending error reports")
+	}
+}
+
+func (r *Reporter) sampled() bool {
+	return r.sampleRate >= 1 || rand.Float64() < r.sampleRate
+}
+
+// capture sends e in the background.
+func (r *Reporter) capture(e *sentryEvent) {
+	e.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	e.Platform = "go"
+	e.Logger = "rss-telegram-bot"
+	e.Environment = r.environment
+	e.ServerName = r.serverName
+	r.pending.Add(1)
+	go func() {
+		defer r.pending.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+		if err := r.client.send(ctx, e); err != nil {
+			log.Warn().Err(err).Msg("Failed to send error report")
+		}
+	}()
+}
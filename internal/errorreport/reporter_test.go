@@ -0,0 +1,100 @@
+package errorreport
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sink records the events posted to a fake Sentry project 42.
+type sink struct {
+	mu     sync.Mutex
+	events []sentryEvent
+	auth   []string
+}
+
+func newSink(t *testing.T) (*sink, string) {
+	s := &sink{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/42/store/", r.URL.Path)
+		var e sentryEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		s.mu.Lock()
+		s.events = append(s.events, e)
+		s.auth = append(s.auth, r.Header.Get("X-Sentry-Auth"))
+		s.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return s, strings.Replace(srv.URL, "http://", "http://publickey@", 1) + "/42"
+}
+
+func TestNewDisabledWithoutDSN(t *testing.T) {
+	r, err := New(config.ErrorReportingConfig{}, "")
+	require.NoError(t, err)
+	assert.Nil(t, r)
+	r.FeedFailed(1, "https://example.com/feed", "fetch_error", errors.New("boom")) // nil-safe
+	r.Flush()
+}
+
+func TestNewRejectsInvalidDSN(t *testing.T) {
+	_, err := New(config.ErrorReportingConfig{}, "https://example.com/42")
+	assert.Error(t, err)
+}
+
+func TestFeedFailedReportsOncePerStreak(t *testing.T) {
+	s, dsn := newSink(t)
+	r, err := New(config.ErrorReportingConfig{SampleRate: 1, RepeatThreshold: 2, Environment: "test"}, dsn)
+	require.NoError(t, err)
+
+	r.FeedFailed(7, "https://example.com/feed", "fetch_error", errors.New("timeout"))
+	r.Flush()
+	assert.Empty(t, s.events, "below the threshold")
+
+	r.FeedFailed(7, "https://example.com/feed", "fetch_error", errors.New("timeout"))
+	r.FeedFailed(7, "https://example.com/feed", "fetch_error", errors.New("timeout"))
+	r.Flush()
+	require.Len(t, s.events, 1)
+	e := s.events[0]
+	assert.Equal(t, "7", e.Tags["feed_id"])
+	assert.Equal(t, "fetch_error", e.Tags["result"])
+	assert.Equal(t, "https://example.com/feed", e.Extra["feed_url"])
+	assert.Equal(t, "test", e.Environment)
+	assert.Contains(t, s.auth[0], "sentry_key=publickey")
+
+	r.FeedSucceeded(7)
+	r.FeedFailed(7, "https://example.com/feed", "fetch_error", errors.New("timeout"))
+	r.FeedFailed(7, "https://example.com/feed", "fetch_error", errors.New("timeout"))
+	r.Flush()
+	assert.Len(t, s.events, 2, "a new streak is reported again")
+}
+
+func TestSampleRateZeroDropsFeedErrors(t *testing.T) {
+	s, dsn := newSink(t)
+	r, err := New(config.ErrorReportingConfig{SampleRate: 0, RepeatThreshold: 1}, dsn)
+	require.NoError(t, err)
+	r.FeedFailed(1, "https://example.com/feed", "fetch_error", errors.New("timeout"))
+	r.Flush()
+	assert.Empty(t, s.events)
+}
+
+func TestRecoverFeedReportsAndRepanics(t *testing.T) {
+	s, dsn := newSink(t)
+	r, err := New(config.ErrorReportingConfig{SampleRate: 0}, dsn)
+	require.NoError(t, err)
+
+	assert.PanicsWithValue(t, "kaboom", func() {
+		defer r.RecoverFeed(3, "https://example.com/feed")
+		panic("kaboom")
+	})
+	require.Len(t, s.events, 1, "panics ignore sampling")
+	assert.Equal(t, "fatal", s.events[0].Level)
+	assert.Contains(t, s.events[0].Extra["stack"], "TestRecoverFeedReportsAndRepanics")
+}
@@ -0,0 +1,93 @@
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sentryClient sends events to a Sentry-compatible store endpoint (Sentry,
+// GlitchTip, ...) using the plain HTTP protocol.
+type sentryClient struct {
+	endpoint   string
+	auth       string
+	httpClient *http.Client
+}
+
+// newSentryClient parses a DSN of the form
+// https://<public_key>@<host>[/<path>]/<project_id>.
+func newSentryClient(dsn string) (*sentryClient, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry DSN: %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid sentry DSN: missing public key or host")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	project := path[i+1:]
+	if project == "" {
+		return nil, fmt.Errorf("invalid sentry DSN: missing project ID")
+	}
+	return &sentryClient{
+		endpoint:   fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, path[:i], project),
+		auth:       "Sentry sentry_version=7, sentry_client=rss-telegram-bot/1.0, sentry_key=" + u.User.Username(),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// sentryEvent is the subset of the Sentry event payload the bot fills in.
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Message     string            `json:"message"`
+	Exception   []sentryException `json:"exception,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+	Fingerprint []string          `json:"fingerprint,omitempty"`
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func (c *sentryClient) send(ctx context.Context, e *sentryEvent) error {
+	if e.EventID == "" {
+		id := make([]byte, 16)
+		rand.Read(id)
+		e.EventID = hex.EncodeToString(id)
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", c.auth)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("sentry answered %s", resp.Status)
+	}
+	return nil
+}
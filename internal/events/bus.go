@@ -0,0 +1,85 @@
+// Package events fans out feed processing events to interested listeners,
+// such as API clients watching the bot work.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	TypeFeedProcessed = "feed_processed" // A feed run finished; Result says how
+	TypeItemSent      = "item_sent"      // An item was delivered (or logged in dry-run mode)
+	TypeItemFetched   = "item_fetched"   // A new item was found in a feed and is about to be delivered
+)
+
+// Event is something that happened while processing a feed.
+type Event struct {
+	Type      string
+	FeedID    int64
+	FeedURL   string
+	Result    string // For TypeFeedProcessed, e.g. "success", "no_new_items" or "fetch_error"
+	Error     string // For TypeFeedProcessed, why the run failed; empty when it didn't
+	ItemTitle string // For TypeItemSent and TypeItemFetched
+	ItemLink  string // For TypeItemSent and TypeItemFetched
+	Time      time.Time
+}
+
+// Bus delivers published events to every subscriber. Publishing never blocks:
+// a subscriber that falls behind misses events. The zero value is ready to
+// use and a nil *Bus drops everything.
+type Bus struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]chan Event
+}
+
+// NewBus creates an empty Bus.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Publish sends e to all current subscribers, stamping it with the current
+// time if it has none.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default: // Subscriber is behind; drop rather than stall feed processing
+		}
+	}
+}
+
+// Subscribe returns a channel receiving events published from now on, holding
+// up to buffer unread ones, and a function that ends the subscription and
+// closes the channel.
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = make(map[int]chan Event)
+	}
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = ch
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
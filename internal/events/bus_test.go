@@ -0,0 +1,40 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBusDeliversToSubscribers(t *testing.T) {
+	bus := NewBus()
+	ch, cancel := bus.Subscribe(2)
+	bus.Publish(Event{Type: TypeFeedProcessed, FeedID: 1, Result: "success"})
+
+	e := <-ch
+	assert.Equal(t, int64(1), e.FeedID)
+	assert.False(t, e.Time.IsZero())
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok, "channel closed after cancel")
+	bus.Publish(Event{Type: TypeFeedProcessed}) // No subscribers left; must not panic
+	cancel()
+}
+
+func TestBusDropsForSlowSubscribers(t *testing.T) {
+	bus := NewBus()
+	ch, cancel := bus.Subscribe(1)
+	defer cancel()
+	bus.Publish(Event{FeedID: 1})
+	bus.Publish(Event{FeedID: 2}) // Buffer full; dropped instead of blocking
+
+	require.Len(t, ch, 1)
+	assert.Equal(t, int64(1), (<-ch).FeedID)
+}
+
+func TestNilBusDropsEvents(t *testing.T) {
+	var bus *Bus
+	bus.Publish(Event{FeedID: 1})
+}
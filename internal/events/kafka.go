@@ -0,0 +1,88 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// KafkaRESTPublisher produces records through a Kafka REST Proxy (v2 API),
+// as run by Confluent's kafka-rest or compatible gateways such as Redpanda's
+// HTTP proxy. The bot thus needs no Kafka client of its own.
+type KafkaRESTPublisher struct {
+	baseURL       string
+	authorization string
+	client        *http.Client
+}
+
+// NewKafkaRESTPublisher checks baseURL and creates a KafkaRESTPublisher.
+// authorization, if not empty, is sent as the Authorization header, e.g.
+// "Basic dXNlcjpwYXNz".
+func NewKafkaRESTPublisher(baseURL, authorization string, timeout time.Duration) (*KafkaRESTPublisher, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("event_stream.kafka_rest_url %q: expected an http(s) URL", baseURL)
+	}
+	return &KafkaRESTPublisher{baseURL: strings.TrimRight(baseURL, "/"), authorization: authorization, client: &http.Client{Timeout: timeout}}, nil
+}
+
+// Publish produces payload, which must be JSON, with key to the topic named
+// subject.
+func (p *KafkaRESTPublisher) Publish(ctx context.Context, subject, key string, payload []byte) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"records": []map[string]interface{}{{"key": key, "value": json.RawMessage(payload)}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/topics/"+url.PathEscape(subject), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating Kafka REST request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if p.authorization != "" {
+		req.Header.Set("Authorization", p.authorization)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("Kafka REST produce: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Kafka REST produce: server returned %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	// Records can fail individually even though the request succeeded.
+	var result struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if json.Unmarshal(data, &result) == nil {
+		for _, o := range result.Offsets {
+			if o.ErrorCode != nil {
+				return fmt.Errorf("Kafka REST produce: error %d: %s", *o.ErrorCode, o.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// Close releases the publisher's idle connections.
+func (p *KafkaRESTPublisher) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
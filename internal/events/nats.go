@@ -0,0 +1,178 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSPublisher publishes messages to a NATS server over its text protocol.
+// It keeps one connection open, reconnecting when a publish fails, and
+// follows each PUB with a PING so that errors reported by the server, such
+// as permission violations, fail the publish.
+type NATSPublisher struct {
+	url     *url.URL
+	token   string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewNATSPublisher checks serverURL and creates a NATSPublisher. Credentials
+// are taken from the URL's user info or token. It connects on the first
+// publish.
+func NewNATSPublisher(serverURL, token string, timeout time.Duration) (*NATSPublisher, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Host == "" || (u.Scheme != "nats" && u.Scheme != "tls") {
+		return nil, fmt.Errorf("event_stream.nats_url %q: expected e.g. nats://host:4222 or tls://host:4222", serverURL)
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &NATSPublisher{url: u, token: token, timeout: timeout}, nil
+}
+
+// Publish sends payload to subject. NATS messages have no key, so key is
+// ignored.
+func (p *NATSPublisher) Publish(ctx context.Context, subject, key string, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err := p.publish(ctx, subject, payload)
+	if err != nil && ctx.Err() == nil {
+		// The server may have dropped an idle connection; retry once on a new one.
+		p.closeConn()
+		err = p.publish(ctx, subject, payload)
+	}
+	if err != nil {
+		p.closeConn()
+	}
+	return err
+}
+
+// Close disconnects from the server.
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeConn()
+	return nil
+}
+
+func (p *NATSPublisher) closeConn() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn, p.reader = nil, nil
+	}
+}
+
+// publish sends one message, connecting first if needed.
+func (p *NATSPublisher) publish(ctx context.Context, subject string, payload []byte) error {
+	deadline := time.Now().Add(p.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if p.conn == nil {
+		if err := p.connect(ctx, deadline); err != nil {
+			return err
+		}
+	}
+	_ = p.conn.SetDeadline(deadline)
+	msg := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", subject, len(payload), payload)
+	if _, err := p.conn.Write([]byte(msg)); err != nil {
+		return fmt.Errorf("NATS publish: %w", err)
+	}
+	if err := p.waitPong(); err != nil {
+		return fmt.Errorf("NATS publish: %w", err)
+	}
+	return nil
+}
+
+// connect opens a connection to the server and logs in.
+func (p *NATSPublisher) connect(ctx context.Context, deadline time.Time) error {
+	addr := p.url.Host
+	if p.url.Port() == "" {
+		addr = net.JoinHostPort(p.url.Hostname(), "4222")
+	}
+	conn, err := (&net.Dialer{Deadline: deadline}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connecting to NATS server: %w", err)
+	}
+	_ = conn.SetDeadline(deadline)
+	reader := bufio.NewReader(conn)
+
+	// The server introduces itself in plain text; TLS, if any, starts after.
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("connecting to NATS server: no INFO greeting")
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	_ = json.Unmarshal([]byte(strings.TrimSpace(line[len("INFO "):])), &info)
+	secure := p.url.Scheme == "tls" || info.TLSRequired
+	if secure {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: p.url.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return fmt.Errorf("NATS TLS handshake: %w", err)
+		}
+		conn, reader = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "tls_required": secure, "name": "rss-telegram-bot", "lang": "go", "protocol": 0}
+	if user := p.url.User; user != nil {
+		options["user"] = user.Username()
+		if pass, ok := user.Password(); ok {
+			options["pass"] = pass
+		}
+	}
+	if p.token != "" {
+		options["auth_token"] = p.token
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	p.conn, p.reader = conn, reader
+	if _, err := conn.Write([]byte("CONNECT " + string(connect) + "\r\nPING\r\n")); err != nil {
+		p.closeConn()
+		return fmt.Errorf("NATS connect: %w", err)
+	}
+	if err := p.waitPong(); err != nil {
+		p.closeConn()
+		return fmt.Errorf("NATS connect: %w", err)
+	}
+	return nil
+}
+
+// waitPong reads from the server until it answers a PING, answering its own
+// PINGs on the way.
+func (p *NATSPublisher) waitPong() error {
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
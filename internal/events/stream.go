@@ -0,0 +1,155 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Names of the events published to a stream.
+const (
+	StreamItemFetched   = "item_fetched"
+	StreamItemDelivered = "item_delivered"
+	StreamFeedFailed    = "feed_failed"
+)
+
+// Publisher sends encoded events to a message broker. key identifies the
+// feed, so brokers that partition by key keep a feed's events in order.
+type Publisher interface {
+	Publish(ctx context.Context, subject, key string, payload []byte) error
+	Close() error
+}
+
+// StreamRecord is the JSON body of an event published to a stream.
+type StreamRecord struct {
+	Type      string    `json:"type"` // StreamItemFetched, StreamItemDelivered or StreamFeedFailed
+	Time      time.Time `json:"time"`
+	FeedID    int64     `json:"feed_id"`
+	FeedURL   string    `json:"feed_url,omitempty"`
+	Result    string    `json:"result,omitempty"` // For feed_failed, e.g. "fetch_error"
+	Error     string    `json:"error,omitempty"`  // For feed_failed
+	ItemTitle string    `json:"item_title,omitempty"`
+	ItemLink  string    `json:"item_link,omitempty"`
+}
+
+// newStreamRecord describes e as a StreamRecord, returning nil for events
+// that aren't streamed.
+func newStreamRecord(e Event) *StreamRecord {
+	r := &StreamRecord{Time: e.Time, FeedID: e.FeedID, FeedURL: e.FeedURL}
+	switch {
+	case e.Type == TypeItemFetched:
+		r.Type, r.ItemTitle, r.ItemLink = StreamItemFetched, e.ItemTitle, e.ItemLink
+	case e.Type == TypeItemSent:
+		r.Type, r.ItemTitle, r.ItemLink = StreamItemDelivered, e.ItemTitle, e.ItemLink
+	case e.Type == TypeFeedProcessed && e.Error != "":
+		r.Type, r.Result, r.Error = StreamFeedFailed, e.Result, e.Error
+	default:
+		return nil
+	}
+	return r
+}
+
+// Stream forwards the events of a Bus to NATS or Kafka, publishing each to
+// the subject (or topic) "<prefix>.<event type>". Events that arrive faster
+// than the broker takes them are dropped once the buffer is full; failed
+// publishes are logged and not retried.
+type Stream struct {
+	pub     Publisher
+	prefix  string
+	buffer  int
+	timeout time.Duration
+
+	ctx         context.Context
+	abort       context.CancelFunc
+	unsubscribe func()
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewStream checks cfg and creates a Stream publishing to the configured
+// broker. secret is the resolved nats_token or kafka_authorization.
+func NewStream(cfg config.EventStreamConfig, secret string) (*Stream, error) {
+	if cfg.Subject == "" || strings.ContainsAny(cfg.Subject, " \t\r\n*>") {
+		return nil, fmt.Errorf("event_stream.subject %q: must be non-empty and free of whitespace and wildcards", cfg.Subject)
+	}
+	var pub Publisher
+	var err error
+	switch {
+	case cfg.NATSURL != "" && cfg.KafkaRESTURL != "":
+		return nil, fmt.Errorf("set only one of event_stream.nats_url and event_stream.kafka_rest_url")
+	case cfg.NATSURL != "":
+		pub, err = NewNATSPublisher(cfg.NATSURL, secret, cfg.Timeout)
+	case cfg.KafkaRESTURL != "":
+		pub, err = NewKafkaRESTPublisher(cfg.KafkaRESTURL, secret, cfg.Timeout)
+	default:
+		return nil, fmt.Errorf("event_stream needs a nats_url or kafka_rest_url")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newStream(pub, cfg.Subject, cfg.Buffer, cfg.Timeout), nil
+}
+
+func newStream(pub Publisher, prefix string, buffer int, timeout time.Duration) *Stream {
+	s := &Stream{pub: pub, prefix: prefix, buffer: buffer, timeout: timeout, done: make(chan struct{})}
+	s.ctx, s.abort = context.WithCancel(context.Background())
+	return s
+}
+
+// Start forwards events published to bus from now on until Close is called.
+func (s *Stream) Start(bus *Bus) {
+	ch, unsubscribe := bus.Subscribe(s.buffer)
+	s.unsubscribe = unsubscribe
+	go func() {
+		defer close(s.done)
+		for e := range ch {
+			s.send(e)
+		}
+	}()
+}
+
+// send publishes e if it is streamed.
+func (s *Stream) send(e Event) {
+	r := newStreamRecord(e)
+	if r == nil || s.ctx.Err() != nil {
+		return
+	}
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	ctx := s.ctx
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	if err := s.pub.Publish(ctx, s.prefix+"."+r.Type, strconv.FormatInt(r.FeedID, 10), payload); err != nil {
+		log.Warn().Err(err).Str("event", r.Type).Int64("feed_id", r.FeedID).Msg("Failed to publish event to the event stream")
+	}
+}
+
+// Close stops forwarding events, waiting up to timeout for those already
+// received to be published, and disconnects from the broker.
+func (s *Stream) Close(timeout time.Duration) {
+	s.closeOnce.Do(func() {
+		if s.unsubscribe != nil {
+			s.unsubscribe()
+			select {
+			case <-s.done:
+			case <-time.After(timeout):
+				s.abort() // Drop the rest
+				<-s.done
+			}
+		}
+		s.abort()
+		_ = s.pub.Close()
+	})
+}
@@ -0,0 +1,160 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNATSServer accepts one connection at a time, records published
+// messages on pubs and rejects subjects starting with "denied".
+func fakeNATSServer(t *testing.T, pubs chan<- string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte(`INFO {"server_id":"test","max_payload":1048576}` + "\r\n"))
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					switch {
+					case len(fields) == 0:
+					case fields[0] == "CONNECT":
+						if !strings.Contains(line, `"auth_token":"s3cret"`) {
+							conn.Write([]byte("-ERR 'Authorization Violation'\r\n"))
+							return
+						}
+					case fields[0] == "PING":
+						conn.Write([]byte("PONG\r\n"))
+					case fields[0] == "PUB" && len(fields) == 3:
+						n, _ := strconv.Atoi(fields[2])
+						payload := make([]byte, n+2)
+						if _, err := io.ReadFull(r, payload); err != nil {
+							return
+						}
+						if strings.HasPrefix(fields[1], "denied") {
+							conn.Write([]byte("-ERR 'Permissions Violation for Publish to " + fields[1] + "'\r\n"))
+							continue
+						}
+						pubs <- fields[1] + " " + string(payload[:n])
+					}
+				}
+			}()
+		}
+	}()
+	return "nats://" + ln.Addr().String()
+}
+
+func TestNATSPublisher(t *testing.T) {
+	pubs := make(chan string, 4)
+	p, err := NewNATSPublisher(fakeNATSServer(t, pubs), "s3cret", time.Second)
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Publish(context.Background(), "rss-bot.item_fetched", "1", []byte(`{"a":1}`)))
+	assert.Equal(t, `rss-bot.item_fetched {"a":1}`, <-pubs)
+	// Reuses the connection, and reconnects once it is gone
+	require.NoError(t, p.Publish(context.Background(), "rss-bot.item_delivered", "1", []byte(`{}`)))
+	assert.Equal(t, `rss-bot.item_delivered {}`, <-pubs)
+	p.conn.Close()
+	require.NoError(t, p.Publish(context.Background(), "rss-bot.feed_failed", "1", []byte(`{}`)))
+	assert.Equal(t, `rss-bot.feed_failed {}`, <-pubs)
+
+	err = p.Publish(context.Background(), "denied.x", "1", []byte(`{}`))
+	assert.ErrorContains(t, err, "Permissions Violation")
+
+	bad, err := NewNATSPublisher(fakeNATSServer(t, pubs), "wrong", time.Second)
+	require.NoError(t, err)
+	assert.ErrorContains(t, bad.Publish(context.Background(), "rss-bot.x", "1", []byte(`{}`)), "Authorization Violation")
+}
+
+func TestKafkaRESTPublisher(t *testing.T) {
+	var path, contentType, auth string
+	var body struct {
+		Records []struct {
+			Key   string          `json:"key"`
+			Value json.RawMessage `json:"value"`
+		} `json:"records"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType, auth = r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if strings.HasSuffix(path, "missing") {
+			w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":40403,"error":"Topic not found"}]}`))
+			return
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":12,"error_code":null,"error":null}]}`))
+	}))
+	defer srv.Close()
+
+	p, err := NewKafkaRESTPublisher(srv.URL+"/", "Basic abc", time.Second)
+	require.NoError(t, err)
+	require.NoError(t, p.Publish(context.Background(), "rss-bot.item_delivered", "7", []byte(`{"feed_id":7}`)))
+	assert.Equal(t, "/topics/rss-bot.item_delivered", path)
+	assert.Equal(t, "application/vnd.kafka.json.v2+json", contentType)
+	assert.Equal(t, "Basic abc", auth)
+	require.Len(t, body.Records, 1)
+	assert.Equal(t, "7", body.Records[0].Key)
+	assert.JSONEq(t, `{"feed_id":7}`, string(body.Records[0].Value))
+
+	assert.ErrorContains(t, p.Publish(context.Background(), "missing", "7", []byte(`{}`)), "Topic not found")
+}
+
+// recordingPublisher collects what a Stream publishes.
+type recordingPublisher struct {
+	published chan string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, subject, key string, payload []byte) error {
+	p.published <- subject + " " + key + " " + string(payload)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+func TestStreamForwardsEvents(t *testing.T) {
+	bus := NewBus()
+	pub := &recordingPublisher{published: make(chan string, 10)}
+	s := newStream(pub, "rss", 10, time.Second)
+	s.Start(bus)
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	bus.Publish(Event{Type: TypeItemFetched, FeedID: 1, ItemTitle: "Hello", Time: at})
+	bus.Publish(Event{Type: TypeFeedProcessed, FeedID: 1, Result: "success", Time: at}) // Not streamed
+	bus.Publish(Event{Type: TypeItemSent, FeedID: 1, ItemLink: "https://example.com/1", Time: at})
+	bus.Publish(Event{Type: TypeFeedProcessed, FeedID: 2, Result: "fetch_error", Error: "timeout", Time: at})
+	s.Close(time.Second)
+	close(pub.published)
+
+	var got []string
+	for p := range pub.published {
+		got = append(got, p)
+	}
+	assert.Equal(t, []string{
+		`rss.item_fetched 1 {"type":"item_fetched","time":"2026-01-02T03:04:05Z","feed_id":1,"item_title":"Hello"}`,
+		`rss.item_delivered 1 {"type":"item_delivered","time":"2026-01-02T03:04:05Z","feed_id":1,"item_link":"https://example.com/1"}`,
+		`rss.feed_failed 2 {"type":"feed_failed","time":"2026-01-02T03:04:05Z","feed_id":2,"result":"fetch_error","error":"timeout"}`,
+	}, got)
+}
@@ -0,0 +1,87 @@
+package feedsync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ParseFeedList reads a compact feed list such as the FEEDS environment
+// variable: url|chat_id|bot_token entries separated by newlines, spaces or
+// semicolons. Tokens may be literal, since the list comes from the environment
+// rather than git, or env:NAME and file:PATH references. Each distinct token
+// becomes a bot named after the numeric ID it starts with.
+func ParseFeedList(list string) (*Document, error) {
+	doc := &Document{}
+	bots := map[string]string{} // Token to bot name
+	feeds := map[string]bool{}
+	entries := strings.FieldsFunc(list, func(r rune) bool {
+		return r == ';' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	for i, entry := range entries {
+		parts := strings.Split(entry, "|")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("entry %d: expected url|chat_id|bot_token, got %q", i+1, redactEntry(entry))
+		}
+		url, chatID, token := parts[0], parts[1], parts[2]
+		if feeds[url] {
+			return nil, fmt.Errorf("feed %s is listed twice", url)
+		}
+		feeds[url] = true
+		name, ok := bots[token]
+		if !ok {
+			name = listBotName(token, len(bots)+1)
+			bots[token] = name
+			doc.Bots = append(doc.Bots, BotSpec{Name: name, Token: token})
+		}
+		doc.Feeds = append(doc.Feeds, FeedSpec{URL: url, ChatID: chatID, Bot: name})
+	}
+	if len(doc.Feeds) == 0 {
+		return nil, fmt.Errorf("no feeds listed")
+	}
+	return doc, nil
+}
+
+// listBotName names a bot from a feed list by its Telegram bot ID, the part
+// of the token before the colon, falling back to its position in the list.
+func listBotName(token string, n int) string {
+	if id, _, ok := strings.Cut(token, ":"); ok && id != "" && strings.Trim(id, "0123456789") == "" {
+		return "bot-" + id
+	}
+	return fmt.Sprintf("bot-%d", n)
+}
+
+// redactEntry hides the token of a malformed feed list entry in errors.
+func redactEntry(entry string) string {
+	if i := strings.LastIndex(entry, "|"); i >= 0 {
+		return entry[:i+1] + "..."
+	}
+	return entry
+}
+
+// Seed applies docs in order when the database has no feeds yet, so bootstrap
+// settings only take effect on first start. It returns the plans it applied,
+// or nil when feeds already exist. With dryRun the plans are only computed.
+func (s *Syncer) Seed(ctx context.Context, dryRun bool, docs ...*Document) ([]*Plan, error) {
+	feeds, err := s.feeds.ListFeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(feeds) > 0 {
+		return nil, nil
+	}
+	var plans []*Plan
+	for _, doc := range docs {
+		plan, err := s.Plan(ctx, doc, false)
+		if err != nil {
+			return plans, err
+		}
+		if !dryRun {
+			if err := s.Apply(ctx, plan); err != nil {
+				return plans, err
+			}
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
@@ -0,0 +1,64 @@
+package feedsync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFeedList(t *testing.T) {
+	doc, err := ParseFeedList("https://example.com/a.xml|@news|123:abc;\n https://example.com/b.xml|-100123|123:abc\thttps://example.com/c.xml|@other|env:OTHER_TOKEN\n")
+	require.NoError(t, err)
+	require.Len(t, doc.Feeds, 3)
+	assert.Equal(t, []BotSpec{{Name: "bot-123", Token: "123:abc"}, {Name: "bot-2", Token: "env:OTHER_TOKEN"}}, doc.Bots)
+	assert.Equal(t, FeedSpec{URL: "https://example.com/b.xml", ChatID: "-100123", Bot: "bot-123"}, doc.Feeds[1])
+	assert.Equal(t, "bot-2", doc.Feeds[2].Bot)
+
+	for name, list := range map[string]string{
+		"empty":          " ; ",
+		"missing token":  "https://example.com/a.xml|@news",
+		"empty chat":     "https://example.com/a.xml||123:abc",
+		"duplicate feed": "https://x|1|123:abc;https://x|2|123:abc",
+	} {
+		_, err := ParseFeedList(list)
+		assert.Error(t, err, name)
+	}
+	_, err = ParseFeedList("https://example.com/a.xml|123:secret")
+	assert.NotContains(t, err.Error(), "secret", "tokens are not echoed")
+}
+
+func TestSeed(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	syncer := NewSyncer(db, 300)
+	doc, err := ParseFeedList("https://example.com/a.xml|@news|123:abc")
+	require.NoError(t, err)
+
+	plans, err := syncer.Seed(ctx, true, doc)
+	require.NoError(t, err)
+	require.Len(t, plans, 1)
+	feeds, err := database.NewFeedStore(db).ListFeeds(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, feeds, "dry run applies nothing")
+
+	plans, err = syncer.Seed(ctx, false, doc)
+	require.NoError(t, err)
+	require.Len(t, plans, 1)
+	feeds, err = database.NewFeedStore(db).ListFeeds(ctx)
+	require.NoError(t, err)
+	require.Len(t, feeds, 1)
+	assert.Equal(t, "@news", feeds[0].TelegramChatID)
+	require.NotNil(t, feeds[0].TelegramBotID)
+	token, err := database.NewTelegramBotStore(db).GetTokenByBotID(ctx, *feeds[0].TelegramBotID)
+	require.NoError(t, err)
+	assert.Equal(t, "123:abc", token)
+
+	other, err := ParseFeedList("https://example.com/b.xml|@news|123:abc")
+	require.NoError(t, err)
+	plans, err = syncer.Seed(ctx, false, other)
+	require.NoError(t, err)
+	assert.Nil(t, plans, "only an empty database is seeded")
+}
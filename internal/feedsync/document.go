@@ -0,0 +1,186 @@
+// Package feedsync reconciles the database with a declarative YAML document
+// describing feeds, bots, proxies and formatting profiles, so they can be
+// managed in git.
+package feedsync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is the desired state read from a feeds.yaml file. Proxies and
+// profiles are identified by name, bots by token and feeds by URL.
+type Document struct {
+	Proxies  []ProxySpec   `yaml:"proxies"`
+	Profiles []ProfileSpec `yaml:"profiles"`
+	Bots     []BotSpec     `yaml:"bots"`
+	Feeds    []FeedSpec    `yaml:"feeds"`
+}
+
+// ProxySpec describes a proxy.
+type ProxySpec struct {
+	Name               string `yaml:"name"`
+	Type               string `yaml:"type"` // http, https, socks5, socks5h or pac
+	Address            string `yaml:"address"`
+	Username           string `yaml:"username"` // Literal or env:/file: reference, stored as written
+	Password           string `yaml:"password"` // Literal or env:/file: reference, stored as written
+	DefaultForRSS      bool   `yaml:"default_for_rss"`
+	DefaultForTelegram bool   `yaml:"default_for_telegram"`
+	DirectFallback     *bool  `yaml:"direct_fallback"` // Unset follows the config
+}
+
+// ProfileSpec describes a formatting profile. Config holds the profile's
+// settings under their JSON names, e.g. message_template or hashtags.
+type ProfileSpec struct {
+	Name   string         `yaml:"name"`
+	Config map[string]any `yaml:"config"`
+}
+
+// BotSpec describes a Telegram bot. Feeds refer to it by Name, which is
+// stored as the bot's description.
+type BotSpec struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"` // env:NAME or file:PATH; literal tokens don't belong in git
+}
+
+// FeedSpec describes a feed. Settings it doesn't cover, such as pools, auth
+// and fetch overrides, are left as they are on existing feeds.
+type FeedSpec struct {
+	URL        string            `yaml:"url"`
+	Title      string            `yaml:"title"`
+	ChatID     string            `yaml:"chat_id"`
+	ThreadID   int               `yaml:"thread_id"`
+	Bot        string            `yaml:"bot"`         // Name of a bot in the document
+	Proxy      string            `yaml:"proxy"`       // Name of a proxy in the document; empty uses the defaults
+	Profile    string            `yaml:"profile"`     // Name of a profile in the document; empty uses the default formatting
+	Frequency  time.Duration     `yaml:"frequency"`   // e.g. "10m"; 0 uses default_fetch_frequency_seconds
+	Enabled    *bool             `yaml:"enabled"`     // Defaults to true
+	SourceType string            `yaml:"source_type"` // rss or sitemap; empty keeps an existing feed's type
+	UserAgent  string            `yaml:"user_agent"`
+	Headers    map[string]string `yaml:"headers"`
+}
+
+// Load reads and validates a document from path.
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a document. Unknown keys are rejected so typos
+// don't silently drop settings.
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing document: %w", err)
+	}
+	if err := doc.validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (d *Document) validate() error {
+	proxies := make(map[string]bool)
+	for i, p := range d.Proxies {
+		switch {
+		case p.Name == "":
+			return fmt.Errorf("proxies[%d]: name is required", i)
+		case proxies[p.Name]:
+			return fmt.Errorf("proxy %q is defined twice", p.Name)
+		case p.Address == "":
+			return fmt.Errorf("proxy %q: address is required", p.Name)
+		}
+		switch strings.ToLower(p.Type) {
+		case "http", "https", "socks5", "socks5h", "pac":
+		default:
+			return fmt.Errorf("proxy %q: invalid type %q (must be http, https, socks5, socks5h or pac)", p.Name, p.Type)
+		}
+		proxies[p.Name] = true
+	}
+
+	profiles := make(map[string]bool)
+	for i, p := range d.Profiles {
+		switch {
+		case p.Name == "":
+			return fmt.Errorf("profiles[%d]: name is required", i)
+		case profiles[p.Name]:
+			return fmt.Errorf("profile %q is defined twice", p.Name)
+		}
+		if _, err := p.parsedConfig(); err != nil {
+			return fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+		profiles[p.Name] = true
+	}
+
+	bots := make(map[string]bool)
+	for i, b := range d.Bots {
+		switch {
+		case b.Name == "":
+			return fmt.Errorf("bots[%d]: name is required", i)
+		case bots[b.Name]:
+			return fmt.Errorf("bot %q is defined twice", b.Name)
+		case !proxy.IsSecretRef(b.Token):
+			return fmt.Errorf("bot %q: token must be an env:NAME or file:PATH reference", b.Name)
+		}
+		bots[b.Name] = true
+	}
+
+	feeds := make(map[string]bool)
+	for i, f := range d.Feeds {
+		switch {
+		case f.URL == "":
+			return fmt.Errorf("feeds[%d]: url is required", i)
+		case feeds[f.URL]:
+			return fmt.Errorf("feed %s is defined twice", f.URL)
+		case f.ChatID == "":
+			return fmt.Errorf("feed %s: chat_id is required", f.URL)
+		case f.Bot != "" && !bots[f.Bot]:
+			return fmt.Errorf("feed %s: bot %q is not defined in the document", f.URL, f.Bot)
+		case f.Proxy != "" && !proxies[f.Proxy]:
+			return fmt.Errorf("feed %s: proxy %q is not defined in the document", f.URL, f.Proxy)
+		case f.Profile != "" && !profiles[f.Profile]:
+			return fmt.Errorf("feed %s: profile %q is not defined in the document", f.URL, f.Profile)
+		case f.Frequency < 0:
+			return fmt.Errorf("feed %s: frequency must not be negative", f.URL)
+		}
+		switch f.SourceType {
+		case "", database.FeedSourceRSS, database.FeedSourceSitemap:
+		default:
+			return fmt.Errorf("feed %s: source_type %q can't be synced (use rss or sitemap, or add it with 'feed add')", f.URL, f.SourceType)
+		}
+		feeds[f.URL] = true
+	}
+	return nil
+}
+
+// parsedConfig converts Config to a FormattingProfileConfig through its JSON
+// field names, rejecting unknown settings.
+func (p ProfileSpec) parsedConfig() (database.FormattingProfileConfig, error) {
+	var cfg database.FormattingProfileConfig
+	if len(p.Config) == 0 {
+		return cfg, nil
+	}
+	data, err := json.Marshal(p.Config)
+	if err != nil {
+		return cfg, fmt.Errorf("encoding config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
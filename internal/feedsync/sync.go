@@ -0,0 +1,474 @@
+package feedsync
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+)
+
+// Change operations, also used as the diff markers.
+const (
+	OpCreate = "+"
+	OpUpdate = "~"
+	OpDelete = "-"
+)
+
+// Change is one step of a Plan.
+type Change struct {
+	Op     string   // OpCreate, OpUpdate or OpDelete
+	Kind   string   // "proxy", "profile", "bot" or "feed"
+	Name   string   // Name, or URL for feeds
+	Fields []string // What an update changes, as "field: old -> new"
+
+	apply func(ctx context.Context) error
+}
+
+// Plan is the list of changes that brings the database in line with a document.
+type Plan struct {
+	Changes []Change
+
+	// Document names to database IDs; 0 until a planned creation has run.
+	proxyIDs, profileIDs, botIDs map[string]int64
+}
+
+// Empty reports whether the database already matches the document.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String renders the plan as a diff, one line per change plus one per
+// changed field, followed by a summary.
+func (p *Plan) String() string {
+	var b strings.Builder
+	counts := map[string]int{}
+	for _, c := range p.Changes {
+		fmt.Fprintf(&b, "%s %s %s\n", c.Op, c.Kind, c.Name)
+		for _, field := range c.Fields {
+			fmt.Fprintf(&b, "    %s\n", field)
+		}
+		counts[c.Op]++
+	}
+	fmt.Fprintf(&b, "%d to create, %d to update, %d to delete\n", counts[OpCreate], counts[OpUpdate], counts[OpDelete])
+	return b.String()
+}
+
+// Syncer plans and applies documents against the database.
+type Syncer struct {
+	feeds            *database.FeedStore
+	proxies          *database.ProxyStore
+	profiles         *database.FormattingProfileStore
+	bots             *database.TelegramBotStore
+	defaultFrequency int // Seconds, for feeds without a frequency
+}
+
+// NewSyncer creates a Syncer. defaultFrequency (in seconds) applies to feeds
+// that don't set one.
+func NewSyncer(db *database.DB, defaultFrequency int) *Syncer {
+	return &Syncer{
+		feeds:            database.NewFeedStore(db),
+		proxies:          database.NewProxyStore(db),
+		profiles:         database.NewFormattingProfileStore(db),
+		bots:             database.NewTelegramBotStore(db),
+		defaultFrequency: defaultFrequency,
+	}
+}
+
+// Plan compares doc with the database. Entries missing from doc are only
+// deleted with prune. Bot tokens are resolved here, so their env vars and
+// files must be available.
+func (s *Syncer) Plan(ctx context.Context, doc *Document, prune bool) (*Plan, error) {
+	plan := &Plan{proxyIDs: map[string]int64{}, profileIDs: map[string]int64{}, botIDs: map[string]int64{}}
+	var deletes []Change
+
+	// Proxies
+	proxies, err := s.proxies.ListProxies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proxyNames := map[int64]string{}
+	existingProxies := map[string]*database.Proxy{}
+	for _, p := range proxies {
+		proxyNames[p.ID] = p.Name
+		existingProxies[p.Name] = p
+	}
+	for _, spec := range doc.Proxies {
+		s.planProxy(plan, spec, existingProxies[spec.Name])
+	}
+	if prune {
+		for _, p := range proxies {
+			if _, ok := plan.proxyIDs[p.Name]; !ok {
+				id := p.ID
+				deletes = append(deletes, Change{Op: OpDelete, Kind: "proxy", Name: p.Name, apply: func(ctx context.Context) error {
+					return s.proxies.DeleteProxy(ctx, id)
+				}})
+			}
+		}
+	}
+
+	// Formatting profiles
+	profiles, err := s.profiles.ListProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	profileNames := map[int64]string{}
+	existingProfiles := map[string]*database.FormattingProfile{}
+	for _, p := range profiles {
+		profileNames[p.ID] = p.Name
+		existingProfiles[p.Name] = p
+	}
+	for _, spec := range doc.Profiles {
+		if err := s.planProfile(plan, spec, existingProfiles[spec.Name]); err != nil {
+			return nil, err
+		}
+	}
+	if prune {
+		for _, p := range profiles {
+			if _, ok := plan.profileIDs[p.Name]; !ok {
+				id := p.ID
+				deletes = append(deletes, Change{Op: OpDelete, Kind: "profile", Name: p.Name, apply: func(ctx context.Context) error {
+					return s.profiles.DeleteProfile(ctx, id)
+				}})
+			}
+		}
+	}
+
+	// Bots
+	bots, err := s.bots.ListBots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	botNames := map[int64]string{}
+	existingBots := map[string]*database.TelegramBot{}
+	for _, b := range bots {
+		botNames[b.ID] = botName(b)
+		existingBots[b.TokenHash] = b
+	}
+	keptBots := map[int64]bool{}
+	for _, spec := range doc.Bots {
+		token, err := proxy.ResolveSecret(spec.Token)
+		if err != nil {
+			return nil, fmt.Errorf("bot %q token: %w", spec.Name, err)
+		}
+		if token == "" {
+			return nil, fmt.Errorf("bot %q token: %s is empty", spec.Name, spec.Token)
+		}
+		existing := existingBots[database.TokenHash(token)]
+		if existing != nil {
+			keptBots[existing.ID] = true
+		}
+		s.planBot(plan, spec, token, existing)
+	}
+	if prune {
+		for _, b := range bots {
+			if !keptBots[b.ID] {
+				id := b.ID
+				deletes = append(deletes, Change{Op: OpDelete, Kind: "bot", Name: botNames[b.ID], apply: func(ctx context.Context) error {
+					return s.bots.DeleteBot(ctx, id)
+				}})
+			}
+		}
+	}
+
+	// Feeds
+	feeds, err := s.feeds.ListFeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existingFeeds := map[string]*database.Feed{}
+	for _, f := range feeds {
+		existingFeeds[f.URL] = f
+	}
+	names := refNames{proxies: proxyNames, profiles: profileNames, bots: botNames}
+	for _, spec := range doc.Feeds {
+		s.planFeed(plan, spec, existingFeeds[spec.URL], names)
+	}
+	if prune {
+		wanted := map[string]bool{}
+		for _, spec := range doc.Feeds {
+			wanted[spec.URL] = true
+		}
+		var feedDeletes []Change
+		for _, f := range feeds {
+			if !wanted[f.URL] {
+				id := f.ID
+				feedDeletes = append(feedDeletes, Change{Op: OpDelete, Kind: "feed", Name: f.URL, apply: func(ctx context.Context) error {
+					return s.feeds.DeleteFeed(ctx, id)
+				}})
+			}
+		}
+		// Feeds go first so nothing still points at the bots, profiles and proxies removed after them.
+		deletes = append(feedDeletes, deletes...)
+	}
+
+	plan.Changes = append(plan.Changes, deletes...)
+	return plan, nil
+}
+
+// Apply runs the plan's changes in order, stopping at the first error.
+// Changes made before it are kept.
+func (s *Syncer) Apply(ctx context.Context, plan *Plan) error {
+	for _, c := range plan.Changes {
+		if err := c.apply(ctx); err != nil {
+			return fmt.Errorf("%s %s %s: %w", c.Op, c.Kind, c.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Syncer) planProxy(plan *Plan, spec ProxySpec, existing *database.Proxy) {
+	desired := &database.Proxy{
+		Name:                 spec.Name,
+		Type:                 strings.ToLower(spec.Type),
+		Address:              spec.Address,
+		Username:             optionalString(spec.Username),
+		Password:             optionalString(spec.Password),
+		IsDefaultForRSS:      spec.DefaultForRSS,
+		IsDefaultForTelegram: spec.DefaultForTelegram,
+		DirectFallback:       spec.DirectFallback,
+	}
+	if existing == nil {
+		plan.proxyIDs[spec.Name] = 0
+		plan.Changes = append(plan.Changes, Change{Op: OpCreate, Kind: "proxy", Name: spec.Name, apply: func(ctx context.Context) error {
+			id, err := s.proxies.CreateProxy(ctx, desired)
+			plan.proxyIDs[spec.Name] = id
+			return err
+		}})
+		return
+	}
+
+	plan.proxyIDs[spec.Name] = existing.ID
+	var fields []string
+	diffField(&fields, "type", existing.Type, desired.Type)
+	diffField(&fields, "address", existing.Address, desired.Address)
+	diffField(&fields, "username", derefString(existing.Username), spec.Username)
+	if derefString(existing.Password) != spec.Password {
+		fields = append(fields, "password: changed")
+	}
+	diffField(&fields, "default_for_rss", existing.IsDefaultForRSS, desired.IsDefaultForRSS)
+	diffField(&fields, "default_for_telegram", existing.IsDefaultForTelegram, desired.IsDefaultForTelegram)
+	diffField(&fields, "direct_fallback", optionalBool(existing.DirectFallback), optionalBool(desired.DirectFallback))
+	if len(fields) == 0 {
+		return
+	}
+	desired.ID = existing.ID
+	desired.TLS = existing.TLS
+	plan.Changes = append(plan.Changes, Change{Op: OpUpdate, Kind: "proxy", Name: spec.Name, Fields: fields, apply: func(ctx context.Context) error {
+		return s.proxies.UpdateProxy(ctx, desired)
+	}})
+}
+
+func (s *Syncer) planProfile(plan *Plan, spec ProfileSpec, existing *database.FormattingProfile) error {
+	cfg, err := spec.parsedConfig()
+	if err != nil {
+		return fmt.Errorf("profile %q: %w", spec.Name, err)
+	}
+	desired := &database.FormattingProfile{Name: spec.Name, ParsedConfig: cfg}
+	if existing == nil {
+		plan.profileIDs[spec.Name] = 0
+		plan.Changes = append(plan.Changes, Change{Op: OpCreate, Kind: "profile", Name: spec.Name, apply: func(ctx context.Context) error {
+			id, err := s.profiles.CreateProfile(ctx, desired)
+			plan.profileIDs[spec.Name] = id
+			return err
+		}})
+		return nil
+	}
+
+	plan.profileIDs[spec.Name] = existing.ID
+	// Compare re-encoded configs so formatting differences in the stored JSON don't count.
+	current := &database.FormattingProfile{ParsedConfig: existing.ParsedConfig}
+	if err := current.MarshalConfig(); err != nil {
+		return fmt.Errorf("profile %q: %w", spec.Name, err)
+	}
+	if err := desired.MarshalConfig(); err != nil {
+		return fmt.Errorf("profile %q: %w", spec.Name, err)
+	}
+	if current.ConfigJSON == desired.ConfigJSON {
+		return nil
+	}
+	desired.ID = existing.ID
+	plan.Changes = append(plan.Changes, Change{Op: OpUpdate, Kind: "profile", Name: spec.Name, Fields: []string{"config: changed"}, apply: func(ctx context.Context) error {
+		return s.profiles.UpdateProfile(ctx, desired)
+	}})
+	return nil
+}
+
+func (s *Syncer) planBot(plan *Plan, spec BotSpec, token string, existing *database.TelegramBot) {
+	if existing == nil {
+		plan.botIDs[spec.Name] = 0
+		plan.Changes = append(plan.Changes, Change{Op: OpCreate, Kind: "bot", Name: spec.Name, apply: func(ctx context.Context) error {
+			id, err := s.bots.CreateBot(ctx, token, &spec.Name)
+			plan.botIDs[spec.Name] = id
+			return err
+		}})
+		return
+	}
+
+	plan.botIDs[spec.Name] = existing.ID
+	if derefString(existing.Description) == spec.Name {
+		return
+	}
+	id := existing.ID
+	fields := []string{fmt.Sprintf("name: %q -> %q", derefString(existing.Description), spec.Name)}
+	plan.Changes = append(plan.Changes, Change{Op: OpUpdate, Kind: "bot", Name: spec.Name, Fields: fields, apply: func(ctx context.Context) error {
+		return s.bots.SetBotDescription(ctx, id, &spec.Name)
+	}})
+}
+
+// refNames maps database IDs to the names shown in feed diffs.
+type refNames struct {
+	proxies, profiles, bots map[int64]string
+}
+
+func (s *Syncer) planFeed(plan *Plan, spec FeedSpec, existing *database.Feed, names refNames) {
+	frequency := int(spec.Frequency / time.Second)
+	if frequency == 0 {
+		frequency = s.defaultFrequency
+	}
+	enabled := spec.Enabled == nil || *spec.Enabled
+
+	// setFields copies the document's settings onto f, with references
+	// resolved at apply time so newly created bots, proxies and profiles count.
+	// The store drops the numeric ID cached for the old chat when ChatID changes.
+	setFields := func(f *database.Feed) {
+		f.UserTitle = optionalString(spec.Title)
+		if spec.ThreadID != 0 {
+			threadID := spec.ThreadID
+			f.TelegramThreadID = &threadID
+		} else if !f.AutoCreateTopic || f.TelegramChatID != spec.ChatID {
+			// Topics the worker created for an auto-topic feed are kept
+			// while it stays in the same chat.
+			f.TelegramThreadID = nil
+		}
+		f.TelegramChatID = spec.ChatID
+		f.TelegramBotID = plan.ref(plan.botIDs, spec.Bot)
+		f.ProxyID = plan.ref(plan.proxyIDs, spec.Proxy)
+		f.FormattingProfileID = plan.ref(plan.profileIDs, spec.Profile)
+		f.FrequencySeconds = frequency
+		f.IsEnabled = enabled
+		if spec.SourceType != "" {
+			f.SourceType = spec.SourceType
+		}
+		f.UserAgent = optionalString(spec.UserAgent)
+		f.RequestHeaders = spec.Headers
+	}
+
+	if existing == nil {
+		plan.Changes = append(plan.Changes, Change{Op: OpCreate, Kind: "feed", Name: spec.URL, apply: func(ctx context.Context) error {
+			feed := &database.Feed{URL: spec.URL}
+			setFields(feed)
+			_, err := s.feeds.CreateFeed(ctx, feed)
+			return err
+		}})
+		return
+	}
+
+	var fields []string
+	diffField(&fields, "title", derefString(existing.UserTitle), spec.Title)
+	diffField(&fields, "chat_id", existing.TelegramChatID, spec.ChatID)
+	if spec.ThreadID != 0 || !existing.AutoCreateTopic {
+		currentThread := 0
+		if existing.TelegramThreadID != nil {
+			currentThread = *existing.TelegramThreadID
+		}
+		diffField(&fields, "thread_id", currentThread, spec.ThreadID)
+	}
+	diffRef(&fields, "bot", existing.TelegramBotID, names.bots, plan.botIDs, spec.Bot)
+	diffRef(&fields, "proxy", existing.ProxyID, names.proxies, plan.proxyIDs, spec.Proxy)
+	diffRef(&fields, "profile", existing.FormattingProfileID, names.profiles, plan.profileIDs, spec.Profile)
+	diffField(&fields, "frequency", time.Duration(existing.FrequencySeconds)*time.Second, time.Duration(frequency)*time.Second)
+	diffField(&fields, "enabled", existing.IsEnabled, enabled)
+	if spec.SourceType != "" {
+		currentType := existing.SourceType
+		if currentType == "" {
+			currentType = database.FeedSourceRSS
+		}
+		diffField(&fields, "source_type", currentType, spec.SourceType)
+	}
+	diffField(&fields, "user_agent", derefString(existing.UserAgent), spec.UserAgent)
+	if !maps.Equal(existing.RequestHeaders, spec.Headers) {
+		fields = append(fields, "headers: changed")
+	}
+	if len(fields) == 0 {
+		return
+	}
+	plan.Changes = append(plan.Changes, Change{Op: OpUpdate, Kind: "feed", Name: spec.URL, Fields: fields, apply: func(ctx context.Context) error {
+		feed := *existing
+		setFields(&feed)
+		return s.feeds.UpdateFeed(ctx, &feed)
+	}})
+}
+
+// ref returns the ID of the named entry in ids, or nil for an empty name.
+func (p *Plan) ref(ids map[string]int64, name string) *int64 {
+	if name == "" {
+		return nil
+	}
+	id := ids[name]
+	return &id
+}
+
+// diffRef records a change of reference from current to the entry named
+// wanted, which is a change whenever that entry is still to be created.
+func diffRef(fields *[]string, field string, current *int64, currentNames map[int64]string, ids map[string]int64, wanted string) {
+	currentName := ""
+	if current != nil {
+		currentName = currentNames[*current]
+		if currentName == "" {
+			currentName = fmt.Sprintf("#%d", *current)
+		}
+	}
+	if wanted == "" {
+		diffField(fields, field, currentName, "")
+		return
+	}
+	if current == nil || ids[wanted] == 0 || ids[wanted] != *current {
+		*fields = append(*fields, fmt.Sprintf("%s: %q -> %q", field, currentName, wanted))
+	}
+}
+
+// diffField records "field: old -> new" when the values differ.
+func diffField[T comparable](fields *[]string, field string, old, new T) {
+	if old == new {
+		return
+	}
+	if oldString, isString := any(old).(string); isString {
+		*fields = append(*fields, fmt.Sprintf("%s: %q -> %q", field, oldString, any(new)))
+		return
+	}
+	*fields = append(*fields, fmt.Sprintf("%s: %v -> %v", field, old, new))
+}
+
+// botName is how a stored bot is shown: its description, or its ID.
+func botName(b *database.TelegramBot) string {
+	if b.Description != nil && *b.Description != "" {
+		return *b.Description
+	}
+	return fmt.Sprintf("#%d", b.ID)
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// optionalBool renders an optional setting for diffs.
+func optionalBool(b *bool) string {
+	if b == nil {
+		return "unset"
+	}
+	return fmt.Sprint(*b)
+}
@@ -0,0 +1,159 @@
+package feedsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDocument = `
+proxies:
+  - name: eu
+    type: socks5h
+    address: 10.0.0.1:1080
+    password: env:EU_PROXY_PASSWORD
+profiles:
+  - name: compact
+    config:
+      message_template: "{{.ItemTitle}}"
+      hashtags: ["news"]
+bots:
+  - name: main
+    token: env:SYNC_TEST_BOT_TOKEN
+feeds:
+  - url: https://example.com/a.xml
+    title: A
+    chat_id: "@channel"
+    bot: main
+    proxy: eu
+    profile: compact
+    frequency: 10m
+  - url: https://example.com/b.xml
+    chat_id: "-100123"
+    bot: main
+`
+
+func setupTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "sync.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSync(t *testing.T) {
+	t.Setenv("SYNC_TEST_BOT_TOKEN", "123:abc")
+	ctx := context.Background()
+	db := setupTestDB(t)
+	syncer := NewSyncer(db, 300)
+
+	doc, err := Parse([]byte(testDocument))
+	require.NoError(t, err)
+	plan, err := syncer.Plan(ctx, doc, false)
+	require.NoError(t, err)
+	assert.Contains(t, plan.String(), "5 to create, 0 to update, 0 to delete")
+	require.NoError(t, syncer.Apply(ctx, plan))
+
+	feeds, err := database.NewFeedStore(db).ListFeeds(ctx)
+	require.NoError(t, err)
+	require.Len(t, feeds, 2)
+	a := feeds[0]
+	assert.Equal(t, "A", *a.UserTitle)
+	assert.Equal(t, 600, a.FrequencySeconds)
+	require.NotNil(t, a.Proxy)
+	assert.Equal(t, "eu", a.Proxy.Name)
+	assert.Equal(t, "env:EU_PROXY_PASSWORD", *a.Proxy.Password, "secret references are stored as written")
+	require.NotNil(t, a.FormattingProfile)
+	assert.Equal(t, []string{"news"}, a.FormattingProfile.ParsedConfig.Hashtags)
+	assert.Equal(t, a.TelegramBotID, feeds[1].TelegramBotID)
+	assert.Equal(t, 300, feeds[1].FrequencySeconds, "default frequency")
+	assert.True(t, feeds[1].IsEnabled)
+
+	plan, err = syncer.Plan(ctx, doc, true)
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), plan.String())
+
+	// Drop feed b and the proxy, retitle a, and prune.
+	doc.Feeds = doc.Feeds[:1]
+	doc.Feeds[0].Title = "A2"
+	doc.Feeds[0].Proxy = ""
+	doc.Proxies = nil
+	plan, err = syncer.Plan(ctx, doc, true)
+	require.NoError(t, err)
+	diff := plan.String()
+	assert.Contains(t, diff, "~ feed https://example.com/a.xml\n    title: \"A\" -> \"A2\"\n    proxy: \"eu\" -> \"\"\n")
+	assert.Contains(t, diff, "- feed https://example.com/b.xml\n- proxy eu\n")
+	require.NoError(t, syncer.Apply(ctx, plan))
+
+	feeds, err = database.NewFeedStore(db).ListFeeds(ctx)
+	require.NoError(t, err)
+	require.Len(t, feeds, 1)
+	assert.Equal(t, "A2", *feeds[0].UserTitle)
+	assert.Nil(t, feeds[0].ProxyID)
+	proxies, err := database.NewProxyStore(db).ListProxies(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, proxies)
+}
+
+func TestParseRejectsInvalidDocuments(t *testing.T) {
+	for name, doc := range map[string]string{
+		"unknown key":     "feeds:\n  - url: https://x\n    chat_id: \"1\"\n    titel: typo\n",
+		"literal token":   "bots:\n  - name: main\n    token: \"123:abc\"\n",
+		"undefined bot":   "feeds:\n  - url: https://x\n    chat_id: \"1\"\n    bot: main\n",
+		"bad proxy type":  "proxies:\n  - name: p\n    type: ftp\n    address: x:1\n",
+		"unknown setting": "profiles:\n  - name: p\n    config:\n      no_such_setting: true\n",
+		"duplicate feed":  "feeds:\n  - url: https://x\n    chat_id: \"1\"\n  - url: https://x\n    chat_id: \"2\"\n",
+		"scrape source":   "feeds:\n  - url: https://x\n    chat_id: \"1\"\n    source_type: scrape\n",
+	} {
+		_, err := Parse([]byte(doc))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feeds.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testDocument), 0o600))
+	doc, err := Load(path)
+	require.NoError(t, err)
+	assert.Len(t, doc.Feeds, 2)
+}
+
+func TestSyncKeepsAutoCreatedTopic(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	syncer := NewSyncer(db, 300)
+	feeds := database.NewFeedStore(db)
+	thread := 42
+	feedID, err := feeds.CreateFeed(ctx, &database.Feed{URL: "https://example.com/a.xml", FrequencySeconds: 300, TelegramChatID: "@forum",
+		TelegramThreadID: &thread, AutoCreateTopic: true, IsEnabled: true})
+	require.NoError(t, err)
+
+	doc, err := Parse([]byte("feeds:\n  - url: https://example.com/a.xml\n    chat_id: \"@forum\"\n"))
+	require.NoError(t, err)
+	plan, err := syncer.Plan(ctx, doc, false)
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), "the topic the worker created isn't a difference: %s", plan)
+
+	doc.Feeds[0].Frequency = 10 * time.Minute
+	plan, err = syncer.Plan(ctx, doc, false)
+	require.NoError(t, err)
+	require.NoError(t, syncer.Apply(ctx, plan))
+	feed, err := feeds.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	require.NotNil(t, feed.TelegramThreadID, "other changes keep the topic")
+	assert.Equal(t, 42, *feed.TelegramThreadID)
+
+	doc.Feeds[0].ChatID = "@other"
+	plan, err = syncer.Plan(ctx, doc, false)
+	require.NoError(t, err)
+	require.NoError(t, syncer.Apply(ctx, plan))
+	feed, err = feeds.GetFeedByID(ctx, feedID)
+	require.NoError(t, err)
+	assert.Nil(t, feed.TelegramThreadID, "a new chat gets a new topic")
+}
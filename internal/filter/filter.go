@@ -0,0 +1,174 @@
+// Package filter decides which feed items are sent, by matching keywords and
+// regular expressions against their title, content, categories and author.
+package filter
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/mmcdole/gofeed"
+)
+
+// allFields are matched by filters that don't name fields.
+var allFields = []string{database.FilterFieldTitle, database.FilterFieldContent, database.FilterFieldCategories, database.FilterFieldAuthor}
+
+// stripPolicy removes all HTML from item content before matching.
+var stripPolicy = bluemonday.StrictPolicy()
+
+// rule is a compiled database.FeedFilter.
+type rule struct {
+	filter *database.FeedFilter
+	re     *regexp.Regexp
+	fields []string
+}
+
+// Set is the compiled filters of a feed.
+type Set struct {
+	include []rule
+	exclude []rule
+}
+
+// Compile checks and compiles filters. A nil Set, returned for no filters,
+// allows every item.
+func Compile(filters []*database.FeedFilter) (*Set, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	s := &Set{}
+	for _, f := range filters {
+		r, err := compileRule(f)
+		if err != nil {
+			return nil, fmt.Errorf("filter %d: %w", f.ID, err)
+		}
+		switch f.Action {
+		case database.FilterInclude:
+			s.include = append(s.include, r)
+		case database.FilterExclude:
+			s.exclude = append(s.exclude, r)
+		default:
+			return nil, fmt.Errorf("filter %d: unknown action %q", f.ID, f.Action)
+		}
+	}
+	return s, nil
+}
+
+// Validate reports whether f would compile.
+func Validate(f *database.FeedFilter) error {
+	_, err := compileRule(f)
+	return err
+}
+
+func compileRule(f *database.FeedFilter) (rule, error) {
+	if f.Action != database.FilterInclude && f.Action != database.FilterExclude {
+		return rule{}, fmt.Errorf("unknown action %q (expected %q or %q)", f.Action, database.FilterInclude, database.FilterExclude)
+	}
+	r := rule{filter: f, fields: f.Fields}
+	if len(r.fields) == 0 {
+		r.fields = allFields
+	}
+	for _, field := range r.fields {
+		switch field {
+		case database.FilterFieldTitle, database.FilterFieldContent, database.FilterFieldCategories, database.FilterFieldAuthor:
+		default:
+			return rule{}, fmt.Errorf("unknown field %q (expected one of %s)", field, strings.Join(allFields, ", "))
+		}
+	}
+	if strings.TrimSpace(f.Pattern) == "" {
+		return rule{}, fmt.Errorf("empty pattern")
+	}
+
+	var err error
+	switch f.MatchType {
+	case database.FilterKeyword, "":
+		r.re, err = regexp.Compile(keywordPattern(f.Pattern))
+	case database.FilterRegex:
+		r.re, err = regexp.Compile(f.Pattern)
+	default:
+		return rule{}, fmt.Errorf("unknown match type %q (expected %q or %q)", f.MatchType, database.FilterKeyword, database.FilterRegex)
+	}
+	if err != nil {
+		return rule{}, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return r, nil
+}
+
+// keywordPattern matches keyword as a whole word or phrase, ignoring case and
+// runs of whitespace. Word boundaries are only required next to letters and
+// digits, so "C++" and ".NET" match too.
+func keywordPattern(keyword string) string {
+	keyword = strings.TrimSpace(keyword)
+	words := strings.Fields(keyword)
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	pattern := `(?i)` + strings.Join(words, `\s+`)
+	if first, _ := utf8.DecodeRuneInString(keyword); isWordRune(first) {
+		pattern = `(?i)\b` + pattern[len(`(?i)`):]
+	}
+	if last, _ := utf8.DecodeLastRuneInString(keyword); isWordRune(last) {
+		pattern += `\b`
+	}
+	return pattern
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Allows reports whether item passes the filters: it matches none of the
+// exclude filters and, if there are include filters, at least one of them.
+// When it doesn't, the filter deciding it is returned.
+func (s *Set) Allows(item *gofeed.Item) (bool, *database.FeedFilter) {
+	if s == nil {
+		return true, nil
+	}
+	text := itemFields(item)
+	for _, r := range s.exclude {
+		if r.matches(text) {
+			return false, r.filter
+		}
+	}
+	if len(s.include) == 0 {
+		return true, nil
+	}
+	for _, r := range s.include {
+		if r.matches(text) {
+			return true, nil
+		}
+	}
+	return false, s.include[0].filter
+}
+
+func (r rule) matches(text map[string]string) bool {
+	for _, field := range r.fields {
+		if r.re.MatchString(text[field]) {
+			return true
+		}
+	}
+	return false
+}
+
+// itemFields returns the text of item's fields filters match against.
+func itemFields(item *gofeed.Item) map[string]string {
+	var authors []string
+	if item.Author != nil {
+		authors = append(authors, item.Author.Name, item.Author.Email)
+	}
+	for _, a := range item.Authors {
+		if a != nil {
+			authors = append(authors, a.Name, a.Email)
+		}
+	}
+	return map[string]string{
+		database.FilterFieldTitle:      item.Title,
+		database.FilterFieldContent:    html.UnescapeString(stripPolicy.Sanitize(item.Description + "\n" + item.Content)),
+		database.FilterFieldCategories: strings.Join(item.Categories, "\n"),
+		database.FilterFieldAuthor:     strings.Join(authors, "\n"),
+	}
+}
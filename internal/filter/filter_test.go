@@ -0,0 +1,74 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAllows(t *testing.T) {
+	items := map[string]*gofeed.Item{
+		"go":      {Title: "Go 1.30 released", Categories: []string{"golang"}},
+		"ad":      {Title: "Great deals", Description: "<p>This post is <b>Sponsored</b></p>"},
+		"google":  {Title: "Google announces things", Authors: []*gofeed.Person{{Name: "Ann Smith"}}},
+		"cpp":     {Title: "What's new in C++26"},
+		"phrase":  {Title: "Open   source news", Content: "<a href=\"https://example.com/go\">link</a>"},
+		"entity":  {Title: "Tips", Content: "Rock &amp; roll"},
+		"nothing": {Title: "Weather"},
+	}
+	allowed := func(s *Set) []string {
+		var names []string
+		for _, name := range []string{"go", "ad", "google", "cpp", "phrase", "entity", "nothing"} {
+			if ok, _ := s.Allows(items[name]); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	s, err := Compile(nil)
+	require.NoError(t, err)
+	assert.Len(t, allowed(s), len(items), "no filters allow everything")
+
+	s, err = Compile([]*database.FeedFilter{{ID: 1, Action: database.FilterExclude, Pattern: "sponsored"}})
+	require.NoError(t, err)
+	assert.NotContains(t, allowed(s), "ad", "keywords ignore case and HTML")
+	ok, by := s.Allows(items["ad"])
+	assert.False(t, ok)
+	assert.EqualValues(t, 1, by.ID)
+
+	s, err = Compile([]*database.FeedFilter{
+		{ID: 1, Action: database.FilterInclude, Pattern: "go", Fields: []string{database.FilterFieldTitle}},
+		{ID: 2, Action: database.FilterInclude, Pattern: "C++"},
+		{ID: 3, Action: database.FilterInclude, Pattern: "open source"},
+		{ID: 4, Action: database.FilterInclude, Pattern: "ann smith", Fields: []string{database.FilterFieldAuthor}},
+		{ID: 5, Action: database.FilterInclude, Pattern: "rock & roll", Fields: []string{database.FilterFieldContent}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "google", "cpp", "phrase", "entity"}, allowed(s), "keywords match whole words, and the link URL of phrase isn't content")
+
+	s, err = Compile([]*database.FeedFilter{
+		{ID: 1, Action: database.FilterInclude, MatchType: database.FilterRegex, Pattern: `^go`, Fields: []string{database.FilterFieldCategories}},
+		{ID: 2, Action: database.FilterExclude, MatchType: database.FilterRegex, Pattern: `1\.30`},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, allowed(s), "exclude filters win over include filters")
+	ok, by = s.Allows(items["nothing"])
+	assert.False(t, ok)
+	assert.EqualValues(t, 1, by.ID)
+
+	for _, f := range []*database.FeedFilter{
+		{Action: "drop", Pattern: "x"},
+		{Action: database.FilterExclude, Pattern: " "},
+		{Action: database.FilterExclude, MatchType: database.FilterRegex, Pattern: "("},
+		{Action: database.FilterExclude, MatchType: "glob", Pattern: "x"},
+		{Action: database.FilterExclude, Pattern: "x", Fields: []string{"link"}},
+	} {
+		assert.Error(t, Validate(f), "%+v", f)
+		_, err := Compile([]*database.FeedFilter{f})
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,99 @@
+package formatter
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/mmcdole/gofeed"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultDigestTemplate lists the linked titles of a digest's items.
+const defaultDigestTemplate = `<b>{{escapeHTML .FeedTitle}}</b>: {{len .Items}} new {{if eq (len .Items) 1}}item{{else}}items{{end}}
+{{range .Items}}
+• {{if .ItemLink}}<a href="{{escapeHTML .ItemLink}}">{{escapeHTML (or .ItemTitle .ItemLink)}}</a>{{else}}{{escapeHTML .ItemTitle}}{{end}}
+{{- end}}`
+
+// plainTextPolicy strips all HTML, for ItemSummary in digests.
+var plainTextPolicy = bluemonday.StrictPolicy()
+
+// FormatDigest combines the items of a feed in digest mode into one message,
+// rendered with the profile's digest_template. Templates see FeedTitle,
+// FeedURL, From, To, Hashtags and Items, each with ItemTitle, ItemLink,
+// ItemAuthor, ItemDate, ItemSummary (plain text) and ItemContent (Telegram
+// HTML).
+func (f *DefaultFormatter) FormatDigest(ctx context.Context, items []*gofeed.Item, feed *database.Feed, feedTitle string, from, to time.Time, profile *database.FormattingProfile) ([]interfaces.FormattedMessagePart, error) {
+	var cfg database.FormattingProfileConfig
+	if profile != nil {
+		if err := profile.UnmarshalConfig(); err != nil {
+			log.Warn().Err(err).Int64("profile_id", profile.ID).Msg("Failed to unmarshal formatting profile config, using defaults.")
+		} else {
+			cfg = profile.ParsedConfig
+		}
+	}
+
+	title := feed.URL
+	if feed.UserTitle != nil && *feed.UserTitle != "" {
+		title = *feed.UserTitle
+	} else if feedTitle != "" {
+		title = feedTitle
+	}
+
+	itemData := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		content := item.Content
+		if content == "" {
+			content = item.Description
+		}
+		data := map[string]interface{}{
+			"ItemTitle":   item.Title,
+			"ItemLink":    item.Link,
+			"ItemAuthor":  "",
+			"ItemDate":    item.PublishedParsed,
+			"ItemSummary": strings.TrimSpace(html.UnescapeString(plainTextPolicy.Sanitize(item.Description))),
+			"ItemContent": telegramHTMLPolicy.Sanitize(content),
+		}
+		if item.Author != nil {
+			data["ItemAuthor"] = item.Author.Name
+		}
+		itemData = append(itemData, data)
+	}
+
+	tmpl := cfg.DigestTemplate
+	if tmpl == "" {
+		tmpl = defaultDigestTemplate
+	}
+	message, err := renderTemplate("digest", tmpl, map[string]interface{}{
+		"FeedTitle": title,
+		"FeedURL":   feed.URL,
+		"From":      from,
+		"To":        to,
+		"Hashtags":  strings.Join(cfg.Hashtags, " "),
+		"Items":     itemData,
+	})
+	if err != nil {
+		return nil, err
+	}
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return nil, fmt.Errorf("digest template produced an empty message")
+	}
+
+	if cfg.UseTelegraphThresholdChars > 0 && len(message) > cfg.UseTelegraphThresholdChars {
+		telegraphURL, err := createTelegraphPost(title, message, "")
+		if err == nil {
+			return []interfaces.FormattedMessagePart{{
+				Text:      fmt.Sprintf("View the digest on Telegraph: %s", telegraphURL),
+				ParseMode: defaultParseMode,
+			}}, nil
+		}
+		log.Error().Err(err).Msg("Failed to create Telegraph post, will send directly or split.")
+	}
+	return []interfaces.FormattedMessagePart{{Text: message, ParseMode: defaultParseMode}}, nil
+}
@@ -0,0 +1,43 @@
+package formatter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatDigest(t *testing.T) {
+	items := []*gofeed.Item{
+		{Title: "First <post>", Link: "https://example.com/1", Description: "<p>Fish &amp; chips</p>"},
+		{Link: "https://example.com/2"},
+	}
+	feed := &database.Feed{URL: "https://example.com/feed.xml"}
+	from := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+	f := NewDefaultFormatter()
+
+	parts, err := f.FormatDigest(context.Background(), items, feed, "Example & Co", from, to, nil)
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	assert.Equal(t, "HTML", parts[0].ParseMode)
+	assert.Equal(t, `<b>Example &amp; Co</b>: 2 new items
+
+• <a href="https://example.com/1">First &lt;post&gt;</a>
+• <a href="https://example.com/2">https://example.com/2</a>`, parts[0].Text)
+
+	profile := &database.FormattingProfile{ConfigJSON: `{"digest_template": "{{.FeedTitle}} {{.To.Format \"Jan 2\"}}{{range .Items}} | {{.ItemSummary}}{{end}}"}`}
+	title := "Mine"
+	feed.UserTitle = &title
+	parts, err = f.FormatDigest(context.Background(), items[:1], feed, "Example", from, to, profile)
+	require.NoError(t, err)
+	assert.Equal(t, "Mine Oct 15 | Fish & chips", parts[0].Text)
+
+	profile.ConfigJSON = `{"digest_template": "{{.FeedTitle"}`
+	_, err = f.FormatDigest(context.Background(), items, feed, "", from, to, profile)
+	assert.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package formatter
+
+import (
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+// Media kinds reported in ItemMedia.Medium.
+const (
+	MediumImage    = "image"
+	MediumVideo    = "video"
+	MediumAudio    = "audio"
+	MediumDocument = "document"
+)
+
+// ItemMedia is a media object an item references through media:content (also
+// inside media:group), an enclosure, media:thumbnail, itunes:image or the
+// item's image.
+type ItemMedia struct {
+	URL    string
+	Type   string // MIME type, when declared
+	Medium string // One of the Medium constants
+}
+
+// itemMedia lists an item's media, full-size media first, without duplicates.
+func itemMedia(item *gofeed.Item) []ItemMedia {
+	var media []ItemMedia
+	seen := make(map[string]bool)
+	add := func(rawURL, mimeType, medium string) {
+		rawURL = strings.TrimSpace(rawURL)
+		if rawURL == "" || seen[rawURL] {
+			return
+		}
+		seen[rawURL] = true
+		media = append(media, ItemMedia{URL: rawURL, Type: mimeType, Medium: mediumOf(medium, mimeType, rawURL)})
+	}
+
+	mediaExt := item.Extensions["media"]
+	contents := mediaExt["content"]
+	for _, group := range mediaExt["group"] {
+		contents = append(contents, group.Children["content"]...)
+	}
+	for _, c := range contents {
+		add(c.Attrs["url"], c.Attrs["type"], c.Attrs["medium"])
+	}
+	for _, enc := range item.Enclosures {
+		if enc != nil {
+			add(enc.URL, enc.Type, "")
+		}
+	}
+	thumbnails := mediaExt["thumbnail"]
+	for _, group := range mediaExt["group"] {
+		thumbnails = append(thumbnails, group.Children["thumbnail"]...)
+	}
+	for _, t := range thumbnails {
+		add(t.Attrs["url"], "", MediumImage)
+	}
+	if item.ITunesExt != nil {
+		add(item.ITunesExt.Image, "", MediumImage)
+	}
+	if item.Image != nil {
+		add(item.Image.URL, "", MediumImage)
+	}
+	for _, src := range inlineImages(item) {
+		add(src, mime.TypeByExtension(strings.ToLower(path.Ext(src))), MediumImage)
+	}
+	return media
+}
+
+// inlineImages returns the absolute http(s) URLs of the <img> tags in an
+// item's content and description, resolved against its link. Images sized
+// 1x1, usually tracking pixels, are left out.
+func inlineImages(item *gofeed.Item) []string {
+	base, _ := url.Parse(item.Link)
+	var srcs []string
+	for _, body := range []string{item.Content, item.Description} {
+		if !strings.Contains(body, "<img") {
+			continue
+		}
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+		if err != nil {
+			continue
+		}
+		doc.Find("img[src]").Each(func(_ int, img *goquery.Selection) {
+			if img.AttrOr("width", "") == "1" || img.AttrOr("height", "") == "1" {
+				return
+			}
+			u, err := url.Parse(strings.TrimSpace(img.AttrOr("src", "")))
+			if err != nil {
+				return
+			}
+			if base != nil {
+				u = base.ResolveReference(u)
+			}
+			if u.Scheme == "http" || u.Scheme == "https" {
+				srcs = append(srcs, u.String())
+			}
+		})
+	}
+	return srcs
+}
+
+// mediumOf classifies media by its declared medium, else its MIME type, else
+// its file extension.
+func mediumOf(medium, mimeType, rawURL string) string {
+	switch medium {
+	case MediumImage, MediumVideo, MediumAudio, MediumDocument:
+		return medium
+	}
+	if mimeType == "" {
+		if u, err := url.Parse(rawURL); err == nil {
+			mimeType = mime.TypeByExtension(strings.ToLower(path.Ext(u.Path)))
+		}
+	}
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return MediumImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return MediumVideo
+	case strings.HasPrefix(mimeType, "audio/"):
+		return MediumAudio
+	}
+	return MediumDocument
+}
+
+// firstMediaURL returns the URL of the first media of the given kind, or "".
+func firstMediaURL(media []ItemMedia, medium string) string {
+	for _, m := range media {
+		if m.Medium == medium {
+			return m.URL
+		}
+	}
+	return ""
+}
+
+// extensionTemplateData returns the template fields drawn from an item's
+// namespaced extensions.
+func extensionTemplateData(item *gofeed.Item, media []ItemMedia) map[string]interface{} {
+	data := map[string]interface{}{
+		"ItemMedia":      media,
+		"ItemImage":      firstMediaURL(media, MediumImage),
+		"ItemVideo":      firstMediaURL(media, MediumVideo),
+		"ItemAudio":      firstMediaURL(media, MediumAudio),
+		"ItemDuration":   "",
+		"ItemCreator":    "",
+		"ItemCategories": item.Categories,
+		"ItemExtensions": item.Extensions,
+	}
+	if item.ITunesExt != nil {
+		data["ItemDuration"] = item.ITunesExt.Duration
+	}
+	if item.DublinCoreExt != nil && len(item.DublinCoreExt.Creator) > 0 {
+		data["ItemCreator"] = item.DublinCoreExt.Creator[0]
+	}
+	return data
+}
+
+// extValue returns the text of the first namespace:name extension element,
+// e.g. {{ ext .ItemExtensions "media" "credit" }}.
+func extValue(exts ext.Extensions, namespace, name string) string {
+	if values := exts[namespace][name]; len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// extAttr returns an attribute of the first namespace:name extension element,
+// e.g. {{ extAttr .ItemExtensions "media" "content" "url" }}.
+func extAttr(exts ext.Extensions, namespace, name, attr string) string {
+	if values := exts[namespace][name]; len(values) > 0 {
+		return values[0].Attrs[attr]
+	}
+	return ""
+}
+
+// attachableMedia picks the media to send with an item: its first image or
+// video whose URL doesn't match filter. Audio and documents are left to templates.
+func attachableMedia(media []ItemMedia, filter *regexp.Regexp) (ItemMedia, bool) {
+	for _, m := range media {
+		if m.Medium != MediumImage && m.Medium != MediumVideo {
+			continue
+		}
+		if filter != nil && filter.MatchString(m.URL) {
+			continue
+		}
+		return m, true
+	}
+	return ItemMedia{}, false
+}
+
+// albumMedia picks up to limit images and videos whose URLs don't match
+// filter for an album. GIFs are left out, as albums can't hold animations.
+func albumMedia(media []ItemMedia, filter *regexp.Regexp, limit int) []ItemMedia {
+	if limit < 2 {
+		return nil
+	}
+	var album []ItemMedia
+	for _, m := range media {
+		if len(album) == limit {
+			break
+		}
+		if m.Medium != MediumImage && m.Medium != MediumVideo || m.Type == "image/gif" {
+			continue
+		}
+		if filter != nil && filter.MatchString(m.URL) {
+			continue
+		}
+		album = append(album, m)
+	}
+	return album
+}
@@ -0,0 +1,93 @@
+package formatter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/rss"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const extensionsFeed = `<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/"
+	xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel><title>Show</title>
+<item>
+	<title>Episode 1</title>
+	<link>https://example.com/ep1</link>
+	<dc:creator>Jane Host</dc:creator>
+	<itunes:duration>42:10</itunes:duration>
+	<itunes:image href="https://example.com/cover.jpg"/>
+	<enclosure url="https://example.com/ep1.mp3" type="audio/mpeg" length="1"/>
+	<media:group>
+		<media:content url="https://example.com/pixel.gif" medium="image"/>
+		<media:content url="https://example.com/ep1.mp4" type="video/mp4"/>
+	</media:group>
+	<media:thumbnail url="https://example.com/thumb.png"/>
+	<media:credit>Photo Desk</media:credit>
+</item>
+</channel></rss>`
+
+func TestExtensionTemplateData(t *testing.T) {
+	feed, err := rss.ParseFeed(strings.NewReader(extensionsFeed))
+	require.NoError(t, err)
+	item := feed.Items[0]
+
+	media := itemMedia(item)
+	var urls []string
+	for _, m := range media {
+		urls = append(urls, m.URL)
+	}
+	assert.Equal(t, []string{
+		"https://example.com/pixel.gif", "https://example.com/ep1.mp4", "https://example.com/ep1.mp3",
+		"https://example.com/thumb.png", "https://example.com/cover.jpg",
+	}, urls)
+
+	profile := &database.FormattingProfile{ConfigJSON: `{
+		"message_template": "{{.ItemCreator}} | {{.ItemDuration}} | {{.ItemAudio}} | {{ext .ItemExtensions \"media\" \"credit\"}} | {{extAttr .ItemExtensions \"media\" \"thumbnail\" \"url\"}}",
+		"attach_media": true,
+		"media_filter_regex": "pixel"
+	}`}
+	parts, err := NewDefaultFormatter().FormatItem(context.Background(), item, &database.Feed{URL: "https://example.com/feed"}, profile)
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	assert.Equal(t, "Jane Host | 42:10 | https://example.com/ep1.mp3 | Photo Desk | https://example.com/thumb.png", parts[0].Text)
+	assert.Equal(t, "https://example.com/ep1.mp4", parts[0].VideoURL, "filtered media is skipped")
+	assert.Empty(t, parts[0].PhotoURL)
+}
+
+func TestInlineImagesAndAlbums(t *testing.T) {
+	item := &gofeed.Item{
+		Title:       "Gallery",
+		Link:        "https://example.com/posts/gallery",
+		Description: `<p>Look</p><img src="/img/a.jpg"><img src="https://cdn.example.com/b.png" alt="b"><img src="https://t.example.com/p.gif" width="1" height="1"><img src="data:image/png;base64,AAAA">`,
+		Enclosures:  []*gofeed.Enclosure{{URL: "https://example.com/clip.mp4", Type: "video/mp4"}},
+	}
+	media := itemMedia(item)
+	var urls []string
+	for _, m := range media {
+		urls = append(urls, m.URL)
+	}
+	assert.Equal(t, []string{"https://example.com/clip.mp4", "https://example.com/img/a.jpg", "https://cdn.example.com/b.png"}, urls)
+
+	feed := &database.Feed{URL: "https://example.com/feed"}
+	profile := &database.FormattingProfile{ConfigJSON: `{"attach_media": true, "media_album_limit": 2}`}
+	parts, err := NewDefaultFormatter().FormatItem(context.Background(), item, feed, profile)
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	assert.Equal(t, []interfaces.MediaGroupItem{
+		{Type: "video", URL: "https://example.com/clip.mp4"},
+		{Type: "photo", URL: "https://example.com/img/a.jpg"},
+	}, parts[0].MediaGroup)
+	assert.Equal(t, "https://example.com/clip.mp4", parts[0].VideoURL, "the first album item is attached for notifiers without albums")
+
+	profile = &database.FormattingProfile{ConfigJSON: `{"attach_media": true}`}
+	parts, err = NewDefaultFormatter().FormatItem(context.Background(), item, feed, profile)
+	require.NoError(t, err)
+	assert.Empty(t, parts[0].MediaGroup)
+	assert.Equal(t, "https://example.com/clip.mp4", parts[0].VideoURL)
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"html"
 	"regexp"
 	"strings"
 	"text/template"
@@ -16,6 +15,8 @@ import (
 	"github.com/mmcdole/gofeed"
 	"github.com/rs/zerolog/log"
 	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/rss"
+	"github.com/haytac/rss-telegram-bot/internal/telegram"
 	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
 )
 
@@ -86,10 +87,17 @@ func (f *DefaultFormatter) FormatItem(ctx context.Context, item *gofeed.Item, fe
 		"ItemAuthor":  "",
 		"ItemDate":    item.PublishedParsed,
 		"Hashtags":    strings.Join(cfg.Hashtags, " "),
+		// JSON Feed external_url (linkblogs) and attachments/enclosures
+		"ItemExternalURL": item.Custom[rss.CustomKeyExternalURL],
+		"ItemEnclosures":  item.Enclosures,
 	}
 	if item.Author != nil {
 		templateData["ItemAuthor"] = item.Author.Name
 	}
+	media := itemMedia(item)
+	for key, value := range extensionTemplateData(item, media) {
+		templateData[key] = value
+	}
 
 	finalTitle := item.Title
 	if cfg.TitleTemplate != "" {
@@ -135,12 +143,12 @@ func (f *DefaultFormatter) FormatItem(ctx context.Context, item *gofeed.Item, fe
 		if finalTitle != "" {
 			// Title is already processed by template or is raw, escape it for safety if not HTML already.
 			// Assuming finalTitle is plain text here.
-			sb.WriteString(fmt.Sprintf("<b>%s</b>\n", html.EscapeString(finalTitle)))
+			sb.WriteString(fmt.Sprintf("<b>%s</b>\n", telegram.EscapeHTML(finalTitle)))
 		}
 		sb.WriteString(messageBody) // messageBody is already sanitized HTML
 		if item.Link != "" {
 			// Ensure item.Link is properly escaped if it could contain special chars, though usually URLs are fine.
-			sb.WriteString(fmt.Sprintf("\n<a href=\"%s\">Read more</a>", html.EscapeString(item.Link)))
+			sb.WriteString(fmt.Sprintf("\n<a href=\"%s\">Read more</a>", telegram.EscapeHTML(item.Link)))
 		}
 		messageBody = sb.String()
 	}
@@ -155,7 +163,7 @@ func (f *DefaultFormatter) FormatItem(ctx context.Context, item *gofeed.Item, fe
 	fullMessage.WriteString(messageBody)
 
 	if cfg.IncludeAuthor && item.Author != nil && item.Author.Name != "" && !strings.Contains(messageBody, item.Author.Name) {
-		fullMessage.WriteString(fmt.Sprintf("\n\n<i>Author: %s</i>", html.EscapeString(item.Author.Name)))
+		fullMessage.WriteString(fmt.Sprintf("\n\n<i>Author: %s</i>", telegram.EscapeHTML(item.Author.Name)))
 	}
 	if len(cfg.Hashtags) > 0 { // Simpler: just add hashtags if configured, template might handle placement
 		hasHashtagsAlready := false
@@ -190,8 +198,10 @@ func (f *DefaultFormatter) FormatItem(ctx context.Context, item *gofeed.Item, fe
 		telegraphURL, err := createTelegraphPost(finalTitle, finalMessage, authorNameForTelegraph)
 		if err == nil {
 			parts = append(parts, interfaces.FormattedMessagePart{
-				Text:      fmt.Sprintf("View full post on Telegraph: %s", telegraphURL),
-				ParseMode: defaultParseMode, // Or "" if it's just a link
+				Text:          fmt.Sprintf("View full post on Telegraph: %s", telegraphURL),
+				ParseMode:     defaultParseMode, // Or "" if it's just a link
+				Reaction:      reactionFor(cfg, item),
+				DiscussButton: cfg.DiscussButton,
 			})
 			return parts, nil
 		}
@@ -200,10 +210,76 @@ func (f *DefaultFormatter) FormatItem(ctx context.Context, item *gofeed.Item, fe
 
 	// The finalMessage is already HTML-sanitized for Telegram.
 	// The telegram.Client's SplitMessage will handle length.
-	parts = append(parts, interfaces.FormattedMessagePart{Text: finalMessage, ParseMode: defaultParseMode})
+	part := interfaces.FormattedMessagePart{Text: finalMessage, ParseMode: defaultParseMode, Reaction: reactionFor(cfg, item), DiscussButton: cfg.DiscussButton}
+	if cfg.AttachMedia {
+		attachMedia(&part, media, cfg.MediaFilterRegex, cfg.MediaAlbumLimit)
+	}
+	parts = append(parts, part)
 	return parts, nil
 }
 
+// maxAlbumMedia is the most photos and videos Telegram takes in one album.
+const maxAlbumMedia = 10
+
+// attachMedia sends the item's first image or video with the message, which
+// becomes its caption, or with albumLimit of 2 or more up to that many as an
+// album when the item has several. Media URLs matching filterRegex are skipped.
+func attachMedia(part *interfaces.FormattedMessagePart, media []ItemMedia, filterRegex string, albumLimit int) {
+	var filter *regexp.Regexp
+	if filterRegex != "" {
+		var err error
+		if filter, err = regexp.Compile(filterRegex); err != nil {
+			log.Warn().Err(err).Str("regex", filterRegex).Msg("Invalid media filter regex, ignoring it")
+			filter = nil
+		}
+	}
+	m, ok := attachableMedia(media, filter)
+	if !ok {
+		return
+	}
+	if albumLimit > maxAlbumMedia {
+		albumLimit = maxAlbumMedia
+	}
+	if album := albumMedia(media, filter, albumLimit); len(album) >= 2 {
+		for _, am := range album {
+			kind := "photo"
+			if am.Medium == MediumVideo {
+				kind = "video"
+			}
+			part.MediaGroup = append(part.MediaGroup, interfaces.MediaGroupItem{Type: kind, URL: am.URL})
+		}
+		m = album[0]
+	}
+	switch {
+	case m.Medium == MediumImage && m.Type == "image/gif":
+		part.AnimationURL = m.URL
+	case m.Medium == MediumImage:
+		part.PhotoURL = m.URL
+	default:
+		part.VideoURL = m.URL
+	}
+}
+
+// reactionFor returns the emoji reaction configured for the item, or "" if the
+// profile has no reaction or the item does not match ReactionMatchRegex.
+func reactionFor(cfg database.FormattingProfileConfig, item *gofeed.Item) string {
+	if cfg.ReactionEmoji == "" {
+		return ""
+	}
+	if cfg.ReactionMatchRegex == "" {
+		return cfg.ReactionEmoji
+	}
+	re, err := regexp.Compile(cfg.ReactionMatchRegex)
+	if err != nil {
+		log.Warn().Err(err).Str("regex", cfg.ReactionMatchRegex).Msg("Invalid reaction match regex, skipping reaction")
+		return ""
+	}
+	if re.MatchString(item.Title) || re.MatchString(item.Description) || re.MatchString(item.Content) {
+		return cfg.ReactionEmoji
+	}
+	return ""
+}
+
 
 // ... (renderTemplate, replaceEmojiImages, createTelegraphPost remain the same) ...
 func renderTemplate(name, tmplStr string, data interface{}) (string, error) {
@@ -224,7 +300,12 @@ func renderTemplate(name, tmplStr string, data interface{}) (string, error) {
 			}
 			return string(runes[:length]) + "..."
 		},
-		"escapeHTML": html.EscapeString,
+		"ext":                  extValue,
+		"extAttr":              extAttr,
+		"escapeHTML":           telegram.EscapeHTML,
+		"escapeMarkdownV2":     telegram.EscapeMarkdownV2,
+		"escapeMarkdownV2Code": telegram.EscapeMarkdownV2Code,
+		"escapeMarkdownV2URL":  telegram.EscapeMarkdownV2URL,
 	}).Parse(tmplStr)
 	if err != nil {
 		return "", fmt.Errorf("parsing template %s: %w", name, err)
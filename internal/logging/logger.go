@@ -3,6 +3,9 @@ package logging
 import (
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
+
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -38,15 +41,16 @@ func Setup(cfg Config) {
 	}
 
 	multi := zerolog.MultiLevelWriter(writers...)
-	log.Logger = zerolog.New(multi).With().Timestamp().Logger()
+	output = multi
+	filter.w = multi
+	log.Logger = zerolog.New(&filter).With().Timestamp().Logger()
 
 	level, err := zerolog.ParseLevel(cfg.Level)
 	if err != nil {
 		log.Warn().Str("configured_level", cfg.Level).Msg("Invalid log level, defaulting to info")
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	} else {
-		zerolog.SetGlobalLevel(level)
+		level = zerolog.InfoLevel
 	}
+	setConfiguredLevel(level)
 
 	log.Info().Str("level", zerolog.GlobalLevel().String()).Msg("Logger initialized")
 }
@@ -54,4 +58,75 @@ func Setup(cfg Config) {
 // ContextualLogger creates a logger with context fields.
 func ContextualLogger(ctx map[string]interface{}) zerolog.Logger {
 	return log.With().Fields(ctx).Logger()
-}
\ No newline at end of file
+}
+
+// SetLevel changes the global log level, e.g. after a config reload.
+func SetLevel(level string) error {
+	parsed, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	setConfiguredLevel(parsed)
+	return nil
+}
+
+// The configured level is applied by filter rather than zerolog's global
+// level, so that Verbose loggers, which write to output directly, can log
+// below it. The global level only drops to debug while a Verbose logger is in
+// use, keeping disabled debug logging cheap the rest of the time.
+var (
+	output        io.Writer // Unfiltered destination of all loggers; nil until Setup
+	filter        levelFilter
+	configured    atomic.Int32 // zerolog.Level from the config
+	verboseActive atomic.Int32 // Verbose loggers in use
+	globalMu      sync.Mutex
+)
+
+// levelFilter drops events below the configured level.
+type levelFilter struct {
+	w zerolog.LevelWriter
+}
+
+func (f *levelFilter) Write(p []byte) (int, error) {
+	return f.w.Write(p)
+}
+
+func (f *levelFilter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level != zerolog.NoLevel && level < zerolog.Level(configured.Load()) {
+		return len(p), nil
+	}
+	return f.w.WriteLevel(level, p)
+}
+
+func setConfiguredLevel(level zerolog.Level) {
+	configured.Store(int32(level))
+	applyGlobalLevel()
+}
+
+func applyGlobalLevel() {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	level := zerolog.Level(configured.Load())
+	if verboseActive.Load() > 0 && level > zerolog.DebugLevel {
+		level = zerolog.DebugLevel
+	}
+	zerolog.SetGlobalLevel(level)
+}
+
+// Verbose returns a copy of l that logs at debug level whatever the
+// configured level, e.g. for a single feed being debugged, and a function to
+// call once it is no longer used.
+func Verbose(l zerolog.Logger) (zerolog.Logger, func()) {
+	if output == nil {
+		return l.Level(zerolog.DebugLevel), func() {}
+	}
+	verboseActive.Add(1)
+	applyGlobalLevel()
+	var once sync.Once
+	return l.Output(output).Level(zerolog.DebugLevel), func() {
+		once.Do(func() {
+			verboseActive.Add(-1)
+			applyGlobalLevel()
+		})
+	}
+}
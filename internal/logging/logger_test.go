@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerbose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.log")
+	Setup(Config{Level: "info", File: path})
+	defer zerolog.SetGlobalLevel(zerolog.TraceLevel)
+
+	log.Debug().Msg("global debug")
+	l, done := Verbose(log.With().Int64("feed_id", 7).Logger())
+	assert.Equal(t, zerolog.DebugLevel, zerolog.GlobalLevel())
+	l.Debug().Msg("feed debug")
+	log.Debug().Msg("other debug while verbose")
+	done()
+	done() // Idempotent
+	assert.Equal(t, zerolog.InfoLevel, zerolog.GlobalLevel())
+	l.Info().Msg("feed info")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	out := string(data)
+	assert.Contains(t, out, "feed debug")
+	assert.Contains(t, out, `"feed_id":7`)
+	assert.Contains(t, out, "feed info")
+	assert.NotContains(t, out, "global debug")
+	assert.NotContains(t, out, "other debug while verbose")
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// readinessTimeout bounds how long /readyz waits for all checks.
+const readinessTimeout = 5 * time.Second
+
+// ReadinessCheck reports why the bot isn't ready to do its work, or nil.
+type ReadinessCheck func(ctx context.Context) error
+
+var (
+	readinessMu     sync.RWMutex
+	readinessChecks = map[string]ReadinessCheck{}
+)
+
+// AddReadinessCheck registers a check run by /readyz under name.
+func AddReadinessCheck(name string, check ReadinessCheck) {
+	readinessMu.Lock()
+	defer readinessMu.Unlock()
+	readinessChecks[name] = check
+}
+
+// healthz answers as long as the process can serve HTTP.
+func healthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// readyz runs every readiness check and answers 200 if all pass, otherwise
+// 503. The body lists each check's result.
+func readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	readinessMu.RLock()
+	names := make([]string, 0, len(readinessChecks))
+	for name := range readinessChecks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]ReadinessCheck, len(names))
+	for i, name := range names {
+		checks[i] = readinessChecks[name]
+	}
+	readinessMu.RUnlock()
+
+	results := make(map[string]string, len(names))
+	status := http.StatusOK
+	for i, check := range checks {
+		if err := check(ctx); err != nil {
+			results[names[i]] = err.Error()
+			status = http.StatusServiceUnavailable
+		} else {
+			results[names[i]] = "ok"
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"ready": status == http.StatusOK, "checks": results})
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthz(t *testing.T) {
+	rec := httptest.NewRecorder()
+	healthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestReadyz(t *testing.T) {
+	var dbErr error
+	AddReadinessCheck("database", func(context.Context) error { return dbErr })
+	AddReadinessCheck("scheduler", func(context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"ready": true, "checks": {"database": "ok", "scheduler": "ok"}}`, rec.Body.String())
+
+	dbErr = errors.New("database is locked")
+	rec = httptest.NewRecorder()
+	readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"ready": false, "checks": {"database": "database is locked", "scheduler": "ok"}}`, rec.Body.String())
+}
@@ -28,6 +28,15 @@ var (
 		},
 		[]string{"feed_url"},
 	)
+
+	// ItemsFiltered counts new items skipped by feed filters.
+	ItemsFiltered = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rssbot_items_filtered_total",
+			Help: "Total number of new RSS items skipped by feed filters.",
+		},
+		[]string{"feed_url"},
+	)
 	
 	// HTTPCacheEvents counts cache hits and misses for RSS fetching.
 	HTTPCacheEvents = promauto.NewCounterVec(
@@ -55,9 +64,154 @@ var (
             Help: "Number of currently active feed processing goroutines.",
         },
     )
+
+	// PipelineQueued reports the feed runs waiting in each pipeline stage.
+	PipelineQueued = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rssbot_pipeline_queued",
+			Help: "Feed runs waiting for a pipeline stage.",
+		},
+		[]string{"stage"}, // fetch, process, send
+	)
+
+	// FeedNewestItemAge reports how long ago each feed last published an item.
+	FeedNewestItemAge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rssbot_feed_newest_item_age_seconds",
+			Help: "Seconds since the newest item seen in the feed was published.",
+		},
+		[]string{"feed_url"},
+	)
+
+	// ProxyUp is 1 while a proxy passes its health checks and 0 once it is considered down.
+	ProxyUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rssbot_proxy_up",
+			Help: "Whether the proxy is considered healthy (1) or down (0).",
+		},
+		[]string{"proxy"},
+	)
+
+	// ProxyHealthChecks counts proxy health checks by result.
+	ProxyHealthChecks = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rssbot_proxy_health_checks_total",
+			Help: "Total number of proxy health checks.",
+		},
+		[]string{"proxy", "result"}, // result: "ok", "failed"
+	)
+
+	// ProxyProbeDuration reports how long each proxy's last health probe took.
+	ProxyProbeDuration = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rssbot_proxy_probe_duration_seconds",
+			Help: "Duration of the last health probe through the proxy.",
+		},
+		[]string{"proxy"},
+	)
+
+	// ProxyRequests counts requests sent through each proxy by result.
+	ProxyRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rssbot_proxy_requests_total",
+			Help: "Total number of HTTP requests sent through the proxy.",
+		},
+		[]string{"proxy", "result"}, // result: "ok", "error" (no response)
+	)
+
+	// ProxyRequestDuration observes the time from sending a request through a proxy to its response headers.
+	ProxyRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rssbot_proxy_request_duration_seconds",
+			Help:    "Time to response headers for requests sent through the proxy.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"proxy"},
+	)
+
+	// ProxyBytes counts request and response body bytes carried by each proxy.
+	ProxyBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rssbot_proxy_bytes_total",
+			Help: "Total number of body bytes sent and received through the proxy.",
+		},
+		[]string{"proxy", "direction"}, // direction: "sent", "received"
+	)
+
+	// FetchDuration observes successful feed fetches, from the first request
+	// to the body being read, including retries but not parsing.
+	FetchDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rssbot_fetch_duration_seconds",
+			Help:    "Time to fetch a feed, excluding parsing.",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		},
+		[]string{"feed_url"},
+	)
+
+	// ParseDuration observes parsing fetched or pushed feed documents.
+	ParseDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rssbot_parse_duration_seconds",
+			Help:    "Time to parse a feed document.",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+		},
+		[]string{"feed_url"},
+	)
+
+	// FormatDuration observes formatting one item into message parts.
+	FormatDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rssbot_format_duration_seconds",
+			Help:    "Time to format an item for Telegram.",
+			Buckets: []float64{0.0005, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 1},
+		},
+		[]string{"feed_url"},
+	)
+
+	// SendDuration observes delivering one item's messages to Telegram,
+	// including rate limit waits.
+	SendDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rssbot_telegram_send_duration_seconds",
+			Help:    "Time to send an item's messages to Telegram.",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"feed_url", "status"}, // status: success, error
+	)
+
+	// ItemDeliveryLatency observes the time from an item's publication date to
+	// its delivery. Items without a date, or dated in the future, are skipped.
+	ItemDeliveryLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rssbot_item_delivery_latency_seconds",
+			Help:    "Time from an item being published to it being delivered.",
+			Buckets: []float64{10, 30, 60, 300, 900, 1800, 3600, 3 * 3600, 6 * 3600, 12 * 3600, 86400, 7 * 86400},
+		},
+		[]string{"feed_url"},
+	)
+
+	// FeedConsecutiveFailures reports how many fetches of each feed failed in a row.
+	FeedConsecutiveFailures = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rssbot_feed_consecutive_failures",
+			Help: "Failed fetches of the feed since its last successful one.",
+		},
+		[]string{"feed_url"},
+	)
+
+	// FeedStale is 1 for feeds that have published nothing within their stale threshold.
+	FeedStale = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rssbot_feed_stale",
+			Help: "Whether the feed is considered stale (1) or not (0).",
+		},
+		[]string{"feed_url"},
+	)
 )
 
-// StartServer starts the Prometheus metrics HTTP server.
+// StartServer starts the Prometheus metrics HTTP server, which also answers
+// the /healthz liveness and /readyz readiness probes.
 func StartServer(addr string) {
 	if addr == "" {
 		log.Info().Msg("Metrics server address not configured, Prometheus endpoint will not be available.")
@@ -66,6 +220,8 @@ func StartServer(addr string) {
 
 	mux := chi.NewRouter()
 	mux.Handle("/metrics", promhttp.Handler())
+	mux.Get("/healthz", healthz)
+	mux.Get("/readyz", readyz)
 
 	log.Info().Str("address", addr).Msg("Starting Prometheus metrics server")
 	go func() {
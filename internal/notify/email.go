@@ -0,0 +1,294 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+)
+
+const defaultEmailSubject = `[{{.FeedTitle}}] {{.Title}}`
+
+const defaultEmailTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; max-width: 640px; line-height: 1.5">
+<p style="color: #666; font-size: 0.9em">{{if .FeedURL}}<a href="{{.FeedURL}}" style="color: #666">{{.FeedTitle}}</a>{{else}}{{.FeedTitle}}{{end}}{{with .Author}} · {{.}}{{end}}{{with .Published}} · {{.Format "2 Jan 2006 15:04 MST"}}{{end}}</p>
+{{range .Parts}}
+{{with .Image}}<p><img src="{{.}}" alt="" style="max-width: 100%"></p>{{end}}
+{{with .Attachment}}<p><a href="{{.}}">{{.}}</a></p>{{end}}
+{{with .HTML}}<div>{{.}}</div>{{end}}
+{{end}}
+{{with .Link}}<p><a href="{{.}}">Read more</a></p>{{end}}
+</body>
+</html>
+`
+
+// EmailDestination is the config of a database.DestinationEmail destination.
+type EmailDestination struct {
+	To       []string `json:"to"`                 // Recipient addresses
+	Subject  string   `json:"subject,omitempty"`  // Overrides smtp.subject
+	Template string   `json:"template,omitempty"` // Overrides smtp.template
+}
+
+// ParseEmailDestination reads and checks an email destination's config.
+func ParseEmailDestination(raw json.RawMessage) (*EmailDestination, error) {
+	var d EmailDestination
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, fmt.Errorf("invalid email destination: %w", err)
+	}
+	if len(d.To) == 0 {
+		return nil, fmt.Errorf("email destination has no recipients")
+	}
+	for _, to := range d.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", to, err)
+		}
+	}
+	if _, _, err := parseEmailTemplates(d.Subject, d.Template); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// EmailPart is one formatted part of a message in an email template.
+type EmailPart struct {
+	HTML       htmltemplate.HTML // The part's text as HTML
+	Image      string            // Photo URL
+	Attachment string            // Video, animation or document URL
+}
+
+// emailData is what the subject and body templates are executed with.
+type emailData struct {
+	*Message
+	Parts []EmailPart
+}
+
+// EmailNotifier mails messages through an SMTP server.
+type EmailNotifier struct {
+	cfg      config.SMTPConfig
+	password string
+	from     *mail.Address
+	subject  *template.Template
+	body     *htmltemplate.Template
+}
+
+// NewEmailNotifier checks cfg and creates an EmailNotifier. password is the
+// resolved smtp.password.
+func NewEmailNotifier(cfg config.SMTPConfig, password string) (*EmailNotifier, error) {
+	n := &EmailNotifier{cfg: cfg, password: password}
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("smtp.host is not set")
+	}
+	switch cfg.Security {
+	case "starttls", "tls", "none":
+	default:
+		return nil, fmt.Errorf("smtp.security %q: expected starttls, tls or none", cfg.Security)
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("smtp.from %q: %w", cfg.From, err)
+	}
+	n.from = from
+	if n.subject, n.body, err = parseEmailTemplates(cfg.Subject, cfg.Template); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// parseEmailTemplates parses subject and body templates, using the built-in
+// ones for those that are empty.
+func parseEmailTemplates(subject, body string) (*template.Template, *htmltemplate.Template, error) {
+	if subject == "" {
+		subject = defaultEmailSubject
+	}
+	if body == "" {
+		body = defaultEmailTemplate
+	}
+	subjectTmpl, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing email subject template: %w", err)
+	}
+	bodyTmpl, err := htmltemplate.New("body").Parse(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing email template: %w", err)
+	}
+	return subjectTmpl, bodyTmpl, nil
+}
+
+// Send mails msg to the recipients of an email destination.
+func (n *EmailNotifier) Send(ctx context.Context, dest *database.FeedDestination, msg *Message) error {
+	d, err := ParseEmailDestination(dest.Config)
+	if err != nil {
+		return err
+	}
+	subjectTmpl, bodyTmpl := n.subject, n.body
+	if d.Subject != "" || d.Template != "" {
+		// Already checked by ParseEmailDestination
+		custom, customBody, _ := parseEmailTemplates(d.Subject, d.Template)
+		if d.Subject != "" {
+			subjectTmpl = custom
+		}
+		if d.Template != "" {
+			bodyTmpl = customBody
+		}
+	}
+	data, err := n.buildMessage(subjectTmpl, bodyTmpl, d.To, msg)
+	if err != nil {
+		return err
+	}
+	recipients := make([]string, len(d.To))
+	for i, to := range d.To {
+		addr, _ := mail.ParseAddress(to)
+		recipients[i] = addr.Address
+	}
+	return n.deliver(ctx, recipients, data)
+}
+
+// buildMessage renders msg into an RFC 5322 message with a quoted-printable
+// HTML body.
+func (n *EmailNotifier) buildMessage(subjectTmpl *template.Template, bodyTmpl *htmltemplate.Template, to []string, msg *Message) ([]byte, error) {
+	data := emailData{Message: msg}
+	for _, p := range msg.Parts {
+		part := EmailPart{HTML: partHTML(p.Text, p.ParseMode), Image: p.PhotoURL}
+		switch {
+		case p.VideoURL != "":
+			part.Attachment = p.VideoURL
+		case p.AnimationURL != "":
+			part.Attachment = p.AnimationURL
+		case p.DocumentURL != "":
+			part.Attachment = p.DocumentURL
+			if p.DocumentCaption != "" {
+				part.HTML += "<br>\n" + partHTML(p.DocumentCaption, p.ParseMode)
+			}
+		}
+		data.Parts = append(data.Parts, part)
+	}
+
+	var subject strings.Builder
+	if err := subjectTmpl.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("executing email subject template: %w", err)
+	}
+	var body bytes.Buffer
+	qp := quotedprintable.NewWriter(&body)
+	if err := bodyTmpl.Execute(qp, data); err != nil {
+		return nil, fmt.Errorf("executing email template: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) { fmt.Fprintf(&buf, "%s: %s\r\n", name, value) }
+	header("From", n.from.String())
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject.String()), " ")))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(n.from.Address))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/html; charset=UTF-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// partHTML turns the text of a formatted part into HTML. Telegram HTML is a
+// subset of HTML and kept as is; other text is escaped.
+func partHTML(text, parseMode string) htmltemplate.HTML {
+	if text == "" {
+		return ""
+	}
+	if !strings.EqualFold(parseMode, "HTML") {
+		text = html.EscapeString(text)
+	}
+	return htmltemplate.HTML(strings.ReplaceAll(text, "\n", "<br>\n"))
+}
+
+// messageID returns a unique Message-ID in the sender's domain.
+func messageID(from string) string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	domain := "localhost"
+	if _, d, ok := strings.Cut(from, "@"); ok {
+		domain = d
+	}
+	return "<" + hex.EncodeToString(b[:]) + "@" + domain + ">"
+}
+
+// deliver sends data to recipients through the SMTP server.
+func (n *EmailNotifier) deliver(ctx context.Context, recipients []string, data []byte) error {
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: n.cfg.Host}
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if n.cfg.Security == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to SMTP server: %w", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(2 * time.Minute)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP greeting: %w", err)
+	}
+	defer c.Close()
+	if n.cfg.Security == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server does not support STARTTLS (set smtp.security to tls or none)")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("SMTP STARTTLS: %w", err)
+		}
+	}
+	if n.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.cfg.Username, n.password, n.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP auth: %w", err)
+		}
+	}
+	if err := c.Mail(n.from.Address); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+	return c.Quit()
+}
@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// received is a message accepted by fakeSMTP.
+type received struct {
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTP accepts one message without TLS or auth and sends it on the
+// returned channel.
+func fakeSMTP(t *testing.T) (host string, port int, messages <-chan received) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan received, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var msg received
+		tp.PrintfLine("220 fake ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				tp.PrintfLine("250 fake")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				msg.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+				tp.PrintfLine("250 ok")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<> "))
+				tp.PrintfLine("250 ok")
+			case cmd == "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				msg.data = string(data)
+				tp.PrintfLine("250 queued")
+			case cmd == "QUIT":
+				tp.PrintfLine("221 bye")
+				ch <- msg
+				return
+			default:
+				tp.PrintfLine("502 unsupported")
+			}
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, ch
+}
+
+func TestEmailNotifierSend(t *testing.T) {
+	host, port, messages := fakeSMTP(t)
+	n, err := NewEmailNotifier(config.SMTPConfig{Host: host, Port: port, Security: "none", From: "RSS Bot <bot@example.com>"}, "")
+	require.NoError(t, err)
+
+	cfg, err := json.Marshal(EmailDestination{To: []string{"Ann <ann@example.com>", "bob@example.com"}})
+	require.NoError(t, err)
+	msg := &Message{
+		FeedTitle: "Example News", FeedURL: "https://example.com/feed.xml", Title: "Héllo\r\nBcc: x@evil", Link: "https://example.com/1",
+		Parts: []interfaces.FormattedMessagePart{
+			{Text: "<b>Héllo</b>\nworld", ParseMode: "HTML", PhotoURL: "https://example.com/a.png"},
+			{Text: "1 < 2", DocumentURL: "https://example.com/a.pdf"},
+		},
+	}
+	require.NoError(t, n.Send(context.Background(), &database.FeedDestination{Type: database.DestinationEmail, Config: cfg}, msg))
+
+	got := <-messages
+	assert.Equal(t, "bot@example.com", got.from)
+	assert.Equal(t, []string{"ann@example.com", "bob@example.com"}, got.to)
+
+	tp := textproto.NewReader(bufio.NewReader(strings.NewReader(got.data)))
+	header, err := tp.ReadMIMEHeader()
+	require.NoError(t, err)
+	assert.Equal(t, "Ann <ann@example.com>, bob@example.com", header.Get("To"))
+	assert.Equal(t, "=?utf-8?q?[Example_News]_H=C3=A9llo_Bcc:_x@evil?=", header.Get("Subject"))
+	assert.Empty(t, header.Get("Bcc"))
+	assert.Equal(t, "text/html; charset=UTF-8", header.Get("Content-Type"))
+	body, err := io.ReadAll(quotedprintable.NewReader(tp.R))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "<div><b>Héllo</b><br>\nworld</div>")
+	assert.Contains(t, string(body), `<img src="https://example.com/a.png"`)
+	assert.Contains(t, string(body), "<div>1 &lt; 2</div>")
+	assert.Contains(t, string(body), `<a href="https://example.com/a.pdf">`)
+	assert.Contains(t, string(body), `<a href="https://example.com/1">Read more</a>`)
+}
+
+func TestParseEmailDestination(t *testing.T) {
+	_, err := ParseEmailDestination(json.RawMessage(`{"to":[]}`))
+	assert.ErrorContains(t, err, "no recipients")
+	_, err = ParseEmailDestination(json.RawMessage(`{"to":["not an address"]}`))
+	assert.ErrorContains(t, err, "invalid recipient")
+	_, err = ParseEmailDestination(json.RawMessage(`{"to":["a@example.com"],"template":"{{.Oops"}`))
+	assert.ErrorContains(t, err, "parsing email template")
+
+	_, err = NewEmailNotifier(config.SMTPConfig{Host: "mail", Port: 25, Security: "ssl", From: "a@example.com"}, "")
+	assert.ErrorContains(t, err, "smtp.security")
+	_, err = NewEmailNotifier(config.SMTPConfig{Host: "mail", Port: 25, Security: "tls", From: ""}, "")
+	assert.ErrorContains(t, err, "smtp.from")
+}
@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// markdownSpecial matches characters that format text in Markdown.
+var markdownSpecial = regexp.MustCompile("[\\\\`*_~\\[\\]<>#|]")
+
+// escapeMarkdown keeps text from being read as Markdown.
+func escapeMarkdown(text string) string {
+	return markdownSpecial.ReplaceAllString(text, "\\$0")
+}
+
+// markdownV2Escape matches Telegram MarkdownV2 escapes.
+var markdownV2Escape = regexp.MustCompile(`\\([_*\[\]()~` + "`" + `>#+\-=|{}.!\\])`)
+
+// partMarkdown turns the text of a formatted part into Markdown as rendered
+// by Mattermost and similar chat apps.
+func partMarkdown(text, parseMode string) string {
+	switch strings.ToLower(parseMode) {
+	case "html":
+		return telegramHTMLToMarkdown(text)
+	case "markdownv2", "markdown":
+		// Close enough: both use *bold*, _italic_, `code` and [text](url).
+		return markdownV2Escape.ReplaceAllString(text, "$1")
+	}
+	return escapeMarkdown(text)
+}
+
+// telegramHTMLToMarkdown converts the HTML subset Telegram accepts to
+// Markdown. Tags without a Markdown equivalent are dropped, keeping their text.
+func telegramHTMLToMarkdown(s string) string {
+	var b strings.Builder
+	var links []string // href of each open <a>, "" when it had none
+	inPre, inCode := false, false
+	quoteStart := -1
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.TrimSpace(b.String())
+		case html.TextToken:
+			text := string(z.Text())
+			if !inPre && !inCode {
+				text = escapeMarkdown(text)
+			}
+			b.WriteString(text)
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			start := tt != html.EndTagToken
+			var href string
+			if start {
+				for {
+					key, val, more := z.TagAttr()
+					if string(key) == "href" {
+						href = string(val)
+					}
+					if !more {
+						break
+					}
+				}
+			}
+			switch tag {
+			case "b", "strong":
+				b.WriteString("**")
+			case "i", "em":
+				b.WriteString("_")
+			case "s", "strike", "del":
+				b.WriteString("~~")
+			case "code":
+				if !inPre {
+					b.WriteString("`")
+					inCode = start
+				}
+			case "pre":
+				b.WriteString("\n```\n")
+				inPre = start
+			case "br":
+				b.WriteString("\n")
+			case "a":
+				if start {
+					links = append(links, href)
+					if href != "" {
+						b.WriteString("[")
+					}
+				} else if len(links) > 0 {
+					href = links[len(links)-1]
+					links = links[:len(links)-1]
+					if href != "" {
+						b.WriteString("](" + href + ")")
+					}
+				}
+			case "blockquote":
+				if start {
+					quoteStart = b.Len()
+				} else if quoteStart >= 0 {
+					quoted := strings.Trim(b.String()[quoteStart:], "\n")
+					rest := b.String()[:quoteStart]
+					b.Reset()
+					b.WriteString(rest)
+					b.WriteString("\n> " + strings.ReplaceAll(quoted, "\n", "\n> ") + "\n")
+					quoteStart = -1
+				}
+			}
+		}
+	}
+}
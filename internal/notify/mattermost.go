@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+)
+
+// mattermostMaxRunes is below the 16383 character limit of a Mattermost post.
+const mattermostMaxRunes = 16000
+
+// MattermostDestination is the config of a database.DestinationMattermost destination.
+type MattermostDestination struct {
+	URL      string `json:"url"`                // Incoming webhook URL; may be an env:NAME or file:PATH reference
+	Channel  string `json:"channel,omitempty"`  // Overrides the webhook's channel, e.g. "town-square" or "@user"
+	Username string `json:"username,omitempty"` // Overrides the poster's name, if the server allows it
+	IconURL  string `json:"icon_url,omitempty"` // Overrides the poster's avatar, if the server allows it
+}
+
+// ParseMattermostDestination reads and checks a Mattermost destination's config.
+func ParseMattermostDestination(raw json.RawMessage) (*MattermostDestination, error) {
+	var d MattermostDestination
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, fmt.Errorf("invalid Mattermost destination: %w", err)
+	}
+	if d.URL == "" {
+		return nil, fmt.Errorf("Mattermost destination has no webhook URL")
+	}
+	if !strings.HasPrefix(d.URL, "env:") && !strings.HasPrefix(d.URL, "file:") {
+		if u, err := url.Parse(d.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid Mattermost webhook URL: expected an http(s) URL")
+		}
+	}
+	return &d, nil
+}
+
+// MattermostNotifier posts messages to Mattermost incoming webhooks.
+type MattermostNotifier struct {
+	client *http.Client
+	cfg    config.WebhookConfig
+}
+
+// NewMattermostNotifier creates a MattermostNotifier sending through client
+// with the timeout and retries of cfg.
+func NewMattermostNotifier(client *http.Client, cfg config.WebhookConfig) *MattermostNotifier {
+	return &MattermostNotifier{client: client, cfg: cfg}
+}
+
+// Send posts msg to a Mattermost destination as a Markdown message.
+func (n *MattermostNotifier) Send(ctx context.Context, dest *database.FeedDestination, msg *Message) error {
+	d, err := ParseMattermostDestination(dest.Config)
+	if err != nil {
+		return err
+	}
+	hookURL, err := proxy.ResolveSecret(d.URL)
+	if err != nil {
+		return fmt.Errorf("resolving Mattermost webhook URL: %w", err)
+	}
+	body, err := json.Marshal(struct {
+		Text     string `json:"text"`
+		Channel  string `json:"channel,omitempty"`
+		Username string `json:"username,omitempty"`
+		IconURL  string `json:"icon_url,omitempty"`
+	}{mattermostText(msg), d.Channel, d.Username, d.IconURL})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, n.client, n.cfg, hookURL, nil, body)
+}
+
+// mattermostText renders msg as Markdown: its formatted parts, with photos as
+// inline images and other media as links.
+func mattermostText(msg *Message) string {
+	var blocks []string
+	for _, p := range msg.Parts {
+		photos, others := []string{p.PhotoURL}, []string{p.VideoURL, p.AnimationURL, p.DocumentURL}
+		if len(p.MediaGroup) > 1 {
+			photos, others = nil, nil
+			for _, m := range p.MediaGroup {
+				if m.Type == "photo" {
+					photos = append(photos, m.URL)
+				} else {
+					others = append(others, m.URL)
+				}
+			}
+		}
+		for _, photo := range photos {
+			if photo != "" {
+				blocks = append(blocks, "![]("+photo+")")
+			}
+		}
+		for _, media := range others {
+			if media != "" {
+				blocks = append(blocks, media)
+			}
+		}
+		if text := partMarkdown(p.Text, p.ParseMode); text != "" {
+			blocks = append(blocks, text)
+		}
+		if caption := partMarkdown(p.DocumentCaption, p.ParseMode); caption != "" {
+			blocks = append(blocks, caption)
+		}
+	}
+	if len(blocks) == 0 {
+		title := escapeMarkdown(msg.Title)
+		if msg.Link != "" {
+			title = "[" + title + "](" + msg.Link + ")"
+		}
+		blocks = append(blocks, title)
+	}
+	text := strings.Join(blocks, "\n\n")
+	if runes := []rune(text); len(runes) > mattermostMaxRunes {
+		text = string(runes[:mattermostMaxRunes]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelegramHTMLToMarkdown(t *testing.T) {
+	cases := map[string]string{
+		`<b>Bold</b> and <i>italic</i> &amp; <s>gone</s>`:   `**Bold** and _italic_ & ~~gone~~`,
+		`<a href="https://example.com/a_b">Read *this*</a>`: `[Read \*this\*](https://example.com/a_b)`,
+		"<pre>x := a*b</pre>":                               "```\nx := a*b\n```",
+		"line one\n<code>a_b</code>":                        "line one\n`a_b`",
+		"Intro<blockquote>quoted\nlines</blockquote>":       "Intro\n> quoted\n> lines",
+		`<tg-spoiler>secret</tg-spoiler> <u>under</u>`:      `secret under`,
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, telegramHTMLToMarkdown(in), in)
+	}
+	assert.Equal(t, `1 \* 2`, partMarkdown("1 * 2", ""))
+	assert.Equal(t, `*bold* 1.5`, partMarkdown(`*bold* 1\.5`, "MarkdownV2"))
+}
+
+func TestMattermostNotifierSend(t *testing.T) {
+	var payload map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer srv.Close()
+	t.Setenv("MM_HOOK", srv.URL+"/hooks/abc")
+
+	cfg, err := json.Marshal(MattermostDestination{URL: "env:MM_HOOK", Channel: "news"})
+	require.NoError(t, err)
+	n := NewMattermostNotifier(srv.Client(), config.WebhookConfig{})
+	msg := &Message{Title: "Hello", Link: "https://example.com/1", Parts: []interfaces.FormattedMessagePart{
+		{Text: `<b>Hello</b>`, ParseMode: "HTML", PhotoURL: "https://example.com/a.png"},
+	}}
+	require.NoError(t, n.Send(context.Background(), &database.FeedDestination{Type: database.DestinationMattermost, Config: cfg}, msg))
+	assert.Equal(t, "news", payload["channel"])
+	assert.Equal(t, "![](https://example.com/a.png)\n\n**Hello**", payload["text"])
+
+	assert.Equal(t, "[Hello](https://example.com/1)", mattermostText(&Message{Title: "Hello", Link: "https://example.com/1"}))
+	_, err = ParseMattermostDestination(json.RawMessage(`{"url":"not a url"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,297 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+)
+
+// MQTT 3.1.1 control packet types, shifted into the first header byte.
+const (
+	mqttConnect    = 1 << 4
+	mqttConnack    = 2 << 4
+	mqttPublish    = 3 << 4
+	mqttPuback     = 4 << 4
+	mqttDisconnect = 14 << 4
+)
+
+// MQTTPublisher publishes a JSON event for each delivered item to an MQTT
+// broker. It keeps one connection open, reconnecting when a publish fails.
+// Events have the shape of the default webhook payload.
+type MQTTPublisher struct {
+	cfg      config.MQTTConfig
+	password string
+	topic    *template.Template
+
+	mu       sync.Mutex
+	conn     net.Conn
+	reader   *bufio.Reader
+	packetID uint16
+}
+
+// NewMQTTPublisher checks cfg and creates an MQTTPublisher. password is the
+// resolved mqtt.password. It connects on the first publish.
+func NewMQTTPublisher(cfg config.MQTTConfig, password string) (*MQTTPublisher, error) {
+	u, err := url.Parse(cfg.Broker)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("mqtt.broker %q: expected e.g. tcp://host:1883 or ssl://host:8883", cfg.Broker)
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts":
+	default:
+		return nil, fmt.Errorf("mqtt.broker %q: scheme must be tcp, mqtt, ssl, tls or mqtts", cfg.Broker)
+	}
+	if cfg.QoS != 0 && cfg.QoS != 1 {
+		return nil, fmt.Errorf("mqtt.qos %d: expected 0 or 1", cfg.QoS)
+	}
+	topic, err := template.New("topic").Parse(cfg.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("parsing mqtt.topic: %w", err)
+	}
+	return &MQTTPublisher{cfg: cfg, password: password, topic: topic}, nil
+}
+
+// Publish sends an event for msg to the topic rendered for it.
+func (p *MQTTPublisher) Publish(ctx context.Context, msg *Message) error {
+	var topic strings.Builder
+	if err := p.topic.Execute(&topic, msg); err != nil {
+		return fmt.Errorf("executing mqtt.topic: %w", err)
+	}
+	if topic.Len() == 0 || strings.ContainsAny(topic.String(), "+#") {
+		return fmt.Errorf("invalid MQTT topic %q: must be non-empty and free of wildcards", topic.String())
+	}
+	payload, err := json.Marshal(newWebhookPayload(msg))
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err = p.publish(ctx, topic.String(), payload)
+	if err != nil {
+		// The broker may have dropped an idle connection; retry once on a new one.
+		p.closeConn()
+		err = p.publish(ctx, topic.String(), payload)
+	}
+	if err != nil {
+		p.closeConn()
+	}
+	return err
+}
+
+// Close disconnects from the broker.
+func (p *MQTTPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		return nil
+	}
+	_, _ = p.conn.Write([]byte{mqttDisconnect, 0})
+	p.closeConn()
+	return nil
+}
+
+func (p *MQTTPublisher) closeConn() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn, p.reader = nil, nil
+	}
+}
+
+// publish sends one PUBLISH packet, connecting first if needed, and waits
+// for the broker's acknowledgement at QoS 1.
+func (p *MQTTPublisher) publish(ctx context.Context, topic string, payload []byte) error {
+	deadline := time.Now().Add(p.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if p.conn == nil {
+		if err := p.connect(ctx, deadline); err != nil {
+			return err
+		}
+	}
+	_ = p.conn.SetDeadline(deadline)
+
+	var body bytes.Buffer
+	writeMQTTString(&body, topic)
+	header := byte(mqttPublish)
+	if p.cfg.Retain {
+		header |= 1
+	}
+	var id uint16
+	if p.cfg.QoS == 1 {
+		header |= 1 << 1
+		p.packetID++
+		if p.packetID == 0 {
+			p.packetID = 1
+		}
+		id = p.packetID
+		_ = binary.Write(&body, binary.BigEndian, id)
+	}
+	body.Write(payload)
+	if err := writeMQTTPacket(p.conn, header, body.Bytes()); err != nil {
+		return fmt.Errorf("MQTT publish: %w", err)
+	}
+	if p.cfg.QoS == 0 {
+		return nil
+	}
+	for {
+		packetType, data, err := readMQTTPacket(p.reader)
+		if err != nil {
+			return fmt.Errorf("MQTT publish: waiting for acknowledgement: %w", err)
+		}
+		if packetType&0xF0 == mqttPuback && len(data) >= 2 && binary.BigEndian.Uint16(data) == id {
+			return nil
+		}
+	}
+}
+
+// connect opens a connection to the broker and logs in.
+func (p *MQTTPublisher) connect(ctx context.Context, deadline time.Time) error {
+	u, _ := url.Parse(p.cfg.Broker) // Checked by NewMQTTPublisher
+	secure := u.Scheme == "ssl" || u.Scheme == "tls" || u.Scheme == "mqtts"
+	addr := u.Host
+	if u.Port() == "" {
+		port := "1883"
+		if secure {
+			port = "8883"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	var err error
+	if secure {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname()}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to MQTT broker: %w", err)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	var body bytes.Buffer
+	writeMQTTString(&body, "MQTT")
+	body.WriteByte(4)   // Protocol level 3.1.1
+	flags := byte(0x02) // Clean session
+	if p.cfg.Username != "" {
+		flags |= 0x80
+		if p.password != "" {
+			flags |= 0x40
+		}
+	}
+	body.WriteByte(flags)
+	_ = binary.Write(&body, binary.BigEndian, uint16(0)) // No keep-alive; failed publishes reconnect
+	writeMQTTString(&body, p.cfg.ClientID)
+	if p.cfg.Username != "" {
+		writeMQTTString(&body, p.cfg.Username)
+		if p.password != "" {
+			writeMQTTString(&body, p.password)
+		}
+	}
+	reader := bufio.NewReader(conn)
+	if err := writeMQTTPacket(conn, mqttConnect, body.Bytes()); err != nil {
+		conn.Close()
+		return fmt.Errorf("MQTT connect: %w", err)
+	}
+	packetType, data, err := readMQTTPacket(reader)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("MQTT connect: %w", err)
+	}
+	if packetType&0xF0 != mqttConnack || len(data) < 2 {
+		conn.Close()
+		return fmt.Errorf("MQTT connect: unexpected packet type %d", packetType>>4)
+	}
+	if code := data[1]; code != 0 {
+		conn.Close()
+		return fmt.Errorf("MQTT connect refused: %s", mqttConnackReason(code))
+	}
+	p.conn, p.reader = conn, reader
+	return nil
+}
+
+// mqttConnackReason describes a CONNACK return code.
+func mqttConnackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client ID rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("return code %d", code)
+}
+
+// writeMQTTString appends s with its 2-byte length prefix.
+func writeMQTTString(b *bytes.Buffer, s string) {
+	_ = binary.Write(b, binary.BigEndian, uint16(len(s)))
+	b.WriteString(s)
+}
+
+// writeMQTTPacket writes a packet with the given first header byte.
+func writeMQTTPacket(w io.Writer, header byte, body []byte) error {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(packet, body...))
+	return err
+}
+
+// readMQTTPacket reads a packet, returning its first header byte and body.
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed MQTT remaining length")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7F) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
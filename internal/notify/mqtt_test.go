@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mqttPacket is a packet received by fakeBroker.
+type mqttPacket struct {
+	header byte
+	body   []byte
+}
+
+// fakeBroker accepts MQTT connections on a local port, acknowledging
+// CONNECT and QoS 1 PUBLISH packets, and passes on every packet it receives.
+// Connections are dropped after dropAfter publishes when it is set.
+func fakeBroker(t *testing.T, dropAfter int) (string, <-chan mqttPacket) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	packets := make(chan mqttPacket, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				publishes := 0
+				for {
+					header, body, err := readMQTTPacket(r)
+					if err != nil {
+						return
+					}
+					packets <- mqttPacket{header, body}
+					switch header & 0xF0 {
+					case mqttConnect:
+						_ = writeMQTTPacket(conn, mqttConnack, []byte{0, 0})
+					case mqttPublish:
+						publishes++
+						if dropAfter > 0 && publishes > dropAfter {
+							return
+						}
+						if header&0x06 != 0 {
+							topicLen := binary.BigEndian.Uint16(body)
+							_ = writeMQTTPacket(conn, mqttPuback, body[2+topicLen:4+topicLen])
+						}
+					}
+				}
+			}()
+		}
+	}()
+	return "tcp://" + ln.Addr().String(), packets
+}
+
+func nextPacket(t *testing.T, packets <-chan mqttPacket) mqttPacket {
+	t.Helper()
+	select {
+	case p := <-packets:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("no packet received")
+		return mqttPacket{}
+	}
+}
+
+func TestMQTTPublisher(t *testing.T) {
+	broker, packets := fakeBroker(t, 0)
+	p, err := NewMQTTPublisher(config.MQTTConfig{Broker: broker, ClientID: "bot", Username: "user", Topic: "rss/{{.FeedID}}", QoS: 1, Retain: true, Timeout: 5 * time.Second}, "pass")
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Publish(context.Background(), testMessage))
+	connect := nextPacket(t, packets)
+	assert.Equal(t, byte(mqttConnect), connect.header)
+	assert.True(t, bytes.HasSuffix(connect.body, []byte("\x00\x03bot\x00\x04user\x00\x04pass")))
+
+	publish := nextPacket(t, packets)
+	assert.Equal(t, byte(mqttPublish|0x02|0x01), publish.header)
+	topicLen := binary.BigEndian.Uint16(publish.body)
+	assert.Equal(t, "rss/7", string(publish.body[2:2+topicLen]))
+	var payload WebhookPayload
+	require.NoError(t, json.Unmarshal(publish.body[4+topicLen:], &payload))
+	assert.Equal(t, `Say "hi"`, payload.Item.Title)
+	assert.EqualValues(t, 7, payload.Feed.ID)
+}
+
+func TestMQTTPublisherReconnects(t *testing.T) {
+	broker, packets := fakeBroker(t, 1)
+	p, err := NewMQTTPublisher(config.MQTTConfig{Broker: broker, ClientID: "bot", Topic: "rss", QoS: 1, Timeout: 5 * time.Second}, "")
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Publish(context.Background(), testMessage))
+	require.NoError(t, p.Publish(context.Background(), testMessage))
+	var connects int
+	for len(packets) > 0 {
+		if (<-packets).header == mqttConnect {
+			connects++
+		}
+	}
+	assert.Equal(t, 2, connects)
+}
+
+func TestMQTTPublisherRejectsWildcardTopics(t *testing.T) {
+	p, err := NewMQTTPublisher(config.MQTTConfig{Broker: "tcp://localhost:1883", Topic: "rss/{{.FeedTitle}}", Timeout: time.Second}, "")
+	require.NoError(t, err)
+	err = p.Publish(context.Background(), &Message{FeedTitle: "a+b"})
+	assert.ErrorContains(t, err, "wildcards")
+
+	_, err = NewMQTTPublisher(config.MQTTConfig{Broker: "http://localhost", Topic: "rss"}, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,103 @@
+// Package notify delivers feed items to destinations other than a feed's
+// Telegram chat, such as email recipients.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+)
+
+// Message is a formatted feed item on its way to a destination.
+type Message struct {
+	FeedID    int64
+	FeedTitle string // The feed's user title, else the title it publishes, else its URL
+	FeedURL   string
+	Title     string
+	Link      string
+	Author    string
+	Published *time.Time
+	Parts     []interfaces.FormattedMessagePart // As formatted for Telegram by the feed's profile
+}
+
+// NewMessage describes item of feed, formatted as parts. fetchedTitle is the
+// title the feed itself publishes.
+func NewMessage(feed *database.Feed, fetchedTitle string, item *gofeed.Item, parts []interfaces.FormattedMessagePart) *Message {
+	m := &Message{FeedID: feed.ID, FeedTitle: feed.URL, FeedURL: feed.URL, Title: item.Title, Link: item.Link, Published: item.PublishedParsed, Parts: parts}
+	if feed.UserTitle != nil && *feed.UserTitle != "" {
+		m.FeedTitle = *feed.UserTitle
+	} else if fetchedTitle != "" {
+		m.FeedTitle = fetchedTitle
+	}
+	if item.Author != nil {
+		m.Author = item.Author.Name
+	}
+	return m
+}
+
+// PlainText returns the text of msg's parts without formatting.
+func (m *Message) PlainText() string {
+	var texts []string
+	for _, p := range m.Parts {
+		for _, text := range []string{p.Text, p.DocumentCaption} {
+			if text = plainText(text, p.ParseMode); text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// Notifier delivers messages to destinations of one type, reading where to
+// from the destination's config.
+type Notifier interface {
+	Send(ctx context.Context, dest *database.FeedDestination, msg *Message) error
+}
+
+// NotifierFunc adapts a function to a Notifier.
+type NotifierFunc func(ctx context.Context, dest *database.FeedDestination, msg *Message) error
+
+// Send calls f.
+func (f NotifierFunc) Send(ctx context.Context, dest *database.FeedDestination, msg *Message) error {
+	return f(ctx, dest, msg)
+}
+
+// Unavailable returns a Notifier failing every delivery with reason, for
+// destination types whose notifier is not configured.
+func Unavailable(reason string) Notifier {
+	return NotifierFunc(func(context.Context, *database.FeedDestination, *Message) error {
+		return fmt.Errorf("%s", reason)
+	})
+}
+
+// Registry dispatches messages to the notifier of each destination's type.
+// Notifiers are registered at startup; a Registry is safe for concurrent
+// sends once it is set up.
+type Registry struct {
+	notifiers map[string]Notifier
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{notifiers: make(map[string]Notifier)}
+}
+
+// Register makes n deliver destinations of destType, replacing any notifier
+// registered for it before.
+func (r *Registry) Register(destType string, n Notifier) {
+	r.notifiers[destType] = n
+}
+
+// Send delivers msg to dest through the notifier of its type.
+func (r *Registry) Send(ctx context.Context, dest *database.FeedDestination, msg *Message) error {
+	n, ok := r.notifiers[dest.Type]
+	if !ok {
+		return fmt.Errorf("unknown destination type %q", dest.Type)
+	}
+	return n.Send(ctx, dest, msg)
+}
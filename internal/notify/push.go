@@ -0,0 +1,214 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultNtfyServer = "https://ntfy.sh"
+	pushoverAPIURL    = "https://api.pushover.net/1/messages.json"
+
+	ntfyMaxRunes          = 4000 // ntfy's default message size limit is 4096 bytes
+	pushoverMaxRunes      = 1024
+	pushoverTitleMaxRunes = 250
+)
+
+// NtfyDestination is the config of a database.DestinationNtfy destination.
+type NtfyDestination struct {
+	Server   string   `json:"server,omitempty"`   // ntfy server; empty uses https://ntfy.sh
+	Topic    string   `json:"topic"`              // Topic to publish to
+	Token    string   `json:"token,omitempty"`    // Access token for protected topics; may be an env:NAME or file:PATH reference
+	Priority int      `json:"priority,omitempty"` // 1 (min) to 5 (max); 0 uses the server's default
+	Tags     []string `json:"tags,omitempty"`     // Tags or emoji shortcodes shown with the notification
+}
+
+// ParseNtfyDestination reads and checks an ntfy destination's config.
+func ParseNtfyDestination(raw json.RawMessage) (*NtfyDestination, error) {
+	var d NtfyDestination
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, fmt.Errorf("invalid ntfy destination: %w", err)
+	}
+	if d.Topic == "" || strings.Contains(d.Topic, "/") {
+		return nil, fmt.Errorf("invalid ntfy topic %q", d.Topic)
+	}
+	if d.Server != "" {
+		if u, err := url.Parse(d.Server); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid ntfy server %q: expected an http(s) URL", d.Server)
+		}
+	}
+	if d.Priority < 0 || d.Priority > 5 {
+		return nil, fmt.Errorf("invalid ntfy priority %d: expected 1 to 5", d.Priority)
+	}
+	return &d, nil
+}
+
+// PushoverDestination is the config of a database.DestinationPushover destination.
+// The keys may be env:NAME or file:PATH references.
+type PushoverDestination struct {
+	AppToken string `json:"app_token"`          // API token of the Pushover application
+	UserKey  string `json:"user_key"`           // User or group key to notify
+	Device   string `json:"device,omitempty"`   // Limits delivery to one of the user's devices
+	Priority int    `json:"priority,omitempty"` // -2 (silent) to 1 (high)
+	Sound    string `json:"sound,omitempty"`    // Overrides the user's notification sound
+}
+
+// ParsePushoverDestination reads and checks a Pushover destination's config.
+func ParsePushoverDestination(raw json.RawMessage) (*PushoverDestination, error) {
+	var d PushoverDestination
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, fmt.Errorf("invalid Pushover destination: %w", err)
+	}
+	if d.AppToken == "" || d.UserKey == "" {
+		return nil, fmt.Errorf("Pushover destination needs an app token and a user key")
+	}
+	// Emergency priority (2) needs retry and expiry settings and an acknowledgement.
+	if d.Priority < -2 || d.Priority > 1 {
+		return nil, fmt.Errorf("invalid Pushover priority %d: expected -2 to 1", d.Priority)
+	}
+	return &d, nil
+}
+
+// PushNotifier sends messages as push notifications through ntfy or Pushover.
+type PushNotifier struct {
+	client      *http.Client
+	cfg         config.WebhookConfig
+	pushoverURL string
+}
+
+// NewPushNotifier creates a PushNotifier sending through client with the
+// timeout and retries of cfg.
+func NewPushNotifier(client *http.Client, cfg config.WebhookConfig) *PushNotifier {
+	return &PushNotifier{client: client, cfg: cfg, pushoverURL: pushoverAPIURL}
+}
+
+// Send notifies an ntfy or Pushover destination of msg.
+func (n *PushNotifier) Send(ctx context.Context, dest *database.FeedDestination, msg *Message) error {
+	switch dest.Type {
+	case database.DestinationNtfy:
+		return n.sendNtfy(ctx, dest, msg)
+	case database.DestinationPushover:
+		return n.sendPushover(ctx, dest, msg)
+	}
+	return fmt.Errorf("push notifier cannot deliver %q destinations", dest.Type)
+}
+
+// sendNtfy publishes msg to an ntfy topic.
+func (n *PushNotifier) sendNtfy(ctx context.Context, dest *database.FeedDestination, msg *Message) error {
+	d, err := ParseNtfyDestination(dest.Config)
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{}
+	if d.Token != "" {
+		token, err := proxy.ResolveSecret(d.Token)
+		if err != nil {
+			return fmt.Errorf("resolving ntfy token: %w", err)
+		}
+		headers["Authorization"] = "Bearer " + token
+	}
+	server := d.Server
+	if server == "" {
+		server = defaultNtfyServer
+	}
+	body, err := json.Marshal(struct {
+		Topic    string   `json:"topic"`
+		Title    string   `json:"title,omitempty"`
+		Message  string   `json:"message"`
+		Click    string   `json:"click,omitempty"`
+		Priority int      `json:"priority,omitempty"`
+		Tags     []string `json:"tags,omitempty"`
+	}{d.Topic, msg.FeedTitle, truncateRunes(pushText(msg), ntfyMaxRunes), msg.Link, d.Priority, d.Tags})
+	if err != nil {
+		return err
+	}
+	// JSON messages are published to the server's root URL.
+	return postJSON(ctx, n.client, n.cfg, strings.TrimRight(server, "/")+"/", headers, body)
+}
+
+// sendPushover sends msg to a Pushover user.
+func (n *PushNotifier) sendPushover(ctx context.Context, dest *database.FeedDestination, msg *Message) error {
+	d, err := ParsePushoverDestination(dest.Config)
+	if err != nil {
+		return err
+	}
+	appToken, err := proxy.ResolveSecret(d.AppToken)
+	if err != nil {
+		return fmt.Errorf("resolving Pushover app token: %w", err)
+	}
+	userKey, err := proxy.ResolveSecret(d.UserKey)
+	if err != nil {
+		return fmt.Errorf("resolving Pushover user key: %w", err)
+	}
+	body, err := json.Marshal(struct {
+		Token    string `json:"token"`
+		User     string `json:"user"`
+		Title    string `json:"title,omitempty"`
+		Message  string `json:"message"`
+		URL      string `json:"url,omitempty"`
+		Device   string `json:"device,omitempty"`
+		Priority int    `json:"priority,omitempty"`
+		Sound    string `json:"sound,omitempty"`
+	}{appToken, userKey, truncateRunes(msg.FeedTitle, pushoverTitleMaxRunes), truncateRunes(pushText(msg), pushoverMaxRunes), msg.Link, d.Device, d.Priority, d.Sound})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, n.client, n.cfg, n.pushoverURL, nil, body)
+}
+
+// pushText is the body of a push notification for msg: the item's title, or
+// the plain text of its first part for items without one.
+func pushText(msg *Message) string {
+	if msg.Title != "" {
+		return msg.Title
+	}
+	for _, p := range msg.Parts {
+		if text := plainText(p.Text, p.ParseMode); text != "" {
+			return text
+		}
+	}
+	if msg.Link != "" {
+		return msg.Link
+	}
+	return msg.FeedTitle
+}
+
+// plainText strips the formatting from the text of a formatted part.
+func plainText(text, parseMode string) string {
+	switch strings.ToLower(parseMode) {
+	case "html":
+		var b strings.Builder
+		z := html.NewTokenizer(strings.NewReader(text))
+		for tt := z.Next(); tt != html.ErrorToken; tt = z.Next() {
+			switch tt {
+			case html.TextToken:
+				b.Write(z.Text())
+			case html.SelfClosingTagToken, html.StartTagToken:
+				if name, _ := z.TagName(); string(name) == "br" {
+					b.WriteString("\n")
+				}
+			}
+		}
+		text = b.String()
+	case "markdownv2", "markdown":
+		text = markdownV2Escape.ReplaceAllString(text, "$1")
+	}
+	return strings.TrimSpace(text)
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with an ellipsis.
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
+}
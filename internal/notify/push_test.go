@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushNotifierNtfy(t *testing.T) {
+	var payload map[string]interface{}
+	var path, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer srv.Close()
+	t.Setenv("NTFY_TOKEN", "tk_secret")
+
+	cfg, err := json.Marshal(NtfyDestination{Server: srv.URL, Topic: "alerts", Token: "env:NTFY_TOKEN", Priority: 4, Tags: []string{"warning"}})
+	require.NoError(t, err)
+	n := NewPushNotifier(srv.Client(), config.WebhookConfig{})
+	require.NoError(t, n.Send(context.Background(), &database.FeedDestination{Type: database.DestinationNtfy, Config: cfg}, testMessage))
+	assert.Equal(t, "/", path)
+	assert.Equal(t, "Bearer tk_secret", auth)
+	assert.Equal(t, "alerts", payload["topic"])
+	assert.Equal(t, "Example", payload["title"])
+	assert.Equal(t, `Say "hi"`, payload["message"])
+	assert.Equal(t, "https://example.com/1", payload["click"])
+	assert.EqualValues(t, 4, payload["priority"])
+
+	_, err = ParseNtfyDestination(json.RawMessage(`{"topic": "a/b"}`))
+	assert.Error(t, err)
+}
+
+func TestPushNotifierPushover(t *testing.T) {
+	var payload map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		if payload["user"] == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"user":"invalid","status":0}`))
+		}
+	}))
+	defer srv.Close()
+
+	n := NewPushNotifier(srv.Client(), config.WebhookConfig{MaxRetries: 3})
+	n.pushoverURL = srv.URL
+	msg := &Message{FeedTitle: "Status", Parts: []interfaces.FormattedMessagePart{{Text: "<b>Outage</b><br>details", ParseMode: "HTML"}}}
+	cfg, err := json.Marshal(PushoverDestination{AppToken: "app", UserKey: "user", Priority: 1})
+	require.NoError(t, err)
+	require.NoError(t, n.Send(context.Background(), &database.FeedDestination{Type: database.DestinationPushover, Config: cfg}, msg))
+	assert.Equal(t, "app", payload["token"])
+	assert.Equal(t, "Status", payload["title"])
+	assert.Equal(t, "Outage\ndetails", payload["message"])
+	assert.EqualValues(t, 1, payload["priority"])
+
+	// Client errors are not retried
+	cfg, err = json.Marshal(PushoverDestination{AppToken: "app", UserKey: "bad"})
+	require.NoError(t, err)
+	err = n.Send(context.Background(), &database.FeedDestination{Type: database.DestinationPushover, Config: cfg}, msg)
+	assert.ErrorContains(t, err, "400")
+
+	_, err = ParsePushoverDestination(json.RawMessage(`{"app_token": "a", "user_key": "u", "priority": 2}`))
+	assert.Error(t, err)
+}
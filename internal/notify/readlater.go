@@ -0,0 +1,262 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+)
+
+const (
+	pocketAddURL    = "https://getpocket.com/v3/add"
+	readwiseSaveURL = "https://readwise.io/api/v3/save/"
+)
+
+// ReadLaterOptions are the settings common to read-later destinations.
+type ReadLaterOptions struct {
+	Match string   `json:"match,omitempty"` // Regexp the item's title or link must match; empty saves every item
+	Tags  []string `json:"tags,omitempty"`  // Tags to file saved items under
+}
+
+// matches reports whether the item of msg should be saved.
+func (o ReadLaterOptions) matches(msg *Message) bool {
+	if msg.Link == "" {
+		return false // Nothing to save
+	}
+	if o.Match == "" {
+		return true
+	}
+	re := regexp.MustCompile(o.Match) // Checked by check
+	return re.MatchString(msg.Title) || re.MatchString(msg.Link)
+}
+
+// check validates the options.
+func (o ReadLaterOptions) check() error {
+	if o.Match != "" {
+		if _, err := regexp.Compile(o.Match); err != nil {
+			return fmt.Errorf("invalid match pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// PocketDestination is the config of a database.DestinationPocket destination.
+type PocketDestination struct {
+	ReadLaterOptions
+	ConsumerKey string `json:"consumer_key"` // Of your Pocket application; may be an env:NAME or file:PATH reference
+	AccessToken string `json:"access_token"` // Of the account to save to; may be an env:NAME or file:PATH reference
+}
+
+// WallabagDestination is the config of a database.DestinationWallabag destination.
+// The secrets may be env:NAME or file:PATH references.
+type WallabagDestination struct {
+	ReadLaterOptions
+	URL          string `json:"url"` // Base URL of the wallabag instance
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+}
+
+// ReadwiseDestination is the config of a database.DestinationReadwise destination.
+type ReadwiseDestination struct {
+	ReadLaterOptions
+	Token string `json:"token"` // Readwise access token; may be an env:NAME or file:PATH reference
+}
+
+// ParseReadLaterDestination reads and checks the config of a Pocket,
+// wallabag or Readwise Reader destination, returning a *PocketDestination,
+// *WallabagDestination or *ReadwiseDestination.
+func ParseReadLaterDestination(destType string, raw json.RawMessage) (interface{}, error) {
+	var opts ReadLaterOptions
+	var d interface{}
+	switch destType {
+	case database.DestinationPocket:
+		p := &PocketDestination{}
+		if err := json.Unmarshal(raw, p); err != nil {
+			return nil, fmt.Errorf("invalid Pocket destination: %w", err)
+		}
+		if p.ConsumerKey == "" || p.AccessToken == "" {
+			return nil, fmt.Errorf("Pocket destination needs a consumer key and an access token")
+		}
+		opts, d = p.ReadLaterOptions, p
+	case database.DestinationWallabag:
+		w := &WallabagDestination{}
+		if err := json.Unmarshal(raw, w); err != nil {
+			return nil, fmt.Errorf("invalid wallabag destination: %w", err)
+		}
+		if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid wallabag URL %q: expected an http(s) URL", w.URL)
+		}
+		if w.ClientID == "" || w.ClientSecret == "" || w.Username == "" || w.Password == "" {
+			return nil, fmt.Errorf("wallabag destination needs a client ID and secret, a username and a password")
+		}
+		opts, d = w.ReadLaterOptions, w
+	case database.DestinationReadwise:
+		r := &ReadwiseDestination{}
+		if err := json.Unmarshal(raw, r); err != nil {
+			return nil, fmt.Errorf("invalid Readwise destination: %w", err)
+		}
+		if r.Token == "" {
+			return nil, fmt.Errorf("Readwise destination needs an access token")
+		}
+		opts, d = r.ReadLaterOptions, r
+	default:
+		return nil, fmt.Errorf("%q is not a read-later destination type", destType)
+	}
+	if err := opts.check(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// wallabagToken is an OAuth access token of a wallabag account.
+type wallabagToken struct {
+	value   string
+	expires time.Time
+}
+
+// ReadLaterNotifier saves items to Pocket, wallabag or Readwise Reader.
+// Items without a link, or not matching a destination's pattern, are skipped.
+type ReadLaterNotifier struct {
+	client      *http.Client
+	cfg         config.WebhookConfig
+	pocketURL   string
+	readwiseURL string
+
+	tokensMu sync.Mutex
+	tokens   map[string]wallabagToken // By instance URL, client ID and username
+}
+
+// NewReadLaterNotifier creates a ReadLaterNotifier sending through client
+// with the timeout and retries of cfg.
+func NewReadLaterNotifier(client *http.Client, cfg config.WebhookConfig) *ReadLaterNotifier {
+	return &ReadLaterNotifier{client: client, cfg: cfg, pocketURL: pocketAddURL, readwiseURL: readwiseSaveURL, tokens: make(map[string]wallabagToken)}
+}
+
+// Send saves the item of msg to a read-later destination.
+func (n *ReadLaterNotifier) Send(ctx context.Context, dest *database.FeedDestination, msg *Message) error {
+	d, err := ParseReadLaterDestination(dest.Type, dest.Config)
+	if err != nil {
+		return err
+	}
+	switch d := d.(type) {
+	case *PocketDestination:
+		if !d.matches(msg) {
+			return nil
+		}
+		secrets, err := resolveSecrets(d.ConsumerKey, d.AccessToken)
+		if err != nil {
+			return fmt.Errorf("resolving Pocket credentials: %w", err)
+		}
+		body, err := json.Marshal(map[string]string{"url": msg.Link, "title": msg.Title, "tags": strings.Join(d.Tags, ","), "consumer_key": secrets[0], "access_token": secrets[1]})
+		if err != nil {
+			return err
+		}
+		return postJSON(ctx, n.client, n.cfg, n.pocketURL, map[string]string{"X-Accept": "application/json"}, body)
+	case *WallabagDestination:
+		if !d.matches(msg) {
+			return nil
+		}
+		token, err := n.wallabagToken(ctx, d)
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(map[string]string{"url": msg.Link, "title": msg.Title, "tags": strings.Join(d.Tags, ",")})
+		if err != nil {
+			return err
+		}
+		return postJSON(ctx, n.client, n.cfg, strings.TrimRight(d.URL, "/")+"/api/entries.json", map[string]string{"Authorization": "Bearer " + token}, body)
+	case *ReadwiseDestination:
+		if !d.matches(msg) {
+			return nil
+		}
+		token, err := proxy.ResolveSecret(d.Token)
+		if err != nil {
+			return fmt.Errorf("resolving Readwise token: %w", err)
+		}
+		body, err := json.Marshal(struct {
+			URL      string   `json:"url"`
+			Title    string   `json:"title,omitempty"`
+			Author   string   `json:"author,omitempty"`
+			Tags     []string `json:"tags,omitempty"`
+			Location string   `json:"location"`
+		}{msg.Link, msg.Title, msg.Author, d.Tags, "later"})
+		if err != nil {
+			return err
+		}
+		return postJSON(ctx, n.client, n.cfg, n.readwiseURL, map[string]string{"Authorization": "Token " + token}, body)
+	}
+	return nil
+}
+
+// wallabagToken returns an access token for the account of d, logging in
+// when there is no unexpired one.
+func (n *ReadLaterNotifier) wallabagToken(ctx context.Context, d *WallabagDestination) (string, error) {
+	key := d.URL + "\x00" + d.ClientID + "\x00" + d.Username
+	n.tokensMu.Lock()
+	token, ok := n.tokens[key]
+	n.tokensMu.Unlock()
+	if ok && time.Now().Before(token.expires) {
+		return token.value, nil
+	}
+
+	secrets, err := resolveSecrets(d.ClientSecret, d.Password)
+	if err != nil {
+		return "", fmt.Errorf("resolving wallabag credentials: %w", err)
+	}
+	form := url.Values{"grant_type": {"password"}, "client_id": {d.ClientID}, "client_secret": {secrets[0]}, "username": {d.Username}, "password": {secrets[1]}}
+	if n.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.cfg.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(d.URL, "/")+"/oauth/v2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("creating wallabag login request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("logging in to wallabag: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("logging in to wallabag: server returned %s", resp.Status)
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.AccessToken == "" {
+		return "", fmt.Errorf("logging in to wallabag: invalid token response")
+	}
+	// Renew a minute early so tokens don't expire mid-request
+	token = wallabagToken{value: result.AccessToken, expires: time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)}
+	n.tokensMu.Lock()
+	n.tokens[key] = token
+	n.tokensMu.Unlock()
+	return token.value, nil
+}
+
+// resolveSecrets resolves env:NAME and file:PATH references.
+func resolveSecrets(refs ...string) ([]string, error) {
+	values := make([]string, len(refs))
+	for i, ref := range refs {
+		value, err := proxy.ResolveSecret(ref)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
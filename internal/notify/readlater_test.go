@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLaterNotifierReadwise(t *testing.T) {
+	var payload map[string]interface{}
+	var auth string
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		auth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer srv.Close()
+	t.Setenv("READWISE_TOKEN", "rw_secret")
+
+	n := NewReadLaterNotifier(srv.Client(), config.WebhookConfig{})
+	n.readwiseURL = srv.URL
+	cfg, err := json.Marshal(ReadwiseDestination{ReadLaterOptions: ReadLaterOptions{Tags: []string{"rss"}}, Token: "env:READWISE_TOKEN"})
+	require.NoError(t, err)
+	require.NoError(t, n.Send(context.Background(), &database.FeedDestination{Type: database.DestinationReadwise, Config: cfg}, testMessage))
+	assert.Equal(t, "Token rw_secret", auth)
+	assert.Equal(t, testMessage.Link, payload["url"])
+	assert.Equal(t, "later", payload["location"])
+	assert.Equal(t, []interface{}{"rss"}, payload["tags"])
+
+	// Items not matching the pattern are skipped
+	cfg, err = json.Marshal(ReadwiseDestination{ReadLaterOptions: ReadLaterOptions{Match: "^nomatch$"}, Token: "t"})
+	require.NoError(t, err)
+	require.NoError(t, n.Send(context.Background(), &database.FeedDestination{Type: database.DestinationReadwise, Config: cfg}, testMessage))
+	assert.Equal(t, 1, calls)
+}
+
+func TestReadLaterNotifierWallabag(t *testing.T) {
+	logins, saves := 0, 0
+	var entry map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/v2/token":
+			logins++
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "password", r.PostForm.Get("grant_type"))
+			assert.Equal(t, "hunter2", r.PostForm.Get("password"))
+			w.Write([]byte(`{"access_token": "wb_token", "expires_in": 3600}`))
+		case "/api/entries.json":
+			saves++
+			assert.Equal(t, "Bearer wb_token", r.Header.Get("Authorization"))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&entry))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	n := NewReadLaterNotifier(srv.Client(), config.WebhookConfig{})
+	cfg, err := json.Marshal(WallabagDestination{URL: srv.URL + "/", ClientID: "id", ClientSecret: "secret", Username: "me", Password: "hunter2"})
+	require.NoError(t, err)
+	dest := &database.FeedDestination{Type: database.DestinationWallabag, Config: cfg}
+	require.NoError(t, n.Send(context.Background(), dest, testMessage))
+	require.NoError(t, n.Send(context.Background(), dest, testMessage))
+	assert.Equal(t, 1, logins, "token should be reused")
+	assert.Equal(t, 2, saves)
+	assert.Equal(t, testMessage.Link, entry["url"])
+}
+
+func TestParseReadLaterDestination(t *testing.T) {
+	_, err := ParseReadLaterDestination(database.DestinationPocket, json.RawMessage(`{"consumer_key": "k"}`))
+	assert.Error(t, err)
+	_, err = ParseReadLaterDestination(database.DestinationReadwise, json.RawMessage(`{"token": "t", "match": "("}`))
+	assert.Error(t, err)
+	_, err = ParseReadLaterDestination(database.DestinationWallabag, json.RawMessage(`{"url": "ftp://x", "client_id": "a", "client_secret": "b", "username": "c", "password": "d"}`))
+	assert.Error(t, err)
+	d, err := ParseReadLaterDestination(database.DestinationPocket, json.RawMessage(`{"consumer_key": "k", "access_token": "t"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "k", d.(*PocketDestination).ConsumerKey)
+}
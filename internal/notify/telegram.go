@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+)
+
+// TelegramDestination is the config of a database.DestinationTelegram destination.
+type TelegramDestination struct {
+	ChatID   string `json:"chat_id"`             // Numeric chat ID or @channel username
+	ThreadID int    `json:"thread_id,omitempty"` // Forum topic to post into; 0 posts to the general topic
+	BotID    int64  `json:"bot_id"`              // Bot to send with
+}
+
+// ParseTelegramDestination reads and checks a Telegram destination's config.
+func ParseTelegramDestination(raw json.RawMessage) (*TelegramDestination, error) {
+	var d TelegramDestination
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, fmt.Errorf("invalid Telegram destination: %w", err)
+	}
+	if d.ChatID == "" {
+		return nil, fmt.Errorf("Telegram destination has no chat ID")
+	}
+	if d.BotID == 0 {
+		return nil, fmt.Errorf("Telegram destination has no bot")
+	}
+	if d.ThreadID < 0 {
+		return nil, fmt.Errorf("invalid Telegram thread ID %d", d.ThreadID)
+	}
+	return &d, nil
+}
+
+// TelegramNotifier sends messages to Telegram destinations, through the
+// Telegram proxy pool or default proxy.
+type TelegramNotifier struct {
+	sender interfaces.Notifier
+	token  func(ctx context.Context, botID int64) (string, error)
+	proxy  func(ctx context.Context, feedID int64) (*database.Proxy, error)
+}
+
+// NewTelegramNotifier creates a TelegramNotifier sending with sender. token
+// looks up a bot's token and proxy picks the proxy for a feed's messages.
+// Forum topics need a sender that is an interfaces.ThreadNotifier.
+func NewTelegramNotifier(sender interfaces.Notifier, token func(ctx context.Context, botID int64) (string, error), proxy func(ctx context.Context, feedID int64) (*database.Proxy, error)) *TelegramNotifier {
+	return &TelegramNotifier{sender: sender, token: token, proxy: proxy}
+}
+
+// Send sends the parts of msg to a Telegram destination's chat.
+func (n *TelegramNotifier) Send(ctx context.Context, dest *database.FeedDestination, msg *Message) error {
+	d, err := ParseTelegramDestination(dest.Config)
+	if err != nil {
+		return err
+	}
+	token, err := n.token(ctx, d.BotID)
+	if err != nil {
+		return fmt.Errorf("retrieving token of bot %d: %w", d.BotID, err)
+	}
+	proxy, err := n.proxy(ctx, msg.FeedID)
+	if err != nil {
+		return fmt.Errorf("picking Telegram proxy: %w", err)
+	}
+	if d.ThreadID != 0 {
+		threads, ok := n.sender.(interfaces.ThreadNotifier)
+		if !ok {
+			return fmt.Errorf("%s cannot post into forum topics", n.sender.Name())
+		}
+		return threads.SendToThread(ctx, token, d.ChatID, d.ThreadID, msg.Parts, proxy)
+	}
+	return n.sender.Send(ctx, token, d.ChatID, msg.Parts, proxy)
+}
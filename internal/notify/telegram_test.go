@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSender records what it is asked to send.
+type fakeSender struct {
+	token, chatID string
+	threadID      int
+	parts         []interfaces.FormattedMessagePart
+}
+
+func (s *fakeSender) Send(_ context.Context, botToken, chatID string, parts []interfaces.FormattedMessagePart, _ *database.Proxy) error {
+	s.token, s.chatID, s.parts = botToken, chatID, parts
+	return nil
+}
+
+func (s *fakeSender) Name() string { return "fake" }
+
+// fakeThreadSender also posts into forum topics.
+type fakeThreadSender struct{ fakeSender }
+
+func (s *fakeThreadSender) SendToThread(ctx context.Context, botToken, chatID string, threadID int, parts []interfaces.FormattedMessagePart, proxy *database.Proxy) error {
+	s.threadID = threadID
+	return s.Send(ctx, botToken, chatID, parts, proxy)
+}
+
+func (s *fakeThreadSender) NextPoolToken(_ string, tokens []string) string { return tokens[0] }
+
+func (s *fakeThreadSender) ResolvedChatID(string) (int64, bool) { return 0, false }
+
+func (s *fakeThreadSender) CreateForumTopic(context.Context, string, string, string, *database.Proxy) (int, error) {
+	return 0, errors.New("not supported")
+}
+
+func telegramDest(t *testing.T, d TelegramDestination) *database.FeedDestination {
+	t.Helper()
+	cfg, err := json.Marshal(d)
+	require.NoError(t, err)
+	return &database.FeedDestination{Type: database.DestinationTelegram, Config: cfg}
+}
+
+func newTestTelegramNotifier(sender interfaces.Notifier) *TelegramNotifier {
+	token := func(_ context.Context, botID int64) (string, error) {
+		if botID != 3 {
+			return "", errors.New("no such bot")
+		}
+		return "token-3", nil
+	}
+	proxy := func(context.Context, int64) (*database.Proxy, error) { return nil, nil }
+	return NewTelegramNotifier(sender, token, proxy)
+}
+
+func TestTelegramNotifierSend(t *testing.T) {
+	sender := &fakeThreadSender{}
+	n := newTestTelegramNotifier(sender)
+	require.NoError(t, n.Send(context.Background(), telegramDest(t, TelegramDestination{ChatID: "@news", ThreadID: 5, BotID: 3}), testMessage))
+	assert.Equal(t, "token-3", sender.token)
+	assert.Equal(t, "@news", sender.chatID)
+	assert.Equal(t, 5, sender.threadID)
+	assert.Equal(t, testMessage.Parts, sender.parts)
+
+	err := n.Send(context.Background(), telegramDest(t, TelegramDestination{ChatID: "@news", BotID: 4}), testMessage)
+	assert.ErrorContains(t, err, "bot 4")
+}
+
+func TestTelegramNotifierThreadsNeedThreadNotifier(t *testing.T) {
+	sender := &fakeSender{}
+	n := newTestTelegramNotifier(sender)
+	require.NoError(t, n.Send(context.Background(), telegramDest(t, TelegramDestination{ChatID: "-100", BotID: 3}), testMessage))
+	assert.Equal(t, "-100", sender.chatID)
+
+	err := n.Send(context.Background(), telegramDest(t, TelegramDestination{ChatID: "-100", ThreadID: 2, BotID: 3}), testMessage)
+	assert.ErrorContains(t, err, "forum topics")
+}
+
+func TestParseTelegramDestination(t *testing.T) {
+	_, err := ParseTelegramDestination(json.RawMessage(`{"bot_id": 1}`))
+	assert.ErrorContains(t, err, "no chat ID")
+	_, err = ParseTelegramDestination(json.RawMessage(`{"chat_id": "@x"}`))
+	assert.ErrorContains(t, err, "no bot")
+}
+
+func TestRegistrySend(t *testing.T) {
+	r := NewRegistry()
+	var got *database.FeedDestination
+	r.Register("test", NotifierFunc(func(_ context.Context, dest *database.FeedDestination, _ *Message) error {
+		got = dest
+		return nil
+	}))
+	r.Register("off", Unavailable("off is not configured"))
+
+	dest := &database.FeedDestination{ID: 1, Type: "test"}
+	require.NoError(t, r.Send(context.Background(), dest, testMessage))
+	assert.Same(t, dest, got)
+	assert.EqualError(t, r.Send(context.Background(), &database.FeedDestination{Type: "off"}, testMessage), "off is not configured")
+	assert.ErrorContains(t, r.Send(context.Background(), &database.FeedDestination{Type: "pigeon"}, testMessage), `unknown destination type "pigeon"`)
+}
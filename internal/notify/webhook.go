@@ -0,0 +1,229 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+)
+
+// WebhookDestination is the config of a database.DestinationWebhook destination.
+type WebhookDestination struct {
+	URL      string            `json:"url"`
+	Template string            `json:"template,omitempty"` // Go text/template producing the JSON body; empty sends the default payload
+	Secret   string            `json:"secret,omitempty"`   // HMAC-SHA256 key signing the body; may be an env:NAME or file:PATH reference
+	Headers  map[string]string `json:"headers,omitempty"`  // Extra request headers, e.g. for authentication
+}
+
+// ParseWebhookDestination reads and checks a webhook destination's config.
+func ParseWebhookDestination(raw json.RawMessage) (*WebhookDestination, error) {
+	var d WebhookDestination
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, fmt.Errorf("invalid webhook destination: %w", err)
+	}
+	u, err := url.Parse(d.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL %q: expected an http(s) URL", d.URL)
+	}
+	if _, err := parseWebhookTemplate(d.Template); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// WebhookPayload is the JSON body posted when a destination has no template.
+type WebhookPayload struct {
+	Feed struct {
+		ID    int64  `json:"id"`
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	} `json:"feed"`
+	Item struct {
+		Title     string     `json:"title"`
+		Link      string     `json:"link,omitempty"`
+		Author    string     `json:"author,omitempty"`
+		Published *time.Time `json:"published,omitempty"`
+	} `json:"item"`
+	Parts []WebhookPart `json:"parts"`
+}
+
+// WebhookPart is a formatted part of the item in a WebhookPayload.
+type WebhookPart struct {
+	Text         string                      `json:"text,omitempty"`
+	ParseMode    string                      `json:"parse_mode,omitempty"`
+	PhotoURL     string                      `json:"photo_url,omitempty"`
+	VideoURL     string                      `json:"video_url,omitempty"`
+	AnimationURL string                      `json:"animation_url,omitempty"`
+	DocumentURL  string                      `json:"document_url,omitempty"`
+	MediaGroup   []interfaces.MediaGroupItem `json:"media_group,omitempty"` // Album of photos and videos, the first also in photo_url or video_url
+}
+
+// newWebhookPayload describes msg as a WebhookPayload.
+func newWebhookPayload(msg *Message) *WebhookPayload {
+	p := &WebhookPayload{Parts: []WebhookPart{}}
+	p.Feed.ID, p.Feed.Title, p.Feed.URL = msg.FeedID, msg.FeedTitle, msg.FeedURL
+	p.Item.Title, p.Item.Link, p.Item.Author, p.Item.Published = msg.Title, msg.Link, msg.Author, msg.Published
+	for _, part := range msg.Parts {
+		text := part.Text
+		if text == "" {
+			text = part.DocumentCaption
+		}
+		p.Parts = append(p.Parts, WebhookPart{Text: text, ParseMode: part.ParseMode, PhotoURL: part.PhotoURL, VideoURL: part.VideoURL, AnimationURL: part.AnimationURL, DocumentURL: part.DocumentURL, MediaGroup: part.MediaGroup})
+	}
+	return p
+}
+
+// parseWebhookTemplate parses a payload template, returning nil for the
+// default payload. Templates get a json function that encodes a value, e.g.
+// {"text": {{json .Item.Title}}}.
+func parseWebhookTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing webhook template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// WebhookNotifier posts messages as JSON to webhook destinations.
+type WebhookNotifier struct {
+	client *http.Client
+	cfg    config.WebhookConfig
+}
+
+// NewWebhookNotifier creates a WebhookNotifier sending through client.
+func NewWebhookNotifier(client *http.Client, cfg config.WebhookConfig) *WebhookNotifier {
+	return &WebhookNotifier{client: client, cfg: cfg}
+}
+
+// Send posts msg to a webhook destination.
+func (n *WebhookNotifier) Send(ctx context.Context, dest *database.FeedDestination, msg *Message) error {
+	d, err := ParseWebhookDestination(dest.Config)
+	if err != nil {
+		return err
+	}
+	body, err := webhookBody(d, msg)
+	if err != nil {
+		return err
+	}
+	var signature string
+	if d.Secret != "" {
+		secret, err := proxy.ResolveSecret(d.Secret)
+		if err != nil {
+			return fmt.Errorf("resolving webhook secret: %w", err)
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	headers := make(map[string]string, len(d.Headers)+1)
+	for name, value := range d.Headers {
+		headers[name] = value
+	}
+	if signature != "" {
+		headers["X-Hub-Signature-256"] = signature
+	}
+	return postJSON(ctx, n.client, n.cfg, d.URL, headers, body)
+}
+
+// webhookBody renders the JSON body posted for msg.
+func webhookBody(d *WebhookDestination, msg *Message) ([]byte, error) {
+	payload := newWebhookPayload(msg)
+	tmpl, _ := parseWebhookTemplate(d.Template) // Already checked by ParseWebhookDestination
+	if tmpl == nil {
+		return json.Marshal(payload)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, payload); err != nil {
+		return nil, fmt.Errorf("executing webhook template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("webhook template did not produce valid JSON (quote values with the json function)")
+	}
+	return buf.Bytes(), nil
+}
+
+// postJSON posts body to target. Requests failing with a network error, 429 or
+// a 5xx status are retried with exponential backoff as set in cfg.
+func postJSON(ctx context.Context, client *http.Client, cfg config.WebhookConfig, target string, headers map[string]string, body []byte) error {
+	delay := cfg.RetryDelay
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := postOnce(ctx, client, cfg.Timeout, target, headers, body)
+		if err == nil {
+			return nil
+		}
+		if retryAfter < 0 || attempt >= cfg.MaxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(max(delay, retryAfter)):
+		}
+		delay *= 2
+	}
+}
+
+// postOnce makes one delivery attempt. On failure it returns how long to wait
+// before retrying at least, or a negative duration when retrying is futile.
+func postOnce(ctx context.Context, client *http.Client, timeout time.Duration, target string, headers map[string]string, body []byte) (time.Duration, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return -1, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// Leave out the URL, which may embed a credential
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, fmt.Errorf("posting: %w", err)
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+	err = fmt.Errorf("server returned %s: %s", resp.Status, bytes.TrimSpace(snippet))
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return time.Duration(seconds) * time.Second, err
+	case resp.StatusCode >= 500:
+		return 0, err
+	}
+	return -1, err
+}
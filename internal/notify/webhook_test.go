@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func webhookDest(t *testing.T, d WebhookDestination) *database.FeedDestination {
+	t.Helper()
+	cfg, err := json.Marshal(d)
+	require.NoError(t, err)
+	return &database.FeedDestination{Type: database.DestinationWebhook, Config: cfg}
+}
+
+var testMessage = &Message{
+	FeedID: 7, FeedTitle: "Example", FeedURL: "https://example.com/feed.xml", Title: `Say "hi"`, Link: "https://example.com/1",
+	Parts: []interfaces.FormattedMessagePart{{Text: "<b>hi</b>", ParseMode: "HTML"}},
+}
+
+func TestWebhookNotifierSend(t *testing.T) {
+	var calls atomic.Int32
+	var body []byte
+	var signature, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		signature, auth = r.Header.Get("X-Hub-Signature-256"), r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	n := NewWebhookNotifier(srv.Client(), config.WebhookConfig{MaxRetries: 2, RetryDelay: time.Millisecond})
+	dest := webhookDest(t, WebhookDestination{URL: srv.URL, Secret: "s3cret", Headers: map[string]string{"Authorization": "Bearer x"}})
+	require.NoError(t, n.Send(context.Background(), dest, testMessage))
+
+	assert.EqualValues(t, 2, calls.Load(), "the 503 is retried")
+	assert.Equal(t, "Bearer x", auth)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signature)
+
+	var payload WebhookPayload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.EqualValues(t, 7, payload.Feed.ID)
+	assert.Equal(t, `Say "hi"`, payload.Item.Title)
+	require.Len(t, payload.Parts, 1)
+	assert.Equal(t, "<b>hi</b>", payload.Parts[0].Text)
+}
+
+func TestWebhookNotifierTemplate(t *testing.T) {
+	var body []byte
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, _ = io.ReadAll(r.Body)
+		if r.URL.Path == "/gone" {
+			http.Error(w, "no such hook", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	n := NewWebhookNotifier(srv.Client(), config.WebhookConfig{MaxRetries: 3, RetryDelay: time.Millisecond})
+
+	dest := webhookDest(t, WebhookDestination{URL: srv.URL, Template: `{"text": {{json .Item.Title}}, "feed": {{.Feed.ID}}}`})
+	require.NoError(t, n.Send(context.Background(), dest, testMessage))
+	assert.JSONEq(t, `{"text": "Say \"hi\"", "feed": 7}`, string(body))
+
+	err := n.Send(context.Background(), webhookDest(t, WebhookDestination{URL: srv.URL, Template: `{"text": "{{.Item.Title}}"}`}), testMessage)
+	assert.ErrorContains(t, err, "valid JSON")
+
+	calls.Store(0)
+	err = n.Send(context.Background(), webhookDest(t, WebhookDestination{URL: srv.URL + "/gone"}), testMessage)
+	assert.ErrorContains(t, err, "404")
+	assert.EqualValues(t, 1, calls.Load(), "client errors are not retried")
+
+	_, err = ParseWebhookDestination(json.RawMessage(`{"url":"ftp://example.com"}`))
+	assert.ErrorContains(t, err, "invalid webhook URL")
+}
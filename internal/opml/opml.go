@@ -0,0 +1,174 @@
+// Package opml reads and writes feed subscription lists in OPML, the format
+// feed readers import and export them in.
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Namespace is the XML namespace of the attributes the bot adds to outlines
+// it exports: rssbot:userTitle and rssbot:frequency (in seconds).
+const Namespace = "https://github.com/haytac/rss-telegram-bot/opml"
+
+// Feed is a subscription in an OPML document.
+type Feed struct {
+	URL              string
+	Title            string   // The outline's title, usually the feed's own or a reader's name for it
+	HTMLURL          string   // The site the feed belongs to
+	UserTitle        string   // rssbot:userTitle; empty when not set
+	FrequencySeconds int      // rssbot:frequency; 0 when not set
+	Tags             []string // Names of the folders the outline is in, then its categories
+}
+
+type document struct {
+	XMLName   xml.Name `xml:"opml"`
+	Version   string   `xml:"version,attr"`
+	Namespace string   `xml:"xmlns:rssbot,attr,omitempty"`
+	Head      head     `xml:"head"`
+	Body      body     `xml:"body"`
+}
+
+type head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type body struct {
+	Outlines []outline `xml:"outline"`
+}
+
+// outline is a feed when XMLURL is set, and a folder of outlines otherwise.
+// Decoding leaves the rssbot: attributes in Attrs, since encoding/xml only
+// matches them by namespace URL, which documents may not declare.
+type outline struct {
+	Text      string     `xml:"text,attr"`
+	Title     string     `xml:"title,attr,omitempty"`
+	Type      string     `xml:"type,attr,omitempty"`
+	XMLURL    string     `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL   string     `xml:"htmlUrl,attr,omitempty"`
+	Category  string     `xml:"category,attr,omitempty"`
+	UserTitle string     `xml:"rssbot:userTitle,attr,omitempty"`
+	Frequency int        `xml:"rssbot:frequency,attr,omitempty"`
+	Attrs     []xml.Attr `xml:",any,attr"`
+	Outlines  []outline  `xml:"outline"`
+}
+
+// Parse reads the feeds of an OPML document, in document order. Folders
+// become tags of the feeds in them, as do the entries of an outline's
+// category attribute ("/Tech/Go,/News" gives Tech, Go and News).
+func Parse(r io.Reader) ([]Feed, error) {
+	var doc document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing OPML: %w", err)
+	}
+	var feeds []Feed
+	if err := collect(doc.Body.Outlines, nil, &feeds); err != nil {
+		return nil, err
+	}
+	return feeds, nil
+}
+
+func collect(outlines []outline, folders []string, feeds *[]Feed) error {
+	for _, o := range outlines {
+		title := strings.TrimSpace(o.Title)
+		if title == "" {
+			title = strings.TrimSpace(o.Text)
+		}
+		if o.XMLURL == "" {
+			if err := collect(o.Outlines, append(folders[:len(folders):len(folders)], title), feeds); err != nil {
+				return err
+			}
+			continue
+		}
+		feed := Feed{URL: strings.TrimSpace(o.XMLURL), Title: title, HTMLURL: o.HTMLURL}
+		feed.Tags = append(feed.Tags, folders...)
+		for _, category := range strings.Split(o.Category, ",") {
+			feed.Tags = append(feed.Tags, strings.Split(category, "/")...)
+		}
+		for _, a := range o.Attrs {
+			if a.Name.Space != Namespace && a.Name.Space != "rssbot" {
+				continue
+			}
+			switch a.Name.Local {
+			case "userTitle":
+				feed.UserTitle = a.Value
+			case "frequency":
+				seconds, err := strconv.Atoi(a.Value)
+				if err != nil || seconds < 0 {
+					return fmt.Errorf("invalid rssbot:frequency %q of %s", a.Value, feed.URL)
+				}
+				feed.FrequencySeconds = seconds
+			}
+		}
+		feed.Tags = cleanTags(feed.Tags)
+		*feeds = append(*feeds, feed)
+	}
+	return nil
+}
+
+// cleanTags trims tags, dropping empty ones and repeats.
+func cleanTags(tags []string) []string {
+	var out []string
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// Write writes feeds as an OPML 2.0 document titled title. Feeds with tags
+// are put in a folder named after their first tag, and all their tags are
+// listed in the category attribute.
+func Write(w io.Writer, title string, feeds []Feed) error {
+	doc := document{
+		Version:   "2.0",
+		Namespace: Namespace,
+		Head:      head{Title: title, DateCreated: time.Now().UTC().Format(time.RFC1123Z)},
+	}
+	folders := make(map[string]int) // Index of each folder in doc.Body.Outlines
+	for _, f := range feeds {
+		o := outline{
+			Text:      f.Title,
+			Title:     f.Title,
+			Type:      "rss",
+			XMLURL:    f.URL,
+			HTMLURL:   f.HTMLURL,
+			UserTitle: f.UserTitle,
+			Frequency: f.FrequencySeconds,
+		}
+		if o.Text == "" {
+			o.Text = f.URL
+		}
+		tags := cleanTags(f.Tags)
+		if len(tags) == 0 {
+			doc.Body.Outlines = append(doc.Body.Outlines, o)
+			continue
+		}
+		o.Category = "/" + strings.Join(tags, ",/")
+		i, ok := folders[tags[0]]
+		if !ok {
+			i = len(doc.Body.Outlines)
+			folders[tags[0]] = i
+			doc.Body.Outlines = append(doc.Body.Outlines, outline{Text: tags[0], Title: tags[0]})
+		}
+		doc.Body.Outlines[i].Outlines = append(doc.Body.Outlines[i].Outlines, o)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("writing OPML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
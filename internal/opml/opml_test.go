@@ -0,0 +1,61 @@
+package opml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="1.0">
+  <head><title>Reader subscriptions</title></head>
+  <body>
+    <outline text="Loose" type="rss" xmlUrl="https://loose.example.com/feed" htmlUrl="https://loose.example.com/"/>
+    <outline text="Tech" title="Tech">
+      <outline text="Go Blog" type="rss" xmlUrl=" https://go.dev/blog/feed.atom " category="/Languages/Go,/Weekly"/>
+      <outline title="Nested">
+        <outline text="Deep" xmlUrl="https://deep.example.com/rss"/>
+      </outline>
+    </outline>
+  </body>
+</opml>`
+	feeds, err := Parse(strings.NewReader(doc))
+	require.NoError(t, err)
+	assert.Equal(t, []Feed{
+		{URL: "https://loose.example.com/feed", Title: "Loose", HTMLURL: "https://loose.example.com/"},
+		{URL: "https://go.dev/blog/feed.atom", Title: "Go Blog", Tags: []string{"Tech", "Languages", "Go", "Weekly"}},
+		{URL: "https://deep.example.com/rss", Title: "Deep", Tags: []string{"Tech", "Nested"}},
+	}, feeds)
+
+	_, err = Parse(strings.NewReader(`<opml><body><outline xmlUrl="https://a.example.com" rssbot:frequency="often" xmlns:rssbot="` + Namespace + `"/></body></opml>`))
+	assert.ErrorContains(t, err, "rssbot:frequency")
+	_, err = Parse(strings.NewReader("not xml"))
+	assert.Error(t, err)
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	feeds := []Feed{
+		{URL: "https://a.example.com/feed", Title: "A", UserTitle: "My A", FrequencySeconds: 600, Tags: []string{"News", "Daily"}},
+		{URL: "https://b.example.com/feed"},
+		{URL: "https://c.example.com/feed", Title: "C", Tags: []string{"News"}},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, "Feeds", feeds))
+	out := buf.String()
+	assert.Contains(t, out, `xmlns:rssbot="`+Namespace+`"`)
+	assert.Contains(t, out, `rssbot:userTitle="My A" rssbot:frequency="600"`)
+	assert.Equal(t, 1, strings.Count(out, `text="News"`), "feeds sharing a first tag share a folder")
+
+	parsed, err := Parse(&buf)
+	require.NoError(t, err)
+	// Outlines come back grouped by folder
+	assert.Equal(t, []Feed{
+		{URL: "https://a.example.com/feed", Title: "A", UserTitle: "My A", FrequencySeconds: 600, Tags: []string{"News", "Daily"}},
+		{URL: "https://c.example.com/feed", Title: "C", Tags: []string{"News"}},
+		{URL: "https://b.example.com/feed", Title: "https://b.example.com/feed"},
+	}, parsed)
+}
@@ -0,0 +1,267 @@
+// Package output re-publishes the items the bot delivered as RSS and Atom
+// feeds, so feed readers can follow the filtered stream the bot produces.
+package output
+
+import (
+	"crypto/subtle"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/rs/zerolog/log"
+)
+
+// summaryMaxRunes caps the plain text included with each item.
+const summaryMaxRunes = 2000
+
+// Server serves the delivered items at /rss and /atom. Both accept repeated
+// feed=<id> parameters and a chat=<chat_id> parameter to narrow the items
+// down, and limit=<n> up to the configured maximum.
+type Server struct {
+	store *database.DeliveryStore
+	cfg   config.OutputConfig
+	token string
+}
+
+// NewServer creates a Server. When token is not empty, readers must pass it
+// as ?token= or in an "Authorization: Bearer" header.
+func NewServer(store *database.DeliveryStore, cfg config.OutputConfig, token string) *Server {
+	return &Server{store: store, cfg: cfg, token: token}
+}
+
+// Handler returns the HTTP handler serving the feeds.
+func (s *Server) Handler() http.Handler {
+	mux := chi.NewRouter()
+	mux.Get("/rss", s.serveRSS)
+	mux.Get("/atom", s.serveAtom)
+	return mux
+}
+
+// StartServer serves the feeds on addr in the background.
+func (s *Server) StartServer(addr string) {
+	log.Info().Str("address", addr).Msg("Starting output feed server")
+	go func() {
+		if err := http.ListenAndServe(addr, s.Handler()); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("Output feed server failed")
+		}
+	}()
+}
+
+// deliveries authorizes r and loads the deliveries it asks for, writing an
+// error response and returning false on failure.
+func (s *Server) deliveries(w http.ResponseWriter, r *http.Request) ([]*database.Delivery, bool) {
+	if s.token != "" {
+		token := r.URL.Query().Get("token")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = bearer
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return nil, false
+		}
+	}
+	filter := database.DeliveryFilter{ChatID: r.URL.Query().Get("chat"), Limit: s.cfg.MaxItems}
+	for _, v := range r.URL.Query()["feed"] {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid feed %q", v), http.StatusBadRequest)
+			return nil, false
+		}
+		filter.FeedIDs = append(filter.FeedIDs, id)
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, fmt.Sprintf("invalid limit %q", v), http.StatusBadRequest)
+			return nil, false
+		}
+		if limit < filter.Limit || filter.Limit <= 0 {
+			filter.Limit = limit
+		}
+	}
+	deliveries, err := s.store.ListDeliveries(r.Context(), filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load deliveries for the output feed")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return deliveries, true
+}
+
+// selfURL is the URL r was made to, without the token.
+func selfURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	q := r.URL.Query()
+	q.Del("token")
+	u := scheme + "://" + r.Host + r.URL.Path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
+}
+
+// guid identifies a delivery in the output feeds.
+func guid(d *database.Delivery) string {
+	switch {
+	case d.GUID != "":
+		return d.GUID
+	case d.Link != "":
+		return d.Link
+	}
+	return fmt.Sprintf("delivery-%d", d.ID)
+}
+
+// summary is the text shown with a delivery, cut to summaryMaxRunes.
+func summary(d *database.Delivery) string {
+	if runes := []rune(d.Text); len(runes) > summaryMaxRunes {
+		return string(runes[:summaryMaxRunes]) + "…"
+	}
+	return d.Text
+}
+
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string     `xml:"title"`
+	Link        string     `xml:"link,omitempty"`
+	Description string     `xml:"description,omitempty"`
+	GUID        rssGUID    `xml:"guid"`
+	PubDate     string     `xml:"pubDate"`
+	Source      *rssSource `xml:"source,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssSource struct {
+	URL   string `xml:"url,attr"`
+	Title string `xml:",chardata"`
+}
+
+// serveRSS writes the deliveries as an RSS 2.0 feed.
+func (s *Server) serveRSS(w http.ResponseWriter, r *http.Request) {
+	deliveries, ok := s.deliveries(w, r)
+	if !ok {
+		return
+	}
+	doc := rssDoc{Version: "2.0", Channel: rssChannel{Title: s.cfg.Title, Link: selfURL(r), Description: "Items delivered by " + s.cfg.Title}}
+	if len(deliveries) > 0 {
+		doc.Channel.LastBuildDate = deliveries[0].DeliveredAt.UTC().Format(time.RFC1123Z)
+	}
+	for _, d := range deliveries {
+		item := rssItem{Title: d.Title, Link: d.Link, Description: summary(d), GUID: rssGUID{Value: guid(d)}, PubDate: itemTime(d).UTC().Format(time.RFC1123Z)}
+		if d.FeedURL != "" {
+			item.Source = &rssSource{URL: d.FeedURL, Title: d.FeedTitle}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	writeXML(w, "application/rss+xml", doc)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  atomPerson  `xml:"author"` // Stands in for entries without one
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Link      *atomLink   `xml:"link,omitempty"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Summary   string      `xml:"summary,omitempty"`
+	Source    *atomSource `xml:"source,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomSource struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+}
+
+// serveAtom writes the deliveries as an Atom feed.
+func (s *Server) serveAtom(w http.ResponseWriter, r *http.Request) {
+	deliveries, ok := s.deliveries(w, r)
+	if !ok {
+		return
+	}
+	self := selfURL(r)
+	feed := atomFeed{ID: self, Title: s.cfg.Title, Updated: time.Now().UTC().Format(time.RFC3339), Link: atomLink{Href: self, Rel: "self"}, Author: atomPerson{Name: s.cfg.Title}}
+	if len(deliveries) > 0 {
+		feed.Updated = deliveries[0].DeliveredAt.UTC().Format(time.RFC3339)
+	}
+	for _, d := range deliveries {
+		entry := atomEntry{ID: guid(d), Title: d.Title, Updated: d.DeliveredAt.UTC().Format(time.RFC3339), Summary: summary(d)}
+		if d.PublishedAt != nil {
+			entry.Published = d.PublishedAt.UTC().Format(time.RFC3339)
+		}
+		if d.Link != "" {
+			entry.Link = &atomLink{Href: d.Link}
+		}
+		if d.Author != "" {
+			entry.Author = &atomPerson{Name: d.Author}
+		}
+		if d.FeedURL != "" {
+			entry.Source = &atomSource{ID: d.FeedURL, Title: d.FeedTitle}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	writeXML(w, "application/atom+xml", feed)
+}
+
+// itemTime is when a delivery was published, or delivered if unknown.
+func itemTime(d *database.Delivery) time.Time {
+	if d.PublishedAt != nil {
+		return *d.PublishedAt
+	}
+	return d.DeliveredAt
+}
+
+func writeXML(w http.ResponseWriter, contentType string, v interface{}) {
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Warn().Err(err).Msg("Failed to write output feed")
+	}
+}
@@ -0,0 +1,81 @@
+package output
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestServer(t *testing.T) (*Server, int64) {
+	t.Helper()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "output.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	feeds := database.NewFeedStore(db)
+	newsID, err := feeds.CreateFeed(ctx, &database.Feed{URL: "https://example.com/news.xml", FrequencySeconds: 300, TelegramChatID: "-100"})
+	require.NoError(t, err)
+	blogID, err := feeds.CreateFeed(ctx, &database.Feed{URL: "https://example.com/blog.xml", FrequencySeconds: 300, TelegramChatID: "@blog"})
+	require.NoError(t, err)
+
+	store := database.NewDeliveryStore(db)
+	now := time.Now()
+	for _, d := range []*database.Delivery{
+		{FeedID: newsID, FeedTitle: "News", ChatID: "-100", GUID: "n1", Title: "Budget <passed>", Link: "https://example.com/n1", Author: "Ann", Text: "Details & more", DeliveredAt: now.Add(-time.Minute)},
+		{FeedID: blogID, FeedTitle: "Blog", ChatID: "@blog", Title: "New post", Link: "https://example.com/b1", DeliveredAt: now},
+	} {
+		_, err := store.RecordDelivery(ctx, d)
+		require.NoError(t, err)
+	}
+	return NewServer(store, config.OutputConfig{Title: "Curated", MaxItems: 10}, "secret"), newsID
+}
+
+func get(t *testing.T, s *Server, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestServeFeeds(t *testing.T) {
+	s, newsID := setupTestServer(t)
+	assert.Equal(t, http.StatusUnauthorized, get(t, s, "/rss").Code)
+
+	for _, path := range []string{"/rss", "/atom"} {
+		rec := get(t, s, path+"?token=secret")
+		require.Equal(t, http.StatusOK, rec.Code, path)
+		feed, err := gofeed.NewParser().ParseString(rec.Body.String())
+		require.NoError(t, err, path)
+		assert.Equal(t, "Curated", feed.Title)
+		require.Len(t, feed.Items, 2, path)
+		assert.Equal(t, "New post", feed.Items[0].Title)
+		assert.Equal(t, "Budget <passed>", feed.Items[1].Title)
+		assert.Equal(t, "n1", feed.Items[1].GUID)
+		assert.Equal(t, "Details & more", feed.Items[1].Description)
+		assert.NotContains(t, rec.Body.String(), "secret", path)
+	}
+
+	rec := get(t, s, "/rss?token=secret&chat=@blog")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, strings.Count(rec.Body.String(), "<item>"))
+	assert.Contains(t, rec.Body.String(), "New post")
+
+	rec = get(t, s, "/atom?token=secret&limit=5&feed="+strconv.FormatInt(newsID, 10))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, strings.Count(rec.Body.String(), "<entry>"))
+	assert.Contains(t, rec.Body.String(), "Budget")
+
+	assert.Equal(t, http.StatusBadRequest, get(t, s, "/rss?token=secret&feed=x").Code)
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+)
+
+// BenchResult summarizes the benchmark runs through one proxy. Latencies are
+// medians over the successful runs.
+type BenchResult struct {
+	Proxy     *database.Proxy
+	Runs      int
+	Successes int
+	Connect   time.Duration // Until the connection (through the proxy, including TLS) is ready
+	TTFB      time.Duration // Until the first response byte
+	LastErr   error         // Most recent failure, if any
+}
+
+// SuccessRate is the fraction of runs that got a 2xx response.
+func (r BenchResult) SuccessRate() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+	return float64(r.Successes) / float64(r.Runs)
+}
+
+// Bench requests target runs times through each proxy, proxies concurrently and
+// runs one after another on fresh connections, and returns the results ranked
+// by success rate and then TTFB.
+func Bench(ctx context.Context, factory *DefaultHTTPClientFactory, proxies []*database.Proxy, target string, runs int) []BenchResult {
+	results := make([]BenchResult, len(proxies))
+	var wg sync.WaitGroup
+	for i, p := range proxies {
+		wg.Add(1)
+		go func(i int, p *database.Proxy) {
+			defer wg.Done()
+			results[i] = benchProxy(ctx, uncheckedClients{factory}, p, target, runs)
+		}(i, p)
+	}
+	wg.Wait()
+	sort.SliceStable(results, func(a, b int) bool {
+		ra, rb := results[a], results[b]
+		if ra.SuccessRate() != rb.SuccessRate() {
+			return ra.SuccessRate() > rb.SuccessRate()
+		}
+		return ra.TTFB < rb.TTFB
+	})
+	return results
+}
+
+func benchProxy(ctx context.Context, clients uncheckedClients, p *database.Proxy, target string, runs int) BenchResult {
+	result := BenchResult{Proxy: p, Runs: runs}
+	client, err := clients.GetClient(p)
+	if err != nil {
+		result.LastErr = err
+		return result
+	}
+	var connects, ttfbs []time.Duration
+	for i := 0; i < runs && ctx.Err() == nil; i++ {
+		connect, ttfb, err := benchRequest(ctx, client, target)
+		client.CloseIdleConnections() // Measure a fresh connection every run
+		if err != nil {
+			result.LastErr = err
+			continue
+		}
+		result.Successes++
+		connects = append(connects, connect)
+		ttfbs = append(ttfbs, ttfb)
+	}
+	result.Connect, result.TTFB = median(connects), median(ttfbs)
+	return result
+}
+
+// benchRequest times one GET of target.
+func benchRequest(ctx context.Context, client *http.Client, target string) (connect, ttfb time.Duration, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	start := time.Now()
+	var connected time.Time
+	trace := &httptrace.ClientTrace{
+		GotConn:              func(httptrace.GotConnInfo) { connected = time.Now() },
+		GotFirstResponseByte: func() { ttfb = time.Since(start) },
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, target, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("User-Agent", "RSSBotProxyBench/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, 0, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if !connected.IsZero() {
+		connect = connected.Sub(start)
+	}
+	return connect, ttfb, nil
+}
+
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
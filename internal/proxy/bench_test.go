@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBench(t *testing.T) {
+	// Acting as HTTP proxies, these answer the absolute probe URL themselves.
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer fast.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer slow.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer broken.Close()
+
+	proxyFor := func(id int64, srv *httptest.Server) *database.Proxy {
+		return &database.Proxy{ID: id, Name: srv.URL, Type: "http", Address: strings.TrimPrefix(srv.URL, "http://")}
+	}
+	proxies := []*database.Proxy{proxyFor(1, broken), proxyFor(2, slow), proxyFor(3, fast)}
+
+	results := Bench(context.Background(), NewHTTPClientFactory(), proxies, "http://probe.invalid/", 3)
+	require.Len(t, results, 3)
+	assert.Equal(t, []int64{3, 2, 1}, []int64{results[0].Proxy.ID, results[1].Proxy.ID, results[2].Proxy.ID})
+	assert.Equal(t, 1.0, results[0].SuccessRate())
+	assert.Greater(t, results[1].TTFB, results[0].TTFB)
+	assert.Zero(t, results[2].Successes)
+	assert.ErrorContains(t, results[2].LastErr, "502")
+}
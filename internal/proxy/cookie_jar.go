@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/publicsuffix"
+)
+
+// PersistentJar is a feed's cookie jar. It behaves like net/http/cookiejar and
+// writes every cookie servers set through to the database, so sessions survive
+// restarts.
+type PersistentJar struct {
+	jar    *cookiejar.Jar
+	store  *database.CookieStore
+	feedID int64
+}
+
+// NewPersistentJar creates a jar for a feed, loaded with its stored cookies.
+func NewPersistentJar(ctx context.Context, store *database.CookieStore, feedID int64) (*PersistentJar, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+	stored, err := store.ListCookies(ctx, feedID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sc := range stored {
+		scheme := "http"
+		if sc.Secure {
+			scheme = "https"
+		}
+		cookie := &http.Cookie{Name: sc.Name, Value: sc.Value, Path: sc.Path, Secure: sc.Secure, HttpOnly: sc.HTTPOnly}
+		if !sc.HostOnly {
+			cookie.Domain = sc.Domain
+		}
+		if sc.ExpiresAt != nil {
+			cookie.Expires = *sc.ExpiresAt
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: strings.TrimPrefix(sc.Domain, "."), Path: sc.Path}, []*http.Cookie{cookie})
+	}
+	return &PersistentJar{jar: jar, store: store, feedID: feedID}, nil
+}
+
+// Cookies implements http.CookieJar.
+func (j *PersistentJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// SetCookies implements http.CookieJar, persisting the cookies as well.
+func (j *PersistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, c := range cookies {
+		sc := &database.StoredCookie{
+			FeedID:   j.feedID,
+			Name:     c.Name,
+			Domain:   strings.ToLower(u.Hostname()),
+			Path:     c.Path,
+			HostOnly: c.Domain == "",
+			Value:    c.Value,
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+		}
+		if !sc.HostOnly {
+			sc.Domain = strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+		}
+		if sc.Path == "" || !strings.HasPrefix(sc.Path, "/") {
+			sc.Path = defaultCookiePath(u.Path)
+		}
+
+		var err error
+		switch {
+		case c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(time.Now())):
+			err = j.store.DeleteCookie(ctx, j.feedID, sc.Name, sc.Domain, sc.Path)
+		default:
+			if c.MaxAge > 0 {
+				expires := time.Now().Add(time.Duration(c.MaxAge) * time.Second)
+				sc.ExpiresAt = &expires
+			} else if !c.Expires.IsZero() {
+				expires := c.Expires
+				sc.ExpiresAt = &expires
+			}
+			err = j.store.SaveCookie(ctx, sc)
+		}
+		if err != nil {
+			log.Warn().Err(err).Int64("feed_id", j.feedID).Str("cookie", c.Name).Msg("Failed to persist cookie")
+		}
+	}
+}
+
+// defaultCookiePath implements the RFC 6265 default-path algorithm.
+func defaultCookiePath(requestPath string) string {
+	i := strings.LastIndex(requestPath, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
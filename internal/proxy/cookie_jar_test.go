@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistentJar_SurvivesReload(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	feedID, err := database.NewFeedStore(db).CreateFeed(ctx, &database.Feed{URL: "https://example.com/feed", FrequencySeconds: 300, TelegramChatID: "1", IsEnabled: true})
+	require.NoError(t, err)
+	store := database.NewCookieStore(db)
+
+	u, _ := url.Parse("https://example.com/feed")
+	jar, err := NewPersistentJar(ctx, store, feedID)
+	require.NoError(t, err)
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "abc", Path: "/"},
+		{Name: "wide", Value: "1", Domain: "example.com", MaxAge: 3600},
+	})
+
+	reloaded, err := NewPersistentJar(ctx, store, feedID)
+	require.NoError(t, err)
+	got := map[string]string{}
+	for _, c := range reloaded.Cookies(u) {
+		got[c.Name] = c.Value
+	}
+	assert.Equal(t, map[string]string{"session": "abc", "wide": "1"}, got)
+
+	sub, _ := url.Parse("https://www.example.com/")
+	assert.Len(t, reloaded.Cookies(sub), 1, "domain cookie applies to subdomains, host-only cookie does not")
+
+	reloaded.SetCookies(u, []*http.Cookie{{Name: "session", Value: "", Path: "/", MaxAge: -1}})
+	again, err := NewPersistentJar(ctx, store, feedID)
+	require.NoError(t, err)
+	assert.Len(t, again.Cookies(u), 1)
+}
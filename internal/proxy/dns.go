@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// dohMediaType is the RFC 8484 wire-format content type.
+const dohMediaType = "application/dns-message"
+
+// NewResolver returns a resolver that sends every query to server (host or
+// host:port, port 53 by default) or to a DNS-over-HTTPS endpoint, bypassing the
+// system resolver. It returns nil, meaning the system resolver, when neither is
+// set. The DoH endpoint's own host is looked up with the system resolver, so
+// give it as an IP (e.g. https://1.1.1.1/dns-query) where that is unreliable.
+func NewResolver(server, dohURL string) (*net.Resolver, error) {
+	switch {
+	case server != "" && dohURL != "":
+		return nil, errors.New("set either a DNS server or a DoH URL, not both")
+	case dohURL != "":
+		u, err := url.Parse(dohURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("invalid DoH URL %q: must be an https:// URL", dohURL)
+		}
+		return newDoHResolver(dohURL, &http.Client{Timeout: 10 * time.Second}), nil
+	case server != "":
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		dialer := &net.Dialer{Timeout: 5 * time.Second}
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, server)
+			},
+		}, nil
+	}
+	return nil, nil
+}
+
+func newDoHResolver(endpoint string, client *http.Client) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, client: client, endpoint: endpoint}, nil
+		},
+	}
+}
+
+// dohConn carries the Go resolver's TCP-framed DNS exchange over HTTPS: each
+// length-prefixed query written to it is POSTed to the endpoint, and the answer
+// is returned, length-prefixed, from Read. It does not implement
+// net.PacketConn, so the resolver always uses stream framing with it.
+type dohConn struct {
+	ctx      context.Context
+	client   *http.Client
+	endpoint string
+	pending  bytes.Buffer
+	answer   bytes.Reader
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.pending.Write(b)
+	for c.pending.Len() >= 2 {
+		size := int(binary.BigEndian.Uint16(c.pending.Bytes()[:2]))
+		if c.pending.Len() < 2+size {
+			break
+		}
+		query := make([]byte, size)
+		c.pending.Next(2)
+		c.pending.Read(query)
+		answer, err := c.exchange(query)
+		if err != nil {
+			return 0, err
+		}
+		framed := make([]byte, 2+len(answer))
+		binary.BigEndian.PutUint16(framed, uint16(len(answer)))
+		copy(framed[2:], answer)
+		c.answer.Reset(framed)
+	}
+	return len(b), nil
+}
+
+func (c *dohConn) exchange(query []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.endpoint, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dohMediaType)
+	req.Header.Set("Accept", dohMediaType)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DoH query: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH query: status %d", resp.StatusCode)
+	}
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return nil, fmt.Errorf("DoH answer: %w", err)
+	}
+	return answer, nil
+}
+
+func (c *dohConn) Read(b []byte) (int, error) { return c.answer.Read(b) }
+func (c *dohConn) Close() error               { return nil }
+func (c *dohConn) LocalAddr() net.Addr        { return dohAddr{} }
+func (c *dohConn) RemoteAddr() net.Addr       { return dohAddr{} }
+
+// Deadlines are enforced through the dial context and the HTTP client timeout.
+func (c *dohConn) SetDeadline(time.Time) error      { return nil }
+func (c *dohConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(time.Time) error { return nil }
+
+type dohAddr struct{}
+
+func (dohAddr) Network() string { return "doh" }
+func (dohAddr) String() string  { return "doh" }
@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestNewResolver_DoH(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, dohMediaType, r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		var query dnsmessage.Message
+		require.NoError(t, query.Unpack(body))
+
+		answer := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.Header.ID, Response: true, RecursionAvailable: true},
+			Questions: query.Questions,
+		}
+		q := query.Questions[0]
+		if q.Type == dnsmessage.TypeA {
+			answer.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+				Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 7}},
+			}}
+		}
+		packed, err := answer.Pack()
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", dohMediaType)
+		w.Write(packed)
+	}))
+	defer srv.Close()
+
+	resolver := newDoHResolver(srv.URL, srv.Client())
+	addrs, err := resolver.LookupHost(context.Background(), "feeds.example.test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.7"}, addrs)
+}
+
+func TestNewResolver_Config(t *testing.T) {
+	r, err := NewResolver("", "")
+	require.NoError(t, err)
+	assert.Nil(t, r)
+
+	_, err = NewResolver("1.1.1.1", "https://1.1.1.1/dns-query")
+	assert.Error(t, err)
+	_, err = NewResolver("", "http://1.1.1.1/dns-query")
+	assert.Error(t, err)
+
+	r, err = NewResolver("1.1.1.1", "")
+	require.NoError(t, err)
+	assert.NotNil(t, r)
+}
@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// HealthChecker periodically validates every proxy against a probe URL and
+// tells the client factory which ones are down, so pools and direct fallback
+// route around them.
+type HealthChecker struct {
+	factory   *DefaultHTTPClientFactory
+	store     *database.ProxyStore
+	validator *DefaultProxyValidator
+	probeURL  string
+	interval  time.Duration
+	threshold int // Consecutive failures before a proxy is marked down
+	onChange  func(ctx context.Context, p *database.Proxy, healthy bool, err error)
+
+	mu       sync.Mutex
+	failures map[int64]int
+	stopCh   chan struct{}
+}
+
+// NewHealthChecker creates a checker that probes probeURL through each proxy
+// every interval and marks a proxy down after threshold consecutive failures.
+func NewHealthChecker(factory *DefaultHTTPClientFactory, store *database.ProxyStore, probeURL string, interval time.Duration, threshold int) *HealthChecker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &HealthChecker{
+		factory:   factory,
+		store:     store,
+		validator: NewDefaultProxyValidator(uncheckedClients{factory}),
+		probeURL:  probeURL,
+		interval:  interval,
+		threshold: threshold,
+		failures:  make(map[int64]int),
+	}
+}
+
+// OnHealthChange sets a function called when a proxy is marked down, with the
+// error of the probe that did, or healthy again. Set it before Start.
+func (c *HealthChecker) OnHealthChange(fn func(ctx context.Context, p *database.Proxy, healthy bool, err error)) {
+	c.onChange = fn
+}
+
+// uncheckedClients builds clients through a proxy even while it is down,
+// which the health probes need to notice it recovering.
+type uncheckedClients struct{ f *DefaultHTTPClientFactory }
+
+func (u uncheckedClients) GetClient(p *database.Proxy) (*http.Client, error) {
+	return u.f.buildClient(p, p.TLS)
+}
+
+// Start checks all proxies immediately and then every interval until Stop is
+// called or ctx is done.
+func (c *HealthChecker) Start(ctx context.Context) {
+	c.mu.Lock()
+	if c.stopCh != nil {
+		c.mu.Unlock()
+		return
+	}
+	stopCh := make(chan struct{})
+	c.stopCh = stopCh
+	c.mu.Unlock()
+
+	log.Info().Dur("interval", c.interval).Str("probe_url", c.probeURL).Msg("Proxy health checker started")
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			c.CheckAll(ctx)
+			select {
+			case <-stopCh:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the periodic checks.
+func (c *HealthChecker) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopCh != nil {
+		close(c.stopCh)
+		c.stopCh = nil
+	}
+}
+
+// CheckAll probes every configured proxy concurrently.
+func (c *HealthChecker) CheckAll(ctx context.Context) {
+	proxies, err := c.store.ListProxies(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list proxies for health checks")
+		return
+	}
+	var wg sync.WaitGroup
+	for _, p := range proxies {
+		wg.Add(1)
+		go func(p *database.Proxy) {
+			defer wg.Done()
+			c.check(ctx, p)
+		}(p)
+	}
+	wg.Wait()
+}
+
+func (c *HealthChecker) check(ctx context.Context, p *database.Proxy) {
+	start := time.Now()
+	err := c.validator.Validate(ctx, p, c.probeURL)
+	metrics.ProxyProbeDuration.WithLabelValues(p.Name).Set(time.Since(start).Seconds())
+	if ctx.Err() != nil {
+		return // Shutting down; the probe says nothing about the proxy
+	}
+
+	c.mu.Lock()
+	if err == nil {
+		c.failures[p.ID] = 0
+	} else {
+		c.failures[p.ID]++
+	}
+	failures := c.failures[p.ID]
+	c.mu.Unlock()
+
+	healthy := failures < c.threshold
+	wasHealthy := c.factory.ProxyHealthy(p)
+	c.factory.SetProxyHealthy(p.ID, healthy)
+	if err == nil {
+		metrics.ProxyHealthChecks.WithLabelValues(p.Name, "ok").Inc()
+	} else {
+		metrics.ProxyHealthChecks.WithLabelValues(p.Name, "failed").Inc()
+		log.Debug().Err(err).Str("proxy_name", p.Name).Int("consecutive_failures", failures).Msg("Proxy health check failed")
+	}
+	if healthy {
+		metrics.ProxyUp.WithLabelValues(p.Name).Set(1)
+	} else {
+		metrics.ProxyUp.WithLabelValues(p.Name).Set(0)
+	}
+
+	switch {
+	case wasHealthy && !healthy:
+		log.Warn().Err(err).Str("proxy_name", p.Name).Str("proxy_address", p.Address).Msg("Proxy marked down; routing around it")
+	case !wasHealthy && healthy:
+		log.Info().Str("proxy_name", p.Name).Str("proxy_address", p.Address).Msg("Proxy is healthy again")
+	}
+	if wasHealthy != healthy && c.onChange != nil {
+		c.onChange(ctx, p, healthy, err)
+	}
+
+	var healthErr *string
+	if !healthy {
+		msg := err.Error()
+		healthErr = &msg
+	}
+	if errStore := c.store.SetProxyHealth(ctx, p.ID, time.Now(), healthErr); errStore != nil {
+		log.Warn().Err(errStore).Str("proxy_name", p.Name).Msg("Failed to record proxy health")
+	}
+}
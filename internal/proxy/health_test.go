@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthChecker(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	store := database.NewProxyStore(db)
+	ctx := context.Background()
+
+	// An HTTP proxy receives the absolute probe URL; answering it directly is enough.
+	up := true
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxySrv.Close()
+	id, err := store.CreateProxy(ctx, &database.Proxy{Name: "flaky", Type: "http", Address: strings.TrimPrefix(proxySrv.URL, "http://")})
+	require.NoError(t, err)
+	p, err := store.GetProxyByID(ctx, id)
+	require.NoError(t, err)
+
+	factory := NewHTTPClientFactory().WithDirectFallback(true)
+	checker := NewHealthChecker(factory, store, "http://probe.invalid/generate_204", time.Minute, 2)
+	pool := &database.ProxyPool{ID: 1, Proxies: []*database.Proxy{p}}
+
+	checker.CheckAll(ctx)
+	assert.True(t, factory.ProxyHealthy(p))
+
+	up = false
+	checker.CheckAll(ctx)
+	assert.True(t, factory.ProxyHealthy(p), "one failure is below the threshold")
+	checker.CheckAll(ctx)
+	assert.False(t, factory.ProxyHealthy(p))
+	assert.Nil(t, factory.PoolProxy(pool, 1), "pools skip proxies that are down")
+	stored, err := store.GetProxyByID(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, stored.HealthError)
+	assert.Contains(t, *stored.HealthError, "502")
+
+	client, err := factory.GetClient(p)
+	require.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/feed", nil)
+	proxyURL, _ := client.Transport.(*http.Transport).Proxy(req)
+	if proxyURL != nil {
+		assert.NotEqual(t, p.Address, proxyURL.Host, "falls back to a direct connection")
+	}
+
+	up = true
+	checker.CheckAll(ctx)
+	assert.True(t, factory.ProxyHealthy(p))
+	assert.Same(t, p, factory.PoolProxy(pool, 1))
+	stored, err = store.GetProxyByID(ctx, id)
+	require.NoError(t, err)
+	assert.Nil(t, stored.HealthError)
+	assert.NotNil(t, stored.HealthCheckedAt)
+}
@@ -0,0 +1,17 @@
+//go:build quic
+
+package proxy
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Building with -tags quic enables http_version "3".
+func init() {
+	RegisterHTTP3Transport(func(tlsConfig *tls.Config) http.RoundTripper {
+		return &http3.Transport{TLSClientConfig: tlsConfig}
+	})
+}
@@ -1,36 +1,191 @@
 package proxy
 
 import (
-	// "context" // Only if GetClient takes context
+	"context"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/rs/zerolog/log"
 	"golang.org/x/net/proxy" // For SOCKS5
 )
 
 // DefaultHTTPClientFactory is a basic HTTP client factory.
 type DefaultHTTPClientFactory struct {
 	// proxyStore *database.ProxyStore // If needed to fetch default proxies
+	cookieStore    *database.CookieStore
+	resolver       *net.Resolver // nil uses the system resolver
+	network        NetworkOptions
+	jars           map[int64]*PersistentJar // Keyed by feed ID
+	jarsMu         sync.Mutex
+	poolCursors    map[int64]uint64 // Round-robin position per proxy pool ID
+	poolCursorsMu  sync.Mutex
+	down           map[int64]bool // Proxy IDs the health checker considers down
+	downMu         sync.RWMutex
+	directFallback bool                  // Connect directly instead of through a proxy that is down
+	pacScripts     map[string]*pacScript // Downloaded PAC files, keyed by URL
+	pacMu          sync.Mutex
+	rules          []*database.ProxyRule // Host-pattern proxy assignments, in matching order
+	noProxy        func(*url.URL) bool   // Reports destinations exempt from the default proxies; nil when none are
 }
 
 // NewHTTPClientFactory creates a new DefaultHTTPClientFactory.
 func NewHTTPClientFactory(/*proxyStore *database.ProxyStore*/) *DefaultHTTPClientFactory {
-	return &DefaultHTTPClientFactory{/*proxyStore: proxyStore*/}
+	return &DefaultHTTPClientFactory{/*proxyStore: proxyStore*/ jars: make(map[int64]*PersistentJar)}
 }
 
-// GetClient returns an HTTP client, configured with the given proxy if provided.
-// If proxy is nil, it returns a default HTTP client.
+// WithCookieStore enables persisted per-feed cookie jars for GetClientForFeed.
+func (f *DefaultHTTPClientFactory) WithCookieStore(store *database.CookieStore) *DefaultHTTPClientFactory {
+	f.cookieStore = store
+	return f
+}
+
+// WithResolver makes every client dial through r, e.g. one built by
+// NewResolver for a custom DNS server or DoH endpoint. A nil r keeps the system resolver.
+func (f *DefaultHTTPClientFactory) WithResolver(r *net.Resolver) *DefaultHTTPClientFactory {
+	f.resolver = r
+	return f
+}
+
+// WithNetwork applies an IP family preference and local bind address to every
+// client's connections.
+func (f *DefaultHTTPClientFactory) WithNetwork(opts NetworkOptions) *DefaultHTTPClientFactory {
+	f.network = opts
+	return f
+}
+
+// WithDirectFallback makes clients for a proxy the health checker considers
+// down connect directly instead, until the proxy recovers.
+func (f *DefaultHTTPClientFactory) WithDirectFallback(enabled bool) *DefaultHTTPClientFactory {
+	f.directFallback = enabled
+	return f
+}
+
+// SetProxyHealthy records whether a proxy passes its health checks. Proxies
+// are healthy until marked otherwise.
+func (f *DefaultHTTPClientFactory) SetProxyHealthy(proxyID int64, healthy bool) {
+	f.downMu.Lock()
+	defer f.downMu.Unlock()
+	if healthy {
+		delete(f.down, proxyID)
+		return
+	}
+	if f.down == nil {
+		f.down = make(map[int64]bool)
+	}
+	f.down[proxyID] = true
+}
+
+// ProxyHealthy reports whether p is usable; nil (no proxy) always is.
+func (f *DefaultHTTPClientFactory) ProxyHealthy(p *database.Proxy) bool {
+	if p == nil {
+		return true
+	}
+	f.downMu.RLock()
+	defer f.downMu.RUnlock()
+	return !f.down[p.ID]
+}
+
+// usableProxy swaps a proxy that is down for a direct connection when direct
+// fallback is enabled, globally or for that proxy.
+func (f *DefaultHTTPClientFactory) usableProxy(p *database.Proxy) *database.Proxy {
+	if !f.ProxyHealthy(p) && p.AllowsDirectFallback(f.directFallback) {
+		log.Warn().Str("proxy", p.Name).Msg("Proxy is down; connecting directly")
+		return nil
+	}
+	return p
+}
+
+// DialContext dials like the factory's clients do, for protocols other than
+// HTTP that should honor the same resolver and network settings.
+func (f *DefaultHTTPClientFactory) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return newNetworkDialer(f.network, f.resolver).DialContext(ctx, network, addr)
+}
+
+// GetClientForFeed returns a client like GetClient that also carries the feed's
+// cookie jar, when a cookie store is configured, and the feed's TLS settings
+// layered over the proxy's.
+func (f *DefaultHTTPClientFactory) GetClientForFeed(p *database.Proxy, feedID int64, feedTLS *database.TLSConfig) (*http.Client, error) {
+	p = f.usableProxy(p)
+	var proxyTLS *database.TLSConfig
+	if p != nil {
+		proxyTLS = p.TLS
+	}
+	client, err := f.newClient(p, mergeTLS(proxyTLS, feedTLS))
+	if err != nil || f.cookieStore == nil || feedID == 0 {
+		return client, err
+	}
+	jar, err := f.jarFor(feedID)
+	if err != nil {
+		return nil, fmt.Errorf("loading cookie jar for feed %d: %w", feedID, err)
+	}
+	client.Jar = jar
+	return client, nil
+}
+
+func (f *DefaultHTTPClientFactory) jarFor(feedID int64) (*PersistentJar, error) {
+	f.jarsMu.Lock()
+	defer f.jarsMu.Unlock()
+	if jar, ok := f.jars[feedID]; ok {
+		return jar, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	jar, err := NewPersistentJar(ctx, f.cookieStore, feedID)
+	if err != nil {
+		return nil, err
+	}
+	f.jars[feedID] = jar
+	return jar, nil
+}
+
+// GetClient returns an HTTP client, configured with the given proxy (and its
+// TLS settings) if provided. If proxy is nil, it returns a default HTTP client.
+// A proxy that is down is skipped when direct fallback is enabled.
 func (f *DefaultHTTPClientFactory) GetClient(p *database.Proxy) (*http.Client, error) {
+	p = f.usableProxy(p)
+	var tlsSettings *database.TLSConfig
+	if p != nil {
+		tlsSettings = p.TLS
+	}
+	return f.newClient(p, tlsSettings)
+}
+
+// newClient builds a client for p, routed by the proxy rules and the no-proxy
+// list when they apply to it.
+func (f *DefaultHTTPClientFactory) newClient(p *database.Proxy, tlsSettings *database.TLSConfig) (*http.Client, error) {
+	client, err := f.buildClient(p, tlsSettings)
+	if err != nil {
+		return nil, err
+	}
+	if f.noProxy != nil && p != nil && (p.IsDefaultForRSS || p.IsDefaultForTelegram) {
+		direct, err := f.buildClient(nil, tlsSettings)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &bypassTransport{exempt: f.noProxy, proxied: client.Transport, direct: direct.Transport}
+	}
+	if f.routesByRule(p) {
+		client.Transport = &ruleTransport{f: f, fallback: client.Transport, tls: tlsSettings}
+	}
+	return client, nil
+}
+
+// buildClient builds a client that connects through p, or directly if p is nil.
+func (f *DefaultHTTPClientFactory) buildClient(p *database.Proxy, tlsSettings *database.TLSConfig) (*http.Client, error) {
+	tlsConfig, err := BuildTLSConfig(tlsSettings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS settings: %w", err)
+	}
+	dialer := newNetworkDialer(f.network, f.resolver)
 	transport := &http.Transport{
+		TLSClientConfig: tlsConfig,
 		Proxy: http.ProxyFromEnvironment, // Default behavior
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
+		DialContext: dialer.DialContext,
 		ForceAttemptHTTP2:     true,
 		MaxIdleConns:          100,
 		IdleConnTimeout:       90 * time.Second,
@@ -38,13 +193,21 @@ func (f *DefaultHTTPClientFactory) GetClient(p *database.Proxy) (*http.Client, e
 		ExpectContinueTimeout: 1 * time.Second,
 	}
 
-	if p != nil && p.Address != "" {
+	if p != nil && p.Type == "pac" {
+		if transport.Proxy, err = f.pacProxyFunc(p); err != nil {
+			return nil, err
+		}
+	} else if p != nil && p.Address != "" {
 		proxyURLStr := fmt.Sprintf("%s://%s", p.Type, p.Address)
-		if p.Username != nil && *p.Username != "" && p.Password != nil {
+		username, password, hasAuth, err := Credentials(p)
+		if err != nil {
+			return nil, err
+		}
+		if hasAuth {
 			// Add auth to the URL for http/https proxies if user:pass@host:port format is not already in Address
 			// This depends on how p.Address is stored. If it's just host:port, construct full URL here.
 			// Assuming p.Address is host:port for now.
-			userInfo := url.UserPassword(*p.Username, *p.Password)
+			userInfo := url.UserPassword(username, password)
 			parsedProxyURL, err := url.Parse(proxyURLStr)
 			if err != nil {
 				return nil, fmt.Errorf("invalid base proxy URL %s: %w", proxyURLStr, err)
@@ -61,25 +224,38 @@ func (f *DefaultHTTPClientFactory) GetClient(p *database.Proxy) (*http.Client, e
 		switch p.Type {
 		case "http", "https":
 			transport.Proxy = http.ProxyURL(proxyURL)
-		case "socks5":
-			dialer, err := proxy.FromURL(proxyURL, proxy.Direct) // proxy.Direct is the forward dialer
+		case "socks5", "socks5h":
+			socksDialer, err := proxy.FromURL(proxyURL, dialer) // dialer reaches the SOCKS5 server itself
 			if err != nil {
 				return nil, fmt.Errorf("failed to create SOCKS5 dialer from %s: %w", proxyURLStr, err)
 			}
 			// Ensure the dialer is an http.Dialer for transport.DialContext
-			contextDialer, ok := dialer.(proxy.ContextDialer)
+			contextDialer, ok := socksDialer.(proxy.ContextDialer)
 			if !ok {
 				return nil, fmt.Errorf("SOCKS5 dialer does not implement proxy.ContextDialer")
 			}
+			// The SOCKS5 dialer passes hostnames on for the proxy to resolve
+			// (socks5h); plain socks5 resolves them locally first.
 			transport.DialContext = contextDialer.DialContext
+			if p.Type == "socks5" {
+				transport.DialContext = resolveLocally(contextDialer.DialContext, f.resolver, f.network.IPFamily)
+			}
 			transport.Proxy = nil // SOCKS5 is handled by the custom dialer
 		default:
 			return nil, fmt.Errorf("unsupported proxy type: %s", p.Type)
 		}
 	}
 
+	var version string
+	if tlsSettings != nil {
+		version = tlsSettings.HTTPVersion
+	}
+	roundTripper := withHTTPVersion(transport, version, p != nil && p.Address != "")
+	if p != nil {
+		roundTripper = &meteredTransport{name: p.Name, base: roundTripper}
+	}
 	return &http.Client{
-		Transport: transport,
+		Transport: roundTripper,
 		Timeout:   60 * time.Second, // Overall request timeout
 	}, nil
 }
\ No newline at end of file
@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// HTTP versions accepted in TLSConfig.HTTPVersion. The empty value lets the
+// transport negotiate HTTP/2 and fall back to HTTP/1.1.
+const (
+	HTTPVersion1 = "1.1"
+	HTTPVersion2 = "2"
+	HTTPVersion3 = "3"
+)
+
+var validHTTPVersions = map[string]bool{"": true, HTTPVersion1: true, HTTPVersion2: true, HTTPVersion3: true}
+
+// http3RetryAfter is how long a host that failed over HTTP/3 is fetched over
+// HTTP/2 before QUIC is tried again.
+const http3RetryAfter = 10 * time.Minute
+
+// HTTP3TransportFunc builds an HTTP/3 round tripper using tlsConfig.
+type HTTP3TransportFunc func(tlsConfig *tls.Config) http.RoundTripper
+
+var (
+	http3Transport     HTTP3TransportFunc
+	http3MissingLogged sync.Once
+)
+
+// RegisterHTTP3Transport installs the HTTP/3 implementation used for
+// http_version "3". Builds with the quic tag register quic-go's; without one,
+// such clients use HTTP/2.
+func RegisterHTTP3Transport(fn HTTP3TransportFunc) {
+	http3Transport = fn
+}
+
+// withHTTPVersion restricts t to the requested HTTP version, wrapping it in an
+// HTTP/3 transport that falls back to t when version is "3".
+func withHTTPVersion(t *http.Transport, version string, proxied bool) http.RoundTripper {
+	switch version {
+	case HTTPVersion1:
+		t.ForceAttemptHTTP2 = false
+		t.Protocols = new(http.Protocols)
+		t.Protocols.SetHTTP1(true)
+		if t.TLSClientConfig != nil {
+			t.TLSClientConfig = t.TLSClientConfig.Clone()
+			t.TLSClientConfig.NextProtos = []string{"http/1.1"}
+		}
+	case HTTPVersion2:
+		t.Protocols = new(http.Protocols)
+		t.Protocols.SetHTTP2(true)
+	case HTTPVersion3:
+		if proxied {
+			// QUIC runs over UDP, which HTTP and SOCKS5 proxies don't carry.
+			log.Debug().Msg("HTTP/3 requested through a proxy; using HTTP/2")
+			return t
+		}
+		if http3Transport == nil {
+			http3MissingLogged.Do(func() {
+				log.Warn().Msg("HTTP/3 requested but this build has no QUIC support (build with -tags quic); using HTTP/2")
+			})
+			return t
+		}
+		tlsConfig := t.TLSClientConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		return &http3FallbackTransport{h3: http3Transport(tlsConfig.Clone()), fallback: t, broken: make(map[string]time.Time)}
+	}
+	return t
+}
+
+// http3FallbackTransport sends requests over HTTP/3 and retries them over the
+// fallback transport when QUIC fails, e.g. because UDP is blocked or the host
+// doesn't speak HTTP/3. Hosts that failed skip HTTP/3 for http3RetryAfter.
+type http3FallbackTransport struct {
+	h3       http.RoundTripper
+	fallback http.RoundTripper
+	mu       sync.Mutex
+	broken   map[string]time.Time // Host -> when to try HTTP/3 again
+}
+
+func (t *http3FallbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" || t.isBroken(req.URL.Host) {
+		return t.fallback.RoundTrip(req)
+	}
+	resp, err := t.h3.RoundTrip(req)
+	if err == nil || req.Context().Err() != nil {
+		return resp, err
+	}
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, err // The body was consumed and can't be replayed
+		}
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	log.Debug().Err(err).Str("host", req.URL.Host).Msg("HTTP/3 request failed; falling back to HTTP/2")
+	t.mu.Lock()
+	t.broken[req.URL.Host] = time.Now().Add(http3RetryAfter)
+	t.mu.Unlock()
+	return t.fallback.RoundTrip(retry)
+}
+
+func (t *http3FallbackTransport) isBroken(host string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	until, ok := t.broken[host]
+	if ok && time.Now().After(until) {
+		delete(t.broken, host)
+		return false
+	}
+	return ok
+}
+
+// CloseIdleConnections closes idle connections of both transports.
+func (t *http3FallbackTransport) CloseIdleConnections() {
+	type closeIdler interface{ CloseIdleConnections() }
+	if c, ok := t.h3.(closeIdler); ok {
+		c.CloseIdleConnections()
+	}
+	if c, ok := t.fallback.(closeIdler); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingRoundTripper struct{ calls int }
+
+func (f *failingRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	f.calls++
+	return nil, errors.New("quic: no recent network activity")
+}
+
+func TestWithHTTPVersion(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Proto)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	proto := func(rt http.RoundTripper) string {
+		resp, err := (&http.Client{Transport: rt}).Get(srv.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.Proto
+	}
+	base := func() *http.Transport { return srv.Client().Transport.(*http.Transport).Clone() }
+
+	assert.Equal(t, "HTTP/2.0", proto(withHTTPVersion(base(), "", false)))
+	assert.Equal(t, "HTTP/1.1", proto(withHTTPVersion(base(), HTTPVersion1, false)))
+	assert.Equal(t, "HTTP/2.0", proto(withHTTPVersion(base(), HTTPVersion2, false)))
+
+	// Without QUIC support HTTP/3 degrades to the plain transport.
+	assert.Equal(t, "HTTP/2.0", proto(withHTTPVersion(base(), HTTPVersion3, false)))
+
+	h3 := &failingRoundTripper{}
+	RegisterHTTP3Transport(func(*tls.Config) http.RoundTripper { return h3 })
+	defer RegisterHTTP3Transport(nil)
+
+	_, isPlain := withHTTPVersion(base(), HTTPVersion3, true).(*http.Transport)
+	assert.True(t, isPlain, "proxied clients can't use QUIC")
+
+	rt := withHTTPVersion(base(), HTTPVersion3, false)
+	assert.Equal(t, "HTTP/2.0", proto(rt), "a failed HTTP/3 request is retried over HTTP/2")
+	assert.Equal(t, "HTTP/2.0", proto(rt))
+	assert.Equal(t, 1, h3.calls, "a host that failed over HTTP/3 skips it for a while")
+}
+
+func TestBuildTLSConfigHTTPVersion(t *testing.T) {
+	_, err := BuildTLSConfig(&database.TLSConfig{HTTPVersion: "3"})
+	assert.NoError(t, err)
+	_, err = BuildTLSConfig(&database.TLSConfig{HTTPVersion: "2.5"})
+	assert.Error(t, err)
+
+	merged := mergeTLS(&database.TLSConfig{HTTPVersion: "1.1"}, &database.TLSConfig{HTTPVersion: "3"})
+	assert.Equal(t, "3", merged.HTTPVersion)
+}
@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+)
+
+// proxySchemes maps the URL schemes accepted in proxy lists to proxy types.
+var proxySchemes = map[string]string{
+	"http":    "http",
+	"https":   "https",
+	"socks5":  "socks5",
+	"socks5h": "socks5h",
+	"socks":   "socks5",
+}
+
+// ParseProxyList reads one proxy per line in any of these forms:
+//
+//	host:port
+//	host:port:user:pass
+//	user:pass@host:port
+//	scheme://[user:pass@]host:port   (http, https, socks5, socks5h)
+//
+// Blank lines and lines starting with # are skipped; scheme-less entries get
+// defaultType. Subscription lists that are base64-encoded as a whole are
+// decoded first. Unparsable lines are reported as errors alongside the
+// proxies that did parse.
+func ParseProxyList(r io.Reader, defaultType string) ([]*database.Proxy, []error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if decoded, ok := decodeBase64List(raw); ok {
+		raw = decoded
+	}
+
+	var proxies []*database.Proxy
+	var errs []error
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := parseProxyLine(line, defaultType)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", lineNo, err))
+			continue
+		}
+		proxies = append(proxies, p)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return proxies, errs
+}
+
+func parseProxyLine(line, defaultType string) (*database.Proxy, error) {
+	if strings.Contains(line, "://") {
+		u, err := url.Parse(line)
+		if err != nil {
+			return nil, err
+		}
+		pType, ok := proxySchemes[strings.ToLower(u.Scheme)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+		}
+		p := &database.Proxy{Type: pType, Address: u.Host}
+		if u.User != nil {
+			pass, _ := u.User.Password()
+			setProxyAuth(p, u.User.Username(), pass)
+		}
+		return p, checkProxyAddress(p)
+	}
+
+	p := &database.Proxy{Type: defaultType}
+	if at := strings.LastIndex(line, "@"); at >= 0 {
+		user, pass, _ := strings.Cut(line[:at], ":")
+		setProxyAuth(p, user, pass)
+		p.Address = line[at+1:]
+		return p, checkProxyAddress(p)
+	}
+	if _, _, err := net.SplitHostPort(line); err == nil {
+		p.Address = line // host:port, including [IPv6]:port
+		return p, checkProxyAddress(p)
+	}
+	parts := strings.Split(line, ":")
+	switch len(parts) {
+	case 4:
+		p.Address = parts[0] + ":" + parts[1]
+		setProxyAuth(p, parts[2], parts[3])
+	default:
+		return nil, fmt.Errorf("expected host:port or host:port:user:pass, got %q", line)
+	}
+	return p, checkProxyAddress(p)
+}
+
+func setProxyAuth(p *database.Proxy, user, pass string) {
+	if user == "" {
+		return
+	}
+	p.Username, p.Password = &user, &pass
+}
+
+func checkProxyAddress(p *database.Proxy) error {
+	host, port, err := net.SplitHostPort(p.Address)
+	if err != nil || host == "" || port == "" {
+		return fmt.Errorf("invalid proxy address %q", p.Address)
+	}
+	return nil
+}
+
+// decodeBase64List decodes subscription bodies that are a base64-encoded
+// proxy list rather than plain lines.
+func decodeBase64List(raw []byte) ([]byte, bool) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.ContainsAny(trimmed, ":@#") {
+		return nil, false
+	}
+	compact := bytes.Join(bytes.Fields(trimmed), nil)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(string(compact)); err == nil && bytes.Contains(decoded, []byte(":")) {
+			return decoded, true
+		}
+	}
+	return nil, false
+}
+
+// ProxyKey identifies a proxy endpoint and login for deduplication.
+func ProxyKey(p *database.Proxy) string {
+	user := ""
+	if p.Username != nil {
+		user = *p.Username
+	}
+	return p.Type + "://" + user + "@" + strings.ToLower(p.Address)
+}
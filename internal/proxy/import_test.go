@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProxyList(t *testing.T) {
+	list := `# Provider export
+1.2.3.4:8080
+5.6.7.8:3128:alice:s3cret
+
+bob:pw@9.9.9.9:1080
+socks5h://carol:pw@proxy.example.com:1080
+https://10.0.0.1:443
+[2001:db8::1]:8080
+not a proxy
+ftp://1.1.1.1:21
+`
+	proxies, errs := ParseProxyList(strings.NewReader(list), "socks5")
+	assert.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "line 9")
+	require.Len(t, proxies, 6)
+
+	assert.Equal(t, "socks5", proxies[0].Type, "scheme-less entries get the default type")
+	assert.Equal(t, "1.2.3.4:8080", proxies[0].Address)
+	assert.Nil(t, proxies[0].Username)
+
+	assert.Equal(t, "5.6.7.8:3128", proxies[1].Address)
+	assert.Equal(t, "alice", *proxies[1].Username)
+	assert.Equal(t, "s3cret", *proxies[1].Password)
+
+	assert.Equal(t, "9.9.9.9:1080", proxies[2].Address)
+	assert.Equal(t, "bob", *proxies[2].Username)
+
+	assert.Equal(t, "socks5h", proxies[3].Type)
+	assert.Equal(t, "proxy.example.com:1080", proxies[3].Address)
+	assert.Equal(t, "carol", *proxies[3].Username)
+
+	assert.Equal(t, "https", proxies[4].Type)
+	assert.Equal(t, "[2001:db8::1]:8080", proxies[5].Address)
+
+	assert.NotEqual(t, ProxyKey(proxies[0]), ProxyKey(proxies[1]))
+}
+
+func TestParseProxyListBase64(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("http://1.2.3.4:8080\nsocks5://5.6.7.8:1080\n"))
+	// Subscriptions often wrap long base64 bodies.
+	wrapped := encoded[:10] + "\n" + encoded[10:]
+	proxies, errs := ParseProxyList(strings.NewReader(wrapped), "http")
+	assert.Empty(t, errs)
+	require.Len(t, proxies, 2)
+	assert.Equal(t, "socks5", proxies[1].Type)
+}
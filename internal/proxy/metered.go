@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/metrics"
+)
+
+// meteredTransport records per-proxy request, latency and traffic metrics for
+// requests sent through the proxy named name.
+type meteredTransport struct {
+	name string
+	base http.RoundTripper
+}
+
+func (t *meteredTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.ContentLength > 0 {
+		metrics.ProxyBytes.WithLabelValues(t.name, "sent").Add(float64(req.ContentLength))
+	}
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		metrics.ProxyRequests.WithLabelValues(t.name, "error").Inc()
+		return nil, err
+	}
+	metrics.ProxyRequests.WithLabelValues(t.name, "ok").Inc()
+	metrics.ProxyRequestDuration.WithLabelValues(t.name).Observe(time.Since(start).Seconds())
+	resp.Body = &countingBody{ReadCloser: resp.Body, proxy: t.name}
+	return resp, nil
+}
+
+// CloseIdleConnections closes idle connections of the wrapped transport.
+func (t *meteredTransport) CloseIdleConnections() {
+	type closeIdler interface{ CloseIdleConnections() }
+	if c, ok := t.base.(closeIdler); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// countingBody adds the bytes read from a response body to the proxy's
+// received traffic.
+type countingBody struct {
+	io.ReadCloser
+	proxy string
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		metrics.ProxyBytes.WithLabelValues(b.proxy, "received").Add(float64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// proxyTransport returns the *http.Transport underneath a proxied client.
+func proxyTransport(t *testing.T, client *http.Client) *http.Transport {
+	t.Helper()
+	metered, ok := client.Transport.(*meteredTransport)
+	require.True(t, ok, "proxied clients are metered")
+	transport, ok := metered.base.(*http.Transport)
+	require.True(t, ok)
+	return transport
+}
+
+func TestMeteredTransport(t *testing.T) {
+	// Plain HTTP proxies receive the absolute URL and answer for the origin.
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		io.WriteString(w, "hello")
+	}))
+	defer proxySrv.Close()
+
+	p := &database.Proxy{Name: "metered-test", Type: "http", Address: strings.TrimPrefix(proxySrv.URL, "http://")}
+	client, err := NewHTTPClientFactory().GetClient(p)
+	require.NoError(t, err)
+
+	resp, err := client.Post("http://feeds.example/rss", "text/plain", strings.NewReader("ping"))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ProxyRequests.WithLabelValues("metered-test", "ok")))
+	assert.Equal(t, 4.0, testutil.ToFloat64(metrics.ProxyBytes.WithLabelValues("metered-test", "sent")))
+	assert.Equal(t, 5.0, testutil.ToFloat64(metrics.ProxyBytes.WithLabelValues("metered-test", "received")))
+	assert.GreaterOrEqual(t, testutil.CollectAndCount(metrics.ProxyRequestDuration), 1)
+
+	proxySrv.Close()
+	_, err = client.Get("http://feeds.example/rss")
+	assert.Error(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ProxyRequests.WithLabelValues("metered-test", "error")))
+}
@@ -0,0 +1,191 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+)
+
+// IP families accepted by NewNetworkOptions.
+const (
+	IPFamilyAny        = ""
+	IPFamilyIPv4       = "ipv4"        // Only dial IPv4 addresses
+	IPFamilyIPv6       = "ipv6"        // Only dial IPv6 addresses
+	IPFamilyPreferIPv4 = "prefer-ipv4" // Try IPv4 first, then IPv6
+	IPFamilyPreferIPv6 = "prefer-ipv6" // Try IPv6 first, then IPv4
+)
+
+// NetworkOptions controls how outbound connections are dialed.
+type NetworkOptions struct {
+	IPFamily  string       // One of the IPFamily constants
+	LocalAddr *net.TCPAddr // Source address to bind to; nil lets the OS choose
+}
+
+// NewNetworkOptions validates an IP family and resolves the local address to
+// bind to: bindAddress if set, else the address of the named interface that
+// suits the family (IPv4 unless IPv6 is required or preferred). Binding to an
+// address limits connections to that address's family.
+func NewNetworkOptions(family, bindAddress, iface string) (NetworkOptions, error) {
+	opts := NetworkOptions{IPFamily: family}
+	switch family {
+	case IPFamilyAny, IPFamilyIPv4, IPFamilyIPv6, IPFamilyPreferIPv4, IPFamilyPreferIPv6:
+	default:
+		return opts, fmt.Errorf("invalid IP family %q (expected %q, %q, %q or %q)", family, IPFamilyIPv4, IPFamilyIPv6, IPFamilyPreferIPv4, IPFamilyPreferIPv6)
+	}
+
+	switch {
+	case bindAddress != "" && iface != "":
+		return opts, fmt.Errorf("set either a bind address or an interface, not both")
+	case bindAddress != "":
+		ip := net.ParseIP(bindAddress)
+		if ip == nil {
+			return opts, fmt.Errorf("invalid bind address %q: not an IP address", bindAddress)
+		}
+		if (family == IPFamilyIPv4 && ip.To4() == nil) || (family == IPFamilyIPv6 && ip.To4() != nil) {
+			return opts, fmt.Errorf("bind address %s does not match IP family %q", bindAddress, family)
+		}
+		opts.LocalAddr = &net.TCPAddr{IP: ip}
+	case iface != "":
+		ip, err := interfaceAddress(iface, family == IPFamilyIPv6 || family == IPFamilyPreferIPv6)
+		if err != nil {
+			return opts, err
+		}
+		if family == IPFamilyIPv6 && ip.To4() != nil {
+			return opts, fmt.Errorf("interface %s has no IPv6 address", iface)
+		}
+		if family == IPFamilyIPv4 && ip.To4() == nil {
+			return opts, fmt.Errorf("interface %s has no IPv4 address", iface)
+		}
+		opts.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+	return opts, nil
+}
+
+// interfaceAddress returns an address of the named interface, preferring the
+// requested family and, for IPv6, a global address over a link-local one.
+func interfaceAddress(name string, wantIPv6 bool) (net.IP, error) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("network interface %q: %w", name, err)
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("listing addresses of interface %s: %w", name, err)
+	}
+	var fallback net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if (ipNet.IP.To4() == nil) == wantIPv6 {
+			return ipNet.IP, nil
+		}
+		if fallback == nil {
+			fallback = ipNet.IP
+		}
+	}
+	if fallback == nil {
+		return nil, fmt.Errorf("interface %s has no usable address", name)
+	}
+	return fallback, nil
+}
+
+// networkDialer dials TCP restricted to, or preferring, one IP family.
+type networkDialer struct {
+	dialer net.Dialer
+	family string
+}
+
+func newNetworkDialer(opts NetworkOptions, resolver *net.Resolver) *networkDialer {
+	d := &networkDialer{
+		dialer: net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Resolver: resolver},
+		family: opts.IPFamily,
+	}
+	if opts.LocalAddr != nil {
+		d.dialer.LocalAddr = opts.LocalAddr
+	}
+	return d
+}
+
+// DialContext connects to addr according to the dialer's IP family.
+func (d *networkDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+	switch d.family {
+	case IPFamilyIPv4:
+		return d.dialer.DialContext(ctx, "tcp4", addr)
+	case IPFamilyIPv6:
+		return d.dialer.DialContext(ctx, "tcp6", addr)
+	case IPFamilyPreferIPv4:
+		return d.dialPreferring(ctx, "tcp4", "tcp6", addr)
+	case IPFamilyPreferIPv6:
+		return d.dialPreferring(ctx, "tcp6", "tcp4", addr)
+	}
+	return d.dialer.DialContext(ctx, network, addr)
+}
+
+// Dial implements proxy.Dialer for SOCKS5 forward connections.
+func (d *networkDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d *networkDialer) dialPreferring(ctx context.Context, preferred, fallback, addr string) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, preferred, addr)
+	if err == nil || ctx.Err() != nil {
+		return conn, err
+	}
+	if conn, errFallback := d.dialer.DialContext(ctx, fallback, addr); errFallback == nil {
+		return conn, nil
+	}
+	return nil, err
+}
+
+// dialFunc dials addr over network, e.g. through a SOCKS5 proxy.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// resolveLocally wraps dial so hostnames are resolved here, with resolver and
+// in family's order, and the proxy only ever sees IP addresses. This is the
+// socks5 behaviour; socks5h hands hostnames to the proxy instead.
+func resolveLocally(dial dialFunc, resolver *net.Resolver, family string) dialFunc {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+		lookup := "ip"
+		switch family {
+		case IPFamilyIPv4:
+			lookup = "ip4"
+		case IPFamilyIPv6:
+			lookup = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, lookup, host)
+		if err != nil {
+			return nil, err
+		}
+		if family == IPFamilyPreferIPv4 || family == IPFamilyPreferIPv6 {
+			wantV4 := family == IPFamilyPreferIPv4
+			sort.SliceStable(ips, func(i, j int) bool {
+				return (ips[i].To4() != nil) == wantV4 && (ips[j].To4() != nil) != wantV4
+			})
+		}
+		err = fmt.Errorf("no addresses found for %s", host)
+		for _, ip := range ips {
+			var conn net.Conn
+			if conn, err = dial(ctx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+				return conn, nil
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, err
+	}
+}
@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNetworkOptions(t *testing.T) {
+	opts, err := NewNetworkOptions(IPFamilyIPv4, "127.0.0.1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", opts.LocalAddr.IP.String())
+
+	_, err = NewNetworkOptions("ipv5", "", "")
+	assert.Error(t, err)
+	_, err = NewNetworkOptions(IPFamilyIPv6, "127.0.0.1", "")
+	assert.ErrorContains(t, err, "does not match")
+	_, err = NewNetworkOptions("", "127.0.0.1", "lo")
+	assert.Error(t, err)
+	_, err = NewNetworkOptions("", "", "no-such-interface0")
+	assert.Error(t, err)
+
+	loopback := loopbackInterface(t)
+	opts, err = NewNetworkOptions(IPFamilyIPv4, "", loopback)
+	require.NoError(t, err)
+	assert.True(t, opts.LocalAddr.IP.IsLoopback())
+}
+
+func loopbackInterface(t *testing.T) string {
+	ifaces, err := net.Interfaces()
+	require.NoError(t, err)
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagLoopback != 0 {
+			return ifi.Name
+		}
+	}
+	t.Skip("no loopback interface")
+	return ""
+}
+
+func TestNetworkDialerFamily(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	addr := srv.Listener.Addr().String() // An IPv4 loopback address
+
+	dial := func(family string) error {
+		conn, err := newNetworkDialer(NetworkOptions{IPFamily: family}, nil).DialContext(context.Background(), "tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+	assert.NoError(t, dial(IPFamilyIPv4))
+	assert.Error(t, dial(IPFamilyIPv6))
+	assert.NoError(t, dial(IPFamilyPreferIPv6), "falls back to IPv4")
+
+	client, err := NewHTTPClientFactory().WithNetwork(NetworkOptions{IPFamily: IPFamilyIPv6}).GetClient(nil)
+	require.NoError(t, err)
+	_, err = client.Get(srv.URL)
+	assert.Error(t, err, "factory clients honor the IP family")
+}
+
+func TestResolveLocally(t *testing.T) {
+	var dialed []string
+	dial := resolveLocally(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}, nil, IPFamilyIPv4)
+
+	conn, err := dial(context.Background(), "tcp", "localhost:1080")
+	require.NoError(t, err)
+	conn.Close()
+	conn, err = dial(context.Background(), "tcp", "192.0.2.1:80")
+	require.NoError(t, err)
+	conn.Close()
+
+	assert.Equal(t, []string{"127.0.0.1:1080", "192.0.2.1:80"}, dialed, "socks5 proxies only see IP addresses")
+}
@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// WithNoProxy exempts hosts from the default proxies, with NO_PROXY syntax:
+// host names (matching subdomains too), ".domain", IP addresses, CIDR ranges
+// such as 192.168.0.0/16, optional ":port" suffixes, or "*" for everything.
+// Requests to them connect directly. Loopback addresses are always exempt
+// once a list is set.
+func (f *DefaultHTTPClientFactory) WithNoProxy(hosts []string) *DefaultHTTPClientFactory {
+	var entries []string
+	for _, h := range hosts {
+		if h = strings.TrimSpace(h); h != "" {
+			entries = append(entries, h)
+		}
+	}
+	if len(entries) == 0 {
+		f.noProxy = nil
+		return f
+	}
+	// httpproxy only answers "which proxy", so ask it about a placeholder one.
+	placeholder := "http://proxy.invalid"
+	proxyFor := (&httpproxy.Config{HTTPProxy: placeholder, HTTPSProxy: placeholder, NoProxy: strings.Join(entries, ",")}).ProxyFunc()
+	f.noProxy = func(u *url.URL) bool {
+		p, err := proxyFor(u)
+		return err == nil && p == nil
+	}
+	return f
+}
+
+// NoProxyFromEnv returns the hosts listed in the NO_PROXY (or no_proxy)
+// environment variable.
+func NoProxyFromEnv() []string {
+	value := os.Getenv("NO_PROXY")
+	if value == "" {
+		value = os.Getenv("no_proxy")
+	}
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// bypassTransport sends requests for hosts exempt from proxying through
+// direct and all others through proxied.
+type bypassTransport struct {
+	exempt  func(*url.URL) bool
+	proxied http.RoundTripper
+	direct  http.RoundTripper
+}
+
+func (t *bypassTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.exempt(req.URL) {
+		return t.direct.RoundTrip(req)
+	}
+	return t.proxied.RoundTrip(req)
+}
+
+// CloseIdleConnections closes idle connections of both transports.
+func (t *bypassTransport) CloseIdleConnections() {
+	type closeIdler interface{ CloseIdleConnections() }
+	if c, ok := t.proxied.(closeIdler); ok {
+		c.CloseIdleConnections()
+	}
+	if c, ok := t.direct.(closeIdler); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoProxyMatching(t *testing.T) {
+	factory := NewHTTPClientFactory().WithNoProxy([]string{" intranet.example ", ".lan", "10.0.0.0/8", "", "feeds.test:8080"})
+	for _, tc := range []struct {
+		rawURL string
+		want   bool
+	}{
+		{"http://intranet.example/rss", true},
+		{"https://news.intranet.example/rss", true},
+		{"http://nas.lan/feed", true},
+		{"http://lan/feed", false},
+		{"http://10.1.2.3/feed", true},
+		{"http://192.168.1.1/feed", false},
+		{"http://feeds.test:8080/a", true},
+		{"http://feeds.test/a", false},
+		{"http://localhost/feed", true},
+		{"https://example.com/feed", false},
+	} {
+		u, err := url.Parse(tc.rawURL)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, factory.noProxy(u), tc.rawURL)
+	}
+
+	assert.Nil(t, NewHTTPClientFactory().WithNoProxy([]string{" ", ""}).noProxy)
+}
+
+func TestNoProxyBypassesDefaultProxies(t *testing.T) {
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "proxied")
+	}))
+	defer proxySrv.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "direct")
+	}))
+	defer origin.Close()
+
+	def := &database.Proxy{ID: 1, Name: "default", Type: "http", Address: strings.TrimPrefix(proxySrv.URL, "http://"), IsDefaultForRSS: true}
+	get := func(factory *DefaultHTTPClientFactory, p *database.Proxy) string {
+		client, err := factory.GetClient(p)
+		require.NoError(t, err)
+		resp, err := client.Get(origin.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	assert.Equal(t, "proxied", get(NewHTTPClientFactory(), def))
+
+	// The origin listens on loopback, which any no-proxy list exempts.
+	factory := NewHTTPClientFactory().WithNoProxy([]string{"intranet.example"})
+	assert.Equal(t, "direct", get(factory, def))
+
+	own := *def
+	own.IsDefaultForRSS = false
+	assert.Equal(t, "proxied", get(factory, &own), "a feed's own proxy ignores the no-proxy list")
+}
@@ -0,0 +1,253 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/rs/zerolog/log"
+)
+
+// pacRefresh is how long a downloaded PAC file is used before it is fetched
+// again. A failed refresh keeps the previous copy.
+const pacRefresh = time.Hour
+
+// maxPACSize bounds a PAC file download.
+const maxPACSize = 1 << 20
+
+// PACEvaluator runs FindProxyForURL(rawURL, host) from a PAC script, with
+// helpers (isInNet, shExpMatch, ...) defined as globals, and returns its
+// result, e.g. "PROXY proxy.corp:8080; DIRECT".
+type PACEvaluator func(script string, helpers map[string]any, rawURL, host string) (string, error)
+
+var pacEvaluator PACEvaluator
+
+// RegisterPACEvaluator installs the JavaScript engine that runs PAC files.
+// Builds with the pac tag register one; without it, clients for pac proxies
+// can't be built.
+func RegisterPACEvaluator(fn PACEvaluator) {
+	pacEvaluator = fn
+}
+
+// PACSupported reports whether this build can evaluate PAC files.
+func PACSupported() bool {
+	return pacEvaluator != nil
+}
+
+// pacScript is a downloaded PAC file.
+type pacScript struct {
+	body      string
+	fetchedAt time.Time
+}
+
+// pacProxyFunc returns a transport Proxy function that picks DIRECT or a proxy
+// per request by evaluating the PAC file at p.Address. The credentials of p
+// are used for the proxies it selects.
+func (f *DefaultHTTPClientFactory) pacProxyFunc(p *database.Proxy) (func(*http.Request) (*url.URL, error), error) {
+	if pacEvaluator == nil {
+		return nil, fmt.Errorf("proxy %s: PAC files need a build with -tags pac", p.Name)
+	}
+	username, password, hasAuth, err := Credentials(p)
+	if err != nil {
+		return nil, err
+	}
+	helpers := pacHelpers(f.resolver)
+	return func(req *http.Request) (*url.URL, error) {
+		script, err := f.loadPAC(req.Context(), p.Address)
+		if err != nil {
+			return nil, err
+		}
+		// Like browsers, only show the script the origin of HTTPS URLs.
+		target := *req.URL
+		target.User = nil
+		if target.Scheme == "https" {
+			target.Path, target.RawPath, target.RawQuery, target.Fragment = "/", "", "", ""
+		}
+		result, err := pacEvaluator(script, helpers, target.String(), req.URL.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("evaluating PAC file %s: %w", p.Address, err)
+		}
+		proxyURL, err := parsePACResult(result)
+		if proxyURL != nil && hasAuth {
+			proxyURL.User = url.UserPassword(username, password)
+		}
+		return proxyURL, err
+	}, nil
+}
+
+// loadPAC returns the PAC file at pacURL, downloading it when it isn't cached
+// or is older than pacRefresh.
+func (f *DefaultHTTPClientFactory) loadPAC(ctx context.Context, pacURL string) (string, error) {
+	f.pacMu.Lock()
+	cached := f.pacScripts[pacURL]
+	f.pacMu.Unlock()
+	if cached != nil && time.Since(cached.fetchedAt) < pacRefresh {
+		return cached.body, nil
+	}
+
+	body, err := f.fetchPAC(ctx, pacURL)
+	if err != nil {
+		if cached != nil {
+			log.Warn().Err(err).Str("pac_url", pacURL).Msg("Failed to refresh PAC file; using the previous copy")
+			return cached.body, nil
+		}
+		return "", fmt.Errorf("loading PAC file %s: %w", pacURL, err)
+	}
+	f.pacMu.Lock()
+	if f.pacScripts == nil {
+		f.pacScripts = make(map[string]*pacScript)
+	}
+	f.pacScripts[pacURL] = &pacScript{body: body, fetchedAt: time.Now()}
+	f.pacMu.Unlock()
+	return body, nil
+}
+
+// fetchPAC downloads a PAC file over a direct connection, or reads it from
+// disk for file:// URLs.
+func (f *DefaultHTTPClientFactory) fetchPAC(ctx context.Context, pacURL string) (string, error) {
+	u, err := url.Parse(pacURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "file" {
+		data, err := os.ReadFile(u.Path)
+		return string(data), err
+	}
+	client, err := f.buildClient(nil, nil)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pacURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPACSize))
+	return string(data), err
+}
+
+// parsePACResult returns the first proxy of a FindProxyForURL result that this
+// client can use, or nil for DIRECT.
+func parsePACResult(result string) (*url.URL, error) {
+	if strings.TrimSpace(result) == "" {
+		return nil, nil // Scripts returning nothing mean DIRECT
+	}
+	for _, entry := range strings.Split(result, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		var scheme string
+		switch strings.ToUpper(fields[0]) {
+		case "DIRECT":
+			return nil, nil
+		case "PROXY", "HTTP":
+			scheme = "http"
+		case "HTTPS":
+			scheme = "https"
+		case "SOCKS", "SOCKS5":
+			scheme = "socks5"
+		default:
+			continue // e.g. SOCKS4, which the transport doesn't speak
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		return &url.URL{Scheme: scheme, Host: fields[1]}, nil
+	}
+	return nil, fmt.Errorf("no usable proxy in PAC result %q", result)
+}
+
+// pacHelpers returns the standard PAC functions, resolving hostnames with
+// resolver (nil for the system resolver).
+func pacHelpers(resolver *net.Resolver) map[string]any {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	resolve := func(host string) net.IP {
+		if ip := net.ParseIP(host); ip != nil {
+			return ip
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ips, err := resolver.LookupIP(ctx, "ip4", host)
+		if err != nil || len(ips) == 0 {
+			return nil
+		}
+		return ips[0]
+	}
+	var shExpCache sync.Map // Pattern -> *regexp.Regexp
+
+	return map[string]any{
+		"isPlainHostName": func(host string) bool {
+			return !strings.Contains(host, ".")
+		},
+		"dnsDomainIs": func(host, domain string) bool {
+			return strings.HasSuffix(strings.ToLower(host), strings.ToLower(domain))
+		},
+		"localHostOrDomainIs": func(host, hostdom string) bool {
+			host, hostdom = strings.ToLower(host), strings.ToLower(hostdom)
+			return host == hostdom || (!strings.Contains(host, ".") && strings.HasPrefix(hostdom, host+"."))
+		},
+		"isResolvable": func(host string) bool {
+			return resolve(host) != nil
+		},
+		"dnsResolve": func(host string) string {
+			if ip := resolve(host); ip != nil {
+				return ip.String()
+			}
+			return ""
+		},
+		"isInNet": func(host, pattern, mask string) bool {
+			ip, network, m := resolve(host), net.ParseIP(pattern), net.ParseIP(mask)
+			if ip == nil || network == nil || m == nil || ip.To4() == nil || network.To4() == nil || m.To4() == nil {
+				return false
+			}
+			ipMask := net.IPMask(m.To4())
+			return ip.To4().Mask(ipMask).Equal(network.To4().Mask(ipMask))
+		},
+		"myIpAddress": func() string {
+			addrs, err := net.InterfaceAddrs()
+			if err == nil {
+				for _, addr := range addrs {
+					if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+						return ipNet.IP.String()
+					}
+				}
+			}
+			return "127.0.0.1"
+		},
+		"dnsDomainLevels": func(host string) int {
+			return strings.Count(host, ".")
+		},
+		"shExpMatch": func(str, shexp string) bool {
+			re, ok := shExpCache.Load(shexp)
+			if !ok {
+				quoted := regexp.QuoteMeta(shexp)
+				quoted = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(quoted)
+				compiled, err := regexp.Compile("^" + quoted + "$")
+				if err != nil {
+					return false
+				}
+				re, _ = shExpCache.LoadOrStore(shexp, compiled)
+			}
+			return re.(*regexp.Regexp).MatchString(str)
+		},
+	}
+}
@@ -0,0 +1,47 @@
+//go:build pac
+
+package proxy
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// Building with -tags pac enables the pac proxy type.
+func init() {
+	var programs sync.Map // Script -> *goja.Program
+	RegisterPACEvaluator(func(script string, helpers map[string]any, rawURL, host string) (string, error) {
+		program, ok := programs.Load(script)
+		if !ok {
+			compiled, err := goja.Compile("proxy.pac", script, false)
+			if err != nil {
+				return "", err
+			}
+			program, _ = programs.LoadOrStore(script, compiled)
+		}
+		// Runtimes aren't safe for concurrent use, so each call gets its own.
+		vm := goja.New()
+		for name, fn := range helpers {
+			if err := vm.Set(name, fn); err != nil {
+				return "", err
+			}
+		}
+		if _, err := vm.RunProgram(program.(*goja.Program)); err != nil {
+			return "", err
+		}
+		findProxy, ok := goja.AssertFunction(vm.Get("FindProxyForURL"))
+		if !ok {
+			return "", fmt.Errorf("PAC file defines no FindProxyForURL function")
+		}
+		result, err := findProxy(goja.Undefined(), vm.ToValue(rawURL), vm.ToValue(host))
+		if err != nil {
+			return "", err
+		}
+		if goja.IsNull(result) || goja.IsUndefined(result) {
+			return "", nil
+		}
+		return result.String(), nil
+	})
+}
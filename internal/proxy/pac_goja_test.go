@@ -0,0 +1,29 @@
+//go:build pac
+
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGojaPACEvaluator(t *testing.T) {
+	require.True(t, PACSupported())
+	script := `function FindProxyForURL(url, host) {
+		if (dnsDomainIs(host, ".corp")) return "DIRECT";
+		return "PROXY proxy.corp:3128";
+	}`
+	helpers := pacHelpers(nil)
+
+	result, err := pacEvaluator(script, helpers, "http://wiki.corp/", "wiki.corp")
+	require.NoError(t, err)
+	assert.Equal(t, "DIRECT", result)
+	result, err = pacEvaluator(script, helpers, "https://example.com/", "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "PROXY proxy.corp:3128", result)
+
+	_, err = pacEvaluator(`var x = 1;`, helpers, "https://example.com/", "example.com")
+	assert.Error(t, err)
+}
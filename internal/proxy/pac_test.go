@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePACResult(t *testing.T) {
+	for result, want := range map[string]string{
+		"DIRECT":                         "",
+		"":                               "",
+		"PROXY proxy.corp:8080; DIRECT":  "http://proxy.corp:8080",
+		"SOCKS4 old:1080; HTTPS sec:443": "https://sec:443",
+		"  socks5 10.0.0.1:1080 ":        "socks5://10.0.0.1:1080",
+	} {
+		u, err := parsePACResult(result)
+		require.NoError(t, err, result)
+		got := ""
+		if u != nil {
+			got = u.String()
+		}
+		assert.Equal(t, want, got, result)
+	}
+	_, err := parsePACResult("SOCKS4 old:1080")
+	assert.Error(t, err)
+}
+
+func TestPACHelpers(t *testing.T) {
+	h := pacHelpers(nil)
+	assert.True(t, h["shExpMatch"].(func(string, string) bool)("http://intranet.corp/x", "*.corp/*"))
+	assert.False(t, h["shExpMatch"].(func(string, string) bool)("http://example.com/", "*.corp/*"))
+	assert.True(t, h["dnsDomainIs"].(func(string, string) bool)("www.Corp.example", ".corp.example"))
+	assert.True(t, h["isPlainHostName"].(func(string) bool)("intranet"))
+	assert.True(t, h["localHostOrDomainIs"].(func(string, string) bool)("www", "www.corp.example"))
+	assert.False(t, h["localHostOrDomainIs"].(func(string, string) bool)("www.other.example", "www.corp.example"))
+	assert.True(t, h["isInNet"].(func(string, string, string) bool)("10.1.2.3", "10.0.0.0", "255.0.0.0"))
+	assert.False(t, h["isInNet"].(func(string, string, string) bool)("192.168.1.1", "10.0.0.0", "255.0.0.0"))
+	assert.Equal(t, 2, h["dnsDomainLevels"].(func(string) int)("www.corp.example"))
+}
+
+func TestPACProxyFunc(t *testing.T) {
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		fmt.Fprint(w, "corp")
+	}))
+	defer srv.Close()
+
+	// A stand-in for the JavaScript engine: sends *.corp hosts direct.
+	defer RegisterPACEvaluator(pacEvaluator)
+	var seenURL string
+	RegisterPACEvaluator(func(script string, helpers map[string]any, rawURL, host string) (string, error) {
+		seenURL = rawURL
+		if helpers["dnsDomainIs"].(func(string, string) bool)(host, "."+script) {
+			return "DIRECT", nil
+		}
+		return "PROXY proxy.corp:3128; DIRECT", nil
+	})
+
+	user, pass := "alice", "pw"
+	p := &database.Proxy{Name: "corp", Type: "pac", Address: srv.URL + "/proxy.pac", Username: &user, Password: &pass}
+	client, err := NewHTTPClientFactory().GetClient(p)
+	require.NoError(t, err)
+	proxyFor := func(rawURL string) *url.URL {
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		require.NoError(t, err)
+		u, err := proxyTransport(t, client).Proxy(req)
+		require.NoError(t, err)
+		return u
+	}
+
+	assert.Nil(t, proxyFor("http://wiki.corp/page"))
+	u := proxyFor("https://example.com/feed.xml?token=x")
+	require.NotNil(t, u)
+	assert.Equal(t, "http://alice:pw@proxy.corp:3128", u.String())
+	assert.Equal(t, "https://example.com/", seenURL, "HTTPS paths are hidden from the script")
+	assert.Equal(t, 1, fetches, "the PAC file is cached")
+}
+
+func TestPACWithoutEvaluator(t *testing.T) {
+	defer RegisterPACEvaluator(pacEvaluator)
+	RegisterPACEvaluator(nil)
+	_, err := NewHTTPClientFactory().GetClient(&database.Proxy{Name: "corp", Type: "pac", Address: "http://wpad/wpad.dat"})
+	assert.Error(t, err)
+}
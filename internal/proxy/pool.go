@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+)
+
+// PoolProxy picks the proxy a feed's next fetch goes through from pool,
+// following the pool's strategy and skipping proxies that are down. It returns
+// nil when the pool has no healthy proxy.
+func (f *DefaultHTTPClientFactory) PoolProxy(pool *database.ProxyPool, feedID int64) *database.Proxy {
+	if pool == nil {
+		return nil
+	}
+	healthy := make([]*database.Proxy, 0, len(pool.Proxies))
+	for _, p := range pool.Proxies {
+		if f.ProxyHealthy(p) {
+			healthy = append(healthy, p)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	switch pool.Strategy {
+	case database.ProxyPoolRandom:
+		return healthy[rand.IntN(len(healthy))]
+	case database.ProxyPoolSticky:
+		return stickyProxy(healthy, feedID)
+	default:
+		f.poolCursorsMu.Lock()
+		defer f.poolCursorsMu.Unlock()
+		if f.poolCursors == nil {
+			f.poolCursors = make(map[int64]uint64)
+		}
+		// The cursor indexes the full member list so a proxy going down only
+		// shifts its own turns to the next healthy member.
+		cursor := f.poolCursors[pool.ID]
+		for i := uint64(0); ; i++ {
+			p := pool.Proxies[(cursor+i)%uint64(len(pool.Proxies))]
+			if f.ProxyHealthy(p) {
+				f.poolCursors[pool.ID] = cursor + i + 1
+				return p
+			}
+		}
+	}
+}
+
+// stickyProxy chooses a proxy for feedID by rendezvous hashing, so a feed only
+// moves to another proxy when its own proxy leaves the pool.
+func stickyProxy(proxies []*database.Proxy, feedID int64) *database.Proxy {
+	var best *database.Proxy
+	var bestScore uint64
+	for _, p := range proxies {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%d:%d", feedID, p.ID)
+		if score := h.Sum64(); best == nil || score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,45 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolProxy(t *testing.T) {
+	f := NewHTTPClientFactory()
+	proxies := []*database.Proxy{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	pool := &database.ProxyPool{ID: 1, Strategy: database.ProxyPoolRoundRobin, Proxies: proxies}
+	var ids []int64
+	for i := 0; i < 4; i++ {
+		ids = append(ids, f.PoolProxy(pool, 7).ID)
+	}
+	assert.Equal(t, []int64{1, 2, 3, 1}, ids)
+	other := &database.ProxyPool{ID: 2, Proxies: proxies}
+	assert.Equal(t, int64(1), f.PoolProxy(other, 7).ID, "each pool rotates independently")
+
+	random := &database.ProxyPool{ID: 3, Strategy: database.ProxyPoolRandom, Proxies: proxies}
+	for i := 0; i < 10; i++ {
+		assert.Contains(t, proxies, f.PoolProxy(random, 7))
+	}
+
+	sticky := &database.ProxyPool{ID: 4, Strategy: database.ProxyPoolSticky, Proxies: proxies}
+	chosen := f.PoolProxy(sticky, 42)
+	assert.Same(t, chosen, f.PoolProxy(sticky, 42))
+	var rest []*database.Proxy
+	for _, p := range proxies {
+		if p != chosen {
+			rest = append(rest, p)
+		}
+	}
+	shrunk := &database.ProxyPool{ID: 4, Strategy: database.ProxyPoolSticky, Proxies: append(rest, &database.Proxy{ID: 9})}
+	assert.NotSame(t, chosen, f.PoolProxy(shrunk, 42), "a feed moves when its proxy leaves")
+	grown := &database.ProxyPool{ID: 4, Strategy: database.ProxyPoolSticky, Proxies: append([]*database.Proxy{{ID: 10}}, proxies...)}
+	if got := f.PoolProxy(grown, 42); got.ID != 10 {
+		assert.Same(t, chosen, got, "a feed only moves to a newly added proxy")
+	}
+
+	assert.Nil(t, f.PoolProxy(&database.ProxyPool{}, 1))
+}
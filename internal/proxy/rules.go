@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+)
+
+// WithProxyRules routes requests of clients without a proxy of their own, or
+// with a default proxy, through the proxy of the first rule matching the
+// request's host. Rules are matched in the order given.
+func (f *DefaultHTTPClientFactory) WithProxyRules(rules []*database.ProxyRule) *DefaultHTTPClientFactory {
+	f.rules = rules
+	return f
+}
+
+// RuleProxy returns the proxy of the first rule matching host, or nil.
+func (f *DefaultHTTPClientFactory) RuleProxy(host string) *database.Proxy {
+	for _, rule := range f.rules {
+		if rule.Proxy != nil && MatchHostPattern(rule.Pattern, host) {
+			return rule.Proxy
+		}
+	}
+	return nil
+}
+
+// routesByRule reports whether clients for p are subject to proxy rules: a
+// feed's own proxy wins over them, a default proxy doesn't.
+func (f *DefaultHTTPClientFactory) routesByRule(p *database.Proxy) bool {
+	return len(f.rules) > 0 && (p == nil || p.IsDefaultForRSS || p.IsDefaultForTelegram)
+}
+
+// MatchHostPattern reports whether host matches a rule pattern. Matching is
+// case-insensitive, '*' matches any run of characters (dots included), and a
+// leading "*." also matches the bare domain, so "*.medium.com" covers
+// medium.com and all its subdomains.
+func MatchHostPattern(pattern, host string) bool {
+	pattern, host = strings.ToLower(pattern), strings.ToLower(strings.TrimSuffix(host, "."))
+	if rest, ok := strings.CutPrefix(pattern, "*."); ok && host == rest {
+		return true
+	}
+	return globMatch(pattern, host)
+}
+
+// globMatch matches s against pattern, where '*' matches any run of characters.
+func globMatch(pattern, s string) bool {
+	star, restart := -1, 0
+	p, i := 0, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, restart = p, i
+			p++
+		case p < len(pattern) && pattern[p] == s[i]:
+			p++
+			i++
+		case star >= 0:
+			restart++
+			p, i = star+1, restart
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// ruleTransport sends requests for hosts matching a proxy rule through the
+// rule's proxy and all others through fallback.
+type ruleTransport struct {
+	f        *DefaultHTTPClientFactory
+	fallback http.RoundTripper
+	tls      *database.TLSConfig // The client's TLS settings, layered over each rule proxy's
+	mu       sync.Mutex
+	proxied  map[int64]http.RoundTripper // Keyed by proxy ID
+}
+
+func (t *ruleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p := t.f.usableProxy(t.f.RuleProxy(req.URL.Hostname()))
+	if p == nil {
+		return t.fallback.RoundTrip(req)
+	}
+	rt, err := t.transportFor(p)
+	if err != nil {
+		return nil, err
+	}
+	return rt.RoundTrip(req)
+}
+
+func (t *ruleTransport) transportFor(p *database.Proxy) (http.RoundTripper, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rt, ok := t.proxied[p.ID]; ok {
+		return rt, nil
+	}
+	client, err := t.f.buildClient(p, mergeTLS(p.TLS, t.tls))
+	if err != nil {
+		return nil, err
+	}
+	if t.proxied == nil {
+		t.proxied = make(map[int64]http.RoundTripper)
+	}
+	t.proxied[p.ID] = client.Transport
+	return client.Transport, nil
+}
+
+// CloseIdleConnections closes idle connections of every transport.
+func (t *ruleTransport) CloseIdleConnections() {
+	type closeIdler interface{ CloseIdleConnections() }
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, rt := range t.proxied {
+		if c, ok := rt.(closeIdler); ok {
+			c.CloseIdleConnections()
+		}
+	}
+	if c, ok := t.fallback.(closeIdler); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchHostPattern(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, host string
+		want          bool
+	}{
+		{"*.medium.com", "blog.medium.com", true},
+		{"*.medium.com", "a.b.medium.com", true},
+		{"*.medium.com", "medium.com", true},
+		{"*.medium.com", "notmedium.com", false},
+		{"*.onion", "abcdef.ONION", true},
+		{"feeds.example.*", "feeds.example.org", true},
+		{"example.com", "www.example.com", false},
+		{"*", "anything", true},
+	} {
+		assert.Equal(t, tc.want, MatchHostPattern(tc.pattern, tc.host), "%s vs %s", tc.pattern, tc.host)
+	}
+}
+
+func TestProxyRulesRouting(t *testing.T) {
+	// Plain HTTP proxies that answer every request with their own name.
+	namedProxy := func(name string) (*httptest.Server, *database.Proxy) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name)
+		}))
+		return srv, &database.Proxy{ID: int64(len(name)), Name: name, Type: "http", Address: strings.TrimPrefix(srv.URL, "http://")}
+	}
+	usSrv, us := namedProxy("us-east")
+	defer usSrv.Close()
+	defSrv, def := namedProxy("default")
+	defer defSrv.Close()
+	def.IsDefaultForRSS = true
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "direct")
+	}))
+	defer origin.Close()
+
+	factory := NewHTTPClientFactory().WithProxyRules([]*database.ProxyRule{{Pattern: "*.medium.com", ProxyID: us.ID, Proxy: us}})
+	get := func(p *database.Proxy, rawURL string) string {
+		client, err := factory.GetClient(p)
+		require.NoError(t, err)
+		resp, err := client.Get(rawURL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	assert.Equal(t, "us-east", get(nil, "http://blog.medium.com/feed"))
+	assert.Equal(t, "direct", get(nil, origin.URL))
+	assert.Equal(t, "us-east", get(def, "http://blog.medium.com/feed"), "rules win over default proxies")
+	assert.Equal(t, "default", get(def, "http://example.com/feed"))
+
+	own := *def
+	own.IsDefaultForRSS = false
+	assert.Equal(t, "default", get(&own, "http://blog.medium.com/feed"), "a feed's own proxy wins over rules")
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+)
+
+// Prefixes of secret references accepted in proxy credentials.
+const (
+	secretEnvPrefix  = "env:"
+	secretFilePrefix = "file:"
+)
+
+// ResolveSecret returns value, or what it refers to: "env:NAME" is the value of
+// the environment variable NAME and "file:PATH" the contents of PATH without
+// trailing newlines. References keep credentials out of the database; they are
+// resolved each time a client is built, so rotated secrets are picked up.
+func ResolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretEnvPrefix):
+		name := strings.TrimPrefix(value, secretEnvPrefix)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, secretFilePrefix):
+		path := strings.TrimPrefix(value, secretFilePrefix)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return value, nil
+}
+
+// IsSecretRef reports whether value is an env: or file: reference rather than
+// a literal secret.
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, secretEnvPrefix) || strings.HasPrefix(value, secretFilePrefix)
+}
+
+// Credentials returns the username and password of p with secret references
+// resolved. ok is false when p has no credentials.
+func Credentials(p *database.Proxy) (username, password string, ok bool, err error) {
+	if p == nil || p.Username == nil || *p.Username == "" || p.Password == nil {
+		return "", "", false, nil
+	}
+	if username, err = ResolveSecret(*p.Username); err != nil {
+		return "", "", false, fmt.Errorf("proxy %s username: %w", p.Name, err)
+	}
+	if password, err = ResolveSecret(*p.Password); err != nil {
+		return "", "", false, fmt.Errorf("proxy %s password: %w", p.Name, err)
+	}
+	return username, password, true, nil
+}
@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("RSS_BOT_TEST_PROXY_PASS", "from-env")
+	path := filepath.Join(t.TempDir(), "pass")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+
+	for value, want := range map[string]string{
+		"literal":                     "literal",
+		"env:RSS_BOT_TEST_PROXY_PASS": "from-env",
+		"file:" + path:                "from-file",
+	} {
+		got, err := ResolveSecret(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	_, err := ResolveSecret("env:RSS_BOT_TEST_UNSET_VARIABLE")
+	assert.Error(t, err)
+	_, err = ResolveSecret("file:" + filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestClientResolvesCredentialReferences(t *testing.T) {
+	t.Setenv("RSS_BOT_TEST_PROXY_PASS", "s3cret")
+	user, pass := "alice", "env:RSS_BOT_TEST_PROXY_PASS"
+	p := &database.Proxy{Name: "p", Type: "http", Address: "127.0.0.1:3128", Username: &user, Password: &pass}
+
+	client, err := NewHTTPClientFactory().GetClient(p)
+	require.NoError(t, err)
+	proxyURL, err := proxyTransport(t, client).Proxy(&http.Request{URL: &url.URL{Scheme: "http", Host: "example.com"}})
+	require.NoError(t, err)
+	got, _ := proxyURL.User.Password()
+	assert.Equal(t, "s3cret", got)
+
+	pass = "env:RSS_BOT_TEST_UNSET_VARIABLE"
+	_, err = NewHTTPClientFactory().GetClient(p)
+	assert.Error(t, err, "unresolvable references fail client construction")
+}
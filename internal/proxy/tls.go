@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+)
+
+// tlsVersions maps the configured minimum versions to crypto/tls constants.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// BuildTLSConfig turns stored TLS settings into a tls.Config. It returns nil
+// for empty settings so the transport keeps Go's defaults.
+func BuildTLSConfig(c *database.TLSConfig) (*tls.Config, error) {
+	if c.IsZero() {
+		return nil, nil
+	}
+	cfg := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+
+	if c.MinVersion != "" {
+		v, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported minimum TLS version %q (use 1.0, 1.1, 1.2 or 1.3)", c.MinVersion)
+		}
+		cfg.MinVersion = v
+	}
+
+	if !validHTTPVersions[c.HTTPVersion] {
+		return nil, fmt.Errorf("unsupported HTTP version %q (use 1.1, 2 or 3)", c.HTTPVersion)
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if c.ClientCertFile != "" || c.ClientKeyFile != "" {
+		if c.ClientCertFile == "" || c.ClientKeyFile == "" {
+			return nil, fmt.Errorf("a client certificate needs both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(c.ClientCertFile, c.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// mergeTLS overlays the fields set in override onto base.
+func mergeTLS(base, override *database.TLSConfig) *database.TLSConfig {
+	if override.IsZero() {
+		return base
+	}
+	if base.IsZero() {
+		return override
+	}
+	merged := *base
+	if override.InsecureSkipVerify {
+		merged.InsecureSkipVerify = true
+	}
+	if override.CAFile != "" {
+		merged.CAFile = override.CAFile
+	}
+	if override.MinVersion != "" {
+		merged.MinVersion = override.MinVersion
+	}
+	if override.ClientCertFile != "" {
+		merged.ClientCertFile, merged.ClientKeyFile = override.ClientCertFile, override.ClientKeyFile
+	}
+	if override.HTTPVersion != "" {
+		merged.HTTPVersion = override.HTTPVersion
+	}
+	return &merged
+}
@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetClientForFeed_TLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+
+	factory := NewHTTPClientFactory()
+	get := func(proxyTLS, feedTLS *database.TLSConfig) error {
+		var p *database.Proxy
+		if proxyTLS != nil {
+			p = &database.Proxy{TLS: proxyTLS}
+		}
+		client, err := factory.GetClientForFeed(p, 0, feedTLS)
+		require.NoError(t, err)
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	assert.Error(t, get(nil, nil), "private CA is not trusted by default")
+	assert.NoError(t, get(nil, &database.TLSConfig{CAFile: caFile}))
+	assert.NoError(t, get(&database.TLSConfig{CAFile: caFile}, nil), "proxy settings apply to its feeds")
+	assert.NoError(t, get(nil, &database.TLSConfig{InsecureSkipVerify: true}))
+}
+
+func TestMergeTLS(t *testing.T) {
+	merged := mergeTLS(&database.TLSConfig{CAFile: "proxy.pem", MinVersion: "1.2"}, &database.TLSConfig{MinVersion: "1.3"})
+	assert.Equal(t, &database.TLSConfig{CAFile: "proxy.pem", MinVersion: "1.3"}, merged)
+	assert.Nil(t, mergeTLS(nil, &database.TLSConfig{}))
+}
+
+func TestBuildTLSConfig_Invalid(t *testing.T) {
+	_, err := BuildTLSConfig(&database.TLSConfig{MinVersion: "1.4"})
+	assert.Error(t, err)
+	_, err = BuildTLSConfig(&database.TLSConfig{ClientCertFile: "cert.pem"})
+	assert.Error(t, err)
+	cfg, err := BuildTLSConfig(&database.TLSConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, cfg)
+}
@@ -52,7 +52,7 @@ func (v *DefaultProxyValidator) Validate(ctx context.Context, p *database.Proxy,
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		log.Info().Str("proxy_name", p.Name).Str("proxy_address", p.Address).Int("status_code", resp.StatusCode).Msg("Proxy validation successful")
+		log.Debug().Str("proxy_name", p.Name).Str("proxy_address", p.Address).Int("status_code", resp.StatusCode).Msg("Proxy validation successful")
 		return nil
 	}
 
@@ -0,0 +1,203 @@
+package rss
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/mmcdole/gofeed"
+)
+
+// SourceAdapter lets a feed URL with a custom scheme, such as youtube://UC...,
+// stand for a service that has no usable feed of its own. The adapter maps it
+// to the page or API to fetch and builds the feed from the response.
+type SourceAdapter interface {
+	// Resolve returns the HTTP(S) URL to fetch for the adapter URL src.
+	Resolve(src *url.URL) (string, error)
+	// Accept is the Accept header to fetch the resolved URL with.
+	Accept() string
+	// Parse builds a feed from the fetched body. fetchedURL is the resolved URL.
+	Parse(body io.Reader, fetchedURL string) (*gofeed.Feed, error)
+}
+
+// sourceAdapters maps URL schemes to the built-in adapters.
+var sourceAdapters = map[string]SourceAdapter{
+	"youtube":  youTubeAdapter{},
+	"reddit":   redditAdapter{},
+	"telegram": telegramChannelAdapter{},
+}
+
+// AdapterFor returns the source adapter for rawURL's scheme, or nil for
+// ordinary URLs.
+func AdapterFor(rawURL string) SourceAdapter {
+	scheme, _, found := strings.Cut(rawURL, "://")
+	if !found {
+		return nil
+	}
+	return sourceAdapters[strings.ToLower(scheme)]
+}
+
+// ResolveSourceURL returns the URL actually fetched for rawURL: the adapter's
+// target for adapter URLs, rawURL itself otherwise.
+func ResolveSourceURL(rawURL string) (string, error) {
+	adapter := AdapterFor(rawURL)
+	if adapter == nil {
+		return rawURL, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid source URL %q: %w", rawURL, err)
+	}
+	return adapter.Resolve(u)
+}
+
+// sourceName returns the part after the scheme, e.g. "UCxxxx" for youtube://UCxxxx.
+func sourceName(src *url.URL) string {
+	return strings.Trim(src.Host+src.Path, "/")
+}
+
+// youTubeAdapter maps youtube://<channel ID> and youtube://playlist/<ID> to
+// YouTube's own Atom feeds.
+type youTubeAdapter struct{}
+
+var youTubeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func (youTubeAdapter) Resolve(src *url.URL) (string, error) {
+	name := sourceName(src)
+	param := "channel_id"
+	if rest, ok := strings.CutPrefix(name, "playlist/"); ok {
+		param, name = "playlist_id", rest
+	}
+	if !youTubeIDPattern.MatchString(name) {
+		return "", fmt.Errorf("youtube:// expects a channel ID (UC...) or playlist/<ID>, got %q", sourceName(src))
+	}
+	return "https://www.youtube.com/feeds/videos.xml?" + param + "=" + url.QueryEscape(name), nil
+}
+
+func (youTubeAdapter) Accept() string { return feedAcceptHeader }
+
+func (youTubeAdapter) Parse(body io.Reader, _ string) (*gofeed.Feed, error) {
+	return ParseFeed(body)
+}
+
+// redditAdapter maps reddit://<subreddit> to the subreddit's JSON listing of
+// new posts, which carries more than Reddit's RSS (images, self text, scores).
+type redditAdapter struct{}
+
+var subredditPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+func (redditAdapter) Resolve(src *url.URL) (string, error) {
+	name := strings.TrimPrefix(sourceName(src), "r/")
+	if !subredditPattern.MatchString(name) {
+		return "", fmt.Errorf("reddit:// expects a subreddit name, got %q", sourceName(src))
+	}
+	return "https://www.reddit.com/r/" + name + "/new/.json?raw_json=1", nil
+}
+
+func (redditAdapter) Accept() string { return "application/json" }
+
+// redditListing is the subset of Reddit's listing JSON the adapter uses.
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Data struct {
+				Name         string  `json:"name"`
+				Title        string  `json:"title"`
+				Author       string  `json:"author"`
+				Permalink    string  `json:"permalink"`
+				URL          string  `json:"url"`
+				CreatedUTC   float64 `json:"created_utc"`
+				SelftextHTML string  `json:"selftext_html"`
+				PostHint     string  `json:"post_hint"`
+				Subreddit    string  `json:"subreddit"`
+				Stickied     bool    `json:"stickied"`
+			} `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+func (redditAdapter) Parse(body io.Reader, fetchedURL string) (*gofeed.Feed, error) {
+	var listing redditListing
+	if err := json.NewDecoder(body).Decode(&listing); err != nil {
+		return nil, fmt.Errorf("decoding Reddit listing: %w", err)
+	}
+	feed := &gofeed.Feed{FeedType: "reddit", Link: strings.TrimSuffix(fetchedURL, "new/.json?raw_json=1")}
+	for _, child := range listing.Data.Children {
+		post := child.Data
+		if post.Stickied {
+			continue // Pinned posts sit on top for weeks; they aren't new
+		}
+		if feed.Title == "" && post.Subreddit != "" {
+			feed.Title = "r/" + post.Subreddit
+		}
+		created := time.Unix(int64(post.CreatedUTC), 0).UTC()
+		item := &gofeed.Item{
+			GUID:            post.Name,
+			Title:           post.Title,
+			Link:            "https://www.reddit.com" + post.Permalink,
+			Content:         html.UnescapeString(post.SelftextHTML),
+			Published:       created.Format(time.RFC3339),
+			PublishedParsed: &created,
+		}
+		if post.Author != "" {
+			item.Authors = []*gofeed.Person{{Name: "u/" + post.Author}}
+		}
+		if post.PostHint == "image" && post.URL != "" {
+			item.Image = &gofeed.Image{URL: post.URL}
+		} else if post.URL != "" && !strings.Contains(post.URL, post.Permalink) {
+			item.Custom = map[string]string{CustomKeyExternalURL: post.URL}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed, nil
+}
+
+// telegramChannelAdapter maps telegram://<channel> to the public web preview
+// of a Telegram channel at t.me/s/<channel>.
+type telegramChannelAdapter struct{}
+
+var telegramChannelPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{3,}$`)
+
+// telegramPreviewScrape selects posts on a t.me/s/ page.
+var telegramPreviewScrape = &database.ScrapeConfig{
+	ItemSelector:    ".tgme_widget_message[data-post]",
+	TitleSelector:   ".tgme_widget_message_text",
+	LinkSelector:    "a.tgme_widget_message_date",
+	DateSelector:    "a.tgme_widget_message_date time",
+	ContentSelector: ".tgme_widget_message_text",
+}
+
+// telegramTitleLength caps titles taken from the start of a post's text.
+const telegramTitleLength = 100
+
+func (telegramChannelAdapter) Resolve(src *url.URL) (string, error) {
+	name := strings.TrimPrefix(sourceName(src), "@")
+	if !telegramChannelPattern.MatchString(name) {
+		return "", fmt.Errorf("telegram:// expects a public channel username, got %q", sourceName(src))
+	}
+	return "https://t.me/s/" + name, nil
+}
+
+func (telegramChannelAdapter) Accept() string { return htmlAcceptHeader }
+
+func (telegramChannelAdapter) Parse(body io.Reader, fetchedURL string) (*gofeed.Feed, error) {
+	feed, err := ScrapeHTML(body, fetchedURL, telegramPreviewScrape)
+	if err != nil {
+		return nil, err
+	}
+	feed.FeedType = "telegram"
+	feed.Title = strings.TrimSuffix(feed.Title, " – Telegram")
+	for _, item := range feed.Items {
+		if utf8.RuneCountInString(item.Title) > telegramTitleLength {
+			item.Title = string([]rune(item.Title)[:telegramTitleLength-1]) + "…"
+		}
+	}
+	return feed, nil
+}
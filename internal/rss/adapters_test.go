@@ -0,0 +1,82 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSourceURL(t *testing.T) {
+	cases := map[string]string{
+		"youtube://UCabc_123-x":        "https://www.youtube.com/feeds/videos.xml?channel_id=UCabc_123-x",
+		"youtube://playlist/PLxyz":     "https://www.youtube.com/feeds/videos.xml?playlist_id=PLxyz",
+		"reddit://golang":              "https://www.reddit.com/r/golang/new/.json?raw_json=1",
+		"reddit://r/golang":            "https://www.reddit.com/r/golang/new/.json?raw_json=1",
+		"telegram://@durov":            "https://t.me/s/durov",
+		"https://example.com/feed.xml": "https://example.com/feed.xml",
+	}
+	for in, want := range cases {
+		got, err := ResolveSourceURL(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, bad := range []string{"youtube://", "reddit://go lang", "telegram://ab"} {
+		_, err := ResolveSourceURL(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestRedditAdapterParse(t *testing.T) {
+	body := `{"data":{"children":[
+		{"data":{"name":"t3_pin","title":"Rules","permalink":"/r/golang/comments/pin/","stickied":true,"subreddit":"golang"}},
+		{"data":{"name":"t3_a","title":"Go 1.24 released","author":"gopher","permalink":"/r/golang/comments/a/go_124/",
+			"url":"https://go.dev/blog/go1.24","created_utc":1739318400,"subreddit":"golang"}},
+		{"data":{"name":"t3_b","title":"Self post","permalink":"/r/golang/comments/b/self/","url":"https://www.reddit.com/r/golang/comments/b/self/",
+			"selftext_html":"&lt;p&gt;Hello&lt;/p&gt;","created_utc":1739318500,"subreddit":"golang"}}
+	]}}`
+	feed, err := redditAdapter{}.Parse(strings.NewReader(body), "https://www.reddit.com/r/golang/new/.json?raw_json=1")
+	require.NoError(t, err)
+	assert.Equal(t, "r/golang", feed.Title)
+	require.Len(t, feed.Items, 2, "stickied posts are skipped")
+
+	link := feed.Items[0]
+	assert.Equal(t, "t3_a", link.GUID)
+	assert.Equal(t, "https://www.reddit.com/r/golang/comments/a/go_124/", link.Link)
+	assert.Equal(t, "https://go.dev/blog/go1.24", link.Custom[CustomKeyExternalURL])
+	assert.Equal(t, "u/gopher", link.Authors[0].Name)
+	require.NotNil(t, link.PublishedParsed)
+	assert.Equal(t, int64(1739318400), link.PublishedParsed.Unix())
+
+	self := feed.Items[1]
+	assert.Equal(t, "<p>Hello</p>", self.Content)
+	assert.Nil(t, self.Custom)
+}
+
+func TestTelegramChannelAdapterParse(t *testing.T) {
+	page := `<html><head><title>Example Channel – Telegram</title></head><body>
+<div class="tgme_widget_message" data-post="example/41">
+  <div class="tgme_widget_message_text">First <b>post</b></div>
+  <a class="tgme_widget_message_date" href="https://t.me/example/41"><time datetime="2025-01-02T10:00:00+00:00">10:00</time></a>
+</div>
+<div class="tgme_widget_message" data-post="example/42">
+  <div class="tgme_widget_message_text">` + strings.Repeat("long ", 40) + `</div>
+  <a class="tgme_widget_message_date" href="https://t.me/example/42"><time datetime="2025-01-02T11:00:00+00:00">11:00</time></a>
+</div>
+</body></html>`
+	feed, err := telegramChannelAdapter{}.Parse(strings.NewReader(page), "https://t.me/s/example")
+	require.NoError(t, err)
+	assert.Equal(t, "Example Channel", feed.Title)
+	require.Len(t, feed.Items, 2)
+
+	first := feed.Items[0]
+	assert.Equal(t, "https://t.me/example/41", first.GUID)
+	assert.Equal(t, "First post", first.Title)
+	assert.Equal(t, "First <b>post</b>", first.Content)
+	require.NotNil(t, first.PublishedParsed)
+
+	assert.Len(t, []rune(feed.Items[1].Title), telegramTitleLength)
+	assert.True(t, strings.HasSuffix(feed.Items[1].Title, "…"))
+}
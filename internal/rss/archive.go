@@ -0,0 +1,100 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+	"github.com/rs/zerolog/log"
+)
+
+// maxArchivePages bounds an archive walk even when no item limit is set.
+const maxArchivePages = 100
+
+// PrevArchiveURL returns the RFC 5005 rel="prev-archive" link of a feed,
+// resolved against pageURL, or "" if it has none. RSS feeds carry it as an
+// <atom:link>.
+func PrevArchiveURL(feed *gofeed.Feed, pageURL string) string {
+	if feed == nil {
+		return ""
+	}
+	href := feed.Custom[CustomKeyPrevArchive]
+	for _, prefix := range []string{"atom", "atom10"} {
+		for _, ext := range feed.Extensions[prefix]["link"] {
+			if href == "" && ext.Attrs["rel"] == "prev-archive" {
+				href = ext.Attrs["href"]
+			}
+		}
+	}
+	if href == "" {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// FetchArchives walks a feed's archive pages (RFC 5005 rel="prev-archive")
+// from the current document feed, fetched from feedURL, and returns their
+// items, newest page first. It stops after limit items (0 for no limit), at
+// the first page whose items are all older than maxAge (0 for no limit), or
+// after maxArchivePages pages. A page that fails to load ends the walk with
+// the items gathered so far and the error.
+func (f *GoFeedFetcher) FetchArchives(ctx context.Context, feed *gofeed.Feed, feedURL string, limit int, maxAge time.Duration, proxy *database.Proxy, opts interfaces.FetchOptions) ([]*gofeed.Item, error) {
+	var cutoff time.Time
+	if maxAge > 0 {
+		cutoff = time.Now().Add(-maxAge)
+	}
+	var items []*gofeed.Item
+	opts.BodyHash = "" // The feed's own hash says nothing about its archive pages
+	visited := map[string]bool{feedURL: true}
+	pageURL := PrevArchiveURL(feed, feedURL)
+	for pages := 0; pageURL != "" && !visited[pageURL] && pages < maxArchivePages; pages++ {
+		visited[pageURL] = true
+		result, err := f.fetch(ctx, pageURL, nil, nil, proxy, opts, feedAcceptHeader, func(body io.Reader) (*gofeed.Feed, error) {
+			return ParseFeed(body)
+		})
+		if err != nil {
+			return items, fmt.Errorf("fetching archive page %s: %w", pageURL, err)
+		}
+		page := result.Feed
+		if page == nil {
+			break
+		}
+		log.Debug().Str("feed_url", feedURL).Str("archive_url", pageURL).Int("items", len(page.Items)).Msg("Fetched archive page")
+
+		recent := false
+		for _, item := range page.Items {
+			if !cutoff.IsZero() {
+				date := item.PublishedParsed
+				if date == nil {
+					date = item.UpdatedParsed
+				}
+				if date != nil && date.Before(cutoff) {
+					continue
+				}
+			}
+			recent = true
+			items = append(items, item)
+			if limit > 0 && len(items) >= limit {
+				return items, nil
+			}
+		}
+		if !recent && len(page.Items) > 0 {
+			break // Archives only get older from here
+		}
+		pageURL = PrevArchiveURL(page, pageURL)
+	}
+	return items, nil
+}
@@ -0,0 +1,69 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// archivePage renders an RSS page with one item per date and an optional
+// prev-archive link.
+func archivePage(prev string, dates ...string) string {
+	link := ""
+	if prev != "" {
+		link = fmt.Sprintf(`<atom:link rel="prev-archive" href="%s"/>`, prev)
+	}
+	items := ""
+	for _, d := range dates {
+		items += fmt.Sprintf(`<item><guid>%s</guid><title>%s</title><pubDate>%s</pubDate></item>`, d, d, d)
+	}
+	return `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>T</title>` + link + items + `</channel></rss>`
+}
+
+func TestFetchArchives(t *testing.T) {
+	day := func(n int) string { return time.Now().AddDate(0, 0, -n).UTC().Format(time.RFC1123Z) }
+	pages := map[string]string{
+		"/feed":      archivePage("/archive/2", day(1)),
+		"/archive/2": archivePage("1", day(10), day(11)),          // Relative to /archive/2
+		"/archive/1": archivePage("/archive/2", day(40), day(41)), // Loops back
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, page)
+	}))
+	defer srv.Close()
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "")
+	ctx := context.Background()
+
+	current, err := fetcher.Fetch(ctx, srv.URL+"/feed", nil, nil, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/archive/2", PrevArchiveURL(current.Feed, srv.URL+"/feed"))
+
+	items, err := fetcher.FetchArchives(ctx, current.Feed, srv.URL+"/feed", 0, 0, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, items, 4, "all archive pages, stopping at the loop")
+
+	items, err = fetcher.FetchArchives(ctx, current.Feed, srv.URL+"/feed", 3, 0, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, items, 3)
+
+	items, err = fetcher.FetchArchives(ctx, current.Feed, srv.URL+"/feed", 0, 30*24*time.Hour, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, items, 2, "items older than the max age are skipped")
+
+	pages["/archive/2"] = archivePage("/archive/missing", day(10))
+	items, err = fetcher.FetchArchives(ctx, current.Feed, srv.URL+"/feed", 0, 0, nil, interfaces.FetchOptions{MaxRetries: new(int)})
+	assert.Error(t, err)
+	assert.Len(t, items, 1, "items gathered before a failing page are returned")
+}
@@ -0,0 +1,180 @@
+package rss
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/transform"
+)
+
+// acceptEncodingHeader is sent explicitly, which turns off net/http's
+// transparent gzip handling; decompressBody takes over instead.
+const acceptEncodingHeader = "gzip, deflate, br"
+
+// DefaultMaxBodySize caps decompressed response bodies unless the fetcher is
+// configured with another limit.
+const DefaultMaxBodySize int64 = 50 << 20
+
+var (
+	// ErrResponseTooLarge is returned when a body exceeds the size limit.
+	ErrResponseTooLarge = errors.New("response too large")
+	// ErrUnexpectedContentType is returned for responses that can't be a feed or page, such as images or archives.
+	ErrUnexpectedContentType = errors.New("unexpected content type")
+)
+
+// rejectedMediaTypes lists media types that are never feeds or HTML pages.
+// application/octet-stream is deliberately absent: misconfigured servers use it for feeds.
+var rejectedMediaTypes = map[string]bool{
+	"application/pdf":              true,
+	"application/zip":              true,
+	"application/gzip":             true,
+	"application/x-gzip":           true,
+	"application/x-tar":            true,
+	"application/x-7z-compressed":  true,
+	"application/x-rar-compressed": true,
+	"application/vnd.rar":          true,
+}
+
+// xmlEncodingDecl matches the encoding attribute of an XML declaration.
+var xmlEncodingDecl = regexp.MustCompile(`^(\s*(?:\x{FEFF})?<\?xml[^>]*?encoding\s*=\s*)["']([^"']+)["']`)
+
+// readBody decompresses a response body and converts it to UTF-8. Bodies of the
+// wrong content type, or larger than limit bytes once decompressed, are rejected
+// without being read further; limit <= 0 uses DefaultMaxBodySize.
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	if err := checkContentType(resp.Header.Get("Content-Type")); err != nil {
+		return nil, err
+	}
+	if resp.ContentLength > limit {
+		return nil, fmt.Errorf("%w: Content-Length %d exceeds the %d byte limit", ErrResponseTooLarge, resp.ContentLength, limit)
+	}
+	body, err := decompressBody(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	raw, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading body: %w", err)
+	}
+	if int64(len(raw)) > limit {
+		return nil, fmt.Errorf("%w: body exceeds the %d byte limit", ErrResponseTooLarge, limit)
+	}
+	return toUTF8(raw, resp.Header.Get("Content-Type"))
+}
+
+// checkContentType rejects media types that can't hold a feed or HTML page.
+// A missing or unparsable Content-Type is allowed; the parser decides.
+func checkContentType(contentType string) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	if rejectedMediaTypes[mediaType] {
+		return fmt.Errorf("%w %q", ErrUnexpectedContentType, mediaType)
+	}
+	switch mediaType[:strings.Index(mediaType+"/", "/")] {
+	case "image", "audio", "video", "font":
+		return fmt.Errorf("%w %q", ErrUnexpectedContentType, mediaType)
+	}
+	return nil
+}
+
+// decompressBody wraps body according to its Content-Encoding.
+func decompressBody(body io.ReadCloser, contentEncoding string) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("opening gzip body: %w", err)
+		}
+		return readCloser{zr, body}, nil
+	case "deflate":
+		// "deflate" is zlib-wrapped per RFC 9110, but some servers send raw deflate.
+		buffered := bufio.NewReader(body)
+		if header, err := buffered.Peek(2); err == nil && isZlibHeader(header) {
+			zr, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, fmt.Errorf("opening deflate body: %w", err)
+			}
+			return readCloser{zr, body}, nil
+		}
+		return readCloser{flate.NewReader(buffered), body}, nil
+	case "br":
+		return readCloser{brotli.NewReader(body), body}, nil
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", contentEncoding)
+	}
+}
+
+// toUTF8 converts a feed body to UTF-8. The charset comes from the Content-Type
+// header, then the XML declaration; bodies that are neither labelled nor valid
+// UTF-8 are sniffed. A converted document's XML declaration is rewritten to
+// say UTF-8 so the parser doesn't decode it a second time.
+func toUTF8(raw []byte, contentType string) ([]byte, error) {
+	label := ""
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		label = params["charset"]
+	}
+	declared := xmlEncodingDecl.FindSubmatch(raw)
+	if label == "" && declared != nil {
+		label = string(declared[2])
+	}
+	if label == "" {
+		if utf8.Valid(raw) {
+			return raw, nil
+		}
+		_, label, _ = charset.DetermineEncoding(raw, contentType)
+	}
+
+	enc, name := charset.Lookup(label)
+	if enc == nil {
+		return nil, fmt.Errorf("unsupported charset %q", label)
+	}
+	if name == "utf-8" {
+		return raw, nil
+	}
+	converted, _, err := transform.Bytes(enc.NewDecoder(), raw)
+	if err != nil {
+		return nil, fmt.Errorf("converting from %s: %w", name, err)
+	}
+	if declared != nil {
+		converted = xmlEncodingDecl.ReplaceAll(converted, []byte(`${1}"UTF-8"`))
+	}
+	return converted, nil
+}
+
+// readCloser reads from a decompressor and closes the underlying body.
+type readCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (r readCloser) Close() error {
+	if c, ok := r.Reader.(io.Closer); ok {
+		c.Close()
+	}
+	return r.closer.Close()
+}
+
+// isZlibHeader reports whether b starts with a zlib (RFC 1950) stream header.
+func isZlibHeader(b []byte) bool {
+	return b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
@@ -0,0 +1,117 @@
+package rss
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	}
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestReadBody_Decompresses(t *testing.T) {
+	payload := []byte(`<rss version="2.0"><channel><title>T</title></channel></rss>`)
+	for _, enc := range []string{"gzip", "deflate", "raw-deflate", "br"} {
+		t.Run(enc, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("Content-Encoding", enc)
+			if enc == "raw-deflate" {
+				header.Set("Content-Encoding", "deflate")
+			}
+			resp := &http.Response{Header: header, Body: io.NopCloser(bytes.NewReader(compress(t, enc, payload)))}
+			body, err := readBody(resp, 0)
+			require.NoError(t, err)
+			assert.Equal(t, payload, body)
+		})
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	cp1251, err := charmap.Windows1251.NewEncoder().String("Привет")
+	require.NoError(t, err)
+
+	t.Run("xml declaration", func(t *testing.T) {
+		raw := []byte(`<?xml version="1.0" encoding="windows-1251"?><rss><channel><title>` + cp1251 + `</title></channel></rss>`)
+		out, err := toUTF8(raw, "application/rss+xml")
+		require.NoError(t, err)
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?><rss><channel><title>Привет</title></channel></rss>`, string(out))
+
+		feed, err := ParseFeed(bytes.NewReader(out))
+		require.NoError(t, err)
+		assert.Equal(t, "Привет", feed.Title)
+	})
+
+	t.Run("content type", func(t *testing.T) {
+		out, err := toUTF8([]byte(`<rss><channel><title>`+cp1251+`</title></channel></rss>`), "text/xml; charset=windows-1251")
+		require.NoError(t, err)
+		assert.Contains(t, string(out), "Привет")
+	})
+
+	t.Run("utf-8 passthrough", func(t *testing.T) {
+		raw := []byte(`<?xml version="1.0" encoding="utf-8"?><rss/>`)
+		out, err := toUTF8(raw, "")
+		require.NoError(t, err)
+		assert.Equal(t, raw, out)
+	})
+}
+
+func TestReadBody_Limits(t *testing.T) {
+	payload := bytes.Repeat([]byte("a"), 100)
+
+	t.Run("content length", func(t *testing.T) {
+		resp := &http.Response{Header: http.Header{}, ContentLength: 100, Body: io.NopCloser(bytes.NewReader(payload))}
+		_, err := readBody(resp, 50)
+		assert.ErrorIs(t, err, ErrResponseTooLarge)
+	})
+
+	t.Run("decompressed size", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("Content-Encoding", "gzip")
+		resp := &http.Response{Header: header, ContentLength: -1, Body: io.NopCloser(bytes.NewReader(compress(t, "gzip", payload)))}
+		_, err := readBody(resp, 50)
+		assert.ErrorIs(t, err, ErrResponseTooLarge)
+	})
+
+	t.Run("exactly at limit", func(t *testing.T) {
+		resp := &http.Response{Header: http.Header{}, ContentLength: -1, Body: io.NopCloser(bytes.NewReader(payload))}
+		body, err := readBody(resp, 100)
+		require.NoError(t, err)
+		assert.Len(t, body, 100)
+	})
+
+	t.Run("content type", func(t *testing.T) {
+		for _, ct := range []string{"image/png", "video/mp4", "application/zip"} {
+			header := http.Header{}
+			header.Set("Content-Type", ct)
+			resp := &http.Response{Header: header, Body: io.NopCloser(bytes.NewReader(payload))}
+			_, err := readBody(resp, 0)
+			assert.ErrorIs(t, err, ErrUnexpectedContentType, ct)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+)
+
+// feedLinkTypes are the <link rel="alternate"> MIME types treated as feeds.
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/json":      true,
+	"application/rdf+xml":   true,
+}
+
+// commonFeedPaths are where sites often serve a feed without advertising it,
+// tried in order when a page has no feed links.
+var commonFeedPaths = []string{"feed", "rss", "feed.xml", "rss.xml", "atom.xml", "index.xml", "feed.json"}
+
+// feedTypeMIME names the detected feed types the way feed links do.
+var feedTypeMIME = map[gofeed.FeedType]string{
+	gofeed.FeedTypeRSS:  "application/rss+xml",
+	gofeed.FeedTypeAtom: "application/atom+xml",
+	gofeed.FeedTypeJSON: "application/feed+json",
+}
+
+// DiscoveredFeed is a feed advertised by a web page, or found at one of the
+// usual feed paths of its site.
+type DiscoveredFeed struct {
+	URL   string
+	Title string
+	Type  string
+}
+
+// Discovery is the outcome of DiscoverFeeds. IsFeed is set when the URL is
+// itself a feed, in which case Feeds is empty.
+type Discovery struct {
+	IsFeed bool
+	Feeds  []DiscoveredFeed
+}
+
+// DiscoverFeeds fetches pageURL and, unless it already is a feed, returns the
+// feeds the page advertises with <link rel="alternate">, in document order.
+// A page advertising none is checked for a feed at the usual paths, next to
+// the page and then at the site root, and the first found is returned.
+func DiscoverFeeds(ctx context.Context, httpClient *http.Client, pageURL string, opts interfaces.FetchOptions) (*Discovery, error) {
+	resp, body, err := getForDiscovery(ctx, httpClient, pageURL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if gofeed.DetectFeedType(bytes.NewReader(body)) != gofeed.FeedTypeUnknown {
+		return &Discovery{IsFeed: true}, nil
+	}
+	// Redirects change the base that relative links resolve against.
+	feeds, err := FindFeedLinks(bytes.NewReader(body), resp.Request.URL.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(feeds) == 0 {
+		if found := probeFeedPaths(ctx, httpClient, resp.Request.URL, opts); found != nil {
+			feeds = append(feeds, *found)
+		}
+	}
+	return &Discovery{Feeds: feeds}, nil
+}
+
+// probeFeedPaths returns the first feed served at one of commonFeedPaths
+// relative to page, then to its site root, or nil. Failed requests just move
+// on to the next path.
+func probeFeedPaths(ctx context.Context, httpClient *http.Client, page *url.URL, opts interfaces.FetchOptions) *DiscoveredFeed {
+	seen := map[string]bool{page.String(): true}
+	for _, prefix := range []string{"", "/"} {
+		for _, path := range commonFeedPaths {
+			candidate := page.ResolveReference(&url.URL{Path: prefix + path}).String()
+			if seen[candidate] {
+				continue
+			}
+			seen[candidate] = true
+			if ctx.Err() != nil {
+				return nil
+			}
+			_, body, err := getForDiscovery(ctx, httpClient, candidate, opts)
+			if err != nil {
+				continue
+			}
+			if mimeType, ok := feedTypeMIME[gofeed.DetectFeedType(bytes.NewReader(body))]; ok {
+				return &DiscoveredFeed{URL: candidate, Type: mimeType}
+			}
+		}
+	}
+	return nil
+}
+
+// getForDiscovery fetches rawURL accepting feeds or HTML and returns the
+// response, whose body is closed, and the body read.
+func getForDiscovery(ctx context.Context, httpClient *http.Client, rawURL string, opts interfaces.FetchOptions) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request for %s: %w", rawURL, err)
+	}
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	req.Header.Set("Accept", feedAcceptHeader+", text/html;q=0.7")
+	req.Header.Set("Accept-Encoding", acceptEncodingHeader)
+	applyFetchOptions(req, opts)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("fetching %s: status %d", rawURL, resp.StatusCode)
+	}
+	body, err := readBody(resp, DefaultMaxBodySize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", rawURL, err)
+	}
+	return resp, body, nil
+}
+
+// FindFeedLinks extracts feed links from an HTML document, resolving relative
+// URLs against pageURL and skipping duplicates.
+func FindFeedLinks(r io.Reader, pageURL string) ([]DiscoveredFeed, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing HTML: %w", err)
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing page URL: %w", err)
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if ref, err := url.Parse(href); err == nil {
+			base = base.ResolveReference(ref)
+		}
+	}
+
+	var feeds []DiscoveredFeed
+	seen := make(map[string]bool)
+	doc.Find("link[href]").Each(func(_ int, sel *goquery.Selection) {
+		rel, _ := sel.Attr("rel")
+		if !containsFold(strings.Fields(rel), "alternate") {
+			return
+		}
+		linkType, _ := sel.Attr("type")
+		linkType = strings.ToLower(strings.TrimSpace(linkType))
+		if !feedLinkTypes[linkType] {
+			return
+		}
+		href, _ := sel.Attr("href")
+		ref, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		feedURL := base.ResolveReference(ref).String()
+		if seen[feedURL] {
+			return
+		}
+		seen[feedURL] = true
+		title, _ := sel.Attr("title")
+		feeds = append(feeds, DiscoveredFeed{URL: feedURL, Title: strings.TrimSpace(title), Type: linkType})
+	})
+	return feeds, nil
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindFeedLinks(t *testing.T) {
+	const page = `<html><head>
+		<link rel="stylesheet" href="/style.css">
+		<link rel="alternate" type="application/rss+xml" title="Posts" href="/feed.xml">
+		<link rel="alternate" type="application/atom+xml" href="https://example.com/atom">
+		<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+		<link rel="alternate" hreflang="de" href="/de/">
+		<link rel="Alternate Feed" type="application/feed+json" href="feed.json">
+	</head><body></body></html>`
+
+	feeds, err := FindFeedLinks(strings.NewReader(page), "https://example.com/blog/")
+	require.NoError(t, err)
+	require.Len(t, feeds, 3)
+	assert.Equal(t, DiscoveredFeed{URL: "https://example.com/feed.xml", Title: "Posts", Type: "application/rss+xml"}, feeds[0])
+	assert.Equal(t, "https://example.com/atom", feeds[1].URL)
+	assert.Equal(t, "https://example.com/blog/feed.json", feeds[2].URL)
+}
+
+func TestDiscoverFeeds_ProbesCommonPaths(t *testing.T) {
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/blog/", "/blog/feed", "/feed":
+			_, _ = w.Write([]byte(`<html><head><title>Not a feed</title></head></html>`))
+		case "/rss.xml":
+			_, _ = w.Write([]byte(testRSS))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	discovery, err := DiscoverFeeds(context.Background(), srv.Client(), srv.URL+"/blog/", interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.False(t, discovery.IsFeed)
+	assert.Equal(t, []DiscoveredFeed{{URL: srv.URL + "/rss.xml", Type: "application/rss+xml"}}, discovery.Feeds)
+	assert.Equal(t, "/blog/", requested[0])
+	assert.Contains(t, requested, "/blog/atom.xml") // Next to the page before the site root
+	assert.Equal(t, "/rss.xml", requested[len(requested)-1])
+
+	requested = nil
+	discovery, err = DiscoverFeeds(context.Background(), srv.Client(), srv.URL+"/rss.xml", interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.True(t, discovery.IsFeed)
+	assert.Equal(t, []string{"/rss.xml"}, requested)
+}
@@ -0,0 +1,155 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxIconSize bounds a downloaded icon; real favicons are a few KB.
+	maxIconSize = 256 << 10
+	// iconTimeout bounds the whole icon lookup, across all candidates.
+	iconTimeout = 30 * time.Second
+)
+
+// FetchIcon downloads the icon of the site behind feed: the icon its home page
+// links to, /favicon.ico, or failing those the feed's own image. feedURL is
+// used when the feed has no site link.
+func (f *GoFeedFetcher) FetchIcon(ctx context.Context, feed *gofeed.Feed, feedURL string, proxy *database.Proxy, opts interfaces.FetchOptions) (*database.FeedIcon, error) {
+	ctx, cancel := context.WithTimeout(ctx, iconTimeout)
+	defer cancel()
+
+	site := feedURL
+	if feed != nil && feed.Link != "" {
+		site = resolveURL(feedURL, feed.Link)
+	}
+	var candidates []string
+	if linked := f.linkedIcons(ctx, site, proxy, opts); len(linked) > 0 {
+		candidates = append(candidates, linked...)
+	}
+	if u, err := url.Parse(site); err == nil && u.Host != "" {
+		candidates = append(candidates, (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/favicon.ico"}).String())
+	}
+	if feed != nil && feed.Image != nil && feed.Image.URL != "" {
+		candidates = append(candidates, resolveURL(feedURL, feed.Image.URL))
+	}
+
+	var lastErr error = fmt.Errorf("no icon candidates for %s", site)
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		icon, err := f.downloadIcon(ctx, candidate, proxy, opts)
+		if err == nil {
+			return icon, nil
+		}
+		log.Debug().Err(err).Str("icon_url", candidate).Msg("Icon candidate failed")
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// linkedIcons returns the icons a page declares, <link rel="icon"> (and
+// "shortcut icon") before apple-touch-icon, resolved against the page URL.
+func (f *GoFeedFetcher) linkedIcons(ctx context.Context, pageURL string, proxy *database.Proxy, opts interfaces.FetchOptions) []string {
+	resp, err := f.simpleGet(ctx, pageURL, htmlAcceptHeader, proxy, opts)
+	if err != nil {
+		log.Debug().Err(err).Str("page_url", pageURL).Msg("Failed to fetch site page for its icon")
+		return nil
+	}
+	defer resp.Body.Close()
+	body, err := readBody(resp, maxPageTitleBody)
+	if err != nil {
+		return nil
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	base := resp.Request.URL.String()
+	var icons, touchIcons []string
+	doc.Find("link[rel][href]").Each(func(_ int, s *goquery.Selection) {
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		if href == "" {
+			return
+		}
+		for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+			switch rel {
+			case "icon":
+				icons = append(icons, resolveURL(base, href))
+				return
+			case "apple-touch-icon", "apple-touch-icon-precomposed":
+				touchIcons = append(touchIcons, resolveURL(base, href))
+				return
+			}
+		}
+	})
+	return append(icons, touchIcons...)
+}
+
+// downloadIcon fetches iconURL, accepting only image responses up to maxIconSize.
+func (f *GoFeedFetcher) downloadIcon(ctx context.Context, iconURL string, proxy *database.Proxy, opts interfaces.FetchOptions) (*database.FeedIcon, error) {
+	resp, err := f.simpleGet(ctx, iconURL, "image/*", proxy, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.ContentLength > maxIconSize {
+		return nil, fmt.Errorf("%w: icon is %d bytes", ErrResponseTooLarge, resp.ContentLength)
+	}
+	body, err := decompressBody(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, maxIconSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading icon: %w", err)
+	}
+	if len(data) > maxIconSize {
+		return nil, fmt.Errorf("%w: icon exceeds %d bytes", ErrResponseTooLarge, maxIconSize)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty icon")
+	}
+
+	// Servers often send icons as application/octet-stream, and some answer
+	// missing files with an HTML page, so trust the content over the header.
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("not an image (%s)", contentType)
+	}
+	return &database.FeedIcon{URL: resp.Request.URL.String(), ContentType: contentType, Data: data}, nil
+}
+
+// resolveURL resolves href against base, returning href unchanged when
+// either doesn't parse.
+func resolveURL(base, href string) string {
+	b, err := url.Parse(base)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return b.ResolveReference(ref).String()
+}
@@ -0,0 +1,61 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestFetchIcon(t *testing.T) {
+	linked := true
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			// Soft 404: an HTML page with status 200.
+			fmt.Fprint(w, "<html><body>Not found</body></html>")
+			return
+		}
+		if linked {
+			fmt.Fprint(w, `<html><head><link rel="apple-touch-icon" href="/touch.png"><link rel="shortcut icon" href="static/icon.png"></head></html>`)
+		} else {
+			fmt.Fprint(w, `<html><head><title>Blog</title></head></html>`)
+		}
+	})
+	mux.HandleFunc("/static/icon.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(testPNG)
+	})
+	mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/x-icon")
+		w.Write([]byte{0, 0, 1, 0})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "")
+	ctx := context.Background()
+	feed := &gofeed.Feed{Link: srv.URL + "/"}
+
+	icon, err := fetcher.FetchIcon(ctx, feed, srv.URL+"/feed.xml", nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/static/icon.png", icon.URL, "rel=icon wins over apple-touch-icon")
+	assert.Equal(t, "image/png", icon.ContentType, "sniffed from the data")
+	assert.Equal(t, testPNG, icon.Data)
+
+	linked = false
+	icon, err = fetcher.FetchIcon(ctx, nil, srv.URL+"/feed.xml", nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/favicon.ico", icon.URL)
+	assert.Equal(t, "image/x-icon", icon.ContentType)
+
+	_, err = fetcher.downloadIcon(ctx, srv.URL+"/missing.png", nil, interfaces.FetchOptions{})
+	assert.Error(t, err, "HTML answers aren't icons")
+}
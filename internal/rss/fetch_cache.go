@@ -0,0 +1,128 @@
+package rss
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+)
+
+// fetchCache shares fetched feeds between feeds that poll the same URL through
+// the same proxy with the same request settings, e.g. one feed delivered to
+// several chats. Fetches of a key already in flight wait for it instead of
+// downloading again. Only full (200) results are kept, for ttl.
+type fetchCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*fetchCacheEntry
+}
+
+type fetchCacheEntry struct {
+	done    chan struct{} // Closed once result/err are set
+	ready   bool
+	result  *interfaces.FetchResult
+	err     error
+	expires time.Time
+}
+
+func newFetchCache(ttl time.Duration) *fetchCache {
+	return &fetchCache{ttl: ttl, entries: make(map[string]*fetchCacheEntry)}
+}
+
+// do returns the cached result for key, waiting for an in-flight fetch if
+// there is one, or calls fetch and caches its result. Callers get their own
+// copy of the feed, and a nil Feed (as for a 304) when their validators or
+// body hash already match the cached response.
+func (c *fetchCache) do(ctx context.Context, key string, etag, lastModified *string, bodyHash string, fetch func() (*interfaces.FetchResult, error)) (*interfaces.FetchResult, error) {
+	now := time.Now()
+	c.mu.Lock()
+	for k, e := range c.entries {
+		if e.ready && now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	if e, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if e.err == nil && e.result != nil && e.result.Feed != nil {
+			return sharedResult(e.result, etag, lastModified, bodyHash), nil
+		}
+		// The shared fetch failed or was conditional on another feed's validators.
+		return fetch()
+	}
+	e := &fetchCacheEntry{done: make(chan struct{})}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	result, err := fetch()
+
+	c.mu.Lock()
+	e.result, e.err, e.ready = result, err, true
+	e.expires = time.Now().Add(c.ttl)
+	if err != nil || result == nil || result.Feed == nil {
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+	close(e.done)
+
+	if err != nil || result == nil || result.Feed == nil {
+		return result, err
+	}
+	return sharedResult(result, nil, nil, ""), nil
+}
+
+// sharedResult copies a cached result for one caller. Items are copied too,
+// since formatting may modify them per feed.
+func sharedResult(cached *interfaces.FetchResult, etag, lastModified *string, bodyHash string) *interfaces.FetchResult {
+	if sameValidator(etag, cached.NewEtag) || (isEmpty(etag) && sameValidator(lastModified, cached.NewLastModified)) || sameValidator(&bodyHash, cached.NewBodyHash) {
+		return &interfaces.FetchResult{NewEtag: etag, NewLastModified: lastModified, NewBodyHash: cached.NewBodyHash, PermanentURL: cached.PermanentURL}
+	}
+	result := *cached
+	feed := *cached.Feed
+	feed.Items = make([]*gofeed.Item, len(cached.Feed.Items))
+	for i, item := range cached.Feed.Items {
+		copied := *item
+		feed.Items[i] = &copied
+	}
+	result.Feed = &feed
+	return &result
+}
+
+func sameValidator(a, b *string) bool {
+	return !isEmpty(a) && !isEmpty(b) && *a == *b
+}
+
+func isEmpty(s *string) bool {
+	return s == nil || *s == ""
+}
+
+// fetchCacheKey identifies a fetch by URL, proxy and every request setting
+// that can change the response.
+func fetchCacheKey(url string, proxy *database.Proxy, opts interfaces.FetchOptions) string {
+	key := struct {
+		URL          string
+		ProxyID      int64
+		UserAgent    string
+		Headers      map[string]string
+		Auth         *database.FeedCredentials
+		Cookies      map[string]string
+		TLS          *database.TLSConfig
+		FlareSolverr bool
+	}{url, 0, opts.UserAgent, opts.Headers, opts.Auth, opts.Cookies, opts.TLS, opts.UseFlareSolverr}
+	if proxy != nil {
+		key.ProxyID = proxy.ID
+	}
+	raw, _ := json.Marshal(key) // Map keys are sorted, so equal settings give equal keys
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
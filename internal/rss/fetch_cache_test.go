@@ -0,0 +1,78 @@
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchCacheSharesFetches(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(50 * time.Millisecond) // Let concurrent fetches overlap
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "").WithFetchCache(time.Minute)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([]*interfaces.FetchResult, 3)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			results[i], err = fetcher.Fetch(ctx, srv.URL, nil, nil, nil, interfaces.FetchOptions{FeedID: int64(i + 1)})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, requests.Load(), "concurrent fetches of one URL share a request")
+	require.NotNil(t, results[0].Feed)
+	require.NotNil(t, results[1].Feed)
+	results[0].Feed.Items[0].Title = "changed by one feed's formatting"
+	assert.NotEqual(t, results[0].Feed.Items[0].Title, results[1].Feed.Items[0].Title, "each feed gets its own items")
+
+	etag := `"v1"`
+	result, err := fetcher.Fetch(ctx, srv.URL, &etag, nil, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, result.Feed, "a feed that already has this version sees it as not modified")
+	assert.EqualValues(t, 1, requests.Load())
+
+	result, err = fetcher.Fetch(ctx, srv.URL, nil, nil, nil, interfaces.FetchOptions{BodyHash: *results[0].NewBodyHash})
+	require.NoError(t, err)
+	assert.Nil(t, result.Feed, "so does a feed that already has this body")
+	assert.EqualValues(t, 1, requests.Load())
+
+	_, err = fetcher.Fetch(ctx, srv.URL, nil, nil, nil, interfaces.FetchOptions{UserAgent: "Other/1.0"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, requests.Load(), "different request settings aren't shared")
+}
+
+func TestFetchCacheExpires(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "").WithFetchCache(10 * time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		_, err := fetcher.Fetch(context.Background(), srv.URL, nil, nil, nil, interfaces.FetchOptions{})
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.EqualValues(t, 2, requests.Load())
+}
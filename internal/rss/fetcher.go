@@ -1,61 +1,260 @@
 package rss
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/mmcdole/gofeed"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/haytac/rss-telegram-bot/internal/database"
 	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
 )
 
-// Define these constants here
-const (
-	maxFetchRetries    = 3
-	initialRetryDelay  = 2 * time.Second
-	maxRetryDelay      = 30 * time.Second
-)
+// RetryPolicy controls request timeouts and how failed fetches are retried.
+type RetryPolicy struct {
+	MaxRetries    int           // Retries after the first attempt; 0 disables retrying
+	InitialDelay  time.Duration // Backoff before the first retry, doubled on each further one
+	MaxDelay      time.Duration // Upper bound for the backoff
+	Timeout       time.Duration // Per-request timeout, including reading the body
+}
+
+// DefaultRetryPolicy is used unless the fetcher is configured otherwise.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:   3,
+	InitialDelay: 2 * time.Second,
+	MaxDelay:     30 * time.Second,
+	Timeout:      60 * time.Second,
+}
+
+// DefaultUserAgent is sent when neither the config nor the feed sets one.
+const DefaultUserAgent = "RSSBot/1.0 (+https://github.com/haytac/rss-telegram-bot)"
 
 // GoFeedFetcher implements FeedFetcher using gofeed.
 type GoFeedFetcher struct {
-	clientFactory interfaces.HTTPClientFactory
+	clientFactory  interfaces.HTTPClientFactory
+	userAgent      string
+	maxBodySize    int64
+	retry          RetryPolicy
+	hosts          atomic.Pointer[hostLimiter]                                    // nil when per-host rate limiting is off
+	solver         *FlareSolverr                                                  // nil when no FlareSolverr instance is configured
+	dial           func(ctx context.Context, network, addr string) (net.Conn, error) // Non-HTTP connections (IMAP)
+	cache          *fetchCache                                                    // nil when fetch results aren't shared between feeds
+	directFallback bool                                                           // Retry fetches that can't connect through a proxy without it, unless the proxy or feed says otherwise
+}
+
+// NewGoFeedFetcher creates a new GoFeedFetcher. An empty userAgent uses DefaultUserAgent.
+func NewGoFeedFetcher(clientFactory interfaces.HTTPClientFactory, userAgent string) *GoFeedFetcher {
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	return &GoFeedFetcher{clientFactory: clientFactory, userAgent: userAgent, maxBodySize: DefaultMaxBodySize, retry: DefaultRetryPolicy}
+}
+
+// WithRetryPolicy sets the global retry policy. Zero durations and a negative
+// MaxRetries keep the defaults.
+func (f *GoFeedFetcher) WithRetryPolicy(p RetryPolicy) *GoFeedFetcher {
+	f.retry = f.retry.merge(p.MaxRetries, p.InitialDelay, p.MaxDelay, p.Timeout)
+	return f
+}
+
+// WithHostRateLimit limits requests to each host to perMinute (0 for no default
+// limit) and spaces them at least minInterval apart (0 for no spacing).
+// overrides sets budgets per domain, e.g. {"reddit.com": 10}, shared by the
+// domain and all of its subdomains. It may be called again while fetches run
+// to change the limits.
+func (f *GoFeedFetcher) WithHostRateLimit(perMinute int, minInterval time.Duration, overrides map[string]int) *GoFeedFetcher {
+	var hosts *hostLimiter
+	if perMinute > 0 || minInterval > 0 || len(overrides) > 0 {
+		hosts = newHostLimiter(perMinute, minInterval, overrides)
+	}
+	f.hosts.Store(hosts)
+	return f
+}
+
+// WithFlareSolverr routes feeds flagged for it, and fetches answered with a
+// Cloudflare challenge, through solver.
+func (f *GoFeedFetcher) WithFlareSolverr(solver *FlareSolverr) *GoFeedFetcher {
+	f.solver = solver
+	return f
+}
+
+// WithDirectFallback lets fetches that can't connect through a proxy retry
+// without it. A proxy's or feed's own setting takes precedence.
+func (f *GoFeedFetcher) WithDirectFallback(enabled bool) *GoFeedFetcher {
+	f.directFallback = enabled
+	return f
+}
+
+// WithDialer makes connections that don't go through the HTTP client factory,
+// such as IMAP, dial with dial, e.g. to honor the same network settings.
+func (f *GoFeedFetcher) WithDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) *GoFeedFetcher {
+	f.dial = dial
+	return f
+}
+
+// WithFetchCache shares each fetched feed for ttl between feeds polling the
+// same URL with the same proxy and request settings, so it is downloaded and
+// parsed once. ttl <= 0 disables sharing.
+func (f *GoFeedFetcher) WithFetchCache(ttl time.Duration) *GoFeedFetcher {
+	f.cache = nil
+	if ttl > 0 {
+		f.cache = newFetchCache(ttl)
+	}
+	return f
+}
+
+// policyFor applies a feed's overrides to the global retry policy.
+func (f *GoFeedFetcher) policyFor(opts interfaces.FetchOptions) RetryPolicy {
+	maxRetries := -1
+	if opts.MaxRetries != nil {
+		maxRetries = *opts.MaxRetries
+	}
+	return f.retry.merge(maxRetries, opts.RetryDelay, 0, opts.Timeout)
+}
+
+// merge returns p with every set (non-negative retries, positive duration) argument applied.
+func (p RetryPolicy) merge(maxRetries int, initialDelay, maxDelay, timeout time.Duration) RetryPolicy {
+	if maxRetries >= 0 {
+		p.MaxRetries = maxRetries
+	}
+	if initialDelay > 0 {
+		p.InitialDelay = initialDelay
+	}
+	if maxDelay > 0 {
+		p.MaxDelay = maxDelay
+	}
+	if timeout > 0 {
+		p.Timeout = timeout
+	}
+	if p.MaxDelay < p.InitialDelay {
+		p.MaxDelay = p.InitialDelay
+	}
+	return p
+}
+
+// WithMaxBodySize caps how many bytes of a (decompressed) response are read;
+// larger responses fail with ErrResponseTooLarge. n <= 0 keeps DefaultMaxBodySize.
+func (f *GoFeedFetcher) WithMaxBodySize(n int64) *GoFeedFetcher {
+	if n > 0 {
+		f.maxBodySize = n
+	}
+	return f
+}
+
+// Fetch retrieves an RSS feed with retries, or shares a recent fetch of it
+// when a fetch cache is configured.
+func (f *GoFeedFetcher) Fetch(ctx context.Context, url string, etag, lastModified *string, proxy *database.Proxy, opts interfaces.FetchOptions) (*interfaces.FetchResult, error) {
+	if f.cache != nil {
+		return f.cache.do(ctx, fetchCacheKey(url, proxy, opts), etag, lastModified, opts.BodyHash, func() (*interfaces.FetchResult, error) {
+			return f.fetchFeed(ctx, url, etag, lastModified, proxy, opts)
+		})
+	}
+	return f.fetchFeed(ctx, url, etag, lastModified, proxy, opts)
+}
+
+func (f *GoFeedFetcher) fetchFeed(ctx context.Context, url string, etag, lastModified *string, proxy *database.Proxy, opts interfaces.FetchOptions) (*interfaces.FetchResult, error) {
+	if adapter := AdapterFor(url); adapter != nil {
+		return f.fetchAdapted(ctx, url, adapter, etag, lastModified, proxy, opts)
+	}
+	return f.fetch(ctx, url, etag, lastModified, proxy, opts, feedAcceptHeader, func(body io.Reader) (*gofeed.Feed, error) {
+		return ParseFeed(body)
+	})
+}
+
+// fetchAdapted fetches the URL a source adapter maps url to and builds the feed with the adapter.
+func (f *GoFeedFetcher) fetchAdapted(ctx context.Context, url string, adapter SourceAdapter, etag, lastModified *string, proxy *database.Proxy, opts interfaces.FetchOptions) (*interfaces.FetchResult, error) {
+	target, err := ResolveSourceURL(url)
+	if err != nil {
+		return nil, err
+	}
+	result, err := f.fetch(ctx, target, etag, lastModified, proxy, opts, adapter.Accept(), func(body io.Reader) (*gofeed.Feed, error) {
+		return adapter.Parse(body, target)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.PermanentURL = "" // The adapter URL stays the feed's identity
+	return result, nil
 }
 
-// NewGoFeedFetcher creates a new GoFeedFetcher.
-func NewGoFeedFetcher(clientFactory interfaces.HTTPClientFactory) *GoFeedFetcher {
-	return &GoFeedFetcher{clientFactory: clientFactory}
+// FetchScrape retrieves an HTML page with retries and synthesizes a feed from it
+// using the configured CSS selectors.
+func (f *GoFeedFetcher) FetchScrape(ctx context.Context, url string, cfg *database.ScrapeConfig, etag, lastModified *string, proxy *database.Proxy, opts interfaces.FetchOptions) (*interfaces.FetchResult, error) {
+	if cfg == nil || cfg.ItemSelector == "" {
+		return nil, fmt.Errorf("scrape feed %s has no item selector configured", url)
+	}
+	return f.fetch(ctx, url, etag, lastModified, proxy, opts, htmlAcceptHeader, func(body io.Reader) (*gofeed.Feed, error) {
+		return ScrapeHTML(body, url, cfg)
+	})
+}
+
+// fetch performs a conditional GET with retries and hands a 200 response body
+// to parse. When the request can't get through proxy and direct fallback is
+// allowed for it, the fetch is repeated over a direct connection.
+func (f *GoFeedFetcher) fetch(ctx context.Context, url string, etag, lastModified *string, proxy *database.Proxy, opts interfaces.FetchOptions, accept string, parse func(io.Reader) (*gofeed.Feed, error)) (*interfaces.FetchResult, error) {
+	result, err := f.fetchThrough(ctx, url, etag, lastModified, proxy, opts, accept, parse)
+	var connErr net.Error // Transport failures (*url.Error); HTTP status and parse errors aren't
+	if err == nil || proxy == nil || ctx.Err() != nil || !errors.As(err, &connErr) || !proxy.AllowsDirectFallback(f.directFallback) {
+		return result, err
+	}
+	log.Warn().Err(err).Str("feed_url", url).Str("proxy", proxy.Name).Msg("Fetch through proxy failed; retrying over a direct connection")
+	return f.fetchThrough(ctx, url, etag, lastModified, nil, opts, accept, parse)
 }
 
-// Fetch retrieves an RSS feed with retries.
-func (f *GoFeedFetcher) Fetch(ctx context.Context, url string, etag, lastModified *string, proxy *database.Proxy) (*interfaces.FetchResult, error) {
+// fetchThrough performs a conditional GET through proxy (nil for a direct
+// connection) with retries and hands a 200 response body to parse, unless it
+// hashes the same as opts.BodyHash, which is reported like a 304.
+func (f *GoFeedFetcher) fetchThrough(ctx context.Context, url string, etag, lastModified *string, proxy *database.Proxy, opts interfaces.FetchOptions, accept string, parse func(io.Reader) (*gofeed.Feed, error)) (*interfaces.FetchResult, error) {
+	if opts.UseFlareSolverr {
+		if f.solver == nil {
+			return nil, fmt.Errorf("feed %s requires FlareSolverr, but flaresolverr.url is not configured", url)
+		}
+		return f.fetchViaSolver(ctx, url, proxy, opts, parse)
+	}
+
 	var lastErr error
-	currentDelay := initialRetryDelay // Now defined
+	policy := f.policyFor(opts)
+	currentDelay := policy.InitialDelay
 
-	for attempt := 0; attempt <= maxFetchRetries; attempt++ { // Now defined
+	for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
 		if attempt > 0 {
 			log.Warn().Str("feed_url", url).Int("attempt", attempt).Dur("delay", currentDelay).Msg("Retrying fetch after error")
 			select {
 			case <-time.After(currentDelay):
 				currentDelay *= 2
-				if currentDelay > maxRetryDelay { // Now defined
-					currentDelay = maxRetryDelay // Now defined
+				if currentDelay > policy.MaxDelay {
+					currentDelay = policy.MaxDelay
 				}
 			case <-ctx.Done():
 				return nil, fmt.Errorf("fetch context cancelled during retry backoff for %s: %w", url, ctx.Err())
 			}
 		}
 
-		httpClient, errClient := f.clientFactory.GetClient(proxy)
+		if hosts := f.hosts.Load(); hosts != nil {
+			if err := hosts.Wait(ctx, url); err != nil {
+				return nil, fmt.Errorf("waiting for host rate limit for %s: %w", url, err)
+			}
+		}
+
+		sharedClient, errClient := f.clientFor(proxy, opts)
 		if errClient != nil {
 			return nil, fmt.Errorf("failed to get HTTP client for %s: %w", url, errClient)
 		}
+		httpClient := *sharedClient // Shallow copy so the feed's timeout doesn't leak to other users
+		httpClient.Timeout = policy.Timeout
+		var redirects redirectTracker
+		httpClient.CheckRedirect = redirects.checkRedirect(sharedClient.CheckRedirect, authHeader(opts))
 
 		req, errReq := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if errReq != nil {
@@ -67,26 +266,40 @@ func (f *GoFeedFetcher) Fetch(ctx context.Context, url string, etag, lastModifie
 		if lastModified != nil && *lastModified != "" {
 			req.Header.Set("If-Modified-Since", *lastModified)
 		}
-		req.Header.Set("User-Agent", "RSSBot/1.0 (+https://your.bot.contact.info)")
+		req.Header.Set("User-Agent", f.userAgent)
+		req.Header.Set("Accept", accept)
+		req.Header.Set("Accept-Encoding", acceptEncodingHeader)
+		applyFetchOptions(req, opts)
+		applyCookies(&httpClient, req, opts.Cookies)
 
 		resp, errDo := httpClient.Do(req)
 		if errDo != nil {
 			lastErr = fmt.Errorf("attempt %d: failed to fetch feed %s: %w", attempt, url, errDo)
-			if errors.Is(errDo, context.Canceled) || errors.Is(errDo, context.DeadlineExceeded) {
+			if ctx.Err() != nil { // The caller gave up; a per-request timeout is retried
 				return nil, lastErr
 			}
 			continue
 		}
+		// Only feeds being debugged carry a logger in ctx
+		zerolog.Ctx(ctx).Debug().Str("feed_url", url).Int("attempt", attempt).Int("status", resp.StatusCode).
+			Interface("request_headers", redactHeaders(req.Header, authHeader(opts))).Interface("response_headers", redactHeaders(resp.Header)).Msg("Feed response")
 
 		if resp.StatusCode == http.StatusNotModified {
 			log.Debug().Str("feed_url", url).Msg("Feed not modified (304)")
 			resp.Body.Close()
-			return &interfaces.FetchResult{Feed: nil, NewEtag: etag, NewLastModified: lastModified}, nil
+			return &interfaces.FetchResult{Feed: nil, NewEtag: etag, NewLastModified: lastModified, PermanentURL: redirects.permanentURL(resp)}, nil
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 32<<10))
 			resp.Body.Close()
+			if f.solver != nil && isCloudflareChallenge(resp, bodyBytes) {
+				log.Info().Str("feed_url", url).Int("status", resp.StatusCode).Msg("Cloudflare challenge detected, retrying through FlareSolverr")
+				return f.fetchViaSolver(ctx, url, proxy, opts, parse)
+			}
+			if len(bodyBytes) > 1024 {
+				bodyBytes = bodyBytes[:1024]
+			}
 			lastErr = fmt.Errorf("attempt %d: failed to fetch feed %s: status %d, body: %s", attempt, url, resp.StatusCode, string(bodyBytes))
 			if resp.StatusCode >= 400 && resp.StatusCode < 500 {
 				return nil, lastErr
@@ -94,9 +307,23 @@ func (f *GoFeedFetcher) Fetch(ctx context.Context, url string, etag, lastModifie
 			continue
 		}
 
-		fp := gofeed.NewParser()
-		feed, errParse := fp.Parse(resp.Body)
+		body, errBody := readBody(resp, f.maxBodySize)
 		resp.Body.Close()
+		if errBody != nil {
+			lastErr = fmt.Errorf("attempt %d: failed to read feed %s: %w", attempt, url, errBody)
+			if errors.Is(errBody, ErrResponseTooLarge) || errors.Is(errBody, ErrUnexpectedContentType) {
+				return nil, lastErr // Retrying would download the same thing again
+			}
+			continue
+		}
+		newBodyHash := bodyHash(body)
+		if newBodyHash == opts.BodyHash {
+			log.Debug().Str("feed_url", url).Msg("Feed body unchanged")
+			return &interfaces.FetchResult{Feed: nil, NewEtag: etag, NewLastModified: lastModified, NewBodyHash: &newBodyHash, PermanentURL: redirects.permanentURL(resp)}, nil
+		}
+		parseStart := time.Now()
+		feed, errParse := parse(bytes.NewReader(body))
+		parseDuration := time.Since(parseStart)
 		if errParse != nil {
 			lastErr = fmt.Errorf("attempt %d: failed to parse feed %s: %w", attempt, url, errParse)
 			continue
@@ -104,17 +331,180 @@ func (f *GoFeedFetcher) Fetch(ctx context.Context, url string, etag, lastModifie
 
 		newEtagHeader := resp.Header.Get("ETag")
 		newLastModifiedHeader := resp.Header.Get("Last-Modified")
+		hub, topic := WebSubLinks(feed, resp.Header)
+		if topic == "" {
+			topic = url
+		}
 		return &interfaces.FetchResult{
 			Feed:            feed,
 			NewEtag:         &newEtagHeader,
 			NewLastModified: &newLastModifiedHeader,
+			NewBodyHash:     &newBodyHash,
+			HubURL:          hub,
+			TopicURL:        topic,
+			PermanentURL:    redirects.permanentURL(resp),
+			ParseDuration:   parseDuration,
 		}, nil
 	}
-	return nil, fmt.Errorf("all %d fetch attempts failed for %s: last error: %w", maxFetchRetries+1, url, lastErr) // Now defined
+	return nil, fmt.Errorf("all %d fetch attempts failed for %s: last error: %w", policy.MaxRetries+1, url, lastErr)
+}
+
+// fetchViaSolver loads url through FlareSolverr and parses the page it returns.
+// The browser doesn't support conditional requests, so the result carries no
+// validators; only an unchanged body hash spares parsing it again.
+func (f *GoFeedFetcher) fetchViaSolver(ctx context.Context, url string, proxy *database.Proxy, opts interfaces.FetchOptions, parse func(io.Reader) (*gofeed.Feed, error)) (*interfaces.FetchResult, error) {
+	body, header, err := f.solver.Get(ctx, url, proxy, opts.Cookies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed %s through FlareSolverr: %w", url, err)
+	}
+	if int64(len(body)) > f.maxBodySize {
+		return nil, fmt.Errorf("failed to fetch feed %s through FlareSolverr: %w: body exceeds the %d byte limit", url, ErrResponseTooLarge, f.maxBodySize)
+	}
+	var noValidator string
+	newBodyHash := bodyHash(body)
+	if newBodyHash == opts.BodyHash {
+		return &interfaces.FetchResult{NewEtag: &noValidator, NewLastModified: &noValidator, NewBodyHash: &newBodyHash}, nil
+	}
+	parseStart := time.Now()
+	feed, err := parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse feed %s fetched through FlareSolverr: %w", url, err)
+	}
+	parseDuration := time.Since(parseStart)
+	hub, topic := WebSubLinks(feed, header)
+	if topic == "" {
+		topic = url
+	}
+	return &interfaces.FetchResult{Feed: feed, NewEtag: &noValidator, NewLastModified: &noValidator, NewBodyHash: &newBodyHash, HubURL: hub, TopicURL: topic, ParseDuration: parseDuration}, nil
+}
+
+// bodyHash identifies a response body, for telling unchanged feeds from
+// servers that send no validators.
+func bodyHash(body []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(body))
+}
+
+// redactHeaders returns a copy of h for logging, with credentials, and the
+// secret headers named in extra, removed.
+func redactHeaders(h http.Header, extra ...string) http.Header {
+	out := h.Clone()
+	for _, name := range append([]string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}, extra...) {
+		if _, ok := out[http.CanonicalHeaderKey(name)]; ok {
+			out[http.CanonicalHeaderKey(name)] = []string{"[redacted]"}
+		}
+	}
+	return out
+}
+
+// authHeader names the header carrying a feed's secret under header auth.
+func authHeader(opts interfaces.FetchOptions) string {
+	if opts.Auth == nil || opts.Auth.Type != database.FeedAuthHeader {
+		return ""
+	}
+	return opts.Auth.Header
+}
+
+// clientFor returns the feed's HTTP client, with its cookie jar and TLS settings when supported.
+func (f *GoFeedFetcher) clientFor(proxy *database.Proxy, opts interfaces.FetchOptions) (*http.Client, error) {
+	if feedFactory, ok := f.clientFactory.(interfaces.FeedHTTPClientFactory); ok && (opts.FeedID != 0 || opts.TLS != nil) {
+		return feedFactory.GetClientForFeed(proxy, opts.FeedID, opts.TLS)
+	}
+	return f.clientFactory.GetClient(proxy)
+}
+
+// applyCookies seeds configured cookies into the client's jar unless the jar
+// already holds a cookie of that name for the URL (a server-refreshed session
+// wins over the configured value). Without a jar they are sent directly.
+func applyCookies(client *http.Client, req *http.Request, cookies map[string]string) {
+	if len(cookies) == 0 {
+		return
+	}
+	if client.Jar == nil {
+		for name, value := range cookies {
+			req.AddCookie(&http.Cookie{Name: name, Value: value})
+		}
+		return
+	}
+	have := make(map[string]bool)
+	for _, c := range client.Jar.Cookies(req.URL) {
+		have[c.Name] = true
+	}
+	var seed []*http.Cookie
+	for name, value := range cookies {
+		if !have[name] {
+			seed = append(seed, &http.Cookie{Name: name, Value: value, Path: "/"})
+		}
+	}
+	if len(seed) > 0 {
+		client.Jar.SetCookies(req.URL, seed)
+	}
 }
 
-// GetNewItems function (ensure this is correct from previous steps)
-func GetNewItems(feedData *gofeed.Feed, isItemProcessedFunc func(itemGUIDHash string) (bool, error)) ([]*gofeed.Item, string, error) {
+// applyFetchOptions applies a feed's User-Agent, extra headers, and credentials to req.
+func applyFetchOptions(req *http.Request, opts interfaces.FetchOptions) {
+	if opts.UserAgent != "" {
+		req.Header.Set("User-Agent", opts.UserAgent)
+	}
+	for name, value := range opts.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	if opts.Auth != nil {
+		switch opts.Auth.Type {
+		case database.FeedAuthBasic:
+			req.SetBasicAuth(opts.Auth.Username, opts.Auth.Secret)
+		case database.FeedAuthBearer:
+			req.Header.Set("Authorization", "Bearer "+opts.Auth.Secret)
+		case database.FeedAuthHeader:
+			req.Header.Set(opts.Auth.Header, opts.Auth.Secret)
+		}
+	}
+}
+
+// NewestItemTime returns the latest published (or updated) time among the feed's
+// items, ignoring dates in the future. It returns nil if no item is dated.
+func NewestItemTime(feed *gofeed.Feed) *time.Time {
+	if feed == nil {
+		return nil
+	}
+	var newest *time.Time
+	now := time.Now()
+	for _, item := range feed.Items {
+		t := item.PublishedParsed
+		if t == nil {
+			t = item.UpdatedParsed
+		}
+		if t == nil || t.After(now) {
+			continue
+		}
+		if newest == nil || t.After(*newest) {
+			newest = t
+		}
+	}
+	return newest
+}
+
+// ItemHash returns the hash an item is recorded as processed under: the
+// SHA-256 of its GUID, or of its link without one. Items with neither have no
+// hash and return "".
+func ItemHash(item *gofeed.Item) string {
+	identifier := item.GUID
+	if identifier == "" {
+		identifier = item.Link
+	}
+	if identifier == "" {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(identifier)))
+}
+
+// GetNewItems returns the items of feedData not processed yet, oldest first,
+// and the hash of the newest item in the feed. filterUnprocessed is called
+// once with the hashes of all items and returns those not processed yet.
+func GetNewItems(feedData *gofeed.Feed, filterUnprocessed func(itemGUIDHashes []string) ([]string, error)) ([]*gofeed.Item, string, error) {
     var newItems []*gofeed.Item
     var latestItemHash string // This will be the hash of the newest item in the current fetch data
 
@@ -139,38 +529,35 @@ func GetNewItems(feedData *gofeed.Feed, isItemProcessedFunc func(itemGUIDHash st
     // We'll use its hash as the potential new "high water mark" for the feed's LastProcessedItemGUIDHash
     // if no *new* items are actually sent.
     if len(feedData.Items) > 0 {
-        newestItemInFeed := feedData.Items[0]
-        identifier := newestItemInFeed.GUID
-        if identifier == "" {
-            identifier = newestItemInFeed.Link
-        }
-        if identifier != "" {
-            latestItemHash = fmt.Sprintf("%x", sha256.Sum256([]byte(identifier)))
-        }
+        latestItemHash = ItemHash(feedData.Items[0])
     }
 
 
+    // Hash every item first so that all of them are looked up in one go.
+    items := make([]*gofeed.Item, 0, len(feedData.Items))
+    hashes := make([]string, 0, len(feedData.Items))
     for _, item := range feedData.Items {
-        itemIdentifier := item.GUID
-        if itemIdentifier == "" {
-            itemIdentifier = item.Link
-        }
-        if itemIdentifier == "" {
+        itemHash := ItemHash(item)
+        if itemHash == "" {
             log.Warn().Str("item_title", item.Title).Msg("Item has no GUID or Link, cannot process.")
             continue
         }
+        items = append(items, item)
+        hashes = append(hashes, itemHash)
+    }
 
-        hash := fmt.Sprintf("%x", sha256.Sum256([]byte(itemIdentifier)))
-
-        processed, err := isItemProcessedFunc(hash)
-        if err != nil {
-            return nil, "", fmt.Errorf("checking if item processed (hash %s): %w", hash, err)
-        }
-
-        if !processed {
+    unprocessed, err := filterUnprocessed(hashes)
+    if err != nil {
+        return nil, "", fmt.Errorf("checking which items were processed: %w", err)
+    }
+    isNew := make(map[string]bool, len(unprocessed))
+    for _, hash := range unprocessed {
+        isNew[hash] = true
+    }
+    for i, item := range items {
+        if isNew[hashes[i]] {
             newItems = append(newItems, item)
-            // No need to update latestItemHash here based on *new* items,
-            // latestItemHash already reflects the newest item from the entire fetched feed.
+            delete(isNew, hashes[i]) // Items sharing a GUID are delivered once
         }
     }
 
@@ -0,0 +1,310 @@
+package rss
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticClientFactory hands out one client regardless of proxy.
+type staticClientFactory struct{ client *http.Client }
+
+func (f staticClientFactory) GetClient(*database.Proxy) (*http.Client, error) { return f.client, nil }
+
+const testRSS = `<rss version="2.0"><channel><title>T</title><item><guid>1</guid><title>A</title></item></channel></rss>`
+
+func TestFetch_UserAgentAndHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "GlobalAgent/1.0")
+
+	_, err := fetcher.Fetch(context.Background(), srv.URL, nil, nil, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "GlobalAgent/1.0", got.Get("User-Agent"))
+
+	opts := interfaces.FetchOptions{UserAgent: "FeedAgent/2.0", Headers: map[string]string{"X-Api-Key": "k", "Accept": "application/rss+xml"}}
+	res, err := fetcher.Fetch(context.Background(), srv.URL, nil, nil, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "FeedAgent/2.0", got.Get("User-Agent"))
+	assert.Equal(t, "k", got.Get("X-Api-Key"))
+	assert.Equal(t, "application/rss+xml", got.Get("Accept"))
+	require.Len(t, res.Feed.Items, 1)
+}
+
+func TestFetch_Auth(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "")
+
+	_, err := fetcher.Fetch(context.Background(), srv.URL, nil, nil, nil, interfaces.FetchOptions{
+		Auth: &database.FeedCredentials{Type: database.FeedAuthBasic, Username: "alice", Secret: "pw"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Basic YWxpY2U6cHc=", gotAuth)
+
+	_, err = fetcher.Fetch(context.Background(), srv.URL, nil, nil, nil, interfaces.FetchOptions{
+		Auth: &database.FeedCredentials{Type: database.FeedAuthBearer, Secret: "tok"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer tok", gotAuth)
+
+	var gotKey string
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotKey = r.Header.Get("Authorization"), r.Header.Get("X-Api-Key")
+		_, _ = w.Write([]byte(testRSS))
+	})
+	opts := interfaces.FetchOptions{Auth: &database.FeedCredentials{Type: database.FeedAuthHeader, Header: "X-Api-Key", Secret: "key"}}
+	_, err = fetcher.Fetch(context.Background(), srv.URL, nil, nil, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "key", gotKey)
+	assert.Empty(t, gotAuth)
+
+	req := httptest.NewRequest(http.MethodGet, srv.URL, nil)
+	applyFetchOptions(req, opts)
+	assert.Equal(t, []string{"[redacted]"}, redactHeaders(req.Header, authHeader(opts))["X-Api-Key"], "the secret header stays out of debug logs")
+}
+
+func TestFetch_UnchangedBody(t *testing.T) {
+	body := testRSS
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip") // No ETag or Last-Modified
+		_, _ = w.Write(compress(t, "gzip", []byte(body)))
+	}))
+	defer srv.Close()
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "")
+	ctx := context.Background()
+
+	res, err := fetcher.Fetch(ctx, srv.URL, nil, nil, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, res.Feed)
+	require.NotNil(t, res.NewBodyHash)
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256([]byte(testRSS))), *res.NewBodyHash, "the decompressed body is hashed")
+
+	opts := interfaces.FetchOptions{BodyHash: *res.NewBodyHash}
+	res, err = fetcher.Fetch(ctx, srv.URL, nil, nil, nil, opts)
+	require.NoError(t, err)
+	assert.Nil(t, res.Feed, "an unchanged body is reported as not modified")
+	assert.Equal(t, opts.BodyHash, *res.NewBodyHash)
+
+	body = strings.Replace(testRSS, "<title>A</title>", "<title>B</title>", 1)
+	res, err = fetcher.Fetch(ctx, srv.URL, nil, nil, nil, opts)
+	require.NoError(t, err)
+	require.NotNil(t, res.Feed)
+	assert.NotEqual(t, opts.BodyHash, *res.NewBodyHash)
+}
+
+func TestNewestItemTime(t *testing.T) {
+	older := time.Now().Add(-48 * time.Hour)
+	newer := time.Now().Add(-time.Hour)
+	future := time.Now().Add(24 * time.Hour)
+	feed := &gofeed.Feed{Items: []*gofeed.Item{
+		{PublishedParsed: &older},
+		{UpdatedParsed: &newer},
+		{PublishedParsed: &future},
+		{},
+	}}
+	got := NewestItemTime(feed)
+	require.NotNil(t, got)
+	assert.True(t, got.Equal(newer))
+
+	assert.Nil(t, NewestItemTime(&gofeed.Feed{Items: []*gofeed.Item{{}}}))
+	assert.Nil(t, NewestItemTime(nil))
+}
+
+func TestItemHash(t *testing.T) {
+	hash := func(s string) string { return fmt.Sprintf("%x", sha256.Sum256([]byte(s))) }
+	assert.Equal(t, hash("guid"), ItemHash(&gofeed.Item{GUID: "guid", Link: "https://example.com/1"}))
+	assert.Equal(t, hash("https://example.com/1"), ItemHash(&gofeed.Item{Link: "https://example.com/1"}))
+	assert.Empty(t, ItemHash(&gofeed.Item{Title: "No identifier"}))
+}
+
+func TestGetNewItems(t *testing.T) {
+	day1 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2, day3 := day1.AddDate(0, 0, 1), day1.AddDate(0, 0, 2)
+	feed := &gofeed.Feed{Items: []*gofeed.Item{
+		{GUID: "b", PublishedParsed: &day2},
+		{GUID: "c", PublishedParsed: &day3},
+		{Title: "no identifier"},
+		{Link: "https://example.com/a", PublishedParsed: &day1},
+		{GUID: "c", PublishedParsed: &day1}, // Repeated GUID
+	}}
+	hash := func(id string) string { return fmt.Sprintf("%x", sha256.Sum256([]byte(id))) }
+
+	calls := 0
+	newItems, latest, err := GetNewItems(feed, func(hashes []string) ([]string, error) {
+		calls++
+		assert.Len(t, hashes, 4)
+		var unprocessed []string
+		for _, h := range hashes {
+			if h != hash("b") {
+				unprocessed = append(unprocessed, h)
+			}
+		}
+		return unprocessed, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls, "all items are looked up at once")
+	assert.Equal(t, hash("c"), latest)
+	require.Len(t, newItems, 2)
+	assert.Equal(t, "https://example.com/a", newItems[0].Link, "oldest first")
+	assert.Equal(t, "c", newItems[1].GUID)
+	assert.Equal(t, &day3, newItems[1].PublishedParsed)
+}
+
+func TestFetch_RetryPolicy(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "").WithRetryPolicy(RetryPolicy{MaxRetries: 2, InitialDelay: time.Millisecond})
+	_, err := fetcher.Fetch(context.Background(), srv.URL, nil, nil, nil, interfaces.FetchOptions{})
+	require.Error(t, err)
+	assert.EqualValues(t, 3, hits.Load())
+
+	hits.Store(0)
+	noRetries := 0
+	_, err = fetcher.Fetch(context.Background(), srv.URL, nil, nil, nil, interfaces.FetchOptions{MaxRetries: &noRetries})
+	require.Error(t, err)
+	assert.EqualValues(t, 1, hits.Load(), "per-feed override disables retries")
+}
+
+func TestFetch_TimeoutIsRetried(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "").WithRetryPolicy(RetryPolicy{MaxRetries: 1, InitialDelay: time.Millisecond})
+	res, err := fetcher.Fetch(context.Background(), srv.URL, nil, nil, nil, interfaces.FetchOptions{Timeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, hits.Load())
+	assert.Equal(t, "T", res.Feed.Title)
+}
+
+func TestFetch_PermanentRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/temp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/old", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testRSS))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "")
+	res, err := fetcher.Fetch(context.Background(), srv.URL+"/old", nil, nil, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/new", res.PermanentURL)
+
+	res, err = fetcher.Fetch(context.Background(), srv.URL+"/temp", nil, nil, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, res.PermanentURL, "a temporary hop in the chain keeps the stored URL")
+
+	res, err = fetcher.Fetch(context.Background(), srv.URL+"/new", nil, nil, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, res.PermanentURL)
+}
+
+func TestFetch_AuthHeaderStaysOnFeedHost(t *testing.T) {
+	var gotKey, offSiteKey string
+	offSite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offSiteKey = r.Header.Get("X-Api-Key")
+		w.Write([]byte(testRSS))
+	}))
+	defer offSite.Close()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/feed", http.StatusFound)
+	})
+	mux.HandleFunc("/away", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, offSite.URL+"/feed", http.StatusFound)
+	})
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("X-Api-Key")
+		w.Write([]byte(testRSS))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fetcher := NewGoFeedFetcher(staticClientFactory{&http.Client{}}, "")
+	opts := interfaces.FetchOptions{Auth: &database.FeedCredentials{Type: database.FeedAuthHeader, Header: "X-Api-Key", Secret: "key"}}
+	_, err := fetcher.Fetch(context.Background(), srv.URL+"/moved", nil, nil, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "key", gotKey, "redirects on the feed's host keep the header")
+
+	_, err = fetcher.Fetch(context.Background(), srv.URL+"/away", nil, nil, nil, opts)
+	require.NoError(t, err)
+	assert.Empty(t, offSiteKey, "the secret isn't sent to another host")
+}
+
+// proxyingClientFactory sends requests through the given proxy's address, or
+// directly with the direct client when there is none.
+type proxyingClientFactory struct{ direct *http.Client }
+
+func (f proxyingClientFactory) GetClient(p *database.Proxy) (*http.Client, error) {
+	if p == nil {
+		return f.direct, nil
+	}
+	return &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(&url.URL{Scheme: "http", Host: p.Address})}}, nil
+}
+
+func TestFetch_DirectFallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+	dead, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	deadProxy := &database.Proxy{Name: "dead", Type: "http", Address: dead.Addr().String()}
+	dead.Close()
+
+	noRetries := 0
+	opts := interfaces.FetchOptions{MaxRetries: &noRetries}
+	fetcher := NewGoFeedFetcher(proxyingClientFactory{srv.Client()}, "")
+	_, err = fetcher.Fetch(context.Background(), srv.URL, nil, nil, deadProxy, opts)
+	assert.Error(t, err, "no fallback by default")
+
+	fetcher.WithDirectFallback(true)
+	result, err := fetcher.Fetch(context.Background(), srv.URL, nil, nil, deadProxy, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "T", result.Feed.Title)
+
+	off := false
+	deadProxy.DirectFallback = &off
+	_, err = fetcher.Fetch(context.Background(), srv.URL, nil, nil, deadProxy, opts)
+	assert.Error(t, err, "the proxy's setting wins over the fetcher's")
+}
@@ -0,0 +1,163 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+	"github.com/mmcdole/gofeed"
+	"github.com/rs/zerolog/log"
+)
+
+// FlareSolverr fetches pages through a FlareSolverr instance, which loads them
+// in a headless browser to get past Cloudflare challenges.
+type FlareSolverr struct {
+	endpoint   string // e.g. http://flaresolverr:8191/v1
+	maxTimeout time.Duration
+	client     *http.Client
+}
+
+// NewFlareSolverr creates a FlareSolverr client for endpoint. maxTimeout bounds
+// how long the browser may spend on a challenge (default 60s).
+func NewFlareSolverr(endpoint string, maxTimeout time.Duration) *FlareSolverr {
+	if maxTimeout <= 0 {
+		maxTimeout = 60 * time.Second
+	}
+	return &FlareSolverr{
+		endpoint:   endpoint,
+		maxTimeout: maxTimeout,
+		client:     &http.Client{Timeout: maxTimeout + 30*time.Second},
+	}
+}
+
+type flareSolverrCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type flareSolverrProxy struct {
+	URL      string `json:"url"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type flareSolverrRequest struct {
+	Cmd        string               `json:"cmd"`
+	URL        string               `json:"url"`
+	MaxTimeout int64                `json:"maxTimeout"` // Milliseconds
+	Cookies    []flareSolverrCookie `json:"cookies,omitempty"`
+	Proxy      *flareSolverrProxy   `json:"proxy,omitempty"`
+}
+
+type flareSolverrResponse struct {
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+	Solution struct {
+		URL      string            `json:"url"`
+		Status   int               `json:"status"`
+		Headers  map[string]string `json:"headers"`
+		Response string            `json:"response"`
+	} `json:"solution"`
+}
+
+// Get loads pageURL through FlareSolverr, using proxy p and cookies if given, and
+// returns the page body and the response headers the browser saw.
+func (s *FlareSolverr) Get(ctx context.Context, pageURL string, p *database.Proxy, cookies map[string]string) ([]byte, http.Header, error) {
+	payload := flareSolverrRequest{Cmd: "request.get", URL: pageURL, MaxTimeout: s.maxTimeout.Milliseconds()}
+	for name, value := range cookies {
+		payload.Cookies = append(payload.Cookies, flareSolverrCookie{Name: name, Value: value})
+	}
+	if p != nil && p.Type == "pac" {
+		log.Debug().Str("proxy", p.Name).Msg("FlareSolverr can't use PAC proxies; its own network settings apply")
+	} else if p != nil && p.Address != "" {
+		payload.Proxy = &flareSolverrProxy{URL: p.Type + "://" + p.Address}
+		username, password, hasAuth, err := proxy.Credentials(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		if hasAuth {
+			payload.Proxy.Username, payload.Proxy.Password = username, password
+		}
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encoding FlareSolverr request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating FlareSolverr request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("calling FlareSolverr: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out flareSolverrResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, DefaultMaxBodySize)).Decode(&out); err != nil {
+		return nil, nil, fmt.Errorf("decoding FlareSolverr response (status %d): %w", resp.StatusCode, err)
+	}
+	if out.Status != "ok" {
+		return nil, nil, fmt.Errorf("FlareSolverr: %s", out.Message)
+	}
+	if out.Solution.Status != http.StatusOK {
+		return nil, nil, fmt.Errorf("FlareSolverr: %s answered with status %d", pageURL, out.Solution.Status)
+	}
+	header := http.Header{}
+	for name, value := range out.Solution.Headers {
+		header.Set(name, value)
+	}
+	return unwrapBrowserView([]byte(out.Solution.Response)), header, nil
+}
+
+// unwrapBrowserView recovers a feed document from a browser's rendering of it.
+// Chrome shows XML and JSON served as text inside a <pre> element, so the
+// FlareSolverr response is an HTML page around the original document. Other
+// documents, including real HTML pages for scrape feeds, are returned as is.
+func unwrapBrowserView(body []byte) []byte {
+	if gofeed.DetectFeedType(bytes.NewReader(body)) != gofeed.FeedTypeUnknown {
+		return body
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return body
+	}
+	pre := doc.Find("body > pre").First()
+	if pre.Length() == 0 || doc.Find("body").Children().Length() != 1 {
+		return body
+	}
+	text := []byte(strings.TrimSpace(pre.Text()))
+	if gofeed.DetectFeedType(bytes.NewReader(text)) == gofeed.FeedTypeUnknown {
+		return body
+	}
+	return text
+}
+
+// isCloudflareChallenge reports whether a non-200 response is a Cloudflare
+// challenge page rather than a genuine error. snippet is the start of the body.
+func isCloudflareChallenge(resp *http.Response, snippet []byte) bool {
+	if resp.Header.Get("Cf-Mitigated") == "challenge" {
+		return true
+	}
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	if !strings.Contains(strings.ToLower(resp.Header.Get("Server")), "cloudflare") {
+		return false
+	}
+	for _, marker := range []string{"challenge-platform", "cf-chl", "Just a moment..."} {
+		if bytes.Contains(snippet, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetch_CloudflareChallengeFallsBackToFlareSolverr(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "cloudflare")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<html><title>Just a moment...</title><script src="/cdn-cgi/challenge-platform/x.js"></script></html>`))
+	}))
+	defer origin.Close()
+
+	var solved flareSolverrRequest
+	solver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&solved))
+		// Chrome renders XML served as text inside a <pre>.
+		page := `<html><head></head><body><pre style="word-wrap: break-word;">` + html.EscapeString(testRSS) + `</pre></body></html>`
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":   "ok",
+			"solution": map[string]any{"url": solved.URL, "status": 200, "response": page},
+		})
+	}))
+	defer solver.Close()
+
+	fetcher := NewGoFeedFetcher(staticClientFactory{origin.Client()}, "").WithFlareSolverr(NewFlareSolverr(solver.URL, 0))
+	res, err := fetcher.Fetch(context.Background(), origin.URL, nil, nil, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "request.get", solved.Cmd)
+	assert.Equal(t, origin.URL, solved.URL)
+	assert.Equal(t, "T", res.Feed.Title)
+	require.Len(t, res.Feed.Items, 1)
+}
+
+func TestFetch_FlareSolverrRequired(t *testing.T) {
+	fetcher := NewGoFeedFetcher(staticClientFactory{http.DefaultClient}, "")
+	_, err := fetcher.Fetch(context.Background(), "https://example.com/feed", nil, nil, nil, interfaces.FetchOptions{UseFlareSolverr: true})
+	assert.ErrorContains(t, err, "flaresolverr.url")
+}
@@ -0,0 +1,79 @@
+package rss
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// hostLimiter spaces out requests per host so that many feeds on one site stay
+// under a requests-per-minute budget however many fetches run concurrently.
+type hostLimiter struct {
+	perMinute   int            // Default budget per host; 0 leaves hosts without an override unlimited
+	minInterval time.Duration  // Least time between two requests to a host (or overridden domain), whatever its budget
+	overrides   map[string]int // Budget per domain, shared by its subdomains
+	limiters    map[string]*rate.Limiter
+	limitersMu  sync.Mutex
+}
+
+func newHostLimiter(perMinute int, minInterval time.Duration, overrides map[string]int) *hostLimiter {
+	normalized := make(map[string]int, len(overrides))
+	for domain, n := range overrides {
+		normalized[strings.ToLower(strings.TrimPrefix(domain, "."))] = n
+	}
+	return &hostLimiter{perMinute: perMinute, minInterval: minInterval, overrides: normalized, limiters: make(map[string]*rate.Limiter)}
+}
+
+// Wait blocks until a request to rawURL's host is allowed or ctx is done.
+func (h *hostLimiter) Wait(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil // The request itself will report the bad URL
+	}
+	limiter := h.limiterFor(strings.ToLower(u.Hostname()))
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
+
+// limiterFor returns the limiter for host, keyed by the most specific
+// configured domain that host belongs to, or by host itself. It lets requests
+// through at the slower of the budget and the minimum interval.
+func (h *hostLimiter) limiterFor(host string) *rate.Limiter {
+	key, perMinute := host, h.perMinute
+	for domain := host; domain != ""; {
+		if n, ok := h.overrides[domain]; ok {
+			key, perMinute = domain, n
+			break
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	var interval time.Duration
+	if perMinute > 0 {
+		interval = time.Minute / time.Duration(perMinute)
+	}
+	if h.minInterval > interval {
+		interval = h.minInterval
+	}
+	if interval <= 0 {
+		return nil
+	}
+
+	h.limitersMu.Lock()
+	defer h.limitersMu.Unlock()
+	limiter, exists := h.limiters[key]
+	if !exists {
+		limiter = rate.NewLimiter(rate.Every(interval), 1)
+		h.limiters[key] = limiter
+	}
+	return limiter
+}
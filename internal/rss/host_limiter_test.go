@@ -0,0 +1,51 @@
+package rss
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestHostLimiter_Keys(t *testing.T) {
+	h := newHostLimiter(0, 0, map[string]int{"reddit.com": 10})
+
+	assert.Nil(t, h.limiterFor("example.com"), "no default limit")
+	www := h.limiterFor("www.reddit.com")
+	assert.NotNil(t, www)
+	assert.Same(t, www, h.limiterFor("old.reddit.com"), "subdomains share the domain budget")
+	assert.Same(t, www, h.limiterFor("reddit.com"))
+
+	h = newHostLimiter(60, 0, nil)
+	a := h.limiterFor("a.example.com")
+	assert.NotNil(t, a)
+	assert.NotSame(t, a, h.limiterFor("b.example.com"), "default limit is per host")
+}
+
+func TestHostLimiter_MinInterval(t *testing.T) {
+	h := newHostLimiter(0, 2*time.Second, map[string]int{"reddit.com": 6})
+
+	spaced := h.limiterFor("example.com")
+	require.NotNil(t, spaced, "spacing applies to hosts without a budget")
+	assert.Equal(t, rate.Every(2*time.Second), spaced.Limit())
+	assert.Equal(t, rate.Every(10*time.Second), h.limiterFor("www.reddit.com").Limit(), "a slower budget wins")
+
+	h = newHostLimiter(600, 2*time.Second, nil)
+	assert.Equal(t, rate.Every(2*time.Second), h.limiterFor("example.com").Limit(), "spacing caps a faster budget")
+}
+
+func TestWithHostRateLimit_Reconfigure(t *testing.T) {
+	f := NewGoFeedFetcher(nil, "").WithHostRateLimit(0, 0, nil)
+	assert.Nil(t, f.hosts.Load())
+
+	f.WithHostRateLimit(0, 0, map[string]int{".Reddit.com": 10})
+	assert.NotNil(t, f.hosts.Load().limiterFor("www.reddit.com"))
+
+	f.WithHostRateLimit(0, time.Second, nil)
+	assert.NotNil(t, f.hosts.Load().limiterFor("example.com"))
+
+	f.WithHostRateLimit(0, 0, nil)
+	assert.Nil(t, f.hosts.Load(), "removing all limits turns limiting off")
+}
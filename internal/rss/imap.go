@@ -0,0 +1,392 @@
+package rss
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/proxy"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+	"github.com/rs/zerolog/log"
+)
+
+// imapMaxItems caps the messages fetched per poll, newest first, so the first
+// poll of a busy mailbox doesn't post its whole history.
+const imapMaxItems = 20
+
+const imapInternalDateLayout = "_2-Jan-2006 15:04:05 -0700"
+
+var (
+	imapLiteralRe      = regexp.MustCompile(`\{(\d+)\}$`)
+	imapUIDValidityRe  = regexp.MustCompile(`\[UIDVALIDITY (\d+)\]`)
+	imapFetchUIDRe     = regexp.MustCompile(`\bUID (\d+)`)
+	imapInternalDateRe = regexp.MustCompile(`INTERNALDATE "([^"]+)"`)
+)
+
+// imapSource is a parsed imap:// or imaps:// feed URL.
+type imapSource struct {
+	addr        string
+	host        string
+	implicitTLS bool
+	mailbox     string
+}
+
+// parseIMAPURL parses imaps://host[:port]/Mailbox (TLS, default port 993) or
+// imap://host[:port]/Mailbox (STARTTLS, default port 143). The mailbox
+// defaults to INBOX.
+func parseIMAPURL(raw string) (*imapSource, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMAP URL %q: %w", raw, err)
+	}
+	src := &imapSource{host: u.Hostname(), mailbox: strings.Trim(u.Path, "/")}
+	port := u.Port()
+	switch u.Scheme {
+	case "imaps":
+		src.implicitTLS = true
+		if port == "" {
+			port = "993"
+		}
+	case "imap":
+		if port == "" {
+			port = "143"
+		}
+	default:
+		return nil, fmt.Errorf("invalid IMAP URL %q: scheme must be imap or imaps", raw)
+	}
+	if src.host == "" {
+		return nil, fmt.Errorf("invalid IMAP URL %q: missing host", raw)
+	}
+	if src.mailbox == "" {
+		src.mailbox = "INBOX"
+	}
+	src.addr = net.JoinHostPort(src.host, port)
+	return src, nil
+}
+
+// FetchIMAP reads new messages from a mailbox, logging in with the feed's
+// basic auth credentials. The returned ETag records the mailbox's UIDVALIDITY
+// and the highest UID seen, so later polls only download newer messages; when
+// there are none the result has a nil Feed, like a 304. Proxies are not used.
+func (f *GoFeedFetcher) FetchIMAP(ctx context.Context, feedURL string, etag *string, opts interfaces.FetchOptions) (*interfaces.FetchResult, error) {
+	if opts.Auth == nil || opts.Auth.Type != database.FeedAuthBasic {
+		return nil, fmt.Errorf("IMAP feed %s needs a username and password (--auth-username/--auth-password)", feedURL)
+	}
+	src, err := parseIMAPURL(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg, err := proxy.BuildTLSConfig(opts.TLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg == nil {
+		tlsCfg = &tls.Config{}
+	}
+	tlsCfg.ServerName = src.host
+
+	ctx, cancel := context.WithTimeout(ctx, f.policyFor(opts).Timeout)
+	defer cancel()
+	dial := f.dial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	c, err := dialIMAP(ctx, dial, src, tlsCfg)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", src.addr, err)
+	}
+	defer c.close()
+
+	if _, err := c.command("LOGIN %s %s", imapQuote(opts.Auth.Username), imapQuote(opts.Auth.Secret)); err != nil {
+		return nil, err
+	}
+	validity, err := c.examine(src.mailbox)
+	if err != nil {
+		return nil, err
+	}
+	var lastUID uint32
+	if etag != nil {
+		if v, uid, ok := parseIMAPState(*etag); ok && v == validity {
+			lastUID = uid
+		}
+	}
+	uids, err := c.searchUIDs(lastUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(uids) == 0 {
+		state := formatIMAPState(validity, lastUID)
+		return &interfaces.FetchResult{NewEtag: &state}, nil
+	}
+	if len(uids) > imapMaxItems {
+		uids = uids[len(uids)-imapMaxItems:]
+	}
+	messages, err := c.fetchMessages(uids, f.maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &gofeed.Feed{Title: src.mailbox + " (" + src.host + ")", FeedType: "imap"}
+	for i := len(messages) - 1; i >= 0; i-- { // Newest first, like a feed
+		m := messages[i]
+		item, err := mailItem(m.body, fmt.Sprintf("imap:%s/%s/%d/%d", src.host, src.mailbox, validity, m.uid), m.received)
+		if err != nil {
+			log.Warn().Err(err).Str("feed_url", feedURL).Uint32("uid", m.uid).Msg("Skipping unreadable message")
+			continue
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	state := formatIMAPState(validity, uids[len(uids)-1])
+	return &interfaces.FetchResult{Feed: feed, NewEtag: &state}, nil
+}
+
+func formatIMAPState(validity, lastUID uint32) string {
+	return fmt.Sprintf("imap:%d:%d", validity, lastUID)
+}
+
+func parseIMAPState(s string) (validity, lastUID uint32, ok bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 || parts[0] != "imap" {
+		return 0, 0, false
+	}
+	v, err1 := strconv.ParseUint(parts[1], 10, 32)
+	u, err2 := strconv.ParseUint(parts[2], 10, 32)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return uint32(v), uint32(u), true
+}
+
+// imapQuote renders s as an IMAP quoted string.
+func imapQuote(s string) string {
+	s = strings.NewReplacer("\r", "", "\n", "").Replace(s)
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// imapConn is a minimal IMAP4rev1 client: just enough to log in, open a
+// mailbox read-only, and download messages by UID.
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+	stop func() bool
+}
+
+// imapResponse is one server response line, with any literals it carried
+// replaced by "{}" in text and collected in order.
+type imapResponse struct {
+	text     string
+	literals [][]byte
+}
+
+type imapMessage struct {
+	uid      uint32
+	received time.Time
+	body     []byte
+}
+
+func dialIMAP(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), src *imapSource, tlsCfg *tls.Config) (*imapConn, error) {
+	raw, err := dial(ctx, "tcp", src.addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		raw.SetDeadline(deadline)
+	}
+	c := &imapConn{conn: raw, stop: context.AfterFunc(ctx, func() { raw.Close() })}
+	if src.implicitTLS {
+		if err := c.startTLS(ctx, tlsCfg); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+	c.r = bufio.NewReader(c.conn)
+	greeting, err := c.readResponse(0)
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting.text, "* OK") && !strings.HasPrefix(greeting.text, "* PREAUTH") {
+		c.close()
+		return nil, fmt.Errorf("unexpected IMAP greeting %q", greeting.text)
+	}
+	if !src.implicitTLS {
+		// Credentials are never sent in the clear.
+		if _, err := c.command("STARTTLS"); err != nil {
+			c.close()
+			return nil, err
+		}
+		if err := c.startTLS(ctx, tlsCfg); err != nil {
+			c.close()
+			return nil, err
+		}
+		c.r = bufio.NewReader(c.conn)
+	}
+	return c, nil
+}
+
+func (c *imapConn) startTLS(ctx context.Context, tlsCfg *tls.Config) error {
+	tc := tls.Client(c.conn, tlsCfg)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("TLS handshake: %w", err)
+	}
+	c.conn = tc
+	return nil
+}
+
+func (c *imapConn) close() {
+	if c.r != nil {
+		c.tag++
+		fmt.Fprintf(c.conn, "a%d LOGOUT\r\n", c.tag)
+	}
+	c.stop()
+	c.conn.Close()
+}
+
+// command sends a command and returns its untagged responses, failing unless
+// the server completes it with OK.
+func (c *imapConn) command(format string, args ...any) ([]imapResponse, error) {
+	return c.commandLimit(0, format, args...)
+}
+
+func (c *imapConn) commandLimit(maxLiteral int64, format string, args ...any) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%d", c.tag)
+	verb, _, _ := strings.Cut(format, " ")
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, fmt.Errorf("IMAP %s: %w", verb, err)
+	}
+	var untagged []imapResponse
+	for {
+		resp, err := c.readResponse(maxLiteral)
+		if err != nil {
+			return nil, fmt.Errorf("IMAP %s: %w", verb, err)
+		}
+		if status, ok := strings.CutPrefix(resp.text, tag+" "); ok {
+			if !strings.HasPrefix(status, "OK") {
+				return nil, fmt.Errorf("IMAP %s failed: %s", verb, status)
+			}
+			return untagged, nil
+		}
+		if strings.HasPrefix(resp.text, "* ") {
+			untagged = append(untagged, resp)
+		}
+	}
+}
+
+func (c *imapConn) readResponse(maxLiteral int64) (imapResponse, error) {
+	var resp imapResponse
+	var text strings.Builder
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return resp, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		m := imapLiteralRe.FindStringSubmatchIndex(line)
+		if m == nil {
+			text.WriteString(line)
+			resp.text = text.String()
+			return resp, nil
+		}
+		n, err := strconv.ParseInt(line[m[2]:m[3]], 10, 64)
+		if err != nil || n > maxLiteral {
+			return resp, fmt.Errorf("response literal of %s bytes exceeds the %d byte limit", line[m[2]:m[3]], maxLiteral)
+		}
+		literal := make([]byte, n)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return resp, err
+		}
+		text.WriteString(line[:m[0]])
+		text.WriteString("{}")
+		resp.literals = append(resp.literals, literal)
+	}
+}
+
+// examine opens a mailbox read-only, so fetched messages stay unread, and
+// returns its UIDVALIDITY.
+func (c *imapConn) examine(mailbox string) (uint32, error) {
+	resps, err := c.command("EXAMINE %s", imapQuote(mailbox))
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range resps {
+		if m := imapUIDValidityRe.FindStringSubmatch(r.text); m != nil {
+			v, err := strconv.ParseUint(m[1], 10, 32)
+			if err == nil {
+				return uint32(v), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("IMAP EXAMINE %s: server sent no UIDVALIDITY", mailbox)
+}
+
+// searchUIDs returns the UIDs above after, ascending.
+func (c *imapConn) searchUIDs(after uint32) ([]uint32, error) {
+	query := "ALL"
+	if after > 0 {
+		query = fmt.Sprintf("UID %d:*", after+1)
+	}
+	resps, err := c.command("UID SEARCH %s", query)
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, r := range resps {
+		fields, ok := strings.CutPrefix(r.text, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Fields(fields) {
+			uid, err := strconv.ParseUint(field, 10, 32)
+			// "n:*" always matches the highest UID, even when it is below n.
+			if err == nil && uint32(uid) > after {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	return uids, nil
+}
+
+// fetchMessages downloads the full messages with the given UIDs without
+// marking them as read, ordered by UID.
+func (c *imapConn) fetchMessages(uids []uint32, maxSize int64) ([]imapMessage, error) {
+	set := make([]string, len(uids))
+	for i, uid := range uids {
+		set[i] = strconv.FormatUint(uint64(uid), 10)
+	}
+	resps, err := c.commandLimit(maxSize, "UID FETCH %s (UID INTERNALDATE BODY.PEEK[])", strings.Join(set, ","))
+	if err != nil {
+		return nil, err
+	}
+	var messages []imapMessage
+	for _, r := range resps {
+		m := imapFetchUIDRe.FindStringSubmatch(r.text)
+		if m == nil || !strings.Contains(r.text, " FETCH ") || len(r.literals) == 0 {
+			continue
+		}
+		uid, err := strconv.ParseUint(m[1], 10, 32)
+		if err != nil {
+			continue
+		}
+		msg := imapMessage{uid: uint32(uid), body: r.literals[len(r.literals)-1]}
+		if d := imapInternalDateRe.FindStringSubmatch(r.text); d != nil {
+			msg.received, _ = time.Parse(imapInternalDateLayout, d[1])
+		}
+		messages = append(messages, msg)
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].uid < messages[j].uid })
+	return messages, nil
+}
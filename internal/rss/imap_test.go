@@ -0,0 +1,169 @@
+package rss
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNewsletter = "From: =?UTF-8?Q?Caf=C3=A9_Weekly?= <news@example.com>\r\n" +
+	"Subject: =?UTF-8?B?SXNzdWUgIzQy?=\r\n" +
+	"Message-ID: <issue42@example.com>\r\n" +
+	"Date: Mon, 03 Feb 2025 08:00:00 +0000\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=b1\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Plain version\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/html; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"<html><head><style>p{}</style></head><body><p>Caf=E9 news</p></body></html>\r\n" +
+	"--b1--\r\n"
+
+func TestMailItem(t *testing.T) {
+	item, err := mailItem([]byte(testNewsletter), "fallback", time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "Issue #42", item.Title)
+	assert.Equal(t, "issue42@example.com", item.GUID)
+	assert.Equal(t, "Café Weekly", item.Author.Name)
+	assert.Equal(t, "<p>Café news</p>", item.Content, "HTML body preferred, unwrapped from the document")
+	require.NotNil(t, item.PublishedParsed)
+
+	plain := "Subject: Hi\r\n\r\nLine 1 & more\r\nLine 2\r\n"
+	received := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	item, err = mailItem([]byte(plain), "fallback", received)
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", item.GUID)
+	assert.Equal(t, "Line 1 &amp; more<br>\nLine 2", item.Content)
+	assert.Equal(t, received, *item.PublishedParsed)
+}
+
+// serveFakeIMAP answers one IMAP session over TLS with the given messages
+// (UIDs 1..n) in a mailbox with UIDVALIDITY 7.
+func serveFakeIMAP(t *testing.T, messages []string) string {
+	certSrv := httptest.NewTLSServer(nil)
+	cert := certSrv.TLS.Certificates[0]
+	certSrv.Close()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				fmt.Fprint(conn, "* OK fake IMAP ready\r\n")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					tag, cmd, _ := strings.Cut(strings.TrimSpace(line), " ")
+					switch {
+					case strings.HasPrefix(cmd, "LOGIN"):
+						if cmd != `LOGIN "reader" "p\"ss"` {
+							fmt.Fprintf(conn, "%s NO bad credentials\r\n", tag)
+							continue
+						}
+					case strings.HasPrefix(cmd, "EXAMINE"):
+						fmt.Fprintf(conn, "* %d EXISTS\r\n* OK [UIDVALIDITY 7] ok\r\n", len(messages))
+					case strings.HasPrefix(cmd, "UID SEARCH"):
+						from := 1
+						fmt.Sscanf(cmd, "UID SEARCH UID %d:*", &from)
+						var uids []string
+						for uid := from; uid <= len(messages); uid++ {
+							uids = append(uids, fmt.Sprint(uid))
+						}
+						if len(uids) == 0 && len(messages) > 0 {
+							uids = []string{fmt.Sprint(len(messages))} // n:* always matches the last UID
+						}
+						fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(uids, " "))
+					case strings.HasPrefix(cmd, "UID FETCH"):
+						var set string
+						fmt.Sscanf(cmd, "UID FETCH %s", &set)
+						for _, s := range strings.Split(set, ",") {
+							var uid int
+							fmt.Sscan(s, &uid)
+							msg := messages[uid-1]
+							fmt.Fprintf(conn, "* %d FETCH (INTERNALDATE \" 1-Feb-2025 10:00:00 +0000\" BODY[] {%d}\r\n%s UID %d)\r\n", uid, len(msg), msg, uid)
+						}
+					case cmd == "LOGOUT":
+						fmt.Fprintf(conn, "* BYE\r\n%s OK bye\r\n", tag)
+						return
+					}
+					fmt.Fprintf(conn, "%s OK done\r\n", tag)
+				}
+			}(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestFetchIMAP(t *testing.T) {
+	second := strings.Replace(testNewsletter, "issue42", "issue43", 1)
+	addr := serveFakeIMAP(t, []string{testNewsletter, second})
+	feedURL := "imaps://" + addr + "/Newsletters"
+	opts := interfaces.FetchOptions{
+		Auth: &database.FeedCredentials{Type: database.FeedAuthBasic, Username: "reader", Secret: `p"ss`},
+		TLS:  &database.TLSConfig{InsecureSkipVerify: true},
+	}
+	fetcher := NewGoFeedFetcher(staticClientFactory{}, "")
+
+	result, err := fetcher.FetchIMAP(context.Background(), feedURL, nil, opts)
+	require.NoError(t, err)
+	require.Len(t, result.Feed.Items, 2)
+	assert.Equal(t, "issue43@example.com", result.Feed.Items[0].GUID, "newest message first")
+	require.NotNil(t, result.NewEtag)
+	assert.Equal(t, "imap:7:2", *result.NewEtag)
+
+	result, err = fetcher.FetchIMAP(context.Background(), feedURL, result.NewEtag, opts)
+	require.NoError(t, err)
+	assert.Nil(t, result.Feed, "no messages above the stored UID")
+	assert.Equal(t, "imap:7:2", *result.NewEtag)
+
+	stale := "imap:6:2"
+	result, err = fetcher.FetchIMAP(context.Background(), feedURL, &stale, opts)
+	require.NoError(t, err)
+	assert.Len(t, result.Feed.Items, 2, "a new UIDVALIDITY invalidates the stored UID")
+
+	opts.Auth.Secret = "wrong"
+	_, err = fetcher.FetchIMAP(context.Background(), feedURL, nil, opts)
+	assert.ErrorContains(t, err, "IMAP LOGIN failed")
+	assert.NotContains(t, err.Error(), "wrong")
+}
+
+func TestParseIMAPURL(t *testing.T) {
+	src, err := parseIMAPURL("imap://mail.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "mail.example.com:143", src.addr)
+	assert.Equal(t, "INBOX", src.mailbox)
+	assert.False(t, src.implicitTLS)
+
+	src, err = parseIMAPURL("imaps://mail.example.com/Lists/Go")
+	require.NoError(t, err)
+	assert.Equal(t, "mail.example.com:993", src.addr)
+	assert.Equal(t, "Lists/Go", src.mailbox)
+
+	_, err = parseIMAPURL("https://mail.example.com")
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/mmcdole/gofeed"
+	jsonfeed "github.com/mmcdole/gofeed/json"
+)
+
+// feedAcceptHeader advertises every format the parser understands, so servers
+// doing content negotiation can return JSON Feed as well as RSS/Atom.
+const feedAcceptHeader = "application/rss+xml, application/atom+xml, application/feed+json, application/json;q=0.9, application/xml;q=0.8, text/xml;q=0.8, */*;q=0.5"
+
+// Custom keys set on gofeed.Item.Custom for JSON Feed fields the universal model lacks.
+const (
+	CustomKeyExternalURL = "external_url"
+)
+
+// JSONFeedTranslator wraps gofeed's DefaultJSONTranslator to fix what it gets
+// wrong or drops for JSON Feed 1.1:
+//   - attachments become enclosures with Length set to size_in_bytes
+//     (gofeed stores duration_in_seconds there);
+//   - external_url is exposed via Item.Custom[CustomKeyExternalURL].
+type JSONFeedTranslator struct {
+	gofeed.DefaultJSONTranslator
+}
+
+// Translate converts a *json.Feed into the universal feed type.
+func (t *JSONFeedTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	source, ok := feed.(*jsonfeed.Feed)
+	if !ok {
+		return nil, fmt.Errorf("feed did not match expected type of *json.Feed")
+	}
+	result, err := t.DefaultJSONTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+
+	// DefaultJSONTranslator maps items one-to-one and in order.
+	for i, item := range result.Items {
+		if i >= len(source.Items) || source.Items[i] == nil {
+			break
+		}
+		src := source.Items[i]
+		if src.ExternalURL != "" {
+			if item.Custom == nil {
+				item.Custom = make(map[string]string)
+			}
+			item.Custom[CustomKeyExternalURL] = src.ExternalURL
+			if item.Link == "" {
+				item.Link = src.ExternalURL
+			}
+		}
+		if src.Attachments != nil {
+			item.Enclosures = nil
+			for _, a := range *src.Attachments {
+				if a.URL == "" {
+					continue
+				}
+				enc := &gofeed.Enclosure{URL: a.URL, Type: a.MimeType}
+				if a.SizeInBytes > 0 {
+					enc.Length = strconv.FormatInt(a.SizeInBytes, 10)
+				}
+				item.Enclosures = append(item.Enclosures, enc)
+			}
+		}
+	}
+	return result, nil
+}
+
+// ParseFeed parses an RSS, Atom or JSON Feed document, e.g. one fetched or
+// delivered by a WebSub hub.
+func ParseFeed(body io.Reader) (*gofeed.Feed, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if gofeed.DetectFeedType(bytes.NewReader(data)) == gofeed.FeedTypeJSON {
+		data = coerceJSONFeedIDs(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))) // JSON has no byte order mark
+	}
+	return newFeedParser().Parse(bytes.NewReader(data))
+}
+
+// coerceJSONFeedIDs turns item ids given as numbers, which the spec says
+// readers must accept as strings, into strings: gofeed rejects the whole
+// document otherwise. An id of 42 becomes "42", so the item's GUID, and so
+// its dedup hash, is the same either way. Documents it can't decode are
+// returned as they are, for the parser to report.
+func coerceJSONFeedIDs(data []byte) []byte {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return data
+	}
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(doc["items"], &items); err != nil {
+		return data
+	}
+	changed := false
+	for _, item := range items {
+		id := bytes.TrimSpace(item["id"])
+		if len(id) == 0 || id[0] == '"' || bytes.Equal(id, []byte("null")) {
+			continue
+		}
+		quoted, err := json.Marshal(string(id))
+		if err != nil {
+			return data
+		}
+		item["id"] = quoted
+		changed = true
+	}
+	if !changed {
+		return data
+	}
+	rawItems, err := json.Marshal(items)
+	if err != nil {
+		return data
+	}
+	doc["items"] = rawItems
+	coerced, err := json.Marshal(doc)
+	if err != nil {
+		return data
+	}
+	return coerced
+}
+
+// newFeedParser returns a gofeed parser configured with the bot's translators.
+func newFeedParser() *gofeed.Parser {
+	fp := gofeed.NewParser()
+	fp.JSONTranslator = &JSONFeedTranslator{}
+	fp.AtomTranslator = &AtomFeedTranslator{}
+	fp.RSSTranslator = &RSSFeedTranslator{}
+	return fp
+}
@@ -0,0 +1,64 @@
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONFeedTranslator_AttachmentsAndExternalURL(t *testing.T) {
+	const doc = `{
+		"version": "https://jsonfeed.org/version/1.1",
+		"title": "Linkblog",
+		"items": [{
+			"id": "1",
+			"title": "Episode",
+			"external_url": "https://example.org/original",
+			"attachments": [{"url": "https://example.com/ep.mp3", "mime_type": "audio/mpeg", "size_in_bytes": 12345, "duration_in_seconds": 60}]
+		}]
+	}`
+
+	feed, err := newFeedParser().ParseString(doc)
+	require.NoError(t, err)
+	require.Len(t, feed.Items, 1)
+
+	item := feed.Items[0]
+	assert.Equal(t, "https://example.org/original", item.Custom[CustomKeyExternalURL])
+	assert.Equal(t, "https://example.org/original", item.Link)
+	require.Len(t, item.Enclosures, 1)
+	assert.Equal(t, "https://example.com/ep.mp3", item.Enclosures[0].URL)
+	assert.Equal(t, "audio/mpeg", item.Enclosures[0].Type)
+	assert.Equal(t, "12345", item.Enclosures[0].Length)
+}
+
+func TestFetch_JSONFeedIDs(t *testing.T) {
+	const doc = "\xef\xbb\xbf" + `{
+		"version": "https://jsonfeed.org/version/1.1",
+		"title": "Blog",
+		"items": [
+			{"id": 42, "url": "https://example.com/42", "content_text": "Numeric id"},
+			{"id": "https://example.com/post", "url": "https://example.com/post", "content_html": "<p>String id</p>"},
+			{"url": "https://example.com/no-id", "content_text": "No id"}
+		]
+	}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/feed+json")
+		_, _ = w.Write([]byte(doc))
+	}))
+	defer srv.Close()
+
+	res, err := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "").Fetch(context.Background(), srv.URL, nil, nil, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "json", res.Feed.FeedType)
+	require.Len(t, res.Feed.Items, 3)
+	assert.Equal(t, "42", res.Feed.Items[0].GUID)
+	assert.Equal(t, "https://example.com/post", res.Feed.Items[1].GUID)
+	assert.Empty(t, res.Feed.Items[2].GUID) // Deduplicated by link instead
+	assert.Equal(t, "https://example.com/no-id", res.Feed.Items[2].Link)
+}
@@ -0,0 +1,153 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/net/html/charset"
+)
+
+// maxMIMEDepth bounds multipart nesting in mailItem.
+const maxMIMEDepth = 5
+
+var mailWordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+// mailItem turns an RFC 5322 message into a feed item: the subject is its
+// title and the HTML body (or the plain text one) its content. The Message-ID
+// is its GUID, falling back to fallbackGUID; received dates undated messages.
+func mailItem(raw []byte, fallbackGUID string, received time.Time) (*gofeed.Item, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("parsing message: %w", err)
+	}
+	subject, err := mailWordDecoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	item := &gofeed.Item{Title: strings.TrimSpace(subject), GUID: strings.Trim(msg.Header.Get("Message-Id"), "<> ")}
+	if item.GUID == "" {
+		item.GUID = fallbackGUID
+	}
+	date, err := msg.Header.Date()
+	if err != nil && !received.IsZero() {
+		date, err = received, nil
+	}
+	if err == nil {
+		item.PublishedParsed = &date
+		item.Published = date.Format(time.RFC1123Z)
+	}
+	parser := mail.AddressParser{WordDecoder: mailWordDecoder}
+	if from, err := parser.Parse(msg.Header.Get("From")); err == nil {
+		item.Authors = []*gofeed.Person{{Name: from.Name, Email: from.Address}}
+		if from.Name == "" {
+			item.Authors[0].Name = from.Address
+		}
+		item.Author = item.Authors[0]
+	}
+
+	htmlBody, textBody := mailBody(textproto.MIMEHeader(msg.Header), msg.Body, 0)
+	switch {
+	case htmlBody != "":
+		item.Content = htmlDocumentBody(htmlBody)
+	case textBody != "":
+		item.Content = strings.ReplaceAll(html.EscapeString(strings.TrimSpace(strings.ReplaceAll(textBody, "\r\n", "\n"))), "\n", "<br>\n")
+	}
+	return item, nil
+}
+
+// mailBody returns the first text/html and text/plain bodies of a MIME entity,
+// decoded to UTF-8. Attachments are skipped.
+func mailBody(header textproto.MIMEHeader, body io.Reader, depth int) (htmlBody, textBody string) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+	if disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition")); disposition == "attachment" {
+		return "", ""
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMIMEDepth || params["boundary"] == "" {
+			return "", ""
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for htmlBody == "" || textBody == "" {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			h, t := mailBody(part.Header, part, depth+1)
+			if htmlBody == "" {
+				htmlBody = h
+			}
+			if textBody == "" {
+				textBody = t
+			}
+		}
+		return htmlBody, textBody
+	}
+	if mediaType != "text/html" && mediaType != "text/plain" {
+		return "", ""
+	}
+
+	var r io.Reader = body
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, newlineStripper{body})
+	case "quoted-printable":
+		r = quotedprintable.NewReader(body)
+	}
+	if label := params["charset"]; label != "" {
+		if decoded, err := charset.NewReaderLabel(label, r); err == nil {
+			r = decoded
+		}
+	}
+	data, err := io.ReadAll(r)
+	if err != nil && len(data) == 0 {
+		return "", ""
+	}
+	if mediaType == "text/html" {
+		return string(data), ""
+	}
+	return "", string(data)
+}
+
+// htmlDocumentBody returns the contents of an HTML document's <body> without
+// its scripts and styles; newsletters are usually full documents.
+func htmlDocumentBody(s string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(s))
+	if err != nil {
+		return s
+	}
+	doc.Find("script, style").Remove()
+	body, err := doc.Find("body").Html()
+	if err != nil {
+		return s
+	}
+	return strings.TrimSpace(body)
+}
+
+// newlineStripper drops the line breaks base64 bodies are wrapped with.
+type newlineStripper struct{ r io.Reader }
+
+func (n newlineStripper) Read(p []byte) (int, error) {
+	count, err := n.r.Read(p)
+	out := p[:0]
+	for _, b := range p[:count] {
+		if b != '\r' && b != '\n' {
+			out = append(out, b)
+		}
+	}
+	return len(out), err
+}
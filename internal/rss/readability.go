@@ -0,0 +1,224 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"golang.org/x/net/html"
+)
+
+const (
+	articleTimeout    = 20 * time.Second // Per page fetched for its article
+	maxArticleBody    = 5 << 20
+	minArticleChars   = 250 // Less text than this isn't taken for the article
+	minParagraphChars = 25  // Shorter paragraphs don't score their ancestors
+	// articleJunkElements are never part of an article's text; h1 is the
+	// page's title, which the message already has.
+	articleJunkElements = "script, style, noscript, template, iframe, form, nav, aside, header, footer, h1, svg, button, input, select, textarea, object, embed"
+)
+
+// ErrNoArticle is returned for pages without enough text to be an article.
+var ErrNoArticle = errors.New("no article found in page")
+
+var (
+	// unlikelyArticleClass matches the class or id of page chrome; such
+	// elements are dropped unless maybeArticleClass matches too.
+	unlikelyArticleClass = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|menu|modal|newsletter|pager|pagination|popup|promo|related|remark|replies|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|widget`)
+	maybeArticleClass    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveArticleClass = regexp.MustCompile(`(?i)article|body|content|entry|h-entry|hentry|main|page|post|story|text`)
+	negativeArticleClass = regexp.MustCompile(`(?i)-ad-|banner|byline|combx|comment|contact|foot|hidden|masthead|meta|outbrain|promo|related|share|shoutbox|sidebar|skyscraper|sponsor|tags|tool|widget`)
+	sentenceEnd          = regexp.MustCompile(`\.( |$)`)
+)
+
+// FetchArticle fetches an item's page and returns its main content as HTML,
+// for feeds that publish only summaries.
+func (f *GoFeedFetcher) FetchArticle(ctx context.Context, pageURL string, proxy *database.Proxy, opts interfaces.FetchOptions) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, articleTimeout)
+	defer cancel()
+	resp, err := f.simpleGet(ctx, pageURL, htmlAcceptHeader, proxy, opts)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := readBody(resp, maxArticleBody)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", pageURL, err)
+	}
+	// Redirects change the base that relative links resolve against.
+	return ExtractArticle(bytes.NewReader(body), resp.Request.URL.String())
+}
+
+// ExtractArticle returns the main content of an HTML page as HTML, found the
+// way Readability does: each paragraph scores its parent, and half as much
+// its grandparent, by its length and commas; scores are weighed by class
+// names and the share of text in links; and the best-scoring element is
+// kept with the siblings scoring close to it. Links and images are made
+// absolute against pageURL.
+func ExtractArticle(r io.Reader, pageURL string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return "", fmt.Errorf("parsing HTML: %w", err)
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing page URL: %w", err)
+	}
+	absolutizeURLs(doc, base)
+
+	doc.Find(articleJunkElements).Remove()
+	doc.Find("body *").Each(func(_ int, sel *goquery.Selection) {
+		if sel.Is("article, main") {
+			return
+		}
+		match := sel.AttrOr("class", "") + " " + sel.AttrOr("id", "")
+		if unlikelyArticleClass.MatchString(match) && !maybeArticleClass.MatchString(match) {
+			sel.Remove()
+		}
+	})
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*goquery.Selection
+	doc.Find("p, pre, td, div").Each(func(_ int, para *goquery.Selection) {
+		if para.Is("div") && para.Children().Filter("article, blockquote, div, dl, figure, ol, p, pre, section, table, ul").Length() > 0 {
+			return // Only divs used as paragraphs
+		}
+		text := collapseSpace(para.Text())
+		if len([]rune(text)) < minParagraphChars {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len([]rune(text)))/100, 3)
+		for i, ancestor := range []*goquery.Selection{para.Parent(), para.Parent().Parent()} {
+			if ancestor.Length() == 0 || ancestor.Is("html") {
+				continue
+			}
+			node := ancestor.Get(0)
+			if _, ok := scores[node]; !ok {
+				scores[node] = initialArticleScore(ancestor)
+				candidates = append(candidates, ancestor)
+			}
+			if i == 0 {
+				scores[node] += score
+			} else {
+				scores[node] += score / 2
+			}
+		}
+	})
+
+	var top *goquery.Selection
+	topScore := 0.0
+	for _, c := range candidates {
+		node := c.Get(0)
+		scores[node] *= 1 - linkDensity(c)
+		if top == nil || scores[node] > topScore {
+			top, topScore = c, scores[node]
+		}
+	}
+	if top == nil {
+		return "", ErrNoArticle
+	}
+
+	var out strings.Builder
+	textChars := 0
+	threshold := math.Max(10, topScore*0.2)
+	top.Parent().Children().Each(func(_ int, sibling *goquery.Selection) {
+		if !keepArticleSibling(sibling, top, scores, threshold) {
+			return
+		}
+		if h, err := goquery.OuterHtml(sibling); err == nil {
+			out.WriteString(h)
+			textChars += len([]rune(collapseSpace(sibling.Text())))
+		}
+	})
+	if textChars < minArticleChars {
+		return "", ErrNoArticle
+	}
+	return out.String(), nil
+}
+
+// keepArticleSibling reports whether a sibling of the top candidate belongs
+// to the article: it is the candidate, scored close to it, or is a paragraph
+// of prose rather than links.
+func keepArticleSibling(sibling, top *goquery.Selection, scores map[*html.Node]float64, threshold float64) bool {
+	node := sibling.Get(0)
+	if node == top.Get(0) {
+		return true
+	}
+	if score, ok := scores[node]; ok && score >= threshold {
+		return true
+	}
+	if !sibling.Is("p") {
+		return false
+	}
+	text := collapseSpace(sibling.Text())
+	density := linkDensity(sibling)
+	n := len([]rune(text))
+	return (n > 80 && density < 0.25) || (n > 0 && density == 0 && sentenceEnd.MatchString(text))
+}
+
+// initialArticleScore is where a candidate's score starts, from its tag and
+// the words in its class and id.
+func initialArticleScore(sel *goquery.Selection) float64 {
+	score := 0.0
+	switch goquery.NodeName(sel) {
+	case "article", "main":
+		score = 10
+	case "div":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+	for _, attr := range []string{"class", "id"} {
+		value := sel.AttrOr(attr, "")
+		if value == "" {
+			continue
+		}
+		if negativeArticleClass.MatchString(value) {
+			score -= 25
+		}
+		if positiveArticleClass.MatchString(value) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// linkDensity is the share of sel's text that is in links.
+func linkDensity(sel *goquery.Selection) float64 {
+	total := len([]rune(collapseSpace(sel.Text())))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	sel.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linked += len([]rune(collapseSpace(a.Text())))
+	})
+	return float64(linked) / float64(total)
+}
+
+// absolutizeURLs resolves the links and image sources of doc against base.
+func absolutizeURLs(doc *goquery.Document, base *url.URL) {
+	for _, target := range []struct{ selector, attr string }{{"a[href]", "href"}, {"img[src]", "src"}} {
+		doc.Find(target.selector).Each(func(_ int, sel *goquery.Selection) {
+			ref, err := url.Parse(strings.TrimSpace(sel.AttrOr(target.attr, "")))
+			if err != nil {
+				return
+			}
+			sel.SetAttr(target.attr, base.ResolveReference(ref).String())
+		})
+	}
+}
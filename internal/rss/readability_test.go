@@ -0,0 +1,64 @@
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const articlePage = `<html><head><title>Story</title><script>track()</script></head><body>
+	<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+	<div class="sidebar"><p>Subscribe to our newsletter, it is full of great things, really, truly, honestly.</p></div>
+	<div id="main-column">
+		<div class="post-content">
+			<h1>The story</h1>
+			<p>The first paragraph of the story is long enough to count, with commas, clauses, and details that matter.</p>
+			<p>A second paragraph carries on with the story, adding context, quotes, and numbers for readers.</p>
+			<p>The third paragraph wraps it up, with a <a href="/more">relative link</a> and an image below.</p>
+			<img src="images/chart.png" alt="Chart">
+		</div>
+		<div class="comments"><p>First! This comment is long enough to score, but it sits in the comments.</p></div>
+	</div>
+	<footer><p>Copyright, all rights reserved, by the publisher of this fine site.</p></footer>
+</body></html>`
+
+func TestExtractArticle(t *testing.T) {
+	article, err := ExtractArticle(strings.NewReader(articlePage), "https://example.com/news/story.html")
+	require.NoError(t, err)
+	assert.Contains(t, article, "The first paragraph of the story")
+	assert.Contains(t, article, "The third paragraph wraps it up")
+	assert.Contains(t, article, `href="https://example.com/more"`)
+	assert.Contains(t, article, `src="https://example.com/news/images/chart.png"`)
+	assert.NotContains(t, article, "newsletter")
+	assert.NotContains(t, article, "First!")
+	assert.NotContains(t, article, "Copyright")
+	assert.NotContains(t, article, "track()")
+	assert.NotContains(t, article, "<h1>") // The message has the item's title already
+
+	_, err = ExtractArticle(strings.NewReader(`<html><body><p>Just a short teaser, nothing more to read here.</p></body></html>`), "https://example.com/")
+	assert.ErrorIs(t, err, ErrNoArticle)
+}
+
+func TestFetchArticle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/news/story.html", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(articlePage))
+	}))
+	defer srv.Close()
+
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "")
+	article, err := fetcher.FetchArticle(context.Background(), srv.URL+"/old", nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, article, "A second paragraph carries on")
+	assert.Contains(t, article, `src="`+srv.URL+`/news/images/chart.png"`) // Resolved against the page redirected to
+}
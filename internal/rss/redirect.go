@@ -0,0 +1,50 @@
+package rss
+
+import (
+	"errors"
+	"net/http"
+)
+
+// maxRedirects matches net/http's default redirect limit.
+const maxRedirects = 10
+
+// redirectTracker records whether every redirect a request followed was
+// permanent (301 or 308), meaning the resource has moved for good.
+type redirectTracker struct {
+	followed  bool
+	permanent bool
+}
+
+// checkRedirect returns a CheckRedirect hook that records each hop and then
+// defers to next, or to net/http's default policy when next is nil. The
+// secretHeader, if any, is dropped on hops to another host: net/http only
+// does that for the standard credential headers.
+func (t *redirectTracker) checkRedirect(next func(*http.Request, []*http.Request) error, secretHeader string) func(*http.Request, []*http.Request) error {
+	t.followed, t.permanent = false, true
+	return func(req *http.Request, via []*http.Request) error {
+		if secretHeader != "" && len(via) > 0 && req.URL.Host != via[0].URL.Host {
+			req.Header.Del(secretHeader)
+		}
+		if next != nil {
+			if err := next(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= maxRedirects {
+			return errors.New("stopped after 10 redirects")
+		}
+		t.followed = true
+		if req.Response == nil || (req.Response.StatusCode != http.StatusMovedPermanently && req.Response.StatusCode != http.StatusPermanentRedirect) {
+			t.permanent = false
+		}
+		return nil
+	}
+}
+
+// permanentURL returns the URL resp was finally served from if the request
+// only got there through permanent redirects, and "" otherwise.
+func (t *redirectTracker) permanentURL(resp *http.Response) string {
+	if !t.followed || !t.permanent || resp.Request == nil {
+		return ""
+	}
+	return resp.Request.URL.String()
+}
@@ -0,0 +1,135 @@
+package rss
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/mmcdole/gofeed"
+)
+
+const htmlAcceptHeader = "text/html, application/xhtml+xml;q=0.9, */*;q=0.5"
+
+// scrapeDateLayouts are tried in order when a ScrapeConfig has no DateLayout.
+var scrapeDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"02.01.2006",
+	"01/02/2006",
+}
+
+// ScrapeHTML synthesizes a feed from an HTML page. Each element matching
+// cfg.ItemSelector becomes an item; relative links are resolved against pageURL.
+// Items without a link are skipped since the link doubles as the GUID.
+func ScrapeHTML(r io.Reader, pageURL string, cfg *database.ScrapeConfig) (*gofeed.Feed, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing HTML: %w", err)
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing page URL: %w", err)
+	}
+
+	feed := &gofeed.Feed{
+		Title:    strings.TrimSpace(doc.Find("title").First().Text()),
+		Link:     pageURL,
+		FeedType: "scrape",
+	}
+	if desc, ok := doc.Find(`meta[name="description"]`).Attr("content"); ok {
+		feed.Description = strings.TrimSpace(desc)
+	}
+
+	doc.Find(cfg.ItemSelector).Each(func(_ int, sel *goquery.Selection) {
+		link := scrapeLink(sel, cfg.LinkSelector)
+		if link == "" {
+			return
+		}
+		ref, err := url.Parse(link)
+		if err != nil {
+			return
+		}
+		link = base.ResolveReference(ref).String()
+
+		item := &gofeed.Item{
+			Title: collapseSpace(scrapeSelect(sel, cfg.TitleSelector).Text()),
+			Link:  link,
+			GUID:  link,
+		}
+		if cfg.ContentSelector != "" {
+			if content, err := sel.Find(cfg.ContentSelector).First().Html(); err == nil {
+				item.Content = strings.TrimSpace(content)
+			}
+		}
+		if cfg.DateSelector != "" {
+			if t := scrapeDate(sel.Find(cfg.DateSelector).First(), cfg.DateLayout); t != nil {
+				item.PublishedParsed = t
+				item.Published = t.Format(time.RFC3339)
+			}
+		}
+		feed.Items = append(feed.Items, item)
+	})
+	return feed, nil
+}
+
+// scrapeSelect returns the first match of selector within sel, or sel itself when selector is empty.
+func scrapeSelect(sel *goquery.Selection, selector string) *goquery.Selection {
+	if selector == "" {
+		return sel
+	}
+	return sel.Find(selector).First()
+}
+
+// scrapeLink finds the item's link: the href of the link selector match, the item
+// itself if it is an anchor, or its first anchor.
+func scrapeLink(sel *goquery.Selection, selector string) string {
+	var target *goquery.Selection
+	switch {
+	case selector != "":
+		target = sel.Find(selector).First()
+	case goquery.NodeName(sel) == "a":
+		target = sel
+	default:
+		target = sel.Find("a[href]").First()
+	}
+	href, _ := target.Attr("href")
+	return strings.TrimSpace(href)
+}
+
+// scrapeDate parses a date from a datetime attribute (as on <time>) or the element text.
+func scrapeDate(sel *goquery.Selection, layout string) *time.Time {
+	raw, ok := sel.Attr("datetime")
+	if !ok {
+		raw = sel.Text()
+	}
+	raw = collapseSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	layouts := scrapeDateLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+	for _, l := range layouts {
+		if t, err := time.Parse(l, raw); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
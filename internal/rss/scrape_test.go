@@ -0,0 +1,51 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScrapeHTML(t *testing.T) {
+	const page = `<html><head><title>News</title></head><body>
+		<article class="post">
+			<h2><a href="/posts/1">First   post</a></h2>
+			<time datetime="2024-05-01T10:00:00Z">May 1</time>
+			<div class="body"><p>Hello</p></div>
+		</article>
+		<article class="post">
+			<h2><a href="https://other.example/2">Second</a></h2>
+			<span class="date">Jun 3, 2024</span>
+		</article>
+		<article class="post"><h2>No link</h2></article>
+	</body></html>`
+
+	cfg := &database.ScrapeConfig{
+		ItemSelector:    "article.post",
+		TitleSelector:   "h2",
+		DateSelector:    "time, .date",
+		ContentSelector: ".body",
+	}
+	feed, err := ScrapeHTML(strings.NewReader(page), "https://example.com/blog/", cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, "News", feed.Title)
+	require.Len(t, feed.Items, 2)
+
+	first := feed.Items[0]
+	assert.Equal(t, "First post", first.Title)
+	assert.Equal(t, "https://example.com/posts/1", first.Link)
+	assert.Equal(t, first.Link, first.GUID)
+	assert.Equal(t, "<p>Hello</p>", first.Content)
+	require.NotNil(t, first.PublishedParsed)
+	assert.True(t, first.PublishedParsed.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)))
+
+	second := feed.Items[1]
+	assert.Equal(t, "https://other.example/2", second.Link)
+	require.NotNil(t, second.PublishedParsed)
+	assert.Equal(t, time.June, second.PublishedParsed.Month())
+}
@@ -0,0 +1,237 @@
+package rss
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+	"github.com/rs/zerolog/log"
+)
+
+const sitemapAcceptHeader = "application/xml, text/xml;q=0.9, */*;q=0.5"
+
+const (
+	// sitemapMaxItems caps the URLs turned into items per fetch, newest first,
+	// so the first fetch of a large site doesn't post its whole archive.
+	sitemapMaxItems = 100
+	// sitemapMaxChildren caps how many sitemaps of an index are read, most
+	// recently modified first.
+	sitemapMaxChildren = 10
+	// pageTitleTimeout bounds each page fetched for an item title.
+	pageTitleTimeout = 15 * time.Second
+	// maxPageTitleBody caps how much of a page is read looking for its title.
+	maxPageTitleBody = 2 << 20
+)
+
+// sitemapDateLayouts are the W3C Datetime forms allowed for <lastmod>.
+var sitemapDateLayouts = []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"}
+
+// sitemapDocument is either a <urlset> or a <sitemapindex>.
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+func (e sitemapEntry) lastModified() *time.Time {
+	raw := strings.TrimSpace(e.LastMod)
+	for _, layout := range sitemapDateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// FetchSitemap polls a sitemap (or sitemap index) and reports its URLs as
+// items. An item's GUID includes the URL's <lastmod>, so modified pages come
+// round again as new items. Items are titled with their URL; the result's
+// EnrichItems replaces that with the page title for the items actually sent.
+func (f *GoFeedFetcher) FetchSitemap(ctx context.Context, url string, etag, lastModified *string, proxy *database.Proxy, opts interfaces.FetchOptions) (*interfaces.FetchResult, error) {
+	opts.BodyHash = "" // An unchanged index can still list changed sitemaps
+	result, err := f.fetch(ctx, url, etag, lastModified, proxy, opts, sitemapAcceptHeader, func(body io.Reader) (*gofeed.Feed, error) {
+		return f.sitemapFeed(ctx, url, body, proxy, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.EnrichItems = func(ctx context.Context, items []*gofeed.Item) {
+		f.fillPageTitles(ctx, items, proxy, opts)
+	}
+	return result, nil
+}
+
+// sitemapFeed builds a feed from a sitemap body, reading the most recently
+// modified children when it is an index.
+func (f *GoFeedFetcher) sitemapFeed(ctx context.Context, sitemapURL string, body io.Reader, proxy *database.Proxy, opts interfaces.FetchOptions) (*gofeed.Feed, error) {
+	var doc sitemapDocument
+	if err := xml.NewDecoder(body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing sitemap: %w", err)
+	}
+	entries := doc.URLs
+	switch doc.XMLName.Local {
+	case "urlset":
+	case "sitemapindex":
+		children := doc.Sitemaps
+		sortSitemapEntries(children)
+		if len(children) > sitemapMaxChildren {
+			children = children[:sitemapMaxChildren]
+		}
+		for _, child := range children {
+			childDoc, err := f.fetchChildSitemap(ctx, child.Loc, proxy, opts)
+			if err != nil {
+				log.Warn().Err(err).Str("sitemap_url", child.Loc).Msg("Skipping unreadable child sitemap")
+				continue
+			}
+			entries = append(entries, childDoc.URLs...)
+		}
+	default:
+		return nil, fmt.Errorf("not a sitemap: root element <%s>", doc.XMLName.Local)
+	}
+
+	sortSitemapEntries(entries)
+	if len(entries) > sitemapMaxItems {
+		entries = entries[:sitemapMaxItems]
+	}
+	feed := &gofeed.Feed{Title: sitemapURL, Link: sitemapURL, FeedType: "sitemap"}
+	for _, e := range entries {
+		loc := strings.TrimSpace(e.Loc)
+		if loc == "" {
+			continue
+		}
+		item := &gofeed.Item{Title: loc, Link: loc, GUID: loc}
+		if t := e.lastModified(); t != nil {
+			item.GUID = loc + "#" + t.UTC().Format(time.RFC3339)
+			item.PublishedParsed = t
+			item.Published = t.Format(time.RFC3339)
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed, nil
+}
+
+// sortSitemapEntries orders entries by lastmod, newest first; undated entries
+// keep their document order after the dated ones.
+func sortSitemapEntries(entries []sitemapEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, tj := entries[i].lastModified(), entries[j].lastModified()
+		if ti == nil || tj == nil {
+			return ti != nil && tj == nil
+		}
+		return ti.After(*tj)
+	})
+}
+
+// fetchChildSitemap fetches one sitemap of an index. Child sitemaps are often
+// gzipped files (.xml.gz) served without a Content-Encoding.
+func (f *GoFeedFetcher) fetchChildSitemap(ctx context.Context, url string, proxy *database.Proxy, opts interfaces.FetchOptions) (*sitemapDocument, error) {
+	resp, err := f.simpleGet(ctx, url, sitemapAcceptHeader, proxy, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := decompressBody(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	buffered := bufio.NewReader(io.LimitReader(body, f.maxBodySize))
+	var r io.Reader = buffered
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("opening gzipped sitemap: %w", err)
+		}
+		defer zr.Close()
+		r = io.LimitReader(zr, f.maxBodySize)
+	}
+	var doc sitemapDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing sitemap %s: %w", url, err)
+	}
+	return &doc, nil
+}
+
+// fillPageTitles replaces URL titles with each page's <title> (or og:title).
+// Pages that can't be fetched keep the URL as their title.
+func (f *GoFeedFetcher) fillPageTitles(ctx context.Context, items []*gofeed.Item, proxy *database.Proxy, opts interfaces.FetchOptions) {
+	for _, item := range items {
+		if item.Title != item.Link {
+			continue
+		}
+		if title := f.pageTitle(ctx, item.Link, proxy, opts); title != "" {
+			item.Title = title
+		}
+	}
+}
+
+func (f *GoFeedFetcher) pageTitle(ctx context.Context, pageURL string, proxy *database.Proxy, opts interfaces.FetchOptions) string {
+	ctx, cancel := context.WithTimeout(ctx, pageTitleTimeout)
+	defer cancel()
+	resp, err := f.simpleGet(ctx, pageURL, htmlAcceptHeader, proxy, opts)
+	if err != nil {
+		log.Debug().Err(err).Str("page_url", pageURL).Msg("Failed to fetch page for its title")
+		return ""
+	}
+	defer resp.Body.Close()
+	body, err := readBody(resp, maxPageTitleBody)
+	if err != nil {
+		return ""
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	if og, ok := doc.Find(`meta[property="og:title"]`).Attr("content"); ok && strings.TrimSpace(og) != "" {
+		return collapseSpace(og)
+	}
+	return collapseSpace(doc.Find("title").First().Text())
+}
+
+// simpleGet performs a single GET with the feed's client, request options and
+// host rate limit, returning only 200 responses.
+func (f *GoFeedFetcher) simpleGet(ctx context.Context, url, accept string, proxy *database.Proxy, opts interfaces.FetchOptions) (*http.Response, error) {
+	if hosts := f.hosts.Load(); hosts != nil {
+		if err := hosts.Wait(ctx, url); err != nil {
+			return nil, err
+		}
+	}
+	client, err := f.clientFor(proxy, opts)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Accept-Encoding", acceptEncodingHeader)
+	applyFetchOptions(req, opts)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s: status %d", url, resp.StatusCode)
+	}
+	return resp, nil
+}
@@ -0,0 +1,74 @@
+package rss
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchSitemapIndex(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<?xml version="1.0"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/old.xml</loc><lastmod>2024-01-01</lastmod></sitemap>
+  <sitemap><loc>%[1]s/posts.xml.gz</loc><lastmod>2025-02-01T10:00:00Z</lastmod></sitemap>
+</sitemapindex>`, srv.URL)
+		case "/old.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>%s/about</loc></url></urlset>`, srv.URL)
+		case "/posts.xml.gz":
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			fmt.Fprintf(zw, `<urlset>
+  <url><loc>%[1]s/posts/1</loc><lastmod>2025-01-30</lastmod></url>
+  <url><loc>%[1]s/posts/2</loc><lastmod>2025-02-01T09:30:00+01:00</lastmod></url>
+</urlset>`, srv.URL)
+			zw.Close()
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(buf.Bytes())
+		case "/posts/2":
+			fmt.Fprint(w, `<html><head><title> Second   post </title></head></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "")
+	result, err := fetcher.FetchSitemap(context.Background(), srv.URL+"/sitemap.xml", nil, nil, nil, interfaces.FetchOptions{})
+	require.NoError(t, err)
+	items := result.Feed.Items
+	require.Len(t, items, 3)
+
+	assert.Equal(t, srv.URL+"/posts/2", items[0].Link, "newest lastmod first")
+	assert.Equal(t, srv.URL+"/posts/2#2025-02-01T08:30:00Z", items[0].GUID, "GUID changes with lastmod")
+	assert.Equal(t, srv.URL+"/posts/1", items[1].Link)
+	assert.Equal(t, srv.URL+"/about", items[2].Link)
+	assert.Equal(t, srv.URL+"/about", items[2].GUID, "undated URLs are keyed by location only")
+
+	require.NotNil(t, result.EnrichItems)
+	result.EnrichItems(context.Background(), items[:2])
+	assert.Equal(t, "Second post", items[0].Title)
+	assert.Equal(t, srv.URL+"/posts/1", items[1].Title, "pages that fail keep their URL as title")
+}
+
+func TestFetchSitemapRejectsOtherXML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testRSS)
+	}))
+	defer srv.Close()
+
+	fetcher := NewGoFeedFetcher(staticClientFactory{srv.Client()}, "")
+	_, err := fetcher.FetchSitemap(context.Background(), srv.URL, nil, nil, nil, interfaces.FetchOptions{})
+	assert.ErrorContains(t, err, "not a sitemap")
+}
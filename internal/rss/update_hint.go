@@ -0,0 +1,76 @@
+package rss
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	rssfeed "github.com/mmcdole/gofeed/rss"
+)
+
+// CustomKeyTTL is set on gofeed.Feed.Custom by RSSFeedTranslator to the
+// channel's <ttl>, in minutes.
+const CustomKeyTTL = "ttl"
+
+// RSSFeedTranslator wraps gofeed's DefaultRSSTranslator to keep the channel's
+// <ttl>, which the universal feed model otherwise drops.
+type RSSFeedTranslator struct {
+	gofeed.DefaultRSSTranslator
+}
+
+// Translate converts an *rss.Feed into the universal feed type.
+func (t *RSSFeedTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	source, ok := feed.(*rssfeed.Feed)
+	if !ok {
+		return nil, fmt.Errorf("feed did not match expected type of *rss.Feed")
+	}
+	result, err := t.DefaultRSSTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+	if ttl := strings.TrimSpace(source.TTL); ttl != "" {
+		setFeedCustom(result, CustomKeyTTL, ttl)
+	}
+	return result, nil
+}
+
+// syUpdatePeriods maps sy:updatePeriod values to durations.
+var syUpdatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// UpdateInterval returns how often a feed says it is updated: RSS <ttl>, or
+// the syndication module's sy:updatePeriod divided by sy:updateFrequency. When
+// both are present the longer wins. It returns zero if the feed declares
+// neither.
+func UpdateInterval(feed *gofeed.Feed) time.Duration {
+	if feed == nil {
+		return 0
+	}
+	var interval time.Duration
+	if minutes, err := strconv.Atoi(feed.Custom[CustomKeyTTL]); err == nil && minutes > 0 {
+		interval = time.Duration(minutes) * time.Minute
+	}
+	sy := feed.Extensions["sy"]
+	if periodExt := sy["updatePeriod"]; len(periodExt) > 0 {
+		period, ok := syUpdatePeriods[strings.ToLower(strings.TrimSpace(periodExt[0].Value))]
+		if ok {
+			frequency := 1
+			if freqExt := sy["updateFrequency"]; len(freqExt) > 0 {
+				if n, err := strconv.Atoi(strings.TrimSpace(freqExt[0].Value)); err == nil && n > 0 {
+					frequency = n
+				}
+			}
+			if d := period / time.Duration(frequency); d > interval {
+				interval = d
+			}
+		}
+	}
+	return interval
+}
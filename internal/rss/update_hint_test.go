@@ -0,0 +1,34 @@
+package rss
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateInterval(t *testing.T) {
+	cases := []struct {
+		name    string
+		channel string
+		want    time.Duration
+	}{
+		{"none", ``, 0},
+		{"ttl", `<ttl>90</ttl>`, 90 * time.Minute},
+		{"sy period", `<sy:updatePeriod>daily</sy:updatePeriod>`, 24 * time.Hour},
+		{"sy frequency", `<sy:updatePeriod>hourly</sy:updatePeriod><sy:updateFrequency>4</sy:updateFrequency>`, 15 * time.Minute},
+		{"longer wins", `<ttl>60</ttl><sy:updatePeriod>daily</sy:updatePeriod><sy:updateFrequency>2</sy:updateFrequency>`, 12 * time.Hour},
+		{"invalid ignored", `<ttl>soon</ttl><sy:updatePeriod>fortnightly</sy:updatePeriod>`, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := `<rss version="2.0" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel><title>T</title>` +
+				tc.channel + `<item><title>A</title></item></channel></rss>`
+			feed, err := newFeedParser().ParseString(doc)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, UpdateInterval(feed))
+		})
+	}
+	assert.Zero(t, UpdateInterval(nil))
+}
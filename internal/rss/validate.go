@@ -0,0 +1,212 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/mmcdole/gofeed"
+)
+
+// Severities of validation findings.
+const (
+	SeverityError   = "error"   // The bot will misbehave, e.g. skip or resend items
+	SeverityWarning = "warning" // The feed works, but less well than it could
+	SeverityInfo    = "info"
+)
+
+// manyItemsThreshold is the item count above which a feed is reported as
+// unusually large.
+const manyItemsThreshold = 500
+
+// Finding is one issue found while validating a feed.
+type Finding struct {
+	Severity string
+	Message  string
+}
+
+// ValidationReport describes a fetched feed and the issues found in it.
+type ValidationReport struct {
+	URL      string
+	FeedType string // e.g. "rss 2.0", "atom 1.0", "json 1.1"
+	Title    string
+	Items    int
+	Findings []Finding
+}
+
+// HasErrors reports whether any finding has SeverityError.
+func (r *ValidationReport) HasErrors() bool {
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ValidationReport) add(severity, format string, args ...interface{}) {
+	r.Findings = append(r.Findings, Finding{Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateFeed fetches feedURL and checks it against the assumptions the worker
+// makes: stable item identifiers, dates, well-formed markup, a reasonable size,
+// and caching headers for conditional requests.
+// Adapter URLs (see AdapterFor) are resolved and checked as the adapter parses them.
+func ValidateFeed(ctx context.Context, httpClient *http.Client, feedURL string, opts interfaces.FetchOptions) (*ValidationReport, error) {
+	target, err := ResolveSourceURL(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	accept, parse := feedAcceptHeader, ParseFeed
+	if adapter := AdapterFor(feedURL); adapter != nil {
+		accept = adapter.Accept()
+		parse = func(body io.Reader) (*gofeed.Feed, error) { return adapter.Parse(body, target) }
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", target, err)
+	}
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Accept-Encoding", acceptEncodingHeader)
+	applyFetchOptions(req, opts)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", feedURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: status %d", feedURL, resp.StatusCode)
+	}
+	body, err := readBody(resp, DefaultMaxBodySize)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", feedURL, err)
+	}
+	return lintFeed(feedURL, resp.Header, body, parse), nil
+}
+
+// lintFeed checks an already fetched (decompressed, UTF-8) feed body.
+func lintFeed(feedURL string, header http.Header, body []byte, parse func(io.Reader) (*gofeed.Feed, error)) *ValidationReport {
+	report := &ValidationReport{URL: feedURL}
+
+	if header.Get("ETag") == "" && header.Get("Last-Modified") == "" {
+		report.add(SeverityWarning, "no ETag or Last-Modified header: every poll downloads the whole feed")
+	}
+	if mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil && mediaType == "text/html" {
+		report.add(SeverityWarning, "served as text/html; this may be a web page rather than a feed (try 'feed add' to discover the real feed)")
+	}
+	if len(body) > 5<<20 {
+		report.add(SeverityWarning, "feed is %.1f MiB; large feeds are slow to fetch and parse", float64(len(body))/(1<<20))
+	}
+
+	feed, err := parse(bytes.NewReader(body))
+	if err != nil {
+		report.add(SeverityError, "not a parseable feed: %v", err)
+		return report
+	}
+	report.FeedType = strings.TrimSpace(feed.FeedType + " " + feed.FeedVersion)
+	report.Title = feed.Title
+	report.Items = len(feed.Items)
+
+	if msg := wellFormedError(feed.FeedType, body); msg != "" {
+		syntax := "XML"
+		if feed.FeedType == "json" {
+			syntax = "JSON"
+		}
+		report.add(SeverityWarning, "malformed %s recovered by the parser (%s); other readers may reject it", syntax, msg)
+	}
+	lintItems(report, feed)
+
+	if hub, _ := WebSubLinks(feed, header); hub != "" {
+		report.add(SeverityInfo, "advertises WebSub hub %s; new items can be pushed instead of polled", hub)
+	}
+	return report
+}
+
+// wellFormedError returns why body isn't well-formed XML or JSON, or "".
+// Documents built by source adapters aren't checked.
+func wellFormedError(feedType string, body []byte) string {
+	switch feedType {
+	case "rss", "atom":
+	case "json":
+		if !json.Valid(body) {
+			return "invalid JSON"
+		}
+		return ""
+	default:
+		return ""
+	}
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = true
+	dec.CharsetReader = func(label string, input io.Reader) (io.Reader, error) { return input, nil } // Already UTF-8
+	for {
+		if _, err := dec.Token(); err != nil {
+			if err == io.EOF {
+				return ""
+			}
+			return err.Error()
+		}
+	}
+}
+
+// lintItems reports the item-level problems that affect deduplication and ordering.
+func lintItems(report *ValidationReport, feed *gofeed.Feed) {
+	if len(feed.Items) == 0 {
+		report.add(SeverityWarning, "feed has no items")
+		return
+	}
+	if len(feed.Items) > manyItemsThreshold {
+		report.add(SeverityWarning, "feed has %d items; the first fetch will post all of them", len(feed.Items))
+	}
+
+	var noGUID, noIdentifier, noDate, futureDate int
+	seen := make(map[string]int)
+	duplicates := 0
+	now := time.Now()
+	for _, item := range feed.Items {
+		id := item.GUID
+		if id == "" {
+			noGUID++
+			id = item.Link
+		}
+		if id == "" {
+			noIdentifier++
+		} else if seen[id]++; seen[id] == 2 {
+			duplicates++
+		}
+		date := item.PublishedParsed
+		if date == nil {
+			date = item.UpdatedParsed
+		}
+		if date == nil {
+			noDate++
+		} else if date.After(now.Add(24 * time.Hour)) {
+			futureDate++
+		}
+	}
+
+	total := len(feed.Items)
+	if noIdentifier > 0 {
+		report.add(SeverityError, "%d of %d items have neither a GUID nor a link and will be skipped", noIdentifier, total)
+	}
+	if noGUID > noIdentifier {
+		report.add(SeverityWarning, "%d of %d items have no GUID; their link is used instead, so a changed link re-posts the item", noGUID-noIdentifier, total)
+	}
+	if duplicates > 0 {
+		report.add(SeverityWarning, "%d identifiers are shared by several items, which the bot can't tell apart", duplicates)
+	}
+	if noDate > 0 {
+		report.add(SeverityWarning, "%d of %d items have no date; they are ordered as listed and stale-feed detection can't use them", noDate, total)
+	}
+	if futureDate > 0 {
+		report.add(SeverityWarning, "%d items are dated more than a day in the future", futureDate)
+	}
+}
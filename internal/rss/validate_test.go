@@ -0,0 +1,57 @@
+package rss
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func findingMessages(r *ValidationReport, severity string) []string {
+	var out []string
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			out = append(out, f.Message)
+		}
+	}
+	return out
+}
+
+func TestLintFeed(t *testing.T) {
+	body := `<rss version="2.0"><channel><title>Lint & Co</title>
+<item><title>No id</title></item>
+<item><title>Link only</title><link>https://example.com/a</link></item>
+<item><title>Dated</title><guid>x</guid><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>
+<item><title>Dup</title><guid>x</guid></item>
+</channel></rss>`
+	report := lintFeed("https://example.com/feed", http.Header{}, []byte(body), ParseFeed)
+
+	assert.Equal(t, "Lint & Co", report.Title)
+	assert.Equal(t, 4, report.Items)
+	assert.True(t, report.HasErrors())
+
+	errs := strings.Join(findingMessages(report, SeverityError), "\n")
+	assert.Contains(t, errs, "1 of 4 items have neither a GUID nor a link")
+
+	warnings := strings.Join(findingMessages(report, SeverityWarning), "\n")
+	assert.Contains(t, warnings, "no ETag or Last-Modified")
+	assert.Contains(t, warnings, "malformed XML", "the bare & is recovered")
+	assert.Contains(t, warnings, "1 of 4 items have no GUID")
+	assert.Contains(t, warnings, "1 identifiers are shared")
+	assert.Contains(t, warnings, "3 of 4 items have no date")
+}
+
+func TestLintFeed_Clean(t *testing.T) {
+	header := http.Header{}
+	header.Set("ETag", `"v1"`)
+	report := lintFeed("https://example.com/feed", header, []byte(`<rss version="2.0"><channel><title>T</title>
+<item><guid>1</guid><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item></channel></rss>`), ParseFeed)
+	assert.Empty(t, report.Findings)
+	assert.Equal(t, "rss 2.0", report.FeedType)
+}
+
+func TestLintFeed_NotAFeed(t *testing.T) {
+	report := lintFeed("https://example.com/", http.Header{"Etag": {"x"}}, []byte(`<html><body>hi</body></html>`), ParseFeed)
+	assert.True(t, report.HasErrors())
+}
@@ -0,0 +1,121 @@
+package rss
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/atom"
+)
+
+// Custom keys set on gofeed.Feed.Custom by AtomFeedTranslator.
+const (
+	CustomKeyHub         = "websub_hub"
+	CustomKeySelf        = "websub_self"
+	CustomKeyPrevArchive = "prev_archive" // RFC 5005 archived feed paging
+)
+
+// AtomFeedTranslator wraps gofeed's DefaultAtomTranslator to keep the rel="hub",
+// rel="self" and rel="prev-archive" links, which the universal feed model otherwise drops.
+type AtomFeedTranslator struct {
+	gofeed.DefaultAtomTranslator
+}
+
+// Translate converts an *atom.Feed into the universal feed type.
+func (t *AtomFeedTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	source, ok := feed.(*atom.Feed)
+	if !ok {
+		return nil, fmt.Errorf("feed did not match expected type of *atom.Feed")
+	}
+	result, err := t.DefaultAtomTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range source.Links {
+		if link == nil || link.Href == "" {
+			continue
+		}
+		switch link.Rel {
+		case "hub":
+			setFeedCustom(result, CustomKeyHub, link.Href)
+		case "self":
+			setFeedCustom(result, CustomKeySelf, link.Href)
+		case "prev-archive":
+			setFeedCustom(result, CustomKeyPrevArchive, link.Href)
+		}
+	}
+	return result, nil
+}
+
+func setFeedCustom(feed *gofeed.Feed, key, value string) {
+	if feed.Custom == nil {
+		feed.Custom = make(map[string]string)
+	}
+	if _, exists := feed.Custom[key]; !exists {
+		feed.Custom[key] = value
+	}
+}
+
+// WebSubLinks returns the hub and topic (self) URLs advertised for a feed, per
+// WebSub discovery: HTTP Link headers take precedence over links in the document.
+// Either value is empty when not advertised.
+func WebSubLinks(feed *gofeed.Feed, header http.Header) (hub, self string) {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			href, rels := parseLinkHeader(link)
+			for _, rel := range rels {
+				if rel == "hub" && hub == "" {
+					hub = href
+				} else if rel == "self" && self == "" {
+					self = href
+				}
+			}
+		}
+	}
+	if feed == nil {
+		return hub, self
+	}
+	if hub == "" {
+		hub = feed.Custom[CustomKeyHub]
+	}
+	if self == "" {
+		self = feed.Custom[CustomKeySelf]
+	}
+	// RSS feeds advertise the hub with <atom:link rel="hub">.
+	for _, prefix := range []string{"atom", "atom10"} {
+		for _, ext := range feed.Extensions[prefix]["link"] {
+			switch ext.Attrs["rel"] {
+			case "hub":
+				if hub == "" {
+					hub = ext.Attrs["href"]
+				}
+			case "self":
+				if self == "" {
+					self = ext.Attrs["href"]
+				}
+			}
+		}
+	}
+	return hub, self
+}
+
+// parseLinkHeader splits one Link header entry, `<url>; rel="hub self"`, into its
+// URL and rel values.
+func parseLinkHeader(link string) (string, []string) {
+	link = strings.TrimSpace(link)
+	end := strings.Index(link, ">")
+	if !strings.HasPrefix(link, "<") || end < 0 {
+		return "", nil
+	}
+	href := link[1:end]
+	var rels []string
+	for _, param := range strings.Split(link[end+1:], ";") {
+		name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+			continue
+		}
+		rels = append(rels, strings.Fields(strings.Trim(strings.TrimSpace(value), `"`))...)
+	}
+	return href, rels
+}
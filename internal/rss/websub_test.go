@@ -0,0 +1,42 @@
+package rss
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSubLinks(t *testing.T) {
+	const atomDoc = `<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>T</title>
+  <link rel="hub" href="https://hub.example/"/>
+  <link rel="self" href="https://example.com/feed.atom"/>
+</feed>`
+	feed, err := ParseFeed(strings.NewReader(atomDoc))
+	require.NoError(t, err)
+
+	hub, self := WebSubLinks(feed, http.Header{})
+	assert.Equal(t, "https://hub.example/", hub)
+	assert.Equal(t, "https://example.com/feed.atom", self)
+
+	header := http.Header{}
+	header.Add("Link", `<https://header-hub.example/>; rel="hub", <https://example.com/self>; rel="self"`)
+	hub, self = WebSubLinks(feed, header)
+	assert.Equal(t, "https://header-hub.example/", hub)
+	assert.Equal(t, "https://example.com/self", self)
+
+	const rssDoc = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+  <title>T</title>
+  <atom:link rel="hub" href="https://rss-hub.example/"/>
+</channel></rss>`
+	feed, err = ParseFeed(strings.NewReader(rssDoc))
+	require.NoError(t, err)
+	hub, self = WebSubLinks(feed, http.Header{})
+	assert.Equal(t, "https://rss-hub.example/", hub)
+	assert.Empty(t, self)
+}
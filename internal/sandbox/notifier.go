@@ -0,0 +1,191 @@
+// Package sandbox provides a notifier that records sends instead of making
+// them, and a server with fixture feeds to poll, so templates, filters and
+// schedules can be rehearsed end to end without touching Telegram.
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+)
+
+// Send is a recorded send.
+type Send struct {
+	ID       int64     `json:"id"`
+	Time     time.Time `json:"time"`
+	BotID    string    `json:"bot_id"` // The part of the bot token before the colon; the secret part is not kept
+	ChatID   string    `json:"chat_id"`
+	ThreadID int       `json:"thread_id,omitempty"`
+	Topic    string    `json:"topic,omitempty"` // Name of a forum topic created instead of a message sent
+	Parts    []Part    `json:"parts,omitempty"`
+}
+
+// Part is a recorded message part.
+type Part struct {
+	Text            string                      `json:"text,omitempty"`
+	ParseMode       string                      `json:"parse_mode,omitempty"`
+	PhotoURL        string                      `json:"photo_url,omitempty"`
+	VideoURL        string                      `json:"video_url,omitempty"`
+	AnimationURL    string                      `json:"animation_url,omitempty"`
+	DocumentURL     string                      `json:"document_url,omitempty"`
+	DocumentCaption string                      `json:"document_caption,omitempty"`
+	DocumentName    string                      `json:"document_name,omitempty"`
+	MediaGroup      []interfaces.MediaGroupItem `json:"media_group,omitempty"`
+	Reaction        string                      `json:"reaction,omitempty"`
+	DiscussButton   string                      `json:"discuss_button,omitempty"`
+}
+
+// Notifier records what would be sent to Telegram. It implements
+// interfaces.ThreadNotifier, so feeds posting into forum topics or through
+// bot pools work as they would with the real client.
+type Notifier struct {
+	mu     sync.Mutex
+	sends  []Send // Oldest first, max at most
+	max    int
+	nextID int64
+	file   *os.File // nil keeps sends in memory only
+	topics int      // Forum topics created so far
+	pools  map[string]int
+}
+
+// NewNotifier creates a Notifier keeping the last max sends in memory. When
+// file is not empty, every send is also appended to it as a JSON line.
+func NewNotifier(max int, file string) (*Notifier, error) {
+	if max <= 0 {
+		max = 1000
+	}
+	n := &Notifier{max: max, pools: make(map[string]int)}
+	if file != "" {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("opening sandbox file: %w", err)
+		}
+		n.file = f
+	}
+	return n, nil
+}
+
+// Name identifies the notifier in logs and metrics.
+func (n *Notifier) Name() string { return "sandbox" }
+
+// Send records parts as sent to chatID.
+func (n *Notifier) Send(ctx context.Context, botToken, chatID string, parts []interfaces.FormattedMessagePart, proxy *database.Proxy) error {
+	return n.SendToThread(ctx, botToken, chatID, 0, parts, proxy)
+}
+
+// SendToThread records parts as sent to a forum topic of chatID.
+func (n *Notifier) SendToThread(ctx context.Context, botToken, chatID string, threadID int, parts []interfaces.FormattedMessagePart, proxy *database.Proxy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	send := Send{BotID: botID(botToken), ChatID: chatID, ThreadID: threadID}
+	for _, p := range parts {
+		send.Parts = append(send.Parts, Part{
+			Text: p.Text, ParseMode: p.ParseMode, PhotoURL: p.PhotoURL, VideoURL: p.VideoURL, AnimationURL: p.AnimationURL,
+			DocumentURL: p.DocumentURL, DocumentCaption: p.DocumentCaption, DocumentName: p.DocumentName, MediaGroup: p.MediaGroup,
+			Reaction: p.Reaction, DiscussButton: p.DiscussButton,
+		})
+	}
+	return n.record(send)
+}
+
+// NextPoolToken rotates through the tokens of a bot pool.
+func (n *Notifier) NextPoolToken(poolKey string, tokens []string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	i := n.pools[poolKey] % len(tokens)
+	n.pools[poolKey] = i + 1
+	return tokens[i]
+}
+
+// ResolvedChatID reports that no @username is resolved: chats are recorded
+// as configured.
+func (n *Notifier) ResolvedChatID(chatID string) (int64, bool) {
+	return 0, false
+}
+
+// CreateForumTopic records the topic and returns a made-up ID for it.
+func (n *Notifier) CreateForumTopic(ctx context.Context, botToken, chatID, name string, proxy *database.Proxy) (int, error) {
+	n.mu.Lock()
+	n.topics++
+	id := n.topics
+	n.mu.Unlock()
+	if err := n.record(Send{BotID: botID(botToken), ChatID: chatID, ThreadID: id, Topic: name}); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ProxyPicker returns nil: sandbox sends use no proxies.
+func (n *Notifier) ProxyPicker() interfaces.ProxyPicker {
+	return nil
+}
+
+// record stores send, appending it to the file if there is one.
+func (n *Notifier) record(send Send) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.nextID++
+	send.ID = n.nextID
+	send.Time = time.Now().UTC()
+	n.sends = append(n.sends, send)
+	if len(n.sends) > n.max {
+		n.sends = append(n.sends[:0], n.sends[len(n.sends)-n.max:]...)
+	}
+	if n.file == nil {
+		return nil
+	}
+	line, err := json.Marshal(send)
+	if err != nil {
+		return err
+	}
+	if _, err := n.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing sandbox file: %w", err)
+	}
+	return nil
+}
+
+// Sends returns the recorded sends still kept in memory, oldest first. When
+// chatID is not empty, only sends to that chat are returned.
+func (n *Notifier) Sends(chatID string) []Send {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	sends := []Send{}
+	for _, s := range n.sends {
+		if chatID == "" || s.ChatID == chatID {
+			sends = append(sends, s)
+		}
+	}
+	return sends
+}
+
+// Reset forgets the sends kept in memory. The file, if any, is kept.
+func (n *Notifier) Reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sends = nil
+}
+
+// Close closes the file, if any.
+func (n *Notifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.file == nil {
+		return nil
+	}
+	err := n.file.Close()
+	n.file = nil
+	return err
+}
+
+func botID(token string) string {
+	id, _, _ := strings.Cut(token, ":")
+	return id
+}
@@ -0,0 +1,130 @@
+package sandbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/haytac/rss-telegram-bot/internal/rss"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifierRecordsSends(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sends.jsonl")
+	n, err := NewNotifier(2, file)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, n.Send(ctx, "111:secret", "@news", []interfaces.FormattedMessagePart{{Text: "<b>One</b>", ParseMode: "HTML"}}, nil))
+	topic, err := n.CreateForumTopic(ctx, "111:secret", "-100", "Releases", nil)
+	require.NoError(t, err)
+	require.NoError(t, n.SendToThread(ctx, "222:secret", "-100", topic, []interfaces.FormattedMessagePart{{Text: "Two", PhotoURL: "https://example.com/a.jpg"}}, nil))
+
+	// Only the last two are kept in memory
+	sends := n.Sends("")
+	require.Len(t, sends, 2)
+	assert.Equal(t, "Releases", sends[0].Topic)
+	assert.Equal(t, Send{ID: 3, Time: sends[1].Time, BotID: "222", ChatID: "-100", ThreadID: topic, Parts: []Part{{Text: "Two", PhotoURL: "https://example.com/a.jpg"}}}, sends[1])
+	assert.Empty(t, n.Sends("@news"))
+	assert.Equal(t, "a", n.NextPoolToken("pool:1", []string{"a", "b"}))
+	assert.Equal(t, "b", n.NextPoolToken("pool:1", []string{"a", "b"}))
+
+	// The file has all of them, without the secret part of tokens
+	require.NoError(t, n.Close())
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+	var lines []Send
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		assert.NotContains(t, scanner.Text(), "secret")
+		var s Send
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &s))
+		lines = append(lines, s)
+	}
+	require.Len(t, lines, 3)
+	assert.Equal(t, "@news", lines[0].ChatID)
+	assert.Equal(t, []Part{{Text: "<b>One</b>", ParseMode: "HTML"}}, lines[0].Parts)
+}
+
+func TestServer(t *testing.T) {
+	n, err := NewNotifier(10, "")
+	require.NoError(t, err)
+	s, err := NewServer(n, "")
+	require.NoError(t, err)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	// The built-in fixtures parse like real feeds
+	for _, name := range []string{"news.xml", "atom.xml", "feed.json", "ticker.xml?every=1h&items=3"} {
+		resp, err := http.Get(srv.URL + "/feeds/" + name)
+		require.NoError(t, err, name)
+		feed, err := rss.ParseFeed(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err, name)
+		assert.NotEmpty(t, feed.Items, name)
+	}
+	resp, err := http.Get(srv.URL + "/feeds/ticker.xml?every=1h&items=3")
+	require.NoError(t, err)
+	ticker, err := rss.ParseFeed(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Len(t, ticker.Items, 3)
+	assert.Equal(t, 1, int(ticker.Items[0].PublishedParsed.Sub(*ticker.Items[1].PublishedParsed).Hours()))
+
+	resp, err = http.Get(srv.URL + "/feeds/ticker.xml?every=1ms")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	require.NoError(t, n.Send(context.Background(), "1:x", "a", []interfaces.FormattedMessagePart{{Text: "first"}}, nil))
+	require.NoError(t, n.Send(context.Background(), "1:x", "b", []interfaces.FormattedMessagePart{{Text: "second"}}, nil))
+	require.NoError(t, n.Send(context.Background(), "1:x", "a", []interfaces.FormattedMessagePart{{Text: "third"}}, nil))
+	getSends := func(query string) []Send {
+		resp, err := http.Get(srv.URL + "/sends" + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var sends []Send
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&sends))
+		return sends
+	}
+	assert.Len(t, getSends(""), 3)
+	sends := getSends("?chat=a&limit=1")
+	require.Len(t, sends, 1)
+	assert.Equal(t, "third", sends[0].Parts[0].Text)
+
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/sends", nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, getSends(""))
+}
+
+func TestServerFixturesDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mine.xml"), []byte(`<rss version="2.0"><channel><title>Mine</title><item><title>Hi</title></item></channel></rss>`), 0o644))
+	n, err := NewNotifier(10, "")
+	require.NoError(t, err)
+	s, err := NewServer(n, dir)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feeds/mine.xml", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<title>Mine</title>")
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feeds/news.xml", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	_, err = NewServer(n, filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
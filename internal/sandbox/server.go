@@ -0,0 +1,175 @@
+package sandbox
+
+import (
+	"embed"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// builtinFixtures are the feeds served when no fixtures directory is set.
+//
+//go:embed fixtures
+var builtinFixtures embed.FS
+
+// Ticker feed bounds, keeping generated documents small.
+const (
+	defaultTickerEvery = time.Minute
+	minTickerEvery     = time.Second
+	defaultTickerItems = 10
+	maxTickerItems     = 100
+)
+
+// Server serves fixture feeds at /feeds/ and the sends a Notifier recorded
+// at /sends. /feeds/ticker.xml is generated: a feed gaining an item every
+// ?every= (one minute by default), for rehearsing schedules and delivery of
+// new items.
+type Server struct {
+	notifier *Notifier
+	fixtures fs.FS
+}
+
+// NewServer creates a Server for notifier. Fixtures are read from dir, or
+// the built-in ones when dir is empty.
+func NewServer(notifier *Notifier, dir string) (*Server, error) {
+	var fixtures fs.FS
+	if dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("sandbox.fixtures_dir: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("sandbox.fixtures_dir %q is not a directory", dir)
+		}
+		fixtures = os.DirFS(dir)
+	} else {
+		sub, err := fs.Sub(builtinFixtures, "fixtures")
+		if err != nil {
+			return nil, err // The embedded directory is fixed at build time
+		}
+		fixtures = sub
+	}
+	return &Server{notifier: notifier, fixtures: fixtures}, nil
+}
+
+// Handler returns the HTTP handler of the server.
+func (s *Server) Handler() http.Handler {
+	mux := chi.NewRouter()
+	mux.Get("/feeds/ticker.xml", s.serveTicker)
+	mux.Handle("/feeds/*", http.StripPrefix("/feeds/", http.FileServerFS(s.fixtures)))
+	mux.Get("/sends", s.serveSends)
+	mux.Delete("/sends", s.resetSends)
+	return mux
+}
+
+// StartServer serves on addr in the background.
+func (s *Server) StartServer(addr string) {
+	log.Info().Str("address", addr).Msg("Starting sandbox server")
+	go func() {
+		if err := http.ListenAndServe(addr, s.Handler()); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("Sandbox server failed")
+		}
+	}()
+}
+
+// serveSends lists the recorded sends, oldest first, narrowed down by
+// ?chat=<chat_id> and ?limit=<n> (the newest n).
+func (s *Server) serveSends(w http.ResponseWriter, r *http.Request) {
+	sends := s.notifier.Sends(r.URL.Query().Get("chat"))
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, fmt.Sprintf("invalid limit %q", v), http.StatusBadRequest)
+			return
+		}
+		if len(sends) > limit {
+			sends = sends[len(sends)-limit:]
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sends); err != nil {
+		log.Warn().Err(err).Msg("Failed to write sandbox sends")
+	}
+}
+
+func (s *Server) resetSends(w http.ResponseWriter, r *http.Request) {
+	s.notifier.Reset()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type tickerRSS struct {
+	XMLName xml.Name      `xml:"rss"`
+	Version string        `xml:"version,attr"`
+	Channel tickerChannel `xml:"channel"`
+}
+
+type tickerChannel struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description"`
+	Items       []tickerItem `xml:"item"`
+}
+
+type tickerItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Description string `xml:"description"`
+}
+
+// serveTicker generates an RSS feed whose newest item was published at the
+// start of the current ?every= period, with ?items= items in all.
+func (s *Server) serveTicker(w http.ResponseWriter, r *http.Request) {
+	every := defaultTickerEvery
+	if v := r.URL.Query().Get("every"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < minTickerEvery {
+			http.Error(w, fmt.Sprintf("invalid every %q: expected a duration of at least %s", v, minTickerEvery), http.StatusBadRequest)
+			return
+		}
+		every = d
+	}
+	items := defaultTickerItems
+	if v := r.URL.Query().Get("items"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxTickerItems {
+			http.Error(w, fmt.Sprintf("invalid items %q: expected 1 to %d", v, maxTickerItems), http.StatusBadRequest)
+			return
+		}
+		items = n
+	}
+
+	feed := tickerRSS{Version: "2.0", Channel: tickerChannel{
+		Title:       "Sandbox Ticker",
+		Link:        "https://ticker.example.com/",
+		Description: fmt.Sprintf("A new item every %s.", every),
+	}}
+	newest := time.Now().UTC().Truncate(every)
+	for i := 0; i < items; i++ {
+		published := newest.Add(-time.Duration(i) * every)
+		n := published.UnixNano() / int64(every)
+		feed.Channel.Items = append(feed.Channel.Items, tickerItem{
+			Title:       fmt.Sprintf("Tick %d", n),
+			Link:        fmt.Sprintf("https://ticker.example.com/ticks/%d", n),
+			GUID:        fmt.Sprintf("sandbox-ticker-%s-%d", every, n),
+			PubDate:     published.Format(time.RFC1123Z),
+			Description: fmt.Sprintf("Published at %s.", published.Format(time.RFC3339)),
+		})
+	}
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		log.Warn().Err(err).Msg("Failed to write sandbox ticker feed")
+	}
+}
@@ -3,10 +3,12 @@ package scheduler
 import (
 	"container/heap"
 	"context"
+	"math/rand/v2"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/haytac/rss-telegram-bot/internal/config"
 	"github.com/haytac/rss-telegram-bot/internal/database" // Module path
 )
 
@@ -16,6 +18,7 @@ type ScheduledTask struct {
 	NextRun   time.Time
 	index     int // Index in the heap.
 	taskFunc  func(f *database.Feed)
+	waiting   bool // In the due queue, not yet handed to taskFunc
 }
 
 // PriorityQueue implements heap.Interface and holds ScheduledTasks.
@@ -57,16 +60,22 @@ func (pq *PriorityQueue) Pop() interface{} {
 type FeedScheduler struct {
 	pq      PriorityQueue
 	mu      sync.Mutex
-	timer   *time.Timer
 	stopCh  chan struct{}
 	running bool
+	cfg     config.SchedulerConfig
+	due     []*ScheduledTask // Due tasks waiting for the dispatcher, oldest first
+	wake    chan struct{}    // Tells the dispatcher tasks are due
+	rearm   chan struct{}    // Tells the loop the earliest run moved
 }
 
-// NewFeedScheduler creates a new scheduler.
-func NewFeedScheduler() *FeedScheduler {
+// NewFeedScheduler creates a new scheduler spreading runs as cfg says.
+func NewFeedScheduler(cfg config.SchedulerConfig) *FeedScheduler {
 	return &FeedScheduler{
 		pq:     make(PriorityQueue, 0),
 		stopCh: make(chan struct{}),
+		cfg:    cfg,
+		wake:   make(chan struct{}, 1),
+		rearm:  make(chan struct{}, 1),
 	}
 }
 
@@ -83,16 +92,27 @@ func (s *FeedScheduler) Add(feed *database.Feed, taskFunc func(f *database.Feed)
 	// Initial run slightly delayed to distribute load, or immediately if desired.
 	// Or, if LastFetchedAt is available, schedule relative to that.
 	nextRun := time.Now().Add(5 * time.Second) // Small initial delay
-	if feed.LastFetchedAt != nil {
+	due := true
+	lastRun := feed.LastFetchedAt
+	if feed.ConsecutiveFailures > 0 && feed.LastErrorAt != nil && (lastRun == nil || feed.LastErrorAt.After(*lastRun)) {
+		lastRun = feed.LastErrorAt // A failing feed keeps backing off across restarts
+	}
+	if lastRun != nil {
 		// Schedule based on last fetch + frequency, but not in the past
-		potentialNextRun := feed.LastFetchedAt.Add(time.Duration(feed.FrequencySeconds) * time.Second)
+		potentialNextRun := lastRun.Add(s.interval(feed))
 		if potentialNextRun.After(time.Now()){
 			nextRun = potentialNextRun
+			due = false
 		} else {
 			// If it's already due, run soon
 			nextRun = time.Now().Add(1 * time.Second) 
 		}
 	}
+	// Feeds due at startup are spread over the stagger window rather than all
+	// fetched at once; feeds added later keep the small delay.
+	if due && !s.running && s.cfg.StartupStagger > 0 {
+		nextRun = nextRun.Add(rand.N(s.cfg.StartupStagger))
+	}
 
 
 	task := &ScheduledTask{
@@ -103,12 +123,87 @@ func (s *FeedScheduler) Add(feed *database.Feed, taskFunc func(f *database.Feed)
 	heap.Push(&s.pq, task)
 	log.Info().Int64("feed_id", feed.ID).Str("url", feed.URL).Time("initial_run_at", nextRun).Msg("Feed added to scheduler")
 
-	if s.running && (s.timer == nil || nextRun.Before(s.pq[0].NextRun)) {
-		s.resetTimer()
+	if s.running && s.pq[0] == task {
+		s.rearmTimer()
 	}
 	return nil
 }
 
+// SetUpdateHint records the update interval a feed declares; it takes effect
+// when the feed is next rescheduled.
+func (s *FeedScheduler) SetUpdateHint(feedID int64, hint time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, task := range s.pq {
+		if task.Feed.ID != feedID {
+			continue
+		}
+		if hint <= 0 {
+			task.Feed.UpdateHintSeconds = nil
+		} else {
+			seconds := int(hint / time.Second)
+			task.Feed.UpdateHintSeconds = &seconds
+		}
+	}
+}
+
+// SetFailures records a feed's consecutive failed fetches. A failing feed's
+// next run moves back to its backed-off interval from now; once it succeeds
+// again, a run pushed back that way is brought forward to its usual interval.
+func (s *FeedScheduler) SetFailures(feedID int64, failures int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	moved := false
+	for _, task := range s.pq {
+		if task.Feed.ID != feedID || task.Feed.ConsecutiveFailures == failures {
+			continue
+		}
+		task.Feed.ConsecutiveFailures = failures
+		nextRun := now.Add(s.jittered(s.interval(task.Feed)))
+		if (failures > 0 && nextRun.After(task.NextRun)) || (failures == 0 && nextRun.Before(task.NextRun)) {
+			task.NextRun = nextRun
+			heap.Fix(&s.pq, task.index)
+			moved = true
+			log.Debug().Int64("feed_id", feedID).Int("consecutive_failures", failures).Time("next_run_at", nextRun).Msg("Feed rescheduled after failure count change")
+		}
+	}
+	if s.running && moved {
+		s.rearmTimer()
+	}
+}
+
+// interval is how long to wait between runs of feed: its poll interval,
+// doubled for each consecutive failed fetch up to the configured maximum
+// backoff, which never shortens it.
+func (s *FeedScheduler) interval(feed *database.Feed) time.Duration {
+	interval := feed.PollInterval()
+	if s.cfg.MaxBackoff <= interval {
+		return interval
+	}
+	for i := 0; i < feed.ConsecutiveFailures; i++ {
+		interval *= 2
+		if interval >= s.cfg.MaxBackoff {
+			return s.cfg.MaxBackoff
+		}
+	}
+	return interval
+}
+
+// Scheduled reports whether the feed is scheduled.
+func (s *FeedScheduler) Scheduled(feedID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, task := range s.pq {
+		if task.Feed.ID == feedID {
+			return true
+		}
+	}
+	return false
+}
+
 // Start begins the scheduler loop.
 func (s *FeedScheduler) Start(ctx context.Context) {
 	s.mu.Lock()
@@ -118,32 +213,42 @@ func (s *FeedScheduler) Start(ctx context.Context) {
 	}
 	s.running = true
 	s.stopCh = make(chan struct{})
+	stopCh := s.stopCh
 	s.mu.Unlock()
 
 	log.Info().Msg("Scheduler started")
-	s.resetTimer() // Set initial timer
+	go s.dispatch(stopCh)
 
 	go func() {
+		// Only this loop touches the timer; others signal rearm instead.
+		timer := time.NewTimer(s.nextDelay())
+		defer timer.Stop()
 		for {
 			select {
-			case <-s.stopCh:
+			case <-stopCh:
 				log.Info().Msg("Scheduler stopping...")
-				if s.timer != nil {
-					s.timer.Stop()
-				}
 				s.mu.Lock()
 				s.running = false
 				s.mu.Unlock()
 				log.Info().Msg("Scheduler stopped")
 				return
-			case <-s.timer.C: // timer will be nil if pq is empty
+			case <-s.rearm:
+			case <-timer.C:
 				s.runPendingTasks()
-				s.resetTimer()
 			}
+			timer.Stop()
+			timer.Reset(s.nextDelay())
 		}
 	}()
 }
 
+// Running reports whether the scheduler loop is running.
+func (s *FeedScheduler) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
 func (s *FeedScheduler) runPendingTasks() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -157,48 +262,96 @@ func (s *FeedScheduler) runPendingTasks() {
 
 		heap.Pop(&s.pq) // Remove it
 
-		log.Debug().Int64("feed_id", task.Feed.ID).Str("url", task.Feed.URL).Msg("Executing scheduled task")
-		go task.taskFunc(task.Feed) // Run task in a new goroutine
+		if task.waiting {
+			log.Debug().Int64("feed_id", task.Feed.ID).Msg("Feed still waiting for its previous dispatch, skipping run")
+		} else {
+			task.waiting = true
+			s.due = append(s.due, task)
+		}
 
 		// Reschedule for next run
-		task.NextRun = now.Add(time.Duration(task.Feed.FrequencySeconds) * time.Second)
+		task.NextRun = now.Add(s.jittered(s.interval(task.Feed)))
 		heap.Push(&s.pq, task)
 		log.Debug().Int64("feed_id", task.Feed.ID).Time("next_run_at", task.NextRun).Msg("Feed rescheduled")
 	}
+	if len(s.due) > 0 {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
 }
 
-func (s *FeedScheduler) resetTimer() {
-	// This function MUST be called with s.mu locked if s.running is true,
-	// or before s.running is set to true during Start.
-	if !s.running { // If called from Add before Start
-		return
+// dispatch hands due tasks to their taskFunc one at a time, in the order they
+// fell due, until stopCh is closed. A taskFunc that waits, e.g. for room in
+// the fetch queue, holds up the rest, so however many feeds are due at once
+// only one goroutine waits for them.
+func (s *FeedScheduler) dispatch(stopCh <-chan struct{}) {
+	for {
+		s.mu.Lock()
+		if len(s.due) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-stopCh:
+				return
+			case <-s.wake:
+				continue
+			}
+		}
+		task := s.due[0]
+		s.due[0] = nil
+		s.due = s.due[1:]
+		task.waiting = false
+		feed, taskFunc := task.Feed, task.taskFunc
+		s.mu.Unlock()
+
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+		log.Debug().Int64("feed_id", feed.ID).Str("url", feed.URL).Msg("Executing scheduled task")
+		taskFunc(feed)
 	}
+}
 
-	if s.timer != nil {
-		s.timer.Stop()
-		s.timer = nil // Ensure old timer channel is not read
+// jittered varies interval randomly by up to the configured jitter share, so
+// feeds polled at the same frequency drift apart instead of running together.
+func (s *FeedScheduler) jittered(interval time.Duration) time.Duration {
+	spread := time.Duration(float64(interval) * s.cfg.Jitter)
+	if spread <= 0 {
+		return interval
 	}
+	return interval - spread + rand.N(2*spread+1)
+}
+
+// nextDelay returns how long the loop waits for the earliest scheduled run.
+func (s *FeedScheduler) nextDelay() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	if s.pq.Len() == 0 {
-		log.Debug().Msg("Scheduler queue is empty, timer not set.")
-		// Create a dummy timer that will never fire, or handle this case specially
-		// For simplicity, we can just let it be nil. The select will block.
-		// Or set a very long timer to prevent busy loop on stopCh
-		s.timer = time.NewTimer(24 * time.Hour) // Effectively idle
-		return
+		log.Debug().Msg("Scheduler queue is empty, idling.")
+		return 24 * time.Hour // Woken by rearm when a feed is added
 	}
-
-	nextRunDelay := s.pq[0].NextRun.Sub(time.Now())
+	nextRunDelay := time.Until(s.pq[0].NextRun)
 	if nextRunDelay < 0 {
 		nextRunDelay = 0 // Run immediately if overdue
 	}
-	
-	s.timer = time.NewTimer(nextRunDelay)
 	log.Debug().Dur("next_timer_fire_in", nextRunDelay).Msg("Scheduler timer reset")
+	return nextRunDelay
 }
 
+// rearmTimer wakes the loop to set its timer for the earliest run again.
+func (s *FeedScheduler) rearmTimer() {
+	select {
+	case s.rearm <- struct{}{}:
+	default:
+	}
+}
 
-// Stop signals the scheduler to halt.
+// Stop signals the scheduler to halt. It doesn't wait for runs already handed
+// to their task; the feed worker's Drain does.
 func (s *FeedScheduler) Stop() {
 	s.mu.Lock()
 	if !s.running {
@@ -206,8 +359,6 @@ func (s *FeedScheduler) Stop() {
 		return
 	}
 	close(s.stopCh)
-	// Wait for running to be false? Or just signal?
-	// For graceful shutdown, might need a WaitGroup or similar.
 	s.mu.Unlock()
 	log.Info().Msg("Scheduler stop signal sent")
 }
\ No newline at end of file
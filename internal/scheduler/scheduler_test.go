@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/config"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupStagger(t *testing.T) {
+	s := NewFeedScheduler(config.SchedulerConfig{StartupStagger: time.Hour})
+	lastFetched := time.Now()
+	start := time.Now()
+	for i := int64(1); i <= 20; i++ {
+		require.NoError(t, s.Add(&database.Feed{ID: i, FrequencySeconds: 300}, func(*database.Feed) {}))
+	}
+	require.NoError(t, s.Add(&database.Feed{ID: 21, FrequencySeconds: 300, LastFetchedAt: &lastFetched}, func(*database.Feed) {}))
+
+	var latest time.Time
+	for _, task := range s.pq {
+		if task.Feed.ID == 21 {
+			assert.WithinDuration(t, lastFetched.Add(5*time.Minute), task.NextRun, time.Second, "feeds not due yet keep their time")
+			continue
+		}
+		assert.False(t, task.NextRun.Before(start.Add(5*time.Second)))
+		assert.True(t, task.NextRun.Before(start.Add(time.Hour+6*time.Second)))
+		if task.NextRun.After(latest) {
+			latest = task.NextRun
+		}
+	}
+	assert.True(t, latest.After(start.Add(time.Minute)), "due feeds are spread over the window")
+}
+
+func TestJittered(t *testing.T) {
+	s := NewFeedScheduler(config.SchedulerConfig{Jitter: 0.1})
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 50; i++ {
+		d := s.jittered(10 * time.Minute)
+		assert.GreaterOrEqual(t, d, 9*time.Minute)
+		assert.LessOrEqual(t, d, 11*time.Minute)
+		seen[d] = true
+	}
+	assert.Greater(t, len(seen), 1)
+	assert.Equal(t, 10*time.Minute, NewFeedScheduler(config.SchedulerConfig{}).jittered(10*time.Minute))
+}
+
+func TestDueFeedsAreDispatchedOneAtATime(t *testing.T) {
+	s := NewFeedScheduler(config.SchedulerConfig{})
+	var running, maxRunning, calls atomic.Int32
+	task := func(*database.Feed) {
+		n := running.Add(1)
+		if n > maxRunning.Load() {
+			maxRunning.Store(n)
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		calls.Add(1)
+	}
+	for i := int64(1); i <= 30; i++ {
+		require.NoError(t, s.Add(&database.Feed{ID: i, FrequencySeconds: 3600}, task))
+	}
+	for _, task := range s.pq {
+		task.NextRun = time.Now()
+	}
+	heap.Init(&s.pq)
+
+	s.Start(context.Background())
+	defer s.Stop()
+	require.Eventually(t, func() bool { return calls.Load() == 30 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), maxRunning.Load())
+}
+
+func TestFailingFeedsBackOff(t *testing.T) {
+	s := NewFeedScheduler(config.SchedulerConfig{MaxBackoff: time.Hour})
+	feed := &database.Feed{ID: 1, FrequencySeconds: 600}
+	assert.Equal(t, 10*time.Minute, s.interval(feed))
+	feed.ConsecutiveFailures = 2
+	assert.Equal(t, 40*time.Minute, s.interval(feed))
+	feed.ConsecutiveFailures = 30
+	assert.Equal(t, time.Hour, s.interval(feed), "the backoff is capped")
+	assert.Equal(t, 10*time.Minute, NewFeedScheduler(config.SchedulerConfig{}).interval(feed), "no backoff without max_backoff")
+	slow := &database.Feed{ID: 2, FrequencySeconds: 7200, ConsecutiveFailures: 3}
+	assert.Equal(t, 2*time.Hour, s.interval(slow), "the cap never shortens an interval")
+
+	require.NoError(t, s.Add(&database.Feed{ID: 3, FrequencySeconds: 600}, func(*database.Feed) {}))
+	task := s.pq[0]
+	s.SetFailures(3, 1)
+	assert.WithinDuration(t, time.Now().Add(20*time.Minute), task.NextRun, time.Second, "a failure pushes the next run back")
+	s.SetFailures(3, 0)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), task.NextRun, time.Second, "a success brings it forward again")
+
+	lastError := time.Now().Add(-5 * time.Minute)
+	require.NoError(t, s.Add(&database.Feed{ID: 4, FrequencySeconds: 600, ConsecutiveFailures: 1, LastErrorAt: &lastError}, func(*database.Feed) {}))
+	for _, task := range s.pq {
+		if task.Feed.ID == 4 {
+			assert.WithinDuration(t, lastError.Add(20*time.Minute), task.NextRun, time.Second, "the backoff carries over a restart")
+		}
+	}
+}
+
+func TestRescheduledFeedsKeepRunning(t *testing.T) {
+	s := NewFeedScheduler(config.SchedulerConfig{})
+	var calls atomic.Int32
+	require.NoError(t, s.Add(&database.Feed{ID: 1, FrequencySeconds: 1}, func(*database.Feed) { calls.Add(1) }))
+	s.pq[0].NextRun = time.Now()
+
+	s.Start(context.Background())
+	defer s.Stop()
+	s.SetFailures(1, 1)
+	require.Eventually(t, func() bool { return calls.Load() >= 2 }, 5*time.Second, 10*time.Millisecond, "the loop follows the moved run")
+	s.SetFailures(1, 0)
+	n := calls.Load()
+	require.Eventually(t, func() bool { return calls.Load() > n }, 5*time.Second, 10*time.Millisecond)
+}
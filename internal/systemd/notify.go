@@ -0,0 +1,51 @@
+// Package systemd implements the parts of the sd_notify protocol the bot
+// uses, so it can run as a Type=notify service with a watchdog. Outside
+// systemd every call is a no-op.
+package systemd
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notification states understood by systemd.
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Notify sends state to the service manager. It reports false, with no
+// error, when not running under systemd (NOTIFY_SOCKET is unset).
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	return send(socket, state)
+}
+
+// Reloading returns the state announcing a configuration reload. systemd
+// expects READY=1 once it is done.
+func Reloading() string {
+	return "RELOADING=1\nMONOTONIC_USEC=" + strconv.FormatInt(monotonicUsec(), 10)
+}
+
+// Status returns a state setting the status line shown by systemctl status.
+func Status(msg string) string {
+	return "STATUS=" + msg
+}
+
+// WatchdogInterval returns how often systemd expects WATCHDOG=1, or 0 when
+// the unit has no WatchdogSec or the watchdog is meant for another process.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package systemd
+
+func send(string, string) (bool, error) {
+	return false, nil
+}
+
+func monotonicUsec() int64 {
+	return 0
+}
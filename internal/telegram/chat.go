@@ -0,0 +1,149 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/internal/metrics"
+)
+
+// ChatInfo is the subset of a Bot API Chat object the bot cares about.
+// tgbotapi v5 predates forum topics, so the raw getChat response is decoded here.
+type ChatInfo struct {
+	ID           int64            `json:"id"`
+	Type         string           `json:"type"` // private, group, supergroup, channel
+	Title        string           `json:"title,omitempty"`
+	Username     string           `json:"username,omitempty"`
+	IsForum      bool             `json:"is_forum,omitempty"`
+	LinkedChatID int64            `json:"linked_chat_id,omitempty"` // Discussion group of a channel, or the channel of a discussion group
+	Permissions  *chatPermissions `json:"permissions,omitempty"`
+
+	// Filled in from getChatMember for the bot itself.
+	BotStatus       string `json:"-"`
+	CanManageTopics bool   `json:"-"`
+}
+
+type chatPermissions struct {
+	CanSendMessages bool `json:"can_send_messages"`
+	CanManageTopics bool `json:"can_manage_topics"`
+}
+
+type chatMemberInfo struct {
+	Status          string `json:"status"` // creator, administrator, member, restricted, left, kicked
+	CanPostMessages bool   `json:"can_post_messages,omitempty"`
+	CanSendMessages bool   `json:"can_send_messages,omitempty"`
+	CanManageTopics bool   `json:"can_manage_topics,omitempty"`
+}
+
+// GetChatInfo looks up a chat by numeric ID or @username using getChat.
+func (c *Client) GetChatInfo(ctx context.Context, botToken, chatIDStr string, proxy *database.Proxy) (*ChatInfo, error) {
+	bot, err := c.getBotAPI(botToken, proxy)
+	if err != nil {
+		return nil, fmt.Errorf("getting bot API: %w", err)
+	}
+	return getChatInfo(bot, chatIDStr)
+}
+
+func getChatInfo(bot *tgbotapi.BotAPI, chatIDStr string) (*ChatInfo, error) {
+	params := make(tgbotapi.Params)
+	params["chat_id"] = chatIDStr
+	resp, err := bot.MakeRequest("getChat", params)
+	if err != nil {
+		return nil, fmt.Errorf("getChat for '%s': %w", chatIDStr, err)
+	}
+	var info ChatInfo
+	if err := json.Unmarshal(resp.Result, &info); err != nil {
+		return nil, fmt.Errorf("decoding getChat result for '%s': %w", chatIDStr, err)
+	}
+	return &info, nil
+}
+
+// VerifyChatAccess checks that the bot can post to chatIDStr, and, when threadID
+// is non-zero, that the chat is a forum so the message can go to that topic.
+// It is meant to fail fast at configuration time instead of at first delivery.
+func (c *Client) VerifyChatAccess(ctx context.Context, botToken, chatIDStr string, threadID int, proxy *database.Proxy) (*ChatInfo, error) {
+	bot, err := c.getBotAPI(botToken, proxy)
+	if err != nil {
+		return nil, fmt.Errorf("getting bot API: %w", err)
+	}
+
+	info, err := getChatInfo(bot, chatIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("bot @%s cannot see chat '%s' (is it a member?): %w", bot.Self.UserName, chatIDStr, err)
+	}
+
+	if info.Type == "private" {
+		// Private chats only require that the user has started the bot, which getChat already proved.
+		return info, nil
+	}
+
+	params := make(tgbotapi.Params)
+	params["chat_id"] = chatIDStr
+	params.AddNonZero64("user_id", bot.Self.ID)
+	resp, err := bot.MakeRequest("getChatMember", params)
+	if err != nil {
+		return nil, fmt.Errorf("getChatMember for bot @%s in '%s': %w", bot.Self.UserName, chatIDStr, err)
+	}
+	var member chatMemberInfo
+	if err := json.Unmarshal(resp.Result, &member); err != nil {
+		return nil, fmt.Errorf("decoding getChatMember result: %w", err)
+	}
+	info.BotStatus = member.Status
+	isAdmin := member.Status == "administrator" || member.Status == "creator"
+	info.CanManageTopics = member.Status == "creator" || member.CanManageTopics ||
+		(!isAdmin && info.Permissions != nil && info.Permissions.CanManageTopics)
+
+	switch {
+	case member.Status == "left" || member.Status == "kicked":
+		return nil, fmt.Errorf("bot @%s is not a member of chat '%s' (status: %s)", bot.Self.UserName, chatIDStr, member.Status)
+	case info.Type == "channel":
+		if member.Status != "creator" && !(member.Status == "administrator" && member.CanPostMessages) {
+			return nil, fmt.Errorf("bot @%s must be a channel administrator with 'Post messages' rights in '%s'", bot.Self.UserName, chatIDStr)
+		}
+	case member.Status == "restricted" && !member.CanSendMessages:
+		return nil, fmt.Errorf("bot @%s is restricted from sending messages in '%s'", bot.Self.UserName, chatIDStr)
+	case !isAdmin && info.Permissions != nil && !info.Permissions.CanSendMessages:
+		return nil, fmt.Errorf("members of '%s' cannot send messages; promote bot @%s to administrator", chatIDStr, bot.Self.UserName)
+	}
+
+	if threadID != 0 && !info.IsForum {
+		return nil, fmt.Errorf("chat '%s' has no topics enabled, cannot post to thread %d", chatIDStr, threadID)
+	}
+	return info, nil
+}
+
+// maxForumTopicNameLength is the Bot API limit for forum topic names.
+const maxForumTopicNameLength = 128
+
+// CreateForumTopic creates a topic in a forum supergroup and returns its message_thread_id.
+// The bot needs the can_manage_topics administrator right.
+func (c *Client) CreateForumTopic(ctx context.Context, botToken, chatIDStr, name string, proxy *database.Proxy) (int, error) {
+	bot, err := c.getBotAPI(botToken, proxy)
+	if err != nil {
+		return 0, fmt.Errorf("getting bot API: %w", err)
+	}
+
+	if runes := []rune(name); len(runes) > maxForumTopicNameLength {
+		name = string(runes[:maxForumTopicNameLength])
+	}
+	params := make(tgbotapi.Params)
+	params["chat_id"] = chatIDStr
+	params["name"] = name
+	resp, err := bot.MakeRequest("createForumTopic", params)
+	if err != nil {
+		metrics.TelegramAPICalls.WithLabelValues("createForumTopic", "error").Inc()
+		return 0, fmt.Errorf("createForumTopic '%s' in chat '%s': %w", name, chatIDStr, err)
+	}
+	metrics.TelegramAPICalls.WithLabelValues("createForumTopic", "success").Inc()
+
+	var topic struct {
+		MessageThreadID int `json:"message_thread_id"`
+	}
+	if err := json.Unmarshal(resp.Result, &topic); err != nil {
+		return 0, fmt.Errorf("decoding createForumTopic result: %w", err)
+	}
+	return topic.MessageThreadID, nil
+}
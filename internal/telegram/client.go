@@ -2,7 +2,9 @@ package telegram
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"sync" // Needed for Client struct's mutexes
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -14,7 +16,7 @@ import (
 
 const (
 	telegramMaxMessageLength = 4096 // THIS CONSTANT MUST BE PRESENT
-	globalMessagesPerSecond  = 25
+	globalMessagesPerSecond  = 25 // Per bot; Telegram's broadcast limit applies to each token separately
 	chatMessagesPerSecond    = 1
 )
 
@@ -22,11 +24,18 @@ const (
 // THIS STRUCT DEFINITION MUST BE PRESENT
 type Client struct {
 	clientFactory  interfaces.HTTPClientFactory
-	bots           map[string]*tgbotapi.BotAPI
+	bots           map[string]*tgbotapi.BotAPI // Keyed by bot token + proxy ID
 	botsMu         sync.RWMutex // Uses "sync"
-	globalLimiter  *rate.Limiter // Uses "golang.org/x/time/rate"
-	chatLimiters   map[string]*rate.Limiter
+	botLimiters    map[string]*rate.Limiter // Keyed by bot token
+	botLimitersMu  sync.Mutex
+	chatLimiters   map[string]*rate.Limiter // Keyed by bot token + chat ID
 	chatLimitersMu sync.Mutex // Uses "sync"
+	poolCursors    map[string]uint64 // Round-robin position per bot pool
+	poolCursorsMu  sync.Mutex
+	resolvedChats   map[string]int64 // @username -> numeric chat ID, learned from sent messages
+	resolvedChatsMu sync.RWMutex
+	discussions     discussionTracker
+	updates         updatePoller
 }
 
 // NewClient creates a new Telegram client.
@@ -34,21 +43,28 @@ func NewClient(clientFactory interfaces.HTTPClientFactory) *Client { // Returns
 	return &Client{ // Uses Client
 		clientFactory: clientFactory,
 		bots:          make(map[string]*tgbotapi.BotAPI),
-		globalLimiter: rate.NewLimiter(rate.Limit(globalMessagesPerSecond), globalMessagesPerSecond*2),
+		botLimiters:   make(map[string]*rate.Limiter),
 		chatLimiters:  make(map[string]*rate.Limiter),
+		poolCursors:   make(map[string]uint64),
+		resolvedChats: make(map[string]int64),
 	}
 }
 
 func (c *Client) getBotAPI(botToken string, proxy *database.Proxy) (*tgbotapi.BotAPI, error) {
+	// One instance per proxy, so a bot can move between the proxies of a pool.
+	key := botToken
+	if proxy != nil {
+		key += "|" + strconv.FormatInt(proxy.ID, 10)
+	}
 	c.botsMu.RLock() // Uses c.botsMu
-	bot, exists := c.bots[botToken]
+	bot, exists := c.bots[key]
 	c.botsMu.RUnlock()
 	if exists {
 		return bot, nil
 	}
 	c.botsMu.Lock()
 	defer c.botsMu.Unlock()
-	if bot, exists = c.bots[botToken]; exists {
+	if bot, exists = c.bots[key]; exists {
 		return bot, nil
 	}
 	httpClient, err := c.clientFactory.GetClient(proxy)
@@ -60,22 +76,69 @@ func (c *Client) getBotAPI(botToken string, proxy *database.Proxy) (*tgbotapi.Bo
 		return nil, fmt.Errorf("failed to create bot API instance: %w", err)
 	}
 	log.Info().Str("bot_username", api.Self.UserName).Msg("Telegram bot authorized")
-	c.bots[botToken] = api
+	c.bots[key] = api
 	return api, nil
 }
 
-func (c *Client) getChatLimiter(chatID string) *rate.Limiter {
+// Authorize checks botToken with Telegram through proxy and keeps the bot
+// ready for sending, so readiness reflects working bots before the first
+// message goes out.
+func (c *Client) Authorize(botToken string, proxy *database.Proxy) error {
+	_, err := c.getBotAPI(botToken, proxy)
+	return err
+}
+
+// AuthorizedBots returns how many bot instances have been authorized.
+func (c *Client) AuthorizedBots() int {
+	c.botsMu.RLock()
+	defer c.botsMu.RUnlock()
+	return len(c.bots)
+}
+
+func (c *Client) getBotLimiter(botToken string) *rate.Limiter {
+	c.botLimitersMu.Lock()
+	defer c.botLimitersMu.Unlock()
+	limiter, exists := c.botLimiters[botToken]
+	if !exists {
+		limiter = rate.NewLimiter(rate.Limit(globalMessagesPerSecond), globalMessagesPerSecond*2)
+		c.botLimiters[botToken] = limiter
+	}
+	return limiter
+}
+
+// getChatLimiter returns the limiter for a chat as seen by one bot. Telegram's
+// per-chat limit applies per bot, so pooled bots each get their own budget.
+func (c *Client) getChatLimiter(botToken, chatID string) *rate.Limiter {
 	c.chatLimitersMu.Lock() // Uses c.chatLimitersMu
 	defer c.chatLimitersMu.Unlock()
-	limiter, exists := c.chatLimiters[chatID]
+	key := botToken + ":" + chatID
+	limiter, exists := c.chatLimiters[key]
 	if !exists {
 		limiter = rate.NewLimiter(rate.Limit(chatMessagesPerSecond), chatMessagesPerSecond*2) // Uses rate.NewLimiter
-		c.chatLimiters[chatID] = limiter
+		c.chatLimiters[key] = limiter
 	}
 	return limiter
 }
 
+// NextPoolToken picks the next bot token from a pool in round-robin order.
+// poolKey identifies the pool (e.g. its database ID) so each pool rotates independently.
+func (c *Client) NextPoolToken(poolKey string, tokens []string) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	c.poolCursorsMu.Lock()
+	defer c.poolCursorsMu.Unlock()
+	cursor := c.poolCursors[poolKey]
+	c.poolCursors[poolKey] = cursor + 1
+	return tokens[cursor%uint64(len(tokens))]
+}
+
 func (c *Client) Send(ctx context.Context, botToken, chatIDStr string, parts []interfaces.FormattedMessagePart, proxy *database.Proxy) error {
+	return c.SendToThread(ctx, botToken, chatIDStr, 0, parts, proxy)
+}
+
+// SendToThread sends parts like Send, posting them into a forum topic when threadID is non-zero.
+func (c *Client) SendToThread(ctx context.Context, botToken, chatIDStr string, threadID int, parts []interfaces.FormattedMessagePart, proxy *database.Proxy) error {
 	bot, err := c.getBotAPI(botToken, proxy)
 	if err != nil {
 		return fmt.Errorf("getting bot API: %w", err)
@@ -89,121 +152,189 @@ func (c *Client) Send(ctx context.Context, botToken, chatIDStr string, parts []i
 	}
 
 	globalCtxLimiter := context.Background()
-	operationLogger := log.With().Str("chat_id_str", chatIDStr).Str("bot_username", bot.Self.UserName).Logger()
+	operationLogger := log.With().Str("chat_id_str", chatIDStr).Int("thread_id", threadID).Str("bot_username", bot.Self.UserName).Logger()
 
-	for i, part := range parts {
-		if err := c.globalLimiter.Wait(globalCtxLimiter); err != nil { // Uses c.globalLimiter
-			return fmt.Errorf("global rate limiter wait: %w", err)
+	// Text parts longer than Telegram's limit, and media whose captions exceed the
+	// caption limit, are split here so that every chunk can be threaded under the
+	// first delivered message.
+	var expandedParts []interfaces.FormattedMessagePart
+	for _, part := range parts {
+		if part.HasMedia() {
+			expandedParts = append(expandedParts, splitCaptionPart(part)...)
+			continue
 		}
-		chatLimiter := c.getChatLimiter(chatIDStr)
+		expandedParts = append(expandedParts, splitTextPart(part)...)
+	}
+
+	// replyToMessageID holds the ID of the first message sent for this item.
+	// Subsequent parts are sent as replies to it so they thread together visually.
+	replyToMessageID := 0
+	reaction := ""
+	discussButton := ""
+
+	for i, part := range expandedParts {
+		if err := c.getBotLimiter(botToken).Wait(globalCtxLimiter); err != nil {
+			return fmt.Errorf("bot rate limiter wait: %w", err)
+		}
+		chatLimiter := c.getChatLimiter(botToken, chatIDStr)
 		if err := chatLimiter.Wait(globalCtxLimiter); err != nil {
 			return fmt.Errorf("chat rate limiter wait for %s: %w", chatIDStr, err)
 		}
 
 		partLogger := operationLogger.With().Int("part_index", i).Logger()
-		var msgConfig tgbotapi.Chattable
-
-		if part.PhotoURL != "" {
-			photoFile := tgbotapi.FileURL(part.PhotoURL)
-			cfg := tgbotapi.PhotoConfig{
-				BaseFile: tgbotapi.BaseFile{
-					BaseChat: tgbotapi.BaseChat{
-						ReplyToMessageID: 0,
-					},
-					File: photoFile,
-				},
-				Caption:   part.Text,
-				ParseMode: part.ParseMode,
-			}
-			if isChannelUsername {
-				cfg.BaseChat.ChannelUsername = chatIDStr
-			} else {
-				cfg.BaseChat.ChatID = numericChatID
-			}
-			msgConfig = cfg
-			partLogger.Debug().Str("photo_url", part.PhotoURL).Msg("Preparing to send photo")
-
-		} else if part.DocumentURL != "" {
-			docFile := tgbotapi.FileURL(part.DocumentURL)
-			cfg := tgbotapi.DocumentConfig{
-				BaseFile: tgbotapi.BaseFile{
-					BaseChat: tgbotapi.BaseChat{
-						ReplyToMessageID: 0,
-					},
-					File: docFile,
-				},
-				Caption:   part.DocumentCaption,
-				ParseMode: part.ParseMode,
-			}
-			if isChannelUsername {
-				cfg.BaseChat.ChannelUsername = chatIDStr
-			} else {
-				cfg.BaseChat.ChatID = numericChatID
-			}
-			msgConfig = cfg
-			partLogger.Debug().Str("document_url", part.DocumentURL).Msg("Preparing to send document")
-
-		} else if part.Text != "" {
-			cfg := tgbotapi.MessageConfig{
-				BaseChat: tgbotapi.BaseChat{
-					ReplyToMessageID: 0,
-				},
-				Text:                  part.Text,
-				ParseMode:             part.ParseMode,
-				DisableWebPagePreview: false,
-			}
-			if isChannelUsername {
-				cfg.BaseChat.ChannelUsername = chatIDStr
-			} else {
-				cfg.BaseChat.ChatID = numericChatID
-			}
-			msgConfig = cfg
-			partLogger.Debug().Int("text_length", len(part.Text)).Msg("Preparing to send text message")
-		} else {
-			partLogger.Warn().Msg("Skipping message part: no text, photo, or document URL provided.")
-			continue
+		if reaction == "" {
+			reaction = part.Reaction
+		}
+		if discussButton == "" {
+			discussButton = part.DiscussButton
 		}
 
-		if msgConfig == nil {
-			partLogger.Error().Msg("Internal error: msgConfig is nil before sending, skipping part.")
+		// Requests are built as raw Bot API parameters because tgbotapi v5's typed
+		// configs predate forum topics and cannot carry message_thread_id.
+		method, params := partRequest(part)
+		if method == "" {
+			partLogger.Warn().Msg("Skipping message part: no text, photo, video, animation, document URL, or album provided.")
 			continue
 		}
+		params["chat_id"] = chatIDStr
+		params.AddNonZero("message_thread_id", threadID)
+		if replyToMessageID != 0 {
+			params.AddNonZero("reply_to_message_id", replyToMessageID)
+			params.AddBool("allow_sending_without_reply", true)
+		}
+		partLogger.Debug().Str("method", method).Int("text_length", len(part.Text)).Msg("Preparing to send message part")
 
-		if _, err := bot.Send(msgConfig); err != nil {
+		sentMsg, err := sendRequest(bot, method, params)
+		if err != nil {
 			partLogger.Error().Err(err).Msg("Failed to send message to Telegram")
 			return fmt.Errorf("sending message part to chat '%s': %w", chatIDStr, err)
 		}
-		partLogger.Debug().Msg("Message part sent successfully")
+		if replyToMessageID == 0 {
+			replyToMessageID = sentMsg.MessageID
+			if isChannelUsername && sentMsg.Chat != nil && sentMsg.Chat.ID != 0 {
+				c.resolvedChatsMu.Lock()
+				c.resolvedChats[chatIDStr] = sentMsg.Chat.ID
+				c.resolvedChatsMu.Unlock()
+			}
+		}
+		partLogger.Debug().Int("message_id", sentMsg.MessageID).Msg("Message part sent successfully")
+	}
+
+	if reaction != "" && replyToMessageID != 0 {
+		// A failed reaction is cosmetic; the item itself was delivered.
+		if err := c.setMessageReaction(bot, chatIDStr, replyToMessageID, reaction); err != nil {
+			operationLogger.Warn().Err(err).Str("reaction", reaction).Msg("Failed to set reaction on delivered message")
+		}
+	}
+	if discussButton != "" && replyToMessageID != 0 && threadID == 0 {
+		// Like reactions, a missing button doesn't undo the delivery.
+		if err := c.addDiscussButton(ctx, bot, chatIDStr, replyToMessageID, discussButton); err != nil {
+			operationLogger.Warn().Err(err).Msg("Failed to add discuss button to delivered message")
+		}
 	}
 	return nil
 }
 
-// SplitMessage uses interfaces.FormattedMessagePart
+// partRequest maps a message part to its Bot API method and parameters, without
+// the chat or reply fields. It returns an empty method for parts with nothing to send.
+func partRequest(part interfaces.FormattedMessagePart) (string, tgbotapi.Params) {
+	params := make(tgbotapi.Params)
+	switch {
+	case len(part.MediaGroup) > 1:
+		media := make([]map[string]string, len(part.MediaGroup))
+		for i, m := range part.MediaGroup {
+			media[i] = map[string]string{"type": m.Type, "media": m.URL}
+		}
+		if part.Text != "" { // The first item's caption shows as the album's
+			media[0]["caption"] = part.Text
+			if part.ParseMode != "" {
+				media[0]["parse_mode"] = part.ParseMode
+			}
+		}
+		encoded, _ := json.Marshal(media) // Maps of strings always encode
+		params["media"] = string(encoded)
+		return "sendMediaGroup", params
+	case part.PhotoURL != "":
+		params["photo"] = part.PhotoURL
+		params.AddNonEmpty("caption", part.Text)
+		params.AddNonEmpty("parse_mode", part.ParseMode)
+		return "sendPhoto", params
+	case part.VideoURL != "":
+		params["video"] = part.VideoURL
+		params.AddNonEmpty("caption", part.Text)
+		params.AddNonEmpty("parse_mode", part.ParseMode)
+		params.AddBool("supports_streaming", true)
+		return "sendVideo", params
+	case part.AnimationURL != "":
+		params["animation"] = part.AnimationURL
+		params.AddNonEmpty("caption", part.Text)
+		params.AddNonEmpty("parse_mode", part.ParseMode)
+		return "sendAnimation", params
+	case part.DocumentURL != "":
+		params["document"] = part.DocumentURL
+		params.AddNonEmpty("caption", part.DocumentCaption)
+		params.AddNonEmpty("parse_mode", part.ParseMode)
+		return "sendDocument", params
+	case part.Text != "":
+		params["text"] = part.Text
+		params.AddNonEmpty("parse_mode", part.ParseMode)
+		return "sendMessage", params
+	}
+	return "", params
+}
+
+// sendRequest performs a raw send* call and decodes the resulting message, or
+// the first message of an album.
+func sendRequest(bot *tgbotapi.BotAPI, method string, params tgbotapi.Params) (tgbotapi.Message, error) {
+	var msg tgbotapi.Message
+	resp, err := bot.MakeRequest(method, params)
+	if err != nil {
+		return msg, err
+	}
+	if method == "sendMediaGroup" {
+		var msgs []tgbotapi.Message
+		if err := json.Unmarshal(resp.Result, &msgs); err != nil {
+			return msg, fmt.Errorf("decoding %s result: %w", method, err)
+		}
+		if len(msgs) > 0 {
+			msg = msgs[0]
+		}
+		return msg, nil
+	}
+	if err := json.Unmarshal(resp.Result, &msg); err != nil {
+		return msg, fmt.Errorf("decoding %s result: %w", method, err)
+	}
+	return msg, nil
+}
+
+// SplitMessage splits text into parts that fit Telegram's message length limit,
+// cutting at line or word boundaries and keeping HTML tags balanced in each part.
 func SplitMessage(text, parseMode string) []interfaces.FormattedMessagePart {
-	// Uses telegramMaxMessageLength
-	if len(text) <= telegramMaxMessageLength {
+	if len([]rune(text)) <= telegramMaxMessageLength {
 		return []interfaces.FormattedMessagePart{{Text: text, ParseMode: parseMode}}
 	}
 	var parts []interfaces.FormattedMessagePart
-	runes := []rune(text)
-	currentPartStartIndex := 0
-	for i := 0; i < len(runes); {
-		// Uses telegramMaxMessageLength
-		end := currentPartStartIndex + telegramMaxMessageLength
-		if end > len(runes) {
-			end = len(runes)
-		}
-		actualEnd := end
-		parts = append(parts, interfaces.FormattedMessagePart{Text: string(runes[currentPartStartIndex:actualEnd]), ParseMode: parseMode})
-		currentPartStartIndex = actualEnd
-		i = currentPartStartIndex
+	rest := text
+	for rest != "" {
+		var head string
+		head, rest = splitFormatted(rest, parseMode, telegramMaxMessageLength)
+		parts = append(parts, interfaces.FormattedMessagePart{Text: head, ParseMode: parseMode})
 	}
 	if len(parts) > 1 {
-		log.Warn().Int("original_len_runes", len(runes)).Int("num_parts", len(parts)).Msg("Message split due to length")
+		log.Warn().Int("original_len_runes", len([]rune(text))).Int("num_parts", len(parts)).Msg("Message split due to length")
 	}
 	return parts
 }
 
+// ResolvedChatID returns the numeric chat ID learned from a successful send to
+// an @username chat, if any.
+func (c *Client) ResolvedChatID(chatIDStr string) (int64, bool) {
+	c.resolvedChatsMu.RLock()
+	defer c.resolvedChatsMu.RUnlock()
+	id, ok := c.resolvedChats[chatIDStr]
+	return id, ok
+}
+
 func (c *Client) Name() string { // Uses *Client
 	return "telegram"
 }
\ No newline at end of file
@@ -0,0 +1,32 @@
+package telegram
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartRequestMediaGroup(t *testing.T) {
+	part := interfaces.FormattedMessagePart{
+		Text:      "<b>Gallery</b>",
+		ParseMode: tgbotapi.ModeHTML,
+		PhotoURL:  "https://example.com/a.jpg",
+		MediaGroup: []interfaces.MediaGroupItem{
+			{Type: "photo", URL: "https://example.com/a.jpg"},
+			{Type: "video", URL: "https://example.com/b.mp4"},
+		},
+	}
+	method, params := partRequest(part)
+	assert.Equal(t, "sendMediaGroup", method)
+	assert.JSONEq(t, `[
+		{"type": "photo", "media": "https://example.com/a.jpg", "caption": "<b>Gallery</b>", "parse_mode": "HTML"},
+		{"type": "video", "media": "https://example.com/b.mp4"}
+	]`, params["media"])
+
+	part.MediaGroup = part.MediaGroup[:1]
+	method, params = partRequest(part)
+	assert.Equal(t, "sendPhoto", method, "a single item is sent on its own")
+	assert.Equal(t, "https://example.com/a.jpg", params["photo"])
+}
@@ -0,0 +1,196 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/haytac/rss-telegram-bot/internal/metrics"
+)
+
+// discussionWait bounds how long a send waits for Telegram to copy a channel
+// post into the linked discussion group.
+const discussionWait = 8 * time.Second
+
+// discussionTracker learns where channel posts land in their linked
+// discussion groups. Telegram only reveals that through the automatic
+// forward the bot receives as a member of the group, so updates are polled
+// with getUpdates.
+type discussionTracker struct {
+	mu       sync.Mutex
+	channels map[string]discussionChannel // By chat ID string as configured
+	forwards map[[2]int64]int             // {channel ID, post ID} -> message ID in the group
+}
+
+// forwardRecheck is how often a send waiting for a forward checks again while
+// another caller, such as a command listener, is polling the bot's updates.
+const forwardRecheck = 200 * time.Millisecond
+
+// maxPendingForwards bounds the forwards remembered for posts nobody waits
+// for, such as those not sent by the bot.
+const maxPendingForwards = 1000
+
+// discussionChannel is a chat looked up for its discussion group.
+type discussionChannel struct {
+	ID    int64
+	Group *discussionGroup // nil when the chat is not a channel or has no discussion group
+}
+
+// discussionGroup is the discussion group linked to a channel.
+type discussionGroup struct {
+	ID       int64
+	Username string
+}
+
+// threadURL links to the comment thread of the group message with the given ID.
+func (g *discussionGroup) threadURL(messageID int) string {
+	path := g.Username
+	if path == "" {
+		path = "c/" + strings.TrimPrefix(strconv.FormatInt(g.ID, 10), "-100")
+	}
+	return fmt.Sprintf("https://t.me/%s/%d?thread=%d", path, messageID, messageID)
+}
+
+// automaticForward is the part of a Bot API message identifying a channel post
+// copied into its discussion group. Bot API 7 replaced forward_from_chat and
+// forward_from_message_id with forward_origin; both are read.
+type automaticForward struct {
+	MessageID          int            `json:"message_id"`
+	IsAutomaticForward bool           `json:"is_automatic_forward"`
+	ForwardFromChat    *tgbotapi.Chat `json:"forward_from_chat"`
+	ForwardFromMessage int            `json:"forward_from_message_id"`
+	ForwardOrigin      *struct {
+		Type      string         `json:"type"`
+		Chat      *tgbotapi.Chat `json:"chat"`
+		MessageID int            `json:"message_id"`
+	} `json:"forward_origin"`
+}
+
+// origin returns the channel and post the message was forwarded from.
+func (f *automaticForward) origin() (int64, int, bool) {
+	if !f.IsAutomaticForward {
+		return 0, 0, false
+	}
+	if o := f.ForwardOrigin; o != nil && o.Type == "channel" && o.Chat != nil {
+		return o.Chat.ID, o.MessageID, true
+	}
+	if f.ForwardFromChat != nil && f.ForwardFromMessage != 0 {
+		return f.ForwardFromChat.ID, f.ForwardFromMessage, true
+	}
+	return 0, 0, false
+}
+
+// addDiscussButton attaches an inline button labelled label to a channel post,
+// opening its comment thread in the channel's discussion group. It does
+// nothing for chats that aren't channels with a discussion group.
+func (c *Client) addDiscussButton(ctx context.Context, bot *tgbotapi.BotAPI, chatIDStr string, postID int, label string) error {
+	channel, err := c.discussionChannel(bot, chatIDStr)
+	if err != nil || channel.Group == nil {
+		return err
+	}
+	forwardID, err := c.waitForForward(ctx, bot, channel.ID, postID)
+	if err != nil {
+		return err
+	}
+
+	params := make(tgbotapi.Params)
+	params["chat_id"] = chatIDStr
+	params.AddNonZero("message_id", postID)
+	markup := map[string]interface{}{
+		"inline_keyboard": [][]map[string]string{{{"text": label, "url": channel.Group.threadURL(forwardID)}}},
+	}
+	if err := params.AddInterface("reply_markup", markup); err != nil {
+		return fmt.Errorf("encoding discuss button: %w", err)
+	}
+	if _, err := bot.MakeRequest("editMessageReplyMarkup", params); err != nil {
+		metrics.TelegramAPICalls.WithLabelValues("editMessageReplyMarkup", "error").Inc()
+		return fmt.Errorf("editMessageReplyMarkup for message %d in chat '%s': %w", postID, chatIDStr, err)
+	}
+	metrics.TelegramAPICalls.WithLabelValues("editMessageReplyMarkup", "success").Inc()
+	return nil
+}
+
+// discussionChannel looks up the chat for its discussion group, caching
+// the result.
+func (c *Client) discussionChannel(bot *tgbotapi.BotAPI, chatIDStr string) (discussionChannel, error) {
+	t := &c.discussions
+	t.mu.Lock()
+	channel, known := t.channels[chatIDStr]
+	t.mu.Unlock()
+	if known {
+		return channel, nil
+	}
+
+	info, err := getChatInfo(bot, chatIDStr)
+	if err != nil {
+		return channel, err
+	}
+	channel.ID = info.ID
+	if info.Type == "channel" && info.LinkedChatID != 0 {
+		channel.Group = &discussionGroup{ID: info.LinkedChatID}
+		if linked, err := getChatInfo(bot, strconv.FormatInt(info.LinkedChatID, 10)); err == nil {
+			channel.Group.Username = linked.Username
+		}
+	}
+	t.mu.Lock()
+	if t.channels == nil {
+		t.channels = make(map[string]discussionChannel)
+	}
+	t.channels[chatIDStr] = channel
+	t.mu.Unlock()
+	return channel, nil
+}
+
+// waitForForward polls the bot's updates until the automatic forward of the
+// channel post shows up in the discussion group, returning its message ID.
+// Forwards of other posts seen on the way are remembered for their senders.
+func (c *Client) waitForForward(ctx context.Context, bot *tgbotapi.BotAPI, channelID int64, postID int) (int, error) {
+	t := &c.discussions
+	key := [2]int64{channelID, int64(postID)}
+	ctx, cancel := context.WithTimeout(ctx, discussionWait)
+	defer cancel()
+	poller := c.poller(bot)
+	for {
+		t.mu.Lock()
+		forwardID, ok := t.forwards[key]
+		if ok {
+			delete(t.forwards, key)
+		}
+		t.mu.Unlock()
+		if ok {
+			return forwardID, nil
+		}
+		if ctx.Err() != nil {
+			return 0, fmt.Errorf("post %d did not appear in the discussion group within %s (is the bot a member of the group?)", postID, discussionWait)
+		}
+
+		if !poller.TryLock() {
+			// Someone else is polling and records the forwards it gets
+			select {
+			case <-ctx.Done():
+			case <-time.After(forwardRecheck):
+			}
+			continue
+		}
+		err := c.pollUpdates(bot, 1)
+		poller.Unlock()
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// recordForward remembers the group message a channel post was forwarded as.
+func (c *Client) recordForward(channelID int64, postID, messageID int) {
+	t := &c.discussions
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.forwards == nil || len(t.forwards) > maxPendingForwards {
+		t.forwards = make(map[[2]int64]int)
+	}
+	t.forwards[[2]int64{channelID, int64(postID)}] = messageID
+}
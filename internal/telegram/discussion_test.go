@@ -0,0 +1,33 @@
+package telegram
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscussionGroupThreadURL(t *testing.T) {
+	assert.Equal(t, "https://t.me/mygroup/42?thread=42", (&discussionGroup{ID: -1001234, Username: "mygroup"}).threadURL(42))
+	assert.Equal(t, "https://t.me/c/1234/42?thread=42", (&discussionGroup{ID: -1001234}).threadURL(42))
+}
+
+func TestAutomaticForwardOrigin(t *testing.T) {
+	for name, raw := range map[string]string{
+		"forward_origin": `{"message_id": 9, "is_automatic_forward": true, "forward_origin": {"type": "channel", "chat": {"id": -1005}, "message_id": 3}}`,
+		"legacy":         `{"message_id": 9, "is_automatic_forward": true, "forward_from_chat": {"id": -1005}, "forward_from_message_id": 3}`,
+	} {
+		var f automaticForward
+		require.NoError(t, json.Unmarshal([]byte(raw), &f), name)
+		channelID, postID, ok := f.origin()
+		assert.True(t, ok, name)
+		assert.Equal(t, int64(-1005), channelID, name)
+		assert.Equal(t, 3, postID, name)
+	}
+
+	var manual automaticForward
+	require.NoError(t, json.Unmarshal([]byte(`{"message_id": 9, "forward_origin": {"type": "channel", "chat": {"id": -1005}, "message_id": 3}}`), &manual))
+	_, _, ok := manual.origin()
+	assert.False(t, ok, "manual forwards are not discussion threads")
+}
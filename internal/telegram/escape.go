@@ -0,0 +1,46 @@
+package telegram
+
+import "strings"
+
+// markdownV2Replacer escapes every character the Bot API reserves in MarkdownV2 text.
+// The backslash must be escaped too, otherwise it would swallow the next character.
+var markdownV2Replacer = strings.NewReplacer(
+	`\`, `\\`,
+	"_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`,
+	"=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// markdownV2CodeReplacer escapes text inside `code` and ```pre``` entities,
+// where only the backtick and backslash are special.
+var markdownV2CodeReplacer = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+
+// markdownV2LinkReplacer escapes the URL part of an inline link (...),
+// where only ')' and backslash are special.
+var markdownV2LinkReplacer = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+// htmlReplacer escapes the characters Telegram's HTML parse mode requires.
+// Telegram understands &lt; &gt; &amp; and &quot; only, so we stay within those.
+var htmlReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// EscapeMarkdownV2 escapes plain text for use in a MarkdownV2 message, so that
+// feed content can't produce 400 "can't parse entities" errors.
+func EscapeMarkdownV2(s string) string {
+	return markdownV2Replacer.Replace(s)
+}
+
+// EscapeMarkdownV2Code escapes text placed inside a MarkdownV2 code or pre entity.
+func EscapeMarkdownV2Code(s string) string {
+	return markdownV2CodeReplacer.Replace(s)
+}
+
+// EscapeMarkdownV2URL escapes a URL placed inside the (...) part of a MarkdownV2 inline link.
+func EscapeMarkdownV2URL(s string) string {
+	return markdownV2LinkReplacer.Replace(s)
+}
+
+// EscapeHTML escapes plain text for use in an HTML parse mode message,
+// including inside attribute values such as href.
+func EscapeHTML(s string) string {
+	return htmlReplacer.Replace(s)
+}
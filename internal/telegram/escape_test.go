@@ -0,0 +1,61 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"plain text untouched", "Hello world", "Hello world"},
+		{"all reserved characters", "_*[]()~`>#+-=|{}.!", "\\_\\*\\[\\]\\(\\)\\~\\`\\>\\#\\+\\-\\=\\|\\{\\}\\.\\!"},
+		{"backslash escaped first", `a\b`, `a\\b`},
+		{"already escaped text is escaped again", `\.`, `\\\.`},
+		{"sentence", "Go 1.24 released! (finally)", `Go 1\.24 released\! \(finally\)`},
+		{"url in text", "https://example.com/a_b?x=1", `https://example\.com/a\_b?x\=1`},
+		{"unicode preserved", "Привет — 🔥 *hot*", `Привет — 🔥 \*hot\*`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, EscapeMarkdownV2(tt.in))
+		})
+	}
+}
+
+func TestEscapeMarkdownV2Code(t *testing.T) {
+	assert.Equal(t, "fmt.Println(\"*_hi_*\")", EscapeMarkdownV2Code("fmt.Println(\"*_hi_*\")"), "only ` and \\ are special in code")
+	assert.Equal(t, "\\`x\\` \\\\n", EscapeMarkdownV2Code("`x` \\n"))
+}
+
+func TestEscapeMarkdownV2URL(t *testing.T) {
+	assert.Equal(t, "https://en.wikipedia.org/wiki/Go_(game\\)", EscapeMarkdownV2URL("https://en.wikipedia.org/wiki/Go_(game)"))
+	assert.Equal(t, `https://example.com/a\\b`, EscapeMarkdownV2URL(`https://example.com/a\b`))
+}
+
+func TestEscapeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"plain text untouched", "Hello world", "Hello world"},
+		{"tags", "<b>bold</b>", "&lt;b&gt;bold&lt;/b&gt;"},
+		{"ampersand first", "&lt;", "&amp;lt;"},
+		{"quotes for attributes", `a "quoted" href`, "a &quot;quoted&quot; href"},
+		{"single quote untouched", "it's", "it's"},
+		{"markdown characters untouched", "_*[]()", "_*[]()"},
+		{"query string", "https://example.com/?a=1&b=2", "https://example.com/?a=1&amp;b=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, EscapeHTML(tt.in))
+		})
+	}
+}
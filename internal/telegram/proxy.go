@@ -0,0 +1,34 @@
+package telegram
+
+import (
+	"context"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultProxy returns the proxy for Telegram traffic that has no proxy of its
+// own: one picked from the Telegram proxy pool for feedID (0 for traffic not
+// tied to a feed), or the default Telegram proxy when no pool is set or none
+// of its proxies is usable. A nil picker skips the pool.
+func DefaultProxy(ctx context.Context, store *database.ProxyStore, picker interfaces.ProxyPicker, feedID int64) (*database.Proxy, error) {
+	pool, err := store.GetTelegramPool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pool != nil && picker != nil {
+		if p := picker.PoolProxy(pool, feedID); p != nil {
+			return p, nil
+		}
+		log.Warn().Int64("proxy_pool_id", pool.ID).Msg("No usable proxy in the Telegram proxy pool; using the default Telegram proxy")
+	}
+	return store.GetDefaultProxy(ctx, "telegram")
+}
+
+// ProxyPicker returns the client's HTTP client factory as a ProxyPicker, or
+// nil if it can't pick from pools.
+func (c *Client) ProxyPicker() interfaces.ProxyPicker {
+	picker, _ := c.clientFactory.(interfaces.ProxyPicker)
+	return picker
+}
@@ -0,0 +1,33 @@
+package telegram
+
+import (
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/haytac/rss-telegram-bot/internal/metrics"
+)
+
+// reactionTypeEmoji mirrors the Bot API ReactionTypeEmoji object.
+type reactionTypeEmoji struct {
+	Type  string `json:"type"`
+	Emoji string `json:"emoji"`
+}
+
+// setMessageReaction sets a single emoji reaction on a message.
+// tgbotapi v5 has no typed config for setMessageReaction, so the call is made
+// through the raw MakeRequest API.
+func (c *Client) setMessageReaction(bot *tgbotapi.BotAPI, chatIDStr string, messageID int, emoji string) error {
+	params := make(tgbotapi.Params)
+	params["chat_id"] = chatIDStr // Numeric IDs and @usernames are both accepted as strings
+	params.AddNonZero("message_id", messageID)
+	if err := params.AddInterface("reaction", []reactionTypeEmoji{{Type: "emoji", Emoji: emoji}}); err != nil {
+		return fmt.Errorf("encoding reaction: %w", err)
+	}
+
+	if _, err := bot.MakeRequest("setMessageReaction", params); err != nil {
+		metrics.TelegramAPICalls.WithLabelValues("setMessageReaction", "error").Inc()
+		return fmt.Errorf("setMessageReaction for message %d in chat '%s': %w", messageID, chatIDStr, err)
+	}
+	metrics.TelegramAPICalls.WithLabelValues("setMessageReaction", "success").Inc()
+	return nil
+}
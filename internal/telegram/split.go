@@ -0,0 +1,143 @@
+package telegram
+
+import (
+	"regexp"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+)
+
+// telegramMaxCaptionLength is the Bot API limit for photo, video, animation and document captions.
+const telegramMaxCaptionLength = 1024
+
+// htmlTagPattern matches a single opening or closing HTML tag, capturing the slash and tag name.
+var htmlTagPattern = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9-]*)[^>]*>`)
+
+// htmlTagReserve is kept free below the limit so closing tags added to the
+// head of an HTML split still fit.
+const htmlTagReserve = 64
+
+// splitFormatted cuts text into head (at most limit runes) and the remaining tail.
+// It prefers to cut at a newline or space, never cuts inside an HTML tag or
+// entity, and for HTML closes tags still open in head and reopens them in tail.
+func splitFormatted(text, parseMode string, limit int) (head, tail string) {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text, ""
+	}
+	isHTML := parseMode == tgbotapi.ModeHTML
+	if isHTML && limit > htmlTagReserve*2 {
+		limit -= htmlTagReserve
+	}
+
+	cut := limit
+	for i := limit; i > limit*3/4; i-- {
+		if runes[i-1] == '\n' {
+			cut = i
+			break
+		}
+		if runes[i-1] == ' ' && cut == limit {
+			cut = i // Remember the last space, but keep looking for a newline
+		}
+	}
+
+	if isHTML {
+		headRunes := runes[:cut]
+		lastLt := lastIndexRune(headRunes, '<')
+		if lastLt > lastIndexRune(headRunes, '>') {
+			cut = lastLt
+		}
+		lastAmp := lastIndexRune(runes[:cut], '&')
+		if lastAmp >= 0 && lastAmp > lastIndexRune(runes[:cut], ';') {
+			cut = lastAmp
+		}
+		if cut == 0 { // Pathological input (e.g. one giant tag); fall back to a hard cut
+			cut = limit
+		}
+	}
+
+	head, tail = string(runes[:cut]), string(runes[cut:])
+	if isHTML && strings.TrimSpace(tail) != "" {
+		open := unclosedHTMLTags(head)
+		var closing, reopening strings.Builder
+		for i := len(open) - 1; i >= 0; i-- {
+			closing.WriteString("</" + htmlTagName(open[i]) + ">")
+		}
+		for _, tag := range open {
+			reopening.WriteString(tag)
+		}
+		head += closing.String()
+		tail = reopening.String() + tail
+	}
+	return strings.TrimSpace(head), strings.TrimSpace(tail)
+}
+
+// unclosedHTMLTags returns the opening tags (verbatim, with attributes) left open at the end of s.
+func unclosedHTMLTags(s string) []string {
+	var stack []string
+	for _, m := range htmlTagPattern.FindAllStringSubmatch(s, -1) {
+		if m[1] == "" {
+			stack = append(stack, m[0])
+			continue
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			if strings.EqualFold(htmlTagName(stack[i]), m[2]) {
+				stack = append(stack[:i], stack[i+1:]...)
+				break
+			}
+		}
+	}
+	return stack
+}
+
+func htmlTagName(tag string) string {
+	if m := htmlTagPattern.FindStringSubmatch(tag); m != nil {
+		return strings.ToLower(m[2])
+	}
+	return ""
+}
+
+func lastIndexRune(runes []rune, r rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitTextPart splits a text part longer than Telegram's message limit into
+// parts in the same parse mode. They keep the part's reaction and discuss
+// button, which go on the first delivered message.
+func splitTextPart(part interfaces.FormattedMessagePart) []interfaces.FormattedMessagePart {
+	if len([]rune(part.Text)) <= telegramMaxMessageLength {
+		return []interfaces.FormattedMessagePart{part}
+	}
+	chunks := SplitMessage(part.Text, part.ParseMode)
+	for i := range chunks {
+		chunks[i].Reaction, chunks[i].DiscussButton = part.Reaction, part.DiscussButton
+	}
+	return chunks
+}
+
+// splitCaptionPart splits a media part whose caption exceeds Telegram's caption
+// limit into the media part with a shortened caption, followed by text parts
+// carrying the remainder in the same parse mode.
+func splitCaptionPart(part interfaces.FormattedMessagePart) []interfaces.FormattedMessagePart {
+	caption := part.Text
+	if part.DocumentURL != "" && part.PhotoURL == "" && part.VideoURL == "" && part.AnimationURL == "" {
+		caption = part.DocumentCaption
+	}
+	if len([]rune(caption)) <= telegramMaxCaptionLength {
+		return []interfaces.FormattedMessagePart{part}
+	}
+
+	head, tail := splitFormatted(caption, part.ParseMode, telegramMaxCaptionLength)
+	if caption == part.Text {
+		part.Text = head
+	} else {
+		part.DocumentCaption = head
+	}
+	return append([]interfaces.FormattedMessagePart{part}, SplitMessage(tail, part.ParseMode)...)
+}
@@ -0,0 +1,102 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitFormatted_ShortTextUnchanged(t *testing.T) {
+	head, tail := splitFormatted("hello", tgbotapi.ModeHTML, 10)
+	assert.Equal(t, "hello", head)
+	assert.Empty(t, tail)
+}
+
+func TestSplitFormatted_PrefersWordBoundary(t *testing.T) {
+	text := strings.Repeat("word ", 30) // 150 runes
+	head, tail := splitFormatted(text, "", 100)
+	assert.LessOrEqual(t, len([]rune(head)), 100)
+	assert.True(t, strings.HasSuffix(head, "word"), "head should end on a whole word, got %q", head)
+	assert.Equal(t, strings.TrimSpace(text), head+" "+tail)
+}
+
+func TestSplitFormatted_BalancesHTMLTags(t *testing.T) {
+	text := `<b>` + strings.Repeat("bold text ", 40) + `</b> <a href="https://example.com">link</a>`
+	head, tail := splitFormatted(text, tgbotapi.ModeHTML, 200)
+
+	assert.LessOrEqual(t, len([]rune(head)), 200)
+	assert.True(t, strings.HasPrefix(head, "<b>"))
+	assert.True(t, strings.HasSuffix(head, "</b>"), "open <b> must be closed in head: %q", head)
+	assert.True(t, strings.HasPrefix(tail, "<b>"), "<b> must be reopened in tail: %q", tail)
+	assert.Empty(t, unclosedHTMLTags(head))
+}
+
+func TestSplitFormatted_NeverCutsInsideTagOrEntity(t *testing.T) {
+	prefix := strings.Repeat("x", 900)
+	for _, suffix := range []string{
+		`<a href="https://example.com/` + strings.Repeat("p", 100) + `">t</a>`,
+		strings.Repeat("&amp;", 40),
+	} {
+		head, tail := splitFormatted(prefix+suffix, tgbotapi.ModeHTML, 1000)
+		assert.True(t, strings.HasPrefix(head, prefix))
+		assert.LessOrEqual(t, strings.LastIndex(head, "<"), strings.LastIndex(head, ">"), "head ends inside a tag: %q", head[len(prefix):])
+		assert.LessOrEqual(t, strings.LastIndex(head, "&"), strings.LastIndex(head, ";"), "head ends inside an entity: %q", head[len(prefix):])
+		assert.Equal(t, prefix+suffix, head+tail)
+	}
+}
+
+func TestSplitMessage_AllPartsFitLimit(t *testing.T) {
+	text := strings.Repeat("<i>line of italic text</i>\n", 400) // ~10800 runes
+	parts := SplitMessage(text, tgbotapi.ModeHTML)
+	require.Greater(t, len(parts), 2)
+	for _, p := range parts {
+		assert.LessOrEqual(t, len([]rune(p.Text)), telegramMaxMessageLength)
+		assert.Empty(t, unclosedHTMLTags(p.Text))
+		assert.Equal(t, tgbotapi.ModeHTML, p.ParseMode)
+	}
+}
+
+func TestSplitTextPart(t *testing.T) {
+	short := interfaces.FormattedMessagePart{Text: "short", Reaction: "🔥"}
+	assert.Equal(t, []interfaces.FormattedMessagePart{short}, splitTextPart(short))
+
+	long := interfaces.FormattedMessagePart{Text: strings.Repeat("word ", 2000), ParseMode: tgbotapi.ModeHTML, Reaction: "🔥"}
+	parts := splitTextPart(long)
+	require.Greater(t, len(parts), 1)
+	for _, p := range parts {
+		assert.LessOrEqual(t, len([]rune(p.Text)), telegramMaxMessageLength)
+		assert.Equal(t, "🔥", p.Reaction, "the reaction survives the split")
+	}
+}
+
+func TestSplitTextPartKeepsDiscussButton(t *testing.T) {
+	long := interfaces.FormattedMessagePart{Text: strings.Repeat("word ", 2000), DiscussButton: "💬 Comments"}
+	parts := splitTextPart(long)
+	require.Greater(t, len(parts), 1)
+	assert.Equal(t, "💬 Comments", parts[0].DiscussButton, "SendToThread takes the button from the first delivered part")
+	for _, p := range parts {
+		assert.Equal(t, "💬 Comments", p.DiscussButton)
+	}
+}
+
+func TestSplitCaptionPart(t *testing.T) {
+	short := interfaces.FormattedMessagePart{PhotoURL: "https://example.com/a.jpg", Text: "short", ParseMode: tgbotapi.ModeHTML}
+	assert.Equal(t, []interfaces.FormattedMessagePart{short}, splitCaptionPart(short))
+
+	long := interfaces.FormattedMessagePart{PhotoURL: "https://example.com/a.jpg", Text: strings.Repeat("caption ", 300), ParseMode: tgbotapi.ModeHTML}
+	parts := splitCaptionPart(long)
+	require.Len(t, parts, 1+1)
+	assert.Equal(t, long.PhotoURL, parts[0].PhotoURL)
+	assert.LessOrEqual(t, len([]rune(parts[0].Text)), telegramMaxCaptionLength)
+	assert.Empty(t, parts[1].PhotoURL, "remainder is sent as plain text")
+	assert.Equal(t, tgbotapi.ModeHTML, parts[1].ParseMode)
+
+	doc := interfaces.FormattedMessagePart{DocumentURL: "https://example.com/a.pdf", DocumentCaption: strings.Repeat("d ", 700)}
+	parts = splitCaptionPart(doc)
+	require.Len(t, parts, 2)
+	assert.LessOrEqual(t, len([]rune(parts[0].DocumentCaption)), telegramMaxCaptionLength)
+}
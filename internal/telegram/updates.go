@@ -0,0 +1,217 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/rs/zerolog/log"
+)
+
+// commandPollTimeout is how long a getUpdates long poll of ListenCommands
+// waits for updates.
+const commandPollTimeout = 10
+
+// commandRetryDelay is how long ListenCommands waits after a failed poll.
+const commandRetryDelay = 5 * time.Second
+
+// updatePoller fetches the updates of each bot with getUpdates. Telegram
+// hands every update out once, so discussion threads and commands share it:
+// whoever polls a bot records the forwards and passes on the commands found.
+type updatePoller struct {
+	mu       sync.Mutex
+	offsets  map[string]int            // Next getUpdates offset per bot token
+	pollers  map[string]*sync.Mutex    // Serializes getUpdates per bot token
+	commands map[string]chan<- Command // Of the bots ListenCommands runs for
+}
+
+// maxPendingCommands bounds the commands waiting for their handler.
+const maxPendingCommands = 100
+
+// Command is a bot command such as "/add https://example.com/feed.xml" sent
+// to the bot.
+type Command struct {
+	ChatID   int64
+	UserID   int64 // 0 when not sent by a user, e.g. in channels
+	Username string
+	Name     string   // Lower case, without the slash and any @botname suffix
+	Args     []string // The rest of the message, split on whitespace
+}
+
+// CommandHandler answers a command. Replies are sent to the command's chat as
+// HTML; an empty reply sends nothing.
+type CommandHandler func(ctx context.Context, cmd Command) string
+
+// incomingMessage is the part of a Bot API message the poller reads.
+type incomingMessage struct {
+	automaticForward
+	Chat *tgbotapi.Chat `json:"chat"`
+	From *tgbotapi.User `json:"from"`
+	Text string         `json:"text"`
+}
+
+// command parses the message as a command for the bot named botName. Commands
+// addressed to other bots (/list@otherbot) are not.
+func (m *incomingMessage) command(botName string) (Command, bool) {
+	if m.Chat == nil || !strings.HasPrefix(m.Text, "/") {
+		return Command{}, false
+	}
+	fields := strings.Fields(m.Text)
+	name, target, _ := strings.Cut(strings.TrimPrefix(fields[0], "/"), "@")
+	if name == "" || (target != "" && !strings.EqualFold(target, botName)) {
+		return Command{}, false
+	}
+	cmd := Command{ChatID: m.Chat.ID, Name: strings.ToLower(name), Args: fields[1:]}
+	if m.From != nil {
+		cmd.UserID = m.From.ID
+		cmd.Username = m.From.UserName
+	}
+	return cmd, true
+}
+
+// poller returns the mutex serializing getUpdates calls of bot.
+func (c *Client) poller(bot *tgbotapi.BotAPI) *sync.Mutex {
+	u := &c.updates
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	poller := u.pollers[bot.Token]
+	if poller == nil {
+		if u.pollers == nil {
+			u.pollers = make(map[string]*sync.Mutex)
+		}
+		poller = &sync.Mutex{}
+		u.pollers[bot.Token] = poller
+	}
+	return poller
+}
+
+// pollUpdates fetches pending message updates of the bot, waiting timeout
+// seconds at most. Automatic forwards among them are recorded for
+// discussion threads, and commands are passed to the bot's listener, if
+// any. Callers must hold the bot's poller.
+func (c *Client) pollUpdates(bot *tgbotapi.BotAPI, timeout int) error {
+	u := &c.updates
+	u.mu.Lock()
+	offset := u.offsets[bot.Token]
+	u.mu.Unlock()
+
+	params := make(tgbotapi.Params)
+	params.AddNonZero("offset", offset)
+	params.AddNonZero("timeout", timeout)
+	if err := params.AddInterface("allowed_updates", []string{"message"}); err != nil {
+		return err
+	}
+	resp, err := bot.MakeRequest("getUpdates", params)
+	if err != nil {
+		return fmt.Errorf("getUpdates: %w", err)
+	}
+	var updates []struct {
+		UpdateID int              `json:"update_id"`
+		Message  *incomingMessage `json:"message"`
+	}
+	if err := json.Unmarshal(resp.Result, &updates); err != nil {
+		return fmt.Errorf("decoding getUpdates result: %w", err)
+	}
+
+	u.mu.Lock()
+	if u.offsets == nil {
+		u.offsets = make(map[string]int)
+	}
+	commands := u.commands[bot.Token]
+	for _, update := range updates {
+		if update.UpdateID >= u.offsets[bot.Token] {
+			u.offsets[bot.Token] = update.UpdateID + 1
+		}
+	}
+	u.mu.Unlock()
+
+	for _, update := range updates {
+		if update.Message == nil {
+			continue
+		}
+		if channelID, postID, ok := update.Message.origin(); ok {
+			c.recordForward(channelID, postID, update.Message.MessageID)
+			continue
+		}
+		if commands == nil {
+			continue
+		}
+		if cmd, ok := update.Message.command(bot.Self.UserName); ok {
+			select {
+			case commands <- cmd:
+			default:
+				log.Warn().Str("command", cmd.Name).Int64("chat_id", cmd.ChatID).Msg("Too many bot commands pending, dropping one")
+			}
+		}
+	}
+	return nil
+}
+
+// ListenCommands long-polls the updates of the bot until ctx is done,
+// answering the commands among them with handle. Commands are handled one at
+// a time, in the order they arrive. Failed polls are retried after a delay.
+func (c *Client) ListenCommands(ctx context.Context, botToken string, proxy *database.Proxy, handle CommandHandler) error {
+	bot, err := c.getBotAPI(botToken, proxy)
+	if err != nil {
+		return err
+	}
+	commands := make(chan Command, maxPendingCommands)
+	u := &c.updates
+	u.mu.Lock()
+	if u.commands == nil {
+		u.commands = make(map[string]chan<- Command)
+	}
+	if u.commands[botToken] != nil {
+		u.mu.Unlock()
+		return fmt.Errorf("already listening for commands of bot %s", bot.Self.UserName)
+	}
+	u.commands[botToken] = commands
+	u.mu.Unlock()
+	defer func() {
+		u.mu.Lock()
+		delete(u.commands, botToken)
+		u.mu.Unlock()
+	}()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case cmd := <-commands:
+				reply := handle(ctx, cmd)
+				if reply == "" {
+					continue
+				}
+				parts := []interfaces.FormattedMessagePart{{Text: reply, ParseMode: "HTML"}}
+				if err := c.Send(ctx, botToken, strconv.FormatInt(cmd.ChatID, 10), parts, proxy); err != nil {
+					log.Error().Err(err).Str("command", cmd.Name).Int64("chat_id", cmd.ChatID).Msg("Failed to reply to bot command")
+				}
+			}
+		}
+	}()
+
+	log.Info().Str("bot_username", bot.Self.UserName).Msg("Listening for bot commands")
+	poller := c.poller(bot)
+	for ctx.Err() == nil {
+		poller.Lock()
+		err := c.pollUpdates(bot, commandPollTimeout)
+		poller.Unlock()
+		if err == nil {
+			continue
+		}
+		log.Warn().Err(err).Str("bot_username", bot.Self.UserName).Msg("Failed to poll bot commands, retrying")
+		select {
+		case <-ctx.Done():
+		case <-time.After(commandRetryDelay):
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package telegram
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncomingMessageCommand(t *testing.T) {
+	parse := func(raw string) (Command, bool) {
+		var m incomingMessage
+		require.NoError(t, json.Unmarshal([]byte(raw), &m))
+		return m.command("FeedBot")
+	}
+
+	cmd, ok := parse(`{"message_id": 1, "chat": {"id": -100}, "from": {"id": 7, "username": "ann"}, "text": "/Add@feedbot  https://example.com/feed.xml   -1001"}`)
+	require.True(t, ok)
+	assert.Equal(t, Command{ChatID: -100, UserID: 7, Username: "ann", Name: "add", Args: []string{"https://example.com/feed.xml", "-1001"}}, cmd)
+
+	cmd, ok = parse(`{"message_id": 2, "chat": {"id": 5}, "text": "/list"}`)
+	require.True(t, ok)
+	assert.Equal(t, Command{ChatID: 5, Name: "list", Args: []string{}}, cmd)
+
+	for _, raw := range []string{
+		`{"message_id": 3, "chat": {"id": 5}, "text": "/list@OtherBot"}`,
+		`{"message_id": 4, "chat": {"id": 5}, "text": "hello /list"}`,
+		`{"message_id": 5, "chat": {"id": 5}, "text": "/"}`,
+		`{"message_id": 6, "chat": {"id": 5}}`,
+	} {
+		_, ok := parse(raw)
+		assert.False(t, ok, raw)
+	}
+}
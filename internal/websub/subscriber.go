@@ -0,0 +1,327 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/haytac/rss-telegram-bot/pkg/interfaces"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxPushBodySize caps content distribution bodies read from hubs.
+	maxPushBodySize = 10 << 20
+	// pendingRetryAfter is how long to wait for a hub to verify a subscription
+	// before sending the request again.
+	pendingRetryAfter = 10 * time.Minute
+	// minRenewBefore renews leases at least this long before they expire.
+	minRenewBefore = time.Hour
+)
+
+// PushHandler receives content pushed by a hub for a feed. body is the feed
+// document the hub distributed; it may be empty for "thin" pings. It is called
+// before the hub is answered and must not block: it starts processing the push
+// in the background and reports whether it did, e.g. false when shutting down,
+// in which case the hub is asked to retry.
+type PushHandler func(feedID int64, body []byte) bool
+
+// Subscriber manages WebSub subscriptions for feeds and serves the callback
+// endpoint hubs use to verify subscriptions and deliver updates.
+type Subscriber struct {
+	store         *database.WebSubStore
+	clientFactory interfaces.HTTPClientFactory
+	callbackBase  string
+	leaseSeconds  int
+	onPush        PushHandler
+}
+
+// NewSubscriber creates a Subscriber. callbackBase is the public base URL under
+// which the handler is reachable; per-feed callbacks are callbackBase/websub/{feedID}.
+func NewSubscriber(store *database.WebSubStore, clientFactory interfaces.HTTPClientFactory, callbackBase string, leaseSeconds int) *Subscriber {
+	return &Subscriber{
+		store:         store,
+		clientFactory: clientFactory,
+		callbackBase:  strings.TrimRight(callbackBase, "/"),
+		leaseSeconds:  leaseSeconds,
+	}
+}
+
+// OnPush sets the function called for each verified content distribution request.
+func (s *Subscriber) OnPush(fn PushHandler) {
+	s.onPush = fn
+}
+
+// Handler returns the HTTP handler for hub callbacks.
+func (s *Subscriber) Handler() http.Handler {
+	mux := chi.NewRouter()
+	mux.Get("/websub/{feedID}", s.handleVerify)
+	mux.Post("/websub/{feedID}", s.handlePush)
+	return mux
+}
+
+// StartServer serves the callback endpoint on addr in the background.
+func (s *Subscriber) StartServer(addr string) {
+	log.Info().Str("address", addr).Str("callback_base", s.callbackBase).Msg("Starting WebSub callback server")
+	go func() {
+		if err := http.ListenAndServe(addr, s.Handler()); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("WebSub callback server failed")
+		}
+	}()
+}
+
+// Ensure subscribes the feed to hub for topic unless a matching subscription is
+// active and not close to expiry, or was requested recently and awaits
+// verification. An empty hub reuses the hub of the stored subscription, so
+// leases keep being renewed while the feed answers 304 Not Modified.
+func (s *Subscriber) Ensure(ctx context.Context, feedID int64, hubURL, topicURL string, proxy *database.Proxy) error {
+	existing, err := s.store.GetSubscription(ctx, feedID)
+	if err != nil {
+		return err
+	}
+	if hubURL == "" {
+		if existing == nil {
+			return nil
+		}
+		hubURL, topicURL = existing.HubURL, existing.TopicURL
+	}
+	if existing != nil && existing.HubURL == hubURL && existing.TopicURL == topicURL && !s.needsRenewal(existing) {
+		return nil
+	}
+	if existing != nil && awaitingRenewal(existing, hubURL, topicURL) {
+		return nil
+	}
+	return s.subscribe(ctx, feedID, hubURL, topicURL, proxy)
+}
+
+// awaitingRenewal reports whether the renewal of an active subscription to hubURL
+// for topicURL was requested recently and awaits verification.
+func awaitingRenewal(sub *database.WebSubSubscription, hubURL, topicURL string) bool {
+	return sub.PendingHubURL != nil && *sub.PendingHubURL == hubURL &&
+		sub.PendingTopicURL != nil && *sub.PendingTopicURL == topicURL &&
+		time.Since(sub.UpdatedAt) <= pendingRetryAfter
+}
+
+func (s *Subscriber) needsRenewal(sub *database.WebSubSubscription) bool {
+	now := time.Now()
+	switch sub.State {
+	case database.WebSubStateActive:
+		if sub.LeaseExpiresAt == nil {
+			return false
+		}
+		renewBefore := time.Duration(s.leaseSeconds) * time.Second / 10
+		if renewBefore < minRenewBefore {
+			renewBefore = minRenewBefore
+		}
+		return now.Add(renewBefore).After(*sub.LeaseExpiresAt)
+	case database.WebSubStatePending, database.WebSubStateDenied:
+		return now.Sub(sub.UpdatedAt) > pendingRetryAfter
+	}
+	return true
+}
+
+// subscribe sends a subscription request to the hub. The hub verifies it
+// asynchronously by calling back handleVerify.
+func (s *Subscriber) subscribe(ctx context.Context, feedID int64, hubURL, topicURL string, proxy *database.Proxy) error {
+	secret, err := newSecret()
+	if err != nil {
+		return err
+	}
+	// Stored before the request so the verification callback, which can arrive
+	// before the hub responds, finds the pending subscription. An active one
+	// keeps its secret until then.
+	sub := &database.WebSubSubscription{FeedID: feedID, HubURL: hubURL, TopicURL: topicURL, Secret: secret}
+	if err := s.store.SaveSubscription(ctx, sub); err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("hub.mode", "subscribe")
+	form.Set("hub.topic", topicURL)
+	form.Set("hub.callback", s.CallbackURL(feedID))
+	form.Set("hub.secret", secret)
+	if s.leaseSeconds > 0 {
+		form.Set("hub.lease_seconds", strconv.Itoa(s.leaseSeconds))
+	}
+
+	httpClient, err := s.clientFactory.GetClient(proxy)
+	if err != nil {
+		return fmt.Errorf("failed to get HTTP client for hub %s: %w", hubURL, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hubURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create subscription request for hub %s: %w", hubURL, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("subscribing to hub %s: %w", hubURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("hub %s rejected subscription: status %d, body: %s", hubURL, resp.StatusCode, string(body))
+	}
+	log.Info().Int64("feed_id", feedID).Str("hub_url", hubURL).Str("topic_url", topicURL).Msg("Requested WebSub subscription")
+	return nil
+}
+
+// CallbackURL returns the callback URL registered with hubs for a feed.
+func (s *Subscriber) CallbackURL(feedID int64) string {
+	return fmt.Sprintf("%s/websub/%d", s.callbackBase, feedID)
+}
+
+// handleVerify answers the hub's verification of intent (and denial notices).
+func (s *Subscriber) handleVerify(w http.ResponseWriter, r *http.Request) {
+	feedID, err := strconv.ParseInt(chi.URLParam(r, "feedID"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	q := r.URL.Query()
+	mode, topic := q.Get("hub.mode"), q.Get("hub.topic")
+	l := log.With().Int64("feed_id", feedID).Str("hub_mode", mode).Str("topic_url", topic).Logger()
+
+	sub, err := s.store.GetSubscription(r.Context(), feedID)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to load WebSub subscription for verification")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	switch mode {
+	case "subscribe":
+		if sub == nil || requestedTopic(sub) != topic {
+			l.Warn().Msg("Rejecting WebSub verification for unknown subscription")
+			http.NotFound(w, r)
+			return
+		}
+		lease, _ := strconv.Atoi(q.Get("hub.lease_seconds"))
+		if lease <= 0 {
+			lease = s.leaseSeconds
+		}
+		if err := s.store.ActivateSubscription(r.Context(), feedID, time.Now().Add(time.Duration(lease)*time.Second)); err != nil {
+			l.Error().Err(err).Msg("Failed to activate WebSub subscription")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		l.Info().Int("lease_seconds", lease).Msg("WebSub subscription verified")
+	case "unsubscribe":
+		// We never unsubscribe from a feed we still have a subscription for.
+		if sub != nil && sub.TopicURL == topic {
+			http.NotFound(w, r)
+			return
+		}
+	case "denied":
+		if sub != nil {
+			if err := s.store.SetSubscriptionState(r.Context(), feedID, database.WebSubStateDenied); err != nil {
+				l.Error().Err(err).Msg("Failed to record WebSub denial")
+			}
+		}
+		l.Warn().Str("reason", q.Get("hub.reason")).Msg("WebSub hub denied subscription")
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		http.Error(w, "unsupported hub.mode", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = io.WriteString(w, q.Get("hub.challenge"))
+}
+
+// requestedTopic returns the topic of the subscription's latest request to
+// the hub, the one a verification of intent is for.
+func requestedTopic(sub *database.WebSubSubscription) string {
+	if sub.PendingTopicURL != nil {
+		return *sub.PendingTopicURL
+	}
+	return sub.TopicURL
+}
+
+// handlePush accepts a content distribution request and hands it to the push handler.
+func (s *Subscriber) handlePush(w http.ResponseWriter, r *http.Request) {
+	feedID, err := strconv.ParseInt(chi.URLParam(r, "feedID"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	l := log.With().Int64("feed_id", feedID).Logger()
+
+	sub, err := s.store.GetSubscription(r.Context(), feedID)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to load WebSub subscription for push")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if sub == nil || sub.State != database.WebSubStateActive {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPushBodySize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	// Per the spec, bad signatures are acknowledged but the content is ignored.
+	if !validSignature(r.Header.Get("X-Hub-Signature"), sub.Secret, body) {
+		l.Warn().Msg("Ignoring WebSub push with missing or invalid signature")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	l.Info().Int("body_bytes", len(body)).Msg("Received WebSub push")
+	if s.onPush != nil && !s.onPush(feedID, body) {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validSignature checks an X-Hub-Signature header of the form "sha256=<hex>".
+func validSignature(header, secret string, body []byte) bool {
+	algo, sig, found := strings.Cut(header, "=")
+	if !found {
+		return false
+	}
+	var newHash func() hash.Hash
+	switch strings.ToLower(algo) {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating WebSub secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,108 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haytac/rss-telegram-bot/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidSignature(t *testing.T) {
+	body := []byte(`<feed/>`)
+	sig := sign("secret", body)
+
+	assert.True(t, validSignature(sig, "secret", body))
+	assert.False(t, validSignature(sig, "other", body))
+	assert.False(t, validSignature(sig, "secret", []byte(`<feed>changed</feed>`)))
+	assert.False(t, validSignature("", "secret", body))
+	assert.False(t, validSignature("md5=abcd", "secret", body))
+}
+
+// sign returns the X-Hub-Signature of body for secret.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// defaultClientFactory hands out http.DefaultClient.
+type defaultClientFactory struct{}
+
+func (defaultClientFactory) GetClient(*database.Proxy) (*http.Client, error) {
+	return http.DefaultClient, nil
+}
+
+func TestRenewalKeepsActiveSubscription(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "websub.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+	const feedID, topic = int64(1), "https://example.com/feed.xml"
+
+	var requests atomic.Int32
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	store := database.NewWebSubStore(db)
+	require.NoError(t, store.SaveSubscription(ctx, &database.WebSubSubscription{FeedID: feedID, HubURL: hub.URL, TopicURL: topic, Secret: "old"}))
+	require.NoError(t, store.ActivateSubscription(ctx, feedID, time.Now().Add(time.Minute)))
+
+	s := NewSubscriber(store, defaultClientFactory{}, "https://bot.example.com", 86400)
+	pushes := make(chan []byte, 1)
+	s.OnPush(func(feedID int64, body []byte) bool {
+		pushes <- body
+		return true
+	})
+
+	// The lease is about to expire: renew it, keeping the active subscription
+	require.NoError(t, s.Ensure(ctx, feedID, hub.URL, topic, nil))
+	require.NoError(t, s.Ensure(ctx, feedID, hub.URL, topic, nil))
+	assert.Equal(t, int32(1), requests.Load(), "renewal awaiting verification is not requested again")
+	sub, err := store.GetSubscription(ctx, feedID)
+	require.NoError(t, err)
+	assert.Equal(t, database.WebSubStateActive, sub.State)
+	assert.Equal(t, "old", sub.Secret)
+	require.NotNil(t, sub.LeaseExpiresAt)
+	require.NotNil(t, sub.PendingSecret)
+	newSecret := *sub.PendingSecret
+
+	// Pushes signed with the active secret are still delivered
+	body := []byte(`<feed/>`)
+	req := httptest.NewRequest(http.MethodPost, "/websub/1", strings.NewReader(string(body)))
+	req.Header.Set("X-Hub-Signature", sign("old", body))
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	select {
+	case got := <-pushes:
+		assert.Equal(t, body, got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("push not delivered")
+	}
+
+	// Verification promotes the renewal
+	q := url.Values{"hub.mode": {"subscribe"}, "hub.topic": {topic}, "hub.challenge": {"c"}, "hub.lease_seconds": {"86400"}}
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/websub/1?"+q.Encode(), nil))
+	assert.Equal(t, "c", rec.Body.String())
+	sub, err = store.GetSubscription(ctx, feedID)
+	require.NoError(t, err)
+	assert.Equal(t, newSecret, sub.Secret)
+	assert.Nil(t, sub.PendingSecret)
+	assert.True(t, sub.LeaseExpiresAt.After(time.Now().Add(time.Hour)))
+}
@@ -3,6 +3,7 @@ package interfaces
 import (
 	"context"
 	"net/http" // Needed for HTTPClientFactory
+	"time"
 
 	// External dependencies needed by type definitions in this file
 	"github.com/mmcdole/gofeed"
@@ -17,6 +18,14 @@ type FetchResult struct {
 	Feed            *gofeed.Feed
 	NewEtag         *string
 	NewLastModified *string
+	NewBodyHash     *string       // SHA-256 of the fetched body, compared on the next fetch for servers without validators
+	HubURL          string        // WebSub hub advertised by the feed, if any
+	TopicURL        string        // WebSub topic (rel="self") URL; defaults to the fetched URL
+	PermanentURL    string        // Final URL when the fetch followed only permanent (301/308) redirects
+	ParseDuration   time.Duration // Time spent parsing the document, for metrics
+	// EnrichItems, when set, completes items before they are sent, e.g. by
+	// fetching details too costly to fetch for every item on every poll.
+	EnrichItems func(ctx context.Context, items []*gofeed.Item)
 }
 
 // FormattedMessagePart represents a piece of a message to be sent.
@@ -24,15 +33,102 @@ type FormattedMessagePart struct {
 	Text            string
 	ParseMode       string
 	PhotoURL        string
+	VideoURL        string
+	AnimationURL    string // GIFs and silent MP4s, rendered inline with autoplay
 	DocumentURL     string
 	DocumentCaption string
 	DocumentName    string
+	MediaGroup      []MediaGroupItem // Photos and videos sent as one album captioned with Text; PhotoURL or VideoURL still names the first, for notifiers without albums
+	Reaction        string // Emoji reaction to set on the first delivered message, if any
+	DiscussButton   string // Label of a button opening the first delivered message's comments, for channels with a discussion group
+}
+
+// MediaGroupItem is a photo or video of an album.
+type MediaGroupItem struct {
+	Type string `json:"type"` // "photo" or "video"
+	URL  string `json:"url"`
+}
+
+// HasMedia reports whether the part carries a photo, video, animation, document, or album.
+func (p FormattedMessagePart) HasMedia() bool {
+	return p.PhotoURL != "" || p.VideoURL != "" || p.AnimationURL != "" || p.DocumentURL != "" || len(p.MediaGroup) > 0
+}
+
+// FetchOptions carries per-feed request settings for a fetch.
+type FetchOptions struct {
+	UserAgent string            // Overrides the fetcher's default User-Agent when non-empty
+	Headers   map[string]string // Extra request headers; these win over the fetcher's defaults
+	Auth      *database.FeedCredentials // Basic or bearer credentials for protected feeds
+	FeedID    int64             // Selects the feed's cookie jar when the client factory supports one
+	Cookies   map[string]string // Configured cookies, seeded into the jar (or sent directly without one)
+	Timeout    time.Duration     // Per-request timeout override; zero uses the fetcher's
+	MaxRetries *int              // Retry count override; nil uses the fetcher's
+	RetryDelay time.Duration     // Initial retry backoff override; zero uses the fetcher's
+	TLS        *database.TLSConfig // Feed TLS settings, layered over the proxy's by the client factory
+	UseFlareSolverr bool           // Always fetch through FlareSolverr instead of directly
+	BodyHash        string         // Body hash of the previous fetch; a body hashing the same is reported as not modified
+}
+
+// FetchOptionsForFeed returns the request settings stored on a feed.
+func FetchOptionsForFeed(feed *database.Feed) FetchOptions {
+	opts := FetchOptions{Headers: feed.RequestHeaders, FeedID: feed.ID, Cookies: feed.Cookies, TLS: feed.TLS, UseFlareSolverr: feed.UseFlareSolverr}
+	if feed.UserAgent != nil {
+		opts.UserAgent = *feed.UserAgent
+	}
+	if feed.FetchTimeoutSeconds != nil {
+		opts.Timeout = time.Duration(*feed.FetchTimeoutSeconds) * time.Second
+	}
+	opts.MaxRetries = feed.FetchMaxRetries
+	if feed.FetchRetryDelaySeconds != nil {
+		opts.RetryDelay = time.Duration(*feed.FetchRetryDelaySeconds) * time.Second
+	}
+	if feed.HTTPBodyHash != nil {
+		opts.BodyHash = *feed.HTTPBodyHash
+	}
+	return opts
 }
 
 // FeedFetcher fetches RSS feed items.
 type FeedFetcher interface {
 	// Uses database.Proxy from the import above
-	Fetch(ctx context.Context, url string, etag, lastModified *string, proxy *database.Proxy) (*FetchResult, error)
+	Fetch(ctx context.Context, url string, etag, lastModified *string, proxy *database.Proxy, opts FetchOptions) (*FetchResult, error)
+}
+
+// ScrapeFetcher builds feeds from HTML pages for feeds of source type "scrape".
+type ScrapeFetcher interface {
+	FetchScrape(ctx context.Context, url string, cfg *database.ScrapeConfig, etag, lastModified *string, proxy *database.Proxy, opts FetchOptions) (*FetchResult, error)
+}
+
+// SitemapFetcher turns sitemaps into feeds for feeds of source type "sitemap".
+type SitemapFetcher interface {
+	FetchSitemap(ctx context.Context, url string, etag, lastModified *string, proxy *database.Proxy, opts FetchOptions) (*FetchResult, error)
+}
+
+// ArchiveFetcher walks RFC 5005 archive pages to backfill a feed's history.
+type ArchiveFetcher interface {
+	FetchArchives(ctx context.Context, feed *gofeed.Feed, feedURL string, limit int, maxAge time.Duration, proxy *database.Proxy, opts FetchOptions) ([]*gofeed.Item, error)
+}
+
+// ProxyPicker chooses the proxy for a feed's fetch from a proxy pool, or nil
+// when none of its proxies is usable.
+type ProxyPicker interface {
+	PoolProxy(pool *database.ProxyPool, feedID int64) *database.Proxy
+}
+
+// IconFetcher downloads the favicon of the site behind a feed.
+type IconFetcher interface {
+	FetchIcon(ctx context.Context, feed *gofeed.Feed, feedURL string, proxy *database.Proxy, opts FetchOptions) (*database.FeedIcon, error)
+}
+
+// ArticleFetcher extracts the main content of an item's page, for profiles
+// sending full articles instead of the feed's summaries.
+type ArticleFetcher interface {
+	FetchArticle(ctx context.Context, pageURL string, proxy *database.Proxy, opts FetchOptions) (string, error)
+}
+
+// MailFetcher reads mailboxes as feeds for feeds of source type "imap".
+type MailFetcher interface {
+	FetchIMAP(ctx context.Context, url string, etag *string, opts FetchOptions) (*FetchResult, error)
 }
 
 // Formatter formats a feed item for notification.
@@ -42,6 +138,14 @@ type Formatter interface {
 	FormatItem(ctx context.Context, item *gofeed.Item, feed *database.Feed, profile *database.FormattingProfile) ([]FormattedMessagePart, error)
 }
 
+// DigestFormatter is a Formatter that can also combine the items of a feed in
+// digest mode into one digest message.
+type DigestFormatter interface {
+	Formatter
+	// FormatDigest formats the items collected from from to to, oldest first.
+	FormatDigest(ctx context.Context, items []*gofeed.Item, feed *database.Feed, feedTitle string, from, to time.Time, profile *database.FormattingProfile) ([]FormattedMessagePart, error)
+}
+
 // Notifier sends notifications.
 type Notifier interface {
 	// Uses FormattedMessagePart defined in this package
@@ -50,12 +154,35 @@ type Notifier interface {
 	Name() string
 }
 
+// ThreadNotifier is a Notifier that can post into Telegram forum topics,
+// spread items over bot pools and create topics for feeds.
+type ThreadNotifier interface {
+	Notifier
+	// SendToThread sends parts like Send, into a forum topic when threadID is non-zero.
+	SendToThread(ctx context.Context, botToken, chatID string, threadID int, parts []FormattedMessagePart, proxy *database.Proxy) error
+	// NextPoolToken picks the token of a bot pool to send the next item with.
+	NextPoolToken(poolKey string, tokens []string) string
+	// ResolvedChatID returns the numeric ID learned for an @username chat.
+	ResolvedChatID(chatID string) (int64, bool)
+	CreateForumTopic(ctx context.Context, botToken, chatID, name string, proxy *database.Proxy) (int, error)
+}
+
 // Scheduler manages timed tasks for fetching feeds.
 type Scheduler interface {
 	// Uses database.Feed from the import above
 	Add(feed *database.Feed, task func(f *database.Feed)) error
+	// SetUpdateHint records the update interval a feed declares, so it is
+	// polled no more often than that.
+	SetUpdateHint(feedID int64, hint time.Duration)
+	// SetFailures records a feed's consecutive failed fetches, so it is
+	// polled less often while it keeps failing.
+	SetFailures(feedID int64, failures int)
+	// Scheduled reports whether the feed is scheduled.
+	Scheduled(feedID int64) bool
 	Start(ctx context.Context)
 	Stop()
+	// Running reports whether the scheduler loop is running.
+	Running() bool
 }
 
 // ProxyValidator checks if a proxy is working.
@@ -67,4 +194,10 @@ type ProxyValidator interface {
 // HTTPClientFactory creates HTTP clients.
 type HTTPClientFactory interface {
     GetClient(proxy *database.Proxy) (*http.Client, error) // Uses http.Client
+}
+
+// FeedHTTPClientFactory is implemented by client factories that keep per-feed
+// state, such as cookie jars, on the clients they return.
+type FeedHTTPClientFactory interface {
+	GetClientForFeed(proxy *database.Proxy, feedID int64, tls *database.TLSConfig) (*http.Client, error)
 }
\ No newline at end of file